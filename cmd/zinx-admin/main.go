@@ -0,0 +1,444 @@
+// zinx-admin 集群管理命令行工具, 通过注册中心发现全部节点, 访问每个节点的管理HTTP服务
+//
+// 用法:
+//
+//	zinx-admin nodes  -registry etcd -addrs 127.0.0.1:2379 -service game     列出全部节点
+//	zinx-admin conns  -service game [-node id] [-list]                       每个节点的连接数量与收发统计
+//	zinx-admin drain  -service game -node id [-wait 30s]                     排空节点的连接后停止节点
+//	zinx-admin config -service game -f patch.json [-node id]                 推送配置, 只需包含要修改的字段
+//	zinx-admin config -service game [-field MaxConn,Profile] [-node id]      查看节点当前生效的配置(不包括密钥)
+//	zinx-admin kick   -service game -tag uid:10001                           断开集群中拥有标签的连接, 如踢下线某个用户
+//
+// 节点需要设置管理HTTP服务与注册中心, 管理HTTP服务的地址随实例元数据注册
+// 注册中心与访问令牌也可以通过环境变量ZINX_REGISTRY、ZINX_REGISTRY_ADDRS、ZINX_ADMIN_TOKEN设置
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+)
+
+// commands 全部子命令
+var commands = map[string]func(args []string) error{
+	"nodes":  runNodes,
+	"conns":  runConns,
+	"drain":  runDrain,
+	"config": runConfig,
+	"kick":   runKick,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: zinx-admin <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  nodes   list registered nodes")
+	fmt.Fprintln(os.Stderr, "  conns   show connection count and traffic of each node")
+	fmt.Fprintln(os.Stderr, "  drain   drain connections and stop a node")
+	fmt.Fprintln(os.Stderr, "  config  show effective config of nodes, or push config changes with -f")
+	fmt.Fprintln(os.Stderr, "  kick    kick connections with the given tags cluster-wide")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "zinx-admin %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// cluster 全部子命令共用的参数
+type cluster struct {
+	registry      string
+	addrs         string
+	namespace     string
+	registryToken string
+	service       string
+	token         string
+	node          string
+	timeout       time.Duration
+}
+
+func clusterFlags(fs *flag.FlagSet) *cluster {
+	c := &cluster{}
+	fs.StringVar(&c.registry, "registry", envOr("ZINX_REGISTRY", zdiscovery.KindEtcd), "registry kind: etcd, consul or nacos")
+	fs.StringVar(&c.addrs, "addrs", envOr("ZINX_REGISTRY_ADDRS", "127.0.0.1:2379"), "comma separated registry addresses")
+	fs.StringVar(&c.namespace, "namespace", "", "etcd key prefix or nacos namespace")
+	fs.StringVar(&c.registryToken, "registry-token", os.Getenv("ZINX_REGISTRY_TOKEN"), "consul ACL token")
+	fs.StringVar(&c.service, "service", "", "service name of the nodes")
+	fs.StringVar(&c.token, "token", os.Getenv("ZINX_ADMIN_TOKEN"), "admin token of the nodes")
+	fs.StringVar(&c.node, "node", "", "only the node with this instance ID")
+	fs.DurationVar(&c.timeout, "timeout", 5*time.Second, "timeout of each request")
+	return c
+}
+
+func envOr(key, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
+
+// node 一个节点
+type node struct {
+	instance ziface.ServiceInstance
+	admin    string //管理HTTP服务的地址, 没有时为空
+}
+
+// discover 从注册中心发现节点, 按实例ID过滤
+func (c *cluster) discover() ([]node, error) {
+	if c.service == "" {
+		return nil, errors.New("missing -service")
+	}
+	registry, err := zdiscovery.NewRegistry(c.registry, strings.Split(c.addrs, ","), c.namespace, c.registryToken)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	instances, err := registry.Discover(ctx, c.service)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []node
+	for _, instance := range instances {
+		if c.node != "" && instance.ID != c.node {
+			continue
+		}
+		nodes = append(nodes, node{instance: instance, admin: adminAddr(instance)})
+	}
+	if c.node != "" && len(nodes) == 0 {
+		return nil, fmt.Errorf("node %s not found", c.node)
+	}
+	return nodes, nil
+}
+
+// adminAddr 实例的管理HTTP服务地址, 监听所有地址时使用实例的主机
+func adminAddr(instance ziface.ServiceInstance) string {
+	addr := instance.Metadata[zdiscovery.MetaAdminAddr]
+	if addr == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if instanceHost, _, err := net.SplitHostPort(instance.Addr); err == nil {
+			host = instanceHost
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// call 访问节点的管理接口, form不为nil时作为表单提交, 否则提交body, 结果解码到resp
+func (c *cluster) call(n node, method, path string, form url.Values, body []byte, resp interface{}) error {
+	if n.admin == "" {
+		return errors.New("admin endpoint is not registered")
+	}
+	contentType := "application/json"
+	if form != nil {
+		body = []byte(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+n.admin+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", contentType)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(data)))
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(data, resp)
+}
+
+// each 并发地对每个节点执行fn, 按节点顺序返回结果
+func each(nodes []node, fn func(n node) (interface{}, error)) ([]interface{}, []error) {
+	results := make([]interface{}, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = fn(nodes[i])
+		}(i)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// report 打印每个节点的结果, 有节点失败时返回错误
+func report(w io.Writer, nodes []node, errs []error, line func(i int) string) error {
+	failed := 0
+	for i, n := range nodes {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(w, "%s\terror: %v\n", n.instance.ID, errs[i])
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", n.instance.ID, line(i))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d nodes failed", failed, len(nodes))
+	}
+	return nil
+}
+
+func runNodes(args []string) error {
+	fs := flag.NewFlagSet("nodes", flag.ExitOnError)
+	c := clusterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tADDR\tLOAD\tADMIN")
+	for _, n := range nodes {
+		admin := n.admin
+		if admin == "" {
+			admin = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", n.instance.ID, n.instance.Addr, n.instance.Load, admin)
+	}
+	return w.Flush()
+}
+
+// connsResp 节点的连接统计
+type connsResp struct {
+	Connections int                     `json:"connections"`
+	Stats       ziface.ConnManagerStats `json:"stats"`
+}
+
+func runConns(args []string) error {
+	fs := flag.NewFlagSet("conns", flag.ExitOnError)
+	c := clusterFlags(fs)
+	list := fs.Bool("list", false, "list every connection")
+	ip := fs.String("ip", "", "only connections from this IP")
+	tag := fs.String("tag", "", "only connections with this tag, key:value")
+	limit := fs.Int("limit", 0, "max connections listed per node")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *ip != "" {
+		query.Set("ip", *ip)
+	}
+	if *tag != "" {
+		query.Set("tag", *tag)
+	}
+	if *limit > 0 {
+		query.Set("limit", fmt.Sprint(*limit))
+	}
+	results, errs := each(nodes, func(n node) (interface{}, error) {
+		var resp connsResp
+		err := c.call(n, http.MethodGet, "/admin/conns?"+query.Encode(), nil, nil, &resp)
+		return resp, err
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCONNS\tMATCHED\tBYTES_IN\tBYTES_OUT")
+	err = report(w, nodes, errs, func(i int) string {
+		resp := results[i].(connsResp)
+		line := fmt.Sprintf("%d\t%d\t%d\t%d", resp.Connections, len(resp.Stats.Conns), resp.Stats.Total.BytesIn, resp.Stats.Total.BytesOut)
+		if *list {
+			for _, conn := range resp.Stats.Conns {
+				line += fmt.Sprintf("\n  %d\t%s\t%d\t%d", conn.ConnID, conn.RemoteAddr, conn.BytesIn, conn.BytesOut)
+			}
+		}
+		return line
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func runDrain(args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	c := clusterFlags(fs)
+	wait := fs.Duration("wait", 0, "max time to wait for connections to close, default by the node")
+	all := fs.Bool("all", false, "drain all nodes when -node is not set")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if c.node == "" && !*all {
+		return errors.New("missing -node, use -all to drain every node")
+	}
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	if *wait > 0 {
+		form.Set("timeout", wait.String())
+	}
+	_, errs := each(nodes, func(n node) (interface{}, error) {
+		return nil, c.call(n, http.MethodPost, "/admin/drain", form, nil, nil)
+	})
+	return report(os.Stdout, nodes, errs, func(i int) string { return "drain started" })
+}
+
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	c := clusterFlags(fs)
+	file := fs.String("f", "", "JSON file with the config fields to change, show the effective config if empty")
+	fields := fs.String("field", "", "comma separated config fields to show, e.g. MaxConn,Profile")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return showConfig(c, *fields)
+	}
+	data, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("%s is not valid JSON", *file)
+	}
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	results, errs := each(nodes, func(n node) (interface{}, error) {
+		var resp ziface.ConfigReloadReport
+		err := c.call(n, http.MethodPost, "/admin/config", nil, data, &resp)
+		return resp, err
+	})
+	return report(os.Stdout, nodes, errs, func(i int) string {
+		resp := results[i].(ziface.ConfigReloadReport)
+		return fmt.Sprintf("applied %v, restart required %v", resp.Applied, resp.RestartRequired)
+	})
+}
+
+// showConfig 查看每个节点当前生效的配置, 指定字段时按表格对比各个节点的值
+func showConfig(c *cluster, fields string) error {
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+	results, errs := each(nodes, func(n node) (interface{}, error) {
+		var config map[string]json.RawMessage
+		err := c.call(n, http.MethodGet, "/admin/config", nil, nil, &config)
+		return config, err
+	})
+
+	if fields == "" {
+		return report(os.Stdout, nodes, errs, func(i int) string {
+			data, _ := json.MarshalIndent(results[i], "", "  ")
+			return string(data)
+		})
+	}
+
+	names := strings.Split(fields, ",")
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\t"+strings.Join(names, "\t"))
+	err = report(w, nodes, errs, func(i int) string {
+		config := results[i].(map[string]json.RawMessage)
+		values := make([]string, len(names))
+		for j, name := range names {
+			values[j] = "-"
+			if value, ok := config[strings.TrimSpace(name)]; ok {
+				values[j] = string(value)
+			}
+		}
+		return strings.Join(values, "\t")
+	})
+	_ = w.Flush()
+	return err
+}
+
+func runKick(args []string) error {
+	fs := flag.NewFlagSet("kick", flag.ExitOnError)
+	c := clusterFlags(fs)
+	var tags stringList
+	fs.Var(&tags, "tag", "connection tag key:value, repeatable, e.g. -tag uid:10001")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return errors.New("missing -tag")
+	}
+	nodes, err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"tag": tags}
+	results, errs := each(nodes, func(n node) (interface{}, error) {
+		var resp struct {
+			Count int `json:"count"`
+		}
+		err := c.call(n, http.MethodPost, "/admin/kick", form, nil, &resp)
+		return resp.Count, err
+	})
+	return report(os.Stdout, nodes, errs, func(i int) string {
+		return fmt.Sprintf("kicked %d connections", results[i].(int))
+	})
+}
+
+// stringList 可以重复的字符串参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,90 @@
+// zinx 命令行工具
+//
+// 用法:
+//
+//	zinx gen -i msg.json -o msg_gen.go    根据JSON Schema消息定义生成代码
+//	zinx gen -i msg.proto -o msg_zinx.go  根据proto消息定义(// @msgid 注释)生成注册、路由与发送代码
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aceld/zinx/zschema"
+)
+
+// commands 全部子命令
+var commands = map[string]func(args []string) error{
+	"gen": runGen,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: zinx <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  gen    generate msgID registry, router stubs, send helpers and validation code")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "zinx %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	input := fs.String("i", "", "message definition file (.json or .proto)")
+	output := fs.String("o", "", "output go file, default stdout")
+	pkg := fs.String("pkg", "", "go package name, overrides the one in the definition file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		fs.Usage()
+		return fmt.Errorf("missing -i")
+	}
+
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+
+	var schema *zschema.Schema
+	if strings.EqualFold(filepath.Ext(*input), ".proto") {
+		schema, err = zschema.ParseProto(data)
+	} else {
+		schema, err = zschema.ParseJSONSchema(data)
+	}
+	if err != nil {
+		return err
+	}
+	if *pkg != "" {
+		schema.Package = *pkg
+	}
+
+	code, err := zschema.Generate(schema)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(*output, code, 0644)
+}
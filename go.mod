@@ -6,4 +6,5 @@ require (
 	github.com/golang/protobuf v1.3.3
 	github.com/gorilla/websocket v1.5.0
 	github.com/stretchr/testify v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -37,6 +37,7 @@ func InitConfigFlag(defaultValue string, tips string) {
 	isInit = true
 
 	uflag.StringVar(&Args.ConfigFile, "c", defaultValue, tips)
+	uflag.StringVar(&Args.ConfigFile, "config", defaultValue, tips)
 	return
 }
 
@@ -0,0 +1,173 @@
+// Package zbridge 提供zinx与外部消息总线(NATS、Kafka等)之间的桥接
+// 客户端发来的指定消息发布到总线的主题, 总线上的消息注入到本节点的连接、分组或发布订阅主题
+// 内置NATS的实现, 其他消息系统实现ziface.IMessageBus即可接入, 如使用Kafka客户端库的适配器
+package zbridge
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+// DefaultPublishTimeout 发布到消息总线的默认超时时间
+const DefaultPublishTimeout = 5 * time.Second
+
+// BusMessage 在消息总线上传递的消息, 使用JSON编码, Data为base64
+// 发布时记录消息来自的节点与连接; 注入时按以下顺序选择目标:
+// Group不为空时向分组广播, Topic不为空时发布到发布订阅主题,
+// Node不为空时由该节点发送给ConnID对应的连接(原样带回节点与连接即可回复客户端), 否则向本节点的全部连接广播
+type BusMessage struct {
+	MsgID  uint32            `json:"msgID"`
+	Data   []byte            `json:"data,omitempty"`
+	Node   string            `json:"node,omitempty"`
+	ConnID uint64            `json:"connID"`
+	Group  string            `json:"group,omitempty"`
+	Topic  string            `json:"topic,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+}
+
+// BridgeConfig 桥接配置
+type BridgeConfig struct {
+	Bus            ziface.IMessageBus                              //消息总线
+	NodeID         string                                          //本节点ID, 默认为"主机名-进程ID"
+	Meta           func(request ziface.IRequest) map[string]string //发布时附带的元数据, 如连接上的用户ID, 可以为nil
+	PublishTimeout time.Duration                                   //发布的超时时间, 默认5秒
+}
+
+// Bridge zinx服务与消息总线之间的桥接
+type Bridge struct {
+	server ziface.IServer
+	config BridgeConfig
+
+	lock sync.Mutex
+	subs []uint64
+}
+
+// NewBridge 在server上创建桥接, 消息总线由调用者创建与关闭
+func NewBridge(server ziface.IServer, config BridgeConfig) *Bridge {
+	if config.NodeID == "" {
+		hostname, _ := os.Hostname()
+		config.NodeID = hostname + "-" + strconv.Itoa(os.Getpid())
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = DefaultPublishTimeout
+	}
+	return &Bridge{server: server, config: config}
+}
+
+// NodeID 本节点ID
+func (b *Bridge) NodeID() string {
+	return b.config.NodeID
+}
+
+// Forward 客户端发来的msgIDs消息发布到总线的subject主题, 由总线上的其他服务处理, 需在Start之前调用
+// 会为msgIDs添加路由, 需要在本地同时处理的消息在路由中调用PublishRequest
+func (b *Bridge) Forward(subject string, msgIDs ...uint32) {
+	for _, msgID := range msgIDs {
+		b.server.AddRouter(msgID, &forwardRouter{bridge: b, subject: subject})
+	}
+}
+
+// PublishRequest 将请求发布到总线的subject主题
+func (b *Bridge) PublishRequest(subject string, request ziface.IRequest) error {
+	msg := BusMessage{
+		MsgID:  request.GetMsgID(),
+		Data:   request.GetData(),
+		Node:   b.config.NodeID,
+		ConnID: request.GetConnection().GetConnID(),
+	}
+	if b.config.Meta != nil {
+		msg.Meta = b.config.Meta(request)
+	}
+	return b.Publish(subject, msg)
+}
+
+// Publish 将消息发布到总线的subject主题
+func (b *Bridge) Publish(subject string, msg BusMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.PublishTimeout)
+	defer cancel()
+	return b.config.Bus.Publish(ctx, subject, data)
+}
+
+// Subscribe 订阅总线的subject主题, 收到的消息注入到本节点的连接、分组或发布订阅主题
+// 每个节点都订阅同一个主题时, 分组与广播消息可以到达集群中全部节点上的连接
+func (b *Bridge) Subscribe(subject string) error {
+	subID, err := b.config.Bus.Subscribe(subject, b.inject)
+	if err != nil {
+		return err
+	}
+	b.lock.Lock()
+	b.subs = append(b.subs, subID)
+	b.lock.Unlock()
+	return nil
+}
+
+// Close 取消桥接的全部订阅
+func (b *Bridge) Close() {
+	b.lock.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.lock.Unlock()
+	for _, subID := range subs {
+		if err := b.config.Bus.Unsubscribe(subID); err != nil {
+			zlog.Ins().ErrorF("bridge unsubscribe err: %v", err)
+		}
+	}
+}
+
+// inject 将总线上的消息注入到本节点
+func (b *Bridge) inject(subject string, data []byte) {
+	var msg BusMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		zlog.Ins().ErrorF("bridge decode message from %s err: %v", subject, err)
+		return
+	}
+
+	var err error
+	switch {
+	case msg.Group != "":
+		if group, ok := b.server.GetGroupMgr().Get(msg.Group); ok {
+			err = group.Broadcast(msg.MsgID, msg.Data)
+		}
+	case msg.Topic != "":
+		_, err = b.server.GetPubSub().Publish(msg.Topic, msg.MsgID, msg.Data)
+	case msg.Node != "":
+		if msg.Node != b.config.NodeID {
+			return
+		}
+		var conn ziface.IConnection
+		if conn, err = b.server.GetConnMgr().Get(msg.ConnID); err == nil {
+			err = conn.SendBuffMsg(msg.MsgID, msg.Data)
+		}
+	default:
+		err = b.server.GetConnMgr().Broadcast(msg.MsgID, msg.Data)
+	}
+	if err != nil {
+		zlog.Ins().ErrorF("bridge inject message from %s msgID = %d err: %v", subject, msg.MsgID, err)
+	}
+}
+
+// forwardRouter 将消息发布到总线的路由
+type forwardRouter struct {
+	znet.BaseRouter
+	bridge  *Bridge
+	subject string
+}
+
+func (r *forwardRouter) Handle(request ziface.IRequest) {
+	if err := r.bridge.PublishRequest(r.subject, request); err != nil {
+		zlog.Ins().ErrorF("bridge publish msgID = %d to %s err: %v", request.GetMsgID(), r.subject, err)
+		request.SetError(err)
+	}
+}
@@ -0,0 +1,139 @@
+package zbridge
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBus 进程内的消息总线
+type memoryBus struct {
+	lock   sync.Mutex
+	subs   map[uint64]memorySub
+	nextID uint64
+}
+
+type memorySub struct {
+	subject string
+	handler ziface.BusHandler
+}
+
+func (b *memoryBus) Publish(ctx context.Context, subject string, data []byte) error {
+	b.lock.Lock()
+	var handlers []ziface.BusHandler
+	for _, sub := range b.subs {
+		if sub.subject == subject {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.lock.Unlock()
+	for _, handler := range handlers {
+		handler(subject, data)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(subject string, handler ziface.BusHandler) (uint64, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.nextID++
+	b.subs[b.nextID] = memorySub{subject: subject, handler: handler}
+	return b.nextID, nil
+}
+
+func (b *memoryBus) Unsubscribe(subID uint64) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subs, subID)
+	return nil
+}
+
+func (b *memoryBus) Close() error {
+	return nil
+}
+
+type clientRouter struct {
+	znet.BaseRouter
+	received chan string
+}
+
+func (r *clientRouter) Handle(request ziface.IRequest) {
+	r.received <- string(request.GetData())
+}
+
+func TestBridge(t *testing.T) {
+	bus := &memoryBus{subs: map[uint64]memorySub{}}
+
+	s := znet.NewServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = 28965
+	bridge := NewBridge(s, BridgeConfig{
+		Bus:    bus,
+		NodeID: "n1",
+		Meta: func(request ziface.IRequest) map[string]string {
+			return map[string]string{"uid": "u1"}
+		},
+	})
+	bridge.Forward("zinx.chat", 5)
+	assert.Nil(t, bridge.Subscribe("zinx.push"))
+	s.Start()
+	defer s.Stop()
+	defer bridge.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	// 其他服务收到客户端发来的消息
+	published := make(chan BusMessage, 1)
+	_, _ = bus.Subscribe("zinx.chat", func(subject string, data []byte) {
+		var msg BusMessage
+		assert.Nil(t, json.Unmarshal(data, &msg))
+		published <- msg
+	})
+
+	router := &clientRouter{received: make(chan string, 10)}
+	client := znet.NewClient("127.0.0.1", 28965)
+	client.AddRouter(1, router)
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Nil(t, client.Conn().SendMsg(5, []byte("hi")))
+	var msg BusMessage
+	select {
+	case msg = <-published:
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait publish timeout")
+	}
+	assert.Equal(t, uint32(5), msg.MsgID)
+	assert.Equal(t, []byte("hi"), msg.Data)
+	assert.Equal(t, "n1", msg.Node)
+	assert.Equal(t, "u1", msg.Meta["uid"])
+
+	// 总线上的消息注入到连接、分组与全部连接
+	connID := msg.ConnID
+	conn, err := s.GetConnMgr().Get(connID)
+	assert.Nil(t, err)
+	s.GetGroupMgr().Join("room1", conn)
+
+	push := func(msg BusMessage) {
+		assert.Nil(t, bridge.Publish("zinx.push", msg))
+	}
+	push(BusMessage{MsgID: 1, Data: []byte("other node"), Node: "n2", ConnID: connID})
+	push(BusMessage{MsgID: 1, Data: []byte("to conn"), Node: "n1", ConnID: connID})
+	assert.Equal(t, "to conn", recvString(t, router.received))
+	push(BusMessage{MsgID: 1, Data: []byte("to room"), Group: "room1"})
+	assert.Equal(t, "to room", recvString(t, router.received))
+	push(BusMessage{MsgID: 1, Data: []byte("to all")})
+	assert.Equal(t, "to all", recvString(t, router.received))
+
+	// 取消订阅后不再注入
+	bridge.Close()
+	push(BusMessage{MsgID: 1, Data: []byte("closed")})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(router.received))
+}
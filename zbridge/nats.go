@@ -0,0 +1,320 @@
+package zbridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	// DefaultNATSTimeout 连接NATS与写入的默认超时时间
+	DefaultNATSTimeout = 5 * time.Second
+	// DefaultNATSReconnectDelay 与NATS断开后重连的默认间隔
+	DefaultNATSReconnectDelay = time.Second
+)
+
+var (
+	ErrBusClosed       = errors.New("message bus closed")
+	ErrBusDisconnected = errors.New("message bus disconnected")
+)
+
+// NATSConfig NATS连接配置
+type NATSConfig struct {
+	Addr           string        //NATS地址, 如"127.0.0.1:4222"
+	User           string        //用户名
+	Password       string        //密码
+	Token          string        //认证令牌
+	Name           string        //连接名称, 便于在NATS监控中区分节点
+	Timeout        time.Duration //连接与写入超时时间, 默认5秒
+	ReconnectDelay time.Duration //断开后重连的间隔, 默认1秒
+}
+
+// natsSub 一个订阅, 重连后重新订阅
+type natsSub struct {
+	subject string
+	handler ziface.BusHandler
+}
+
+// NATSBus NATS消息总线, 实现NATS核心协议的发布与订阅, 不依赖NATS的客户端库
+// 断开后按间隔自动重连并恢复订阅, 断开期间发布返回ErrBusDisconnected
+type NATSBus struct {
+	config NATSConfig
+
+	lock   sync.Mutex
+	conn   net.Conn
+	subs   map[uint64]*natsSub
+	nextID uint64
+	closed bool
+
+	writeLock sync.Mutex
+}
+
+// NewNATSBus 连接NATS, 认证失败或无法连接时返回错误
+func NewNATSBus(config NATSConfig) (*NATSBus, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultNATSTimeout
+	}
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = DefaultNATSReconnectDelay
+	}
+	b := &NATSBus{config: config, subs: make(map[uint64]*natsSub)}
+	conn, r, err := b.connect()
+	if err != nil {
+		return nil, err
+	}
+	go b.readLoop(conn, r)
+	return b, nil
+}
+
+// connect 建立连接, 发送CONNECT与现有的订阅, 以PING/PONG确认
+func (b *NATSBus) connect() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", b.config.Addr, b.config.Timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(b.config.Timeout))
+	r := bufio.NewReader(conn)
+
+	// 服务端首先发送INFO
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, nil, errors.New("nats: unexpected greeting " + strings.TrimSpace(line))
+	}
+
+	options, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"lang":       "go",
+		"version":    "zinx",
+		"name":       b.config.Name,
+		"user":       b.config.User,
+		"pass":       b.config.Password,
+		"auth_token": b.config.Token,
+	})
+	buf := "CONNECT " + string(options) + "\r\n"
+	sent := make(map[uint64]struct{})
+	b.lock.Lock()
+	for sid, sub := range b.subs {
+		buf += "SUB " + sub.subject + " " + strconv.FormatUint(sid, 10) + "\r\n"
+		sent[sid] = struct{}{}
+	}
+	b.lock.Unlock()
+	buf += "PING\r\n"
+	if _, err := conn.Write([]byte(buf)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, nil, errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		conn.Close()
+		return nil, nil, ErrBusClosed
+	}
+	b.conn = conn
+	// 连接期间新增的订阅
+	var missed string
+	for sid, sub := range b.subs {
+		if _, ok := sent[sid]; !ok {
+			missed += "SUB " + sub.subject + " " + strconv.FormatUint(sid, 10) + "\r\n"
+		}
+	}
+	b.lock.Unlock()
+
+	if missed != "" {
+		if err := b.write(conn, missed); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	return conn, r, nil
+}
+
+// readLoop 读取服务端的消息, 连接断开后重连
+func (b *NATSBus) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		err := b.read(conn, r)
+
+		b.lock.Lock()
+		b.conn = nil
+		closed := b.closed
+		b.lock.Unlock()
+		conn.Close()
+		if closed {
+			return
+		}
+		zlog.Ins().ErrorF("nats %s disconnected: %v", b.config.Addr, err)
+
+		for {
+			time.Sleep(b.config.ReconnectDelay)
+			b.lock.Lock()
+			closed := b.closed
+			b.lock.Unlock()
+			if closed {
+				return
+			}
+			if conn, r, err = b.connect(); err == nil {
+				zlog.Ins().InfoF("nats %s reconnected", b.config.Addr)
+				break
+			}
+			if err == ErrBusClosed {
+				return
+			}
+			zlog.Ins().ErrorF("nats %s reconnect err: %v", b.config.Addr, err)
+		}
+	}
+}
+
+func (b *NATSBus) read(conn net.Conn, r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				return errors.New("nats: invalid MSG " + line)
+			}
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return err
+			}
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return err
+			}
+			sid, _ := strconv.ParseUint(fields[2], 10, 64)
+			b.lock.Lock()
+			sub, ok := b.subs[sid]
+			b.lock.Unlock()
+			if ok {
+				sub.handler(fields[1], payload[:n])
+			}
+		case line == "PING":
+			if err := b.write(conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			zlog.Ins().ErrorF("nats %s %s", b.config.Addr, line)
+		}
+	}
+}
+
+func (b *NATSBus) write(conn net.Conn, data string) error {
+	b.writeLock.Lock()
+	defer b.writeLock.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(b.config.Timeout))
+	_, err := conn.Write([]byte(data))
+	return err
+}
+
+// current 当前的连接
+func (b *NATSBus) current() (net.Conn, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	if b.conn == nil {
+		return nil, ErrBusDisconnected
+	}
+	return b.conn, nil
+}
+
+func (b *NATSBus) Publish(ctx context.Context, subject string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	conn, err := b.current()
+	if err != nil {
+		return err
+	}
+	return b.write(conn, "PUB "+subject+" "+strconv.Itoa(len(data))+"\r\n"+string(data)+"\r\n")
+}
+
+// Subscribe 订阅主题, 主题支持NATS的通配符('*'匹配一级, '>'匹配剩余层级)
+// 处理方法在读取消息的Goroutine中同步调用, 不应阻塞
+func (b *NATSBus) Subscribe(subject string, handler ziface.BusHandler) (uint64, error) {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return 0, ErrBusClosed
+	}
+	b.nextID++
+	sid := b.nextID
+	b.subs[sid] = &natsSub{subject: subject, handler: handler}
+	conn := b.conn
+	b.lock.Unlock()
+
+	// 断开期间的订阅在重连后发送
+	if conn == nil {
+		return sid, nil
+	}
+	return sid, b.write(conn, "SUB "+subject+" "+strconv.FormatUint(sid, 10)+"\r\n")
+}
+
+func (b *NATSBus) Unsubscribe(subID uint64) error {
+	b.lock.Lock()
+	if _, ok := b.subs[subID]; !ok {
+		b.lock.Unlock()
+		return nil
+	}
+	delete(b.subs, subID)
+	conn := b.conn
+	b.lock.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return b.write(conn, "UNSUB "+strconv.FormatUint(subID, 10)+"\r\n")
+}
+
+func (b *NATSBus) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if b.conn != nil {
+		return b.conn.Close()
+	}
+	return nil
+}
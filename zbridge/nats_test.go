@@ -0,0 +1,169 @@
+package zbridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNATS 内存中的NATS服务, 只支持精确匹配的主题
+type fakeNATS struct {
+	listener net.Listener
+	token    string
+	lock     sync.Mutex
+	conns    map[net.Conn]map[string]string //连接 -> sid -> 主题
+}
+
+func newFakeNATS(t *testing.T, token string) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeNATS{listener: listener, token: token, conns: map[net.Conn]map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeNATS) addr() string {
+	return f.listener.Addr().String()
+}
+
+// kick 断开全部客户端连接
+func (f *fakeNATS) kick() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func (f *fakeNATS) serve(conn net.Conn) {
+	defer func() {
+		f.lock.Lock()
+		delete(f.conns, conn)
+		f.lock.Unlock()
+		conn.Close()
+	}()
+	_, _ = conn.Write([]byte("INFO {\"server_id\":\"fake\"}\r\n"))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			var options map[string]interface{}
+			_ = json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &options)
+			if options["auth_token"] != f.token {
+				_, _ = conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+				return
+			}
+			f.lock.Lock()
+			f.conns[conn] = map[string]string{}
+			f.lock.Unlock()
+		case "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+		case "SUB":
+			f.lock.Lock()
+			f.conns[conn][fields[2]] = fields[1]
+			f.lock.Unlock()
+		case "UNSUB":
+			f.lock.Lock()
+			delete(f.conns[conn], fields[1])
+			f.lock.Unlock()
+		case "PUB":
+			n, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			f.lock.Lock()
+			for c, subs := range f.conns {
+				for sid, subject := range subs {
+					if subject == fields[1] {
+						_, _ = c.Write([]byte("MSG " + subject + " " + sid + " " + strconv.Itoa(n) + "\r\n" + string(payload)))
+					}
+				}
+			}
+			f.lock.Unlock()
+		}
+	}
+}
+
+func TestNATSBus(t *testing.T) {
+	server := newFakeNATS(t, "secret")
+	defer server.listener.Close()
+
+	_, err := NewNATSBus(NATSConfig{Addr: server.addr(), Token: "wrong"})
+	assert.NotNil(t, err)
+
+	bus, err := NewNATSBus(NATSConfig{Addr: server.addr(), Token: "secret", ReconnectDelay: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer bus.Close()
+
+	received := make(chan string, 10)
+	subID, err := bus.Subscribe("events", func(subject string, data []byte) {
+		received <- subject + ":" + string(data)
+	})
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	assert.Nil(t, bus.Publish(ctx, "events", []byte("hello\r\nworld")))
+	assert.Equal(t, "events:hello\r\nworld", recvString(t, received))
+
+	// 断开后自动重连并恢复订阅
+	server.kick()
+	// 断开之前的发布可能写入旧的连接而丢失, 重试直到收到
+	var got string
+	for deadline := time.Now().Add(3 * time.Second); got == "" && time.Now().Before(deadline); {
+		_ = bus.Publish(ctx, "events", []byte("again"))
+		select {
+		case got = <-received:
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, "events:again", got)
+	time.Sleep(50 * time.Millisecond)
+	for len(received) > 0 {
+		<-received
+	}
+
+	assert.Nil(t, bus.Unsubscribe(subID))
+	assert.Nil(t, bus.Publish(ctx, "events", []byte("ignored")))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, len(received))
+
+	assert.Nil(t, bus.Close())
+	assert.Equal(t, ErrBusClosed, bus.Publish(ctx, "events", nil))
+}
+
+func recvString(t *testing.T, ch chan string) string {
+	t.Helper()
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait message timeout")
+	}
+	return ""
+}
@@ -0,0 +1,276 @@
+// Package zclient 提供基于znet.Client的客户端工具, 如zinx节点之间通信使用的连接池
+package zclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+// ErrNoHealthyConn 连接池中没有可用的连接
+var ErrNoHealthyConn = errors.New("no healthy connection in pool")
+
+// Selector 从连接池中选择连接的策略
+type Selector int
+
+const (
+	SelectRoundRobin   Selector = iota //轮询
+	SelectLeastPending                 //选择正在发送与等待回复的请求最少的连接
+)
+
+const (
+	// DefaultPoolSize 连接池默认的连接数量
+	DefaultPoolSize = 4
+	// DefaultHealthInterval 默认的健康检查间隔
+	DefaultHealthInterval = 5 * time.Second
+	// DefaultMaxRetries 幂等的消息发送失败后默认的重试次数
+	DefaultMaxRetries = 2
+)
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	Size           int                                 //连接数量, 默认4
+	Selector       Selector                            //选择连接的策略, 默认轮询
+	ClientOptions  []znet.ClientOption                 //创建每个客户端使用的Option, 如封包方式
+	Reconnect      ziface.ReconnectConfig              //每个连接的自动重连配置
+	HealthInterval time.Duration                       //健康检查间隔, 默认5秒
+	HealthCheck    func(conn ziface.IConnection) error //健康检查, 如发送Call等待回复, 为nil时只检查连接是否存活
+	MaxRetries     int                                 //幂等的消息发送失败后在其他连接上重试的次数, 默认2, 小于0不重试
+}
+
+// poolMember 连接池中的一个客户端
+type poolMember struct {
+	client ziface.IClient
+	// 正在发送与等待回复的请求数量
+	pending int64
+	// 健康检查失败的连接, 重连得到新的连接之后恢复可用
+	failed     ziface.IConnection
+	failedLock sync.Mutex
+}
+
+// healthyConn 客户端当前可用的连接, 不可用时返回nil
+func (m *poolMember) healthyConn() ziface.IConnection {
+	conn := m.client.Conn()
+	if conn == nil || conn.Context().Err() != nil {
+		return nil
+	}
+	m.failedLock.Lock()
+	defer m.failedLock.Unlock()
+	if m.failed == conn {
+		return nil
+	}
+	return conn
+}
+
+// Pool 到一个zinx服务的客户端连接池, 用于zinx节点之间的通信
+// 连接断开后自动重连, 健康检查失败的连接被断开重连, 幂等的消息发送失败时在其他连接上透明地重试
+type Pool struct {
+	ip     string
+	port   int
+	config PoolConfig
+
+	members    []*poolMember
+	next       uint64
+	idempotent sync.Map
+
+	exitChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPool 创建到ip:port的客户端连接池, 需调用Start建立连接
+func NewPool(ip string, port int, config PoolConfig) *Pool {
+	if config.Size <= 0 {
+		config.Size = DefaultPoolSize
+	}
+	if config.HealthInterval <= 0 {
+		config.HealthInterval = DefaultHealthInterval
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+
+	p := &Pool{
+		ip:       ip,
+		port:     port,
+		config:   config,
+		members:  make([]*poolMember, config.Size),
+		exitChan: make(chan struct{}),
+	}
+	for i := range p.members {
+		opts := append([]znet.ClientOption{znet.WithReconnectClient(config.Reconnect)}, config.ClientOptions...)
+		p.members[i] = &poolMember{client: znet.NewClient(ip, port, opts...)}
+	}
+	return p
+}
+
+// Clients 连接池中的客户端, 可以在Start之前添加路由、拦截器等
+func (p *Pool) Clients() []ziface.IClient {
+	clients := make([]ziface.IClient, len(p.members))
+	for i, m := range p.members {
+		clients[i] = m.client
+	}
+	return clients
+}
+
+// SetIdempotent 设置幂等的消息ID, 这些消息发送失败时在其他连接上重试
+func (p *Pool) SetIdempotent(msgIDs ...uint32) {
+	for _, msgID := range msgIDs {
+		p.idempotent.Store(msgID, struct{}{})
+	}
+}
+
+func (p *Pool) isIdempotent(msgID uint32) bool {
+	_, ok := p.idempotent.Load(msgID)
+	return ok
+}
+
+// Start 建立全部连接并开始健康检查
+func (p *Pool) Start() {
+	for _, m := range p.members {
+		m.client.Start()
+	}
+	go p.healthLoop()
+}
+
+// Stop 停止健康检查并关闭全部连接
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.exitChan)
+		for _, m := range p.members {
+			m.client.Stop()
+		}
+	})
+}
+
+func (p *Pool) healthLoop() {
+	ticker := time.NewTicker(p.config.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.exitChan:
+			return
+		}
+	}
+}
+
+// checkHealth 检查每个连接, 检查失败的连接不再被选择, 并断开以便重连
+func (p *Pool) checkHealth() {
+	if p.config.HealthCheck == nil {
+		return
+	}
+	for _, m := range p.members {
+		conn := m.healthyConn()
+		if conn == nil {
+			continue
+		}
+		if err := p.config.HealthCheck(conn); err != nil {
+			zlog.Ins().ErrorF("pool conn to %s health check err: %v", conn.RemoteAddr(), err)
+			m.failedLock.Lock()
+			m.failed = conn
+			m.failedLock.Unlock()
+			conn.Stop()
+		}
+	}
+}
+
+// Healthy 可用的连接数量
+func (p *Pool) Healthy() int {
+	var n int
+	for _, m := range p.members {
+		if m.healthyConn() != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// pick 按策略选择一个可用的连接, 跳过exclude中的客户端
+func (p *Pool) pick(exclude map[*poolMember]struct{}) (*poolMember, ziface.IConnection) {
+	n := len(p.members)
+	start := int(atomic.AddUint64(&p.next, 1) % uint64(n))
+
+	var best *poolMember
+	var bestConn ziface.IConnection
+	for i := 0; i < n; i++ {
+		m := p.members[(start+i)%n]
+		if _, ok := exclude[m]; ok {
+			continue
+		}
+		conn := m.healthyConn()
+		if conn == nil {
+			continue
+		}
+		if p.config.Selector != SelectLeastPending {
+			return m, conn
+		}
+		if best == nil || atomic.LoadInt64(&m.pending) < atomic.LoadInt64(&best.pending) {
+			best, bestConn = m, conn
+		}
+	}
+	return best, bestConn
+}
+
+// Get 按策略选择一个可用的连接
+func (p *Pool) Get() (ziface.IConnection, error) {
+	_, conn := p.pick(nil)
+	if conn == nil {
+		return nil, ErrNoHealthyConn
+	}
+	return conn, nil
+}
+
+// do 在选择的连接上执行发送, 幂等的消息失败时在其他连接上重试
+func (p *Pool) do(msgID uint32, send func(conn ziface.IConnection) error) error {
+	retries := 0
+	if p.isIdempotent(msgID) && p.config.MaxRetries > 0 {
+		retries = p.config.MaxRetries
+	}
+
+	var exclude map[*poolMember]struct{}
+	err := ErrNoHealthyConn
+	for attempt := 0; attempt <= retries; attempt++ {
+		m, conn := p.pick(exclude)
+		if conn == nil {
+			break
+		}
+		atomic.AddInt64(&m.pending, 1)
+		err = send(conn)
+		atomic.AddInt64(&m.pending, -1)
+		if err == nil || err == context.Canceled || err == context.DeadlineExceeded {
+			return err
+		}
+
+		if exclude == nil {
+			exclude = make(map[*poolMember]struct{})
+		}
+		exclude[m] = struct{}{}
+	}
+	return err
+}
+
+// SendMsg 选择一个连接发送消息
+func (p *Pool) SendMsg(msgID uint32, data []byte) error {
+	return p.do(msgID, func(conn ziface.IConnection) error {
+		return conn.SendMsg(msgID, data)
+	})
+}
+
+// Call 选择一个连接发送请求并同步等待回复, 需使用支持关联序号的封包方式与解码器
+// 等待回复超时或被取消时不重试
+func (p *Pool) Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) {
+	var reply []byte
+	err := p.do(msgID, func(conn ziface.IConnection) error {
+		var err error
+		reply, err = conn.Call(ctx, msgID, data)
+		return err
+	})
+	return reply, err
+}
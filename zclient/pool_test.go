@@ -0,0 +1,88 @@
+package zclient
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+type countRouter struct {
+	znet.BaseRouter
+	count int32
+}
+
+func (r *countRouter) Handle(request ziface.IRequest) {
+	atomic.AddInt32(&r.count, 1)
+}
+
+func waitHealthy(p *Pool, n int) bool {
+	for i := 0; i < 100; i++ {
+		if p.Healthy() == n {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPool(t *testing.T) {
+	router := &countRouter{}
+	s := znet.NewServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = 28984
+	s.AddRouter(1, router)
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	p := NewPool("127.0.0.1", 28984, PoolConfig{
+		Size:      3,
+		Selector:  SelectLeastPending,
+		Reconnect: ziface.ReconnectConfig{MinDelay: 10 * time.Millisecond},
+	})
+	p.Start()
+	defer p.Stop()
+	assert.True(t, waitHealthy(p, 3))
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, p.SendMsg(1, []byte("ping")))
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&router.count))
+
+	// 服务端断开全部连接后自动重连
+	for _, conn := range s.GetConnMgr().GetAll() {
+		conn.Stop()
+	}
+	assert.True(t, waitHealthy(p, 3))
+
+	var tried []ziface.IConnection
+	send := func(conn ziface.IConnection) error {
+		tried = append(tried, conn)
+		return errors.New("write failed")
+	}
+	// 非幂等的消息不重试
+	assert.NotNil(t, p.do(1, send))
+	assert.Len(t, tried, 1)
+
+	// 幂等的消息在其他连接上重试
+	tried = nil
+	p.SetIdempotent(2)
+	assert.NotNil(t, p.do(2, send))
+	assert.Len(t, tried, 3)
+	assert.NotEqual(t, tried[0], tried[1])
+	assert.NotEqual(t, tried[1], tried[2])
+	assert.NotEqual(t, tried[0], tried[2])
+}
+
+func TestPoolNoHealthyConn(t *testing.T) {
+	p := NewPool("127.0.0.1", 28984, PoolConfig{Size: 2})
+	_, err := p.Get()
+	assert.Equal(t, ErrNoHealthyConn, err)
+	assert.Equal(t, ErrNoHealthyConn, p.SendMsg(1, nil))
+}
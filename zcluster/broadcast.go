@@ -0,0 +1,94 @@
+package zcluster
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+var ErrInvalidBroadcast = errors.New("invalid cluster broadcast message")
+
+// encodeBroadcast 广播消息的数据: 消息ID(4字节) + 分组名称长度(2字节) + 分组名称 + 消息数据, 小端序
+// 分组名称为空时向网关上的全部连接广播
+func encodeBroadcast(group string, msgID uint32, data []byte) []byte {
+	buf := make([]byte, 6+len(group)+len(data))
+	binary.LittleEndian.PutUint32(buf, msgID)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(group)))
+	copy(buf[6:], group)
+	copy(buf[6+len(group):], data)
+	return buf
+}
+
+func decodeBroadcast(buf []byte) (group string, msgID uint32, data []byte, err error) {
+	if len(buf) < 6 {
+		return "", 0, nil, ErrInvalidBroadcast
+	}
+	msgID = binary.LittleEndian.Uint32(buf)
+	n := int(binary.LittleEndian.Uint16(buf[4:]))
+	if len(buf) < 6+n {
+		return "", 0, nil, ErrInvalidBroadcast
+	}
+	return string(buf[6 : 6+n]), msgID, buf[6+n:], nil
+}
+
+// Broadcast 向集群中全部网关上的全部客户端连接广播消息, 如全服公告
+// 通过本逻辑服务到每个网关的一个连接发送, 每个网关只收到一次
+func (c *Cluster) Broadcast(msgID uint32, data []byte) error {
+	return c.broadcast("", msgID, data)
+}
+
+// BroadcastGroup 向集群中全部网关上加入分组的客户端连接广播消息
+// 客户端连接通过JoinGroup加入所在网关的分组, 分组可以跨越多个网关
+func (c *Cluster) BroadcastGroup(group string, msgID uint32, data []byte) error {
+	if group == "" {
+		return ErrInvalidBroadcast
+	}
+	return c.broadcast(group, msgID, data)
+}
+
+func (c *Cluster) broadcast(group string, msgID uint32, data []byte) error {
+	if len(group) > 0xFFFF {
+		return ErrInvalidBroadcast
+	}
+	buf := encodeBroadcast(group, msgID, data)
+
+	var lastErr error
+	for nodeID, conn := range c.nodeConns() {
+		if err := conn.SendMsg(BroadcastMsgID, buf); err != nil {
+			zlog.Ins().ErrorF("cluster broadcast to node %s err: %v", nodeID, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// JoinGroup 将请求所属的客户端连接加入其所在网关上的分组, 之后可以通过BroadcastGroup广播
+// 客户端连接断开时由网关自动离开分组
+func (c *Cluster) JoinGroup(request ziface.IRequest, group string) error {
+	return SendToSession(request.GetConnection(), request.GetSessionID(), GroupJoinMsgID, []byte(group))
+}
+
+// LeaveGroup 将请求所属的客户端连接离开其所在网关上的分组
+func (c *Cluster) LeaveGroup(request ziface.IRequest, group string) error {
+	return SendToSession(request.GetConnection(), request.GetSessionID(), GroupLeaveMsgID, []byte(group))
+}
+
+// broadcast 执行逻辑服务要求的广播, 分组不存在时忽略
+func (g *Gateway) broadcast(buf []byte) {
+	group, msgID, data, err := decodeBroadcast(buf)
+	if err != nil {
+		zlog.Ins().ErrorF("gateway broadcast err: %v", err)
+		return
+	}
+
+	if group == "" {
+		err = g.server.GetConnMgr().Broadcast(msgID, data)
+	} else if grp, ok := g.server.GetGroupMgr().Get(group); ok {
+		err = grp.Broadcast(msgID, data)
+	}
+	if err != nil {
+		zlog.Ins().ErrorF("gateway broadcast group = %q msgID = %d err: %v", group, msgID, err)
+	}
+}
@@ -0,0 +1,71 @@
+package zcluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastCodec(t *testing.T) {
+	group, msgID, data, err := decodeBroadcast(encodeBroadcast("room1", 7, []byte("hi")))
+	assert.Nil(t, err)
+	assert.Equal(t, "room1", group)
+	assert.Equal(t, uint32(7), msgID)
+	assert.Equal(t, []byte("hi"), data)
+
+	_, _, _, err = decodeBroadcast([]byte{1, 0, 0, 0, 9, 0})
+	assert.Equal(t, ErrInvalidBroadcast, err)
+}
+
+func TestClusterBroadcast(t *testing.T) {
+	logicA, clusterA := startClusterNode(t, NewMemoryRouteTable(), 28969)
+	defer logicA.Stop()
+	logicB, clusterB := startClusterNode(t, NewMemoryRouteTable(), 28968)
+	defer logicB.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	registry := &staticRegistry{instances: []ziface.ServiceInstance{
+		{ID: "a", Name: "logic", Addr: "127.0.0.1:28969"},
+		{ID: "b", Name: "logic", Addr: "127.0.0.1:28968"},
+	}}
+	gate1, gateway1 := startGatewayNode("g1", 28967, registry)
+	defer gate1.Stop()
+	defer gateway1.Stop()
+	gate2, gateway2 := startGatewayNode("g2", 28966, registry)
+	defer gate2.Stop()
+	defer gateway2.Stop()
+	time.Sleep(300 * time.Millisecond)
+
+	client1, replies1 := dialGateway(t, 28967, "u1")
+	defer client1.Stop()
+	client2, replies2 := dialGateway(t, 28966, "u2")
+	defer client2.Stop()
+	client3, replies3 := dialGateway(t, 28966, "u3")
+	defer client3.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	// 不同网关上的客户端加入同一个分组
+	assert.Nil(t, client1.Conn().SendMsg(4, []byte("room1")))
+	assert.Equal(t, "joined", recv(t, replies1))
+	assert.Nil(t, client2.Conn().SendMsg(4, []byte("room1")))
+	assert.Equal(t, "joined", recv(t, replies2))
+
+	// 任意逻辑服务的广播到达全部网关上的连接, 每个连接只收到一次
+	assert.Nil(t, clusterB.Broadcast(1, []byte("notice")))
+	for _, replies := range []chan string{replies1, replies2, replies3} {
+		assert.Equal(t, "notice", recv(t, replies))
+	}
+
+	assert.Nil(t, clusterA.BroadcastGroup("room1", 1, []byte("room msg")))
+	assert.Equal(t, "room msg", recv(t, replies1))
+	assert.Equal(t, "room msg", recv(t, replies2))
+	assert.Nil(t, clusterA.BroadcastGroup("room2", 1, []byte("nobody")))
+	assert.Equal(t, ErrInvalidBroadcast, clusterA.BroadcastGroup("", 1, nil))
+
+	time.Sleep(100 * time.Millisecond)
+	for _, replies := range []chan string{replies1, replies2, replies3} {
+		assert.Equal(t, 0, len(replies))
+	}
+}
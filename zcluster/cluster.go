@@ -0,0 +1,161 @@
+package zcluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+var (
+	ErrUserOffline      = errors.New("user is offline")
+	ErrNodeNotConnected = errors.New("gateway node is not connected")
+)
+
+// ClusterConfig 逻辑服务的集群配置
+type ClusterConfig struct {
+	Routes   ziface.IRouteTable //集群共享的用户路由表
+	RouteTTL time.Duration      //用户路由的过期时间, 为0时不过期, 客户端断开时解除
+}
+
+// Cluster 逻辑服务上的集群视图, 通过共享的用户路由表向连接在任意网关上的用户发送消息
+// 需要在NewLogicServer创建的逻辑服务上使用, 会添加SessionClosedMsgID的路由
+type Cluster struct {
+	server ziface.IServer
+	config ClusterConfig
+
+	lock sync.Mutex
+	// 本节点绑定的用户路由, 会话关闭时解除
+	bound map[ziface.UserRoute]struct{}
+
+	onSessionClosed func(request ziface.IRequest)
+}
+
+// NewCluster 在逻辑服务上创建集群视图
+func NewCluster(server ziface.IServer, config ClusterConfig) *Cluster {
+	c := &Cluster{
+		server: server,
+		config: config,
+		bound:  make(map[ziface.UserRoute]struct{}),
+	}
+	server.AddRouter(SessionClosedMsgID, &clusterRouter{handle: c.handleSessionClosed})
+	return c
+}
+
+// SetOnSessionClosed 设置网关上的客户端连接断开时的回调, 在解除该会话的用户路由之后调用
+func (c *Cluster) SetOnSessionClosed(hookFunc func(request ziface.IRequest)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onSessionClosed = hookFunc
+}
+
+// BindUser 将用户绑定到请求所属的客户端连接, 如登录成功之后调用, 之后可以在任意逻辑服务上SendToUser
+func (c *Cluster) BindUser(request ziface.IRequest, userID string) error {
+	nodeID := NodeID(request)
+	if nodeID == "" {
+		return ErrNodeNotConnected
+	}
+	route := ziface.UserRoute{UserID: userID, NodeID: nodeID, SessionID: request.GetSessionID()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), zdiscovery.DefaultTimeout)
+	defer cancel()
+	if err := c.config.Routes.Bind(ctx, route, c.config.RouteTTL); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.bound[route] = struct{}{}
+	c.lock.Unlock()
+	return nil
+}
+
+// SendToUser 向用户发送消息, 用户可以连接在集群中的任意网关上
+func (c *Cluster) SendToUser(userID string, msgID uint32, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), zdiscovery.DefaultTimeout)
+	defer cancel()
+	route, ok, err := c.config.Routes.Lookup(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserOffline
+	}
+	return c.SendToConn(route.NodeID, route.SessionID, msgID, data)
+}
+
+// SendToConn 向网关节点上逻辑会话ID对应的客户端连接发送消息, 使用该网关到本逻辑服务的任意一个连接
+func (c *Cluster) SendToConn(nodeID string, sessionID uint32, msgID uint32, data []byte) error {
+	err := ErrNodeNotConnected
+	for _, conn := range c.server.GetConnMgr().GetAll() {
+		if connNodeID(conn) != nodeID || conn.Context().Err() != nil {
+			continue
+		}
+		if err = SendToSession(conn, sessionID, msgID, data); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Nodes 连接到本逻辑服务的网关节点ID
+func (c *Cluster) Nodes() []string {
+	var nodes []string
+	for nodeID := range c.nodeConns() {
+		nodes = append(nodes, nodeID)
+	}
+	return nodes
+}
+
+// nodeConns 每个网关节点到本逻辑服务的一个可用连接
+func (c *Cluster) nodeConns() map[string]ziface.IConnection {
+	conns := make(map[string]ziface.IConnection)
+	for _, conn := range c.server.GetConnMgr().GetAll() {
+		nodeID := connNodeID(conn)
+		if _, ok := conns[nodeID]; ok || nodeID == "" || conn.Context().Err() != nil {
+			continue
+		}
+		conns[nodeID] = conn
+	}
+	return conns
+}
+
+// handleSessionClosed 解除会话绑定的用户路由
+func (c *Cluster) handleSessionClosed(request ziface.IRequest) {
+	nodeID, sessionID := NodeID(request), request.GetSessionID()
+
+	var routes []ziface.UserRoute
+	c.lock.Lock()
+	for route := range c.bound {
+		if route.NodeID == nodeID && route.SessionID == sessionID {
+			routes = append(routes, route)
+			delete(c.bound, route)
+		}
+	}
+	hookFunc := c.onSessionClosed
+	c.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), zdiscovery.DefaultTimeout)
+	defer cancel()
+	for _, route := range routes {
+		if err := c.config.Routes.Unbind(ctx, route); err != nil {
+			zlog.Ins().ErrorF("cluster unbind user %s err: %v", route.UserID, err)
+		}
+	}
+	if hookFunc != nil {
+		hookFunc(request)
+	}
+}
+
+// clusterRouter 集群内部消息的路由
+type clusterRouter struct {
+	znet.BaseRouter
+	handle func(request ziface.IRequest)
+}
+
+func (r *clusterRouter) Handle(request ziface.IRequest) {
+	r.handle(request)
+}
@@ -0,0 +1,100 @@
+package zcluster
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zclient"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+func startClusterNode(t *testing.T, routes ziface.IRouteTable, port int) (ziface.IServer, *Cluster) {
+	s := NewLogicServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = port
+	cluster := NewCluster(s, ClusterConfig{Routes: routes})
+	// 登录: 绑定用户
+	s.AddRouter(3, &clusterRouter{handle: func(request ziface.IRequest) {
+		assert.Nil(t, cluster.BindUser(request, string(request.GetData())))
+		_ = request.Reply([]byte("ok"))
+	}})
+	// 加入分组, 网关加入分组之后回复
+	s.AddRouter(4, &clusterRouter{handle: func(request ziface.IRequest) {
+		assert.Nil(t, cluster.JoinGroup(request, string(request.GetData())))
+		_ = SendToSession(request.GetConnection(), request.GetSessionID(), 3, []byte("joined"))
+	}})
+	s.Start()
+	return s, cluster
+}
+
+func startGatewayNode(nodeID string, port int, registry ziface.IRegistry) (ziface.IServer, *Gateway) {
+	s := znet.NewServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = port
+	s.AddRouter(2, &clusterRouter{handle: func(request ziface.IRequest) {}})
+	gateway := NewGateway(s, GatewayConfig{
+		NodeID:   nodeID,
+		Registry: registry,
+		Service:  "logic",
+		Pool:     zclient.PoolConfig{Reconnect: ziface.ReconnectConfig{MinDelay: 10 * time.Millisecond}},
+	})
+	s.Start()
+	gateway.Start()
+	return s, gateway
+}
+
+func TestClusterSendToUser(t *testing.T) {
+	routes := NewMemoryRouteTable()
+	logicA, clusterA := startClusterNode(t, routes, 28973)
+	defer logicA.Stop()
+	logicB, clusterB := startClusterNode(t, routes, 28972)
+	defer logicB.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	registry := &staticRegistry{instances: []ziface.ServiceInstance{
+		{ID: "a", Name: "logic", Addr: "127.0.0.1:28973"},
+		{ID: "b", Name: "logic", Addr: "127.0.0.1:28972"},
+	}}
+	gate1, gateway1 := startGatewayNode("g1", 28971, registry)
+	defer gate1.Stop()
+	defer gateway1.Stop()
+	gate2, gateway2 := startGatewayNode("g2", 28970, registry)
+	defer gate2.Stop()
+	defer gateway2.Stop()
+	time.Sleep(300 * time.Millisecond)
+
+	// 每个逻辑服务都连接着全部网关
+	for _, cluster := range []*Cluster{clusterA, clusterB} {
+		nodes := cluster.Nodes()
+		sort.Strings(nodes)
+		assert.Equal(t, []string{"g1", "g2"}, nodes)
+	}
+
+	client1, replies1 := dialGateway(t, 28971, "u1")
+	defer client1.Stop()
+	client2, replies2 := dialGateway(t, 28970, "u2")
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Nil(t, client1.Conn().SendMsg(3, []byte("u1")))
+	assert.Equal(t, "ok", recv(t, replies1))
+	assert.Nil(t, client2.Conn().SendMsg(3, []byte("u2")))
+	assert.Equal(t, "ok", recv(t, replies2))
+
+	// 任意逻辑服务都可以向任意网关上的用户发送消息
+	for _, cluster := range []*Cluster{clusterA, clusterB} {
+		assert.Nil(t, cluster.SendToUser("u1", 1, []byte("to u1")))
+		assert.Equal(t, "to u1", recv(t, replies1))
+		assert.Nil(t, cluster.SendToUser("u2", 1, []byte("to u2")))
+		assert.Equal(t, "to u2", recv(t, replies2))
+	}
+	assert.Equal(t, ErrUserOffline, clusterA.SendToUser("u3", 1, nil))
+
+	// 客户端断开连接后解除用户路由
+	client2.Stop()
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, ErrUserOffline, clusterA.SendToUser("u2", 1, nil))
+	assert.Equal(t, ErrNodeNotConnected, clusterA.SendToConn("g3", 1, 1, nil))
+}
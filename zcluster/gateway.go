@@ -0,0 +1,428 @@
+// Package zcluster 提供集群部署的组件, 如终结客户端连接并将消息转发给后端逻辑服务的网关
+package zcluster
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zclient"
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+)
+
+const (
+	// DefaultRefreshInterval 网关重新发现逻辑服务实例的默认间隔
+	DefaultRefreshInterval = 5 * time.Second
+	// SessionClosedMsgID 客户端连接断开时网关通知逻辑服务的消息ID, 逻辑服务发送该消息时网关断开客户端连接
+	SessionClosedMsgID uint32 = 99996
+)
+
+var (
+	ErrNoBackend     = errors.New("no backend instance available")
+	ErrSessionClosed = errors.New("gateway session closed")
+	ErrNotMuxConn    = errors.New("connection does not support mux sessions")
+)
+
+// GatewayConfig 网关配置
+type GatewayConfig struct {
+	NodeID          string                               //网关节点ID, 集群内唯一, 默认为"主机名-进程ID"
+	Registry        ziface.IRegistry                     //发现逻辑服务实例的注册中心
+	Service         string                               //逻辑服务名称
+	Pool            zclient.PoolConfig                   //到每个逻辑服务实例的连接池配置, 封包方式与解码器固定使用逻辑会话的封包方式与解码器
+	RefreshInterval time.Duration                        //重新发现逻辑服务实例的间隔, 默认5秒
+	StickyKey       func(conn ziface.IConnection) string //会话粘性的键(如用户ID、房间ID), 按一致性哈希将键相同的客户端连接转发给同一个实例, 为nil或返回""时选择会话最少的实例
+	HashReplicas    int                                  //一致性哈希中每个实例的虚拟节点数量, 默认160
+	OnRebalance     func(change *RingChange)             //逻辑服务实例增减导致一致性哈希变化时的回调, 可以据此迁移受影响的用户或房间
+}
+
+// backend 一个逻辑服务实例与到它的连接池
+type backend struct {
+	instance ziface.ServiceInstance
+	pool     *zclient.Pool
+	// 绑定到该实例的会话数量
+	sessions int64
+}
+
+// gatewaySession 网关上的一个客户端连接, 以逻辑会话ID转发给绑定的逻辑服务实例
+type gatewaySession struct {
+	id   uint32
+	conn ziface.IConnection
+
+	lock    sync.Mutex
+	backend *backend
+	// 会话固定使用连接池中的一个连接, 保证转发的消息有序
+	backendConn ziface.IConnection
+	closed      bool
+}
+
+// Gateway 网关, 终结客户端连接, 将没有本地路由的消息转发给通过注册中心发现的逻辑服务实例
+// 每个客户端连接是一个逻辑会话, 绑定到一个逻辑服务实例, 逻辑服务的回复与推送按逻辑会话ID转发回客户端
+// 逻辑服务需使用逻辑会话的封包方式与解码器, 见NewLogicServer
+type Gateway struct {
+	server ziface.IServer
+	config GatewayConfig
+
+	backends map[string]*backend //实例ID -> 逻辑服务实例
+	lock     sync.RWMutex
+	ring     *HashRing //实例ID的一致性哈希
+
+	sessions sync.Map //逻辑会话ID -> *gatewaySession
+	conns    sync.Map //客户端ConnID -> *gatewaySession
+	nextID   uint32
+
+	members  *Membership
+	stopped  bool
+	stopOnce sync.Once
+}
+
+// NewGateway 在server上创建网关, server的默认路由被设置为转发, 本地添加的路由(如登录)仍然由网关处理
+func NewGateway(server ziface.IServer, config GatewayConfig) *Gateway {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	if config.NodeID == "" {
+		hostname, _ := os.Hostname()
+		config.NodeID = hostname + "-" + strconv.Itoa(os.Getpid())
+	}
+
+	g := &Gateway{
+		server:   server,
+		config:   config,
+		backends: make(map[string]*backend),
+		ring:     NewHashRing(config.HashReplicas),
+		members: NewMembership(MembershipConfig{
+			Registry: config.Registry,
+			Service:  config.Service,
+			Interval: config.RefreshInterval,
+		}),
+	}
+	g.ring.SetOnRebalance(config.OnRebalance)
+	g.members.Subscribe(g.onMember)
+	server.SetDefaultRouter(&forwardRouter{gateway: g})
+	server.GetEventBus().Subscribe(func(event ziface.Event) {
+		g.closeSession(event.Conn)
+	}, ziface.EventConnClosed)
+	return g
+}
+
+// Start 发现逻辑服务实例并开始定期刷新
+func (g *Gateway) Start() {
+	g.members.Start()
+}
+
+// Stop 停止刷新并关闭到全部逻辑服务实例的连接
+func (g *Gateway) Stop() {
+	g.stopOnce.Do(func() {
+		g.members.Stop()
+
+		g.lock.Lock()
+		backends := g.backends
+		g.backends = make(map[string]*backend)
+		g.stopped = true
+		g.lock.Unlock()
+		for _, b := range backends {
+			b.pool.Stop()
+		}
+	})
+}
+
+// Members 逻辑服务实例的成员视图, 可以订阅实例的加入/离开事件
+func (g *Gateway) Members() *Membership {
+	return g.members
+}
+
+// Backends 当前的逻辑服务实例
+func (g *Gateway) Backends() []ziface.ServiceInstance {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	instances := make([]ziface.ServiceInstance, 0, len(g.backends))
+	for _, b := range g.backends {
+		instances = append(instances, b.instance)
+	}
+	return instances
+}
+
+// onMember 逻辑服务实例加入时创建连接池, 离开时立即关闭连接池, 不再向该实例转发
+func (g *Gateway) onMember(event MemberEvent) {
+	instance := event.Instance
+	g.lock.Lock()
+	if g.stopped {
+		g.lock.Unlock()
+		return
+	}
+	b, ok := g.backends[instance.ID]
+	switch event.Type {
+	case MemberJoin:
+		if ok {
+			g.lock.Unlock()
+			return
+		}
+		var err error
+		if b, err = g.newBackend(instance); err != nil {
+			g.lock.Unlock()
+			zlog.Ins().ErrorF("gateway backend %s err: %v", instance.Addr, err)
+			return
+		}
+		g.backends[instance.ID] = b
+		g.lock.Unlock()
+
+		zlog.Ins().InfoF("gateway backend %s(%s) added", instance.ID, instance.Addr)
+		g.startBackend(b)
+		g.ring.Add(instance.ID)
+	case MemberLeave:
+		if !ok {
+			g.lock.Unlock()
+			return
+		}
+		delete(g.backends, instance.ID)
+		g.lock.Unlock()
+
+		zlog.Ins().InfoF("gateway backend %s(%s) removed", instance.ID, instance.Addr)
+		g.ring.Remove(instance.ID)
+		b.pool.Stop()
+	case MemberUpdate:
+		if ok {
+			b.instance = instance
+		}
+		g.lock.Unlock()
+	}
+}
+
+func (g *Gateway) newBackend(instance ziface.ServiceInstance) (*backend, error) {
+	host, port, err := net.SplitHostPort(instance.Addr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	config := g.config.Pool
+	config.ClientOptions = append(append([]znet.ClientOption{}, config.ClientOptions...), znet.WithPacketClient(zpack.NewMuxDataPack()))
+	return &backend{instance: instance, pool: zclient.NewPool(host, p, config)}, nil
+}
+
+// NodeID 网关节点ID
+func (g *Gateway) NodeID() string {
+	return g.config.NodeID
+}
+
+// startBackend 建立到逻辑服务实例的连接, 连接建立后发送网关节点ID, 在解码器之后接收属于会话的消息
+func (g *Gateway) startBackend(b *backend) {
+	for _, client := range b.pool.Clients() {
+		client.SetDecoder(zdecoder.NewMuxTLVDecoder())
+		client.SetOnConnStart(func(conn ziface.IConnection) {
+			if err := conn.SendMsg(NodeHelloMsgID, []byte(g.config.NodeID)); err != nil {
+				zlog.Ins().ErrorF("gateway hello to %s err: %v", conn.RemoteAddr(), err)
+			}
+		})
+	}
+	b.pool.Start()
+	for _, client := range b.pool.Clients() {
+		client.AddInterceptor(&backendReceiver{gateway: g})
+	}
+}
+
+// pick 为会话选择逻辑服务实例, 有粘性的键时按一致性哈希选择, 所属实例不可用时顺时针选择下一个实例, 否则选择会话最少的实例
+func (g *Gateway) pick(key string) *backend {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if key != "" {
+		for _, id := range g.ring.GetN(key, len(g.backends)) {
+			if b, ok := g.backends[id]; ok && b.pool.Healthy() > 0 {
+				return b
+			}
+		}
+		return nil
+	}
+
+	var best *backend
+	for _, b := range g.backends {
+		if b.pool.Healthy() == 0 {
+			continue
+		}
+		if best == nil || atomic.LoadInt64(&b.sessions) < atomic.LoadInt64(&best.sessions) {
+			best = b
+		}
+	}
+	return best
+}
+
+// Ring 逻辑服务实例ID的一致性哈希, 可以用来查询用户或房间所属的实例
+func (g *Gateway) Ring() *HashRing {
+	return g.ring
+}
+
+// alive 实例仍然在发现的实例中
+func (g *Gateway) alive(b *backend) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.backends[b.instance.ID] == b
+}
+
+// session 客户端连接的会话, 第一次转发时创建
+func (g *Gateway) session(conn ziface.IConnection) *gatewaySession {
+	if s, ok := g.conns.Load(conn.GetConnID()); ok {
+		return s.(*gatewaySession)
+	}
+	s := &gatewaySession{id: atomic.AddUint32(&g.nextID, 1), conn: conn}
+	if actual, loaded := g.conns.LoadOrStore(conn.GetConnID(), s); loaded {
+		return actual.(*gatewaySession)
+	}
+	g.sessions.Store(s.id, s)
+	// 连接在创建会话之前已经关闭
+	if conn.Context().Err() != nil {
+		g.closeSession(conn)
+	}
+	return s
+}
+
+// backendConn 会话绑定的逻辑服务实例的连接, 实例下线时重新选择实例, 连接断开时从连接池重新选择连接
+func (g *Gateway) backendConn(s *gatewaySession) (ziface.IConnection, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+	if s.backend != nil && !g.alive(s.backend) {
+		atomic.AddInt64(&s.backend.sessions, -1)
+		s.backend, s.backendConn = nil, nil
+	}
+	if s.backend == nil {
+		var key string
+		if g.config.StickyKey != nil {
+			key = g.config.StickyKey(s.conn)
+		}
+		if s.backend = g.pick(key); s.backend == nil {
+			return nil, ErrNoBackend
+		}
+		atomic.AddInt64(&s.backend.sessions, 1)
+	}
+	if s.backendConn == nil || s.backendConn.Context().Err() != nil {
+		conn, err := s.backend.pool.Get()
+		if err != nil {
+			return nil, err
+		}
+		s.backendConn = conn
+	}
+	return s.backendConn, nil
+}
+
+// forward 将客户端的消息以会话的逻辑会话ID转发给逻辑服务, 携带的关联序号原样转发
+func (g *Gateway) forward(request ziface.IRequest) error {
+	s := g.session(request.GetConnection())
+	conn, err := g.backendConn(s)
+	if err != nil {
+		return err
+	}
+	w, ok := conn.(ziface.IMuxWriter)
+	if !ok {
+		return ErrNotMuxConn
+	}
+	return w.SendMuxMsg(s.id, request.GetSeq(), request.GetMsgID(), request.GetData())
+}
+
+// closeSession 客户端连接断开时删除会话并通知逻辑服务
+func (g *Gateway) closeSession(conn ziface.IConnection) {
+	if conn == nil {
+		return
+	}
+	value, ok := g.conns.Load(conn.GetConnID())
+	if !ok {
+		return
+	}
+	s := value.(*gatewaySession)
+	if s.conn != conn {
+		return
+	}
+	g.conns.Delete(conn.GetConnID())
+	g.sessions.Delete(s.id)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	if s.backend == nil {
+		return
+	}
+	atomic.AddInt64(&s.backend.sessions, -1)
+	if w, ok := s.backendConn.(ziface.IMuxWriter); ok && s.backendConn.Context().Err() == nil {
+		_ = w.SendMuxMsg(s.id, 0, SessionClosedMsgID, nil)
+	}
+	s.backend, s.backendConn = nil, nil
+}
+
+// deliver 将逻辑服务的消息转发给会话的客户端连接
+func (g *Gateway) deliver(request ziface.IRequest) {
+	value, ok := g.sessions.Load(request.GetSessionID())
+	if !ok {
+		zlog.Ins().DebugF("gateway session %d not found, drop msgID = %d", request.GetSessionID(), request.GetMsgID())
+		return
+	}
+	conn := value.(*gatewaySession).conn
+	switch request.GetMsgID() {
+	case SessionClosedMsgID:
+		conn.Stop()
+		return
+	case GroupJoinMsgID:
+		g.server.GetGroupMgr().Join(string(request.GetData()), conn)
+		return
+	case GroupLeaveMsgID:
+		g.server.GetGroupMgr().Leave(string(request.GetData()), conn)
+		return
+	}
+
+	var err error
+	if seq := request.GetSeq(); seq != 0 {
+		err = conn.SendSeqMsg(seq, request.GetMsgID(), request.GetData())
+	} else {
+		err = conn.SendMsg(request.GetMsgID(), request.GetData())
+	}
+	if err != nil {
+		zlog.Ins().ErrorF("gateway deliver to connID = %d err: %v", conn.GetConnID(), err)
+	}
+}
+
+// forwardRouter 网关的默认路由, 转发没有本地路由的消息
+type forwardRouter struct {
+	znet.BaseRouter
+	gateway *Gateway
+}
+
+func (r *forwardRouter) Handle(request ziface.IRequest) {
+	if err := r.gateway.forward(request); err != nil {
+		zlog.Ins().ErrorF("gateway forward connID = %d msgID = %d err: %v", request.GetConnection().GetConnID(), request.GetMsgID(), err)
+		request.SetError(err)
+	}
+}
+
+// backendReceiver 到逻辑服务连接的解码之后的拦截器, 将属于会话的消息转发给客户端, 处理逻辑服务要求的广播
+type backendReceiver struct {
+	gateway *Gateway
+}
+
+func (r *backendReceiver) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok {
+		return chain.Proceed(chain.Request())
+	}
+	if request.GetSessionID() == 0 {
+		if request.GetMsgID() != BroadcastMsgID {
+			return chain.Proceed(chain.Request())
+		}
+		r.gateway.broadcast(request.GetData())
+		return nil
+	}
+	r.gateway.deliver(request)
+	return nil
+}
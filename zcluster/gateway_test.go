@@ -0,0 +1,190 @@
+package zcluster
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zclient"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticRegistry 返回固定实例的注册中心
+type staticRegistry struct {
+	lock      sync.Mutex
+	instances []ziface.ServiceInstance
+	err       error
+}
+
+func (r *staticRegistry) Register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	return nil
+}
+
+func (r *staticRegistry) KeepAlive(ctx context.Context, instance ziface.ServiceInstance) error {
+	return nil
+}
+
+func (r *staticRegistry) Deregister(ctx context.Context, instance ziface.ServiceInstance) error {
+	return nil
+}
+
+func (r *staticRegistry) Discover(ctx context.Context, name string) ([]ziface.ServiceInstance, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]ziface.ServiceInstance(nil), r.instances...), nil
+}
+
+type funcRouter struct {
+	znet.BaseRouter
+	handle func(request ziface.IRequest)
+}
+
+func (r *funcRouter) Handle(request ziface.IRequest) {
+	r.handle(request)
+}
+
+// logicNode 逻辑服务, 回复"节点名称:数据", 记录关闭的会话
+type logicNode struct {
+	znet.BaseRouter
+	name   string
+	lock   sync.Mutex
+	closed []uint32
+	conn   ziface.IConnection
+}
+
+func (n *logicNode) Handle(request ziface.IRequest) {
+	n.lock.Lock()
+	n.conn = request.GetConnection()
+	n.lock.Unlock()
+	_ = request.Reply([]byte(n.name + ":" + string(request.GetData())))
+}
+
+func (n *logicNode) closedSessions() []uint32 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return append([]uint32(nil), n.closed...)
+}
+
+func startLogicNode(t *testing.T, name string, port int) (ziface.IServer, *logicNode) {
+	node := &logicNode{name: name}
+	s := NewLogicServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = port
+	s.AddRouter(1, node)
+	s.AddRouter(SessionClosedMsgID, &funcRouter{handle: func(request ziface.IRequest) {
+		node.lock.Lock()
+		node.closed = append(node.closed, request.GetSessionID())
+		node.lock.Unlock()
+	}})
+	s.Start()
+	return s, node
+}
+
+// replyRouter 记录客户端收到的回复
+type replyRouter struct {
+	znet.BaseRouter
+	replies chan string
+}
+
+func (r *replyRouter) Handle(request ziface.IRequest) {
+	r.replies <- string(request.GetData())
+}
+
+func dialGateway(t *testing.T, port int, uid string) (ziface.IClient, chan string) {
+	router := &replyRouter{replies: make(chan string, 10)}
+	client := znet.NewClient("127.0.0.1", port)
+	client.AddRouter(1, router)
+	client.AddRouter(3, router)
+	client.SetOnConnStart(func(conn ziface.IConnection) {
+		_ = conn.SendMsg(2, []byte(uid))
+	})
+	client.Start()
+	return client, router.replies
+}
+
+func recv(t *testing.T, replies chan string) string {
+	t.Helper()
+	select {
+	case reply := <-replies:
+		return reply
+	case <-time.After(3 * time.Second):
+		t.Fatal("wait reply timeout")
+	}
+	return ""
+}
+
+func TestGateway(t *testing.T) {
+	logicA, nodeA := startLogicNode(t, "a", 28976)
+	defer logicA.Stop()
+	logicB, nodeB := startLogicNode(t, "b", 28975)
+	defer logicB.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	registry := &staticRegistry{instances: []ziface.ServiceInstance{
+		{ID: "a", Name: "logic", Addr: "127.0.0.1:28976"},
+		{ID: "b", Name: "logic", Addr: "127.0.0.1:28975"},
+	}}
+
+	s := znet.NewServer()
+	s.(*znet.Server).IP = "127.0.0.1"
+	s.(*znet.Server).Port = 28974
+	// 网关本地处理登录消息, 记录用户ID作为会话粘性的键
+	s.AddRouter(2, &funcRouter{handle: func(request ziface.IRequest) {
+		request.GetConnection().SetProperty("uid", string(request.GetData()))
+	}})
+	gateway := NewGateway(s, GatewayConfig{
+		Registry: registry,
+		Service:  "logic",
+		Pool:     zclient.PoolConfig{Reconnect: ziface.ReconnectConfig{MinDelay: 10 * time.Millisecond}},
+		StickyKey: func(conn ziface.IConnection) string {
+			uid, _ := conn.GetProperty("uid")
+			return uid.(string)
+		},
+	})
+	s.Start()
+	defer s.Stop()
+	gateway.Start()
+	defer gateway.Stop()
+	assert.Equal(t, 2, len(gateway.Backends()))
+	time.Sleep(200 * time.Millisecond)
+
+	// 相同用户ID的连接转发给同一个逻辑服务实例
+	client1, replies1 := dialGateway(t, 28974, "u1")
+	defer client1.Stop()
+	client2, replies2 := dialGateway(t, 28974, "u1")
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Nil(t, client1.Conn().SendMsg(1, []byte("hello")))
+	reply1 := recv(t, replies1)
+	assert.Nil(t, client2.Conn().SendMsg(1, []byte("world")))
+	reply2 := recv(t, replies2)
+	node := reply1[:1]
+	assert.Equal(t, node+":hello", reply1)
+	assert.Equal(t, node+":world", reply2)
+
+	// 客户端断开连接时通知逻辑服务
+	logic := nodeA
+	if node == "b" {
+		logic = nodeB
+	}
+	client2.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	// 逻辑服务主动推送与断开客户端连接
+	logic.lock.Lock()
+	conn := logic.conn
+	logic.lock.Unlock()
+	assert.Equal(t, []uint32{2}, logic.closedSessions())
+	sessionID := uint32(1)
+	assert.Nil(t, SendToSession(conn, sessionID, 1, []byte("push")))
+	assert.Equal(t, "push", recv(t, replies1))
+	assert.Nil(t, Kick(conn, sessionID))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 0, s.GetConnMgr().Len())
+}
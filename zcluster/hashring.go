@@ -0,0 +1,210 @@
+package zcluster
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultHashReplicas 一致性哈希中每个节点的默认虚拟节点数量
+const DefaultHashReplicas = 160
+
+// ringState 哈希环的一个不可变快照
+type ringState struct {
+	points []uint64          //排序后的虚拟节点哈希
+	owners map[uint64]string //虚拟节点哈希 -> 节点
+	nodes  map[string]struct{}
+}
+
+// hashKey FNV-1a哈希, 再混合各位, 相近的键(如"u1"与"u2")也能均匀分布在环上
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// get 键顺时针方向最多n个不同的节点
+func (rs *ringState) get(key string, n int) []string {
+	if len(rs.points) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(rs.nodes) {
+		n = len(rs.nodes)
+	}
+	h := hashKey(key)
+	i := sort.Search(len(rs.points), func(i int) bool { return rs.points[i] >= h })
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for j := 0; j < len(rs.points) && len(nodes) < n; j++ {
+		node := rs.owners[rs.points[(i+j)%len(rs.points)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// RingChange 哈希环节点变化, 用于在节点增减后迁移受影响的键(如用户、房间)
+type RingChange struct {
+	Added   []string //新增的节点
+	Removed []string //移除的节点
+	before  *ringState
+	after   *ringState
+}
+
+// Moved 键在变化前后所属的节点, 所属节点改变时moved为true, 节点为空表示当时环中没有节点
+func (c *RingChange) Moved(key string) (from, to string, moved bool) {
+	if nodes := c.before.get(key, 1); len(nodes) > 0 {
+		from = nodes[0]
+	}
+	if nodes := c.after.get(key, 1); len(nodes) > 0 {
+		to = nodes[0]
+	}
+	return from, to, from != to
+}
+
+// HashRing 带虚拟节点的一致性哈希, 节点增减时只有少部分键改变所属的节点
+// 网关用它为用户或房间选择逻辑服务实例, 让相关的消息落在同一个节点上
+type HashRing struct {
+	replicas    int
+	lock        sync.RWMutex
+	state       *ringState
+	onRebalance func(change *RingChange)
+}
+
+// NewHashRing 创建一致性哈希, replicas为每个节点的虚拟节点数量, 小于等于0时使用DefaultHashReplicas
+func NewHashRing(replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashReplicas
+	}
+	return &HashRing{replicas: replicas, state: &ringState{owners: map[uint64]string{}, nodes: map[string]struct{}{}}}
+}
+
+// SetOnRebalance 设置节点变化时的回调, 在节点变化之后同步调用
+func (r *HashRing) SetOnRebalance(hookFunc func(change *RingChange)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.onRebalance = hookFunc
+}
+
+// Add 添加节点
+func (r *HashRing) Add(nodes ...string) {
+	r.update(func(current map[string]struct{}) {
+		for _, node := range nodes {
+			current[node] = struct{}{}
+		}
+	})
+}
+
+// Remove 移除节点
+func (r *HashRing) Remove(nodes ...string) {
+	r.update(func(current map[string]struct{}) {
+		for _, node := range nodes {
+			delete(current, node)
+		}
+	})
+}
+
+// Set 设置全部节点, 如每次从注册中心发现实例之后调用
+func (r *HashRing) Set(nodes []string) {
+	r.update(func(current map[string]struct{}) {
+		for node := range current {
+			delete(current, node)
+		}
+		for _, node := range nodes {
+			current[node] = struct{}{}
+		}
+	})
+}
+
+// Get 键所属的节点, 环中没有节点时返回false
+func (r *HashRing) Get(key string) (string, bool) {
+	nodes := r.GetN(key, 1)
+	if len(nodes) == 0 {
+		return "", false
+	}
+	return nodes[0], true
+}
+
+// GetN 键顺时针方向最多n个不同的节点, 第一个为键所属的节点, 之后的节点可用于副本或所属节点不可用时的后备
+func (r *HashRing) GetN(key string, n int) []string {
+	r.lock.RLock()
+	state := r.state
+	r.lock.RUnlock()
+	return state.get(key, n)
+}
+
+// Nodes 全部节点
+func (r *HashRing) Nodes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	nodes := make([]string, 0, len(r.state.nodes))
+	for node := range r.state.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// update 修改节点集合并重建哈希环, 节点有变化时调用回调
+func (r *HashRing) update(modify func(current map[string]struct{})) {
+	r.lock.Lock()
+	before := r.state
+	nodes := make(map[string]struct{}, len(before.nodes))
+	for node := range before.nodes {
+		nodes[node] = struct{}{}
+	}
+	modify(nodes)
+
+	change := &RingChange{before: before}
+	for node := range nodes {
+		if _, ok := before.nodes[node]; !ok {
+			change.Added = append(change.Added, node)
+		}
+	}
+	for node := range before.nodes {
+		if _, ok := nodes[node]; !ok {
+			change.Removed = append(change.Removed, node)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		r.lock.Unlock()
+		return
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+
+	after := &ringState{owners: make(map[uint64]string, len(nodes)*r.replicas), nodes: nodes}
+	for node := range nodes {
+		for i := 0; i < r.replicas; i++ {
+			h := hashKey(node + "#" + strconv.Itoa(i))
+			// 哈希冲突时保留字典序较小的节点, 保证结果与添加顺序无关
+			if owner, ok := after.owners[h]; ok && owner < node {
+				continue
+			}
+			if _, ok := after.owners[h]; !ok {
+				after.points = append(after.points, h)
+			}
+			after.owners[h] = node
+		}
+	}
+	sort.Slice(after.points, func(i, j int) bool { return after.points[i] < after.points[j] })
+	change.after = after
+	r.state = after
+	hookFunc := r.onRebalance
+	r.lock.Unlock()
+
+	if hookFunc != nil {
+		hookFunc(change)
+	}
+}
@@ -0,0 +1,77 @@
+package zcluster
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	ring := NewHashRing(0)
+	_, ok := ring.Get("u1")
+	assert.False(t, ok)
+
+	var changes []*RingChange
+	ring.SetOnRebalance(func(change *RingChange) {
+		changes = append(changes, change)
+	})
+	ring.Set([]string{"a", "b", "c"})
+	assert.Equal(t, []string{"a", "b", "c"}, ring.Nodes())
+	assert.Equal(t, 1, len(changes))
+	assert.Equal(t, []string{"a", "b", "c"}, changes[0].Added)
+
+	// 虚拟节点让键大致均匀分布
+	counts := map[string]int{}
+	before := map[string]string{}
+	for i := 0; i < 3000; i++ {
+		key := "u" + strconv.Itoa(i)
+		node, ok := ring.Get(key)
+		assert.True(t, ok)
+		counts[node]++
+		before[key] = node
+	}
+	for _, n := range counts {
+		assert.InDelta(t, 1000, n, 250)
+	}
+
+	// 结果与添加顺序无关
+	other := NewHashRing(0)
+	other.Add("c", "b")
+	other.Add("a")
+	for key, node := range before {
+		owner, _ := other.Get(key)
+		assert.Equal(t, node, owner)
+	}
+
+	// 添加节点只迁移部分键, 且都迁移到新节点
+	ring.Add("d")
+	assert.Equal(t, 2, len(changes))
+	assert.Equal(t, []string{"d"}, changes[1].Added)
+	moved := 0
+	for key, node := range before {
+		from, to, ok := changes[1].Moved(key)
+		assert.Equal(t, node, from)
+		if ok {
+			moved++
+			assert.Equal(t, "d", to)
+		}
+		owner, _ := ring.Get(key)
+		assert.Equal(t, to, owner)
+	}
+	assert.InDelta(t, 750, moved, 250)
+
+	// 没有变化时不调用回调
+	ring.Add("d")
+	assert.Equal(t, 2, len(changes))
+
+	nodes := ring.GetN("u1", 10)
+	assert.Equal(t, 4, len(nodes))
+	owner, _ := ring.Get("u1")
+	assert.Equal(t, owner, nodes[0])
+
+	ring.Remove("a", "b", "c", "d")
+	assert.Equal(t, []string{"a", "b", "c", "d"}, changes[2].Removed)
+	_, ok = ring.Get("u1")
+	assert.False(t, ok)
+}
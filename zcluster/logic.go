@@ -0,0 +1,62 @@
+package zcluster
+
+import (
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+)
+
+const (
+	// NodeHelloMsgID 网关连接到逻辑服务后发送本网关节点ID的消息ID
+	NodeHelloMsgID uint32 = 99995
+	// BroadcastMsgID 逻辑服务要求网关向本地的全部连接或分组广播的消息ID, 数据见Cluster.Broadcast
+	BroadcastMsgID uint32 = 99994
+	// GroupJoinMsgID 逻辑服务要求网关将会话的客户端连接加入分组的消息ID, 数据为分组名称
+	GroupJoinMsgID uint32 = 99993
+	// GroupLeaveMsgID 逻辑服务要求网关将会话的客户端连接离开分组的消息ID, 数据为分组名称
+	GroupLeaveMsgID uint32 = 99992
+)
+
+// 网关连接上保存网关节点ID的属性
+const propNodeID = "zinx.cluster.node"
+
+// NewLogicServer 创建网关后面的逻辑服务, 使用逻辑会话的封包方式与解码器
+// 网关转发的请求通过request.GetSessionID区分客户端, request.Reply回复给对应的客户端
+// 客户端连接断开时逻辑服务收到SessionClosedMsgID消息, 可以添加该消息的路由清理会话状态, 使用Cluster时通过Cluster.SetOnSessionClosed
+func NewLogicServer(opts ...znet.Option) ziface.IServer {
+	s := znet.NewServer(append([]znet.Option{znet.WithPacket(zpack.NewMuxDataPack())}, opts...)...)
+	s.SetDecoder(zdecoder.NewMuxTLVDecoder())
+	s.AddRouter(NodeHelloMsgID, &clusterRouter{handle: func(request ziface.IRequest) {
+		request.GetConnection().SetProperty(propNodeID, string(request.GetData()))
+	}})
+	return s
+}
+
+// NodeID 请求来自的网关节点ID, 网关还没有发送节点ID时为空
+func NodeID(request ziface.IRequest) string {
+	return connNodeID(request.GetConnection())
+}
+
+func connNodeID(conn ziface.IConnection) string {
+	nodeID, err := conn.GetProperty(propNodeID)
+	if err != nil {
+		return ""
+	}
+	id, _ := nodeID.(string)
+	return id
+}
+
+// SendToSession 逻辑服务通过网关的连接主动推送消息给会话的客户端
+func SendToSession(conn ziface.IConnection, sessionID uint32, msgID uint32, data []byte) error {
+	w, ok := conn.(ziface.IMuxWriter)
+	if !ok {
+		return ErrNotMuxConn
+	}
+	return w.SendMuxMsg(sessionID, 0, msgID, data)
+}
+
+// Kick 逻辑服务通知网关断开会话的客户端连接
+func Kick(conn ziface.IConnection, sessionID uint32) error {
+	return SendToSession(conn, sessionID, SessionClosedMsgID, nil)
+}
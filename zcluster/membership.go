@@ -0,0 +1,186 @@
+package zcluster
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultMembershipInterval 成员视图从注册中心刷新的默认间隔
+const DefaultMembershipInterval = time.Second
+
+// MemberEventType 成员变化的类型
+type MemberEventType int
+
+const (
+	MemberJoin   MemberEventType = iota + 1 //节点加入(注册到注册中心)
+	MemberLeave                             //节点离开(注销或租约过期)
+	MemberUpdate                            //节点的地址、负载或元数据变化
+)
+
+func (t MemberEventType) String() string {
+	switch t {
+	case MemberJoin:
+		return "join"
+	case MemberLeave:
+		return "leave"
+	case MemberUpdate:
+		return "update"
+	}
+	return "unknown"
+}
+
+// MemberEvent 成员变化事件
+type MemberEvent struct {
+	Type     MemberEventType
+	Instance ziface.ServiceInstance
+}
+
+// MembershipConfig 成员视图配置
+type MembershipConfig struct {
+	Registry ziface.IRegistry //注册中心, 节点通过注册与续约发送心跳, 停止续约的节点在租约过期后离开
+	Service  string           //服务名称
+	Interval time.Duration    //从注册中心刷新的间隔, 默认1秒
+}
+
+// Membership 集群节点的实时成员视图, 定期从注册中心刷新并产生加入/离开事件
+// 路由层订阅事件后可以立即停止向已离开的节点发送, 而不是每条消息等待超时
+// 查询注册中心失败时保持当前视图, 不会因为注册中心短暂不可用而移除全部节点
+type Membership struct {
+	config MembershipConfig
+
+	lock     sync.RWMutex
+	members  map[string]ziface.ServiceInstance
+	handlers []func(event MemberEvent)
+
+	// 保证事件按顺序派发
+	refreshLock sync.Mutex
+	exitChan    chan struct{}
+	startOnce   sync.Once
+	stopOnce    sync.Once
+}
+
+// NewMembership 创建成员视图
+func NewMembership(config MembershipConfig) *Membership {
+	if config.Interval <= 0 {
+		config.Interval = DefaultMembershipInterval
+	}
+	return &Membership{
+		config:   config,
+		members:  make(map[string]ziface.ServiceInstance),
+		exitChan: make(chan struct{}),
+	}
+}
+
+// Subscribe 订阅成员变化事件, 在刷新的Goroutine中按顺序同步调用, 不应阻塞
+// 需在Start之前订阅才能收到初始成员的加入事件
+func (m *Membership) Subscribe(handler func(event MemberEvent)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Start 立即刷新一次成员视图, 之后按间隔定期刷新
+func (m *Membership) Start() {
+	m.startOnce.Do(func() {
+		m.Refresh()
+		go func() {
+			ticker := time.NewTicker(m.config.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					m.Refresh()
+				case <-m.exitChan:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止刷新
+func (m *Membership) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.exitChan)
+	})
+}
+
+// Members 当前存活的节点, 按实例ID排序
+func (m *Membership) Members() []ziface.ServiceInstance {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	members := make([]ziface.ServiceInstance, 0, len(m.members))
+	for _, instance := range m.members {
+		members = append(members, instance)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// Alive 节点是否存活
+func (m *Membership) Alive(id string) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, ok := m.members[id]
+	return ok
+}
+
+// Refresh 从注册中心刷新成员视图并派发变化事件, 返回查询注册中心的错误
+func (m *Membership) Refresh() error {
+	m.refreshLock.Lock()
+	defer m.refreshLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), zdiscovery.DefaultTimeout)
+	defer cancel()
+	instances, err := m.config.Registry.Discover(ctx, m.config.Service)
+	if err != nil {
+		zlog.Ins().ErrorF("membership discover %s err: %v", m.config.Service, err)
+		return err
+	}
+
+	alive := make(map[string]ziface.ServiceInstance, len(instances))
+	for _, instance := range instances {
+		alive[instance.ID] = instance
+	}
+
+	var events []MemberEvent
+	m.lock.Lock()
+	for id, instance := range m.members {
+		if _, ok := alive[id]; !ok {
+			events = append(events, MemberEvent{Type: MemberLeave, Instance: instance})
+		}
+	}
+	for id, instance := range alive {
+		old, ok := m.members[id]
+		if !ok {
+			events = append(events, MemberEvent{Type: MemberJoin, Instance: instance})
+		} else if !reflect.DeepEqual(old, instance) {
+			events = append(events, MemberEvent{Type: MemberUpdate, Instance: instance})
+		}
+	}
+	m.members = alive
+	handlers := m.handlers
+	m.lock.Unlock()
+
+	// 先派发离开事件, 再按实例ID派发加入与更新事件
+	sort.Slice(events, func(i, j int) bool {
+		li, lj := events[i].Type == MemberLeave, events[j].Type == MemberLeave
+		if li != lj {
+			return li
+		}
+		return events[i].Instance.ID < events[j].Instance.ID
+	})
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+	return nil
+}
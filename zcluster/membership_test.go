@@ -0,0 +1,49 @@
+package zcluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMembership(t *testing.T) {
+	registry := &staticRegistry{instances: []ziface.ServiceInstance{
+		{ID: "a", Name: "logic", Addr: "127.0.0.1:1"},
+		{ID: "b", Name: "logic", Addr: "127.0.0.1:2"},
+	}}
+	members := NewMembership(MembershipConfig{Registry: registry, Service: "logic", Interval: time.Hour})
+
+	var events []string
+	members.Subscribe(func(event MemberEvent) {
+		events = append(events, event.Type.String()+":"+event.Instance.ID)
+	})
+	members.Start()
+	defer members.Stop()
+	assert.Equal(t, []string{"join:a", "join:b"}, events)
+	assert.True(t, members.Alive("a"))
+
+	// 节点离开、加入与负载变化
+	events = nil
+	registry.lock.Lock()
+	registry.instances = []ziface.ServiceInstance{
+		{ID: "b", Name: "logic", Addr: "127.0.0.1:2", Load: 10},
+		{ID: "c", Name: "logic", Addr: "127.0.0.1:3"},
+	}
+	registry.lock.Unlock()
+	assert.Nil(t, members.Refresh())
+	assert.Equal(t, []string{"leave:a", "update:b", "join:c"}, events)
+	assert.False(t, members.Alive("a"))
+
+	// 注册中心不可用时保持当前视图
+	events = nil
+	registry.lock.Lock()
+	registry.err = errors.New("registry down")
+	registry.lock.Unlock()
+	assert.NotNil(t, members.Refresh())
+	assert.Nil(t, events)
+	assert.Equal(t, 2, len(members.Members()))
+	assert.Equal(t, "b", members.Members()[0].ID)
+}
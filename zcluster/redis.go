@@ -0,0 +1,169 @@
+package zcluster
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/zdiscovery"
+)
+
+// DefaultRedisPoolSize 到Redis的默认空闲连接数量
+const DefaultRedisPoolSize = 8
+
+// RedisConfig Redis连接配置
+type RedisConfig struct {
+	Addr     string        //Redis地址, 如"127.0.0.1:6379"
+	Password string        //密码, 为空时不认证
+	DB       int           //数据库编号
+	PoolSize int           //空闲连接数量, 默认8
+	Timeout  time.Duration //连接与读写超时时间, 默认5秒
+	Prefix   string        //键前缀, 默认"zinx:"
+}
+
+// redisError Redis返回的错误回复
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisConn 一个Redis连接, 使用RESP协议
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisClient 最小的Redis客户端, 只实现集群组件使用的命令, 不依赖Redis的客户端库
+type redisClient struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+func newRedisClient(config RedisConfig) *redisClient {
+	if config.PoolSize <= 0 {
+		config.PoolSize = DefaultRedisPoolSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = zdiscovery.DefaultTimeout
+	}
+	if config.Prefix == "" {
+		config.Prefix = "zinx:"
+	}
+	return &redisClient{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+func (c *redisClient) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: c.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if c.config.Password != "" {
+		if _, err := c.exec(ctx, rc, "AUTH", c.config.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.config.DB != 0 {
+		if _, err := c.exec(ctx, rc, "SELECT", strconv.Itoa(c.config.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// do 执行一条命令, 网络错误时关闭连接, 否则连接放回空闲连接
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	var rc *redisConn
+	select {
+	case rc = <-c.idle:
+	default:
+		var err error
+		if rc, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.exec(ctx, rc, args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		rc.conn.Close()
+		return nil, err
+	}
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisClient) exec(ctx context.Context, rc *redisConn, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(c.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = rc.conn.SetDeadline(deadline)
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := rc.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readReply(rc.r)
+}
+
+// readReply 读取一个RESP回复, 字符串以string返回, 空回复为nil, 数组为[]interface{}
+func readReply(r *bufio.Reader) (interface{}, error) {
+	value, err := zdecoder.ReadRESP(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	return replyValue(value)
+}
+
+func replyValue(value *zdecoder.RESPValue) (interface{}, error) {
+	if value.Null {
+		return nil, nil
+	}
+	switch value.Type {
+	case zdecoder.RESPSimpleStringType, zdecoder.RESPBulkStringType:
+		return string(value.Str), nil
+	case zdecoder.RESPErrorType, zdecoder.RESPBulkErrorType:
+		return nil, redisError(value.Str)
+	case zdecoder.RESPIntegerType:
+		return value.Int, nil
+	case zdecoder.RESPArrayType:
+		items := make([]interface{}, len(value.Array))
+		for i, item := range value.Array {
+			var err error
+			if items[i], err = replyValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", value.Type)
+}
+
+// close 关闭全部空闲连接
+func (c *redisClient) close() {
+	for {
+		select {
+		case rc := <-c.idle:
+			rc.conn.Close()
+		default:
+			return
+		}
+	}
+}
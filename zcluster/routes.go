@@ -0,0 +1,116 @@
+package zcluster
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// MemoryRouteTable 进程内的用户路由表, 用于只有一个逻辑服务节点或测试
+type MemoryRouteTable struct {
+	lock   sync.Mutex
+	routes map[string]memoryRoute
+}
+
+type memoryRoute struct {
+	route    ziface.UserRoute
+	expireAt time.Time
+}
+
+func NewMemoryRouteTable() *MemoryRouteTable {
+	return &MemoryRouteTable{routes: make(map[string]memoryRoute)}
+}
+
+func (t *MemoryRouteTable) Bind(ctx context.Context, route ziface.UserRoute, ttl time.Duration) error {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.routes[route.UserID] = memoryRoute{route: route, expireAt: expireAt}
+	return nil
+}
+
+func (t *MemoryRouteTable) Unbind(ctx context.Context, route ziface.UserRoute) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if r, ok := t.routes[route.UserID]; ok && r.route == route {
+		delete(t.routes, route.UserID)
+	}
+	return nil
+}
+
+func (t *MemoryRouteTable) Lookup(ctx context.Context, userID string) (ziface.UserRoute, bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	r, ok := t.routes[userID]
+	if !ok {
+		return ziface.UserRoute{}, false, nil
+	}
+	if !r.expireAt.IsZero() && time.Now().After(r.expireAt) {
+		delete(t.routes, userID)
+		return ziface.UserRoute{}, false, nil
+	}
+	return r.route, true, nil
+}
+
+// redisUnbindScript 只在路由没有被其他会话覆盖时删除
+const redisUnbindScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// RedisRouteTable 保存在Redis中的用户路由表, 键为"前缀route:用户ID", 值为路由的JSON
+type RedisRouteTable struct {
+	client *redisClient
+}
+
+func NewRedisRouteTable(config RedisConfig) *RedisRouteTable {
+	return &RedisRouteTable{client: newRedisClient(config)}
+}
+
+func (t *RedisRouteTable) key(userID string) string {
+	return t.client.config.Prefix + "route:" + userID
+}
+
+func (t *RedisRouteTable) Bind(ctx context.Context, route ziface.UserRoute, ttl time.Duration) error {
+	value, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", t.key(route.UserID), string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err = t.client.do(ctx, args...)
+	return err
+}
+
+func (t *RedisRouteTable) Unbind(ctx context.Context, route ziface.UserRoute) error {
+	value, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.do(ctx, "EVAL", redisUnbindScript, "1", t.key(route.UserID), string(value))
+	return err
+}
+
+func (t *RedisRouteTable) Lookup(ctx context.Context, userID string) (ziface.UserRoute, bool, error) {
+	var route ziface.UserRoute
+	reply, err := t.client.do(ctx, "GET", t.key(userID))
+	if err != nil || reply == nil {
+		return route, false, err
+	}
+	value, _ := reply.(string)
+	if err := json.Unmarshal([]byte(value), &route); err != nil {
+		return route, false, err
+	}
+	return route, true, nil
+}
+
+// Close 关闭路由表的空闲连接, 之后的查询会重新建立连接
+func (t *RedisRouteTable) Close() {
+	t.client.close()
+}
@@ -0,0 +1,172 @@
+package zcluster
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis 内存中的Redis, 只实现集群组件使用的命令
+type fakeRedis struct {
+	listener net.Listener
+	password string
+	lock     sync.Mutex
+	kvs      map[string]string
+	hashes   map[string]map[string]string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeRedis{listener: listener, password: password, kvs: map[string]string{}, hashes: map[string]map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		if args[0] == "AUTH" {
+			authed = args[1] == f.password
+		}
+		if !authed {
+			_, _ = conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		_, _ = conn.Write([]byte(f.exec(args)))
+	}
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch args[0] {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		f.kvs[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		if value, ok := f.kvs[args[1]]; ok {
+			return bulk(value)
+		}
+		return "$-1\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.kvs[key]; ok {
+				delete(f.kvs, key)
+				n++
+			}
+			if _, ok := f.hashes[key]; ok {
+				delete(f.hashes, key)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "HSET":
+		if f.hashes[args[1]] == nil {
+			f.hashes[args[1]] = map[string]string{}
+		}
+		f.hashes[args[1]][args[2]] = args[3]
+		return ":1\r\n"
+	case "HDEL":
+		delete(f.hashes[args[1]], args[2])
+		return ":1\r\n"
+	case "HGETALL":
+		reply := "*" + strconv.Itoa(len(f.hashes[args[1]])*2) + "\r\n"
+		for field, value := range f.hashes[args[1]] {
+			reply += bulk(field) + bulk(value)
+		}
+		return reply
+	case "PEXPIRE", "PERSIST":
+		// 不模拟过期
+		return ":1\r\n"
+	case "EVAL":
+		// 只支持比较后删除的脚本
+		if args[1] == redisUnbindScript && f.kvs[args[3]] == args[4] {
+			delete(f.kvs, args[3])
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func testRouteTable(t *testing.T, routes ziface.IRouteTable) {
+	ctx := context.Background()
+	u1 := ziface.UserRoute{UserID: "u1", NodeID: "g1", SessionID: 1}
+	assert.Nil(t, routes.Bind(ctx, u1, 0))
+	route, ok, err := routes.Lookup(ctx, "u1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, u1, route)
+
+	// 用户在其他网关重新登录后, 旧会话关闭不解除新的路由
+	u1g2 := ziface.UserRoute{UserID: "u1", NodeID: "g2", SessionID: 7}
+	assert.Nil(t, routes.Bind(ctx, u1g2, 0))
+	assert.Nil(t, routes.Unbind(ctx, u1))
+	route, ok, _ = routes.Lookup(ctx, "u1")
+	assert.True(t, ok)
+	assert.Equal(t, u1g2, route)
+
+	assert.Nil(t, routes.Unbind(ctx, u1g2))
+	_, ok, err = routes.Lookup(ctx, "u1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryRouteTable(t *testing.T) {
+	routes := NewMemoryRouteTable()
+	testRouteTable(t, routes)
+
+	ctx := context.Background()
+	assert.Nil(t, routes.Bind(ctx, ziface.UserRoute{UserID: "u2", NodeID: "g1", SessionID: 2}, 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	_, ok, _ := routes.Lookup(ctx, "u2")
+	assert.False(t, ok)
+}
+
+func TestRedisRouteTable(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	defer redis.listener.Close()
+
+	routes := NewRedisRouteTable(RedisConfig{Addr: redis.listener.Addr().String(), Password: "secret", DB: 1})
+	defer routes.Close()
+	testRouteTable(t, routes)
+
+	// 错误的密码
+	bad := NewRedisRouteTable(RedisConfig{Addr: redis.listener.Addr().String(), Password: "wrong"})
+	defer bad.Close()
+	_, _, err := bad.Lookup(context.Background(), "u1")
+	assert.NotNil(t, err)
+}
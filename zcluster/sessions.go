@@ -0,0 +1,68 @@
+package zcluster
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// RedisSessionStore 保存在Redis中的会话存储, 每个会话一个Hash, 键为"前缀session:会话令牌", 字段为属性名
+// 通过SessionManager.SetStore设置后, 客户端可以在任意网关上恢复会话, 其他节点也可以读取会话属性
+type RedisSessionStore struct {
+	client *redisClient
+}
+
+func NewRedisSessionStore(config RedisConfig) *RedisSessionStore {
+	return &RedisSessionStore{client: newRedisClient(config)}
+}
+
+func (s *RedisSessionStore) key(token string) string {
+	return s.client.config.Prefix + "session:" + token
+}
+
+func (s *RedisSessionStore) Load(ctx context.Context, token string) (map[string][]byte, bool, error) {
+	reply, err := s.client.do(ctx, "HGETALL", s.key(token))
+	if err != nil {
+		return nil, false, err
+	}
+	items, _ := reply.([]interface{})
+	if len(items) == 0 {
+		return nil, false, nil
+	}
+	values := make(map[string][]byte, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		key, _ := items[i].(string)
+		value, _ := items[i+1].(string)
+		values[key] = []byte(value)
+	}
+	return values, true, nil
+}
+
+func (s *RedisSessionStore) Set(ctx context.Context, token string, key string, value []byte) error {
+	_, err := s.client.do(ctx, "HSET", s.key(token), key, string(value))
+	return err
+}
+
+func (s *RedisSessionStore) Delete(ctx context.Context, token string, key string) error {
+	_, err := s.client.do(ctx, "HDEL", s.key(token), key)
+	return err
+}
+
+func (s *RedisSessionStore) Expire(ctx context.Context, token string, ttl time.Duration) error {
+	if ttl <= 0 {
+		_, err := s.client.do(ctx, "PERSIST", s.key(token))
+		return err
+	}
+	_, err := s.client.do(ctx, "PEXPIRE", s.key(token), strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (s *RedisSessionStore) Remove(ctx context.Context, token string) error {
+	_, err := s.client.do(ctx, "DEL", s.key(token))
+	return err
+}
+
+// Close 服务停止时释放会话存储占用的Redis连接
+func (s *RedisSessionStore) Close() {
+	s.client.close()
+}
@@ -0,0 +1,49 @@
+package zcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisSessionStore(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	defer redis.listener.Close()
+
+	store := NewRedisSessionStore(RedisConfig{Addr: redis.listener.Addr().String()})
+	defer store.Close()
+	ctx := context.Background()
+
+	_, ok, err := store.Load(ctx, "t1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, store.Set(ctx, "t1", "uid", []byte(`"u1"`)))
+	assert.Nil(t, store.Set(ctx, "t1", "level", []byte("3")))
+	assert.Nil(t, store.Delete(ctx, "t1", "level"))
+	assert.Nil(t, store.Expire(ctx, "t1", time.Minute))
+	values, ok, err := store.Load(ctx, "t1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string][]byte{"uid": []byte(`"u1"`)}, values)
+
+	// 网关故障后客户端在其他网关上恢复会话
+	g1 := znet.NewSessionManager(time.Minute, 0, nil)
+	g1.SetStore(store, time.Minute)
+	g2 := znet.NewSessionManager(time.Minute, 0, nil)
+	g2.SetStore(store, time.Minute)
+
+	session := g1.Create(&znet.Connection{})
+	session.SetProperty("uid", "u2")
+	resumed, ok := g2.Resume(session.Token(), &znet.Connection{})
+	assert.True(t, ok)
+	uid, _ := resumed.GetProperty("uid")
+	assert.Equal(t, "u2", uid)
+
+	assert.Nil(t, store.Remove(ctx, "t1"))
+	_, ok, _ = store.Load(ctx, "t1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,61 @@
+package zcluster
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// RedisTimerStore 保存在Redis中的持久化定时器存储, 全部定时器保存在一个Hash中, 键为"前缀timers", 字段为定时器ID
+// 通过TimerScheduler.SetStore设置后, 部署或重启期间定时器不丢失; 多个进程共享时需要使用不同的Prefix
+type RedisTimerStore struct {
+	client *redisClient
+}
+
+func NewRedisTimerStore(config RedisConfig) *RedisTimerStore {
+	return &RedisTimerStore{client: newRedisClient(config)}
+}
+
+func (s *RedisTimerStore) key() string {
+	return s.client.config.Prefix + "timers"
+}
+
+func (s *RedisTimerStore) Save(ctx context.Context, timer ziface.DurableTimer) error {
+	data, err := json.Marshal(timer)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do(ctx, "HSET", s.key(), timer.ID, string(data))
+	return err
+}
+
+func (s *RedisTimerStore) Delete(ctx context.Context, id string) error {
+	_, err := s.client.do(ctx, "HDEL", s.key(), id)
+	return err
+}
+
+func (s *RedisTimerStore) LoadAll(ctx context.Context) ([]ziface.DurableTimer, error) {
+	reply, err := s.client.do(ctx, "HGETALL", s.key())
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+	timers := make([]ziface.DurableTimer, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		value, _ := items[i+1].(string)
+		var timer ziface.DurableTimer
+		if err := json.Unmarshal([]byte(value), &timer); err != nil {
+			return nil, err
+		}
+		timers = append(timers, timer)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].At < timers[j].At })
+	return timers, nil
+}
+
+// Close 关闭定时器存储的空闲连接
+func (s *RedisTimerStore) Close() {
+	s.client.close()
+}
@@ -0,0 +1,34 @@
+package zcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisTimerStore(t *testing.T) {
+	redis := newFakeRedis(t, "")
+	defer redis.listener.Close()
+
+	store := NewRedisTimerStore(RedisConfig{Addr: redis.listener.Addr().String()})
+	defer store.Close()
+	ctx := context.Background()
+
+	timers, err := store.LoadAll(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, timers)
+
+	unban := ziface.DurableTimer{ID: "unban:1", Task: "unban", Payload: []byte("10086"), At: 2000}
+	mail := ziface.DurableTimer{ID: "mail:1", Task: "mail", At: 1000}
+	assert.Nil(t, store.Save(ctx, unban))
+	assert.Nil(t, store.Save(ctx, mail))
+	assert.Nil(t, store.Save(ctx, ziface.DurableTimer{ID: "mail:2", Task: "mail", At: 3000}))
+	assert.Nil(t, store.Delete(ctx, "mail:2"))
+	assert.Nil(t, store.Delete(ctx, "missing"))
+
+	timers, err = store.LoadAll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []ziface.DurableTimer{mail, unban}, timers)
+}
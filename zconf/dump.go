@@ -0,0 +1,13 @@
+package zconf
+
+// Dump 当前生效的完整配置: 默认值、配置文件(或配置来源)、当前环境(Profile)、环境变量与命令行参数合并之后的结果
+// 密钥字段的值已去掉, 修改返回的配置不影响GlobalObject; 用于排查问题时确认节点实际使用的配置
+func Dump() *Config {
+	conf, err := cloneConfig(GlobalObject)
+	if err != nil {
+		copied := *GlobalObject
+		conf = &copied
+	}
+	conf.Redact()
+	return conf
+}
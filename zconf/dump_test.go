@@ -0,0 +1,24 @@
+package zconf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	saved := *GlobalObject
+	defer func() { *GlobalObject = saved }()
+	GlobalObject.AdminToken = "token"
+	GlobalObject.MaxConn = 123
+	GlobalObject.MsgMaxPacketSize = map[uint32]uint32{1: 1024}
+
+	conf := Dump()
+	assert.Equal(t, 123, conf.MaxConn)
+	assert.Empty(t, conf.AdminToken)
+	assert.Equal(t, "token", GlobalObject.AdminToken)
+
+	// 返回的是拷贝
+	conf.MsgMaxPacketSize[1] = 1
+	assert.Equal(t, uint32(1024), GlobalObject.MsgMaxPacketSize[1])
+}
@@ -0,0 +1,136 @@
+package zconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultEnvPrefix 覆盖配置的环境变量的默认前缀
+const DefaultEnvPrefix = "ZINX_"
+
+// EnvPrefixEnv 设置环境变量前缀的环境变量, 如ZINX_ENV_PREFIX=GAME_时使用GAME_TCP_PORT
+const EnvPrefixEnv = "ZINX_ENV_PREFIX"
+
+// EnvPrefix 覆盖配置的环境变量的前缀, 由环境变量ZINX_ENV_PREFIX设置, 默认为"ZINX_"
+func EnvPrefix() string {
+	if prefix, ok := os.LookupEnv(EnvPrefixEnv); ok {
+		return prefix
+	}
+	return DefaultEnvPrefix
+}
+
+// EnvName 配置字段对应的环境变量名称: 前缀 + 字段名按单词转为大写并以下划线连接
+// 如TCPPort为ZINX_TCP_PORT, MaxConn为ZINX_MAX_CONN, IOReadBuffSize为ZINX_IO_READ_BUFF_SIZE
+// 字段的env标签指定了名称时使用标签, 如StatsDAddr为ZINX_STATSD_ADDR
+func EnvName(prefix string, field reflect.StructField) string {
+	if name := field.Tag.Get("env"); name != "" {
+		return prefix + name
+	}
+	return prefix + snakeUpper(field.Name)
+}
+
+// EnvNames 全部配置字段对应的环境变量名称, 字段名 -> 环境变量名称
+func EnvNames(prefix string) map[string]string {
+	names := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		names[t.Field(i).Name] = EnvName(prefix, t.Field(i))
+	}
+	return names
+}
+
+// snakeUpper 驼峰命名转为大写下划线命名, 连续的大写字母视为一个单词
+func snakeUpper(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// LoadEnv 用环境变量覆盖配置, 环境变量名称见EnvName, 容器中无需配置文件即可修改配置
+// 值的格式: 字符串与数字原样填写, 布尔值为true/false/1/0, 字符串数组以逗号分隔,
+// 其余类型(如MsgMaxPacketSize、Listeners)填写JSON
+func (g *Config) LoadEnv(prefix string) error {
+	v := reflect.ValueOf(g).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := EnvName(prefix, t.Field(i))
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setStringValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("invalid %s=%q: %v", name, value, err)
+		}
+	}
+	return nil
+}
+
+// setStringValue 解析字符串形式的值(环境变量、properties配置)并设置到字段
+func setStringValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+		return nil
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setStringValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			items := make([]string, 0)
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+	}
+
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		return err
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
@@ -0,0 +1,69 @@
+package zconf
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvName(t *testing.T) {
+	names := EnvNames(DefaultEnvPrefix)
+	assert.Equal(t, "ZINX_TCP_PORT", names["TCPPort"])
+	assert.Equal(t, "ZINX_MAX_CONN", names["MaxConn"])
+	assert.Equal(t, "ZINX_IO_READ_BUFF_SIZE", names["IOReadBuffSize"])
+	assert.Equal(t, "ZINX_IP_VERSION", names["IPVersion"])
+	assert.Equal(t, "ZINX_TCP_NO_DELAY", names["TCPNoDelay"])
+	assert.Equal(t, "ZINX_MSG_MAX_PACKET_SIZE", names["MsgMaxPacketSize"])
+	assert.Equal(t, "ZINX_STATSD_ADDR", names["StatsDAddr"])
+	assert.Equal(t, "ZINX_DOGSTATSD", names["DogStatsD"])
+	assert.Equal(t, "ZINX_BANNED_IPS", names["BannedIPs"])
+
+	field, _ := reflect.TypeOf(Config{}).FieldByName("TCPPort")
+	assert.Equal(t, "GAME_TCP_PORT", EnvName("GAME_", field))
+}
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"TEST_ZINX_TCP_PORT":            "9100",
+		"TEST_ZINX_NAME":                "gate",
+		"TEST_ZINX_MAX_PACKET_SIZE":     "8192",
+		"TEST_ZINX_TCP_NO_DELAY":        "false",
+		"TEST_ZINX_ADMIN_DASHBOARD":     "1",
+		"TEST_ZINX_BANNED_IPS":          "10.0.0.1, 10.0.0.2",
+		"TEST_ZINX_MSG_MAX_PACKET_SIZE": `{"1": 1024}`,
+		"TEST_ZINX_LISTENERS":           `[{"IPVersion": "tcp6", "Host": "::"}]`,
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key := range env {
+			os.Unsetenv(key)
+		}
+	}()
+
+	conf := Config{TCPPort: 8999, Name: "game", MaxConn: 100}
+	assert.Nil(t, conf.LoadEnv("TEST_ZINX_"))
+	assert.Equal(t, 9100, conf.TCPPort)
+	assert.Equal(t, "gate", conf.Name)
+	assert.Equal(t, 100, conf.MaxConn)
+	assert.Equal(t, uint32(8192), conf.MaxPacketSize)
+	assert.False(t, *conf.TCPNoDelay)
+	assert.True(t, conf.AdminDashboard)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, conf.BannedIPs)
+	assert.Equal(t, uint32(1024), conf.MaxPacketSizeOf(1))
+	assert.Equal(t, []ListenAddr{{IPVersion: "tcp6", Host: "::"}}, conf.Listeners)
+
+	os.Setenv("TEST_ZINX_MAX_CONN", "many")
+	defer os.Unsetenv("TEST_ZINX_MAX_CONN")
+	err := conf.LoadEnv("TEST_ZINX_")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "TEST_ZINX_MAX_CONN")
+
+	os.Setenv(EnvPrefixEnv, "GAME_")
+	assert.Equal(t, "GAME_", EnvPrefix())
+	os.Unsetenv(EnvPrefixEnv)
+	assert.Equal(t, DefaultEnvPrefix, EnvPrefix())
+}
@@ -0,0 +1,78 @@
+package zconf
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// flagValues 命令行中设置的配置, 字段名 -> 值, 没有设置的字段不覆盖配置文件与环境变量
+var flagValues = make(map[string]string)
+
+// FlagName 配置字段对应的命令行参数名称: 环境变量名称(不含前缀)转为小写并以中划线连接
+// 如TCPPort为--tcp-port, LogDir为--log-dir, StatsDAddr为--statsd-addr
+func FlagName(field reflect.StructField) string {
+	return strings.ToLower(strings.ReplaceAll(EnvName("", field), "_", "-"))
+}
+
+// configFlag 记录命令行中设置的字符串值, 由LoadFlags按字段的类型解析, 格式与环境变量相同
+type configFlag struct {
+	field string
+	value string
+}
+
+func (f *configFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *configFlag) Set(value string) error {
+	f.value = value
+	flagValues[f.field] = value
+	return nil
+}
+
+// boolFlag 布尔类型的配置可以只写参数名, 如--dogstatsd
+type boolFlag struct {
+	configFlag
+}
+
+func (f *boolFlag) IsBoolFlag() bool {
+	return true
+}
+
+// bindFlags 为每个配置字段注册命令行参数, 已经被注册的名称跳过
+func bindFlags(fs *flag.FlagSet) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := FlagName(field)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+
+		usage := fmt.Sprintf("覆盖配置%s, 优先于环境变量%s与配置文件", field.Name, EnvName(DefaultEnvPrefix, field))
+		value := configFlag{field: field.Name}
+		if field.Type.Kind() == reflect.Bool {
+			fs.Var(&boolFlag{value}, name, usage)
+		} else {
+			fs.Var(&value, name, usage)
+		}
+	}
+}
+
+// LoadFlags 用命令行参数覆盖配置, 参数名称见FlagName, 无需修改配置文件即可临时调整配置(如本地运行、systemd服务)
+// 优先级从低到高: 默认值、配置文件(或配置来源)、环境变量、命令行参数
+func (g *Config) LoadFlags() error {
+	v := reflect.ValueOf(g).Elem()
+	for name, value := range flagValues {
+		field, _ := v.Type().FieldByName(name)
+		if err := setStringValue(v.FieldByName(name), value); err != nil {
+			return fmt.Errorf("invalid --%s=%q: %v", FlagName(field), value, err)
+		}
+	}
+	return nil
+}
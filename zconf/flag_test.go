@@ -0,0 +1,63 @@
+package zconf
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagName(t *testing.T) {
+	field := func(name string) reflect.StructField {
+		f, ok := reflect.TypeOf(Config{}).FieldByName(name)
+		assert.True(t, ok, name)
+		return f
+	}
+	assert.Equal(t, "tcp-port", FlagName(field("TCPPort")))
+	assert.Equal(t, "log-dir", FlagName(field("LogDir")))
+	assert.Equal(t, "statsd-addr", FlagName(field("StatsDAddr")))
+
+	// 每个字段的参数名称不重复
+	names := make(map[string]string)
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := FlagName(typ.Field(i))
+		assert.Empty(t, names[name], name)
+		names[name] = typ.Field(i).Name
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	saved := flagValues
+	flagValues = make(map[string]string)
+	defer func() { flagValues = saved }()
+
+	fs := flag.NewFlagSet("zinx", flag.ContinueOnError)
+	fs.String("host", "", "registered by application")
+	bindFlags(fs)
+	assert.Nil(t, fs.Parse([]string{"--tcp-port", "7001", "--log-dir=/var/log/zinx", "--dogstatsd", "--banned-ips", "10.0.0.1,10.0.0.2", "--host", "1.2.3.4"}))
+
+	assert.Nil(t, os.Setenv("ZINX_TCP_PORT", "7000"))
+	assert.Nil(t, os.Setenv("ZINX_MAX_CONN", "50"))
+	defer os.Unsetenv("ZINX_TCP_PORT")
+	defer os.Unsetenv("ZINX_MAX_CONN")
+
+	// 优先级: 配置文件 < 环境变量 < 命令行参数
+	conf := &Config{TCPPort: 8999, MaxConn: 10, Host: "0.0.0.0"}
+	assert.Nil(t, conf.LoadEnv(DefaultEnvPrefix))
+	assert.Nil(t, conf.LoadFlags())
+	assert.Equal(t, 7001, conf.TCPPort)
+	assert.Equal(t, 50, conf.MaxConn)
+	assert.Equal(t, "/var/log/zinx", conf.LogDir)
+	assert.True(t, conf.DogStatsD)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, conf.BannedIPs)
+	// 应用已经注册的参数不被覆盖
+	assert.Equal(t, "0.0.0.0", conf.Host)
+
+	fs = flag.NewFlagSet("zinx", flag.ContinueOnError)
+	bindFlags(fs)
+	assert.Nil(t, fs.Parse([]string{"--max-conn", "abc"}))
+	assert.NotNil(t, conf.LoadFlags())
+}
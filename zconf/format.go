@@ -0,0 +1,141 @@
+package zconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的配置文件格式, 按扩展名识别
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	// FormatProperties 每行一个key=value, 如Apollo默认的命名空间, 值的格式与环境变量相同
+	FormatProperties = "properties"
+)
+
+// configExts 配置文件不存在时依次查找的同名文件的扩展名
+var configExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// FormatOf 按扩展名识别配置文件格式, .yaml/.yml为YAML, .toml为TOML, .properties为properties, 其余为JSON
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".properties":
+		return FormatProperties
+	}
+	return FormatJSON
+}
+
+// Unmarshal 按格式解析配置到v, v中没有出现在配置中的字段保持原值
+// YAML与TOML先解析为通用的键值, 再按JSON的规则赋值, 因此字段名与zinx.json相同(不区分大小写)
+func Unmarshal(format string, data []byte, v interface{}) error {
+	var values interface{}
+	switch format {
+	case FormatJSON:
+		return json.Unmarshal(data, v)
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		values = normalizeYAML(values)
+	case FormatTOML:
+		table, err := decodeTOML(string(data))
+		if err != nil {
+			return err
+		}
+		values = table
+	case FormatProperties:
+		return unmarshalProperties(string(data), v)
+	default:
+		return fmt.Errorf("unsupported config format %q", format)
+	}
+
+	// 空的YAML文件
+	if values == nil {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// normalizeYAML 将YAML中非字符串键的映射(如MsgMaxPacketSize的消息ID)转换为字符串键, 以便编码为JSON
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	}
+	return value
+}
+
+// findConfigFile 配置文件不存在时, 查找同一目录下同名的其他格式的配置文件, 如zinx.json不存在时使用zinx.yaml
+func findConfigFile(path string) (string, bool) {
+	if exists, _ := PathExists(path); exists {
+		return path, true
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range configExts {
+		if candidate := base + ext; candidate != path {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, true
+			}
+		}
+	}
+	return path, false
+}
+
+// unmarshalProperties 解析properties, 键为字段名(不区分大小写), 值按字段的类型解析, 没有对应字段的键被忽略
+func unmarshalProperties(data string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("properties: cannot unmarshal into %T", v)
+	}
+	rv = rv.Elem()
+
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			return fmt.Errorf("properties: line %d: missing =", i+1)
+		}
+		key, value := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+
+		field := rv.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		if err := setStringValue(field, value); err != nil {
+			return fmt.Errorf("properties: line %d: invalid %s=%q: %v", i+1, key, value, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package zconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJSON = `{
+	"Name": "game",
+	"TCPPort": 9000,
+	"MaxPacketSize": 8192,
+	"MsgMaxPacketSize": {"1": 1024, "2": 2048},
+	"TCPNoDelay": false,
+	"BannedIPs": ["10.0.0.1", "10.0.0.2"],
+	"Listeners": [{"IPVersion": "tcp6", "Host": "::"}, {"Port": 9001}]
+}`
+
+const testYAML = `
+# 游戏服
+Name: game
+TCPPort: 9000
+maxPacketSize: 8192
+MsgMaxPacketSize:
+  1: 1024
+  2: 2048
+TCPNoDelay: false
+BannedIPs: [10.0.0.1, 10.0.0.2]
+Listeners:
+  - IPVersion: tcp6
+    Host: "::"
+  - Port: 9001
+`
+
+const testTOML = `
+# 游戏服
+Name = "game"
+TCPPort = 9_000
+maxPacketSize = 0x2000 # 8192
+TCPNoDelay = false
+BannedIPs = [
+	"10.0.0.1",
+	'10.0.0.2', # 结尾的逗号
+]
+
+[MsgMaxPacketSize]
+1 = 1024
+"2" = 2048
+
+[[Listeners]]
+IPVersion = "tcp6"
+Host = "::"
+
+[[Listeners]]
+Port = 9001
+`
+
+func TestUnmarshalFormats(t *testing.T) {
+	var expected Config
+	assert.Nil(t, Unmarshal(FormatJSON, []byte(testJSON), &expected))
+	assert.Equal(t, 9000, expected.TCPPort)
+
+	for format, data := range map[string]string{FormatYAML: testYAML, FormatTOML: testTOML} {
+		conf := Config{LogDir: "./log"}
+		assert.Nil(t, Unmarshal(format, []byte(data), &conf), format)
+		assert.Equal(t, "./log", conf.LogDir, format)
+		conf.LogDir = ""
+		assert.Equal(t, expected, conf, format)
+	}
+
+	var conf Config
+	assert.Nil(t, Unmarshal(FormatYAML, []byte(""), &conf))
+	assert.NotNil(t, Unmarshal(FormatYAML, []byte("TCPPort: [1"), &conf))
+	assert.NotNil(t, Unmarshal("ini", []byte(""), &conf))
+
+	assert.Equal(t, FormatYAML, FormatOf("conf/zinx.yml"))
+	assert.Equal(t, FormatTOML, FormatOf("conf/zinx.TOML"))
+	assert.Equal(t, FormatJSON, FormatOf("conf/zinx.json"))
+}
+
+func TestDecodeTOML(t *testing.T) {
+	values, err := decodeTOML(`
+a.b = "x\ty\u00e9"
+c = { d = 1.5, "e f" = true }
+g = """
+line1
+line2 \
+  continued"""
+h = '''C:\path'''
+i = -3
+
+[a.sub]
+j = []
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{
+			"b":   "x\tyé",
+			"sub": map[string]interface{}{"j": []interface{}{}},
+		},
+		"c": map[string]interface{}{"d": 1.5, "e f": true},
+		"g": "line1\nline2 continued",
+		"h": `C:\path`,
+		"i": int64(-3),
+	}, values)
+
+	for _, data := range []string{
+		"a = 1\na = 2",
+		"a = ",
+		"a = \"x",
+		"a = [1 2]",
+		"a = 1 b = 2",
+		"a = 1\n[a]",
+		"[a\nb = 1",
+		"a = 2023-01-01T00:00:00Z",
+	} {
+		_, err := decodeTOML(data)
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"duplicate key", "a = 1\na = 2", "line 2: duplicate key a"},
+		{"duplicate dotted key", "a.b = 1\na.b = 2", "duplicate key a.b"},
+		{"duplicate key in table", "[a]\nb = 1\nb = 2", "duplicate key b"},
+		{"duplicate key in inline table", "a = { b = 1, b = 2 }", "duplicate key b"},
+		{"key redefined as table", "a = 1\n[a]", "key a is not a table"},
+		{"table redefined", "[a]\nb = 1\n[a]", "line 3: table a already defined"},
+		{"sub table redefined", "[a.b]\n[a]\n[a.b]", "table a.b already defined"},
+		{"dotted table redefined", "[a]\nb.c = 1\n[a.b]", "table a.b already defined"},
+		{"table extended by dotted key", "[a.b]\nc = 1\n[a]\nb.d = 2", "table b already defined"},
+		{"inline table extended by dotted key", "a = { b = 1 }\na.c = 2", "inline table a cannot be extended"},
+		{"inline table extended by table", "a = { b = 1 }\n[a.c]", "inline table a cannot be extended"},
+		{"inline table redefined", "a = { b = 1 }\n[a]", "inline table a cannot be extended"},
+		{"unterminated basic string", `a = "x`, "unterminated string"},
+		{"unterminated string at end of line", "a = \"x\nb = 1\"", "line 1: unterminated string"},
+		{"unterminated literal string", "a = 'x", "unterminated string"},
+		{"unterminated multiline string", "a = \"\"\"x\ny", "unterminated string"},
+		{"unterminated multiline literal string", "a = '''x", "unterminated string"},
+		{"unterminated quoted key", `"a = 1`, "unterminated string"},
+		{"unterminated array", "a = [1, 2", "unterminated array"},
+		{"unterminated multiline array", "a = [\n1,\n2,\n", "line 4: unterminated array"},
+		{"unterminated inline table", "a = { b = 1", "unterminated inline table"},
+		{"unterminated table name", "[a\nb = 1", "expected ] after table name"},
+		{"invalid escape", `a = "\x"`, `invalid escape \x`},
+		{"invalid escape in multiline string", `a = """\q"""`, `invalid escape \q`},
+		{"short unicode escape", `a = "\u12"`, "invalid unicode escape"},
+		{"truncated unicode escape", `a = "\U0001`, "invalid unicode escape"},
+		{"surrogate unicode escape", `a = "\uD800"`, "invalid unicode escape"},
+		{"escape at end of input", `a = "\`, "unterminated string"},
+		{"missing value", "a = ", "missing value"},
+		{"missing comma", "a = [1 2]", "expected , or ] in array"},
+		{"value after value", "a = 1 b = 2", "unexpected"},
+		{"datetime", "a = 2023-01-01T00:00:00Z", "invalid value"},
+	} {
+		_, err := decodeTOML(tc.data)
+		if assert.NotNil(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.err, tc.name)
+		}
+	}
+
+	// 合法的表定义方式: 先定义子表再定义上级表, 点分键创建的表中添加子表, 表数组中的子表
+	values, err := decodeTOML(`
+[a.b.c]
+x = 1
+[a]
+y = 2
+[fruit]
+apple.color = "red"
+[fruit.apple.texture]
+smooth = true
+[[items]]
+name = "a"
+[items.sub]
+n = 1
+[[items]]
+name = "b"
+[items.sub]
+n = 2
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"x": int64(1)}, values["a"].(map[string]interface{})["b"].(map[string]interface{})["c"])
+	assert.Equal(t, map[string]interface{}{"smooth": true}, values["fruit"].(map[string]interface{})["apple"].(map[string]interface{})["texture"])
+	assert.Len(t, values["items"], 2)
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zconf")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// zinx.json不存在时使用同名的zinx.yaml
+	jsonPath := filepath.Join(dir, "zinx.json")
+	path, ok := findConfigFile(jsonPath)
+	assert.False(t, ok)
+	assert.Equal(t, jsonPath, path)
+
+	yamlPath := filepath.Join(dir, "zinx.yaml")
+	assert.Nil(t, ioutil.WriteFile(yamlPath, []byte(testYAML), 0644))
+	path, ok = findConfigFile(jsonPath)
+	assert.True(t, ok)
+	assert.Equal(t, yamlPath, path)
+
+	var conf Config
+	assert.Nil(t, conf.Load(path))
+	assert.Equal(t, 9000, conf.TCPPort)
+	assert.Equal(t, uint32(2048), conf.MaxPacketSizeOf(2))
+
+	tomlPath := filepath.Join(dir, "zinx.toml")
+	assert.Nil(t, ioutil.WriteFile(tomlPath, []byte(testTOML), 0644))
+	conf = Config{}
+	assert.Nil(t, conf.Load(tomlPath))
+	assert.Equal(t, "game", conf.Name)
+	assert.Equal(t, 2, len(conf.Listeners))
+}
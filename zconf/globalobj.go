@@ -11,7 +11,7 @@
 package zconf
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/aceld/zinx/utils/commandline/args"
 	"github.com/aceld/zinx/utils/commandline/uflag"
@@ -25,7 +25,8 @@ import (
 
 /*
 存储一切有关Zinx框架的全局参数，供其他模块使用
-一些参数也可以通过 用户根据 zinx.json来配置
+一些参数也可以通过 用户根据 zinx.json(或zinx.yaml、zinx.toml)来配置
+环境变量(如ZINX_TCP_PORT、ZINX_MAX_CONN)覆盖配置文件中的值, 名称规则见EnvName
 */
 type Config struct {
 	/*
@@ -34,17 +35,22 @@ type Config struct {
 	Host    string //当前服务器主机IP
 	TCPPort int    //当前服务器主机监听端口号
 	Name    string //当前服务器名称
+	Profile string //当前的环境(如dev、staging、prod), 配置中Profiles下该环境的配置合并到基础配置之上
+
+	IPVersion string       //监听使用的网络类型: tcp(IPv4/IPv6双栈), tcp4, tcp6
+	Listeners []ListenAddr //附加绑定的地址, 如IPv4与IPv6分别使用tcp4与tcp6监听
 
 	/*
 		Zinx
 	*/
-	Version          string //当前Zinx版本号
-	MaxPacketSize    uint32 //读写数据包的最大值
-	MaxConn          int    //当前服务器主机允许的最大链接个数
-	WorkerPoolSize   uint32 //业务工作Worker池的数量
-	MaxWorkerTaskLen uint32 //业务工作Worker对应负责的任务队列最大任务存储数量
-	MaxMsgChanLen    uint32 //SendBuffMsg发送消息的缓冲最大长度
-	IOReadBuffSize   uint32 //每次IO最大的读取长度
+	Version          string            //当前Zinx版本号
+	MaxPacketSize    uint32            //读写数据包的最大值
+	MsgMaxPacketSize map[uint32]uint32 //按消息ID单独限制的数据包最大值(如聊天文本4KB、文件分片1MB)，未配置的MsgID使用MaxPacketSize
+	MaxConn          int               //当前服务器主机允许的最大链接个数
+	WorkerPoolSize   uint32            //业务工作Worker池的数量
+	MaxWorkerTaskLen uint32            //业务工作Worker对应负责的任务队列最大任务存储数量
+	MaxMsgChanLen    uint32            //SendBuffMsg发送消息的缓冲最大长度
+	IOReadBuffSize   uint32            //每次IO最大的读取长度
 
 	/*
 		logger
@@ -63,6 +69,84 @@ type Config struct {
 	*/
 	CertFile       string // 证书文件名称 默认""
 	PrivateKeyFile string // 私钥文件名称 默认"" --如果没有设置证书和私钥文件，则不启用TLS加密
+
+	PrivateKeyPassword string `secret:"true"` // 加密的私钥文件(PEM)的密码, 默认""
+
+	/*
+		TCP socket, 应用到新接受的TCP连接
+	*/
+	TCPNoDelay           *bool // 是否设置TCP_NODELAY(禁用Nagle算法), 不设置时使用Go的默认值(禁用Nagle)
+	TCPKeepAlive         int   // 开始发送保活探测前的空闲时间(单位：秒), 0使用默认(15秒), 小于0关闭保活
+	TCPKeepAliveInterval int   // 保活探测的间隔时间(单位：秒), 0使用默认
+	TCPKeepAliveCount    int   // 保活探测连续失败多少次后断开连接, 0使用系统默认
+	TCPReadBuffer        int   // SO_RCVBUF(单位：字节), 0使用系统默认
+	TCPWriteBuffer       int   // SO_SNDBUF(单位：字节), 0使用系统默认
+	TCPLinger            int   // SO_LINGER(单位：秒), 0使用默认(关闭后在后台发送剩余数据), 小于0关闭时丢弃未发送的数据
+
+	/*
+		Admin
+	*/
+	AdminAddr  string // 管理HTTP服务的监听地址(如"127.0.0.1:9090"), 默认"" --为空时不启用
+	AdminToken string `secret:"true"` // 访问管理HTTP服务的令牌, 请求需携带 "Authorization: Bearer <token>"
+
+	AdminDashboard bool // 是否在管理HTTP服务中提供Web控制台(/admin/ui), 默认false
+
+	/*
+		Limits
+	*/
+	ConnReadRate    int      // 每个连接的读带宽(单位：字节/秒), 0不限制
+	ConnWriteRate   int      // 每个连接的写带宽(单位：字节/秒), 0不限制
+	ServerReadRate  int      // 全部连接共享的读带宽(单位：字节/秒), 0不限制
+	ServerWriteRate int      // 全部连接共享的写带宽(单位：字节/秒), 0不限制
+	BannedIPs       []string `env:"BANNED_IPS"` // 禁止建立连接的IP
+
+	/*
+		Worker auto scaling
+	*/
+	WorkerPoolMaxSize   uint32 // 大于WorkerPoolSize时Worker数量在WorkerPoolSize与该值之间按负载自动伸缩, 0不伸缩
+	WorkerScaleWaitTime int    // 任务平均等待时间(单位：毫秒)超过该值时增加Worker, 0使用默认(50毫秒)
+	WorkerStealing      bool   // 空闲的Worker是否从其他Worker的任务队列中窃取任务(不伸缩时有效), 默认false
+	WorkerDispatchMode  string // Worker选择策略: affinity(默认)、hash、round_robin、least_loaded
+
+	/*
+		Slow handler
+	*/
+	SlowHandlerTime int // 路由处理时间(单位：毫秒)超过该值时记录日志并发布SlowHandler事件, 0不检测
+	SlowLogSize     int // 慢日志保存处理时间最长的请求数量, 0不记录
+	SlowLogPayload  int // 慢日志保存的消息内容的最大字节数, 默认0不保存
+
+	/*
+		StatsD
+	*/
+	StatsDAddr     string   `env:"STATSD_ADDR"`     // StatsD的UDP地址(如"127.0.0.1:8125"), 默认"" --为空时不推送
+	StatsDPrefix   string   `env:"STATSD_PREFIX"`   // 指标名称前缀, 默认""
+	StatsDTags     []string `env:"STATSD_TAGS"`     // 附加到全部指标的标签, 格式"key:value"
+	DogStatsD      bool     `env:"DOGSTATSD"`       // 是否使用DogStatsD的标签格式, 默认false
+	StatsDInterval int      `env:"STATSD_INTERVAL"` // 推送服务运行状态的间隔(单位：秒), 0使用默认(10秒)
+
+	/*
+		Registry
+	*/
+	Registry          string   // 注册中心类型: etcd、consul、nacos, 默认"" --为空时不注册
+	RegistryAddrs     []string // 注册中心的地址, 如"http://127.0.0.1:2379"
+	RegistryNamespace string   // etcd的键前缀或Nacos的命名空间ID
+	RegistryToken     string   `secret:"true"` // Consul的ACL令牌
+	RegistryTTL       int      // 注册的租约时间(单位：秒), 0使用默认(10秒)
+	ServiceName       string   // 注册的服务名称, 默认为Name
+	ServiceAddr       string   // 注册的访问地址, 默认为Host与TCPPort, 监听0.0.0.0时需要设置
+
+	/*
+		Timer
+	*/
+	TimerTick   int   // 时间轮最底层的刻度间隔, 即定时器的精度(单位：毫秒), 0使用默认(1000毫秒)
+	TimerScales []int // 从最底层开始每层时间轮的刻度数, 层数为元素个数, 默认[60, 60, 12](秒、分钟、小时三层)
+}
+
+// ListenAddr 附加绑定的地址, 与主端口共享路由
+type ListenAddr struct {
+	IPVersion string //tcp,tcp4,tcp6, 为空时与IPVersion一致
+	Host      string //绑定的IP地址, 如"::"
+	Port      int    //绑定的端口, 为0时使用TCPPort
 }
 
 /*
@@ -82,21 +166,53 @@ func PathExists(path string) (bool, error) {
 	return false, err
 }
 
+// Load 从配置文件加载配置并合并当前环境(Profile)的配置, 配置文件中没有的字段保持原值
+// 按扩展名识别格式: .json、.yaml/.yml、.toml
+func (g *Config) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return g.unmarshal(FormatOf(path), data)
+}
+
+// LoadOverrides 加载配置文件(或配置来源)之后, 依次用环境变量与命令行参数覆盖配置, 然后读取密钥字段引用的密钥
+func (g *Config) LoadOverrides() error {
+	if err := g.LoadEnv(EnvPrefix()); err != nil {
+		return err
+	}
+	if err := g.LoadFlags(); err != nil {
+		return err
+	}
+	return g.ResolveSecrets()
+}
+
 // Reload 读取用户的配置文件
 func (g *Config) Reload() {
-	confFilePath := args.Args.ConfigFile
-	if confFileExists, _ := PathExists(confFilePath); confFileExists != true {
+	confFilePath, confFileExists := findConfigFile(args.Args.ConfigFile)
+	if confFileExists != true {
 		zlog.Ins().ErrorF("Config File %s is not exist!!", confFilePath)
-		return
+	} else {
+		//找到其他格式的同名配置文件时, 之后重新加载配置也使用该文件
+		args.Args.ConfigFile = confFilePath
+
+		data, err := ioutil.ReadFile(confFilePath)
+		if err != nil {
+			panic(err)
+		}
+		//将json、yaml或toml数据解析到struct中, 并合并当前环境的配置
+		err = g.unmarshal(FormatOf(confFilePath), data)
+		if err != nil {
+			panic(err)
+		}
 	}
 
-	data, err := ioutil.ReadFile(confFilePath)
-	if err != nil {
+	//环境变量与命令行参数覆盖配置文件中的值, 没有配置文件时也生效; 读取引用的密钥
+	if err := g.LoadOverrides(); err != nil {
 		panic(err)
 	}
-	//将json数据解析到struct中
-	err = json.Unmarshal(data, g)
-	if err != nil {
+	//一次报告全部错误的配置, 而不是运行中才暴露
+	if err := g.Validate(); err != nil {
 		panic(err)
 	}
 
@@ -120,11 +236,24 @@ func (g *Config) Show() {
 		field := objVal.Field(i)
 		typeField := objType.Field(i)
 
+		//不输出密钥的值
+		if typeField.Tag.Get("secret") == "true" && !field.IsZero() {
+			fmt.Printf("%s: ******\n", typeField.Name)
+			continue
+		}
 		fmt.Printf("%s: %v\n", typeField.Name, field.Interface())
 	}
 	fmt.Println("==============================")
 }
 
+// MaxPacketSizeOf 获取指定消息ID允许的数据包最大值, 0表示不限制
+func (g *Config) MaxPacketSizeOf(msgID uint32) uint32 {
+	if size, ok := g.MsgMaxPacketSize[msgID]; ok {
+		return size
+	}
+	return g.MaxPacketSize
+}
+
 func (g *Config) HeartbeatMaxDuration() time.Duration {
 	return time.Duration(g.HeartbeatMax) * time.Second
 }
@@ -140,6 +269,8 @@ func init() {
 
 	// 初始化配置模块flag
 	args.InitConfigFlag(pwd+"/conf/zinx.json", "配置文件，如果没有设置，则默认为<exeDir>/conf/zinx.json")
+	// 每个配置字段对应的命令行参数, 如--tcp-port、--log-dir
+	bindFlags(flag.CommandLine)
 	// 初始化日志模块flag TODO
 
 	// 解析
@@ -154,6 +285,7 @@ func init() {
 		Name:              "ZinxServerApp",
 		Version:           "V1.0",
 		TCPPort:           8999,
+		IPVersion:         "tcp",
 		Host:              "0.0.0.0",
 		MaxConn:           12000,
 		MaxPacketSize:     4096,
@@ -0,0 +1,67 @@
+package zconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// profilesKey 配置中各个环境的差异配置所在的键, 如
+//
+//	{"TCPPort": 8999, "Profile": "dev", "Profiles": {"prod": {"MaxConn": 20000, "LogIsolationLevel": 1}}}
+const profilesKey = "Profiles"
+
+// profileName 当前选择的环境: 命令行参数--profile优先, 其次为环境变量ZINX_PROFILE, 最后为配置中的Profile
+func profileName(base string) string {
+	field, _ := reflect.TypeOf(Config{}).FieldByName("Profile")
+	if value, ok := flagValues[field.Name]; ok {
+		return value
+	}
+	if value, ok := os.LookupEnv(EnvName(EnvPrefix(), field)); ok {
+		return value
+	}
+	return base
+}
+
+// unmarshal 解析配置, 然后将选择的环境在Profiles中的配置合并到基础配置之上
+// 只需写出与基础配置不同的字段, 映射类型的字段(如MsgMaxPacketSize)按键合并; properties格式不支持Profiles
+func (g *Config) unmarshal(format string, data []byte) error {
+	if err := Unmarshal(format, data, g); err != nil {
+		return err
+	}
+	g.Profile = profileName(g.Profile)
+	if g.Profile == "" || format == FormatProperties {
+		return nil
+	}
+
+	var values map[string]interface{}
+	if err := Unmarshal(format, data, &values); err != nil {
+		return err
+	}
+	var profiles map[string]interface{}
+	for key, value := range values {
+		if strings.EqualFold(key, profilesKey) {
+			profiles, _ = value.(map[string]interface{})
+		}
+	}
+	// 没有Profiles的配置(如配置中心中已经区分环境的配置)不需要合并
+	if profiles == nil {
+		return nil
+	}
+	overlay, ok := profiles[g.Profile]
+	if !ok {
+		return fmt.Errorf("profile %q not found in %s", g.Profile, profilesKey)
+	}
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return err
+	}
+	profile := g.Profile
+	if err := json.Unmarshal(data, g); err != nil {
+		return fmt.Errorf("profile %q: %v", profile, err)
+	}
+	g.Profile = profile
+	return nil
+}
@@ -0,0 +1,59 @@
+package zconf
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	data := []byte(`{
+	"TCPPort": 8999,
+	"MaxConn": 100,
+	"MsgMaxPacketSize": {"1": 1024},
+	"Profile": "dev",
+	"Profiles": {
+		"dev": {"LogIsolationLevel": 0},
+		"prod": {"MaxConn": 20000, "MsgMaxPacketSize": {"2": 4096}, "Profile": "other"}
+	}
+}`)
+
+	// 配置中的Profile
+	conf := &Config{}
+	assert.Nil(t, conf.unmarshal(FormatJSON, data))
+	assert.Equal(t, "dev", conf.Profile)
+	assert.Equal(t, 100, conf.MaxConn)
+
+	// 环境变量选择环境, 只覆盖不同的字段, 映射按键合并
+	assert.Nil(t, os.Setenv("ZINX_PROFILE", "prod"))
+	defer os.Unsetenv("ZINX_PROFILE")
+	conf = &Config{}
+	assert.Nil(t, conf.unmarshal(FormatJSON, data))
+	assert.Equal(t, "prod", conf.Profile)
+	assert.Equal(t, 8999, conf.TCPPort)
+	assert.Equal(t, 20000, conf.MaxConn)
+	assert.Equal(t, map[uint32]uint32{1: 1024, 2: 4096}, conf.MsgMaxPacketSize)
+
+	// 命令行参数优先于环境变量
+	saved := flagValues
+	flagValues = map[string]string{"Profile": "staging"}
+	defer func() { flagValues = saved }()
+	conf = &Config{}
+	assert.NotNil(t, conf.unmarshal(FormatJSON, data))
+
+	// 没有Profiles的配置不需要合并
+	conf = &Config{}
+	assert.Nil(t, conf.unmarshal(FormatJSON, []byte(`{"MaxConn": 5}`)))
+	assert.Equal(t, "staging", conf.Profile)
+	assert.Equal(t, 5, conf.MaxConn)
+
+	// YAML与TOML
+	flagValues = map[string]string{"Profile": "prod"}
+	conf = &Config{}
+	assert.Nil(t, conf.unmarshal(FormatYAML, []byte("MaxConn: 100\nProfiles:\n  prod:\n    MaxConn: 300\n")))
+	assert.Equal(t, 300, conf.MaxConn)
+	conf = &Config{}
+	assert.Nil(t, conf.unmarshal(FormatTOML, []byte("MaxConn = 100\n\n[Profiles.prod]\nMaxConn = 400\n")))
+	assert.Equal(t, 400, conf.MaxConn)
+}
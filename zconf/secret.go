@@ -0,0 +1,178 @@
+package zconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SecretResolver 按引用读取密钥的值, ref为引用中冒号之后的部分, 如"${vault:secret/data/zinx#token}"中的"secret/data/zinx#token"
+type SecretResolver func(ctx context.Context, ref string) (string, error)
+
+var (
+	secretLock      sync.RWMutex
+	secretResolvers = map[string]SecretResolver{
+		"env":   resolveEnvSecret,
+		"file":  resolveFileSecret,
+		"vault": resolveVaultSecret,
+	}
+)
+
+// RegisterSecretResolver 注册密钥的读取方式, 如云厂商的KMS, 之后配置中可以使用"${scheme:ref}"引用
+// 内置env(环境变量)、file(文件内容, 如Kubernetes Secret与Docker Secret)与vault(HashiCorp Vault的KV引擎)
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretLock.Lock()
+	defer secretLock.Unlock()
+	secretResolvers[scheme] = resolver
+}
+
+// parseSecretRef 解析"${scheme:ref}"形式的引用, 其他值原样使用
+func parseSecretRef(value string) (scheme, ref string, ok bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", "", false
+	}
+	inner := value[2 : len(value)-1]
+	sep := strings.Index(inner, ":")
+	if sep <= 0 {
+		return "", "", false
+	}
+	return inner[:sep], inner[sep+1:], true
+}
+
+// ResolveSecret 读取"${scheme:ref}"引用的密钥, 不是引用的值原样返回
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := parseSecretRef(value)
+	if !ok {
+		return value, nil
+	}
+	secretLock.RLock()
+	resolver, ok := secretResolvers[scheme]
+	secretLock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown secret resolver %q", scheme)
+	}
+	secret, err := resolver(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %s:%s: %v", scheme, ref, err)
+	}
+	return secret, nil
+}
+
+// ResolveSecrets 读取结构体v中标记了`secret:"true"`的字符串(或字符串数组)字段引用的密钥, v为结构体指针
+// 密钥不以明文出现在配置文件中, 如AdminToken配置为"${env:ZINX_ADMIN_TOKEN}"或"${file:/run/secrets/admin_token}";
+// 其他模块的配置(如Redis密码)同样可以使用该标签
+func ResolveSecrets(ctx context.Context, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("resolve secrets: %T is not a struct pointer", v)
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		value := rv.Field(i)
+		switch {
+		case value.Kind() == reflect.String:
+			secret, err := ResolveSecret(ctx, value.String())
+			if err != nil {
+				return fmt.Errorf("%s: %v", field.Name, err)
+			}
+			value.SetString(secret)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < value.Len(); j++ {
+				secret, err := ResolveSecret(ctx, value.Index(j).String())
+				if err != nil {
+					return fmt.Errorf("%s[%d]: %v", field.Name, j, err)
+				}
+				value.Index(j).SetString(secret)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveSecrets 读取配置中密钥字段(AdminToken、PrivateKeyPassword等)引用的密钥
+func (g *Config) ResolveSecrets() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultSourceTimeout)
+	defer cancel()
+	return ResolveSecrets(ctx, g)
+}
+
+// Redact 去掉密钥字段的值, 用于输出或返回配置
+func (g *Config) Redact() {
+	v := reflect.ValueOf(g).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("secret") == "true" {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+}
+
+func resolveEnvSecret(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable not set")
+	}
+	return value, nil
+}
+
+// resolveFileSecret 文件的内容, 去掉末尾的换行
+func resolveFileSecret(ctx context.Context, path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveVaultSecret 读取Vault的KV引擎中的密钥, ref格式为"路径#键", 如"secret/data/zinx#admin_token"
+// 地址与令牌由环境变量VAULT_ADDR、VAULT_TOKEN设置, 企业版的命名空间由VAULT_NAMESPACE设置; 同时支持KV v1与v2
+func resolveVaultSecret(ctx context.Context, ref string) (string, error) {
+	sep := strings.LastIndex(ref, "#")
+	if sep < 0 {
+		return "", errors.New(`vault ref must be "path#key"`)
+	}
+	path, key := strings.Trim(ref[:sep], "/"), ref[sep+1:]
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR not set")
+	}
+
+	header := http.Header{"X-Vault-Token": {os.Getenv("VAULT_TOKEN")}}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		header.Set("X-Vault-Namespace", namespace)
+	}
+	_, data, err := sourceHTTP(ctx, http.DefaultClient, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+path, header, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	values := resp.Data
+	// KV v2的值在data.data中
+	if nested, ok := values["data"].(map[string]interface{}); ok {
+		values = nested
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,83 @@
+package zconf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zconf-secret")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		switch r.URL.Path {
+		case "/v1/secret/data/zinx":
+			fmt.Fprint(w, `{"data":{"data":{"key_password":"v2-pass"},"metadata":{"version":1}}}`)
+		case "/v1/kv/zinx":
+			fmt.Fprint(w, `{"data":{"token":"v1-token"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+	assert.Nil(t, os.Setenv("VAULT_ADDR", vault.URL))
+	assert.Nil(t, os.Setenv("VAULT_TOKEN", "vault-token"))
+	assert.Nil(t, os.Setenv("ZINX_TEST_SECRET", "env-token"))
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+	defer os.Unsetenv("ZINX_TEST_SECRET")
+
+	conf := &Config{
+		Name:               "${env:ZINX_TEST_SECRET}",
+		AdminToken:         "${file:" + tokenFile + "}",
+		PrivateKeyPassword: "${vault:secret/data/zinx#key_password}",
+		RegistryToken:      "${vault:kv/zinx#token}",
+	}
+	assert.Nil(t, conf.ResolveSecrets())
+	// 只有标记为密钥的字段被解析
+	assert.Equal(t, "${env:ZINX_TEST_SECRET}", conf.Name)
+	assert.Equal(t, "file-token", conf.AdminToken)
+	assert.Equal(t, "v2-pass", conf.PrivateKeyPassword)
+	assert.Equal(t, "v1-token", conf.RegistryToken)
+
+	RegisterSecretResolver("kms", func(ctx context.Context, ref string) (string, error) {
+		return "decrypted:" + ref, nil
+	})
+	secret, err := ResolveSecret(context.Background(), "${kms:abc}")
+	assert.Nil(t, err)
+	assert.Equal(t, "decrypted:abc", secret)
+	secret, err = ResolveSecret(context.Background(), "plain")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", secret)
+
+	for _, value := range []string{"${env:ZINX_MISSING_SECRET}", "${unknown:x}", "${vault:secret/data/zinx#missing}", "${vault:nokey}"} {
+		_, err := ResolveSecret(context.Background(), value)
+		assert.NotNil(t, err, value)
+	}
+
+	// 其他模块的配置
+	var redis struct {
+		Addr     string
+		Password string `secret:"true"`
+	}
+	redis.Password = "${env:ZINX_TEST_SECRET}"
+	assert.Nil(t, ResolveSecrets(context.Background(), &redis))
+	assert.Equal(t, "env-token", redis.Password)
+
+	conf.Redact()
+	assert.Empty(t, conf.AdminToken)
+	assert.Empty(t, conf.PrivateKeyPassword)
+	assert.Equal(t, "${env:ZINX_TEST_SECRET}", conf.Name)
+}
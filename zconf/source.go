@@ -0,0 +1,114 @@
+package zconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultSourceTimeout 从配置来源读取配置的默认超时时间
+const DefaultSourceTimeout = 5 * time.Second
+
+// FileSource 本地配置文件, 格式按扩展名识别
+type FileSource struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFileSource 创建配置文件来源, interval为不支持文件通知的系统上检查文件的间隔, 为0时使用DefaultWatchInterval
+func NewFileSource(path string, interval time.Duration) *FileSource {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &FileSource{path: path, interval: interval}
+}
+
+func (s *FileSource) Name() string {
+	return s.path
+}
+
+func (s *FileSource) Read(ctx context.Context) ([]byte, string, error) {
+	data, err := ioutil.ReadFile(s.path)
+	return data, FormatOf(s.path), err
+}
+
+// Watch Linux上使用inotify监听, 其他系统按间隔检查文件的修改时间
+func (s *FileSource) Watch(ctx context.Context, notify func()) {
+	watchFile(s.path, s.interval, notify, ctx.Done())
+	notify()
+	<-ctx.Done()
+}
+
+// LoadSource 从配置来源加载配置, 配置中没有的字段保持原值
+func (g *Config) LoadSource(ctx context.Context, source ziface.IConfigSource) error {
+	data, format, err := source.Read(ctx)
+	if err != nil {
+		return err
+	}
+	return g.unmarshal(format, data)
+}
+
+// UseSource 从配置来源(如etcd、Nacos、Apollo配置中心)加载配置到GlobalObject, 需在创建Server之前调用
+// 配置文件中的值作为基础, 之后仍然由环境变量与命令行参数覆盖并校验, 失败时GlobalObject保持不变
+// 大量服务实例共享配置中心的同一份配置, 镜像中无需携带配置文件; 运行中的变化见Server.WatchConfigSource
+func UseSource(source ziface.IConfigSource) error {
+	conf, err := loadConfig(GlobalObject, source)
+	if err != nil {
+		return err
+	}
+	if err := SetGlobal(conf); err != nil {
+		return err
+	}
+	zlog.Ins().InfoF("config loaded from %s", source.Name())
+	return nil
+}
+
+// DefaultSourceWatchInterval 不支持长轮询的配置中心(如etcd的HTTP网关)检查配置是否变化的默认间隔
+const DefaultSourceWatchInterval = 5 * time.Second
+
+// sourceRetryInterval 访问配置中心失败后重试的间隔
+var sourceRetryInterval = 5 * time.Second
+
+// sourceHTTP 访问配置中心的HTTP接口, 返回状态码与内容, 2xx与304以外的状态码返回错误
+func sourceHTTP(ctx context.Context, client *http.Client, method, url string, header http.Header, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if res.StatusCode != http.StatusNotModified && (res.StatusCode < 200 || res.StatusCode >= 300) {
+		return res.StatusCode, data, fmt.Errorf("%s %s: %s %s", method, url, res.Status, bytes.TrimSpace(data))
+	}
+	return res.StatusCode, data, nil
+}
+
+// sleepContext 等待d, ctx结束时返回false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
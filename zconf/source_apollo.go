@@ -0,0 +1,175 @@
+package zconf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Apollo默认的集群与命名空间
+const (
+	DefaultApolloCluster   = "default"
+	DefaultApolloNamespace = "application"
+)
+
+// apolloLongPollTimeout Apollo通知接口的长轮询最长时间(服务端为60秒)
+var apolloLongPollTimeout = 90 * time.Second
+
+// ApolloSource 保存在Apollo配置中心的配置, 通过Apollo的Config Service接口访问
+// properties格式的命名空间(如application)中每个配置项为Config的一个字段, 值的格式与环境变量相同;
+// 其他格式的命名空间(如"zinx.yaml"、"zinx.json")使用命名空间的全部内容, 格式按扩展名识别
+type ApolloSource struct {
+	addr      string
+	appID     string
+	cluster   string
+	namespace string
+	secret    string
+	client    *http.Client
+}
+
+// NewApolloSource 创建Apollo配置来源, addr为Config Service的地址, 如"http://127.0.0.1:8080"
+// cluster与namespace为空时使用DefaultApolloCluster与DefaultApolloNamespace, secret为应用的访问密钥, 未开启访问密钥时为空
+func NewApolloSource(addr, appID, cluster, namespace, secret string) *ApolloSource {
+	if cluster == "" {
+		cluster = DefaultApolloCluster
+	}
+	if namespace == "" {
+		namespace = DefaultApolloNamespace
+	}
+	return &ApolloSource{
+		addr:      strings.TrimSuffix(addr, "/"),
+		appID:     appID,
+		cluster:   cluster,
+		namespace: namespace,
+		secret:    secret,
+		// 超时时间由每次请求的ctx控制, 长轮询需要比DefaultSourceTimeout更长的时间
+		client: &http.Client{},
+	}
+}
+
+func (s *ApolloSource) Name() string {
+	return "apollo:" + s.appID + "/" + s.namespace
+}
+
+// isProperties 没有扩展名或扩展名为.properties的命名空间为properties格式
+func (s *ApolloSource) isProperties() bool {
+	return !strings.Contains(s.namespace, ".") || strings.HasSuffix(s.namespace, ".properties")
+}
+
+// get 访问Apollo的接口, 开启访问密钥时按Apollo的规则签名
+func (s *ApolloSource) get(ctx context.Context, pathWithQuery string) (int, []byte, error) {
+	var header http.Header
+	if s.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha1.New, []byte(s.secret))
+		mac.Write([]byte(timestamp + "\n" + pathWithQuery))
+		header = http.Header{
+			"Authorization": {"Apollo " + s.appID + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))},
+			"Timestamp":     {timestamp},
+		}
+	}
+	return sourceHTTP(ctx, s.client, http.MethodGet, s.addr+pathWithQuery, header, nil)
+}
+
+type apolloConfigResp struct {
+	Configurations map[string]string `json:"configurations"`
+	ReleaseKey     string            `json:"releaseKey"`
+}
+
+func (s *ApolloSource) Read(ctx context.Context) ([]byte, string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSourceTimeout)
+		defer cancel()
+	}
+	path := "/configs/" + url.PathEscape(s.appID) + "/" + url.PathEscape(s.cluster) + "/" + url.PathEscape(s.namespace)
+	_, data, err := s.get(ctx, path)
+	if err != nil {
+		return nil, "", err
+	}
+	var resp apolloConfigResp
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, "", err
+	}
+
+	if !s.isProperties() {
+		return []byte(resp.Configurations["content"]), FormatOf(s.namespace), nil
+	}
+	keys := make([]string, 0, len(resp.Configurations))
+	for key := range resp.Configurations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key + "=" + strings.ReplaceAll(resp.Configurations[key], "\n", " ") + "\n")
+	}
+	return []byte(b.String()), FormatProperties, nil
+}
+
+type apolloNotification struct {
+	NamespaceName  string `json:"namespaceName"`
+	NotificationID int64  `json:"notificationId"`
+}
+
+// Watch 使用Apollo的长轮询通知接口, 第一次请求的通知ID为-1, 立即返回当前的通知ID
+func (s *ApolloSource) Watch(ctx context.Context, notify func()) {
+	// properties格式的命名空间在通知接口中不带扩展名
+	name := strings.TrimSuffix(s.namespace, ".properties")
+	var id int64 = -1
+	for {
+		changed, err := s.poll(ctx, name, &id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			zlog.Ins().ErrorF("[WATCH] %s err: %v", s.Name(), err)
+			if !sleepContext(ctx, sourceRetryInterval) {
+				return
+			}
+			continue
+		}
+		if changed {
+			notify()
+		}
+	}
+}
+
+// poll 长轮询等待命名空间的通知, 有新的通知时更新id并返回true, 超时没有变化时返回false(HTTP 304)
+func (s *ApolloSource) poll(ctx context.Context, name string, id *int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, apolloLongPollTimeout)
+	defer cancel()
+
+	notifications, err := json.Marshal([]apolloNotification{{NamespaceName: name, NotificationID: *id}})
+	if err != nil {
+		return false, err
+	}
+	query := url.Values{"appId": {s.appID}, "cluster": {s.cluster}, "notifications": {string(notifications)}}
+	status, data, err := s.get(ctx, "/notifications/v2?"+query.Encode())
+	if err != nil || status == http.StatusNotModified {
+		return false, err
+	}
+
+	var resp []apolloNotification
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return false, err
+	}
+	changed := false
+	for _, n := range resp {
+		if n.NamespaceName == name && n.NotificationID != *id {
+			*id = n.NotificationID
+			changed = true
+		}
+	}
+	return changed, nil
+}
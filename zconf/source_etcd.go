@@ -0,0 +1,107 @@
+package zconf
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// EtcdSource 保存在etcd中的配置, 通过etcd v3的HTTP/JSON网关访问
+// 配置格式按键的扩展名识别, 如"/zinx/config/game.yaml"为YAML, 没有扩展名时为JSON
+type EtcdSource struct {
+	endpoints []string
+	key       string
+	interval  time.Duration
+	client    *http.Client
+}
+
+// NewEtcdSource 创建etcd配置来源, endpoints为etcd的地址, 如"http://127.0.0.1:2379"
+// interval为检查配置是否变化的间隔, 为0时使用DefaultSourceWatchInterval
+func NewEtcdSource(endpoints []string, key string, interval time.Duration) *EtcdSource {
+	if interval <= 0 {
+		interval = DefaultSourceWatchInterval
+	}
+	return &EtcdSource{
+		endpoints: endpoints,
+		key:       key,
+		interval:  interval,
+		client:    &http.Client{Timeout: DefaultSourceTimeout},
+	}
+}
+
+func (s *EtcdSource) Name() string {
+	return "etcd:" + s.key
+}
+
+type etcdRangeResp struct {
+	Kvs []struct {
+		Value       string `json:"value"`
+		ModRevision int64  `json:"mod_revision,string"`
+	} `json:"kvs"`
+}
+
+// get 依次尝试每个endpoint读取键的值与修改版本, 键不存在时版本为0
+func (s *EtcdSource) get(ctx context.Context) ([]byte, int64, error) {
+	body, err := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(s.key))})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = errors.New("no etcd endpoint")
+	for _, endpoint := range s.endpoints {
+		var data []byte
+		_, data, err = sourceHTTP(ctx, s.client, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/v3/kv/range",
+			http.Header{"Content-Type": {"application/json"}}, body)
+		if err != nil {
+			continue
+		}
+		var resp etcdRangeResp
+		if err = json.Unmarshal(data, &resp); err != nil {
+			continue
+		}
+		if len(resp.Kvs) == 0 {
+			return nil, 0, nil
+		}
+		value, err := base64.StdEncoding.DecodeString(resp.Kvs[0].Value)
+		return value, resp.Kvs[0].ModRevision, err
+	}
+	return nil, 0, err
+}
+
+func (s *EtcdSource) Read(ctx context.Context) ([]byte, string, error) {
+	value, revision, err := s.get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if revision == 0 {
+		return nil, "", fmt.Errorf("etcd key %s not found", s.key)
+	}
+	return value, FormatOf(s.key), nil
+}
+
+// Watch 按间隔检查键的修改版本, HTTP网关的watch接口需要保持流式连接, 配置很少变化, 轮询更简单可靠
+func (s *EtcdSource) Watch(ctx context.Context, notify func()) {
+	var last int64 = -1
+	for {
+		_, revision, err := s.get(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			zlog.Ins().ErrorF("[WATCH] %s err: %v", s.Name(), err)
+		} else if revision != last {
+			last = revision
+			notify()
+		}
+		if !sleepContext(ctx, s.interval) {
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package zconf
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultNacosGroup Nacos配置默认的分组
+const DefaultNacosGroup = "DEFAULT_GROUP"
+
+// nacosLongPollTimeout Nacos监听配置的长轮询时间
+var nacosLongPollTimeout = 30 * time.Second
+
+// NacosSource 保存在Nacos配置中心的配置, 通过Nacos的Open API访问
+// 配置格式按dataId的扩展名识别, 如"zinx.yaml"为YAML, 没有扩展名时为JSON
+type NacosSource struct {
+	addrs     []string
+	namespace string
+	group     string
+	dataID    string
+	client    *http.Client
+}
+
+// NewNacosSource 创建Nacos配置来源, addrs为Nacos的地址, 如"http://127.0.0.1:8848"
+// namespace为命名空间ID, 为空时使用public命名空间; group为空时使用DefaultNacosGroup
+func NewNacosSource(addrs []string, namespace, group, dataID string) *NacosSource {
+	if group == "" {
+		group = DefaultNacosGroup
+	}
+	return &NacosSource{
+		addrs:     addrs,
+		namespace: namespace,
+		group:     group,
+		dataID:    dataID,
+		// 超时时间由每次请求的ctx控制, 长轮询需要比DefaultSourceTimeout更长的时间
+		client: &http.Client{},
+	}
+}
+
+func (s *NacosSource) Name() string {
+	return "nacos:" + s.group + "/" + s.dataID
+}
+
+// call 依次尝试每个地址调用Nacos的Open API
+func (s *NacosSource) call(ctx context.Context, method, path string, header http.Header, body []byte) ([]byte, error) {
+	err := errors.New("no nacos address")
+	for _, addr := range s.addrs {
+		var data []byte
+		_, data, err = sourceHTTP(ctx, s.client, method, strings.TrimSuffix(addr, "/")+path, header, body)
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (s *NacosSource) Read(ctx context.Context) ([]byte, string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSourceTimeout)
+		defer cancel()
+	}
+	query := url.Values{"dataId": {s.dataID}, "group": {s.group}}
+	if s.namespace != "" {
+		query.Set("tenant", s.namespace)
+	}
+	data, err := s.call(ctx, http.MethodGet, "/nacos/v1/cs/configs?"+query.Encode(), nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, FormatOf(s.dataID), nil
+}
+
+// Watch 使用Nacos的长轮询监听接口, 请求携带当前内容的MD5, 配置变化时Nacos立即返回变化的dataId
+func (s *NacosSource) Watch(ctx context.Context, notify func()) {
+	var sum string
+	changed := true
+	for {
+		if changed {
+			data, _, err := s.Read(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				zlog.Ins().ErrorF("[WATCH] %s err: %v", s.Name(), err)
+				if !sleepContext(ctx, sourceRetryInterval) {
+					return
+				}
+				continue
+			}
+			hash := md5.Sum(data)
+			sum = hex.EncodeToString(hash[:])
+			changed = false
+			notify()
+		}
+
+		var err error
+		changed, err = s.listen(ctx, sum)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			zlog.Ins().ErrorF("[WATCH] %s err: %v", s.Name(), err)
+			if !sleepContext(ctx, sourceRetryInterval) {
+				return
+			}
+		}
+	}
+}
+
+// listen 长轮询等待配置变化, 超时没有变化时返回false
+func (s *NacosSource) listen(ctx context.Context, sum string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, nacosLongPollTimeout+DefaultSourceTimeout)
+	defer cancel()
+
+	// 格式为dataId^2group^2contentMD5^2tenant^1, 没有命名空间时省略tenant
+	listening := s.dataID + "\x02" + s.group + "\x02" + sum
+	if s.namespace != "" {
+		listening += "\x02" + s.namespace
+	}
+	listening += "\x01"
+	header := http.Header{
+		"Content-Type":         {"application/x-www-form-urlencoded"},
+		"Long-Pulling-Timeout": {fmt.Sprint(nacosLongPollTimeout.Milliseconds())},
+	}
+	body := url.Values{"Listening-Configs": {listening}}.Encode()
+	data, err := s.call(ctx, http.MethodPost, "/nacos/v1/cs/configs/listener", header, []byte(body))
+	if err != nil {
+		return false, err
+	}
+	return len(strings.TrimSpace(string(data))) > 0, nil
+}
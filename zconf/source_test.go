@@ -0,0 +1,257 @@
+package zconf
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalProperties(t *testing.T) {
+	conf := &Config{Name: "old", MaxConn: 10}
+	data := "# comment\nmaxconn = 100\nBannedIPs: 10.0.0.1, 10.0.0.2\nUnknown=1\n\n"
+	assert.Nil(t, Unmarshal(FormatProperties, []byte(data), conf))
+	assert.Equal(t, 100, conf.MaxConn)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, conf.BannedIPs)
+	assert.Equal(t, "old", conf.Name)
+
+	assert.NotNil(t, Unmarshal(FormatProperties, []byte("MaxConn=abc"), conf))
+	assert.NotNil(t, Unmarshal(FormatProperties, []byte("MaxConn"), conf))
+	assert.Equal(t, FormatProperties, FormatOf("application.properties"))
+}
+
+// collectNotify 在Goroutine中监听配置来源, 返回通知次数
+func collectNotify(source interface {
+	Watch(ctx context.Context, notify func())
+}) (func() int, context.CancelFunc) {
+	var lock sync.Mutex
+	count := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	go source.Watch(ctx, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		count++
+	})
+	return func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return count
+	}, cancel
+}
+
+func TestEtcdSource(t *testing.T) {
+	var lock sync.Mutex
+	value, revision := "MaxConn: 100\n", int64(5)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		var req map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		key, _ := base64.StdEncoding.DecodeString(req["key"])
+		if string(key) != "/zinx/game.yaml" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintf(w, `{"kvs":[{"value":%q,"mod_revision":"%d"}]}`, base64.StdEncoding.EncodeToString([]byte(value)), revision)
+	}))
+	defer srv.Close()
+
+	// 第一个地址不可用时使用下一个地址
+	source := NewEtcdSource([]string{"http://127.0.0.1:1", srv.URL}, "/zinx/game.yaml", 20*time.Millisecond)
+	assert.Equal(t, "etcd:/zinx/game.yaml", source.Name())
+	data, format, err := source.Read(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, FormatYAML, format)
+	assert.Equal(t, value, string(data))
+
+	_, _, err = NewEtcdSource([]string{srv.URL}, "/zinx/missing.json", 0).Read(context.Background())
+	assert.NotNil(t, err)
+
+	count, cancel := collectNotify(source)
+	defer cancel()
+	assert.Eventually(t, func() bool { return count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	value, revision = "MaxConn: 200\n", 6
+	lock.Unlock()
+	assert.Eventually(t, func() bool { return count() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNacosSource(t *testing.T) {
+	var lock sync.Mutex
+	content := `{"MaxConn": 100}`
+	changed := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nacos/v1/cs/configs":
+			q := r.URL.Query()
+			assert.Equal(t, "zinx.json", q.Get("dataId"))
+			assert.Equal(t, "GAME", q.Get("group"))
+			assert.Equal(t, "prod", q.Get("tenant"))
+			lock.Lock()
+			defer lock.Unlock()
+			fmt.Fprint(w, content)
+		case "/nacos/v1/cs/configs/listener":
+			assert.Equal(t, "30000", r.Header.Get("Long-Pulling-Timeout"))
+			listening := r.PostFormValue("Listening-Configs")
+			lock.Lock()
+			sum := md5.Sum([]byte(content))
+			lock.Unlock()
+			assert.Equal(t, "zinx.json\x02GAME\x02"+hex.EncodeToString(sum[:])+"\x02prod\x01", listening)
+			select {
+			case <-changed:
+				fmt.Fprint(w, "zinx.json%02GAME%02prod%01")
+			case <-time.After(200 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer srv.Close()
+
+	source := NewNacosSource([]string{srv.URL}, "prod", "GAME", "zinx.json")
+	assert.Equal(t, "nacos:GAME/zinx.json", source.Name())
+	data, format, err := source.Read(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, FormatJSON, format)
+	assert.Equal(t, content, string(data))
+
+	count, cancel := collectNotify(source)
+	defer cancel()
+	assert.Eventually(t, func() bool { return count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, count())
+
+	lock.Lock()
+	content = `{"MaxConn": 200}`
+	lock.Unlock()
+	changed <- struct{}{}
+	assert.Eventually(t, func() bool { return count() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestApolloSource(t *testing.T) {
+	var lock sync.Mutex
+	notificationID := int64(10)
+	configs := map[string]string{"MaxConn": "100", "Name": "game"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Apollo game:"))
+		assert.NotEmpty(t, r.Header.Get("Timestamp"))
+		lock.Lock()
+		defer lock.Unlock()
+		switch r.URL.Path {
+		case "/configs/game/default/application":
+			_ = json.NewEncoder(w).Encode(apolloConfigResp{Configurations: configs, ReleaseKey: "1"})
+		case "/configs/game/default/zinx.yaml":
+			_ = json.NewEncoder(w).Encode(apolloConfigResp{Configurations: map[string]string{"content": "MaxConn: 300\n"}})
+		case "/notifications/v2":
+			var req []apolloNotification
+			assert.Nil(t, json.Unmarshal([]byte(r.URL.Query().Get("notifications")), &req))
+			assert.Equal(t, "application", req[0].NamespaceName)
+			if req[0].NotificationID == notificationID {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]apolloNotification{{NamespaceName: "application", NotificationID: notificationID}})
+		}
+	}))
+	defer srv.Close()
+
+	source := NewApolloSource(srv.URL, "game", "", "", "secret")
+	assert.Equal(t, "apollo:game/application", source.Name())
+	data, format, err := source.Read(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, FormatProperties, format)
+	assert.Equal(t, "MaxConn=100\nName=game\n", string(data))
+
+	conf := &Config{}
+	assert.Nil(t, conf.LoadSource(context.Background(), source))
+	assert.Equal(t, 100, conf.MaxConn)
+	assert.Equal(t, "game", conf.Name)
+
+	data, format, err = NewApolloSource(srv.URL, "game", "", "zinx.yaml", "secret").Read(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, FormatYAML, format)
+	assert.Equal(t, "MaxConn: 300\n", string(data))
+
+	count, cancel := collectNotify(source)
+	defer cancel()
+	assert.Eventually(t, func() bool { return count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	notificationID = 11
+	lock.Unlock()
+	assert.Eventually(t, func() bool { return count() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+// memSource 内存中的配置来源, 用于测试
+type memSource struct {
+	lock    sync.Mutex
+	data    string
+	changed chan struct{}
+}
+
+func (s *memSource) Name() string { return "mem" }
+
+func (s *memSource) Read(ctx context.Context) ([]byte, string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return []byte(s.data), FormatJSON, nil
+}
+
+func (s *memSource) Watch(ctx context.Context, notify func()) {
+	notify()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.changed:
+			notify()
+		}
+	}
+}
+
+func (s *memSource) set(data string) {
+	s.lock.Lock()
+	s.data = data
+	s.lock.Unlock()
+	s.changed <- struct{}{}
+}
+
+func TestSourceWatcher(t *testing.T) {
+	source := &memSource{data: `{"MaxConn": 100}`, changed: make(chan struct{})}
+	w, err := NewSourceWatcher(source)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, 100, w.Current().MaxConn)
+
+	changes := make(chan int, 10)
+	w.OnChange(func(old, new *Config) { changes <- new.MaxConn })
+	source.set(`{"MaxConn": 200}`)
+	select {
+	case n := <-changes:
+		assert.Equal(t, 200, n)
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+}
+
+func TestUseSource(t *testing.T) {
+	old := *GlobalObject
+	defer func() { *GlobalObject = old }()
+
+	assert.Nil(t, UseSource(&memSource{data: `{"Name": "remote", "MaxConn": 321}`}))
+	assert.Equal(t, "remote", GlobalObject.Name)
+	assert.Equal(t, 321, GlobalObject.MaxConn)
+
+	// 校验失败时GlobalObject保持不变
+	assert.NotNil(t, UseSource(&memSource{data: `{"MaxConn": -1}`}))
+	assert.Equal(t, 321, GlobalObject.MaxConn)
+}
@@ -0,0 +1,539 @@
+package zconf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlParser TOML解析器, 支持配置文件常用的语法:
+// 键值对、点分键、[表]、[[表数组]]、字符串(基本、字面量与多行)、整数、浮点数、布尔值、数组与内联表
+// 日期时间不能用于zinx的配置, 不支持
+type tomlParser struct {
+	data    string
+	pos     int
+	line    int
+	root    map[string]interface{}
+	current map[string]interface{}
+	// 表的定义方式, 用于检查重复定义的表与扩展内联表, 以表的地址为键
+	kinds map[uintptr]tomlTableKind
+}
+
+// tomlTableKind 表的定义方式
+type tomlTableKind int
+
+const (
+	tomlImplicit tomlTableKind = iota // 作为[a.b]的上级表隐式创建, 之后可以用[a]定义
+	tomlHeader                        // 由[a]定义, 不能再次定义, 也不能在其他表中用点分键扩展
+	tomlDotted                        // 由点分键a.b = 1创建, 不能再用[a]定义
+	tomlInline                        // 内联表, 定义后不能扩展
+)
+
+// decodeTOML 将TOML解析为通用的键值
+func decodeTOML(data string) (map[string]interface{}, error) {
+	p := &tomlParser{data: data, line: 1, root: make(map[string]interface{}), kinds: make(map[uintptr]tomlTableKind)}
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) kindOf(table map[string]interface{}) tomlTableKind {
+	return p.kinds[reflect.ValueOf(table).Pointer()]
+}
+
+func (p *tomlParser) setKind(table map[string]interface{}, kind tomlTableKind) {
+	p.kinds[reflect.ValueOf(table).Pointer()] = kind
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// skipSpace 跳过空格与制表符
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment 跳过注释, 不包括行尾
+func (p *tomlParser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.data[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank 跳过空白、注释与换行
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		switch p.peek() {
+		case '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine 一条语句之后只能有空白与注释
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		if strings.HasPrefix(p.data[p.pos:], "[[") {
+			err = p.parseArrayTable()
+		} else if p.peek() == '[' {
+			err = p.parseTable()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTable [a.b]
+func (p *tomlParser) parseTable() error {
+	p.pos++
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != ']' {
+		return p.errorf("expected ] after table name")
+	}
+	p.pos++
+	table, err := p.table(p.root, keys, tomlImplicit)
+	if err != nil {
+		return err
+	}
+	if p.kindOf(table) != tomlImplicit {
+		return p.errorf("table %s already defined", strings.Join(keys, "."))
+	}
+	p.setKind(table, tomlHeader)
+	p.current = table
+	return nil
+}
+
+// parseArrayTable [[a.b]], 在表数组a.b中添加一个表
+func (p *tomlParser) parseArrayTable() error {
+	p.pos += 2
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(p.data[p.pos:], "]]") {
+		return p.errorf("expected ]] after array table name")
+	}
+	p.pos += 2
+
+	parent, err := p.table(p.root, keys[:len(keys)-1], tomlImplicit)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if p.kindOf(parent) == tomlInline {
+		return p.errorf("inline table %s cannot be extended", strings.Join(keys[:len(keys)-1], "."))
+	}
+	var array []interface{}
+	switch v := parent[last].(type) {
+	case nil:
+	case []interface{}:
+		array = v
+	default:
+		return p.errorf("key %s is not an array of tables", last)
+	}
+	table := make(map[string]interface{})
+	p.setKind(table, tomlHeader)
+	parent[last] = append(array, table)
+	p.current = table
+	return nil
+}
+
+// table 按键路径找到或创建表, 路径中的表数组使用最后一个表
+// kind为新建表的定义方式; 点分键不能扩展内联表与由[a]定义的表, [a.b]不能扩展内联表
+func (p *tomlParser) table(table map[string]interface{}, keys []string, kind tomlTableKind) (map[string]interface{}, error) {
+	for i, key := range keys {
+		switch v := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			p.setKind(child, kind)
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			switch p.kindOf(v) {
+			case tomlInline:
+				return nil, p.errorf("inline table %s cannot be extended", strings.Join(keys[:i+1], "."))
+			case tomlHeader, tomlImplicit:
+				if kind == tomlDotted {
+					return nil, p.errorf("table %s already defined", strings.Join(keys[:i+1], "."))
+				}
+			}
+			table = v
+		case []interface{}:
+			if len(v) == 0 {
+				return nil, p.errorf("key %s is not a table", key)
+			}
+			child, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("key %s is not a table", key)
+			}
+			table = child
+		default:
+			return nil, p.errorf("key %s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// parseKeyValue key = value, 点分键在table中创建子表
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected = after key %s", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.table(table, keys[:len(keys)-1], tomlDotted)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := parent[last]; ok {
+		return p.errorf("duplicate key %s", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey 解析裸键、引号键或点分键, 结束后跳过空白
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.data[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("invalid key")
+			}
+			key = p.data[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch c := p.peek(); {
+	case strings.HasPrefix(p.data[p.pos:], `"""`):
+		return p.parseMultilineString(`"""`, true)
+	case strings.HasPrefix(p.data[p.pos:], "'''"):
+		return p.parseMultilineString("'''", false)
+	case c == '"':
+		return p.parseBasicString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.data[p.pos])) {
+		p.pos++
+	}
+	token := p.data[start:p.pos]
+	switch token {
+	case "":
+		return nil, p.errorf("missing value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	number := strings.Replace(token, "_", "", -1)
+	if i, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %s", token)
+}
+
+// parseBasicString "..." 支持转义
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// parseLiteralString '...' 不转义
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.data[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineString 三个引号包围的多行基本字符串或多行字面量字符串, 紧跟开始引号的换行被忽略
+func (p *tomlParser) parseMultilineString(quote string, escape bool) (string, error) {
+	p.pos += len(quote)
+	if strings.HasPrefix(p.data[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.peek() == '\n' {
+		p.pos++
+		p.line++
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.data[p.pos:], quote) {
+			p.pos += len(quote)
+			return b.String(), nil
+		}
+		c := p.data[p.pos]
+		if escape && c == '\\' {
+			// 行尾的反斜杠删除之后的空白与换行
+			rest := strings.TrimLeft(p.data[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos++
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.data[p.pos])) {
+					if p.data[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if c == '\n' {
+			p.line++
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+}
+
+// parseEscape 解析反斜杠开始的转义字符
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	p.pos++
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.data) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.data[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.pos += n
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// parseArray [v1, v2, ...], 可以跨行, 允许结尾的逗号
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++
+	array := make([]interface{}, 0)
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		case 0:
+			return nil, p.errorf("unterminated array")
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// parseInlineTable {k1 = v1, k2 = v2}
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		p.setKind(table, tomlInline)
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.setKind(table, tomlInline)
+			return table, nil
+		case 0:
+			return nil, p.errorf("unterminated inline table")
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
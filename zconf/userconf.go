@@ -1,6 +1,10 @@
 package zconf
 
-import "github.com/aceld/zinx/zlog"
+import (
+	"reflect"
+
+	"github.com/aceld/zinx/zlog"
+)
 
 // 注意如果使用UserConf应该调用方法同步至 GlobalConfObject 因为其他参数是调用的此结构体参数
 func UserConfToGlobal(config *Config) {
@@ -15,6 +19,15 @@ func UserConfToGlobal(config *Config) {
 	if config.TCPPort != 0 {
 		GlobalObject.TCPPort = config.TCPPort
 	}
+	if config.Profile != "" {
+		GlobalObject.Profile = config.Profile
+	}
+	if config.IPVersion != "" {
+		GlobalObject.IPVersion = config.IPVersion
+	}
+	if config.Listeners != nil {
+		GlobalObject.Listeners = config.Listeners
+	}
 
 	// Zinx
 	if config.Version != "" {
@@ -23,6 +36,9 @@ func UserConfToGlobal(config *Config) {
 	if config.MaxPacketSize != 0 {
 		GlobalObject.MaxPacketSize = config.MaxPacketSize
 	}
+	if config.MsgMaxPacketSize != nil {
+		GlobalObject.MsgMaxPacketSize = config.MsgMaxPacketSize
+	}
 	if config.MaxConn != 0 {
 		GlobalObject.MaxConn = config.MaxConn
 	}
@@ -32,6 +48,27 @@ func UserConfToGlobal(config *Config) {
 	if config.MaxWorkerTaskLen != 0 {
 		GlobalObject.MaxWorkerTaskLen = config.MaxWorkerTaskLen
 	}
+	if config.WorkerPoolMaxSize != 0 {
+		GlobalObject.WorkerPoolMaxSize = config.WorkerPoolMaxSize
+	}
+	if config.WorkerScaleWaitTime != 0 {
+		GlobalObject.WorkerScaleWaitTime = config.WorkerScaleWaitTime
+	}
+	if config.WorkerStealing {
+		GlobalObject.WorkerStealing = true
+	}
+	if config.WorkerDispatchMode != "" {
+		GlobalObject.WorkerDispatchMode = config.WorkerDispatchMode
+	}
+	if config.SlowHandlerTime != 0 {
+		GlobalObject.SlowHandlerTime = config.SlowHandlerTime
+	}
+	if config.SlowLogSize != 0 {
+		GlobalObject.SlowLogSize = config.SlowLogSize
+	}
+	if config.SlowLogPayload != 0 {
+		GlobalObject.SlowLogPayload = config.SlowLogPayload
+	}
 	if config.MaxMsgChanLen != 0 {
 		GlobalObject.MaxMsgChanLen = config.MaxMsgChanLen
 	}
@@ -68,4 +105,118 @@ func UserConfToGlobal(config *Config) {
 	if config.PrivateKeyFile != "" {
 		GlobalObject.PrivateKeyFile = config.PrivateKeyFile
 	}
+
+	// TCP socket
+	if config.TCPNoDelay != nil {
+		GlobalObject.TCPNoDelay = config.TCPNoDelay
+	}
+	if config.TCPKeepAlive != 0 {
+		GlobalObject.TCPKeepAlive = config.TCPKeepAlive
+	}
+	if config.TCPKeepAliveInterval != 0 {
+		GlobalObject.TCPKeepAliveInterval = config.TCPKeepAliveInterval
+	}
+	if config.TCPKeepAliveCount != 0 {
+		GlobalObject.TCPKeepAliveCount = config.TCPKeepAliveCount
+	}
+	if config.TCPReadBuffer != 0 {
+		GlobalObject.TCPReadBuffer = config.TCPReadBuffer
+	}
+	if config.TCPWriteBuffer != 0 {
+		GlobalObject.TCPWriteBuffer = config.TCPWriteBuffer
+	}
+	if config.TCPLinger != 0 {
+		GlobalObject.TCPLinger = config.TCPLinger
+	}
+
+	// Admin
+	if config.AdminAddr != "" {
+		GlobalObject.AdminAddr = config.AdminAddr
+	}
+	if config.AdminToken != "" {
+		GlobalObject.AdminToken = config.AdminToken
+	}
+	if config.AdminDashboard {
+		GlobalObject.AdminDashboard = true
+	}
+
+	// Limits
+	if config.ConnReadRate != 0 {
+		GlobalObject.ConnReadRate = config.ConnReadRate
+	}
+	if config.ConnWriteRate != 0 {
+		GlobalObject.ConnWriteRate = config.ConnWriteRate
+	}
+	if config.ServerReadRate != 0 {
+		GlobalObject.ServerReadRate = config.ServerReadRate
+	}
+	if config.ServerWriteRate != 0 {
+		GlobalObject.ServerWriteRate = config.ServerWriteRate
+	}
+	if config.BannedIPs != nil {
+		GlobalObject.BannedIPs = config.BannedIPs
+	}
+
+	// StatsD
+	if config.StatsDAddr != "" {
+		GlobalObject.StatsDAddr = config.StatsDAddr
+	}
+	if config.StatsDPrefix != "" {
+		GlobalObject.StatsDPrefix = config.StatsDPrefix
+	}
+	if config.StatsDTags != nil {
+		GlobalObject.StatsDTags = config.StatsDTags
+	}
+	if config.DogStatsD {
+		GlobalObject.DogStatsD = true
+	}
+	if config.StatsDInterval != 0 {
+		GlobalObject.StatsDInterval = config.StatsDInterval
+	}
+
+	// Registry
+	if config.Registry != "" {
+		GlobalObject.Registry = config.Registry
+	}
+	if config.RegistryAddrs != nil {
+		GlobalObject.RegistryAddrs = config.RegistryAddrs
+	}
+	if config.RegistryNamespace != "" {
+		GlobalObject.RegistryNamespace = config.RegistryNamespace
+	}
+	if config.RegistryToken != "" {
+		GlobalObject.RegistryToken = config.RegistryToken
+	}
+	if config.RegistryTTL != 0 {
+		GlobalObject.RegistryTTL = config.RegistryTTL
+	}
+	if config.ServiceName != "" {
+		GlobalObject.ServiceName = config.ServiceName
+	}
+	if config.ServiceAddr != "" {
+		GlobalObject.ServiceAddr = config.ServiceAddr
+	}
+}
+
+// SetGlobal 校验配置并替换全局配置GlobalObject, 同时应用日志设置, 需在创建Server之前调用
+// 与UserConfToGlobal不同, 零值的字段同样生效; 校验失败时GlobalObject保持不变
+func SetGlobal(config *Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	// 只写入变化的字段, 其他Goroutine可能正在读取未变化的字段
+	oldVal, newVal := reflect.ValueOf(GlobalObject).Elem(), reflect.ValueOf(config).Elem()
+	for i := 0; i < newVal.NumField(); i++ {
+		if !reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			oldVal.Field(i).Set(newVal.Field(i))
+		}
+	}
+
+	if GlobalObject.LogFile != "" {
+		zlog.SetLogFile(GlobalObject.LogDir, GlobalObject.LogFile)
+	}
+	if GlobalObject.LogIsolationLevel > zlog.LogDebug {
+		zlog.SetLogLevel(GlobalObject.LogIsolationLevel)
+	}
+	return nil
 }
@@ -0,0 +1,227 @@
+package zconf
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// FieldError 一个配置字段的错误
+type FieldError struct {
+	Field   string //字段名, 如"TCPPort"、"Listeners[1].Port"
+	Message string //错误说明与修改建议
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError 配置校验发现的全部错误
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(lines, fmt.Sprintf("invalid config, %d error(s):", len(e.Errors)))
+	for _, err := range e.Errors {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator 收集校验错误
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) port(field string, port int) {
+	if port < 0 || port > 65535 {
+		v.add(field, "must be between 0 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) network(field, network string) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		v.add(field, "must be tcp, tcp4 or tcp6, got %q", network)
+	}
+}
+
+func (v *validator) positive(field string, value int64) {
+	if value <= 0 {
+		v.add(field, "must be greater than 0, got %d", value)
+	}
+}
+
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.add(field, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) file(field, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		v.add(field, "cannot read %s: %v", path, err)
+	} else if info.IsDir() {
+		v.add(field, "%s is a directory", path)
+	}
+}
+
+// Validate 校验字段的取值范围与字段之间的约束, 一次返回全部错误(*ValidationError), 没有错误时返回nil
+// 加载配置文件与环境变量之后调用, 避免错误的配置在运行中才以难以排查的方式暴露
+func (g *Config) Validate() error {
+	v := &validator{}
+
+	// Server
+	v.port("TCPPort", g.TCPPort)
+	v.network("IPVersion", g.IPVersion)
+	for i, l := range g.Listeners {
+		field := fmt.Sprintf("Listeners[%d]", i)
+		if l.IPVersion != "" {
+			v.network(field+".IPVersion", l.IPVersion)
+		}
+		v.port(field+".Port", l.Port)
+	}
+
+	// Zinx
+	v.positive("MaxConn", int64(g.MaxConn))
+	v.positive("WorkerPoolSize", int64(g.WorkerPoolSize))
+	v.positive("MaxWorkerTaskLen", int64(g.MaxWorkerTaskLen))
+	v.positive("MaxMsgChanLen", int64(g.MaxMsgChanLen))
+	v.positive("IOReadBuffSize", int64(g.IOReadBuffSize))
+	if g.WorkerPoolMaxSize != 0 && g.WorkerPoolMaxSize < g.WorkerPoolSize {
+		v.add("WorkerPoolMaxSize", "must be 0 (no auto scaling) or at least WorkerPoolSize %d, got %d", g.WorkerPoolSize, g.WorkerPoolMaxSize)
+	}
+	v.nonNegative("WorkerScaleWaitTime", g.WorkerScaleWaitTime)
+	switch g.WorkerDispatchMode {
+	case "", ziface.DispatchAffinity, ziface.DispatchHash, ziface.DispatchRoundRobin, ziface.DispatchLeastLoaded:
+	default:
+		v.add("WorkerDispatchMode", "must be affinity, hash, round_robin or least_loaded, got %q", g.WorkerDispatchMode)
+	}
+
+	// 数据包与缓冲: 读取缓冲不超过Socket的接收缓冲, 最大的数据包可以一次写入Socket的发送缓冲
+	if g.TCPReadBuffer > 0 && g.IOReadBuffSize > uint32(g.TCPReadBuffer) {
+		v.add("IOReadBuffSize", "is larger than TCPReadBuffer %d, a single read never fills it; raise TCPReadBuffer or lower IOReadBuffSize", g.TCPReadBuffer)
+	}
+	if g.TCPWriteBuffer > 0 && g.MaxPacketSize > uint32(g.TCPWriteBuffer) {
+		v.add("TCPWriteBuffer", "is smaller than MaxPacketSize %d, every large packet needs several writes; raise TCPWriteBuffer or lower MaxPacketSize", g.MaxPacketSize)
+	}
+
+	// logger
+	if g.LogIsolationLevel < zlog.LogDebug || g.LogIsolationLevel > zlog.LogFatal {
+		v.add("LogIsolationLevel", "must be between %d and %d, got %d", zlog.LogDebug, zlog.LogFatal, g.LogIsolationLevel)
+	}
+
+	// Keepalive
+	v.nonNegative("HeartbeatMax", g.HeartbeatMax)
+
+	// TLS: 证书与私钥需要同时设置, 且文件存在
+	switch {
+	case g.CertFile != "" && g.PrivateKeyFile == "":
+		v.add("PrivateKeyFile", "must be set when CertFile is set")
+	case g.CertFile == "" && g.PrivateKeyFile != "":
+		v.add("CertFile", "must be set when PrivateKeyFile is set")
+	}
+	if g.CertFile != "" {
+		v.file("CertFile", g.CertFile)
+	}
+	if g.PrivateKeyFile != "" {
+		v.file("PrivateKeyFile", g.PrivateKeyFile)
+	}
+	if g.PrivateKeyPassword != "" && g.PrivateKeyFile == "" {
+		v.add("PrivateKeyPassword", "requires PrivateKeyFile")
+	}
+
+	// TCP socket
+	v.nonNegative("TCPKeepAliveInterval", g.TCPKeepAliveInterval)
+	v.nonNegative("TCPKeepAliveCount", g.TCPKeepAliveCount)
+	v.nonNegative("TCPReadBuffer", g.TCPReadBuffer)
+	v.nonNegative("TCPWriteBuffer", g.TCPWriteBuffer)
+
+	// Admin
+	if g.AdminAddr != "" {
+		if _, _, err := net.SplitHostPort(g.AdminAddr); err != nil {
+			v.add("AdminAddr", "must be host:port, got %q", g.AdminAddr)
+		}
+		if g.AdminToken == "" {
+			v.add("AdminToken", "must be set when AdminAddr is set, the admin listener is not started without a token")
+		}
+	}
+	if g.AdminDashboard && g.AdminAddr == "" {
+		v.add("AdminDashboard", "requires AdminAddr")
+	}
+
+	// Limits
+	v.nonNegative("ConnReadRate", g.ConnReadRate)
+	v.nonNegative("ConnWriteRate", g.ConnWriteRate)
+	v.nonNegative("ServerReadRate", g.ServerReadRate)
+	v.nonNegative("ServerWriteRate", g.ServerWriteRate)
+	for i, ip := range g.BannedIPs {
+		if net.ParseIP(ip) == nil {
+			v.add(fmt.Sprintf("BannedIPs[%d]", i), "must be an IP address, got %q", ip)
+		}
+	}
+
+	// Slow handler
+	v.nonNegative("SlowHandlerTime", g.SlowHandlerTime)
+	v.nonNegative("SlowLogSize", g.SlowLogSize)
+	v.nonNegative("SlowLogPayload", g.SlowLogPayload)
+
+	// StatsD
+	if g.StatsDAddr != "" {
+		if _, _, err := net.SplitHostPort(g.StatsDAddr); err != nil {
+			v.add("StatsDAddr", "must be host:port, got %q", g.StatsDAddr)
+		}
+	}
+	for i, tag := range g.StatsDTags {
+		if !strings.Contains(tag, ":") {
+			v.add(fmt.Sprintf("StatsDTags[%d]", i), "must be key:value, got %q", tag)
+		}
+	}
+	v.nonNegative("StatsDInterval", g.StatsDInterval)
+
+	// Registry
+	if g.Registry != "" {
+		switch g.Registry {
+		case zdiscovery.KindEtcd, zdiscovery.KindConsul, zdiscovery.KindNacos:
+		default:
+			v.add("Registry", "must be etcd, consul or nacos, got %q", g.Registry)
+		}
+		if len(g.RegistryAddrs) == 0 {
+			v.add("RegistryAddrs", "must be set when Registry is set")
+		}
+	}
+	v.nonNegative("RegistryTTL", g.RegistryTTL)
+	if g.ServiceAddr != "" {
+		if _, _, err := net.SplitHostPort(g.ServiceAddr); err != nil {
+			v.add("ServiceAddr", "must be host:port, got %q", g.ServiceAddr)
+		}
+	} else if g.Registry != "" && (g.Host == "" || g.Host == "0.0.0.0" || g.Host == "::") {
+		v.add("ServiceAddr", "must be set when listening on all addresses (Host %q), other nodes cannot reach it", g.Host)
+	}
+
+	// Timer: 定时器以毫秒为最小精度, 每层时间轮至少2个刻度
+	v.nonNegative("TimerTick", g.TimerTick)
+	for i, scales := range g.TimerScales {
+		if scales < 2 {
+			v.add(fmt.Sprintf("TimerScales[%d]", i), "must be at least 2, got %d", scales)
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
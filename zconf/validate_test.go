@@ -0,0 +1,86 @@
+package zconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Host:             "0.0.0.0",
+		TCPPort:          8999,
+		IPVersion:        "tcp",
+		MaxConn:          12000,
+		MaxPacketSize:    4096,
+		WorkerPoolSize:   10,
+		MaxWorkerTaskLen: 1024,
+		MaxMsgChanLen:    1024,
+		IOReadBuffSize:   1024,
+		HeartbeatMax:     10,
+	}
+}
+
+func fieldsOf(err error) []string {
+	var fields []string
+	if verr, ok := err.(*ValidationError); ok {
+		for _, e := range verr.Errors {
+			fields = append(fields, e.Field)
+		}
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, validConfig().Validate())
+
+	// 一次返回全部错误
+	conf := validConfig()
+	conf.TCPPort = 70000
+	conf.WorkerPoolSize = 0
+	conf.IPVersion = "udp"
+	conf.Listeners = []ListenAddr{{IPVersion: "tcp6", Port: -1}}
+	conf.WorkerDispatchMode = "random"
+	conf.LogIsolationLevel = 9
+	conf.BannedIPs = []string{"10.0.0.1", "bad"}
+	conf.TimerScales = []int{100, 1}
+	err := conf.Validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{
+		"TCPPort", "IPVersion", "Listeners[0].Port", "WorkerPoolSize",
+		"WorkerDispatchMode", "LogIsolationLevel", "BannedIPs[1]", "TimerScales[1]",
+	}, fieldsOf(err))
+	assert.Contains(t, err.Error(), "invalid config, 8 error(s):")
+	assert.Contains(t, err.Error(), "TCPPort: must be between 0 and 65535, got 70000")
+
+	// 字段之间的约束
+	conf = validConfig()
+	conf.WorkerPoolMaxSize = 5
+	conf.TCPReadBuffer = 512
+	conf.TCPWriteBuffer = 2048
+	conf.AdminAddr = "127.0.0.1:9090"
+	conf.AdminDashboard = true
+	conf.Registry = "etcd"
+	assert.Equal(t, []string{
+		"WorkerPoolMaxSize", "IOReadBuffSize", "TCPWriteBuffer", "AdminToken", "RegistryAddrs", "ServiceAddr",
+	}, fieldsOf(conf.Validate()))
+}
+
+func TestValidateTLS(t *testing.T) {
+	conf := validConfig()
+	conf.CertFile = "missing.crt"
+	assert.Equal(t, []string{"PrivateKeyFile", "CertFile"}, fieldsOf(conf.Validate()))
+
+	dir, err := ioutil.TempDir("", "zconf")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	conf.CertFile = filepath.Join(dir, "server.crt")
+	conf.PrivateKeyFile = filepath.Join(dir, "server.key")
+	assert.Nil(t, ioutil.WriteFile(conf.CertFile, []byte("cert"), 0644))
+	assert.Equal(t, []string{"PrivateKeyFile"}, fieldsOf(conf.Validate()))
+	assert.Nil(t, ioutil.WriteFile(conf.PrivateKeyFile, []byte("key"), 0600))
+	assert.Nil(t, conf.Validate())
+}
@@ -0,0 +1,216 @@
+package zconf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/utils/commandline/args"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultWatchInterval 不支持文件通知的系统上检查配置文件的默认间隔
+const DefaultWatchInterval = time.Second
+
+// watchDebounce 文件变化后等待的时间, 编辑器保存或替换文件时通常连续产生多个事件
+const watchDebounce = 100 * time.Millisecond
+
+// Watcher 监听配置来源(配置文件或配置中心), 变化后重新加载(包括环境变量覆盖与校验)并回调
+// 回调不修改GlobalObject, 由应用决定如何使用新的配置; 加载或校验失败时记录日志并保持当前配置
+type Watcher struct {
+	source ziface.IConfigSource
+	// 创建时GlobalObject的拷贝, 每次都在它的基础上重新加载, 配置中删除的字段恢复为原值
+	base *Config
+
+	lock     sync.Mutex
+	current  *Config
+	handlers []func(old, new *Config)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWatcher 加载并开始监听配置文件, 以当前的GlobalObject为基础, 配置文件中没有的字段保持原值
+// Linux上使用inotify监听配置文件所在的目录(支持编辑器替换文件与Kubernetes ConfigMap的符号链接切换),
+// 其他系统按interval检查文件的修改时间, interval为0时使用DefaultWatchInterval
+func NewWatcher(path string, interval time.Duration) (*Watcher, error) {
+	return NewSourceWatcher(NewFileSource(path, interval))
+}
+
+// NewSourceWatcher 加载并开始监听配置来源, 以当前的GlobalObject为基础, 配置中没有的字段保持原值
+func NewSourceWatcher(source ziface.IConfigSource) (*Watcher, error) {
+	base, err := cloneConfig(GlobalObject)
+	if err != nil {
+		return nil, err
+	}
+	current, err := loadConfig(base, source)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{source: source, base: base, current: current}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	changes := make(chan struct{}, 1)
+	go source.Watch(w.ctx, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	go w.run(changes)
+	return w, nil
+}
+
+// OnChange 添加配置变化的回调, 在监听的Goroutine中按顺序调用
+func (w *Watcher) OnChange(handler func(old, new *Config)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Current 最后一次成功加载的配置
+func (w *Watcher) Current() *Config {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.current
+}
+
+// Close 停止监听
+func (w *Watcher) Close() {
+	w.cancel()
+}
+
+func (w *Watcher) run(changes chan struct{}) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-changes:
+		}
+
+		timer := time.NewTimer(watchDebounce)
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-changes:
+		default:
+		}
+		w.reload()
+	}
+}
+
+// reload 重新加载配置, 有变化时回调
+func (w *Watcher) reload() {
+	w.lock.Lock()
+	old := w.current
+	w.lock.Unlock()
+
+	conf, err := loadConfig(w.base, w.source)
+	if err != nil {
+		zlog.Ins().ErrorF("[WATCH] config %s err: %v", w.source.Name(), err)
+		return
+	}
+	if reflect.DeepEqual(old, conf) {
+		return
+	}
+
+	w.lock.Lock()
+	w.current = conf
+	handlers := w.handlers
+	w.lock.Unlock()
+
+	zlog.Ins().InfoF("[WATCH] config %s changed", w.source.Name())
+	for _, handler := range handlers {
+		handler(old, conf)
+	}
+}
+
+// cloneConfig 深拷贝配置
+func cloneConfig(conf *Config) (*Config, error) {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// loadConfig 在base的拷贝上加载配置来源、环境变量、命令行参数与密钥并校验
+func loadConfig(base *Config, source ziface.IConfigSource) (*Config, error) {
+	conf, err := cloneConfig(base)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultSourceTimeout)
+	defer cancel()
+	if err := conf.LoadSource(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := conf.LoadOverrides(); err != nil {
+		return nil, err
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// pollFile 按间隔检查文件的修改时间与大小, 变化时通知
+func pollFile(path string, interval time.Duration, notify func(), exitChan <-chan struct{}) {
+	stat := func() (time.Time, int64) {
+		if info, err := os.Stat(path); err == nil {
+			return info.ModTime(), info.Size()
+		}
+		return time.Time{}, -1
+	}
+	lastMod, lastSize := stat()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-exitChan:
+				return
+			case <-ticker.C:
+			}
+			mod, size := stat()
+			if !mod.Equal(lastMod) || size != lastSize {
+				lastMod, lastSize = mod, size
+				notify()
+			}
+		}
+	}()
+}
+
+var (
+	watchLock      sync.Mutex
+	defaultWatcher *Watcher
+)
+
+// Watch 监听启动参数指定的配置文件(zinx.json、zinx.yaml或zinx.toml), 修改后回调, old为修改前的配置, new为修改后的配置
+// 第一次调用时开始监听; 服务的热加载见Server.WatchConfig
+func Watch(onChange func(old, new *Config)) error {
+	watchLock.Lock()
+	defer watchLock.Unlock()
+	if defaultWatcher == nil {
+		w, err := NewWatcher(args.Args.ConfigFile, 0)
+		if err != nil {
+			return err
+		}
+		defaultWatcher = w
+	}
+	defaultWatcher.OnChange(onChange)
+	return nil
+}
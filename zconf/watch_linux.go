@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package zconf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// watchFile 使用inotify监听配置文件所在的目录, 目录中配置文件或Kubernetes ConfigMap的"..data"变化时通知
+// 监听目录而不是文件, 编辑器以重命名的方式保存时仍然可以收到后续的变化; inotify不可用时按间隔检查
+func watchFile(path string, interval time.Duration, notify func(), exitChan <-chan struct{}) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		zlog.Ins().ErrorF("[WATCH] inotify err: %v, poll %s every %v", err, path, interval)
+		pollFile(path, interval, notify, exitChan)
+		return
+	}
+	const mask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+		syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_ATTRIB
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		zlog.Ins().ErrorF("[WATCH] inotify watch %s err: %v, poll %s every %v", dir, err, path, interval)
+		pollFile(path, interval, notify, exitChan)
+		return
+	}
+
+	// 非阻塞的描述符由运行时的网络轮询器等待, 关闭文件可以唤醒阻塞的Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-exitChan
+		file.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				eventName := strings.TrimRight(string(nameBytes), "\x00")
+				if eventName == name || strings.HasPrefix(eventName, "..") {
+					notify()
+				}
+			}
+		}
+	}()
+}
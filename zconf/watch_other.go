@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package zconf
+
+import "time"
+
+// watchFile 当前平台按间隔检查配置文件的修改时间
+func watchFile(path string, interval time.Duration, notify func(), exitChan <-chan struct{}) {
+	pollFile(path, interval, notify, exitChan)
+}
@@ -0,0 +1,105 @@
+package zconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zconf-watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "zinx.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: 100\n"), 0644))
+
+	w, err := NewWatcher(path, 50*time.Millisecond)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, 100, w.Current().MaxConn)
+	assert.Equal(t, GlobalObject.WorkerPoolSize, w.Current().WorkerPoolSize)
+
+	var lock sync.Mutex
+	var changes [][2]int
+	w.OnChange(func(old, new *Config) {
+		lock.Lock()
+		defer lock.Unlock()
+		changes = append(changes, [2]int{old.MaxConn, new.MaxConn})
+	})
+	changed := func() [][2]int {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([][2]int(nil), changes...)
+	}
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: 200\n"), 0644))
+	assert.Eventually(t, func() bool { return len(changed()) == 1 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, [][2]int{{100, 200}}, changed())
+
+	// 校验失败时保持当前配置
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: -1\n"), 0644))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 1, len(changed()))
+	assert.Equal(t, 200, w.Current().MaxConn)
+
+	// 编辑器以重命名的方式替换文件
+	tmp := filepath.Join(dir, "zinx.yaml.tmp")
+	assert.Nil(t, ioutil.WriteFile(tmp, []byte("MaxConn: 300\n"), 0644))
+	assert.Nil(t, os.Rename(tmp, path))
+	assert.Eventually(t, func() bool { return len(changed()) == 2 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, [2]int{200, 300}, changed()[1])
+
+	// 停止后不再回调
+	w.Close()
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: 400\n"), 0644))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 2, len(changed()))
+
+	_, err = NewWatcher(filepath.Join(dir, "missing.yaml"), 0)
+	assert.NotNil(t, err)
+}
+
+func TestWatcherRemoveKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zconf-watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "zinx.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: 100\nWorkerPoolSize: 3\n"), 0644))
+
+	w, err := NewWatcher(path, 50*time.Millisecond)
+	assert.Nil(t, err)
+	defer w.Close()
+	assert.Equal(t, uint32(3), w.Current().WorkerPoolSize)
+
+	// 从配置文件中删除的字段恢复为创建时的值
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn: 100\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return w.Current().WorkerPoolSize == GlobalObject.WorkerPoolSize
+	}, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 100, w.Current().MaxConn)
+}
+
+func TestPollFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zconf-poll")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "zinx.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{}`), 0644))
+
+	notified := make(chan struct{}, 10)
+	exitChan := make(chan struct{})
+	defer close(exitChan)
+	pollFile(path, 20*time.Millisecond, func() { notified <- struct{}{} }, exitChan)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"MaxConn": 1}`), 0644))
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not detected")
+	}
+}
@@ -0,0 +1,250 @@
+// 分隔符协议，以任意字节序列作为帧的起始符/结束符，常见于STX/ETX风格的工业设备协议。
+//
+// 起始符  数据内容(含转义)       结束符
+// STX    Body                  ETX
+// 02     31 10 03 32           03
+//
+//   说明：
+//   1.StartDelimiter 为可选的帧起始符，设置后起始符之前的无效数据将被丢弃;
+//   2.EndDelimiter 为帧结束符，必须设置;
+//   3.开启转义(EnableEscape)后，数据内容中出现的转义字符EscapeChar会使其后的一个字节按原样处理，
+//     不会被识别为分隔符，解码后的数据内容中去掉转义字符(上例中 10 03 解码为 03);
+//   4.StripDelimiter 为true时解码后的数据只包含数据内容，否则保留起始符与结束符;
+//   5.数据内容超过MaxFrameLength(默认DefaultDelimiterMaxFrameLength)的帧会被丢弃，防止一直不发送结束符导致内存无限增长。
+
+package zdecoder
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultDelimiterMaxFrameLength 未指定时数据内容的最大长度
+const DefaultDelimiterMaxFrameLength = 64 * 1024
+
+type DelimiterDecoder struct {
+	MsgID          uint32 //分隔符协议映射的消息ID
+	StartDelimiter []byte //帧起始符，为空表示没有起始符
+	EndDelimiter   []byte //帧结束符
+	EnableEscape   bool   //是否开启转义
+	EscapeChar     byte   //转义字符
+	StripDelimiter bool   //解码后是否去掉起始符和结束符
+	MaxFrameLength int    //数据内容的最大长度，不大于0时使用DefaultDelimiterMaxFrameLength
+}
+
+// NewDelimiterDecoder 创建一个分隔符解码器，默认不开启转义，解码后去掉分隔符
+// maxFrameLength不大于0时使用DefaultDelimiterMaxFrameLength，结束符为空时返回错误
+func NewDelimiterDecoder(msgID uint32, startDelimiter, endDelimiter []byte, maxFrameLength int) (*DelimiterDecoder, error) {
+	if len(endDelimiter) == 0 {
+		return nil, errors.New("DelimiterDecoder: EndDelimiter must not be empty")
+	}
+	if maxFrameLength <= 0 {
+		maxFrameLength = DefaultDelimiterMaxFrameLength
+	}
+
+	return &DelimiterDecoder{
+		MsgID:          msgID,
+		StartDelimiter: startDelimiter,
+		EndDelimiter:   endDelimiter,
+		StripDelimiter: true,
+		MaxFrameLength: maxFrameLength,
+	}, nil
+}
+
+// SetEscape 开启转义
+func (dd *DelimiterDecoder) SetEscape(escapeChar byte) *DelimiterDecoder {
+	dd.EnableEscape = true
+	dd.EscapeChar = escapeChar
+	return dd
+}
+
+// SetStripDelimiter 设置解码后是否去掉起始符和结束符
+func (dd *DelimiterDecoder) SetStripDelimiter(strip bool) *DelimiterDecoder {
+	dd.StripDelimiter = strip
+	return dd
+}
+
+// GetLengthField 分隔符协议不基于LengthField断粘包
+func (dd *DelimiterDecoder) GetLengthField() *ziface.LengthField {
+	return nil
+}
+
+// NewFrameDecoder 为每个连接创建独立的分隔符断粘包解码器
+func (dd *DelimiterDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	d := &delimiterFrameDecoder{
+		DelimiterDecoder: *dd,
+	}
+	if d.MaxFrameLength <= 0 {
+		d.MaxFrameLength = DefaultDelimiterMaxFrameLength
+	}
+	return d
+}
+
+func (dd *DelimiterDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	request := chain.Request()
+	if request == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	switch request.(type) {
+	case ziface.IRequest:
+		iRequest := request.(ziface.IRequest)
+		iMessage := iRequest.GetMessage()
+		if iMessage == nil {
+			break
+		}
+
+		//设置ZinxMessage消息ID
+		iMessage.SetMsgID(dd.MsgID)
+	}
+
+	return chain.Proceed(chain.Request())
+}
+
+// Escape 按照转义规则对数据内容进行转义，用于构造发送给对端的帧
+func (dd *DelimiterDecoder) Escape(body []byte) []byte {
+	if !dd.EnableEscape {
+		return body
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(body)))
+	for i := 0; i < len(body); i++ {
+		if body[i] == dd.EscapeChar ||
+			(len(dd.StartDelimiter) > 0 && body[i] == dd.StartDelimiter[0]) ||
+			body[i] == dd.EndDelimiter[0] {
+			buf.WriteByte(dd.EscapeChar)
+		}
+		buf.WriteByte(body[i])
+	}
+	return buf.Bytes()
+}
+
+// Encode 对数据内容进行转义并添加起始符和结束符
+func (dd *DelimiterDecoder) Encode(body []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(dd.StartDelimiter)
+	buf.Write(dd.Escape(body))
+	buf.Write(dd.EndDelimiter)
+	return buf.Bytes()
+}
+
+// delimiterFrameDecoder 分隔符断粘包
+// 半包时记录已经扫描的位置与解析出的数据内容，收到后续数据时从上次的位置继续扫描
+type delimiterFrameDecoder struct {
+	DelimiterDecoder
+	discarding bool   //true 表示当前帧已超长，丢弃数据直到下一个结束符
+	started    bool   //true 表示已经找到当前帧的起始位置(in从起始符开始)
+	scan       int    //in中下一个待扫描的位置
+	body       []byte //当前帧已经解析出的数据内容
+	in         []byte
+	lock       sync.Mutex
+}
+
+func (d *delimiterFrameDecoder) Decode(buff []byte) [][]byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	for {
+		frame, ok := d.decode()
+		if !ok {
+			return resp
+		}
+		if frame != nil {
+			resp = append(resp, frame)
+		}
+	}
+}
+
+// reset 当前帧结束，下一帧重新查找起始位置
+func (d *delimiterFrameDecoder) reset() {
+	d.started = false
+	d.scan = 0
+	d.body = nil
+}
+
+// keepTail 丢弃数据时保留可能是分隔符一部分的尾部数据
+func (d *delimiterFrameDecoder) keepTail(delimiter []byte) {
+	if keep := len(delimiter) - 1; len(d.in) > keep {
+		d.in = d.in[len(d.in)-keep:]
+	}
+}
+
+// decode 解析出一帧数据, ok为false表示半包，需要等待更多的数据
+// 超长的帧被丢弃时 frame 为nil，ok为true
+func (d *delimiterFrameDecoder) decode() (frame []byte, ok bool) {
+	//丢弃模式，丢弃数据直到下一个结束符
+	if d.discarding {
+		idx := bytes.Index(d.in, d.EndDelimiter)
+		if idx < 0 {
+			d.keepTail(d.EndDelimiter)
+			return nil, false
+		}
+		d.in = d.in[idx+len(d.EndDelimiter):]
+		d.discarding = false
+	}
+
+	if !d.started {
+		if len(d.StartDelimiter) > 0 {
+			idx := bytes.Index(d.in, d.StartDelimiter)
+			if idx < 0 {
+				//丢弃起始符之前的无效数据，保留可能是起始符一部分的尾部数据
+				d.keepTail(d.StartDelimiter)
+				return nil, false
+			}
+			d.in = d.in[idx:]
+		}
+		d.started = true
+		d.scan = len(d.StartDelimiter)
+		d.body = make([]byte, 0)
+	}
+
+	i := d.scan
+	for i < len(d.in) {
+		if d.EnableEscape && d.in[i] == d.EscapeChar {
+			if i+1 >= len(d.in) {
+				break
+			}
+			d.body = append(d.body, d.in[i+1])
+			i += 2
+		} else if bytes.HasPrefix(d.in[i:], d.EndDelimiter) {
+			d.in = d.in[i+len(d.EndDelimiter):]
+			body := d.body
+			d.reset()
+			if d.StripDelimiter {
+				return body, true
+			}
+			frame = make([]byte, 0, len(d.StartDelimiter)+len(body)+len(d.EndDelimiter))
+			frame = append(frame, d.StartDelimiter...)
+			frame = append(frame, body...)
+			frame = append(frame, d.EndDelimiter...)
+			return frame, true
+		} else if bytes.HasPrefix(d.EndDelimiter, d.in[i:]) {
+			//剩余的数据可能是结束符的一部分
+			break
+		} else {
+			d.body = append(d.body, d.in[i])
+			i++
+		}
+
+		if len(d.body) > d.MaxFrameLength {
+			zlog.Ins().ErrorF("delimiter frame too long, discard %d bytes", i)
+			d.in = d.in[i:]
+			d.reset()
+			//有起始符时从下一个起始符开始重新同步，否则丢弃至下一个结束符
+			if len(d.StartDelimiter) == 0 {
+				d.discarding = true
+			}
+			return nil, true
+		}
+	}
+
+	d.scan = i
+	return nil, false
+}
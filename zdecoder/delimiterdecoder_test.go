@@ -0,0 +1,76 @@
+package zdecoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelimiterFrameDecoder(t *testing.T) {
+	dd, err := NewDelimiterDecoder(1, []byte{0x02}, []byte{0x03}, 8)
+	assert.Nil(t, err)
+	dd.SetEscape(0x10)
+	d := dd.NewFrameDecoder()
+
+	//起始符之前的无效数据被丢弃, 转义后的结束符按原样处理
+	assert.Equal(t, 0, len(d.Decode([]byte{0xff, 0x02, 0x31, 0x10})))
+	frames := d.Decode([]byte{0x03, 0x32, 0x03, 0x02, 0x33, 0x03})
+	assert.Equal(t, [][]byte{{0x31, 0x03, 0x32}, {0x33}}, frames)
+
+	//编码后可以被正确解码
+	frames = d.Decode(dd.Encode([]byte{0x02, 0x10, 0x03}))
+	assert.Equal(t, [][]byte{{0x02, 0x10, 0x03}}, frames)
+
+	//超长的帧被丢弃
+	frames = d.Decode([]byte{0x02, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x03, 0x02, 0x34, 0x03})
+	assert.Equal(t, [][]byte{{0x34}}, frames)
+}
+
+func TestDelimiterFrameDecoderKeepDelimiter(t *testing.T) {
+	dd, err := NewDelimiterDecoder(1, nil, []byte("\r\n\r\n"), 0)
+	assert.Nil(t, err)
+	d := dd.SetStripDelimiter(false).NewFrameDecoder()
+
+	assert.Equal(t, 0, len(d.Decode([]byte("a\r\n\r"))))
+	frames := d.Decode([]byte("\nb\r\n\r\n"))
+	assert.Equal(t, [][]byte{[]byte("a\r\n\r\n"), []byte("b\r\n\r\n")}, frames)
+}
+
+func TestDelimiterFrameDecoderIncremental(t *testing.T) {
+	dd, err := NewDelimiterDecoder(1, []byte("<<"), []byte(">>"), 0)
+	assert.Nil(t, err)
+	dd.SetEscape('\\')
+	assert.Equal(t, DefaultDelimiterMaxFrameLength, dd.MaxFrameLength)
+	d := dd.NewFrameDecoder().(*delimiterFrameDecoder)
+
+	//逐字节收到的数据从上次扫描的位置继续解析, 分隔符与转义字符可以被拆开
+	data := []byte("xx<<a\\>>b>>y<<c>>")
+	var frames [][]byte
+	for i := range data {
+		frames = append(frames, d.Decode(data[i:i+1])...)
+		assert.LessOrEqual(t, d.scan, len(d.in))
+	}
+	assert.Equal(t, [][]byte{[]byte("a>>b"), []byte("c")}, frames)
+
+	//没有结束符的数据超过默认的最大长度后被丢弃, 之后的帧正常解析
+	chunk := bytes.Repeat([]byte("z"), 1024)
+	d.Decode([]byte("<<"))
+	for i := 0; i <= DefaultDelimiterMaxFrameLength/len(chunk); i++ {
+		d.Decode(chunk)
+	}
+	assert.Less(t, len(d.in), 2*len(chunk))
+	frames = d.Decode([]byte(">><<ok>>"))
+	assert.Equal(t, [][]byte{[]byte("ok")}, frames)
+
+	//丢弃至结束符时结束符可以被拆开
+	nd, _ := NewDelimiterDecoder(1, nil, []byte("\r\n"), 2)
+	d2 := nd.NewFrameDecoder()
+	assert.Equal(t, 0, len(d2.Decode([]byte("abcd\r"))))
+	assert.Equal(t, [][]byte{[]byte("ef")}, d2.Decode([]byte("\nef\r\n")))
+}
+
+func TestNewDelimiterDecoderInvalid(t *testing.T) {
+	_, err := NewDelimiterDecoder(1, []byte{0x02}, nil, 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,158 @@
+// 定长协议，每一帧数据的长度都固定为N字节，常见于硬件设备、PLC网关等协议。
+//
+// 解码前 (16 bytes)                                    解码后 (2帧，每帧8字节)
+// +-------------------------------------------------+      +-------------------------+   +-------------------------+
+// | 01 0A 0B 0C 00 00 00 00 02 0D 00 00 00 00 00 00 |----->| 01 0A 0B 0C 00 00 00 00 |   | 02 0D 00 00 00 00 00 00 |
+// +-------------------------------------------------+      +-------------------------+   +-------------------------+
+//   (FrameLength = 8, MsgIDOffset = 0, MsgIDLength = 1, PaddingByte = 0x00)
+//
+//   说明：
+//   1.FrameLength 为每一帧的固定长度;
+//   2.MsgID 从帧内 MsgIDOffset 偏移处读取 MsgIDLength(1、2或4) 个字节，MsgIDLength为0时使用固定的DefaultMsgID;
+//   3.数据内容不足一帧时由发送方使用PaddingByte补齐，TrimPadding为true时解码后去掉帧尾部的填充字节。
+
+package zdecoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+type FixedLengthDecoder struct {
+	FrameLength  int              //每一帧的固定长度
+	MsgIDOffset  int              //MsgID在帧内的偏移量
+	MsgIDLength  int              //MsgID占用的字节数 0、1、2、4
+	Order        binary.ByteOrder //MsgID的大小端
+	DefaultMsgID uint32           //MsgIDLength为0时使用的消息ID
+	PaddingByte  byte             //填充字节
+	TrimPadding  bool             //解码后是否去掉帧尾部的填充字节
+}
+
+// NewFixedLengthDecoder 创建一个定长解码器，MsgID使用大端序，参数不合法时返回错误
+func NewFixedLengthDecoder(frameLength int, msgIDOffset int, msgIDLength int) (*FixedLengthDecoder, error) {
+	switch msgIDLength {
+	case 0, 1, 2, 4:
+	default:
+		return nil, fmt.Errorf("unsupported MsgIDLength: %d (expected: 0, 1, 2 or 4)", msgIDLength)
+	}
+	if frameLength <= 0 || msgIDOffset < 0 || msgIDOffset+msgIDLength > frameLength {
+		return nil, fmt.Errorf("invalid FrameLength: %d, MsgIDOffset: %d, MsgIDLength: %d", frameLength, msgIDOffset, msgIDLength)
+	}
+
+	return &FixedLengthDecoder{
+		FrameLength: frameLength,
+		MsgIDOffset: msgIDOffset,
+		MsgIDLength: msgIDLength,
+		Order:       binary.BigEndian,
+	}, nil
+}
+
+// SetPadding 设置填充字节，trim为true时解码后去掉帧尾部的填充字节
+func (fd *FixedLengthDecoder) SetPadding(padding byte, trim bool) *FixedLengthDecoder {
+	fd.PaddingByte = padding
+	fd.TrimPadding = trim
+	return fd
+}
+
+// GetLengthField 定长协议不基于LengthField断粘包
+func (fd *FixedLengthDecoder) GetLengthField() *ziface.LengthField {
+	return nil
+}
+
+// NewFrameDecoder 为每个连接创建独立的定长断粘包解码器
+func (fd *FixedLengthDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	return &fixedLengthFrameDecoder{
+		frameLength: fd.FrameLength,
+	}
+}
+
+func (fd *FixedLengthDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	request := chain.Request()
+	if request == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	switch request.(type) {
+	case ziface.IRequest:
+		iRequest := request.(ziface.IRequest)
+		iMessage := iRequest.GetMessage()
+		if iMessage == nil {
+			break
+		}
+
+		data := iMessage.GetData()
+		if len(data) != fd.FrameLength {
+			zlog.Ins().ErrorF("FixedLength-Decode invalid frame size:%d, expected:%d", len(data), fd.FrameLength)
+			return nil
+		}
+
+		//设置ZinxMessage消息ID
+		iMessage.SetMsgID(fd.msgID(data))
+
+		//去掉帧尾部的填充字节
+		if fd.TrimPadding {
+			body := bytes.TrimRight(data, string([]byte{fd.PaddingByte}))
+			iMessage.SetData(body)
+			iMessage.SetDataLen(uint32(len(body)))
+		}
+	}
+
+	return chain.Proceed(chain.Request())
+}
+
+func (fd *FixedLengthDecoder) msgID(frame []byte) uint32 {
+	field := frame[fd.MsgIDOffset : fd.MsgIDOffset+fd.MsgIDLength]
+	switch fd.MsgIDLength {
+	case 1:
+		return uint32(field[0])
+	case 2:
+		return uint32(fd.Order.Uint16(field))
+	case 4:
+		return fd.Order.Uint32(field)
+	default:
+		return fd.DefaultMsgID
+	}
+}
+
+// Pad 使用填充字节将数据补齐为一帧，数据超过帧长度时返回错误
+func (fd *FixedLengthDecoder) Pad(data []byte) ([]byte, error) {
+	if len(data) > fd.FrameLength {
+		return nil, fmt.Errorf("data size %d exceeds FrameLength %d", len(data), fd.FrameLength)
+	}
+
+	frame := make([]byte, fd.FrameLength)
+	copy(frame, data)
+	for i := len(data); i < fd.FrameLength; i++ {
+		frame[i] = fd.PaddingByte
+	}
+	return frame, nil
+}
+
+// fixedLengthFrameDecoder 定长断粘包
+type fixedLengthFrameDecoder struct {
+	frameLength int
+	in          []byte
+	lock        sync.Mutex
+}
+
+func (d *fixedLengthFrameDecoder) Decode(buff []byte) [][]byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	for len(d.in) >= d.frameLength {
+		frame := make([]byte, d.frameLength)
+		copy(frame, d.in[:d.frameLength])
+		resp = append(resp, frame)
+		d.in = d.in[d.frameLength:]
+	}
+
+	return resp
+}
@@ -0,0 +1,94 @@
+package zdecoder
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRequest 只携带消息的请求，用于直接执行解码器的拦截器
+type testRequest struct {
+	ziface.IRequest
+	msg ziface.IMessage
+}
+
+func (r *testRequest) GetMessage() ziface.IMessage {
+	return r.msg
+}
+
+func intercept(interceptor ziface.IInterceptor, msg ziface.IMessage) ziface.IcResp {
+	return interceptor.Intercept(zinterceptor.NewChain(nil, 0, &testRequest{msg: msg}))
+}
+
+func TestFixedLengthFrameDecoder(t *testing.T) {
+	fd, err := NewFixedLengthDecoder(4, 0, 1)
+	assert.Nil(t, err)
+	d := fd.NewFrameDecoder()
+
+	//半包
+	assert.Equal(t, 0, len(d.Decode([]byte{1, 2})))
+
+	//粘包, 剩余的半帧等待后续数据
+	frames := d.Decode([]byte{3, 4, 5, 6, 7, 8, 9})
+	assert.Equal(t, [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}, frames)
+	frames = d.Decode([]byte{10, 11, 12})
+	assert.Equal(t, [][]byte{{9, 10, 11, 12}}, frames)
+}
+
+func TestFixedLengthMsgID(t *testing.T) {
+	frame := []byte{0xAA, 0x01, 0x02, 0x03, 0x04, 0x00, 0x00, 0x00}
+
+	//1、2、4字节的MsgID, 偏移量为1
+	for msgIDLength, expected := range map[int]uint32{1: 0x01, 2: 0x0102, 4: 0x01020304} {
+		fd, err := NewFixedLengthDecoder(len(frame), 1, msgIDLength)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, fd.msgID(frame))
+	}
+
+	//小端序
+	fd, _ := NewFixedLengthDecoder(len(frame), 1, 2)
+	fd.Order = binary.LittleEndian
+	assert.Equal(t, uint32(0x0201), fd.msgID(frame))
+
+	//不读取MsgID时使用DefaultMsgID
+	fd, _ = NewFixedLengthDecoder(len(frame), 0, 0)
+	fd.DefaultMsgID = 9
+	assert.Equal(t, uint32(9), fd.msgID(frame))
+}
+
+func TestFixedLengthTrimPadding(t *testing.T) {
+	fd, err := NewFixedLengthDecoder(8, 0, 1)
+	assert.Nil(t, err)
+	fd.SetPadding(0xFF, true)
+
+	frame, err := fd.Pad([]byte{0x02, 0x0D})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x02, 0x0D, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, frame)
+	_, err = fd.Pad(make([]byte, 9))
+	assert.NotNil(t, err)
+
+	//解码后去掉帧尾部的填充字节
+	msg := zpack.NewMsgPackage(0, frame)
+	assert.NotNil(t, intercept(fd, msg))
+	assert.Equal(t, uint32(2), msg.GetMsgID())
+	assert.Equal(t, []byte{0x02, 0x0D}, msg.GetData())
+	assert.Equal(t, uint32(2), msg.GetDataLen())
+
+	//长度不是一帧的数据被丢弃
+	assert.Nil(t, intercept(fd, zpack.NewMsgPackage(0, []byte{1})))
+}
+
+func TestNewFixedLengthDecoderInvalid(t *testing.T) {
+	_, err := NewFixedLengthDecoder(8, 0, 3)
+	assert.NotNil(t, err)
+	_, err = NewFixedLengthDecoder(0, 0, 1)
+	assert.NotNil(t, err)
+	_, err = NewFixedLengthDecoder(4, 2, 4)
+	assert.NotNil(t, err)
+	_, err = NewFixedLengthDecoder(4, -1, 1)
+	assert.NotNil(t, err)
+}
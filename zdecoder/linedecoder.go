@@ -0,0 +1,116 @@
+// 文本行协议，以'\n'或'\r\n'作为每一帧消息的结束符，常见于SMTP风格的文本协议、telnet风格的管理控制台等。
+//
+// 解码前 (17 bytes)                   解码后 (2帧)
+// +--------------------------+      +-------------+   +--------+
+// | "HELO zinx\r\nQUIT\r\n"  |----->| "HELO zinx" |   | "QUIT" |
+// +--------------------------+      +-------------+   +--------+
+//
+//   说明：
+//   1.每一行(去掉行尾的"\r\n"或"\n")作为一个完整的消息内容;
+//   2.文本协议没有消息ID，所有的行都映射到同一个固定的MsgID，由该MsgID对应的Router处理;
+//   3.超过MaxLineLength的行会被整行丢弃，防止恶意客户端一直不发送换行符导致内存无限增长。
+
+package zdecoder
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+type LineDecoder struct {
+	MsgID         uint32 //文本行映射的消息ID
+	MaxLineLength int    //单行最大长度(不含换行符)，0表示不限制
+}
+
+func NewLineDecoder(msgID uint32, maxLineLength int) ziface.IDecoder {
+	return &LineDecoder{
+		MsgID:         msgID,
+		MaxLineLength: maxLineLength,
+	}
+}
+
+// GetLengthField 文本行协议不基于LengthField断粘包
+func (ld *LineDecoder) GetLengthField() *ziface.LengthField {
+	return nil
+}
+
+// NewFrameDecoder 为每个连接创建独立的按行断粘包解码器
+func (ld *LineDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	return &lineFrameDecoder{
+		maxLineLength: ld.MaxLineLength,
+	}
+}
+
+func (ld *LineDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	request := chain.Request()
+	if request == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	switch request.(type) {
+	case ziface.IRequest:
+		iRequest := request.(ziface.IRequest)
+		iMessage := iRequest.GetMessage()
+		if iMessage == nil {
+			break
+		}
+
+		//设置ZinxMessage消息ID
+		iMessage.SetMsgID(ld.MsgID)
+		//解析后的数据为当前行的文本内容
+		iRequest.SetResponse(string(iMessage.GetData()))
+	}
+
+	return chain.Proceed(chain.Request())
+}
+
+// lineFrameDecoder 按行断粘包
+type lineFrameDecoder struct {
+	maxLineLength int
+	discarding    bool //true 表示当前行已超长，丢弃数据直到下一个换行符
+	in            []byte
+	lock          sync.Mutex
+}
+
+func (d *lineFrameDecoder) Decode(buff []byte) [][]byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	for {
+		idx := bytes.IndexByte(d.in, '\n')
+		if idx < 0 {
+			//半包，如果缓冲已经超长，则进入丢弃模式
+			if d.maxLineLength > 0 && len(d.in) > d.maxLineLength+1 {
+				zlog.Ins().ErrorF("line too long, discard %d bytes", len(d.in))
+				d.discarding = true
+				d.in = d.in[:0]
+			}
+			return resp
+		}
+
+		line := d.in[:idx]
+		d.in = d.in[idx+1:]
+
+		if d.discarding {
+			//超长行的剩余部分，丢弃
+			d.discarding = false
+			continue
+		}
+
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if d.maxLineLength > 0 && len(line) > d.maxLineLength {
+			zlog.Ins().ErrorF("line too long, discard %d bytes", len(line))
+			continue
+		}
+
+		frame := make([]byte, len(line))
+		copy(frame, line)
+		resp = append(resp, frame)
+	}
+}
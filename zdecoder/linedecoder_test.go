@@ -0,0 +1,23 @@
+package zdecoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineFrameDecoder(t *testing.T) {
+	d := NewLineDecoder(1, 10).(*LineDecoder).NewFrameDecoder()
+
+	//半包
+	assert.Equal(t, 0, len(d.Decode([]byte("HELO zi"))))
+
+	//粘包, 兼容\r\n与\n
+	frames := d.Decode([]byte("nx\r\nQUIT\n\n"))
+	assert.Equal(t, [][]byte{[]byte("HELO zinx"), []byte("QUIT"), {}}, frames)
+
+	//超长行被丢弃，后续的行正常解析
+	assert.Equal(t, 0, len(d.Decode([]byte("0123456789ABCDEF"))))
+	frames = d.Decode([]byte("abc\nNOOP\r\n"))
+	assert.Equal(t, [][]byte{[]byte("NOOP")}, frames)
+}
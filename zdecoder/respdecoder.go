@@ -0,0 +1,523 @@
+// RESP(REdis Serialization Protocol)，Redis客户端与服务端之间的通信协议，同时支持RESP2与RESP3。
+// 每一个RESP数据都以一个表示类型的字节开头，以"\r\n"作为行结束符，例如:
+//
+//   客户端命令 SET key value
+//   +-----------------------------------------------------------+
+//   | *3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n          |
+//   +-----------------------------------------------------------+
+//
+//   说明：
+//   1.客户端发送的命令是由Bulk String组成的Array，第一个元素为命令名称，其余为命令参数;
+//   2.同时兼容telnet风格的inline命令(如"PING\r\n");
+//   3.命令名称(忽略大小写)通过命令表映射为zinx的MsgID，交由对应的Router处理，未注册的命令使用DefaultMsgID;
+//   4.Router中通过 request.GetResponse().(*RESPCommand) 获取解析后的命令，
+//     通过 RESPSimpleString/RESPBulkString 等方法构造回复，调用 conn.Send() 发送给客户端。
+
+package zdecoder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// RESP数据类型
+const (
+	RESPSimpleStringType byte = '+'
+	RESPErrorType        byte = '-'
+	RESPIntegerType      byte = ':'
+	RESPBulkStringType   byte = '$'
+	RESPArrayType        byte = '*'
+	// RESP3
+	RESPNullType           byte = '_'
+	RESPBooleanType        byte = '#'
+	RESPDoubleType         byte = ','
+	RESPBigNumberType      byte = '('
+	RESPBulkErrorType      byte = '!'
+	RESPVerbatimStringType byte = '='
+	RESPMapType            byte = '%'
+	RESPSetType            byte = '~'
+	RESPAttributeType      byte = '|'
+	RESPPushType           byte = '>'
+	// inline命令，非RESP标准类型
+	RESPInlineType byte = 0
+)
+
+// RESPDefaultMaxFrameLength 默认单个RESP数据的最大长度 512MB，与Redis的proto-max-bulk-len保持一致
+const RESPDefaultMaxFrameLength = 512 * 1024 * 1024
+
+// RESPMaxArrayLength Array、Map等类型的最大元素个数，与Redis对客户端命令参数个数的限制保持一致
+const RESPMaxArrayLength = 1024 * 1024
+
+// RESPMaxDepth ParseRESP解析嵌套的Array、Map等类型的最大深度
+const RESPMaxDepth = 64
+
+const (
+	// respMaxHeaderLength 类型与长度行(如"*3\r\n"、"$5\r\n")的最大长度，超过时不再等待"\r\n"
+	respMaxHeaderLength = 32
+	// respMaxPrealloc 按声明的元素个数预先分配的最大容量，其余随实际收到的数据增长
+	respMaxPrealloc = 1024
+)
+
+var (
+	errRESPIncomplete = errors.New("resp: incomplete data")
+	errRESPProtocol   = errors.New("resp: protocol error")
+	errRESPTooLarge   = errors.New("resp: frame too large")
+	errRESPTooDeep    = errors.New("resp: nesting too deep")
+)
+
+// RESPValue 解析后的RESP数据
+type RESPValue struct {
+	Type  byte         //数据类型
+	Str   []byte       //SimpleString、Error、BulkString、Double、BigNumber、VerbatimString等类型的内容
+	Int   int64        //Integer类型的内容
+	Bool  bool         //Boolean类型的内容
+	Array []*RESPValue //Array、Set、Push类型的元素，Map、Attribute类型按key,value顺序展开
+	Null  bool         //是否为空值
+}
+
+// RESPCommand 客户端发送的命令
+type RESPCommand struct {
+	Name  string   //命令名称，统一转为大写
+	Args  [][]byte //命令参数
+	Value *RESPValue
+}
+
+type RESPDecoder struct {
+	Commands       map[string]uint32 //命令名称(大写)与MsgID的映射
+	DefaultMsgID   uint32            //未注册命令的MsgID
+	MaxFrameLength int               //单个RESP数据的最大长度
+}
+
+func NewRESPDecoder(commands map[string]uint32, defaultMsgID uint32) ziface.IDecoder {
+	cmds := make(map[string]uint32, len(commands))
+	for name, msgID := range commands {
+		cmds[strings.ToUpper(name)] = msgID
+	}
+
+	return &RESPDecoder{
+		Commands:       cmds,
+		DefaultMsgID:   defaultMsgID,
+		MaxFrameLength: RESPDefaultMaxFrameLength,
+	}
+}
+
+// GetLengthField RESP协议不基于LengthField断粘包
+func (rd *RESPDecoder) GetLengthField() *ziface.LengthField {
+	return nil
+}
+
+// NewFrameDecoder 为每个连接创建独立的RESP断粘包解码器
+func (rd *RESPDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	return &respFrameDecoder{
+		maxFrameLength: rd.MaxFrameLength,
+		remaining:      -1,
+	}
+}
+
+func (rd *RESPDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	request := chain.Request()
+	if request == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	switch request.(type) {
+	case ziface.IRequest:
+		iRequest := request.(ziface.IRequest)
+		iMessage := iRequest.GetMessage()
+		if iMessage == nil {
+			break
+		}
+
+		value, _, err := ParseRESPCommand(iMessage.GetData(), rd.MaxFrameLength)
+		if err != nil {
+			zlog.Ins().ErrorF("RESP-Decode err: %v", err)
+			return nil
+		}
+
+		cmd := value.Command()
+		if cmd == nil {
+			zlog.Ins().ErrorF("RESP-Decode invalid command type: %c", value.Type)
+			return nil
+		}
+
+		msgID, ok := rd.Commands[cmd.Name]
+		if !ok {
+			msgID = rd.DefaultMsgID
+		}
+
+		//设置ZinxMessage消息ID
+		iMessage.SetMsgID(msgID)
+		//设置解析后的命令
+		iRequest.SetResponse(cmd)
+	}
+
+	return chain.Proceed(chain.Request())
+}
+
+// Command 将客户端发送的数据转换为命令，数据不是命令格式时返回nil
+func (v *RESPValue) Command() *RESPCommand {
+	var args [][]byte
+
+	switch v.Type {
+	case RESPInlineType:
+		for _, field := range bytes.Fields(v.Str) {
+			args = append(args, field)
+		}
+	case RESPArrayType:
+		for _, item := range v.Array {
+			if item.Type != RESPBulkStringType && item.Type != RESPSimpleStringType {
+				return nil
+			}
+			args = append(args, item.Str)
+		}
+	default:
+		return nil
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	return &RESPCommand{
+		Name:  strings.ToUpper(string(args[0])),
+		Args:  args[1:],
+		Value: v,
+	}
+}
+
+// ParseRESP 从buf中解析出一个完整的RESP数据，返回解析后的数据与其占用的字节数
+// maxFrameLength 小于等于0表示不限制长度; 元素个数不超过RESPMaxArrayLength，嵌套深度不超过RESPMaxDepth
+func ParseRESP(buf []byte, maxFrameLength int) (*RESPValue, int, error) {
+	return parseRESP(buf, 0, maxFrameLength, 0)
+}
+
+// ReadRESP 从r中读取并解析一个完整的RESP数据，用于客户端读取服务端的回复
+// 每次取出r中已经缓冲的全部数据尝试解析，只消费解析出的数据占用的字节
+func ReadRESP(r *bufio.Reader, maxFrameLength int) (*RESPValue, error) {
+	var buf []byte
+	for {
+		if _, err := r.Peek(1); err != nil {
+			return nil, err
+		}
+		data, _ := r.Peek(r.Buffered())
+
+		//解析出的数据引用buf，不能引用r内部的缓冲区
+		in := append(buf[:len(buf):len(buf)], data...)
+		value, n, err := ParseRESP(in, maxFrameLength)
+		if err == nil {
+			_, _ = r.Discard(n - len(buf))
+			return value, nil
+		}
+		if err != errRESPIncomplete {
+			return nil, err
+		}
+		buf = in
+		_, _ = r.Discard(len(data))
+	}
+}
+
+// ParseRESPCommand 从buf中解析出一个客户端命令，返回解析后的数据与其占用的字节数
+// 只接受inline命令与由Bulk String组成的Array(Redis客户端发送命令的格式)，不解析嵌套类型
+func ParseRESPCommand(buf []byte, maxFrameLength int) (*RESPValue, int, error) {
+	if len(buf) == 0 {
+		return nil, 0, errRESPIncomplete
+	}
+	if buf[0] != RESPArrayType {
+		line, next, err := readRESPLine(buf, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &RESPValue{Type: RESPInlineType, Str: line}, next, nil
+	}
+
+	line, next, err := readRESPHeader(buf, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := parseRESPCount(line)
+	if err != nil {
+		return nil, 0, err
+	}
+	value := &RESPValue{Type: RESPArrayType, Array: make([]*RESPValue, 0, minInt(n, respMaxPrealloc))}
+	for i := 0; i < n; i++ {
+		item, itemNext, err := parseRESPBulk(buf, next, maxFrameLength)
+		if err != nil {
+			return nil, 0, err
+		}
+		value.Array = append(value.Array, item)
+		next = itemNext
+	}
+	return value, next, nil
+}
+
+func readRESPLine(buf []byte, pos int) ([]byte, int, error) {
+	idx := bytes.Index(buf[pos:], []byte("\r\n"))
+	if idx < 0 {
+		return nil, 0, errRESPIncomplete
+	}
+	return buf[pos : pos+idx], pos + idx + 2, nil
+}
+
+// readRESPHeader 读取类型与长度行，行过长时为协议错误，避免在没有"\r\n"的数据中反复查找
+func readRESPHeader(buf []byte, pos int) ([]byte, int, error) {
+	line, next, err := readRESPLine(buf, pos)
+	if err == errRESPIncomplete && len(buf)-pos > respMaxHeaderLength {
+		return nil, 0, errRESPProtocol
+	}
+	return line, next, err
+}
+
+// parseRESPCount 解析客户端命令的参数个数
+func parseRESPCount(line []byte) (int, error) {
+	n, err := strconv.Atoi(string(line))
+	if err != nil || n < 0 {
+		return 0, errRESPProtocol
+	}
+	if n > RESPMaxArrayLength {
+		return 0, errRESPTooLarge
+	}
+	return n, nil
+}
+
+// parseRESPBulk 解析客户端命令中的一个Bulk String参数
+func parseRESPBulk(buf []byte, pos int, maxFrameLength int) (*RESPValue, int, error) {
+	if pos >= len(buf) {
+		return nil, 0, errRESPIncomplete
+	}
+	if buf[pos] != RESPBulkStringType {
+		return nil, 0, errRESPProtocol
+	}
+	line, next, err := readRESPHeader(buf, pos+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := parseRESPLength(line, maxFrameLength)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n < 0 {
+		return nil, 0, errRESPProtocol
+	}
+	if len(buf) < next+n+2 {
+		return nil, 0, errRESPIncomplete
+	}
+	if buf[next+n] != '\r' || buf[next+n+1] != '\n' {
+		return nil, 0, errRESPProtocol
+	}
+	return &RESPValue{Type: RESPBulkStringType, Str: buf[next : next+n]}, next + n + 2, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func parseRESPLength(line []byte, maxFrameLength int) (int, error) {
+	n, err := strconv.Atoi(string(line))
+	if err != nil || n < -1 {
+		return 0, errRESPProtocol
+	}
+	if maxFrameLength > 0 && n > maxFrameLength {
+		return 0, errRESPTooLarge
+	}
+	return n, nil
+}
+
+func parseRESP(buf []byte, pos int, maxFrameLength int, depth int) (*RESPValue, int, error) {
+	if pos >= len(buf) {
+		return nil, 0, errRESPIncomplete
+	}
+	if depth > RESPMaxDepth {
+		return nil, 0, errRESPTooDeep
+	}
+
+	typ := buf[pos]
+	switch typ {
+	case RESPSimpleStringType, RESPErrorType, RESPDoubleType, RESPBigNumberType:
+		line, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &RESPValue{Type: typ, Str: line}, next, nil
+
+	case RESPIntegerType:
+		line, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		n, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil {
+			return nil, 0, errRESPProtocol
+		}
+		return &RESPValue{Type: typ, Int: n}, next, nil
+
+	case RESPNullType:
+		_, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &RESPValue{Type: typ, Null: true}, next, nil
+
+	case RESPBooleanType:
+		line, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(line) != 1 || (line[0] != 't' && line[0] != 'f') {
+			return nil, 0, errRESPProtocol
+		}
+		return &RESPValue{Type: typ, Bool: line[0] == 't'}, next, nil
+
+	case RESPBulkStringType, RESPBulkErrorType, RESPVerbatimStringType:
+		line, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		n, err := parseRESPLength(line, maxFrameLength)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n == -1 {
+			return &RESPValue{Type: typ, Null: true}, next, nil
+		}
+		if len(buf) < next+n+2 {
+			return nil, 0, errRESPIncomplete
+		}
+		if buf[next+n] != '\r' || buf[next+n+1] != '\n' {
+			return nil, 0, errRESPProtocol
+		}
+		return &RESPValue{Type: typ, Str: buf[next : next+n]}, next + n + 2, nil
+
+	case RESPArrayType, RESPSetType, RESPPushType, RESPMapType, RESPAttributeType:
+		line, next, err := readRESPLine(buf, pos+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		n, err := parseRESPLength(line, maxFrameLength)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n == -1 {
+			return &RESPValue{Type: typ, Null: true}, next, nil
+		}
+		if n > RESPMaxArrayLength {
+			return nil, 0, errRESPTooLarge
+		}
+		if typ == RESPMapType || typ == RESPAttributeType {
+			n *= 2
+		}
+		value := &RESPValue{Type: typ, Array: make([]*RESPValue, 0, minInt(n, respMaxPrealloc))}
+		for i := 0; i < n; i++ {
+			item, itemNext, err := parseRESP(buf, next, maxFrameLength, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			value.Array = append(value.Array, item)
+			next = itemNext
+		}
+		return value, next, nil
+
+	default:
+		//inline命令
+		line, next, err := readRESPLine(buf, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &RESPValue{Type: RESPInlineType, Str: line}, next, nil
+	}
+}
+
+// respFrameDecoder RESP断粘包，只接受客户端命令的格式(见ParseRESPCommand)
+// 半包时记录已经解析的位置，收到后续数据时从该位置继续，不重新解析整个缓冲区
+type respFrameDecoder struct {
+	maxFrameLength int
+	in             []byte
+	pos            int //当前命令已经解析到的位置
+	remaining      int //当前命令还未解析的参数个数，-1表示还没有解析命令头
+	scan           int //inline命令下次查找"\r\n"的起始位置
+	lock           sync.Mutex
+}
+
+func (d *respFrameDecoder) reset() {
+	d.pos, d.remaining, d.scan = 0, -1, 0
+}
+
+func (d *respFrameDecoder) Decode(buff []byte) [][]byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.in) == 0 {
+		d.reset()
+	}
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	for len(d.in) > 0 {
+		n, err := d.next()
+		if err == errRESPIncomplete {
+			//半包，如果缓冲已经超长，则丢弃
+			if d.maxFrameLength > 0 && len(d.in) > d.maxFrameLength {
+				zlog.Ins().ErrorF("RESP frame too large, discard %d bytes", len(d.in))
+				d.in = d.in[:0]
+			}
+			break
+		}
+		if err != nil {
+			//协议错误，无法继续解析，丢弃缓冲区中全部数据
+			zlog.Ins().ErrorF("RESP frame decode err: %v, discard %d bytes", err, len(d.in))
+			d.in = d.in[:0]
+			break
+		}
+
+		frame := make([]byte, n)
+		copy(frame, d.in[:n])
+		resp = append(resp, frame)
+		d.in = d.in[n:]
+		d.reset()
+	}
+
+	return resp
+}
+
+// next 从上次解析的位置继续解析当前命令，返回完整命令的长度
+func (d *respFrameDecoder) next() (int, error) {
+	if d.in[0] != RESPArrayType {
+		idx := bytes.Index(d.in[d.scan:], []byte("\r\n"))
+		if idx < 0 {
+			//末尾的"\r"可能与下次收到的"\n"组成行结束符
+			d.scan = len(d.in) - 1
+			return 0, errRESPIncomplete
+		}
+		return d.scan + idx + 2, nil
+	}
+
+	if d.remaining < 0 {
+		line, next, err := readRESPHeader(d.in, 1)
+		if err != nil {
+			return 0, err
+		}
+		n, err := parseRESPCount(line)
+		if err != nil {
+			return 0, err
+		}
+		d.remaining, d.pos = n, next
+	}
+	for d.remaining > 0 {
+		_, next, err := parseRESPBulk(d.in, d.pos, d.maxFrameLength)
+		if err != nil {
+			return 0, err
+		}
+		d.pos = next
+		d.remaining--
+	}
+	return d.pos, nil
+}
@@ -0,0 +1,117 @@
+package zdecoder
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRESPFrameDecoder(t *testing.T) {
+	d := NewRESPDecoder(map[string]uint32{"get": 1}, 0).(*RESPDecoder).NewFrameDecoder()
+
+	//半包
+	assert.Equal(t, 0, len(d.Decode([]byte("*2\r\n$3\r\nGET\r\n$3\r\nk"))))
+
+	//粘包，数组命令与inline命令
+	frames := d.Decode([]byte("ey\r\nPING\r\n"))
+	assert.Equal(t, 2, len(frames))
+
+	value, n, err := ParseRESP(frames[0], 0)
+	assert.Nil(t, err)
+	assert.Equal(t, len(frames[0]), n)
+	cmd := value.Command()
+	assert.Equal(t, "GET", cmd.Name)
+	assert.Equal(t, [][]byte{[]byte("key")}, cmd.Args)
+
+	value, _, err = ParseRESP(frames[1], 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "PING", value.Command().Name)
+}
+
+func TestParseRESP3(t *testing.T) {
+	data := RESPMap(RESPSimpleString("ok"), RESPBoolean(true), RESPBulkString([]byte("n")), RESPNull())
+	value, n, err := ParseRESP(data, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, RESPMapType, value.Type)
+	assert.Equal(t, 4, len(value.Array))
+	assert.True(t, value.Array[1].Bool)
+	assert.True(t, value.Array[3].Null)
+
+	_, _, err = ParseRESP([]byte("$10\r\nabc\r\n"), 0)
+	assert.Equal(t, errRESPIncomplete, err)
+
+	_, _, err = ParseRESP([]byte("$100\r\n"), 10)
+	assert.Equal(t, errRESPTooLarge, err)
+}
+
+func TestRESPLimits(t *testing.T) {
+	d := NewRESPDecoder(nil, 0).(*RESPDecoder).NewFrameDecoder()
+
+	//逐字节收到的命令从上次的位置继续解析
+	cmd := []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\nPING\r\n")
+	var frames [][]byte
+	for i := range cmd {
+		frames = append(frames, d.Decode(cmd[i:i+1])...)
+	}
+	assert.Equal(t, [][]byte{cmd[:len(cmd)-6], []byte("PING\r\n")}, frames)
+
+	//参数个数超过限制、参数不是Bulk String、长度行没有结束符都丢弃
+	assert.Equal(t, 0, len(d.Decode([]byte("*536870912\r\n"))))
+	assert.Equal(t, 0, len(d.Decode([]byte("*1\r\n*1\r\n*1\r\n"))))
+	assert.Equal(t, 0, len(d.Decode(append([]byte("*1\r\n$"), make([]byte, 64)...))))
+	assert.Equal(t, 1, len(d.Decode([]byte("PING\r\n"))))
+
+	_, _, err := ParseRESP([]byte("*536870912\r\n"), 0)
+	assert.Equal(t, errRESPTooLarge, err)
+	deep := []byte{}
+	for i := 0; i <= RESPMaxDepth; i++ {
+		deep = append(deep, "*1\r\n"...)
+	}
+	_, _, err = ParseRESP(append(deep, ":1\r\n"...), 0)
+	assert.Equal(t, errRESPTooDeep, err)
+	_, _, err = ParseRESPCommand([]byte("*1\r\n:1\r\n"), 0)
+	assert.Equal(t, errRESPProtocol, err)
+}
+
+func TestRESPWriter(t *testing.T) {
+	// 包含换行的简单字符串与错误改为Bulk类型
+	assert.Equal(t, []byte("+OK\r\n"), RESPSimpleString("OK"))
+	assert.Equal(t, []byte("$4\r\na\r\nb\r\n"), RESPSimpleString("a\r\nb"))
+	assert.Equal(t, []byte("-ERR x\r\n"), RESPError("ERR x"))
+	value, _, err := ParseRESP(RESPError("ERR\nx"), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, RESPBulkErrorType, value.Type)
+	assert.Equal(t, []byte("ERR\nx"), value.Str)
+
+	assert.Equal(t, []byte(",1.5\r\n"), RESPDouble(1.5))
+	assert.Equal(t, []byte(",inf\r\n"), RESPDouble(math.Inf(1)))
+	assert.Equal(t, []byte(",-inf\r\n"), RESPDouble(math.Inf(-1)))
+	assert.Equal(t, []byte(",nan\r\n"), RESPDouble(math.NaN()))
+
+	assert.Panics(t, func() { RESPMap(RESPSimpleString("k")) })
+}
+
+func TestReadRESP(t *testing.T) {
+	data := append(RESPArray(RESPBulkString([]byte("a\r\nb")), RESPInteger(1)), RESPSimpleString("OK")...)
+	r := bufio.NewReaderSize(iotest.OneByteReader(bytes.NewReader(data)), 16)
+
+	// 数据分多次到达, 只消费第一个回复
+	value, err := ReadRESP(r, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, RESPArrayType, value.Type)
+	assert.Equal(t, []byte("a\r\nb"), value.Array[0].Str)
+	assert.Equal(t, int64(1), value.Array[1].Int)
+
+	value, err = ReadRESP(r, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("OK"), value.Str)
+
+	_, err = ReadRESP(r, 0)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,120 @@
+package zdecoder
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+	RESP 回复数据的构造方法
+	Router中处理完命令后，使用以下方法构造回复数据，通过 conn.Send() 发送给客户端
+*/
+
+// RESPSimpleString +OK\r\n
+// 包含\r或\n时无法使用简单字符串表示, 改为Bulk String
+func RESPSimpleString(s string) []byte {
+	if strings.ContainsAny(s, "\r\n") {
+		return RESPBulkString([]byte(s))
+	}
+	return []byte("+" + s + "\r\n")
+}
+
+// RESPError -ERR message\r\n
+// 包含\r或\n时无法使用简单错误表示, 改为Bulk Error !len\r\nmessage\r\n (RESP3)
+func RESPError(msg string) []byte {
+	if strings.ContainsAny(msg, "\r\n") {
+		return respBulk(RESPBulkErrorType, []byte(msg))
+	}
+	return []byte("-" + msg + "\r\n")
+}
+
+// RESPInteger :1000\r\n
+func RESPInteger(n int64) []byte {
+	return []byte(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+// RESPBulkString $5\r\nhello\r\n
+func RESPBulkString(data []byte) []byte {
+	return respBulk(RESPBulkStringType, data)
+}
+
+func respBulk(typ byte, data []byte) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+16))
+	buf.WriteByte(typ)
+	buf.WriteString(strconv.Itoa(len(data)))
+	buf.WriteString("\r\n")
+	buf.Write(data)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// RESPNullBulkString $-1\r\n (RESP2的空值)
+func RESPNullBulkString() []byte {
+	return []byte("$-1\r\n")
+}
+
+// RESPArray 将已经编码好的元素组合为Array *2\r\n...
+func RESPArray(items ...[]byte) []byte {
+	return respAggregate(RESPArrayType, len(items), items)
+}
+
+// RESPNull _\r\n (RESP3的空值)
+func RESPNull() []byte {
+	return []byte("_\r\n")
+}
+
+// RESPBoolean #t\r\n (RESP3)
+func RESPBoolean(b bool) []byte {
+	if b {
+		return []byte("#t\r\n")
+	}
+	return []byte("#f\r\n")
+}
+
+// RESPDouble ,3.14\r\n (RESP3), 无穷大与非数字分别为inf、-inf与nan
+func RESPDouble(f float64) []byte {
+	var s string
+	switch {
+	case math.IsInf(f, 1):
+		s = "inf"
+	case math.IsInf(f, -1):
+		s = "-inf"
+	case math.IsNaN(f):
+		s = "nan"
+	default:
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return []byte("," + s + "\r\n")
+}
+
+// RESPMap 将已经编码好的key,value依次组合为Map %1\r\n... (RESP3)
+// kvs必须成对出现, 数量为奇数时panic
+func RESPMap(kvs ...[]byte) []byte {
+	if len(kvs)%2 == 1 {
+		panic("zdecoder: RESPMap odd argument count")
+	}
+	return respAggregate(RESPMapType, len(kvs)/2, kvs)
+}
+
+// RESPSet 将已经编码好的元素组合为Set ~2\r\n... (RESP3)
+func RESPSet(items ...[]byte) []byte {
+	return respAggregate(RESPSetType, len(items), items)
+}
+
+// RESPPush 将已经编码好的元素组合为Push >2\r\n... (RESP3)
+func RESPPush(items ...[]byte) []byte {
+	return respAggregate(RESPPushType, len(items), items)
+}
+
+func respAggregate(typ byte, n int, items [][]byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(typ)
+	buf.WriteString(strconv.Itoa(n))
+	buf.WriteString("\r\n")
+	for _, item := range items {
+		buf.Write(item)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,102 @@
+// 携带关联序号的TLV, 与zpack.SeqDataPack的封包格式对应
+//
+//+------------+------------+------------+-----------------+
+//|     Tag    |     Seq    |   Length   |     Value       |
+//| 0x00000001 | 0x00000007 | 0x0000000C | "HELLO, WORLD"  |
+//+------------+------------+------------+-----------------+
+// Tag：   uint32类型，占4字节，Tag作为MsgId
+// Seq：   uint32类型，占4字节，关联序号，0表示不携带，回复时最高位置1并回传请求的序号
+// Length：uint32类型，占4字节，Length标记Value长度
+// Value： 占n字节
+//
+//   说明：
+//   lengthFieldOffset   = 8            (Length的字节位索引下标是8) 长度字段的偏差
+//   lengthFieldLength   = 4            (Length是4个byte) 长度字段占的字节数
+//   lengthAdjustment    = 0            (Length只表示Value长度)
+//   initialBytesToStrip = 0            (返回完整的协议内容Tag+Seq+Length+Value)
+//   maxFrameLength      = 2^32 + 4 + 4 + 4
+
+package zdecoder
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const SEQ_TLV_HEADER_SIZE = 12 //表示携带关联序号的TLV空包长度
+
+type SeqTLVDecoder struct {
+	Tag    uint32 //消息类型
+	Seq    uint32 //关联序号
+	Length uint32 //消息长度
+	Value  []byte //消息内容
+}
+
+func NewSeqTLVDecoder() ziface.IDecoder {
+	return &SeqTLVDecoder{}
+}
+
+func (this *SeqTLVDecoder) GetLengthField() *ziface.LengthField {
+	return &ziface.LengthField{
+		MaxFrameLength:      math.MaxUint32 + 4 + 4 + 4,
+		LengthFieldOffset:   8,
+		LengthFieldLength:   4,
+		LengthAdjustment:    0,
+		InitialBytesToStrip: 0,
+	}
+}
+
+// NewFrameDecoder 为每个连接创建独立的断粘包解码器, 收到包头时即按消息ID校验长度, 超长的包不缓存直接丢弃
+func (this *SeqTLVDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	return newTLVFrameDecoder("SeqTLV", SEQ_TLV_HEADER_SIZE, 8)
+}
+
+func (this *SeqTLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	request := chain.Request()
+	if request == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	iRequest, ok := request.(ziface.IRequest)
+	if !ok || iRequest.GetMessage() == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	iMessage := iRequest.GetMessage()
+	data := iMessage.GetData()
+	if len(data) >= SEQ_TLV_HEADER_SIZE {
+		_data := SeqTLVDecoder{
+			Tag:    binary.BigEndian.Uint32(data[0:4]),
+			Seq:    binary.BigEndian.Uint32(data[4:8]),
+			Length: binary.BigEndian.Uint32(data[8:12]),
+		}
+
+		//按消息ID校验数据长度，超出限制或数据不完整的包直接丢弃
+		if maxSize := zconf.GlobalObject.MaxPacketSizeOf(_data.Tag); maxSize > 0 && _data.Length > maxSize {
+			zlog.Ins().ErrorF("SeqTLV-Decode msgID = %d, too large msg data received, len = %d, max = %d", _data.Tag, _data.Length, maxSize)
+			return nil
+		}
+		if uint64(len(data)) < uint64(SEQ_TLV_HEADER_SIZE)+uint64(_data.Length) {
+			zlog.Ins().ErrorF("SeqTLV-Decode msgID = %d, incomplete msg data, len = %d, size = %d", _data.Tag, _data.Length, len(data))
+			return nil
+		}
+
+		_data.Value = make([]byte, _data.Length)
+		copy(_data.Value, data[SEQ_TLV_HEADER_SIZE:])
+
+		iMessage.SetMsgID(_data.Tag)
+		iMessage.SetData(_data.Value)
+		iMessage.SetDataLen(_data.Length)
+		if seqMsg, ok := iMessage.(ziface.ISeqMessage); ok {
+			seqMsg.SetSeq(_data.Seq)
+		}
+
+		iRequest.SetResponse(_data)
+	}
+
+	return chain.Proceed(chain.Request())
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
 	"math"
@@ -65,6 +66,11 @@ func (this *TLVDecoder) GetLengthField() *ziface.LengthField {
 	}
 }
 
+// NewFrameDecoder 为每个连接创建独立的断粘包解码器, 收到包头时即按消息ID校验长度, 超长的包不缓存直接丢弃
+func (this *TLVDecoder) NewFrameDecoder() ziface.IFrameDecoder {
+	return newTLVFrameDecoder("TLV", TLV_HEADER_SIZE, 4)
+}
+
 func (this *TLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
 	request := chain.Request()
 
@@ -95,6 +101,17 @@ func (this *TLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
 			_data.Tag = binary.BigEndian.Uint32(data[0:4])
 			//获取L
 			_data.Length = binary.BigEndian.Uint32(data[4:8])
+
+			//按消息ID校验数据长度，超出限制或数据不完整的包直接丢弃
+			if maxSize := zconf.GlobalObject.MaxPacketSizeOf(_data.Tag); maxSize > 0 && _data.Length > maxSize {
+				zlog.Ins().ErrorF("TLV-Decode msgID = %d, too large msg data received, len = %d, max = %d", _data.Tag, _data.Length, maxSize)
+				return nil
+			}
+			if uint64(datasize) < uint64(TLV_HEADER_SIZE)+uint64(_data.Length) {
+				zlog.Ins().ErrorF("TLV-Decode msgID = %d, incomplete msg data, len = %d, size = %d", _data.Tag, _data.Length, datasize)
+				return nil
+			}
+
 			//确定V的长度
 			_data.Value = make([]byte, _data.Length)
 
@@ -0,0 +1,74 @@
+package zdecoder
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zlog"
+)
+
+// tlvFrameDecoder TLV系列协议(TLV、SeqTLV、MuxTLV)的断粘包
+// 收到包头(Tag与Length)后立即按消息ID校验长度(zconf.MaxPacketSizeOf)，
+// 超出限制的包在读取时直接丢弃，不缓存包体，避免声明超大长度的数据包占用内存
+type tlvFrameDecoder struct {
+	name         string //协议名称，用于日志
+	headerSize   int    //包头长度
+	lengthOffset int    //Length字段的偏移量，Tag固定在包头的起始位置
+	discard      uint64 //超长的包还需要丢弃的字节数
+	in           []byte
+	lock         sync.Mutex
+}
+
+func newTLVFrameDecoder(name string, headerSize, lengthOffset int) *tlvFrameDecoder {
+	return &tlvFrameDecoder{
+		name:         name,
+		headerSize:   headerSize,
+		lengthOffset: lengthOffset,
+	}
+}
+
+func (d *tlvFrameDecoder) Decode(buff []byte) [][]byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	//丢弃超长包的剩余部分
+	if d.discard > 0 {
+		n := d.discard
+		if n > uint64(len(buff)) {
+			n = uint64(len(buff))
+		}
+		buff = buff[n:]
+		d.discard -= n
+	}
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	for len(d.in) >= d.headerSize {
+		tag := binary.BigEndian.Uint32(d.in[0:4])
+		length := binary.BigEndian.Uint32(d.in[d.lengthOffset : d.lengthOffset+4])
+		size := uint64(d.headerSize) + uint64(length)
+
+		if maxSize := zconf.GlobalObject.MaxPacketSizeOf(tag); maxSize > 0 && length > maxSize {
+			zlog.Ins().ErrorF("%s-Decode msgID = %d, too large msg data received, len = %d, max = %d, discard", d.name, tag, length, maxSize)
+			if size > uint64(len(d.in)) {
+				d.discard = size - uint64(len(d.in))
+				d.in = d.in[:0]
+				break
+			}
+			d.in = d.in[size:]
+			continue
+		}
+
+		if uint64(len(d.in)) < size {
+			//半包
+			break
+		}
+		frame := make([]byte, size)
+		copy(frame, d.in[:size])
+		resp = append(resp, frame)
+		d.in = d.in[size:]
+	}
+
+	return resp
+}
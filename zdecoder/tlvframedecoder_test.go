@@ -0,0 +1,48 @@
+package zdecoder
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func tlvPacket(tag uint32, value []byte) []byte {
+	buf := make([]byte, TLV_HEADER_SIZE+len(value))
+	binary.BigEndian.PutUint32(buf[0:4], tag)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(value)))
+	copy(buf[8:], value)
+	return buf
+}
+
+func TestTLVFrameDecoderMaxPacketSize(t *testing.T) {
+	old := zconf.GlobalObject.MsgMaxPacketSize
+	zconf.GlobalObject.MsgMaxPacketSize = map[uint32]uint32{1: 4}
+	defer func() { zconf.GlobalObject.MsgMaxPacketSize = old }()
+
+	d := NewTLVDecoder().(ziface.IFrameDecoderMaker).NewFrameDecoder()
+
+	//声明超大长度的包在收到包头时即丢弃, 不缓存包体
+	huge := make([]byte, TLV_HEADER_SIZE)
+	binary.BigEndian.PutUint32(huge[0:4], 1)
+	binary.BigEndian.PutUint32(huge[4:8], 1<<30)
+	assert.Equal(t, 0, len(d.Decode(huge)))
+	assert.Equal(t, 0, len(d.(*tlvFrameDecoder).in))
+	assert.Equal(t, uint64(1<<30), d.(*tlvFrameDecoder).discard)
+
+	//完整收到的超长包丢弃后继续解析后面的包
+	d = NewTLVDecoder().(ziface.IFrameDecoderMaker).NewFrameDecoder()
+	data := append(tlvPacket(1, []byte("too long")), tlvPacket(1, []byte("ok"))...)
+	data = append(data, tlvPacket(2, []byte("other msg"))...)
+	frames := d.Decode(data[:20])
+	frames = append(frames, d.Decode(data[20:])...)
+	assert.Equal(t, [][]byte{tlvPacket(1, []byte("ok")), tlvPacket(2, []byte("other msg"))}, frames)
+
+	//跨多次读取的超长包
+	d = NewTLVDecoder().(ziface.IFrameDecoderMaker).NewFrameDecoder()
+	assert.Equal(t, 0, len(d.Decode(data[:10])))
+	frames = d.Decode(data[10:])
+	assert.Equal(t, 2, len(frames))
+}
@@ -0,0 +1,155 @@
+package zdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// ConsulRegistry 基于Consul agent HTTP接口的注册中心
+// 实例注册为Consul服务并附带TTL检查, 续约时更新服务元数据并将检查置为通过, 超过TTL未续约时实例不再被发现
+type ConsulRegistry struct {
+	addrs  []string
+	token  string
+	client *http.Client
+
+	// 实例ID -> TTL, 续约时更新元数据需要重新注册
+	ttls map[string]time.Duration
+	lock sync.Mutex
+}
+
+// NewConsulRegistry 创建Consul注册中心, addrs为agent的地址, 如"http://127.0.0.1:8500", token为ACL令牌, 可以为空
+func NewConsulRegistry(addrs []string, token string) *ConsulRegistry {
+	return &ConsulRegistry{
+		addrs:  addrs,
+		token:  token,
+		client: &http.Client{Timeout: DefaultTimeout},
+		ttls:   make(map[string]time.Duration),
+	}
+}
+
+// call 依次尝试每个agent地址
+func (r *ConsulRegistry) call(ctx context.Context, method, path string, req interface{}, resp interface{}) error {
+	var body []byte
+	if req != nil {
+		var err error
+		if body, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if r.token != "" {
+		header.Set("X-Consul-Token", r.token)
+	}
+
+	err := errors.New("no consul address")
+	for _, addr := range r.addrs {
+		if err = doHTTP(ctx, r.client, method, strings.TrimSuffix(addr, "/")+path, header, body, resp); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func checkID(instance ziface.ServiceInstance) string {
+	return "service:" + instance.ID
+}
+
+// Register 注册服务并附带TTL检查, 检查持续失败一段时间后由Consul注销
+func (r *ConsulRegistry) Register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	if err := r.register(ctx, instance, ttl); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.ttls[instance.ID] = ttl
+	r.lock.Unlock()
+	return r.pass(ctx, instance)
+}
+
+func (r *ConsulRegistry) register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	host, port, err := splitAddr(instance.Addr)
+	if err != nil {
+		return err
+	}
+	service := map[string]interface{}{
+		"ID":      instance.ID,
+		"Name":    instance.Name,
+		"Address": host,
+		"Port":    port,
+		"Meta":    toMeta(instance),
+		"Check": map[string]string{
+			"CheckID":                        checkID(instance),
+			"TTL":                            ttl.String(),
+			"DeregisterCriticalServiceAfter": (10 * ttl).String(),
+		},
+	}
+	return r.call(ctx, http.MethodPut, "/v1/agent/service/register", service, nil)
+}
+
+func (r *ConsulRegistry) pass(ctx context.Context, instance ziface.ServiceInstance) error {
+	return r.call(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape(checkID(instance)), nil, nil)
+}
+
+// KeepAlive 更新服务元数据(负载)并将TTL检查置为通过
+func (r *ConsulRegistry) KeepAlive(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	ttl, ok := r.ttls[instance.ID]
+	r.lock.Unlock()
+	if !ok {
+		return ErrNotRegistered
+	}
+
+	var service struct {
+		Meta map[string]string
+	}
+	// 服务已被注销(如agent重启)时返回错误, 由调用方重新注册
+	if err := r.call(ctx, http.MethodGet, "/v1/agent/service/"+url.PathEscape(instance.ID), nil, &service); err != nil {
+		return err
+	}
+	if service.Meta[metaLoad] != toMeta(instance)[metaLoad] {
+		if err := r.register(ctx, instance, ttl); err != nil {
+			return err
+		}
+	}
+	return r.pass(ctx, instance)
+}
+
+// Deregister 注销服务, 检查随之删除
+func (r *ConsulRegistry) Deregister(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	delete(r.ttls, instance.ID)
+	r.lock.Unlock()
+	return r.call(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(instance.ID), nil, nil)
+}
+
+// Discover 查询服务全部检查通过的实例
+func (r *ConsulRegistry) Discover(ctx context.Context, name string) ([]ziface.ServiceInstance, error) {
+	var entries []struct {
+		Service struct {
+			Service string
+			Address string
+			Port    int
+			Meta    map[string]string
+		}
+	}
+	if err := r.call(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(name)+"?passing=true", nil, &entries); err != nil {
+		return nil, err
+	}
+
+	instances := make([]ziface.ServiceInstance, 0, len(entries))
+	for _, entry := range entries {
+		instances = append(instances, fromMeta(name, entry.Service.Address, entry.Service.Port, entry.Service.Meta))
+	}
+	return instances, nil
+}
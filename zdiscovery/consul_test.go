@@ -0,0 +1,143 @@
+package zdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type consulService struct {
+	ID      string
+	Service string
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+// fakeConsul 内存中的Consul agent, 只实现注册中心使用的接口
+type fakeConsul struct {
+	lock     sync.Mutex
+	services map[string]*consulService
+	passing  map[string]bool // checkID -> 检查通过
+	tokens   []string
+}
+
+func newFakeConsul() *httptest.Server {
+	return httptest.NewServer(&fakeConsul{services: map[string]*consulService{}, passing: map[string]bool{}})
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.tokens = append(f.tokens, r.Header.Get("X-Consul-Token"))
+
+	path := r.URL.Path
+	switch {
+	case path == "/v1/agent/service/register":
+		var req struct {
+			ID, Name, Address string
+			Port              int
+			Meta              map[string]string
+			Check             map[string]string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.services[req.ID] = &consulService{ID: req.ID, Service: req.Name, Address: req.Address, Port: req.Port, Meta: req.Meta}
+		if _, ok := f.passing[req.Check["CheckID"]]; !ok {
+			f.passing[req.Check["CheckID"]] = false
+		}
+	case strings.HasPrefix(path, "/v1/agent/check/pass/"):
+		checkID := strings.TrimPrefix(path, "/v1/agent/check/pass/")
+		if _, ok := f.passing[checkID]; !ok {
+			http.Error(w, "unknown check", http.StatusNotFound)
+			return
+		}
+		f.passing[checkID] = true
+	case strings.HasPrefix(path, "/v1/agent/service/deregister/"):
+		id := strings.TrimPrefix(path, "/v1/agent/service/deregister/")
+		delete(f.services, id)
+		delete(f.passing, "service:"+id)
+	case strings.HasPrefix(path, "/v1/agent/service/"):
+		service, ok := f.services[strings.TrimPrefix(path, "/v1/agent/service/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(service)
+	case strings.HasPrefix(path, "/v1/health/service/"):
+		name := strings.TrimPrefix(path, "/v1/health/service/")
+		entries := []map[string]interface{}{}
+		for _, service := range f.services {
+			if service.Service == name && f.passing["service:"+service.ID] {
+				entries = append(entries, map[string]interface{}{"Service": service})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// restart 模拟agent重启, 丢失全部服务
+func (f *fakeConsul) restart() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.services = map[string]*consulService{}
+	f.passing = map[string]bool{}
+}
+
+func TestConsulRegistry(t *testing.T) {
+	server := newFakeConsul()
+	defer server.Close()
+	consul := server.Config.Handler.(*fakeConsul)
+
+	registry, err := NewRegistry(KindConsul, []string{"http://127.0.0.1:1", server.URL}, "", "secret")
+	assert.Nil(t, err)
+	ctx := context.Background()
+	a := ziface.ServiceInstance{ID: "a", Name: "game", Addr: "10.0.0.1:8999", Metadata: map[string]string{"zone": "1"}}
+	b := ziface.ServiceInstance{ID: "b", Name: "game", Addr: "10.0.0.2:8999"}
+	for _, instance := range []ziface.ServiceInstance{a, b} {
+		assert.Nil(t, registry.Register(ctx, instance, 10*time.Second))
+	}
+
+	instances, err := registry.Discover(ctx, "game")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(instances))
+
+	// 续约时更新负载
+	a.Load = 42
+	assert.Nil(t, registry.KeepAlive(ctx, a))
+	instances, _ = registry.Discover(ctx, "game")
+	for _, instance := range instances {
+		if instance.ID == "a" {
+			assert.Equal(t, 42, instance.Load)
+			assert.Equal(t, "1", instance.Metadata["zone"])
+			assert.Equal(t, "10.0.0.1:8999", instance.Addr)
+		}
+	}
+
+	assert.Nil(t, registry.Deregister(ctx, b))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, ErrNotRegistered, registry.KeepAlive(ctx, b))
+
+	// agent丢失服务后续约失败, 需要重新注册
+	consul.restart()
+	assert.NotNil(t, registry.KeepAlive(ctx, a))
+	assert.Nil(t, registry.Register(ctx, a, 10*time.Second))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, 1, len(instances))
+
+	consul.lock.Lock()
+	defer consul.lock.Unlock()
+	for _, token := range consul.tokens {
+		assert.Equal(t, "secret", token)
+	}
+}
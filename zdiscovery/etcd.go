@@ -0,0 +1,198 @@
+// Package zdiscovery 提供服务注册与发现的注册中心实现, 支持etcd、Consul与Nacos
+// 通过注册中心的HTTP接口访问, 不依赖注册中心的客户端库
+package zdiscovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// DefaultEtcdPrefix etcd中服务实例键的默认前缀, 键为"前缀/服务名称/实例ID"
+const DefaultEtcdPrefix = "/zinx/services"
+
+// DefaultTimeout 访问注册中心默认的超时时间
+const DefaultTimeout = 5 * time.Second
+
+var ErrLeaseNotFound = errors.New("etcd lease not found")
+
+var jsonHeader = http.Header{"Content-Type": {"application/json"}}
+
+// EtcdRegistry 基于etcd v3的注册中心, 通过etcd的HTTP/JSON网关访问
+// 实例信息以JSON写入"前缀/服务名称/实例ID", 绑定TTL租约, 服务异常退出后由etcd在租约过期后删除
+type EtcdRegistry struct {
+	endpoints []string
+	prefix    string
+	client    *http.Client
+
+	// 实例ID -> 租约ID
+	leases map[string]int64
+	lock   sync.Mutex
+}
+
+// NewEtcdRegistry 创建etcd注册中心, endpoints为etcd的地址, 如"http://127.0.0.1:2379", prefix为空时使用DefaultEtcdPrefix
+func NewEtcdRegistry(endpoints []string, prefix string) *EtcdRegistry {
+	if prefix == "" {
+		prefix = DefaultEtcdPrefix
+	}
+	return &EtcdRegistry{
+		endpoints: endpoints,
+		prefix:    strings.TrimSuffix(prefix, "/"),
+		client:    &http.Client{Timeout: DefaultTimeout},
+		leases:    make(map[string]int64),
+	}
+}
+
+func (r *EtcdRegistry) key(name, id string) string {
+	return r.prefix + "/" + name + "/" + id
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// call 依次尝试每个endpoint调用etcd的HTTP/JSON接口
+func (r *EtcdRegistry) call(ctx context.Context, path string, req interface{}, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	err = errors.New("no etcd endpoint")
+	for _, endpoint := range r.endpoints {
+		err = doHTTP(ctx, r.client, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, jsonHeader, body, resp)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+type etcdLease struct {
+	ID  int64 `json:"ID,string"`
+	TTL int64 `json:"TTL,string"`
+}
+
+func (r *EtcdRegistry) put(ctx context.Context, instance ziface.ServiceInstance, lease int64) error {
+	value, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+	return r.call(ctx, "/v3/kv/put", map[string]string{
+		"key":   b64(r.key(instance.Name, instance.ID)),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": strconv.FormatInt(lease, 10),
+	}, nil)
+}
+
+// Register 创建TTL租约并写入实例信息
+func (r *EtcdRegistry) Register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	var lease etcdLease
+	if err := r.call(ctx, "/v3/lease/grant", map[string]string{"TTL": strconv.FormatInt(seconds, 10)}, &lease); err != nil {
+		return err
+	}
+	if err := r.put(ctx, instance, lease.ID); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.leases[instance.ID] = lease.ID
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *EtcdRegistry) lease(id string) (int64, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	lease, ok := r.leases[id]
+	return lease, ok
+}
+
+// KeepAlive 续约租约并更新实例信息, 租约已经过期时返回ErrLeaseNotFound, 需要重新注册
+func (r *EtcdRegistry) KeepAlive(ctx context.Context, instance ziface.ServiceInstance) error {
+	leaseID, ok := r.lease(instance.ID)
+	if !ok {
+		return ErrLeaseNotFound
+	}
+	var resp struct {
+		Result etcdLease `json:"result"`
+	}
+	if err := r.call(ctx, "/v3/lease/keepalive", map[string]string{"ID": strconv.FormatInt(leaseID, 10)}, &resp); err != nil {
+		return err
+	}
+	if resp.Result.TTL <= 0 {
+		r.lock.Lock()
+		delete(r.leases, instance.ID)
+		r.lock.Unlock()
+		return ErrLeaseNotFound
+	}
+	return r.put(ctx, instance, leaseID)
+}
+
+// Deregister 撤销租约, 绑定租约的实例信息随之删除
+func (r *EtcdRegistry) Deregister(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	leaseID, ok := r.leases[instance.ID]
+	delete(r.leases, instance.ID)
+	r.lock.Unlock()
+
+	if ok {
+		return r.call(ctx, "/v3/lease/revoke", map[string]string{"ID": strconv.FormatInt(leaseID, 10)}, nil)
+	}
+	return r.call(ctx, "/v3/kv/deleterange", map[string]string{"key": b64(r.key(instance.Name, instance.ID))}, nil)
+}
+
+// Discover 查询服务的全部实例
+func (r *EtcdRegistry) Discover(ctx context.Context, name string) ([]ziface.ServiceInstance, error) {
+	prefix := r.key(name, "")
+	var resp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	err := r.call(ctx, "/v3/kv/range", map[string]string{
+		"key":       b64(prefix),
+		"range_end": b64(prefixEnd(prefix)),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]ziface.ServiceInstance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			continue
+		}
+		var instance ziface.ServiceInstance
+		if err := json.Unmarshal(value, &instance); err != nil {
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// prefixEnd 前缀查询的range_end, 前缀最后一个字节加1
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00"
+}
@@ -0,0 +1,146 @@
+package zdiscovery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEtcd 内存中的etcd HTTP/JSON网关, 只实现注册中心使用的接口
+type fakeEtcd struct {
+	lock   sync.Mutex
+	kvs    map[string]string // key -> value(base64)
+	owner  map[string]int64  // key -> lease
+	leases map[int64]bool
+	nextID int64
+}
+
+func newFakeEtcd() *httptest.Server {
+	f := &fakeEtcd{kvs: map[string]string{}, owner: map[string]int64{}, leases: map[int64]bool{}}
+	return httptest.NewServer(f)
+}
+
+func decodeKey(s string) string {
+	b, _ := base64.StdEncoding.DecodeString(s)
+	return string(b)
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	resp := map[string]interface{}{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextID++
+		f.leases[f.nextID] = true
+		resp["ID"] = strconv.FormatInt(f.nextID, 10)
+		resp["TTL"] = req["TTL"]
+	case "/v3/lease/keepalive":
+		id, _ := strconv.ParseInt(req["ID"], 10, 64)
+		result := map[string]string{"ID": req["ID"]}
+		if f.leases[id] {
+			result["TTL"] = "10"
+		}
+		resp["result"] = result
+	case "/v3/lease/revoke":
+		id, _ := strconv.ParseInt(req["ID"], 10, 64)
+		delete(f.leases, id)
+		for key, lease := range f.owner {
+			if lease == id {
+				delete(f.kvs, key)
+				delete(f.owner, key)
+			}
+		}
+	case "/v3/kv/put":
+		key := decodeKey(req["key"])
+		id, _ := strconv.ParseInt(req["lease"], 10, 64)
+		if !f.leases[id] {
+			http.Error(w, `{"error":"lease not found"}`, http.StatusNotFound)
+			return
+		}
+		f.kvs[key] = req["value"]
+		f.owner[key] = id
+	case "/v3/kv/range":
+		start, end := decodeKey(req["key"]), decodeKey(req["range_end"])
+		var kvs []map[string]string
+		for key, value := range f.kvs {
+			if key >= start && key < end {
+				kvs = append(kvs, map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key)), "value": value})
+			}
+		}
+		resp["kvs"] = kvs
+	case "/v3/kv/deleterange":
+		key := decodeKey(req["key"])
+		delete(f.kvs, key)
+		delete(f.owner, key)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// expire 模拟全部租约过期
+func (f *fakeEtcd) expire() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.leases = map[int64]bool{}
+	f.kvs = map[string]string{}
+	f.owner = map[string]int64{}
+}
+
+func TestEtcdRegistry(t *testing.T) {
+	server := newFakeEtcd()
+	defer server.Close()
+	etcd := server.Config.Handler.(*fakeEtcd)
+
+	// 第一个endpoint不可用时使用下一个
+	registry := NewEtcdRegistry([]string{"http://127.0.0.1:1", server.URL}, "")
+	ctx := context.Background()
+	a := ziface.ServiceInstance{ID: "a", Name: "game", Addr: "10.0.0.1:8999", Metadata: map[string]string{"zone": "1"}}
+	b := ziface.ServiceInstance{ID: "b", Name: "game", Addr: "10.0.0.2:8999"}
+	other := ziface.ServiceInstance{ID: "c", Name: "gamex", Addr: "10.0.0.3:8999"}
+	for _, instance := range []ziface.ServiceInstance{a, b, other} {
+		assert.Nil(t, registry.Register(ctx, instance, 10*time.Second))
+	}
+
+	instances, err := registry.Discover(ctx, "game")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(instances))
+
+	// 续约时更新负载
+	a.Load = 42
+	assert.Nil(t, registry.KeepAlive(ctx, a))
+	instances, _ = registry.Discover(ctx, "game")
+	for _, instance := range instances {
+		if instance.ID == "a" {
+			assert.Equal(t, 42, instance.Load)
+			assert.Equal(t, "1", instance.Metadata["zone"])
+		}
+	}
+
+	assert.Nil(t, registry.Deregister(ctx, b))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, []string{"a"}, []string{instances[0].ID})
+	assert.Equal(t, 1, len(instances))
+
+	// 租约过期后续约失败, 需要重新注册
+	etcd.expire()
+	assert.Equal(t, ErrLeaseNotFound, registry.KeepAlive(ctx, a))
+	assert.Equal(t, ErrLeaseNotFound, registry.KeepAlive(ctx, a))
+	assert.Nil(t, registry.Register(ctx, a, 10*time.Second))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, 1, len(instances))
+}
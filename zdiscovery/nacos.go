@@ -0,0 +1,196 @@
+package zdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// nacosResourceNotFound Nacos心跳时实例不存在返回的code, 需要重新注册
+const nacosResourceNotFound = 20404
+
+// NacosRegistry 基于Nacos v1 Open API的注册中心
+// 实例注册为临时实例, 续约时发送心跳, 超过TTL没有心跳时Nacos将实例置为不健康并删除
+type NacosRegistry struct {
+	addrs     []string
+	namespace string
+	client    *http.Client
+
+	// 实例ID -> TTL, 心跳与重新注册时使用
+	ttls map[string]time.Duration
+	lock sync.Mutex
+}
+
+// NewNacosRegistry 创建Nacos注册中心, addrs为Nacos的地址, 如"http://127.0.0.1:8848", namespace为命名空间ID, 为空时使用public
+func NewNacosRegistry(addrs []string, namespace string) *NacosRegistry {
+	return &NacosRegistry{
+		addrs:     addrs,
+		namespace: namespace,
+		client:    &http.Client{Timeout: DefaultTimeout},
+		ttls:      make(map[string]time.Duration),
+	}
+}
+
+// call 依次尝试每个Nacos地址
+func (r *NacosRegistry) call(ctx context.Context, method, path string, query url.Values, resp interface{}) error {
+	if r.namespace != "" {
+		query.Set("namespaceId", r.namespace)
+	}
+
+	err := errors.New("no nacos address")
+	for _, addr := range r.addrs {
+		u := strings.TrimSuffix(addr, "/") + path + "?" + query.Encode()
+		if err = doHTTP(ctx, r.client, method, u, nil, nil, resp); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// nacosMeta 实例元数据, 附加Nacos的心跳超时与删除时间
+func nacosMeta(instance ziface.ServiceInstance, ttl time.Duration) map[string]string {
+	meta := toMeta(instance)
+	meta["preserved.heart.beat.interval"] = strconv.FormatInt((ttl / 3).Milliseconds(), 10)
+	meta["preserved.heart.beat.timeout"] = strconv.FormatInt(ttl.Milliseconds(), 10)
+	meta["preserved.ip.delete.timeout"] = strconv.FormatInt((2 * ttl).Milliseconds(), 10)
+	return meta
+}
+
+// Register 注册临时实例
+func (r *NacosRegistry) Register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	host, port, err := splitAddr(instance.Addr)
+	if err != nil {
+		return err
+	}
+	meta, err := json.Marshal(nacosMeta(instance, ttl))
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"serviceName": {instance.Name},
+		"ip":          {host},
+		"port":        {strconv.Itoa(port)},
+		"metadata":    {string(meta)},
+		"ephemeral":   {"true"},
+		"healthy":     {"true"},
+	}
+	if err := r.call(ctx, http.MethodPost, "/nacos/v1/ns/instance", query, nil); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.ttls[instance.ID] = ttl
+	r.lock.Unlock()
+	return nil
+}
+
+// KeepAlive 发送心跳, 负载变化时重新注册以更新元数据
+func (r *NacosRegistry) KeepAlive(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	ttl, ok := r.ttls[instance.ID]
+	r.lock.Unlock()
+	if !ok {
+		return ErrNotRegistered
+	}
+	host, port, err := splitAddr(instance.Addr)
+	if err != nil {
+		return err
+	}
+	meta := nacosMeta(instance, ttl)
+	beat, err := json.Marshal(map[string]interface{}{
+		"serviceName": instance.Name,
+		"ip":          host,
+		"port":        port,
+		"metadata":    meta,
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Code int `json:"code"`
+	}
+	query := url.Values{"serviceName": {instance.Name}, "beat": {string(beat)}}
+	if err := r.call(ctx, http.MethodPut, "/nacos/v1/ns/instance/beat", query, &resp); err != nil {
+		return err
+	}
+	// 心跳不会更新已注册实例的元数据, 实例不存在或负载变化时重新注册
+	if resp.Code == nacosResourceNotFound || r.loadChanged(ctx, instance, host, port) {
+		return r.Register(ctx, instance, ttl)
+	}
+	return nil
+}
+
+// loadChanged Nacos中实例记录的负载与当前负载不同
+func (r *NacosRegistry) loadChanged(ctx context.Context, instance ziface.ServiceInstance, host string, port int) bool {
+	var resp struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	query := url.Values{
+		"serviceName": {instance.Name},
+		"ip":          {host},
+		"port":        {strconv.Itoa(port)},
+	}
+	if err := r.call(ctx, http.MethodGet, "/nacos/v1/ns/instance", query, &resp); err != nil {
+		return true
+	}
+	return resp.Metadata[metaLoad] != strconv.Itoa(instance.Load)
+}
+
+// Deregister 注销实例
+func (r *NacosRegistry) Deregister(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	delete(r.ttls, instance.ID)
+	r.lock.Unlock()
+
+	host, port, err := splitAddr(instance.Addr)
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"serviceName": {instance.Name},
+		"ip":          {host},
+		"port":        {strconv.Itoa(port)},
+		"ephemeral":   {"true"},
+	}
+	return r.call(ctx, http.MethodDelete, "/nacos/v1/ns/instance", query, nil)
+}
+
+// Discover 查询服务全部健康的实例
+func (r *NacosRegistry) Discover(ctx context.Context, name string) ([]ziface.ServiceInstance, error) {
+	var resp struct {
+		Hosts []struct {
+			IP       string            `json:"ip"`
+			Port     int               `json:"port"`
+			Metadata map[string]string `json:"metadata"`
+		} `json:"hosts"`
+	}
+	query := url.Values{"serviceName": {name}, "healthyOnly": {"true"}}
+	if err := r.call(ctx, http.MethodGet, "/nacos/v1/ns/instance/list", query, &resp); err != nil {
+		return nil, err
+	}
+
+	instances := make([]ziface.ServiceInstance, 0, len(resp.Hosts))
+	for _, host := range resp.Hosts {
+		meta := make(map[string]string, len(host.Metadata))
+		for k, v := range host.Metadata {
+			if !strings.HasPrefix(k, "preserved.") {
+				meta[k] = v
+			}
+		}
+		instances = append(instances, fromMeta(name, host.IP, host.Port, meta))
+	}
+	return instances, nil
+}
@@ -0,0 +1,144 @@
+package zdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type nacosInstance struct {
+	IP       string            `json:"ip"`
+	Port     int               `json:"port"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// fakeNacos 内存中的Nacos naming服务, 只实现注册中心使用的接口
+type fakeNacos struct {
+	lock       sync.Mutex
+	instances  map[string]map[string]*nacosInstance // namespace/serviceName -> ip:port -> 实例
+	beats      int
+	namespaces []string
+}
+
+func newFakeNacos() *httptest.Server {
+	return httptest.NewServer(&fakeNacos{instances: map[string]map[string]*nacosInstance{}})
+}
+
+func (f *fakeNacos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	query := r.URL.Query()
+	f.namespaces = append(f.namespaces, query.Get("namespaceId"))
+	service := query.Get("namespaceId") + "/" + query.Get("serviceName")
+	key := query.Get("ip") + ":" + query.Get("port")
+
+	switch {
+	case r.URL.Path == "/nacos/v1/ns/instance" && r.Method == http.MethodPost:
+		port, _ := strconv.Atoi(query.Get("port"))
+		instance := &nacosInstance{IP: query.Get("ip"), Port: port}
+		_ = json.Unmarshal([]byte(query.Get("metadata")), &instance.Metadata)
+		if f.instances[service] == nil {
+			f.instances[service] = map[string]*nacosInstance{}
+		}
+		f.instances[service][key] = instance
+		_, _ = w.Write([]byte("ok"))
+	case r.URL.Path == "/nacos/v1/ns/instance" && r.Method == http.MethodGet:
+		instance, ok := f.instances[service][key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(instance)
+	case r.URL.Path == "/nacos/v1/ns/instance" && r.Method == http.MethodDelete:
+		delete(f.instances[service], key)
+		_, _ = w.Write([]byte("ok"))
+	case r.URL.Path == "/nacos/v1/ns/instance/beat":
+		f.beats++
+		var beat struct {
+			IP   string `json:"ip"`
+			Port int    `json:"port"`
+		}
+		_ = json.Unmarshal([]byte(query.Get("beat")), &beat)
+		code := 10200
+		if _, ok := f.instances[service][beat.IP+":"+strconv.Itoa(beat.Port)]; !ok {
+			code = nacosResourceNotFound
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"code": code, "clientBeatInterval": 5000})
+	case r.URL.Path == "/nacos/v1/ns/instance/list":
+		var hosts []*nacosInstance
+		for _, instance := range f.instances[service] {
+			hosts = append(hosts, instance)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"hosts": hosts})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// expire 模拟实例心跳超时被删除
+func (f *fakeNacos) expire() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.instances = map[string]map[string]*nacosInstance{}
+}
+
+func TestNacosRegistry(t *testing.T) {
+	server := newFakeNacos()
+	defer server.Close()
+	nacos := server.Config.Handler.(*fakeNacos)
+
+	registry, err := NewRegistry(KindNacos, []string{"http://127.0.0.1:1", server.URL}, "dev", "")
+	assert.Nil(t, err)
+	ctx := context.Background()
+	a := ziface.ServiceInstance{ID: "a", Name: "game", Addr: "10.0.0.1:8999", Metadata: map[string]string{"zone": "1"}}
+	b := ziface.ServiceInstance{ID: "b", Name: "game", Addr: "10.0.0.2:8999"}
+	for _, instance := range []ziface.ServiceInstance{a, b} {
+		assert.Nil(t, registry.Register(ctx, instance, 9*time.Second))
+	}
+
+	instances, err := registry.Discover(ctx, "game")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(instances))
+
+	// 续约时发送心跳并更新负载, Nacos保留的元数据不返回
+	a.Load = 42
+	assert.Nil(t, registry.KeepAlive(ctx, a))
+	instances, _ = registry.Discover(ctx, "game")
+	for _, instance := range instances {
+		if instance.ID == "a" {
+			assert.Equal(t, 42, instance.Load)
+			assert.Equal(t, map[string]string{"zone": "1"}, instance.Metadata)
+			assert.Equal(t, "10.0.0.1:8999", instance.Addr)
+		}
+	}
+
+	assert.Nil(t, registry.Deregister(ctx, b))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, 1, len(instances))
+
+	// 实例被删除后心跳时重新注册
+	nacos.expire()
+	assert.Nil(t, registry.KeepAlive(ctx, a))
+	instances, _ = registry.Discover(ctx, "game")
+	assert.Equal(t, 1, len(instances))
+
+	nacos.lock.Lock()
+	defer nacos.lock.Unlock()
+	assert.Equal(t, 2, nacos.beats)
+	for _, namespace := range nacos.namespaces {
+		assert.Equal(t, "dev", namespace)
+	}
+}
+
+func TestNewRegistryUnknown(t *testing.T) {
+	_, err := NewRegistry("zookeeper", nil, "", "")
+	assert.NotNil(t, err)
+}
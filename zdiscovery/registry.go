@@ -0,0 +1,122 @@
+package zdiscovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// 注册中心类型
+const (
+	KindEtcd   = "etcd"
+	KindConsul = "consul"
+	KindNacos  = "nacos"
+)
+
+// 保存在Consul与Nacos实例元数据中的zinx实例信息
+const (
+	metaID   = "zinx.id"
+	metaLoad = "zinx.load"
+)
+
+// MetaAdminAddr 实例元数据中管理HTTP服务的地址, 服务设置了管理HTTP服务时自动添加, 用于集群管理工具访问每个节点
+const MetaAdminAddr = "zinx.admin"
+
+// ErrNotRegistered 续约的实例没有通过当前注册中心注册
+var ErrNotRegistered = errors.New("service instance is not registered")
+
+// NewRegistry 按类型创建注册中心, addrs为注册中心的地址, namespace为etcd的键前缀或Nacos的命名空间ID, token为Consul的ACL令牌
+func NewRegistry(kind string, addrs []string, namespace string, token string) (ziface.IRegistry, error) {
+	switch kind {
+	case KindEtcd:
+		return NewEtcdRegistry(addrs, namespace), nil
+	case KindConsul:
+		return NewConsulRegistry(addrs, token), nil
+	case KindNacos:
+		return NewNacosRegistry(addrs, namespace), nil
+	}
+	return nil, fmt.Errorf("unknown registry %q", kind)
+}
+
+// splitAddr 拆分实例地址的host与port
+func splitAddr(addr string) (string, int, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, p, nil
+}
+
+// toMeta 实例的元数据, 附加实例ID与负载
+func toMeta(instance ziface.ServiceInstance) map[string]string {
+	meta := make(map[string]string, len(instance.Metadata)+2)
+	for k, v := range instance.Metadata {
+		meta[k] = v
+	}
+	meta[metaID] = instance.ID
+	meta[metaLoad] = strconv.Itoa(instance.Load)
+	return meta
+}
+
+// fromMeta 从注册中心返回的地址与元数据还原实例
+func fromMeta(name, host string, port int, meta map[string]string) ziface.ServiceInstance {
+	instance := ziface.ServiceInstance{
+		Name:     name,
+		Addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Metadata: make(map[string]string, len(meta)),
+	}
+	for k, v := range meta {
+		switch k {
+		case metaID:
+			instance.ID = v
+		case metaLoad:
+			instance.Load, _ = strconv.Atoi(v)
+		default:
+			instance.Metadata[k] = v
+		}
+	}
+	if instance.ID == "" {
+		instance.ID = name + "-" + instance.Addr
+	}
+	return instance
+}
+
+// doHTTP 发送HTTP请求, 非2xx时返回错误, resp不为nil时解析JSON回复
+func doHTTP(ctx context.Context, client *http.Client, method, url string, header http.Header, body []byte, resp interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s %s", method, url, res.Status, bytes.TrimSpace(data))
+	}
+	if resp == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, resp)
+}
@@ -0,0 +1,32 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iaccesslog.go
+// @Description  访问日志相关声明, 每处理一个消息输出一条结构化记录
+package ziface
+
+import "time"
+
+// AccessRecord 一个消息的访问日志记录
+type AccessRecord struct {
+	Time       time.Time     `json:"time"`            //读取到消息的时间
+	ConnID     uint64        `json:"connID"`          //连接ID
+	RemoteAddr string        `json:"remoteAddr"`      //对端地址
+	MsgID      uint32        `json:"msgID"`           //消息ID
+	Bytes      int           `json:"bytes"`           //消息内容的字节数
+	Latency    time.Duration `json:"latency"`         //从读取到消息到处理完成的时间
+	Attempts   int           `json:"attempts"`        //处理次数
+	Err        string        `json:"error,omitempty"` //处理失败时的错误, 成功时为空
+}
+
+// AccessLogHandler 访问日志的输出方法, 在Worker中同步调用, 不应阻塞
+type AccessLogHandler func(record AccessRecord)
+
+// AccessLogConfig 访问日志配置
+// 每个消息ID每Sample条记录输出1条, RouteSample可以为高频的消息ID单独设置, 0和1表示全部输出
+type AccessLogConfig struct {
+	Handler     AccessLogHandler  //访问日志的输出方法, 为nil时不启用
+	Sample      uint32            //默认的采样间隔
+	RouteSample map[uint32]uint32 //消息ID的采样间隔
+	KeepErrors  bool              //处理失败的消息不采样, 全部输出
+}
@@ -0,0 +1,19 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ibus.go
+// @Description  外部消息总线相关声明, 用于将zinx的消息桥接到NATS、Kafka等消息系统
+package ziface
+
+import "context"
+
+// BusHandler 消息总线的消息处理方法
+type BusHandler func(subject string, data []byte)
+
+// IMessageBus 外部消息总线, 如NATS、Kafka
+type IMessageBus interface {
+	Publish(ctx context.Context, subject string, data []byte) error //发布消息到主题
+	Subscribe(subject string, handler BusHandler) (uint64, error)   //订阅主题, 返回订阅ID
+	Unsubscribe(subID uint64) error                                 //取消订阅
+	Close() error                                                   //关闭到消息总线的连接
+}
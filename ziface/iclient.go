@@ -15,7 +15,12 @@
 // @Author  Aceld - 2023-2-28
 package ziface
 
-import "time"
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+)
 
 type IClient interface {
 	Start()
@@ -33,5 +38,35 @@ type IClient interface {
 	StartHeartBeatWithOption(time.Duration, *HeartBeatOption) //启动心跳检测(自定义回调)
 	GetLengthField() *LengthField
 	SetDecoder(IDecoder)
+	GetDecoder() IDecoder
 	AddInterceptor(IInterceptor)
+	SetProtocolVersion(uint32) //设置期望的协议版本号，连接建立后先进行版本握手
+	GetProtocolVersion() uint32
+	EnableSession(token string) //启用会话恢复, token为之前的会话令牌, 为空表示新建会话
+	GetSessionToken() string    //得到服务端分配的会话令牌
+	SetSessionToken(string)
+
+	Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error)    //向服务端发送请求并同步等待回复
+	Request(ctx context.Context, msgID uint32, data []byte) ([]byte, error) //向服务端发送请求并同步等待回复, ctx没有期限时使用默认的等待时间
+	SetRequestTimeout(timeout time.Duration)                                //设置Request默认的等待回复时间
+	SetTLSConfig(config *tls.Config)                                        //设置TLS配置并启用TLS
+	NewMuxSession() IMuxSession                                             //在连接上创建一个逻辑会话
+	SetWsPath(path string)                                                  //设置Websocket握手请求的路径
+	SetWsHeader(header http.Header)                                         //设置Websocket握手请求附加的Header
+
+	Stats() ClientStats                                             //客户端的统计快照, 包括重连之前的连接
+	SetMetrics(metrics IMetrics)                                    //设置指标接口
+	SetOnConnectFailed(func(err error))                             //设置建立连接失败时的回调
+	SetOnSendError(func(conn IConnection, msgID uint32, err error)) //设置发送消息失败时的回调
+
+	SetReconnect(config ReconnectConfig) //设置自动重连, 连接失败或断开后按指数退避重新连接
+	Reconnects() uint64                  //累计的重连次数
+
+	AddNamedInterceptor(name string, interceptor IInterceptor)     //添加带名称的拦截器
+	RemoveInterceptor(name string) bool                            //运行时删除指定名称的拦截器
+	ReplaceInterceptor(name string, interceptor IInterceptor) bool //运行时替换指定名称的拦截器
+	InterceptorNames() []string                                    //按顺序返回全部拦截器的名称
+	AddOutInterceptor(name string, interceptor IInterceptor)       //添加发出消息的拦截器, 在封包之前执行
+	RemoveOutInterceptor(name string) bool                         //删除指定名称的发出消息拦截器
+	OutInterceptorNames() []string                                 //按顺序返回全部发出消息拦截器的名称
 }
@@ -0,0 +1,33 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iclientstats.go
+// @Description  客户端统计相关声明, 用于集中监控大量的内部客户端
+package ziface
+
+import "time"
+
+// ClientStats 客户端的统计快照, 收发统计包括重连之前的连接
+type ClientStats struct {
+	Connected       bool          `json:"connected"`       //当前是否已连接
+	BytesIn         uint64        `json:"bytesIn"`         //读取的字节数
+	BytesOut        uint64        `json:"bytesOut"`        //写出的字节数
+	MsgsIn          uint64        `json:"msgsIn"`          //读取的消息数
+	MsgsOut         uint64        `json:"msgsOut"`         //写出的消息数
+	Requests        uint64        `json:"requests"`        //Call/Request的次数
+	RequestErrors   uint64        `json:"requestErrors"`   //Call/Request失败(包括超时)的次数
+	AvgRTT          time.Duration `json:"avgRTT"`          //Call/Request成功时的平均往返时间
+	MaxRTT          time.Duration `json:"maxRTT"`          //Call/Request成功时的最长往返时间
+	Reconnects      uint64        `json:"reconnects"`      //重连的次数
+	ConnectFailures uint64        `json:"connectFailures"` //建立连接失败的次数
+	SendErrors      uint64        `json:"sendErrors"`      //发送消息失败的次数
+}
+
+// 客户端上报的指标名称
+const (
+	MetricClientConnectFailed = "zinx.client.connect_failed" //Counter 建立连接失败
+	MetricClientReconnect     = "zinx.client.reconnect"      //Counter 重连
+	MetricClientSendError     = "zinx.client.send_error"     //Counter 发送消息失败, 标签msgID
+	MetricClientRequest       = "zinx.client.request"        //Counter Call/Request, 标签msgID、result(ok/error)
+	MetricClientRTT           = "zinx.client.rtt"            //Histogram Call/Request成功时往返的秒数, 标签msgID
+)
@@ -0,0 +1,25 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  icluster.go
+// @Description  集群相关声明, 用户路由表记录用户连接在哪个网关节点上, 用于跨节点发送消息
+package ziface
+
+import (
+	"context"
+	"time"
+)
+
+// UserRoute 用户的连接所在的网关节点与逻辑会话
+type UserRoute struct {
+	UserID    string `json:"userID"`    //用户ID
+	NodeID    string `json:"nodeID"`    //网关节点ID
+	SessionID uint32 `json:"sessionID"` //网关上客户端连接的逻辑会话ID
+}
+
+// IRouteTable 集群共享的用户路由表
+type IRouteTable interface {
+	Bind(ctx context.Context, route UserRoute, ttl time.Duration) error //绑定用户路由, 覆盖用户之前的路由, ttl为0时不过期
+	Unbind(ctx context.Context, route UserRoute) error                  //解除用户路由, 用户已经绑定到其他会话时不解除
+	Lookup(ctx context.Context, userID string) (UserRoute, bool, error) //查询用户路由, 用户不在线时返回false
+}
@@ -0,0 +1,15 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iconfigsource.go
+// @Description  配置来源相关声明, 配置可以来自本地文件或etcd、Nacos、Apollo等配置中心
+package ziface
+
+import "context"
+
+// IConfigSource 配置来源, 如配置文件、etcd、Nacos、Apollo
+type IConfigSource interface {
+	Name() string                                                     //来源的名称, 用于日志, 如文件路径、etcd的键
+	Read(ctx context.Context) (data []byte, format string, err error) //读取配置的内容与格式: json、yaml、toml或properties
+	Watch(ctx context.Context, notify func())                         //监听配置变化, 可能变化时调用notify, 阻塞直到ctx结束; 开始监听后调用一次notify, 避免遗漏开始监听之前的变化
+}
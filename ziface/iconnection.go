@@ -17,7 +17,9 @@ package ziface
 import (
 	"context"
 	"github.com/gorilla/websocket"
+	"io"
 	"net"
+	"time"
 )
 
 // 定义连接接口
@@ -36,12 +38,33 @@ type IConnection interface {
 
 	Send(data []byte) error
 	SendToQueue(data []byte) error
-	SendMsg(msgID uint32, data []byte) error     //直接将Message数据发送数据给远程的TCP客户端(无缓冲)
-	SendBuffMsg(msgID uint32, data []byte) error //直接将Message数据发送给远程的TCP客户端(有缓冲)
+	SendMsg(msgID uint32, data []byte) error         //直接将Message数据发送数据给远程的TCP客户端(无缓冲)
+	SendBuffMsg(msgID uint32, data []byte) error     //直接将Message数据发送给远程的TCP客户端(有缓冲)
+	OpenStream(msgID uint32) (io.WriteCloser, error) //打开一条消息流, 写入的数据分片后按序发送
+
+	SendSeqMsg(seq uint32, msgID uint32, data []byte) error              //发送携带关联序号的消息, 需使用支持关联序号的封包方式
+	Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) //发送请求并同步等待对端的回复
 
 	SetProperty(key string, value interface{})   //设置链接属性
 	GetProperty(key string) (interface{}, error) //获取链接属性
 	RemoveProperty(key string)                   //移除链接属性
-	IsAlive() bool                               //判断当前连接是否存活
-	SetHeartBeat(checker IHeartbeatChecker)      //设置心跳检测器
+
+	GetOrSetProperty(key string, value interface{}) (actual interface{}, loaded bool)       //属性不存在时设置, 返回当前值
+	CompareAndSwapProperty(key string, old, new interface{}) bool                           //属性当前值等于old时替换为new
+	UpdateProperty(key string, fn func(value interface{}, ok bool) interface{}) interface{} //在锁内根据当前值更新属性
+
+	IsAlive() bool                          //判断当前连接是否存活
+	SetHeartBeat(checker IHeartbeatChecker) //设置心跳检测器
+	GetProtocolVersion() uint32             //获取协商后的协议版本号，0表示未进行版本协商
+	SetRateLimit(limit RateLimit)           //运行时修改当前连接的读写带宽限制
+
+	SetIdleTimeout(read, write time.Duration)             //运行时修改当前连接的读写空闲超时时间, 0表示不检测
+	SetHeartbeatInterval(interval, timeout time.Duration) //运行时修改当前连接的心跳间隔与超时时间, 0表示使用服务的设置
+	Stats() ConnStats                                     //连接的收发统计: 字节数、消息数、最后活动时间、连接时长、错误次数
+
+	AfterFunc(d time.Duration, fn func()) (cancel func())            //d之后执行fn, 连接关闭时自动取消, 不需要为每个连接管理定时器
+	Ticker(d time.Duration, fn func()) (cancel func())               //每隔d执行一次fn, 连接关闭时自动取消
+	TickerJitter(d, jitter time.Duration, fn func()) (cancel func()) //与Ticker相同, 每次加上[0, jitter)的随机偏移, 避免大量连接同时执行
+
+	SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (cancel func()) //delay之后发送消息, 连接关闭时自动取消
 }
@@ -24,4 +24,16 @@ type IConnManager interface {
 	ClearConn()                                                            //删除并停止所有链接
 	GetAllConnID() []uint64                                                //获取所有连接ID
 	Range(func(uint64, IConnection, interface{}) error, interface{}) error //遍历所有连接
+	GetAll() []IConnection                                                 //获取全部连接(快照)
+
+	Broadcast(msgID uint32, data []byte) error                                      //向全部连接广播消息
+	BroadcastFilter(msgID uint32, data []byte, filter func(IConnection) bool) error //向满足条件的连接广播消息
+
+	SetTag(conn IConnection, key string, value string)                       //给连接设置标签(如 zone=5、role=admin)，同一key只保留一个值
+	RemoveTag(conn IConnection, key string)                                  //移除连接的标签
+	GetTags(connID uint64) map[string]string                                 //获取连接的全部标签
+	GetByTags(tags map[string]string) []IConnection                          //获取同时拥有全部标签的连接(基于索引)
+	BroadcastByTags(msgID uint32, data []byte, tags map[string]string) error //向同时拥有全部标签的连接广播消息
+
+	Stats() ConnManagerStats //全部连接的收发统计快照, 用于排查流量异常的客户端
 }
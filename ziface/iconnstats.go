@@ -0,0 +1,28 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iconnstats.go
+// @Description  连接统计相关声明, 用于排查流量异常的客户端
+package ziface
+
+import "time"
+
+// ConnStats 连接的收发统计快照
+type ConnStats struct {
+	ConnID       uint64        `json:"connID"`
+	RemoteAddr   string        `json:"remoteAddr"`
+	BytesIn      uint64        `json:"bytesIn"`      //读取的字节数
+	BytesOut     uint64        `json:"bytesOut"`     //写出的字节数
+	MsgsIn       uint64        `json:"msgsIn"`       //读取的消息数
+	MsgsOut      uint64        `json:"msgsOut"`      //写出的消息数
+	Errors       uint64        `json:"errors"`       //读写与封包出错的次数
+	ConnectedAt  time.Time     `json:"connectedAt"`  //连接建立的时间
+	LastActivity time.Time     `json:"lastActivity"` //最后一次读写的时间
+	Duration     time.Duration `json:"duration"`     //连接时长
+}
+
+// ConnManagerStats 连接管理中全部连接的统计快照
+type ConnManagerStats struct {
+	Total ConnStats   `json:"total"` //全部连接的合计, 不包括ConnID、RemoteAddr与时间
+	Conns []ConnStats `json:"conns"` //每个连接的统计, 按读取的字节数从多到少排列
+}
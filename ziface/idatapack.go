@@ -28,6 +28,12 @@ const (
 	//Zinx 标准封包和拆包方式
 	ZinxDataPack string = "zinx_pack"
 
+	//Zinx 携带关联序号的封包和拆包方式
+	ZinxSeqDataPack string = "zinx_seq_pack"
+
+	//Zinx 携带逻辑会话ID与关联序号的封包和拆包方式
+	ZinxMuxDataPack string = "zinx_mux_pack"
+
 	//...(+)
 	//自定义封包方式在此添加
 )
@@ -0,0 +1,28 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ideadletter.go
+// @Description  死信相关声明, 处理失败的消息交给死信处理方法以便检查或重试
+package ziface
+
+import "time"
+
+// DeadLetter 处理失败的消息及其元数据
+type DeadLetter struct {
+	Conn     IConnection //消息所在的连接
+	MsgID    uint32      //消息ID
+	Data     []byte      //消息内容
+	Err      error       //最后一次处理的错误, 路由panic时为panic的内容
+	Attempts int         //处理次数
+	Time     time.Time   //进入死信的时间
+}
+
+// DeadLetterHandler 死信处理方法
+type DeadLetterHandler func(letter DeadLetter)
+
+// DeadLetterConfig 死信配置
+// 路由通过request.SetError设置错误或发生panic时视为处理失败, 立即重试, 处理次数达到MaxAttempts后交给Handler
+type DeadLetterConfig struct {
+	MaxAttempts int               //最多处理次数, 默认1(不重试)
+	Handler     DeadLetterHandler //死信处理方法, 为nil时不启用
+}
@@ -4,3 +4,9 @@ type IDecoder interface {
 	IInterceptor
 	GetLengthField() *LengthField
 }
+
+// IFrameDecoderMaker 不基于LengthField规则断粘包的解码器(如文本行协议)可实现该接口，
+// 由解码器为每个连接创建独立的断粘包解码器，此时GetLengthField可返回nil
+type IFrameDecoderMaker interface {
+	NewFrameDecoder() IFrameDecoder
+}
@@ -0,0 +1,38 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  idiscovery.go
+// @Description  服务注册与发现相关声明, 网关与其他节点通过注册中心动态发现zinx服务实例
+package ziface
+
+import (
+	"context"
+	"time"
+)
+
+// ServiceInstance 注册到注册中心的服务实例
+type ServiceInstance struct {
+	ID       string            `json:"id"`       //实例ID, 同一服务内唯一
+	Name     string            `json:"name"`     //服务名称
+	Addr     string            `json:"addr"`     //实例的访问地址, host:port
+	Load     int               `json:"load"`     //负载, 如当前连接数
+	Metadata map[string]string `json:"metadata"` //元数据, 如版本、机房
+}
+
+// IRegistry 注册中心
+type IRegistry interface {
+	Register(ctx context.Context, instance ServiceInstance, ttl time.Duration) error //注册服务实例, 超过ttl没有续约时被注册中心删除
+	KeepAlive(ctx context.Context, instance ServiceInstance) error                   //续约, 同时更新实例的负载与元数据
+	Deregister(ctx context.Context, instance ServiceInstance) error                  //注销服务实例
+	Discover(ctx context.Context, name string) ([]ServiceInstance, error)            //查询服务当前全部存活的实例
+}
+
+// RegistryConfig 服务启动时注册到注册中心的配置
+type RegistryConfig struct {
+	Registry IRegistry         //注册中心
+	Name     string            //服务名称, 默认为Server的名称
+	ID       string            //实例ID, 默认为"名称-地址"
+	Addr     string            //注册的访问地址, 默认为监听的IP与端口, 监听0.0.0.0时需要设置
+	Metadata map[string]string //元数据
+	TTL      time.Duration     //租约时间, 默认10秒, 每TTL/3续约一次并更新负载
+}
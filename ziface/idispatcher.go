@@ -0,0 +1,19 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  idispatcher.go
+// @Description  Worker选择策略相关声明, 决定请求提交给工作池中的哪个Worker
+package ziface
+
+// 内置的Worker选择策略
+const (
+	DispatchAffinity    = "affinity"     //按连接ID选择Worker, 同一连接的请求按顺序处理(默认)
+	DispatchHash        = "hash"         //按连接ID与消息ID选择Worker, 同一连接同一消息ID的请求按顺序处理
+	DispatchRoundRobin  = "round_robin"  //轮流选择Worker, 不保证处理顺序
+	DispatchLeastLoaded = "least_loaded" //选择任务队列最短的Worker, 不保证处理顺序
+)
+
+// IDispatcher Worker选择策略
+type IDispatcher interface {
+	Dispatch(request IRequest, pool IWorkerPool) uint64 //返回提交任务的key, 相同key的任务由同一个Worker按顺序执行
+}
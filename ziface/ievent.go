@@ -0,0 +1,65 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ievent.go
+// @Description  服务生命周期事件相关声明, 用于在不修改znet的情况下接入监控、告警等自定义逻辑
+package ziface
+
+import "time"
+
+// EventType 事件类型
+type EventType int
+
+const (
+	EventServerStarted        EventType = iota + 1 //服务启动
+	EventListenerError                             //监听端口出错(监听失败、接受连接失败)
+	EventConnOpened                                //连接建立(OnConnStart之后)
+	EventConnClosed                                //连接关闭(OnConnStop之后)
+	EventWorkerQueueSaturated                      //Worker任务队列已满, 请求分发被阻塞
+	EventShutdownBegun                             //服务开始停止(不再接受新的连接)
+	EventSlowHandler                               //路由处理时间超过慢处理阈值
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventServerStarted:
+		return "ServerStarted"
+	case EventListenerError:
+		return "ListenerError"
+	case EventConnOpened:
+		return "ConnOpened"
+	case EventConnClosed:
+		return "ConnClosed"
+	case EventWorkerQueueSaturated:
+		return "WorkerQueueSaturated"
+	case EventShutdownBegun:
+		return "ShutdownBegun"
+	case EventSlowHandler:
+		return "SlowHandler"
+	}
+	return "Unknown"
+}
+
+// Event 事件, 根据事件类型只有部分字段有值
+type Event struct {
+	Type     EventType
+	Time     time.Time
+	Server   string      //服务名称
+	Addr     string      //监听地址(ServerStarted、ListenerError)
+	Conn     IConnection //连接(ConnOpened、ConnClosed、SlowHandler)
+	WorkerID uint32      //Worker编号(WorkerQueueSaturated)
+	Err      error       //错误(ListenerError)
+
+	MsgID   uint32        //路由的消息ID(SlowHandler)
+	Elapsed time.Duration //路由处理时间(SlowHandler)
+}
+
+// EventHandler 事件处理方法, 在产生事件的Goroutine中同步调用, 不应阻塞
+type EventHandler func(event Event)
+
+// IEventBus 事件总线
+type IEventBus interface {
+	Subscribe(handler EventHandler, types ...EventType) uint64 //订阅事件, 不指定类型时订阅全部事件, 返回订阅ID
+	Unsubscribe(subID uint64)                                  //取消订阅
+	Publish(event Event)                                       //发布事件
+}
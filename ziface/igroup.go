@@ -0,0 +1,46 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  igroup.go
+// @Description  分组(房间)管理相关声明, 用于聊天室、游戏房间等按组广播的场景
+package ziface
+
+import "time"
+
+// IGroup 一个分组(房间)
+type IGroup interface {
+	Name() string                                                   //分组名称
+	Join(conn IConnection)                                          //连接加入分组
+	Leave(conn IConnection)                                         //连接离开分组
+	Has(connID uint64) bool                                         //连接是否在分组中
+	Members() []IConnection                                         //全部成员连接
+	Len() int                                                       //成员数量
+	Broadcast(msgID uint32, data []byte) error                      //向全部成员广播消息
+	BroadcastExcept(msgID uint32, data []byte, connID uint64) error //向除connID之外的成员广播消息(如不回显给发送者)
+
+	SendMsgAt(t time.Time, msgID uint32, data []byte) (cancel func())            //在t向全部成员广播消息, 分组删除时自动取消
+	SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (cancel func()) //delay之后向全部成员广播消息, 分组删除时自动取消
+}
+
+// IGroupManager 分组管理器, 连接断开时自动离开其加入的全部分组
+type IGroupManager interface {
+	Create(name string) (IGroup, error)  //创建分组, 已存在时返回错误
+	Get(name string) (IGroup, bool)      //获取分组
+	GetOrCreate(name string) IGroup      //获取分组，不存在时创建
+	Remove(name string)                  //删除分组, 全部成员离开该分组
+	Join(name string, conn IConnection)  //连接加入分组，分组不存在时创建
+	Leave(name string, conn IConnection) //连接离开分组
+	LeaveAll(conn IConnection)           //连接离开其加入的全部分组
+	GroupsOf(connID uint64) []string     //连接加入的全部分组名称
+	Range(func(group IGroup) bool)       //遍历全部分组, 返回false时停止
+	Len() int                            //分组数量
+	SetHook(hook IGroupHook)             //设置分组变化的钩子，可用于持久化分组信息
+}
+
+// IGroupHook 分组变化的钩子, 在分组状态变化之后同步调用
+type IGroupHook interface {
+	OnCreate(group IGroup)
+	OnRemove(group IGroup)
+	OnJoin(group IGroup, conn IConnection)
+	OnLeave(group IGroup, conn IConnection)
+}
@@ -0,0 +1,31 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ihealth.go
+// @Description  健康检查相关声明, 供负载均衡与Kubernetes探针使用
+package ziface
+
+// HealthMsgID 健康检查默认使用的消息ID, 服务端回复JSON格式的HealthStatus
+const HealthMsgID uint32 = 99997
+
+// HealthConfig 健康检查配置
+type HealthConfig struct {
+	// 独立的HTTP健康检查监听地址(如":8081"), 为空时只在TCP端口上响应HTTP健康检查
+	Addr string
+	// 响应健康检查的消息ID(通常使用HealthMsgID), 0表示不启用
+	MsgID uint32
+	// Worker任务队列的最高使用率超过该值时未就绪, 0时使用默认的0.8
+	QueueThreshold float64
+}
+
+// HealthStatus 健康状态
+type HealthStatus struct {
+	Live              bool     `json:"live"`              //存活
+	Ready             bool     `json:"ready"`             //就绪, 可以接收新的连接
+	Listeners         int      `json:"listeners"`         //正在监听的端口数量
+	ExpectedListeners int      `json:"expectedListeners"` //配置的监听端口数量
+	Connections       int      `json:"connections"`       //当前连接数量
+	MaxConn           int      `json:"maxConn"`           //允许的最大连接数量
+	QueueUsage        float64  `json:"queueUsage"`        //Worker任务队列的最高使用率
+	Reasons           []string `json:"reasons,omitempty"` //未就绪的原因
+}
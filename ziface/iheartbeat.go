@@ -1,5 +1,7 @@
 package ziface
 
+import "time"
+
 type IHeartbeatChecker interface {
 	SetOnRemoteNotAlive(OnRemoteNotAlive)
 	SetHeartbeatMsgFunc(HeartBeatMsgFunc)
@@ -12,6 +14,10 @@ type IHeartbeatChecker interface {
 	Clone() IHeartbeatChecker
 	MsgID() uint32
 	Router() IRouter
+	SetMaxMisses(int)
+	SetInterval(time.Duration)
+	SetPongValidator(HeartBeatPongFunc)
+	SetNotAlivePolicy(NotAlivePolicy)
 }
 
 // 用户自定义的心跳检测消息处理方法
@@ -23,11 +29,30 @@ type HeartBeatFunc func(IConnection) error
 // 用户自定义的远程连接不存活时的处理方法
 type OnRemoteNotAlive func(IConnection)
 
+// HeartBeatPongFunc 用户自定义的心跳回复校验方法, data为收到的心跳消息的内容
+// 设置后只有校验通过的心跳回复才表示对端存活, 其他消息不再计入
+type HeartBeatPongFunc func(conn IConnection, data []byte) bool
+
+// NotAlivePolicy 心跳检测发现对端不存活时的处理策略
+type NotAlivePolicy int
+
+const (
+	NotAliveClose   NotAlivePolicy = iota //调用OnRemoteNotAlive, 没有设置时关闭连接(默认), 不再发送心跳
+	NotAliveNotify                        //每次检测到不存活时调用OnRemoteNotAlive通知, 不关闭连接, 继续发送心跳
+	NotAliveSuspect                       //将连接标记为可疑(连接属性HeartbeatSuspectProperty为true)并调用一次OnRemoteNotAlive, 继续发送心跳, 对端恢复后清除标记
+)
+
+// HeartbeatSuspectProperty NotAliveSuspect策略下标记连接可疑的连接属性
+const HeartbeatSuspectProperty = "zinx.heartbeat.suspect"
+
 type HeartBeatOption struct {
-	MakeMsg          HeartBeatMsgFunc //用户自定义的心跳检测消息处理方法
-	OnRemoteNotAlive OnRemoteNotAlive //用户自定义的远程连接不存活时的处理方法
-	HeadBeatMsgID    uint32           //用户自定义的心跳检测消息ID
-	Router           IRouter          //用户自定义的心跳检测消息业务处理路由
+	MakeMsg          HeartBeatMsgFunc  //用户自定义的心跳检测消息处理方法
+	OnRemoteNotAlive OnRemoteNotAlive  //用户自定义的远程连接不存活时的处理方法
+	HeadBeatMsgID    uint32            //用户自定义的心跳检测消息ID
+	Router           IRouter           //用户自定义的心跳检测消息业务处理路由
+	MaxMisses        int               //连续多少次心跳没有收到对端的任何消息(如心跳回复)时认为对端不存活, 0表示按全局HeartbeatMax判断
+	ValidatePong     HeartBeatPongFunc //用户自定义的心跳回复校验方法, 设置后只有校验通过的心跳回复表示对端存活
+	NotAlivePolicy   NotAlivePolicy    //对端不存活时的处理策略: 关闭连接(默认)、只通知或标记为可疑
 }
 
 const (
@@ -27,5 +27,9 @@ type IBuilder interface {
 	Head(interceptor IInterceptor)
 	Tail(interceptor IInterceptor)
 	AddInterceptor(interceptor IInterceptor)
+	AddNamedInterceptor(name string, interceptor IInterceptor)     //添加带名称的拦截器，名称已存在时替换
+	RemoveInterceptor(name string) bool                            //运行时删除指定名称的拦截器
+	ReplaceInterceptor(name string, interceptor IInterceptor) bool //运行时替换指定名称的拦截器
+	InterceptorNames() []string                                    //按顺序返回全部拦截器的名称
 	Execute(request IcReq) IcResp
 }
@@ -0,0 +1,46 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iintrospect.go
+// @Description  运行时内省相关声明, 用于查询运行中服务的路由、拦截器、监听端口与连接
+package ziface
+
+import "time"
+
+// RouteInfo 已注册路由的信息
+type RouteInfo struct {
+	MsgID            uint32        `json:"msgID"`
+	Router           string        `json:"router"`           //路由的类型
+	Middlewares      int           `json:"middlewares"`      //路由级中间件的数量
+	Priority         MsgPriority   `json:"priority"`         //在Worker任务队列中的优先级
+	Timeout          time.Duration `json:"timeout"`          //处理期限, 0表示不限制
+	OwnPool          bool          `json:"ownPool"`          //是否绑定了独立的Worker工作池
+	ConcurrencyLimit int           `json:"concurrencyLimit"` //同时处理的请求数量上限, 0表示不限制
+}
+
+// ListenerInfo 监听端口的信息
+type ListenerInfo struct {
+	Network string `json:"network"`
+	Addr    string `json:"addr"`
+	Packet  string `json:"packet"`  //封包方式的类型
+	Decoder string `json:"decoder"` //解码器的类型
+}
+
+// ServerInfo 运行中服务的内省信息
+type ServerInfo struct {
+	Name            string         `json:"name"`
+	Routes          []RouteInfo    `json:"routes"`          //按消息ID排列
+	DefaultRouter   string         `json:"defaultRouter"`   //默认路由的类型, 没有时为空
+	Interceptors    []string       `json:"interceptors"`    //按执行顺序排列的拦截器名称
+	OutInterceptors []string       `json:"outInterceptors"` //按执行顺序排列的发出消息拦截器名称
+	Listeners       []ListenerInfo `json:"listeners"`       //主端口与附加的监听端口
+	Connections     int            `json:"connections"`
+}
+
+// ConnFilter 连接过滤条件, 为空的条件不过滤
+type ConnFilter struct {
+	RemoteIP   string            //对端IP
+	Tags       map[string]string //同时拥有的全部标签
+	MinBytesIn uint64            //读取的字节数下限
+	Limit      int               //最多返回的连接数量, 0表示不限制
+}
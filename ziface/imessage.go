@@ -27,3 +27,34 @@ type IMessage interface {
 	SetData([]byte)    //设计消息内容
 	SetDataLen(uint32) //设置消息数据段长度
 }
+
+// SeqReplyFlag 关联序号的最高位, 表示该消息是对端请求的回复
+const SeqReplyFlag uint32 = 1 << 31
+
+/*
+携带关联序号的消息, 用于请求与回复的匹配
+关联序号只在支持的封包方式(如zinx_seq_pack)中传输
+*/
+type ISeqMessage interface {
+	GetSeq() uint32 //获取关联序号, 0表示不携带
+	SetSeq(uint32)  //设置关联序号
+}
+
+/*
+携带逻辑会话ID的消息, 用于一个物理连接上复用多个逻辑会话
+逻辑会话ID只在支持的封包方式(如zinx_mux_pack)中传输
+*/
+type IMuxMessage interface {
+	GetSessionID() uint32 //获取逻辑会话ID, 0表示不属于任何逻辑会话
+	SetSessionID(uint32)  //设置逻辑会话ID
+}
+
+/*
+发出消息拦截器的请求, 在要发出的消息之外携带发出消息的连接
+修改消息时直接调用SetData、SetMsgID等方法, 需要关联序号等信息时通过GetMessage获取原始消息
+*/
+type IOutMessage interface {
+	IMessage
+	GetMessage() IMessage       //获取要发出的原始消息
+	GetConnection() IConnection //获取发出消息的连接
+}
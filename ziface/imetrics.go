@@ -0,0 +1,46 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  imetrics.go
+// @Description  指标相关声明, 在znet的关键位置调用, 用于接入任意的指标系统
+package ziface
+
+import "time"
+
+// 指标名称
+const (
+	MetricConnOpened   = "zinx.conn.opened"   //Counter 建立的连接数
+	MetricConnClosed   = "zinx.conn.closed"   //Counter 关闭的连接数
+	MetricConnections  = "zinx.connections"   //Gauge 当前连接数
+	MetricMsgReceived  = "zinx.msg.received"  //Counter 读取的消息数, 标签msgID
+	MetricMsgBytes     = "zinx.msg.bytes"     //Counter 读取的消息内容字节数, 标签msgID
+	MetricMsgHandled   = "zinx.msg.handled"   //Counter 处理完成的消息数, 标签msgID、result(ok/error)
+	MetricMsgLatency   = "zinx.msg.latency"   //Histogram 从读取到消息到处理完成的秒数, 标签msgID
+	MetricMsgDropped   = "zinx.msg.dropped"   //Counter 没有处理的消息数, 标签msgID、reason(notfound/limited/overflow)
+	MetricWorkerWait   = "zinx.worker.wait"   //Histogram 任务在Worker任务队列中等待的秒数
+	MetricListenerErrs = "zinx.listener.errs" //Counter 监听端口出错次数
+	MetricInFlight     = "zinx.inflight"      //Gauge 已分发但尚未处理完成的消息数
+	MetricQueueUsage   = "zinx.worker.usage"  //Gauge Worker任务队列的最高使用率
+	MetricGoroutines   = "zinx.goroutines"    //Gauge Goroutine数量
+	MetricMemAlloc     = "zinx.mem.alloc"     //Gauge 已分配的堆内存字节数
+	MetricTimerLatency = "zinx.timer.latency" //Histogram 定时器实际触发时间与计划时间之差的秒数
+	MetricTimerLate    = "zinx.timer.late"    //Counter 触发时间晚于计划时间超过允许误差的定时器数
+	MetricTimerPending = "zinx.timer.pending" //Gauge 时间轮中等待触发的定时器数
+)
+
+// IMetrics 指标接口, 在处理连接与消息的Goroutine中同步调用, 不应阻塞
+type IMetrics interface {
+	Counter(name string, value float64, tags map[string]string)   //计数器增加value
+	Gauge(name string, value float64, tags map[string]string)     //仪表盘设置为value
+	Histogram(name string, value float64, tags map[string]string) //直方图记录一次观测值
+}
+
+// StatsDConfig StatsD导出配置
+type StatsDConfig struct {
+	Addr          string            //StatsD的UDP地址, 如"127.0.0.1:8125"
+	Prefix        string            //指标名称前缀, 如"game"
+	Tags          map[string]string //附加到全部指标的标签
+	DogStatsD     bool              //使用DogStatsD的标签格式, 否则标签值按键排序拼接到指标名称中
+	Interval      time.Duration     //推送服务运行状态以及发送缓冲的指标的间隔, 默认10秒
+	MaxPacketSize int               //每个UDP包的最大字节数, 默认1432
+}
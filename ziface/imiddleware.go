@@ -0,0 +1,19 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  imiddleware.go
+// @Description  中间件相关声明, 用于鉴权、日志、统计、异常恢复等横切逻辑
+package ziface
+
+// HandlerFunc 请求处理方法
+type HandlerFunc func(request IRequest)
+
+// Middleware 中间件, 包裹路由的PreHandle/Handle/PostHandle
+// 在调用next之前或之后执行自己的逻辑, 不调用next时请求不会交给路由处理
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// IMiddlewareRouter 支持路由级中间件的路由, 嵌入BaseRouter即可
+type IMiddlewareRouter interface {
+	Use(middlewares ...Middleware) //添加只作用于当前路由的中间件
+	Middlewares() []Middleware     //当前路由的中间件
+}
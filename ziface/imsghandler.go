@@ -14,6 +14,11 @@
 // @Author  Aceld - Thu Mar 11 10:32:29 CST 2019
 package ziface
 
+import "time"
+
+// RouteFunc 根据请求内容计算实际路由的消息ID, 多个操作复用同一个消息ID时可以分别注册路由
+type RouteFunc func(request IRequest) uint32
+
 /*
 消息管理抽象层
 */
@@ -23,6 +28,43 @@ type IMsgHandle interface {
 	StartWorkerPool()                    //启动worker工作池
 	SendMsgToTaskQueue(request IRequest) //将消息交给TaskQueue,由worker进行处理
 
+	Use(middlewares ...Middleware)        //添加全局中间件, 作用于全部路由
+	Group(start, end uint32) IRouterGroup //创建消息ID区间为[start, end]的路由分组
+	SetDefaultRouter(router IRouter)      //设置默认路由, 处理没有匹配到消息ID的请求
+	SetRouteFunc(routeFunc RouteFunc)     //设置路由函数, 根据请求内容计算实际路由的消息ID
+	SetRecoverHandler(RecoverHandler)     //设置全局的panic恢复处理
+
+	SetReplyMsgID(requestID, replyID uint32)               //设置请求消息ID对应的回复消息ID, 用于request.Reply
+	SetRoutePool(pool IWorkerPool, msgIDs ...uint32)       //为消息ID绑定独立的Worker工作池
+	SetMsgPriority(priority MsgPriority, msgIDs ...uint32) //设置消息ID在Worker任务队列中的优先级
+	SetDeadLetter(config DeadLetterConfig)                 //设置处理失败的消息的重试与死信处理
+	SetSlowThreshold(threshold time.Duration)              //设置慢处理阈值, 路由处理时间超过该值时记录日志并发布事件
+	SetConcurrencyLimit(limit int, msgIDs ...uint32)       //设置消息ID同时处理的请求数量上限
+	SetTimeoutHandler(handler HandlerFunc)                 //设置路由处理超时后的处理
+	SetDispatcher(dispatcher IDispatcher)                  //设置Worker选择策略
+	SetOverflowPolicy(config OverflowConfig)               //设置Worker任务队列已满时的处理策略
+	RouteStats() []RouteStats                              //每个路由的处理统计
+	Routes() []RouteInfo                                   //已注册的路由, 按消息ID排列
+	SetAccessLog(config AccessLogConfig)                   //设置访问日志, 每处理完成一个消息输出一条记录
+	SetMetrics(metrics IMetrics)                           //设置指标接口
+	SetSlowLog(size int, maxPayload int)                   //设置慢日志, 保存处理时间最长的请求
+	SlowLog() []SlowLogEntry                               //慢日志中的请求, 按处理时间从长到短排列
+	ResetSlowLog()                                         //清空慢日志
+
 	Execute(request IRequest)                //
 	AddInterceptor(interceptor IInterceptor) //注册责任链任务入口，每个拦截器处理完后，数据都会传递至下一个拦截器，使得消息可以层层处理层层传递，顺序取决于注册顺序
+
+	//运行时管理拦截器，与正在处理中的请求互不影响
+	AddNamedInterceptor(name string, interceptor IInterceptor)
+	RemoveInterceptor(name string) bool
+	ReplaceInterceptor(name string, interceptor IInterceptor) bool
+	InterceptorNames() []string
+
+	InFlight() int64          //已分发但尚未处理完成的请求数量
+	QueueUsage() float64      //Worker任务队列的最高使用率(0~1)
+	WorkerStats() WorkerStats //Worker工作池统计
+
+	SetEventBus(bus IEventBus)      //设置发布Worker任务队列事件的事件总线
+	SetWorkerPool(pool IWorkerPool) //使用外部的Worker工作池(如多个Server共享)
+	GetWorkerPool() IWorkerPool     //获取Worker工作池
 }
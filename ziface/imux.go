@@ -0,0 +1,24 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  imux.go
+// @Description  逻辑会话相关声明, 一个客户端物理连接上复用多个逻辑会话
+package ziface
+
+import "context"
+
+// IMuxSession 客户端连接上的一个逻辑会话, 如压测工具用少量连接模拟大量用户
+// 每个逻辑会话有独立的路由与关联序号空间, 消息包头携带逻辑会话ID, 需使用支持逻辑会话ID的封包方式与解码器
+type IMuxSession interface {
+	ID() uint32                                                          //逻辑会话ID
+	AddRouter(msgID uint32, router IRouter)                              //添加逻辑会话独立的路由
+	SendMsg(msgID uint32, data []byte) error                             //发送属于该逻辑会话的消息
+	SendSeqMsg(seq uint32, msgID uint32, data []byte) error              //发送属于该逻辑会话并携带关联序号的消息
+	Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) //发送请求并同步等待服务端回复
+	Close()                                                              //关闭逻辑会话, 之后收到的消息被丢弃
+}
+
+// IMuxWriter 支持发送属于逻辑会话的消息的连接, 如网关与逻辑服务之间的连接
+type IMuxWriter interface {
+	SendMuxMsg(sessionID uint32, seq uint32, msgID uint32, data []byte) error //发送属于逻辑会话的消息, seq为0时不携带关联序号
+}
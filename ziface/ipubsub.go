@@ -0,0 +1,21 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ipubsub.go
+// @Description  主题发布订阅相关声明, 用于行情推送、通知等按主题扇出的场景
+package ziface
+
+// TopicHandler 主题消息处理方法(服务内部订阅者)
+type TopicHandler func(topic string, msgID uint32, data []byte)
+
+// IPubSub 主题发布订阅
+// 主题以'.'分隔层级, 如 "market.btc.price"; 订阅时 '*' 匹配一个层级, '#' 只能作为最后一级, 匹配剩余的零个或多个层级
+type IPubSub interface {
+	Subscribe(pattern string, conn IConnection) error                   //连接订阅主题
+	Unsubscribe(pattern string, conn IConnection)                       //连接取消订阅主题
+	UnsubscribeAll(conn IConnection)                                    //连接取消全部订阅
+	Subscriptions(connID uint64) []string                               //连接的全部订阅
+	SubscribeFunc(pattern string, handler TopicHandler) (uint64, error) //服务内部订阅主题，返回订阅ID
+	UnsubscribeFunc(subID uint64)                                       //取消服务内部订阅
+	Publish(topic string, msgID uint32, data []byte) (int, error)       //发布消息，返回收到消息的连接数量
+}
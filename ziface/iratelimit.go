@@ -0,0 +1,22 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iratelimit.go
+// @Description  读写带宽限制相关声明
+package ziface
+
+import "context"
+
+// RateLimit 读写带宽限制, 单位为字节/秒, 0表示不限制
+type RateLimit struct {
+	ReadRate  int //读速率
+	WriteRate int //写速率
+	Burst     int //允许突发的字节数, 0表示与速率相同
+}
+
+// IRateLimiter 令牌桶限速器
+type IRateLimiter interface {
+	WaitN(ctx context.Context, n int) error //消耗n个令牌, 令牌不足时阻塞等待, ctx结束时返回错误
+	SetLimit(rate, burst int)               //运行时修改速率与突发量, rate为0表示不限制
+	Limit() (rate, burst int)               //当前速率与突发量
+}
@@ -0,0 +1,22 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  ireconnect.go
+// @Description  客户端自动重连相关声明
+package ziface
+
+import "time"
+
+// ReconnectConfig 客户端自动重连配置
+// 连接失败或断开后等待MinDelay重连, 每次失败后等待时间乘以Multiplier, 最长MaxDelay, 并随机浮动Jitter比例
+type ReconnectConfig struct {
+	MaxAttempts int           //连续重连失败的最多次数, 0不限制
+	MinDelay    time.Duration //第一次重连前的等待时间, 默认1秒
+	MaxDelay    time.Duration //最长等待时间, 默认30秒
+	Multiplier  float64       //每次失败后等待时间的倍数, 默认2
+	Jitter      float64       //等待时间随机浮动的比例(0~1), 默认0.2, 避免大量客户端同时重连
+
+	Login       func(conn IConnection) error              //每次连接建立(包括第一次)后调用, 用于重新登录、订阅, 返回错误时断开连接并重连
+	OnReconnect func(conn IConnection, reconnects uint64) //重连成功(登录之后)调用, reconnects为累计的重连次数
+	OnGiveUp    func(err error)                           //达到最多重连次数后放弃时调用
+}
@@ -0,0 +1,16 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  irecover.go
+// @Description  路由处理请求发生panic时的恢复处理声明
+package ziface
+
+// RecoverHandler 路由处理请求发生panic时调用, recovered为recover()的返回值, stack为panic时的调用栈
+// 可以用于给客户端回复错误消息、统计指标或关闭连接
+type RecoverHandler func(conn IConnection, request IRequest, recovered interface{}, stack []byte)
+
+// IRecoverRouter 支持路由级panic恢复处理的路由, 嵌入BaseRouter即可
+type IRecoverRouter interface {
+	SetRecoverHandler(handler RecoverHandler) //设置只作用于当前路由的恢复处理, 优先于Server的恢复处理
+	RecoverHandler() RecoverHandler
+}
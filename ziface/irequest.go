@@ -14,6 +14,8 @@
 // @Author  Aceld - Thu Mar 11 10:32:29 CST 2019
 package ziface
 
+import "context"
+
 type HandleStep int
 
 /*
@@ -25,9 +27,24 @@ type IRequest interface {
 
 	GetData() []byte  //获取请求消息的数据
 	GetMsgID() uint32 //获取请求的消息ID
+	GetSeq() uint32   //获取请求的关联序号, 0表示不携带
+
+	GetSessionID() uint32 //获取请求所属的逻辑会话ID, 0表示不属于任何逻辑会话
+
+	GetProtocolVersion() uint32 //获取当前连接协商后的协议版本号
 
 	GetMessage() IMessage //获取请求消息的原始数据 add by uuxia 2023-03-10
 
+	Context() context.Context   //请求的Context, 连接关闭或超过路由的处理期限时被取消
+	SetContext(context.Context) //替换请求的Context, 如中间件附加请求范围的值
+
+	Reply(data []byte) error                   //回复请求, 消息ID由回复消息ID映射表决定, 没有映射时与请求相同
+	ReplyWith(msgID uint32, data []byte) error //使用指定的消息ID回复请求
+	SetResponseWriter(writer IResponseWriter)  //替换回复的写入方, 默认为请求所在的连接
+
+	SetError(err error) //路由处理失败时设置错误, 启用死信处理时失败的消息被重试或交给死信处理方法
+	GetError() error
+
 	GetResponse() IcResp //获取解析完后序列化数据
 	SetResponse(IcResp)  //设置解析完后序列化数据
 
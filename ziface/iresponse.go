@@ -0,0 +1,13 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iresponse.go
+// @Description  请求回复相关声明
+package ziface
+
+// IResponseWriter 请求回复的写入方, 默认为请求所在的连接
+// 测试时可以替换为记录回复的实现, 不需要真实的连接
+type IResponseWriter interface {
+	SendMsg(msgID uint32, data []byte) error
+	SendSeqMsg(seq uint32, msgID uint32, data []byte) error //回复携带关联序号的请求
+}
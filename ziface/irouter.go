@@ -13,6 +13,8 @@
 // @Author  Aceld - Thu Mar 11 10:32:29 CST 2019
 package ziface
 
+import "time"
+
 /*
 	路由接口， 这里面路由是 使用框架者给该链接自定的 处理业务方法
 	路由里的IRequest 则包含用该链接的链接信息和该链接的请求数据信息
@@ -22,3 +24,11 @@ type IRouter interface {
 	Handle(request IRequest)     //处理conn业务的方法
 	PostHandle(request IRequest) //处理conn业务之后的钩子方法
 }
+
+/*
+	路由处理请求的期限, 超时后请求的Context被取消
+*/
+type ITimeoutRouter interface {
+	SetTimeout(timeout time.Duration) //设置路由处理请求的期限, 0表示不限制
+	Timeout() time.Duration
+}
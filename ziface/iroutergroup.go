@@ -0,0 +1,15 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iroutergroup.go
+// @Description  路由分组相关声明, 按消息ID区间统一管理一个模块的路由与中间件
+package ziface
+
+// IRouterGroup 路由分组, 包含[start, end]区间内的消息ID
+type IRouterGroup interface {
+	Use(middlewares ...Middleware)          //添加作用于分组内全部消息ID的中间件(包括直接注册在Server上的路由)
+	AddRouter(msgID uint32, router IRouter) //注册路由, msgID不在分组区间内时panic
+	AddRouters(routers map[uint32]IRouter)  //批量注册路由
+	Contains(msgID uint32) bool             //消息ID是否在分组区间内
+	Range() (start uint32, end uint32)      //分组的消息ID区间
+}
@@ -0,0 +1,22 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iroutestats.go
+// @Description  路由统计相关声明, 时间单位为纳秒
+package ziface
+
+import "time"
+
+// RouteStats 路由(消息ID)的处理统计
+type RouteStats struct {
+	MsgID   uint32        `json:"msgID"`
+	Count   uint64        `json:"count"`   //处理次数(包括重试)
+	Errors  uint64        `json:"errors"`  //处理失败(request.SetError或panic)的次数
+	AvgExec time.Duration `json:"avgExec"` //平均处理时间
+	MaxExec time.Duration `json:"maxExec"` //最长处理时间
+
+	// 端到端延迟(从读取到消息至处理完成, 包括在Worker任务队列中的等待)的分位数, 相对误差不超过1/16
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+}
@@ -0,0 +1,36 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  isendbuff.go
+// @Description  发送缓冲队列已满时的处理策略(背压)相关声明
+package ziface
+
+import "time"
+
+// SendBuffPolicy 发送缓冲队列已满时的处理策略
+type SendBuffPolicy int
+
+const (
+	SendBuffBlock      SendBuffPolicy = iota //阻塞等待, 超时后返回错误(默认)
+	SendBuffDropOldest                       //丢弃队列中最早的消息, 放入当前消息
+	SendBuffDropNewest                       //丢弃当前消息
+	SendBuffCloseConn                        //视为慢消费者, 关闭连接
+)
+
+// SendBuffConfig 发送缓冲策略配置
+type SendBuffConfig struct {
+	Policy SendBuffPolicy
+	// 队列已满时先等待的时间, 仍然满则按照策略处理
+	// SendBuffBlock策略为0时使用默认的5ms, 其余策略为0时立即处理
+	Timeout time.Duration
+	// 消息被丢弃时的回调, data为封包后的数据
+	OnDrop func(conn IConnection, data []byte)
+}
+
+// SendBuffStats 发送缓冲队列已满时各种处理结果的计数, 需通过atomic读取
+type SendBuffStats struct {
+	Timeout    uint64 //阻塞等待超时
+	DropOldest uint64 //丢弃最早的消息
+	DropNewest uint64 //丢弃当前消息
+	Closed     uint64 //关闭慢消费者连接
+}
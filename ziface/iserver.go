@@ -15,16 +15,84 @@
 package ziface
 
 import (
+	"context"
+	"net/http"
 	"time"
 )
 
+// ListenerConfig Server附加监听端口的配置
+// 每个监听端口可以使用独立的封包方式和解码器(如旧协议端口与新协议端口共存)，业务路由与主端口共享
+type ListenerConfig struct {
+	IPVersion string    //tcp,tcp4,tcp6, 为空时与Server一致
+	IP        string    //监听的IP地址
+	Port      int       //监听的端口
+	Packet    IDataPack //该端口连接使用的封包方式，nil表示使用Server的封包方式
+	Decoder   IDecoder  //该端口连接使用的解码器，nil表示使用Server的解码器
+}
+
+// ConfigReloadReport 重新加载配置的结果
+type ConfigReloadReport struct {
+	Applied         []string //已经生效的配置字段
+	RestartRequired []string //发生变化但需要重启服务才能生效的配置字段
+}
+
 // 定义服务接口
 type IServer interface {
 	Start()                                                   //启动服务器方法
 	Stop()                                                    //停止服务器方法
+	Drain(ctx context.Context) error                          //排空连接后停止服务, 用于平滑下线
+	Shutdown(ctx context.Context) error                       //平滑停止服务, 等待正在处理的请求完成
+	Restart(ctx context.Context) error                        //热重启, 新进程继承监听端口, 当前进程排空连接
+	SetRestartTimeout(timeout time.Duration)                  //启用收到SIGUSR2信号时热重启
+	SetDrainMsg(msgID uint32, data []byte)                    //设置排空连接时通知客户端的消息
+	AfterFunc(d time.Duration, fn func()) (cancel func())     //d之后执行fn, 服务停止时自动取消, fn中的panic被恢复并记录日志
+	Every(interval time.Duration, fn func()) (stop func())    //每隔interval执行一次fn, 服务停止时自动停止, 用于后台维护任务
 	Serve()                                                   //开启业务服务方法
 	AddRouter(msgID uint32, router IRouter)                   //路由功能：给当前服务注册一个路由业务方法，供客户端链接处理使用
+	Use(middlewares ...Middleware)                            //添加全局中间件, 按添加顺序由外到内包裹全部路由
+	Group(start, end uint32) IRouterGroup                     //创建消息ID区间为[start, end]的路由分组
+	SetDefaultRouter(router IRouter)                          //设置默认路由, 处理没有匹配到消息ID的请求
+	SetRouteFunc(routeFunc RouteFunc)                         //设置路由函数, 根据请求内容计算实际路由的消息ID
+	SetRecoverHandler(handler RecoverHandler)                 //设置路由处理请求发生panic时的恢复处理
+	SetReplyMsgID(requestID, replyID uint32)                  //设置请求消息ID对应的回复消息ID, 用于request.Reply
+	SetRoutePool(pool IWorkerPool, msgIDs ...uint32)          //为消息ID绑定独立的Worker工作池, 隔离耗时的路由
+	SetMsgPriority(priority MsgPriority, msgIDs ...uint32)    //设置消息ID在Worker任务队列中的优先级
+	SetDeadLetter(config DeadLetterConfig)                    //设置处理失败的消息的重试与死信处理
+	SetSlowThreshold(threshold time.Duration)                 //设置慢处理阈值, 路由处理时间超过该值时记录日志并发布SlowHandler事件
+	SetConcurrencyLimit(limit int, msgIDs ...uint32)          //设置消息ID同时处理的请求数量上限, 超过上限的请求被丢弃
+	SetTimeoutHandler(handler HandlerFunc)                    //设置路由处理超时后的处理, 如回复超时消息
+	SetDispatcher(dispatcher IDispatcher)                     //设置Worker选择策略, 如按连接顺序处理或选择最空闲的Worker
+	SetOverflowPolicy(config OverflowConfig)                  //设置Worker任务队列已满时的处理策略: 阻塞、丢弃并回复繁忙或调用过载处理
+	GetWorkerStats() WorkerStats                              //Worker工作池统计, 用于评估WorkerPoolSize与MaxWorkerTaskLen
+	GetRouteStats() []RouteStats                              //每个路由(消息ID)的处理次数、失败次数、处理时间与延迟分位数
+	Introspect() ServerInfo                                   //运行中服务的路由、拦截器与监听端口
+	FindConns(filter ConnFilter) []IConnection                //当前Server上满足过滤条件的连接
+	SetAccessLog(config AccessLogConfig)                      //设置访问日志, 每处理完成一个消息输出连接、消息ID、字节数、延迟与结果, 支持按消息ID采样
+	SetMetrics(metrics IMetrics)                              //设置指标接口, 在连接与消息处理的关键位置调用, 用于接入任意的指标系统
+	SetStatsD(config StatsDConfig)                            //设置StatsD(DogStatsD)导出, 推送连接、消息处理指标与服务运行状态
+	SetRegistry(config RegistryConfig)                        //设置注册中心, 启动时注册服务实例并定期续约, 停止时注销
+	SetSlowLog(size int, maxPayload int)                      //设置慢日志, 保存处理时间最长的size个请求及截断的消息内容, size为0时关闭
+	GetSlowLog() []SlowLogEntry                               //慢日志中的请求, 按处理时间从长到短排列
+	ResetSlowLog()                                            //清空慢日志
 	GetConnMgr() IConnManager                                 //得到链接管理
+	SetConnMgr(mgr IConnManager)                              //使用外部的链接管理(如多个Server共享)
+	SetWorkerPool(pool IWorkerPool)                           //使用外部的Worker工作池(如多个Server共享)
+	GetGroupMgr() IGroupManager                               //得到分组(房间)管理
+	GetPubSub() IPubSub                                       //得到主题发布订阅
+	GetEventBus() IEventBus                                   //得到服务生命周期事件总线
+	SetHealthCheck(config HealthConfig)                       //设置健康检查(独立的HTTP监听地址、消息ID、就绪阈值)
+	Health() HealthStatus                                     //获取服务的健康状态
+	SetAdmin(addr string, token string)                       //设置管理HTTP服务的监听地址与访问令牌
+	SetAdminDashboard(enable bool)                            //设置是否在管理HTTP服务中提供Web控制台
+	Ban(ip string)                                            //禁止IP建立新的连接, 并断开该IP已有的连接
+	Unban(ip string)                                          //解除IP封禁
+	BannedIPs() []string                                      //被封禁的IP
+	ReloadConfig(path string) (ConfigReloadReport, error)     //重新加载配置文件, 可以直接生效的配置应用到运行中的服务
+	ApplyConfig(data []byte) (ConfigReloadReport, error)      //应用推送的JSON配置, 只需包含要修改的字段
+	WatchConfig(path string, interval time.Duration)          //监听配置文件, 变化时重新加载
+	WatchConfigSource(source IConfigSource)                   //监听配置来源(如etcd、Nacos、Apollo配置中心), 变化时重新加载
+	EnableSession(timeout time.Duration, maxPending int)      //启用会话恢复, 客户端断线重连后凭令牌恢复会话
+	GetSessionMgr() ISessionManager                           //得到会话管理, 未启用时为nil
 	SetOnConnStart(func(IConnection))                         //设置该Server的连接创建时Hook函数
 	SetOnConnStop(func(IConnection))                          //设置该Server的连接断开时的Hook函数
 	GetOnConnStart() func(IConnection)                        //得到该Server的连接创建时Hook函数
@@ -37,5 +105,21 @@ type IServer interface {
 	GetHeartBeat() IHeartbeatChecker                          //获取心跳检测器
 	GetLengthField() *LengthField
 	SetDecoder(IDecoder)
+	GetDecoder() IDecoder
 	AddInterceptor(IInterceptor)
+	AddNamedInterceptor(name string, interceptor IInterceptor)     //添加带名称的拦截器
+	RemoveInterceptor(name string) bool                            //运行时删除指定名称的拦截器
+	ReplaceInterceptor(name string, interceptor IInterceptor) bool //运行时替换指定名称的拦截器
+	InterceptorNames() []string                                    //按顺序返回全部拦截器的名称
+	AddOutInterceptor(name string, interceptor IInterceptor)       //添加发出消息的拦截器, 在封包之前执行
+	RemoveOutInterceptor(name string) bool                         //删除指定名称的发出消息拦截器
+	OutInterceptorNames() []string                                 //按顺序返回全部发出消息拦截器的名称
+	AddHTTPHandler(pattern string, handler http.HandlerFunc)       //给TCP端口上收到的普通HTTP请求注册处理方法
+	SetVersionNegotiator(IVersionNegotiator)                       //设置协议版本协商器
+	AddListener(ListenerConfig)                                    //添加附加的监听端口
+
+	SetConnRateLimit(RateLimit)               //设置每个连接默认的读写带宽限制
+	SetServerRateLimit(RateLimit)             //设置全部连接共享的读写带宽限制
+	SetSendBuffConfig(SendBuffConfig)         //设置发送缓冲队列已满时的处理策略
+	SetIdleTimeout(read, write time.Duration) //设置连接默认的读写空闲超时时间
 }
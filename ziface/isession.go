@@ -0,0 +1,49 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  isession.go
+// @Description  会话相关声明, 客户端断线重连后凭会话令牌恢复之前的会话状态
+package ziface
+
+import (
+	"context"
+	"time"
+)
+
+// SessionMsgID 会话建立/恢复使用的消息ID
+// 客户端连接后发送该消息, 数据为之前的会话令牌(为空表示新建会话), 服务端回复绑定的会话令牌
+const SessionMsgID uint32 = 99998
+
+// ISession 会话, 生命周期可以跨越多个连接
+type ISession interface {
+	Token() string                               //会话令牌
+	GetConnection() IConnection                  //当前绑定的连接, 断线等待恢复期间为nil
+	IsOnline() bool                              //是否绑定了连接
+	SetProperty(key string, value interface{})   //设置会话属性, 断线重连后仍然保留
+	GetProperty(key string) (interface{}, error) //获取会话属性
+	RemoveProperty(key string)                   //移除会话属性
+	SendMsg(msgID uint32, data []byte) error     //发送消息, 断线期间缓存, 恢复会话后按序补发; 断线前已放入发送队列的消息不补发
+}
+
+// ISessionManager 会话管理
+type ISessionManager interface {
+	Create(conn IConnection) ISession                       //为连接创建新会话
+	Resume(token string, conn IConnection) (ISession, bool) //连接凭令牌恢复会话, 令牌无效或已过期时返回false
+	Detach(conn IConnection)                                //连接断开, 会话进入等待恢复状态, 超时后过期
+	Get(token string) (ISession, bool)                      //根据令牌获取会话
+	GetByConn(connID uint64) (ISession, bool)               //获取连接绑定的会话
+	Remove(token string)                                    //立即删除会话
+	Len() int                                               //会话数量
+	SetOnExpire(func(session ISession))                     //会话过期(断线后未在超时时间内恢复)时的Hook函数
+	SetStore(store ISessionStore, ttl time.Duration)        //设置会话存储, 会话属性写入存储, 本节点没有的会话从存储中恢复
+}
+
+// ISessionStore 会话存储, 多个节点共享同一个存储时, 客户端可以在任意节点上恢复会话
+// 属性值为JSON编码后的数据, 其他节点也可以直接读取会话属性
+type ISessionStore interface {
+	Load(ctx context.Context, token string) (map[string][]byte, bool, error) //加载会话的全部属性, 会话不存在时返回false
+	Set(ctx context.Context, token string, key string, value []byte) error   //设置会话属性
+	Delete(ctx context.Context, token string, key string) error              //删除会话属性
+	Expire(ctx context.Context, token string, ttl time.Duration) error       //设置会话的过期时间, 为0时不过期
+	Remove(ctx context.Context, token string) error                          //删除会话
+}
@@ -0,0 +1,19 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  islowlog.go
+// @Description  慢日志相关声明, 保存处理时间最长的请求以便事后排查
+package ziface
+
+import "time"
+
+// SlowLogEntry 一个慢请求的记录
+type SlowLogEntry struct {
+	Time       time.Time     `json:"time"`       //处理完成的时间
+	ConnID     uint64        `json:"connID"`     //连接ID
+	RemoteAddr string        `json:"remoteAddr"` //对端地址
+	MsgID      uint32        `json:"msgID"`      //消息ID
+	Duration   time.Duration `json:"duration"`   //路由处理时间
+	Payload    []byte        `json:"payload"`    //截断后的消息内容
+	PayloadLen int           `json:"payloadLen"` //消息内容的原始字节数
+}
@@ -0,0 +1,19 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  istream.go
+// @Description  流式消息相关声明，用于文件传输、大块状态同步等超过单个数据包大小的场景
+package ziface
+
+import "io"
+
+// IStream 接收端的一条消息流, 按序重组发送端分片后的数据
+type IStream interface {
+	io.Reader
+	StreamID() uint32           //流ID, 同一连接内唯一
+	MsgID() uint32              //流所使用的消息ID
+	GetConnection() IConnection //流所属的连接
+}
+
+// StreamHandler 流处理方法, 在独立的Goroutine中执行, 返回后未读取的数据将被丢弃
+type StreamHandler func(stream IStream)
@@ -0,0 +1,23 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  itimerstore.go
+// @Description  持久化定时器相关声明, 定时器保存在文件或Redis中, 进程重启后重新加入时间轮
+package ziface
+
+import "context"
+
+// DurableTimer 持久化的定时器
+type DurableTimer struct {
+	ID      string //定时器的唯一标识, 如"unban:10086", 相同ID的定时器替换之前的
+	Task    string //注册到调度器的任务名称, 重启后按名称找到执行的函数
+	Payload []byte //任务的参数
+	At      int64  //触发时间(unix时间, 单位纳秒)
+}
+
+// ITimerStore 持久化定时器的存储, 保存全部未执行的定时器
+type ITimerStore interface {
+	Save(ctx context.Context, timer DurableTimer) error  //保存定时器, 相同ID的替换
+	Delete(ctx context.Context, id string) error         //删除定时器, 不存在时不返回错误
+	LoadAll(ctx context.Context) ([]DurableTimer, error) //读取全部定时器
+}
@@ -0,0 +1,26 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iversion.go
+// @Description  协议版本协商相关声明
+package ziface
+
+import "time"
+
+// ProtocolCodec 某个协议版本对应的封包方式与解码器
+// Packet/Decoder 为nil时沿用Server默认的配置
+type ProtocolCodec struct {
+	Version uint32    //协议版本号
+	Packet  IDataPack //该版本使用的封包方式
+	Decoder IDecoder  //该版本使用的解码器
+}
+
+// IVersionNegotiator 协议版本协商器
+// 连接建立后对端发送的第一帧数据携带协议版本号(4字节大端)，
+// 服务端根据版本号选择兼容的ProtocolCodec, 并回复协商后的版本号(0表示不支持，随后断开连接)
+type IVersionNegotiator interface {
+	AddVersion(codec *ProtocolCodec)                       //注册一个支持的协议版本
+	Negotiate(clientVersion uint32) (*ProtocolCodec, bool) //根据对端版本号选择兼容的协议版本
+	GetCodec(version uint32) *ProtocolCodec                //获取已注册版本的ProtocolCodec
+	Timeout() time.Duration                                //等待握手帧的超时时间
+}
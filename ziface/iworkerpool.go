@@ -0,0 +1,80 @@
+// Package ziface 主要提供zinx全部抽象层接口定义.
+//
+// 当前文件描述:
+// @Title  iworkerpool.go
+// @Description  Worker工作池相关声明, 同一进程中的多个Server可以共享一个工作池
+package ziface
+
+import "time"
+
+// IWorkerPool Worker工作池
+type IWorkerPool interface {
+	Start()                                              //启动全部Worker, 重复调用只启动一次
+	Size() uint32                                        //Worker的数量
+	Submit(key uint64, task func())                      //提交任务, 相同key的任务由同一个Worker按顺序执行
+	QueueLen(workerID uint32) (length int, capacity int) //Worker任务队列的长度与容量
+	QueueUsage() float64                                 //Worker任务队列的最高使用率(0~1)
+
+	SubmitPriority(key uint64, priority MsgPriority, task func()) //按优先级提交任务, 高优先级的任务先执行
+	Workers() int                                                 //当前Worker数量
+
+	TrySubmitPriority(key uint64, priority MsgPriority, task func()) bool //按优先级提交任务, 任务队列已满时不阻塞并返回false
+}
+
+// OverflowPolicy Worker任务队列已满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowBlock    OverflowPolicy = iota //阻塞读取数据的Goroutine直到任务队列有空位(默认)
+	OverflowDrop                           //丢弃请求, 设置了BusyMsgID时回复客户端服务繁忙
+	OverflowCallback                       //丢弃请求并调用过载处理方法
+)
+
+// OverflowConfig Worker任务队列已满时的处理配置
+type OverflowConfig struct {
+	Policy    OverflowPolicy
+	BusyMsgID uint32                 //OverflowDrop时回复的消息ID, 0不回复
+	BusyData  []byte                 //OverflowDrop时回复的消息内容
+	Handler   func(request IRequest) //OverflowCallback时的过载处理方法
+}
+
+// MsgPriority 消息在Worker任务队列中的优先级
+type MsgPriority int
+
+const (
+	PriorityNormal MsgPriority = iota //普通优先级
+	PriorityHigh                      //高优先级, 如心跳、战斗操作, 先于普通优先级的任务执行
+)
+
+// WorkerScaleConfig Worker工作池自动伸缩配置
+// 任务积压或平均等待时间超过WaitThreshold时增加Worker, 连续IdleRounds个检测周期空闲时减少Worker
+type WorkerScaleConfig struct {
+	MinSize       uint32        //最少Worker数量
+	MaxSize       uint32        //最多Worker数量, 同时也是任务队列的数量
+	Interval      time.Duration //检测周期, 默认1秒
+	WaitThreshold time.Duration //任务平均等待时间的扩容阈值, 默认50毫秒
+	IdleRounds    int           //连续空闲多少个检测周期后缩容, 默认3
+}
+
+// WorkerStats Worker工作池统计, 时间单位为纳秒
+type WorkerStats struct {
+	Workers           int           `json:"workers"`           //当前Worker数量
+	QueueDepth        []int         `json:"queueDepth"`        //每个Worker任务队列中等待的任务数
+	QueueCap          int           `json:"queueCap"`          //每个Worker任务队列的容量
+	Submitted         uint64        `json:"submitted"`         //提交给Worker的任务数
+	Completed         uint64        `json:"completed"`         //Worker处理完成的任务数
+	Dropped           uint64        `json:"dropped"`           //没有匹配到路由而被丢弃的消息数
+	Panics            uint64        `json:"panics"`            //路由处理时发生panic的消息数
+	DeadLetters       uint64        `json:"deadLetters"`       //交给死信处理方法的消息数
+	Slow              uint64        `json:"slow"`              //处理时间超过慢处理阈值的消息数
+	Limited           uint64        `json:"limited"`           //同时处理的请求达到上限而被丢弃的消息数
+	Timeouts          uint64        `json:"timeouts"`          //处理超过路由期限的消息数
+	Steals            uint64        `json:"steals"`            //空闲Worker从其他Worker的任务队列中窃取的任务数
+	OverflowBlocked   uint64        `json:"overflowBlocked"`   //任务队列已满时阻塞等待的请求数
+	OverflowDropped   uint64        `json:"overflowDropped"`   //任务队列已满时丢弃的请求数
+	OverflowCallbacks uint64        `json:"overflowCallbacks"` //任务队列已满时交给过载处理方法的请求数
+	AvgWait           time.Duration `json:"avgWait"`           //任务在队列中的平均等待时间
+	MaxWait           time.Duration `json:"maxWait"`           //任务在队列中的最长等待时间
+	AvgExec           time.Duration `json:"avgExec"`           //任务的平均执行时间
+	MaxExec           time.Duration `json:"maxExec"`           //任务的最长执行时间
+}
@@ -6,51 +6,148 @@
 
 package zinterceptor
 
-import "github.com/aceld/zinx/ziface"
+import (
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// namedInterceptor 带名称的拦截器，名称用于运行时删除/替换，未命名的拦截器名称为空
+type namedInterceptor struct {
+	name        string
+	interceptor ziface.IInterceptor
+}
 
 // Builder 责任链构造器
+// 拦截器列表采用写时复制，运行时增删替换拦截器不影响正在执行中的请求
 type Builder struct {
-	body       []ziface.IInterceptor
+	body       []namedInterceptor
 	head, tail ziface.IInterceptor
-	req        ziface.IcReq
+	chain      []ziface.IInterceptor //当前生效的完整责任链
+	lock       sync.RWMutex
 }
 
 func NewBuilder() ziface.IBuilder {
 	return &Builder{
-		body: make([]ziface.IInterceptor, 0),
+		body: make([]namedInterceptor, 0),
 	}
 }
 
 func (ic *Builder) Head(interceptor ziface.IInterceptor) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
 	ic.head = interceptor
+	ic.rebuild()
 }
 
 func (ic *Builder) Tail(interceptor ziface.IInterceptor) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
 	ic.tail = interceptor
+	ic.rebuild()
 }
 
 func (ic *Builder) AddInterceptor(interceptor ziface.IInterceptor) {
-	ic.body = append(ic.body, interceptor)
+	ic.AddNamedInterceptor("", interceptor)
 }
 
-func (ic *Builder) Execute(req ziface.IcReq) ziface.IcResp {
-	ic.req = req
+// AddNamedInterceptor 添加带名称的拦截器, 名称已存在时替换原拦截器
+func (ic *Builder) AddNamedInterceptor(name string, interceptor ziface.IInterceptor) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	body := make([]namedInterceptor, 0, len(ic.body)+1)
+	replaced := false
+	for _, item := range ic.body {
+		if name != "" && item.name == name {
+			item.interceptor = interceptor
+			replaced = true
+		}
+		body = append(body, item)
+	}
+	if !replaced {
+		body = append(body, namedInterceptor{name: name, interceptor: interceptor})
+	}
+	ic.body = body
+	ic.rebuild()
+}
+
+// RemoveInterceptor 删除指定名称的拦截器
+func (ic *Builder) RemoveInterceptor(name string) bool {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	body := make([]namedInterceptor, 0, len(ic.body))
+	for _, item := range ic.body {
+		if name != "" && item.name == name {
+			continue
+		}
+		body = append(body, item)
+	}
+	if len(body) == len(ic.body) {
+		return false
+	}
+	ic.body = body
+	ic.rebuild()
+
+	return true
+}
+
+// ReplaceInterceptor 替换指定名称的拦截器，保持其在责任链中的位置
+func (ic *Builder) ReplaceInterceptor(name string, interceptor ziface.IInterceptor) bool {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	body := make([]namedInterceptor, len(ic.body))
+	copy(body, ic.body)
+	for i := range body {
+		if name != "" && body[i].name == name {
+			body[i].interceptor = interceptor
+			ic.body = body
+			ic.rebuild()
+			return true
+		}
+	}
+
+	return false
+}
+
+// InterceptorNames 按责任链顺序返回全部拦截器的名称，未命名的拦截器为空字符串
+func (ic *Builder) InterceptorNames() []string {
+	ic.lock.RLock()
+	defer ic.lock.RUnlock()
 
+	names := make([]string, 0, len(ic.body))
+	for _, item := range ic.body {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+// rebuild 重新生成当前生效的责任链，调用方需持有写锁
+func (ic *Builder) rebuild() {
 	//将全部拦截器放入Builder中
 	var interceptors []ziface.IInterceptor
 	if ic.head != nil {
 		interceptors = append(interceptors, ic.head)
 	}
-	if len(ic.body) > 0 {
-		interceptors = append(interceptors, ic.body...)
+	for _, item := range ic.body {
+		interceptors = append(interceptors, item.interceptor)
 	}
 	if ic.tail != nil {
 		interceptors = append(interceptors, ic.tail)
 	}
+	ic.chain = interceptors
+}
+
+func (ic *Builder) Execute(req ziface.IcReq) ziface.IcResp {
+	ic.lock.RLock()
+	interceptors := ic.chain
+	ic.lock.RUnlock()
 
 	//创建一个拦截器责任链，执行每一个拦截器
 	chain := NewChain(interceptors, 0, req)
 
 	//进入责任链执行
-	return chain.Proceed(ic.req)
+	return chain.Proceed(req)
 }
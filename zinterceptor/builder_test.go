@@ -0,0 +1,36 @@
+package zinterceptor
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// tagInterceptor 在请求上追加标记，用于观察责任链的执行顺序
+type tagInterceptor string
+
+func (t tagInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	return chain.Proceed(chain.Request().(string) + string(t))
+}
+
+func TestBuilderRuntimeChange(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddNamedInterceptor("a", tagInterceptor("a"))
+	builder.AddInterceptor(tagInterceptor("b"))
+	builder.AddNamedInterceptor("c", tagInterceptor("c"))
+	assert.Equal(t, "abc", builder.Execute(""))
+	assert.Equal(t, []string{"a", "", "c"}, builder.InterceptorNames())
+
+	assert.True(t, builder.ReplaceInterceptor("a", tagInterceptor("x")))
+	assert.Equal(t, "xbc", builder.Execute(""))
+
+	assert.True(t, builder.RemoveInterceptor("c"))
+	assert.False(t, builder.RemoveInterceptor("c"))
+	assert.False(t, builder.ReplaceInterceptor("", tagInterceptor("y")))
+	assert.Equal(t, "xb", builder.Execute(""))
+
+	// 同名拦截器替换原拦截器
+	builder.AddNamedInterceptor("a", tagInterceptor("z"))
+	assert.Equal(t, "zb", builder.Execute(""))
+}
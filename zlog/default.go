@@ -44,3 +44,14 @@ func SetLogger(newlog ziface.ILogger) {
 func Ins() ziface.ILogger {
 	return zLogInstance
 }
+
+// Sync 刷新日志, 服务关闭前调用
+// 自定义的Logger实现了 Sync() error 方法时一并调用
+func Sync() error {
+	if syncer, ok := zLogInstance.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return err
+		}
+	}
+	return StdZinxLog.Sync()
+}
@@ -357,6 +357,17 @@ func (log *ZinxLoggerCore) SetLogFile(fileDir string, fileName string) {
 	log.fileName = fileName
 }
 
+// Sync 将日志文件的内容刷新到磁盘
+func (log *ZinxLoggerCore) Sync() error {
+	log.fsLock.Lock()
+	defer log.fsLock.Unlock()
+
+	if log.file != nil {
+		return log.file.Sync()
+	}
+	return nil
+}
+
 // 关闭日志绑定的文件
 func (log *ZinxLoggerCore) closeFile() {
 	if log.file != nil {
@@ -0,0 +1,78 @@
+package znet
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// accessLog 访问日志的采样与输出
+type accessLog struct {
+	config ziface.AccessLogConfig
+	// 每个消息ID的记录计数, msgID -> *uint64
+	counters sync.Map
+}
+
+func newAccessLog(config ziface.AccessLogConfig) *accessLog {
+	return &accessLog{config: config}
+}
+
+// sampled 按消息ID的采样间隔判断是否输出本条记录
+func (l *accessLog) sampled(msgID uint32, failed bool) bool {
+	if failed && l.config.KeepErrors {
+		return true
+	}
+	sample := l.config.Sample
+	if s, ok := l.config.RouteSample[msgID]; ok {
+		sample = s
+	}
+	if sample <= 1 {
+		return true
+	}
+
+	counter, ok := l.counters.Load(msgID)
+	if !ok {
+		counter, _ = l.counters.LoadOrStore(msgID, new(uint64))
+	}
+	return (atomic.AddUint64(counter.(*uint64), 1)-1)%uint64(sample) == 0
+}
+
+// log 记录一个处理完成的消息
+func (l *accessLog) log(request ziface.IRequest, msgID uint32, received time.Time, attempts int, err error) {
+	if l == nil || !l.sampled(msgID, err != nil) {
+		return
+	}
+
+	record := ziface.AccessRecord{
+		Time:     received,
+		MsgID:    msgID,
+		Bytes:    len(request.GetData()),
+		Latency:  time.Since(received),
+		Attempts: attempts,
+	}
+	if conn := request.GetConnection(); conn != nil {
+		record.ConnID = conn.GetConnID()
+		if addr := conn.RemoteAddr(); addr != nil {
+			record.RemoteAddr = addr.String()
+		}
+	}
+	if err != nil {
+		record.Err = err.Error()
+	}
+	l.config.Handler(record)
+}
+
+// NewAccessLogWriter 创建按行输出JSON格式访问日志的输出方法, 可以直接作为AccessLogConfig.Handler使用
+func NewAccessLogWriter(w io.Writer) ziface.AccessLogHandler {
+	var lock sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(record ziface.AccessRecord) {
+		lock.Lock()
+		defer lock.Unlock()
+		_ = encoder.Encode(record)
+	}
+}
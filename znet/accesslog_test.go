@@ -0,0 +1,52 @@
+package znet
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	mh := NewMsgHandle()
+	mh.SetAccessLog(ziface.AccessLogConfig{
+		Handler:     NewAccessLogWriter(&buf),
+		RouteSample: map[uint32]uint32{2: 3},
+		KeepErrors:  true,
+	})
+	mh.AddRouter(1, &BaseRouter{})
+	mh.AddRouter(2, &failRouter{})
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, []byte("hello"))))
+	// 处理失败的记录全部输出, 之后4条成功的记录每3条输出1条
+	for i := 0; i < 6; i++ {
+		mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	}
+
+	var records []ziface.AccessRecord
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record ziface.AccessRecord
+		assert.Nil(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	assert.Len(t, records, 5)
+	assert.Equal(t, uint32(1), records[0].MsgID)
+	assert.Equal(t, 5, records[0].Bytes)
+	assert.Equal(t, 1, records[0].Attempts)
+	assert.Empty(t, records[0].Err)
+	assert.False(t, records[0].Time.IsZero())
+	assert.Equal(t, "fail", records[1].Err)
+	assert.Equal(t, "fail", records[2].Err)
+	assert.Empty(t, records[3].Err)
+	assert.Empty(t, records[4].Err)
+
+	// 关闭访问日志
+	mh.SetAccessLog(ziface.AccessLogConfig{})
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, 0, buf.Len())
+}
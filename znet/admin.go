@@ -0,0 +1,339 @@
+package znet
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// SetAdmin 设置管理HTTP服务的监听地址与访问令牌, 需在Start之前调用
+// 管理HTTP服务提供pprof、运行状态、当前配置以及日志级别、封禁IP、排空连接等运行时控制
+func (s *Server) SetAdmin(addr string, token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.adminAddr = addr
+	s.adminToken = token
+}
+
+// startAdminServer 启动管理HTTP服务, 没有设置访问令牌时不启动
+func (s *Server) startAdminServer() {
+	s.lock.RLock()
+	addr, token, dashboard := s.adminAddr, s.adminToken, s.adminDashboard
+	s.lock.RUnlock()
+
+	if addr == "" {
+		return
+	}
+	if token == "" {
+		zlog.Ins().ErrorF("[START] admin listener at %s is not started: admin token is empty", addr)
+		return
+	}
+
+	handler := adminAuth(token, s.newAdminMux())
+	if dashboard {
+		// 控制台页面不需要令牌, 页面中输入令牌后访问其他管理接口
+		mux := http.NewServeMux()
+		mux.HandleFunc("/admin/ui", serveDashboard)
+		mux.Handle("/", handler)
+		handler = mux
+	}
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	s.lock.Lock()
+	s.adminServer = server
+	s.lock.Unlock()
+
+	go func() {
+		zlog.Ins().InfoF("[START] admin listener at %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			zlog.Ins().ErrorF("admin listener err: %v", err)
+		}
+	}()
+}
+
+func (s *Server) stopAdminServer() {
+	s.lock.Lock()
+	server := s.adminServer
+	s.adminServer = nil
+	s.lock.Unlock()
+
+	if server != nil {
+		_ = server.Shutdown(context.Background())
+	}
+}
+
+// adminAuth 校验请求携带的访问令牌
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// pprof
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// 运行状态
+	mux.HandleFunc("/admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		sendBuff := s.getSendBuffStats()
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"name":        s.Name,
+			"connections": s.ConnMgr.Len(),
+			"maxConn":     zconf.GlobalObject.MaxConn,
+			"inFlight":    s.msgHandler.InFlight(),
+			"queueUsage":  s.msgHandler.QueueUsage(),
+			"goroutines":  runtime.NumGoroutine(),
+			"memAlloc":    mem.Alloc,
+			"memSys":      mem.Sys,
+			"numGC":       mem.NumGC,
+			"health":      s.Health(),
+			"workerStats": s.GetWorkerStats(),
+			"sendBuffStats": map[string]uint64{
+				"timeout":    atomic.LoadUint64(&sendBuff.Timeout),
+				"dropOldest": atomic.LoadUint64(&sendBuff.DropOldest),
+				"dropNewest": atomic.LoadUint64(&sendBuff.DropNewest),
+				"closed":     atomic.LoadUint64(&sendBuff.Closed),
+			},
+		})
+	})
+
+	// 连接数量与每个连接的收发统计: GET ip=<ip>&tag=<key:value>&minBytesIn=<n>&limit=<n> 过滤连接
+	mux.HandleFunc("/admin/conns", func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseConnFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"connections": s.ConnMgr.Len(),
+			"stats":       collectConnStats(s.FindConns(filter)),
+		})
+	})
+
+	// 路由、拦截器与监听端口
+	mux.HandleFunc("/admin/info", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Introspect())
+	})
+
+	// 每个路由的处理统计
+	mux.HandleFunc("/admin/routes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"routes": s.GetRouteStats()})
+	})
+
+	// 慢日志: GET 处理时间最长的请求, DELETE 清空
+	mux.HandleFunc("/admin/slowlog", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			s.ResetSlowLog()
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"entries": s.GetSlowLog()})
+	})
+
+	// 断开连接: POST connID=<id>, 或 POST tag=<key:value> 断开同时拥有全部标签的连接(如某个用户的全部连接)
+	mux.HandleFunc("/admin/kick", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tags, ok := r.Form["tag"]; ok {
+			filter := ziface.ConnFilter{Tags: make(map[string]string)}
+			for _, tag := range tags {
+				kv := strings.SplitN(tag, ":", 2)
+				if len(kv) != 2 {
+					http.Error(w, fmt.Sprintf("invalid tag %q", tag), http.StatusBadRequest)
+					return
+				}
+				filter.Tags[kv[0]] = kv[1]
+			}
+			connIDs := make([]uint64, 0)
+			for _, conn := range s.FindConns(filter) {
+				conn.Stop()
+				connIDs = append(connIDs, conn.GetConnID())
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(connIDs), "connIDs": connIDs})
+			return
+		}
+		connID, err := strconv.ParseUint(r.FormValue("connID"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid connID", http.StatusBadRequest)
+			return
+		}
+		conn, err := s.ConnMgr.Get(connID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		conn.Stop()
+		writeJSON(w, http.StatusOK, map[string]uint64{"kicked": connID})
+	})
+
+	// 当前生效的完整配置(见zconf.Dump), 不返回密钥; POST JSON配置(只包含要修改的字段) 应用推送的配置
+	mux.HandleFunc("/admin/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusOK, s.EffectiveConfig())
+			return
+		}
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := s.ApplyConfig(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+
+	// 重新加载启动参数指定的配置文件: POST
+	mux.HandleFunc("/admin/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report, err := s.ReloadConfig("")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+
+	// 设置日志级别: POST level=<0~5>
+	mux.HandleFunc("/admin/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		level, err := strconv.Atoi(r.FormValue("level"))
+		if err != nil || level < zlog.LogDebug || level > zlog.LogFatal {
+			http.Error(w, "invalid level", http.StatusBadRequest)
+			return
+		}
+		zlog.SetLogLevel(level)
+		writeJSON(w, http.StatusOK, map[string]int{"level": level})
+	})
+
+	// 封禁IP: GET 封禁列表, POST ip=<ip> 封禁, DELETE ip=<ip> 解除封禁
+	mux.HandleFunc("/admin/bans", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodDelete:
+			ip := r.FormValue("ip")
+			if ip == "" {
+				http.Error(w, "ip is required", http.StatusBadRequest)
+				return
+			}
+			if r.Method == http.MethodPost {
+				s.Ban(ip)
+			} else {
+				s.Unban(ip)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{"bans": s.BannedIPs()})
+	})
+
+	// 排空连接: POST timeout=<duration>, 在后台执行, 默认等待30秒
+	mux.HandleFunc("/admin/drain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		timeout := DefaultRestartTimeout
+		if value := r.FormValue("timeout"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid timeout", http.StatusBadRequest)
+				return
+			}
+			timeout = d
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := s.Drain(ctx); err != nil {
+				zlog.Ins().ErrorF("[ADMIN] drain err: %v", err)
+			}
+		}()
+		writeJSON(w, http.StatusAccepted, map[string]string{"drain": "started"})
+	})
+
+	return mux
+}
+
+// parseConnFilter 解析请求中的连接过滤条件
+func parseConnFilter(r *http.Request) (ziface.ConnFilter, error) {
+	query := r.URL.Query()
+	filter := ziface.ConnFilter{RemoteIP: query.Get("ip")}
+	for _, tag := range query["tag"] {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			return filter, fmt.Errorf("invalid tag %q", tag)
+		}
+		if filter.Tags == nil {
+			filter.Tags = make(map[string]string)
+		}
+		filter.Tags[kv[0]] = kv[1]
+	}
+	if value := query.Get("minBytesIn"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid minBytesIn %q", value)
+		}
+		filter.MinBytesIn = n
+	}
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid limit %q", value)
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,176 @@
+package znet
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(method, path, token string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(method, "http://127.0.0.1:28991"+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func TestServerAdmin(t *testing.T) {
+	s := NewServer(WithAdmin("127.0.0.1:28991", "secret")).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28992
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// 缺少令牌
+	resp, err := adminRequest(http.MethodGet, "/admin/stats", "", nil)
+	assert.Nil(t, err)
+	if err == nil {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	for _, path := range []string{"/admin/stats", "/admin/conns", "/admin/config", "/debug/pprof/"} {
+		resp, err := adminRequest(http.MethodGet, path, "secret", nil)
+		assert.Nil(t, err, path)
+		if err == nil {
+			_ = resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+		}
+	}
+
+	// 封禁IP后已有连接被断开
+	conn, err := net.Dial("tcp", "127.0.0.1:28992")
+	assert.Nil(t, err)
+	defer conn.Close()
+	data, _ := zpack.NewDataPack().Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	_, _ = conn.Write(data)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, s.GetConnMgr().Len())
+
+	// 按标签断开连接
+	s.GetConnMgr().SetTag(s.GetConnMgr().GetAll()[0], "uid", "10001")
+	resp, err = adminRequest(http.MethodPost, "/admin/kick", "secret", url.Values{"tag": {"uid:10002"}})
+	assert.Nil(t, err)
+	if err == nil {
+		var kicked struct {
+			Count int `json:"count"`
+		}
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&kicked))
+		_ = resp.Body.Close()
+		assert.Equal(t, 0, kicked.Count)
+	}
+	assert.Equal(t, 1, s.GetConnMgr().Len())
+
+	// 推送配置
+	level := zconf.GlobalObject.LogIsolationLevel
+	defer zlog.SetLogLevel(level)
+	req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:28991/admin/config", strings.NewReader(`{"LogIsolationLevel":`+strconv.Itoa(level+1)+`}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	if err == nil {
+		var report ziface.ConfigReloadReport
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&report))
+		_ = resp.Body.Close()
+		assert.Equal(t, []string{"LogIsolationLevel"}, report.Applied)
+		assert.Equal(t, level+1, zconf.GlobalObject.LogIsolationLevel)
+	}
+	_, err = s.ApplyConfig([]byte(`{"LogIsolationLevel":` + strconv.Itoa(level) + `}`))
+	assert.Nil(t, err)
+	_, err = s.ApplyConfig([]byte("{"))
+	assert.NotNil(t, err)
+
+	resp, err = adminRequest(http.MethodPost, "/admin/kick", "secret", url.Values{"tag": {"uid:10001"}})
+	assert.Nil(t, err)
+	if err == nil {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, s.GetConnMgr().Len())
+
+	conn, err = net.Dial("tcp", "127.0.0.1:28992")
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, _ = conn.Write(data)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, s.GetConnMgr().Len())
+
+	resp, err = adminRequest(http.MethodPost, "/admin/bans", "secret", url.Values{"ip": {"127.0.0.1"}})
+	assert.Nil(t, err)
+	if err == nil {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, []string{"127.0.0.1"}, s.BannedIPs())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, s.GetConnMgr().Len())
+
+	s.Unban("127.0.0.1")
+	assert.Empty(t, s.BannedIPs())
+}
+
+func TestServerAdminDashboard(t *testing.T) {
+	s := NewServer(WithAdmin("127.0.0.1:28985", "secret"), WithAdminDashboard()).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28986
+	s.AddRouter(1, &pingRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	request := func(method, path, token string, form url.Values) int {
+		req, _ := http.NewRequest(method, "http://127.0.0.1:28985"+path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err, path) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// 控制台页面不需要令牌, 其他管理接口仍然需要
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/admin/ui", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/admin/routes", "", nil))
+
+	conn, err := net.Dial("tcp", "127.0.0.1:28986")
+	assert.Nil(t, err)
+	defer conn.Close()
+	data, _ := zpack.NewDataPack().Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	_, _ = conn.Write(data)
+	_, _ = conn.Read(make([]byte, len(data)))
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/admin/routes", "secret", nil))
+	routes := s.GetRouteStats()
+	assert.Len(t, routes, 1)
+	assert.Equal(t, uint64(1), routes[0].Count)
+
+	// 断开连接
+	connIDs := s.GetConnMgr().GetAllConnID()
+	assert.Len(t, connIDs, 1)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/admin/kick", "secret", url.Values{"connID": {"x"}}))
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/admin/kick", "secret", url.Values{"connID": {strconv.FormatUint(connIDs[0], 10)}}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, s.GetConnMgr().Len())
+}
@@ -0,0 +1,80 @@
+package znet
+
+import (
+	"net"
+	"sort"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// remoteIP 获取地址中的IP, IPv6地址使用标准格式
+func remoteIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return normalizeIP(host)
+}
+
+// normalizeIP 统一IP地址的格式(如IPv6地址的缩写), 不是IP地址时原样返回
+func normalizeIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
+
+// Ban 禁止IP建立新的连接, 并断开该IP已有的连接
+func (s *Server) Ban(ip string) {
+	ip = normalizeIP(ip)
+
+	s.lock.Lock()
+	if s.bans == nil {
+		s.bans = make(map[string]struct{})
+	}
+	s.bans[ip] = struct{}{}
+	s.lock.Unlock()
+
+	for _, conn := range s.ConnMgr.GetAll() {
+		if remoteIP(conn.RemoteAddr()) == ip {
+			zlog.Ins().InfoF("ban ip %s, stop connID = %d", ip, conn.GetConnID())
+			conn.Stop()
+		}
+	}
+}
+
+func (s *Server) Unban(ip string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.bans, normalizeIP(ip))
+}
+
+func (s *Server) BannedIPs() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ips := make([]string, 0, len(s.bans))
+	for ip := range s.bans {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func (s *Server) isBanned(addr net.Addr) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.bans) == 0 {
+		return false
+	}
+	_, ok := s.bans[remoteIP(addr)]
+	return ok
+}
@@ -1,27 +1,38 @@
 package znet
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
-	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/zdecoder"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
 	"github.com/aceld/zinx/zpack"
 	"github.com/gorilla/websocket"
 	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var ErrClientNotConnected = errors.New("client not connected")
+
 type Client struct {
 	//目标链接服务器的IP
 	Ip string
 	//目标链接服务器的端口
 	Port int
-	// 客户端版本 tcp,websocket
+	// 客户端版本 tcp,websocket,udp
 	version string
 	//客户端链接
-	conn ziface.IConnection
+	conn     ziface.IConnection
+	connLock sync.RWMutex
 	//该client的连接创建时Hook函数
 	onConnStart func(conn ziface.IConnection)
 	//该client的连接断开时的Hook函数
@@ -41,9 +52,50 @@ type Client struct {
 
 	// websocket
 	dialer *websocket.Dialer
+	// websocket握手请求的路径, 如"/ws"
+	wsPath string
+	// websocket握手请求附加的Header, 如认证令牌、Origin
+	wsHeader http.Header
 
 	// errChan
 	ErrChan chan error
+
+	// 期望的协议版本号，0表示不进行版本握手
+	protocolVersion uint32
+
+	// 是否启用会话恢复
+	session bool
+	// 服务端分配的会话令牌, 重连时凭令牌恢复会话
+	sessionToken string
+	sessionLock  sync.RWMutex
+
+	// 自动重连配置, nil表示不重连
+	reconnect *ziface.ReconnectConfig
+	// 重连的次数
+	reconnects uint64
+	// 发出消息的拦截器
+	outChain *outChain
+	// Request默认的等待回复时间
+	requestTimeout time.Duration
+	// TLS配置, nil时跳过证书验证
+	tlsConfig *tls.Config
+	// 连接上复用的逻辑会话, 逻辑会话ID -> *MuxSession
+	muxSessions sync.Map
+	// 最后分配的逻辑会话ID
+	muxID uint32
+	// 分发逻辑会话消息的拦截器只添加一次
+	muxDemuxOnce sync.Once
+	// 是否已经Start, 已添加解码器
+	started int32
+
+	// 客户端的统计
+	stats clientStats
+	// 建立连接失败时的回调
+	onConnectFailed func(err error)
+	// 发送消息失败时的回调
+	onSendError func(conn ziface.IConnection, msgID uint32, err error)
+	// 指标接口, nil表示不上报
+	metrics ziface.IMetrics
 }
 
 func NewClient(ip string, port int, opts ...ClientOption) ziface.IClient {
@@ -56,6 +108,7 @@ func NewClient(ip string, port int, opts ...ClientOption) ziface.IClient {
 		decoder:    zdecoder.NewTLVDecoder(),                     //默认使用zinx的TLV解码器
 		version:    "tcp",
 		ErrChan:    make(chan error),
+		outChain:   newOutChain(),
 	}
 
 	//应用Option设置
@@ -77,6 +130,7 @@ func NewWsClient(ip string, port int, opts ...ClientOption) ziface.IClient {
 		version:    "websocket",
 		dialer:     &websocket.Dialer{},
 		ErrChan:    make(chan error),
+		outChain:   newOutChain(),
 	}
 
 	//应用Option设置
@@ -96,7 +150,74 @@ func NewTLSClient(ip string, port int, opts ...ClientOption) ziface.IClient {
 	return c
 }
 
+// SetTLSConfig 设置TLS配置并启用TLS, 如验证服务端证书的RootCAs、双向认证的客户端证书、ServerName(SNI)
+// 需在Start之前调用, 可以使用NewClientTLSConfig从证书文件创建
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+	c.useTLS = true
+}
+
+// getTLSConfig 使用的TLS配置, 没有设置时跳过证书验证
+func (c *Client) getTLSConfig() *tls.Config {
+	if c.tlsConfig != nil {
+		return c.tlsConfig
+	}
+	return &tls.Config{
+		InsecureSkipVerify: true, //这里是跳过证书验证，因为证书签发机构的CA证书是不被认证的
+	}
+}
+
+// NewClientTLSConfig 从证书文件创建客户端TLS配置
+// caFile为验证服务端证书的CA证书, 为空时使用系统的CA; certFile与keyFile为双向认证(mTLS)的客户端证书与私钥, 为空时不发送;
+// serverName为验证证书与SNI使用的服务端名称, 为空时使用连接的地址
+func NewClientTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewWsTLSClient 创建使用WSS(TLS)的Websocket客户端, 可以通过WithTLSConfigClient设置证书验证
+func NewWsTLSClient(ip string, port int, opts ...ClientOption) ziface.IClient {
+
+	c, _ := NewWsClient(ip, port, opts...).(*Client)
+
+	c.useTLS = true
+
+	return c
+}
+
+// SetWsPath 设置Websocket握手请求的路径, 如服务端通过网关按路径转发时, 需在Start之前调用
+func (c *Client) SetWsPath(path string) {
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	c.wsPath = path
+}
+
+// SetWsHeader 设置Websocket握手请求附加的Header, 如认证令牌、Origin、子协议, 需在Start之前调用
+func (c *Client) SetWsHeader(header http.Header) {
+	c.wsHeader = header
+}
+
 // 启动客户端，发送请求且建立链接
+// 设置了自动重连时, 连接失败或断开后按退避策略重新连接, 直到Stop或达到最多重连次数
 func (c *Client) Start() {
 
 	c.exitChan = make(chan struct{})
@@ -105,74 +226,121 @@ func (c *Client) Start() {
 	if c.decoder != nil {
 		c.msgHandler.AddInterceptor(c.decoder)
 	}
+	// 创建了逻辑会话时, 解码之后将属于逻辑会话的消息分发给逻辑会话
+	atomic.StoreInt32(&c.started, 1)
+	if atomic.LoadUint32(&c.muxID) > 0 {
+		c.addMuxDemux()
+	}
 
-	//客户端将协程池关闭
-	zconf.GlobalObject.WorkerPoolSize = 0
+	//客户端将协程池关闭, 不修改全局配置, 避免影响同一进程中的服务端
+	c.msgHandler.SetWorkerPool(NewWorkerPool(0, 0))
 
 	go func() {
-
-		addr := &net.TCPAddr{
-			IP:   net.ParseIP(c.Ip),
-			Port: c.Port,
-			Zone: "", //for ipv6, ignore
-		}
-
-		//创建原始Socket，得到net.Conn
-		switch c.version {
-		case "websocket":
-			wsAddr := fmt.Sprintf("ws://%s", addr.String())
-
-			//创建原始Socket，得到net.Conn
-			wsConn, _, err := c.dialer.Dial(wsAddr, nil)
-			if err != nil {
-				//创建链接失败
-				zlog.Ins().ErrorF("WsClient connect to server failed, err:%v", err)
-				c.ErrChan <- err
-			}
-			//创建Connection对象
-			c.conn = newWsClientConn(c, wsConn)
-
-		default:
-			var conn net.Conn
-			var err error
-			if c.useTLS {
-				// TLS加密
-				config := &tls.Config{
-					InsecureSkipVerify: true, //这里是跳过证书验证，因为证书签发机构的CA证书是不被认证的
+		for attempts := 0; ; {
+			conn, err := c.dial()
+			if err == nil {
+				attempts = 0
+				c.setConn(conn)
+
+				zlog.Ins().InfoF("[START] Zinx Client LocalAddr: %s, RemoteAddr: %s\n", conn.LocalAddr(), conn.RemoteAddr())
+				//HeartBeat心跳检测
+				if c.hc != nil {
+					//创建链接成功，绑定链接与心跳检测器
+					c.hc.BindConn(conn)
 				}
 
-				conn, err = tls.Dial("tcp", fmt.Sprintf("%v:%v", net.ParseIP(c.Ip), c.Port), config)
-				if err != nil {
-					zlog.Ins().ErrorF("tls client connect to server failed, err:%v", err)
-					c.ErrChan <- err
+				//启动链接
+				go conn.Start()
+
+				select {
+				case <-c.exitChan:
+					zlog.Ins().InfoF("client exit.")
+					return
+				case <-conn.Context().Done():
 				}
+				c.connClosed(conn)
+			} else if c.reconnect == nil {
+				c.connectFailed(err)
+				c.ErrChan <- err
 			} else {
-				conn, err = net.DialTCP("tcp", nil, addr)
-				if err != nil {
-					//创建链接失败
-					zlog.Ins().ErrorF("client connect to server failed, err:%v", err)
-					c.ErrChan <- err
+				c.connectFailed(err)
+				// 重连时不阻塞, 没有接收方时丢弃错误
+				select {
+				case c.ErrChan <- err:
+				default:
 				}
 			}
-			//创建Connection对象
-			c.conn = newClientConn(c, conn)
-		}
 
-		zlog.Ins().InfoF("[START] Zinx Client LocalAddr: %s, RemoteAddr: %s\n", c.conn.LocalAddr(), c.conn.RemoteAddr())
-		//HeartBeat心跳检测
-		if c.hc != nil {
-			//创建链接成功，绑定链接与心跳检测器
-			c.hc.BindConn(c.conn)
+			// 等待重连
+			delay, ok := c.nextReconnect(attempts, err)
+			if !ok {
+				<-c.exitChan
+				zlog.Ins().InfoF("client exit.")
+				return
+			}
+			attempts++
+			select {
+			case <-c.exitChan:
+				zlog.Ins().InfoF("client exit.")
+				return
+			case <-time.After(delay):
+			}
+			c.reconnected()
 		}
+	}()
+}
 
-		//启动链接
-		go c.conn.Start()
+// dial 创建原始Socket并创建Connection对象
+func (c *Client) dial() (ziface.IConnection, error) {
+	addr := &net.TCPAddr{
+		IP:   net.ParseIP(c.Ip),
+		Port: c.Port,
+		Zone: "", //for ipv6, ignore
+	}
 
-		select {
-		case <-c.exitChan:
-			zlog.Ins().InfoF("client exit.")
+	//创建原始Socket，得到net.Conn
+	switch c.version {
+	case "websocket":
+		wsAddr := fmt.Sprintf("ws://%s%s", addr.String(), c.wsPath)
+		if c.useTLS {
+			wsAddr = fmt.Sprintf("wss://%s%s", addr.String(), c.wsPath)
+			c.dialer.TLSClientConfig = c.getTLSConfig()
 		}
-	}()
+
+		//创建原始Socket，得到net.Conn
+		wsConn, _, err := c.dialer.Dial(wsAddr, c.wsHeader)
+		if err != nil {
+			//创建链接失败
+			zlog.Ins().ErrorF("WsClient connect to server failed, err:%v", err)
+			return nil, err
+		}
+		//创建Connection对象
+		return newWsClientConn(c, wsConn), nil
+
+	case "udp":
+		return c.dialUDP()
+
+	default:
+		var conn net.Conn
+		var err error
+		if c.useTLS {
+			// TLS加密
+			conn, err = tls.Dial("tcp", net.JoinHostPort(c.Ip, strconv.Itoa(c.Port)), c.getTLSConfig())
+			if err != nil {
+				zlog.Ins().ErrorF("tls client connect to server failed, err:%v", err)
+				return nil, err
+			}
+		} else {
+			conn, err = net.DialTCP("tcp", nil, addr)
+			if err != nil {
+				//创建链接失败
+				zlog.Ins().ErrorF("client connect to server failed, err:%v", err)
+				return nil, err
+			}
+		}
+		//创建Connection对象
+		return newClientConn(c, conn), nil
+	}
 }
 
 // StartHeartBeat 启动心跳检测
@@ -195,6 +363,9 @@ func (c *Client) StartHeartBeatWithOption(interval time.Duration, option *ziface
 		checker.SetHeartbeatMsgFunc(option.MakeMsg)
 		checker.SetOnRemoteNotAlive(option.OnRemoteNotAlive)
 		checker.BindRouter(option.HeadBeatMsgID, option.Router)
+		checker.SetMaxMisses(option.MaxMisses)
+		checker.SetPongValidator(option.ValidatePong)
+		checker.SetNotAlivePolicy(option.NotAlivePolicy)
 	}
 
 	//添加心跳检测的路由
@@ -205,8 +376,10 @@ func (c *Client) StartHeartBeatWithOption(interval time.Duration, option *ziface
 }
 
 func (c *Client) Stop() {
-	zlog.Ins().InfoF("[STOP] Zinx Client LocalAddr: %s, RemoteAddr: %s\n", c.conn.LocalAddr(), c.conn.RemoteAddr())
-	c.conn.Stop()
+	if conn := c.Conn(); conn != nil {
+		zlog.Ins().InfoF("[STOP] Zinx Client LocalAddr: %s, RemoteAddr: %s\n", conn.LocalAddr(), conn.RemoteAddr())
+		conn.Stop()
+	}
 	c.exitChan <- struct{}{}
 	close(c.exitChan)
 	close(c.ErrChan)
@@ -217,9 +390,46 @@ func (c *Client) AddRouter(msgID uint32, router ziface.IRouter) {
 }
 
 func (c *Client) Conn() ziface.IConnection {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
 	return c.conn
 }
 
+func (c *Client) setConn(conn ziface.IConnection) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	c.conn = conn
+}
+
+// Call 向服务端发送请求并同步等待回复, 服务端路由使用request.Reply回复
+// 客户端与服务端需使用支持关联序号的封包方式与解码器(zpack.SeqDataPack, zdecoder.SeqTLVDecoder)
+func (c *Client) Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) {
+	conn := c.Conn()
+	if conn == nil {
+		return nil, ErrClientNotConnected
+	}
+	start := time.Now()
+	reply, err := conn.Call(ctx, msgID, data)
+	c.observeRequest(msgID, time.Since(start), err)
+	return reply, err
+}
+
+// Request 向服务端发送请求并同步等待回复, ctx没有设置期限时最多等待SetRequestTimeout设置的时间
+// 与Call相同, 客户端与服务端需使用支持关联序号的封包方式与解码器
+func (c *Client) Request(ctx context.Context, msgID uint32, data []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok && c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+	return c.Call(ctx, msgID, data)
+}
+
+// SetRequestTimeout 设置Request默认的等待回复时间, 0表示一直等待直到ctx取消或连接关闭
+func (c *Client) SetRequestTimeout(timeout time.Duration) {
+	c.requestTimeout = timeout
+}
+
 // 设置该Client的连接创建时Hook函数
 func (c *Client) SetOnConnStart(hookFunc func(ziface.IConnection)) {
 	c.onConnStart = hookFunc
@@ -231,8 +441,28 @@ func (c *Client) SetOnConnStop(hookFunc func(ziface.IConnection)) {
 }
 
 // GetOnConnStart 得到该Server的连接创建时Hook函数
+// 启用会话恢复时，在用户的Hook函数之前先发送会话令牌
+// 设置了自动重连时，在用户的Hook函数之后执行登录回调与重连回调
 func (c *Client) GetOnConnStart() func(ziface.IConnection) {
-	return c.onConnStart
+	if !c.session && c.reconnect == nil {
+		return c.onConnStart
+	}
+
+	onConnStart := c.onConnStart
+	return func(conn ziface.IConnection) {
+		if c.session {
+			if err := conn.SendMsg(ziface.SessionMsgID, []byte(c.GetSessionToken())); err != nil {
+				zlog.Ins().ErrorF("send session token err: %v", err)
+			}
+		}
+		if onConnStart != nil {
+			onConnStart(conn)
+		}
+		if c.reconnect != nil {
+			// 连接开始读取数据之后执行, 登录回调可以同步等待服务端的回复
+			go c.afterConnect(conn)
+		}
+	}
 }
 
 // 得到该Server的连接断开时的Hook函数
@@ -258,12 +488,80 @@ func (c *Client) AddInterceptor(interceptor ziface.IInterceptor) {
 	c.msgHandler.AddInterceptor(interceptor)
 }
 
+// AddNamedInterceptor 添加带名称的拦截器，可在运行时通过名称删除或替换
+func (c *Client) AddNamedInterceptor(name string, interceptor ziface.IInterceptor) {
+	c.msgHandler.AddNamedInterceptor(name, interceptor)
+}
+
+// RemoveInterceptor 运行时删除指定名称的拦截器
+func (c *Client) RemoveInterceptor(name string) bool {
+	return c.msgHandler.RemoveInterceptor(name)
+}
+
+// ReplaceInterceptor 运行时替换指定名称的拦截器
+func (c *Client) ReplaceInterceptor(name string, interceptor ziface.IInterceptor) bool {
+	return c.msgHandler.ReplaceInterceptor(name, interceptor)
+}
+
+// InterceptorNames 按顺序返回全部拦截器的名称
+func (c *Client) InterceptorNames() []string {
+	return c.msgHandler.InterceptorNames()
+}
+
 func (c *Client) SetDecoder(decoder ziface.IDecoder) {
 	c.decoder = decoder
 }
+
+func (c *Client) GetDecoder() ziface.IDecoder {
+	return c.decoder
+}
+
 func (c *Client) GetLengthField() *ziface.LengthField {
 	if c.decoder != nil {
 		return c.decoder.GetLengthField()
 	}
 	return nil
 }
+
+// SetProtocolVersion 设置期望的协议版本号, 连接建立后先与服务端完成版本握手
+func (c *Client) SetProtocolVersion(version uint32) {
+	c.protocolVersion = version
+}
+
+func (c *Client) GetProtocolVersion() uint32 {
+	return c.protocolVersion
+}
+
+// EnableSession 启用会话恢复, token为之前的会话令牌, 为空表示新建会话
+// 需在Start之前调用
+func (c *Client) EnableSession(token string) {
+	c.SetSessionToken(token)
+
+	//添加接收会话令牌的路由
+	c.AddRouter(ziface.SessionMsgID, &clientSessionRouter{client: c})
+
+	c.session = true
+}
+
+// GetSessionToken 得到服务端分配的会话令牌, 断线重连时通过EnableSession或WithSessionClient传入
+func (c *Client) GetSessionToken() string {
+	c.sessionLock.RLock()
+	defer c.sessionLock.RUnlock()
+	return c.sessionToken
+}
+
+func (c *Client) SetSessionToken(token string) {
+	c.sessionLock.Lock()
+	defer c.sessionLock.Unlock()
+	c.sessionToken = token
+}
+
+// clientSessionRouter 客户端接收服务端回复的会话令牌
+type clientSessionRouter struct {
+	BaseRouter
+	client *Client
+}
+
+func (r *clientSessionRouter) Handle(request ziface.IRequest) {
+	r.client.SetSessionToken(string(request.GetData()))
+}
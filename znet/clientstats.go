@@ -0,0 +1,149 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// clientStats 客户端的统计, 通过atomic访问
+type clientStats struct {
+	requests        uint64
+	requestErrors   uint64
+	rttSum          int64
+	rttMax          int64
+	connectFailures uint64
+	sendErrors      uint64
+
+	// 已经断开的连接的收发统计合计
+	closed     ziface.ConnStats
+	accounted  ziface.IConnection
+	closedLock sync.Mutex
+}
+
+// SetOnConnectFailed 设置建立连接失败时的回调, 启用自动重连时每次重连失败都会调用
+func (c *Client) SetOnConnectFailed(f func(err error)) {
+	c.onConnectFailed = f
+}
+
+// SetOnSendError 设置通过客户端连接发送消息失败时的回调
+func (c *Client) SetOnSendError(f func(conn ziface.IConnection, msgID uint32, err error)) {
+	c.onSendError = f
+}
+
+// SetMetrics 设置指标接口, 在建立连接失败、重连、发送失败、请求完成以及读取消息时调用, 为nil时不调用
+// 需在Start之前调用
+func (c *Client) SetMetrics(metrics ziface.IMetrics) {
+	c.metrics = metrics
+	c.msgHandler.SetMetrics(metrics)
+}
+
+// Stats 客户端的统计快照, 收发统计包括重连之前的连接
+func (c *Client) Stats() ziface.ClientStats {
+	s := &c.stats
+	stats := ziface.ClientStats{
+		Requests:        atomic.LoadUint64(&s.requests),
+		RequestErrors:   atomic.LoadUint64(&s.requestErrors),
+		MaxRTT:          time.Duration(atomic.LoadInt64(&s.rttMax)),
+		Reconnects:      c.Reconnects(),
+		ConnectFailures: atomic.LoadUint64(&s.connectFailures),
+		SendErrors:      atomic.LoadUint64(&s.sendErrors),
+	}
+	if ok := stats.Requests - stats.RequestErrors; ok > 0 {
+		stats.AvgRTT = time.Duration(atomic.LoadInt64(&s.rttSum) / int64(ok))
+	}
+
+	s.closedLock.Lock()
+	defer s.closedLock.Unlock()
+	stats.BytesIn, stats.BytesOut = s.closed.BytesIn, s.closed.BytesOut
+	stats.MsgsIn, stats.MsgsOut = s.closed.MsgsIn, s.closed.MsgsOut
+	if conn := c.Conn(); conn != nil && conn != s.accounted {
+		stats.Connected = conn.Context().Err() == nil
+		current := conn.Stats()
+		stats.BytesIn += current.BytesIn
+		stats.BytesOut += current.BytesOut
+		stats.MsgsIn += current.MsgsIn
+		stats.MsgsOut += current.MsgsOut
+	}
+	return stats
+}
+
+// connClosed 连接断开后将连接的收发统计计入合计
+func (c *Client) connClosed(conn ziface.IConnection) {
+	current := conn.Stats()
+
+	s := &c.stats
+	s.closedLock.Lock()
+	defer s.closedLock.Unlock()
+	s.closed.BytesIn += current.BytesIn
+	s.closed.BytesOut += current.BytesOut
+	s.closed.MsgsIn += current.MsgsIn
+	s.closed.MsgsOut += current.MsgsOut
+	s.accounted = conn
+}
+
+// connectFailed 建立连接失败
+func (c *Client) connectFailed(err error) {
+	atomic.AddUint64(&c.stats.connectFailures, 1)
+	if c.metrics != nil {
+		c.metrics.Counter(ziface.MetricClientConnectFailed, 1, nil)
+	}
+	if c.onConnectFailed != nil {
+		c.onConnectFailed(err)
+	}
+}
+
+// reconnected 开始重连
+func (c *Client) reconnected() {
+	atomic.AddUint64(&c.reconnects, 1)
+	if c.metrics != nil {
+		c.metrics.Counter(ziface.MetricClientReconnect, 1, nil)
+	}
+}
+
+// sendError 客户端连接发送消息失败, 发出消息的拦截器丢弃的消息不计入
+func (c *Client) sendError(conn ziface.IConnection, msgID uint32, err error) {
+	atomic.AddUint64(&c.stats.sendErrors, 1)
+	if c.metrics != nil {
+		c.metrics.Counter(ziface.MetricClientSendError, 1, msgTags(msgID))
+	}
+	if c.onSendError != nil {
+		c.onSendError(conn, msgID, err)
+	}
+}
+
+// observeRequest 记录Call/Request的结果与往返时间
+func (c *Client) observeRequest(msgID uint32, rtt time.Duration, err error) {
+	s := &c.stats
+	atomic.AddUint64(&s.requests, 1)
+	result := "ok"
+	if err != nil {
+		atomic.AddUint64(&s.requestErrors, 1)
+		result = "error"
+	} else {
+		atomic.AddInt64(&s.rttSum, int64(rtt))
+		for {
+			max := atomic.LoadInt64(&s.rttMax)
+			if int64(rtt) <= max || atomic.CompareAndSwapInt64(&s.rttMax, max, int64(rtt)) {
+				break
+			}
+		}
+	}
+
+	if c.metrics == nil {
+		return
+	}
+	if err == nil {
+		c.metrics.Histogram(ziface.MetricClientRTT, rtt.Seconds(), msgTags(msgID))
+	}
+	tags := msgTags(msgID)
+	tags["result"] = result
+	c.metrics.Counter(ziface.MetricClientRequest, 1, tags)
+}
+
+// sendErrorReporter 报告连接发送消息失败的客户端
+type sendErrorReporter interface {
+	sendError(conn ziface.IConnection, msgID uint32, err error)
+}
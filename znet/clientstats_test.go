@@ -0,0 +1,93 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientStats(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewSeqDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28979
+	s.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	s.AddRouter(2, &replyRouter{})
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	metrics := newRecordMetrics()
+	sendErrs := make(chan uint32, 1)
+	client := NewClient("127.0.0.1", 28979, WithPacketClient(zpack.NewSeqDataPack()), WithMetricsClient(metrics))
+	client.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	client.SetOnSendError(func(conn ziface.IConnection, msgID uint32, err error) {
+		sendErrs <- msgID
+	})
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	data, err := client.Call(context.Background(), 2, []byte("hi"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("re:hi"), data)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Call(ctx, 3, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	stats := client.Stats()
+	assert.True(t, stats.Connected)
+	assert.Equal(t, uint64(2), stats.Requests)
+	assert.Equal(t, uint64(1), stats.RequestErrors)
+	assert.True(t, stats.AvgRTT > 0 && stats.AvgRTT <= stats.MaxRTT)
+	assert.Equal(t, uint64(2), stats.MsgsOut)
+	assert.Equal(t, uint64(1), stats.MsgsIn)
+	assert.Equal(t, float64(1), metrics.get(ziface.MetricClientRTT+",msgID=2"))
+	assert.Equal(t, float64(1), metrics.get(ziface.MetricClientRequest+",msgID=3,result=error"))
+
+	// 连接断开后收发统计仍然计入, 发送失败时回调
+	s.Stop()
+	time.Sleep(200 * time.Millisecond)
+	assert.NotNil(t, client.Conn().SendMsg(5, nil))
+	select {
+	case msgID := <-sendErrs:
+		assert.Equal(t, uint32(5), msgID)
+	case <-time.After(time.Second):
+		t.Fatal("send error callback is not called")
+	}
+	stats = client.Stats()
+	assert.False(t, stats.Connected)
+	assert.Equal(t, uint64(2), stats.MsgsOut)
+	assert.Equal(t, uint64(1), stats.SendErrors)
+	assert.Equal(t, float64(1), metrics.get(ziface.MetricClientSendError+",msgID=5"))
+}
+
+func TestClientConnectFailed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	var failures int32
+	client := NewClient("127.0.0.1", port, WithReconnectClient(ziface.ReconnectConfig{
+		MaxAttempts: 2,
+		MinDelay:    10 * time.Millisecond,
+	}))
+	client.SetOnConnectFailed(func(err error) {
+		atomic.AddInt32(&failures, 1)
+	})
+	client.Start()
+	defer client.Stop()
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&failures))
+	stats := client.Stats()
+	assert.Equal(t, uint64(3), stats.ConnectFailures)
+	assert.Equal(t, uint64(2), stats.Reconnects)
+}
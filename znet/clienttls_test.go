@@ -0,0 +1,102 @@
+package znet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCert 生成自签名证书, 写入dir/name.crt与dir/name.key
+func writeTestCert(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeTestCert(t, dir, "zinx.test")
+	clientCert, clientKey := writeTestCert(t, dir, "client")
+
+	// 要求客户端证书的TLS服务
+	cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	assert.Nil(t, err)
+	clientCAs, err := NewClientTLSConfig(clientCert, "", "", "")
+	assert.Nil(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs.RootCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+				time.Sleep(time.Second)
+			}()
+		}
+	}()
+
+	// 通过SNI名称验证服务端证书, 并发送客户端证书
+	config, err := NewClientTLSConfig(serverCert, clientCert, clientKey, "zinx.test")
+	assert.Nil(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	client := NewClient("127.0.0.1", p, WithTLSConfigClient(config)).(*Client)
+
+	conn, err := client.dial()
+	assert.Nil(t, err)
+	state := conn.GetConnection().(*tls.Conn).ConnectionState()
+	assert.True(t, state.HandshakeComplete)
+	assert.Equal(t, "zinx.test", state.ServerName)
+	_ = conn.GetConnection().Close()
+
+	// 服务端名称与证书不符时验证失败
+	config, err = NewClientTLSConfig(serverCert, clientCert, clientKey, "other.test")
+	assert.Nil(t, err)
+	client.SetTLSConfig(config)
+	_, err = client.dial()
+	assert.NotNil(t, err)
+
+	_, err = NewClientTLSConfig(filepath.Join(dir, "missing.crt"), "", "", "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,39 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.SetConcurrencyLimit(2, 2)
+	router := &blockRouter{block: make(chan struct{}), handled: make(chan uint32, 4)}
+	mh.AddRouter(2, router)
+
+	for i := 0; i < 2; i++ {
+		go mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	}
+	assert.Eventually(t, func() bool {
+		return len(mh.limits[2]) == 2
+	}, time.Second, time.Millisecond)
+
+	// 达到上限的请求被丢弃
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Equal(t, uint64(1), mh.WorkerStats().Limited)
+
+	close(router.block)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, uint32(2), <-router.handled)
+	}
+	assert.Eventually(t, func() bool {
+		return len(mh.limits[2]) == 0
+	}, time.Second, time.Millisecond)
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Equal(t, uint32(2), <-router.handled)
+	assert.Equal(t, uint64(1), mh.WorkerStats().Limited)
+}
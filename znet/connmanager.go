@@ -3,6 +3,7 @@ package znet
 import (
 	"errors"
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
 	"sync"
 
 	"github.com/aceld/zinx/ziface"
@@ -15,6 +16,10 @@ type ConnManager struct {
 	//只读的链接集合
 	connectionsReadOnly map[uint64]ziface.IConnection
 	connLock            sync.RWMutex
+	//连接标签 connID -> key -> value
+	connTags map[uint64]map[string]string
+	//标签索引 key -> value -> connID -> conn
+	tagIndex map[string]map[string]map[uint64]ziface.IConnection
 }
 
 //NewConnManager 创建一个链接管理
@@ -22,6 +27,8 @@ func NewConnManager() *ConnManager {
 	return &ConnManager{
 		connections:         make(map[uint64]ziface.IConnection),
 		connectionsReadOnly: make(map[uint64]ziface.IConnection),
+		connTags:            make(map[uint64]map[string]string),
+		tagIndex:            make(map[string]map[string]map[uint64]ziface.IConnection),
 	}
 }
 
@@ -42,6 +49,7 @@ func (connMgr *ConnManager) Remove(conn ziface.IConnection) {
 	connMgr.connLock.Lock()
 	delete(connMgr.connections, conn.GetConnID()) //删除连接信息
 	delete(connMgr.connectionsReadOnly, conn.GetConnID())
+	connMgr.removeTags(conn.GetConnID())
 	connMgr.connLock.Unlock()
 
 	zlog.Ins().InfoF("connection Remove ConnID=%d successfully: conn num = %d", conn.GetConnID(), connMgr.Len())
@@ -79,6 +87,7 @@ func (connMgr *ConnManager) ClearConn() {
 		conn.Stop()
 		delete(connMgr.connections, connID)
 		delete(connMgr.connectionsReadOnly, connID)
+		connMgr.removeTags(connID)
 	}
 	connMgr.connLock.Unlock()
 
@@ -105,3 +114,191 @@ func (connMgr *ConnManager) Range(cb func(uint64, ziface.IConnection, interface{
 
 	return err
 }
+
+// GetAll 获取全部连接的快照, 遍历时可以安全地增删连接
+func (connMgr *ConnManager) GetAll() []ziface.IConnection {
+	connMgr.connLock.RLock()
+	defer connMgr.connLock.RUnlock()
+
+	conns := make([]ziface.IConnection, 0, len(connMgr.connections))
+	for _, conn := range connMgr.connections {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Broadcast 向全部连接广播消息
+func (connMgr *ConnManager) Broadcast(msgID uint32, data []byte) error {
+	return connMgr.BroadcastFilter(msgID, data, nil)
+}
+
+// BroadcastFilter 向filter返回true的连接广播消息, filter为nil时广播给全部连接
+func (connMgr *ConnManager) BroadcastFilter(msgID uint32, data []byte, filter func(ziface.IConnection) bool) error {
+	connMgr.connLock.RLock()
+	conns := make([]ziface.IConnection, 0, len(connMgr.connections))
+	for _, conn := range connMgr.connections {
+		if filter == nil || filter(conn) {
+			conns = append(conns, conn)
+		}
+	}
+	connMgr.connLock.RUnlock()
+
+	return broadcast(conns, msgID, data)
+}
+
+// broadcast 向一组连接广播消息, 通过各连接的发送队列异步发送
+// 没有发出消息拦截器的连接按封包方式只封包一次, 共享封包后的数据;
+// 安装了拦截器的连接各自执行拦截器后封包, 拦截器可以按连接修改消息
+// 被拦截器丢弃或封包失败的消息不发送给对应的连接, 其余连接继续发送, 返回第一个封包错误
+func broadcast(conns []ziface.IConnection, msgID uint32, data []byte) (err error) {
+	// 连接可能使用不同的封包方式(如附加监听端口、协议版本协商), 分别缓存封包结果
+	packed := make(map[ziface.IDataPack][]byte)
+
+	for _, conn := range conns {
+		codec, ok := conn.(connCodec)
+		if !ok || codec.getPacket() == nil {
+			if err := conn.SendBuffMsg(msgID, data); err != nil {
+				zlog.Ins().ErrorF("Broadcast to ConnID = %d err: %v", conn.GetConnID(), err)
+			}
+			continue
+		}
+
+		out, packet := codec.getOutChain(), codec.getPacket()
+		buf := packed[packet]
+		if buf == nil || out.enabled() {
+			//拦截器可能修改消息, 每个连接使用独立的消息
+			var packErr error
+			buf, packErr = out.pack(conn, packet, zpack.NewMsgPackage(msgID, data))
+			if packErr != nil {
+				if packErr != ErrMsgDropped {
+					zlog.Ins().ErrorF("Broadcast pack msgID = %d to ConnID = %d err: %v", msgID, conn.GetConnID(), packErr)
+					if err == nil {
+						err = packErr
+					}
+				}
+				continue
+			}
+			if !out.enabled() {
+				packed[packet] = buf
+			}
+		}
+
+		if sendErr := conn.SendToQueue(buf); sendErr != nil {
+			zlog.Ins().ErrorF("Broadcast to ConnID = %d err: %v", conn.GetConnID(), sendErr)
+		}
+	}
+
+	return err
+}
+
+// SetTag 给连接设置标签，同一key只保留一个值
+func (connMgr *ConnManager) SetTag(conn ziface.IConnection, key string, value string) {
+	connMgr.connLock.Lock()
+	defer connMgr.connLock.Unlock()
+
+	connID := conn.GetConnID()
+	if _, ok := connMgr.connections[connID]; !ok {
+		return
+	}
+
+	connMgr.removeTag(connID, key)
+
+	if connMgr.connTags[connID] == nil {
+		connMgr.connTags[connID] = make(map[string]string)
+	}
+	connMgr.connTags[connID][key] = value
+
+	if connMgr.tagIndex[key] == nil {
+		connMgr.tagIndex[key] = make(map[string]map[uint64]ziface.IConnection)
+	}
+	if connMgr.tagIndex[key][value] == nil {
+		connMgr.tagIndex[key][value] = make(map[uint64]ziface.IConnection)
+	}
+	connMgr.tagIndex[key][value][connID] = conn
+}
+
+// RemoveTag 移除连接的标签
+func (connMgr *ConnManager) RemoveTag(conn ziface.IConnection, key string) {
+	connMgr.connLock.Lock()
+	defer connMgr.connLock.Unlock()
+
+	connMgr.removeTag(conn.GetConnID(), key)
+}
+
+// GetTags 获取连接的全部标签
+func (connMgr *ConnManager) GetTags(connID uint64) map[string]string {
+	connMgr.connLock.RLock()
+	defer connMgr.connLock.RUnlock()
+
+	tags := make(map[string]string, len(connMgr.connTags[connID]))
+	for key, value := range connMgr.connTags[connID] {
+		tags[key] = value
+	}
+	return tags
+}
+
+// GetByTags 获取同时拥有全部标签的连接, 从命中连接最少的标签开始求交集
+func (connMgr *ConnManager) GetByTags(tags map[string]string) []ziface.IConnection {
+	connMgr.connLock.RLock()
+	defer connMgr.connLock.RUnlock()
+
+	var smallest map[uint64]ziface.IConnection
+	for key, value := range tags {
+		conns := connMgr.tagIndex[key][value]
+		if len(conns) == 0 {
+			return nil
+		}
+		if smallest == nil || len(conns) < len(smallest) {
+			smallest = conns
+		}
+	}
+
+	result := make([]ziface.IConnection, 0, len(smallest))
+	for connID, conn := range smallest {
+		matched := true
+		for key, value := range tags {
+			if v, ok := connMgr.connTags[connID][key]; !ok || v != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, conn)
+		}
+	}
+
+	return result
+}
+
+// BroadcastByTags 向同时拥有全部标签的连接广播消息
+func (connMgr *ConnManager) BroadcastByTags(msgID uint32, data []byte, tags map[string]string) error {
+	return broadcast(connMgr.GetByTags(tags), msgID, data)
+}
+
+// removeTag 移除连接的一个标签及其索引, 调用方需持有写锁
+func (connMgr *ConnManager) removeTag(connID uint64, key string) {
+	value, ok := connMgr.connTags[connID][key]
+	if !ok {
+		return
+	}
+
+	delete(connMgr.connTags[connID], key)
+	if len(connMgr.connTags[connID]) == 0 {
+		delete(connMgr.connTags, connID)
+	}
+
+	delete(connMgr.tagIndex[key][value], connID)
+	if len(connMgr.tagIndex[key][value]) == 0 {
+		delete(connMgr.tagIndex[key], value)
+	}
+	if len(connMgr.tagIndex[key]) == 0 {
+		delete(connMgr.tagIndex, key)
+	}
+}
+
+// removeTags 移除连接的全部标签, 调用方需持有写锁
+func (connMgr *ConnManager) removeTags(connID uint64) {
+	for key := range connMgr.connTags[connID] {
+		connMgr.removeTag(connID, key)
+	}
+}
@@ -0,0 +1,139 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnManagerBroadcast(t *testing.T) {
+	connMgr := NewConnManager()
+	packet := zpack.NewDataPack()
+
+	peers := make(map[uint64]net.Conn)
+	for connID := uint64(1); connID <= 3; connID++ {
+		local, remote := net.Pipe()
+		conn := &Connection{conn: local, connID: connID, packet: packet}
+		conn.ctx, conn.cancel = context.WithCancel(context.Background())
+		defer conn.cancel()
+		connMgr.Add(conn)
+		peers[connID] = remote
+	}
+
+	err := connMgr.BroadcastFilter(1, []byte("hi"), func(conn ziface.IConnection) bool {
+		return conn.GetConnID() != 2
+	})
+	assert.Nil(t, err)
+
+	expected, _ := packet.Pack(zpack.NewMsgPackage(1, []byte("hi")))
+	for _, connID := range []uint64{1, 3} {
+		buf := make([]byte, len(expected))
+		_ = peers[connID].SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(peers[connID], buf)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, buf)
+	}
+
+	// 被过滤的连接收不到消息
+	_ = peers[2].SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = peers[2].Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
+
+// connInterceptor 在发出的消息内容前添加连接ID
+type connInterceptor struct{}
+
+func (connInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	msg := chain.Request().(ziface.IOutMessage)
+	msg.SetData(append([]byte(fmt.Sprintf("%d:", msg.GetConnection().GetConnID())), msg.GetData()...))
+	msg.SetDataLen(uint32(len(msg.GetData())))
+	return chain.Proceed(msg)
+}
+
+// failPack 封包总是失败
+type failPack struct {
+	ziface.IDataPack
+}
+
+func (failPack) Pack(msg ziface.IMessage) ([]byte, error) {
+	return nil, errors.New("pack failed")
+}
+
+func TestBroadcastOutInterceptor(t *testing.T) {
+	packet := zpack.NewDataPack()
+	prefixed := newOutChain()
+	prefixed.add("conn", connInterceptor{})
+	dropped := newOutChain()
+	dropped.add("drop", dropInterceptor(1))
+
+	// 连接1、2共用一个拦截器链, 连接3的拦截器丢弃消息, 连接4没有拦截器, 连接5封包失败
+	var conns []ziface.IConnection
+	peers := make(map[uint64]net.Conn)
+	for connID, out := range map[uint64]*outChain{1: prefixed, 2: prefixed, 3: dropped, 4: nil, 5: nil} {
+		local, remote := net.Pipe()
+		conn := &Connection{conn: local, connID: connID, packet: packet, out: out}
+		if connID == 5 {
+			conn.packet = failPack{packet}
+		}
+		conn.ctx, conn.cancel = context.WithCancel(context.Background())
+		defer conn.cancel()
+		conns = append(conns, conn)
+		peers[connID] = remote
+	}
+	// 封包失败的连接被跳过, 其余连接继续发送
+	assert.NotNil(t, broadcast(conns, 1, []byte("hi")))
+
+	// 安装了拦截器的连接各自封包
+	for connID, data := range map[uint64]string{1: "1:hi", 2: "2:hi", 4: "hi"} {
+		expected, _ := packet.Pack(zpack.NewMsgPackage(1, []byte(data)))
+		buf := make([]byte, len(expected))
+		_ = peers[connID].SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(peers[connID], buf)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, buf)
+	}
+
+	for _, connID := range []uint64{3, 5} {
+		_ = peers[connID].SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		_, err := peers[connID].Read(make([]byte, 1))
+		assert.NotNil(t, err)
+	}
+}
+
+func TestConnManagerTags(t *testing.T) {
+	connMgr := NewConnManager()
+	admin := &Connection{connID: 1}
+	player := &Connection{connID: 2}
+	other := &Connection{connID: 3}
+	connMgr.Add(admin)
+	connMgr.Add(player)
+	connMgr.Add(other)
+
+	connMgr.SetTag(admin, "zone", "5")
+	connMgr.SetTag(admin, "role", "admin")
+	connMgr.SetTag(player, "zone", "5")
+	connMgr.SetTag(other, "zone", "6")
+	connMgr.SetTag(other, "role", "admin")
+
+	conns := connMgr.GetByTags(map[string]string{"zone": "5", "role": "admin"})
+	assert.Equal(t, []ziface.IConnection{admin}, conns)
+	assert.Len(t, connMgr.GetByTags(map[string]string{"zone": "5"}), 2)
+
+	// 同一key只保留一个值
+	connMgr.SetTag(player, "zone", "6")
+	assert.Len(t, connMgr.GetByTags(map[string]string{"zone": "5"}), 1)
+	assert.Equal(t, map[string]string{"zone": "6"}, connMgr.GetTags(2))
+
+	// 连接删除后标签及索引一并删除
+	connMgr.Remove(other)
+	assert.Equal(t, []ziface.IConnection{player}, connMgr.GetByTags(map[string]string{"zone": "6"}))
+	assert.Empty(t, connMgr.GetTags(3))
+}
@@ -0,0 +1,104 @@
+package znet
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// connStats 连接的收发统计, 通过atomic访问, 为nil时不统计
+type connStats struct {
+	bytesIn      uint64
+	bytesOut     uint64
+	msgsIn       uint64
+	msgsOut      uint64
+	errors       uint64
+	lastActivity int64
+	connectedAt  time.Time
+}
+
+func newConnStats() *connStats {
+	return &connStats{connectedAt: time.Now()}
+}
+
+// read 记录读取的字节数
+func (s *connStats) read(n int) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.bytesIn, uint64(n))
+	atomic.StoreInt64(&s.lastActivity, time.Now().UnixNano())
+}
+
+// readMsg 记录读取的消息
+func (s *connStats) readMsg() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.msgsIn, 1)
+}
+
+// write 记录写出的一条消息
+func (s *connStats) write(n int) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.bytesOut, uint64(n))
+	atomic.AddUint64(&s.msgsOut, 1)
+	atomic.StoreInt64(&s.lastActivity, time.Now().UnixNano())
+}
+
+// error 记录读写或封包出错
+func (s *connStats) error() {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.errors, 1)
+}
+
+func (s *connStats) snapshot(conn ziface.IConnection) ziface.ConnStats {
+	stats := ziface.ConnStats{ConnID: conn.GetConnID()}
+	if addr := conn.RemoteAddr(); addr != nil {
+		stats.RemoteAddr = addr.String()
+	}
+	if s == nil {
+		return stats
+	}
+
+	stats.BytesIn = atomic.LoadUint64(&s.bytesIn)
+	stats.BytesOut = atomic.LoadUint64(&s.bytesOut)
+	stats.MsgsIn = atomic.LoadUint64(&s.msgsIn)
+	stats.MsgsOut = atomic.LoadUint64(&s.msgsOut)
+	stats.Errors = atomic.LoadUint64(&s.errors)
+	stats.ConnectedAt = s.connectedAt
+	stats.Duration = time.Since(s.connectedAt)
+	if last := atomic.LoadInt64(&s.lastActivity); last > 0 {
+		stats.LastActivity = time.Unix(0, last)
+	}
+	return stats
+}
+
+// Stats 连接管理中全部连接的统计快照, 按读取的字节数从多到少排列
+func (connMgr *ConnManager) Stats() ziface.ConnManagerStats {
+	return collectConnStats(connMgr.GetAll())
+}
+
+// collectConnStats 多个连接的统计快照与合计, 按读取的字节数从多到少排列
+func collectConnStats(conns []ziface.IConnection) ziface.ConnManagerStats {
+	var stats ziface.ConnManagerStats
+	for _, conn := range conns {
+		s := conn.Stats()
+		stats.Total.BytesIn += s.BytesIn
+		stats.Total.BytesOut += s.BytesOut
+		stats.Total.MsgsIn += s.MsgsIn
+		stats.Total.MsgsOut += s.MsgsOut
+		stats.Total.Errors += s.Errors
+		stats.Conns = append(stats.Conns, s)
+	}
+	sort.Slice(stats.Conns, func(i, j int) bool {
+		return stats.Conns[i].BytesIn > stats.Conns[j].BytesIn
+	})
+	return stats
+}
@@ -0,0 +1,55 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnStats(t *testing.T) {
+	connMgr := NewConnManager()
+	packet := zpack.NewDataPack()
+
+	var conns []*Connection
+	for connID := uint64(1); connID <= 2; connID++ {
+		local, remote := net.Pipe()
+		defer remote.Close()
+		go func() {
+			_, _ = io.Copy(io.Discard, remote)
+		}()
+		conn := &Connection{conn: local, connID: connID, packet: packet, stats: newConnStats()}
+		conn.ctx, conn.cancel = context.WithCancel(context.Background())
+		defer conn.cancel()
+		connMgr.Add(conn)
+		conns = append(conns, conn)
+	}
+
+	assert.Nil(t, conns[0].SendMsg(1, []byte("hello")))
+	assert.Nil(t, conns[0].SendMsg(1, []byte("hi")))
+	conns[1].stats.read(100)
+	conns[1].stats.readMsg()
+
+	stats := conns[0].Stats()
+	assert.Equal(t, uint64(1), stats.ConnID)
+	assert.Equal(t, uint64(2), stats.MsgsOut)
+	assert.Equal(t, uint64(8+5+8+2), stats.BytesOut)
+	assert.False(t, stats.LastActivity.IsZero())
+	assert.True(t, stats.Duration > 0)
+
+	// 关闭后写出失败计入错误次数
+	_ = conns[0].conn.Close()
+	assert.NotNil(t, conns[0].SendMsg(1, nil))
+	assert.Equal(t, uint64(1), conns[0].Stats().Errors)
+
+	mgrStats := connMgr.Stats()
+	assert.Len(t, mgrStats.Conns, 2)
+	assert.Equal(t, uint64(2), mgrStats.Conns[0].ConnID)
+	assert.Equal(t, uint64(100), mgrStats.Total.BytesIn)
+	assert.Equal(t, uint64(1), mgrStats.Total.MsgsIn)
+	assert.Equal(t, uint64(2), mgrStats.Total.MsgsOut)
+	assert.Equal(t, uint64(1), mgrStats.Total.Errors)
+}
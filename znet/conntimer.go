@@ -0,0 +1,107 @@
+package znet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/ztimer"
+)
+
+// timers 连接与分组的定时器共享的时间轮调度器, 第一次使用时创建, 精度由配置的TimerTick决定
+// 按CPU核数分片, 大量连接同时创建、取消定时器时不会争用同一个锁
+var (
+	timersOnce sync.Once
+	timers     *ztimer.ShardedTimerScheduler
+)
+
+func timerScheduler() *ztimer.ShardedTimerScheduler {
+	timersOnce.Do(func() {
+		timers = ztimer.NewAutoExecShardedTimerScheduler(0)
+	})
+	return timers
+}
+
+// connAfterFunc d之后执行fn, ctx(连接的ctx)结束时自动取消, 返回取消定时器的方法
+func connAfterFunc(ctx context.Context, d time.Duration, fn func()) func() {
+	return timerAt(ctx, time.Now().Add(d), fn)
+}
+
+// timerAt 在t执行fn, ctx(连接或分组的ctx)结束时自动取消, 返回取消定时器的方法
+func timerAt(ctx context.Context, t time.Time, fn func()) func() {
+	if ctx == nil {
+		return func() {}
+	}
+	df := ztimer.NewDelayFunc(func(v ...interface{}) { fn() }, nil)
+	handle, err := timerScheduler().CreateTimerAtContext(ctx, df, t.UnixNano())
+	return timerCancel(handle, err)
+}
+
+// connTicker 每隔d执行一次fn, 每次加上[0, jitter)的随机偏移, ctx结束时自动取消, 返回取消定时器的方法
+func connTicker(ctx context.Context, d, jitter time.Duration, fn func()) func() {
+	if ctx == nil {
+		return func() {}
+	}
+	handle, err := timerScheduler().RepeatContext(ctx, d, jitter, fn)
+	return timerCancel(handle, err)
+}
+
+func timerCancel(handle *ztimer.TimerHandle, err error) func() {
+	if err != nil {
+		// 连接已经关闭(分组已经删除)时不再创建定时器
+		if err != context.Canceled {
+			zlog.Ins().ErrorF("create timer err: %v", err)
+		}
+		return func() {}
+	}
+	return handle.Cancel
+}
+
+// AfterFunc d之后执行fn, 连接关闭时自动取消, 返回取消定时器的方法
+func (c *Connection) AfterFunc(d time.Duration, fn func()) func() {
+	return connAfterFunc(c.ctx, d, fn)
+}
+
+// Ticker 每隔d执行一次fn, 连接关闭时自动取消, 返回取消定时器的方法
+func (c *Connection) Ticker(d time.Duration, fn func()) func() {
+	return connTicker(c.ctx, d, 0, fn)
+}
+
+// TickerJitter 与Ticker相同, 每次执行加上[0, jitter)的随机偏移, 避免大量连接同时执行
+func (c *Connection) TickerJitter(d, jitter time.Duration, fn func()) func() {
+	return connTicker(c.ctx, d, jitter, fn)
+}
+
+// AfterFunc d之后执行fn, 连接关闭时自动取消, 返回取消定时器的方法
+func (c *WsConnection) AfterFunc(d time.Duration, fn func()) func() {
+	return connAfterFunc(c.ctx, d, fn)
+}
+
+// Ticker 每隔d执行一次fn, 连接关闭时自动取消, 返回取消定时器的方法
+func (c *WsConnection) Ticker(d time.Duration, fn func()) func() {
+	return connTicker(c.ctx, d, 0, fn)
+}
+
+// TickerJitter 与Ticker相同, 每次执行加上[0, jitter)的随机偏移, 避免大量连接同时执行
+func (c *WsConnection) TickerJitter(d, jitter time.Duration, fn func()) func() {
+	return connTicker(c.ctx, d, jitter, fn)
+}
+
+// SendMsgAfter delay之后发送消息, 用于倒计时、提醒等, 连接关闭时自动取消, 返回取消发送的方法
+func (c *Connection) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) func() {
+	return c.AfterFunc(delay, func() {
+		if err := c.SendMsg(msgID, data); err != nil {
+			zlog.Ins().ErrorF("SendMsgAfter ConnID = %d msgID = %d err: %v", c.connID, msgID, err)
+		}
+	})
+}
+
+// SendMsgAfter delay之后发送消息, 用于倒计时、提醒等, 连接关闭时自动取消, 返回取消发送的方法
+func (c *WsConnection) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) func() {
+	return c.AfterFunc(delay, func() {
+		if err := c.SendMsg(msgID, data); err != nil {
+			zlog.Ins().ErrorF("SendMsgAfter ConnID = %d msgID = %d err: %v", c.connID, msgID, err)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnTimers(t *testing.T) {
+	c := &Connection{}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	var fired, ticks, afterClose int32
+	c.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+	cancel := c.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+	cancel()
+	c.Ticker(time.Second, func() { atomic.AddInt32(&ticks, 1) })
+	c.AfterFunc(3*time.Second, func() { atomic.AddInt32(&afterClose, 1) })
+
+	time.Sleep(2500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&ticks), int32(2))
+
+	// 连接关闭后全部定时器自动取消
+	c.cancel()
+	time.Sleep(100 * time.Millisecond)
+	n := atomic.LoadInt32(&ticks)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&ticks))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&afterClose))
+
+	// 关闭之后创建的定时器不执行
+	c.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&afterClose, 1) })()
+}
+
+func TestSendMsgAfter(t *testing.T) {
+	packet := zpack.NewDataPack()
+	local, remote := net.Pipe()
+	defer remote.Close()
+	c := &Connection{conn: local, connID: 1, packet: packet}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+
+	read := func(timeout time.Duration) ([]byte, error) {
+		_ = remote.SetReadDeadline(time.Now().Add(timeout))
+		buf := make([]byte, 64)
+		n, err := remote.Read(buf)
+		return buf[:n], err
+	}
+
+	c.SendMsgAfter(10*time.Millisecond, 1, []byte("countdown"))
+	c.SendMsgAfter(10*time.Millisecond, 2, []byte("canceled"))()
+	expected, _ := packet.Pack(zpack.NewMsgPackage(1, []byte("countdown")))
+	data, err := read(2 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, data)
+
+	// 定时广播发送给触发时的成员, 分组删除时取消
+	mgr := NewGroupManager()
+	mgr.Join("room", c)
+	room, _ := mgr.Get("room")
+	room.SendMsgAt(time.Now().Add(10*time.Millisecond), 3, []byte("all"))
+	expected, _ = packet.Pack(zpack.NewMsgPackage(3, []byte("all")))
+	data, err = read(2 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, data)
+
+	room2 := mgr.GetOrCreate("room2")
+	room2.SendMsgAfter(time.Second, 5, []byte("removed"))
+	mgr.Remove("room2")
+	assert.Equal(t, context.Canceled, room2.(*Group).ctx.Err())
+	room2.Join(c)
+	_, err = read(1500 * time.Millisecond)
+	assert.NotNil(t, err)
+}
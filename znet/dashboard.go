@@ -0,0 +1,23 @@
+package znet
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// serveDashboard 管理控制台页面, 页面本身不包含数据, 输入访问令牌后通过管理接口获取数据
+func serveDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(dashboardHTML)
+}
+
+// SetAdminDashboard 设置是否在管理HTTP服务中提供Web控制台(/admin/ui), 需在Start之前调用
+// 控制台展示连接、路由吞吐量与处理时间、Worker工作池状态, 并可以断开连接、封禁IP与排空连接
+func (s *Server) SetAdminDashboard(enable bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.adminDashboard = enable
+}
@@ -0,0 +1,49 @@
+package znet
+
+import (
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// DeadLetterQueue 保存最近的死信以便检查或重试, 超过容量时丢弃最早的死信
+// Push可以直接作为DeadLetterConfig.Handler使用
+type DeadLetterQueue struct {
+	letters []ziface.DeadLetter
+	size    int
+	lock    sync.Mutex
+}
+
+// NewDeadLetterQueue 创建死信队列, size为最多保存的死信数量
+func NewDeadLetterQueue(size int) *DeadLetterQueue {
+	if size <= 0 {
+		size = 1
+	}
+	return &DeadLetterQueue{size: size}
+}
+
+func (q *DeadLetterQueue) Push(letter ziface.DeadLetter) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.letters) >= q.size {
+		q.letters = q.letters[1:]
+	}
+	q.letters = append(q.letters, letter)
+}
+
+// Drain 取出并清空队列中的全部死信
+func (q *DeadLetterQueue) Drain() []ziface.DeadLetter {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	letters := q.letters
+	q.letters = nil
+	return letters
+}
+
+func (q *DeadLetterQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.letters)
+}
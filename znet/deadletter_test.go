@@ -0,0 +1,61 @@
+package znet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type failRouter struct {
+	BaseRouter
+	calls int
+}
+
+func (r *failRouter) Handle(request ziface.IRequest) {
+	r.calls++
+	if r.calls < 3 {
+		request.SetError(errors.New("fail"))
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	queue := NewDeadLetterQueue(10)
+	mh := NewMsgHandle()
+	mh.SetRecoverHandler(func(conn ziface.IConnection, request ziface.IRequest, err interface{}, stack []byte) {})
+	mh.SetDeadLetter(ziface.DeadLetterConfig{MaxAttempts: 2, Handler: queue.Push})
+	mh.AddRouter(1, &panicRouter{})
+	router := &failRouter{}
+	mh.AddRouter(2, router)
+
+	// panic的消息重试后进入死信
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, []byte("a"))))
+	// 第二次处理仍然失败
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, []byte("b"))))
+	// 路由已恢复, 一次处理成功
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, []byte("c"))))
+
+	letters := queue.Drain()
+	assert.Len(t, letters, 2)
+	assert.Equal(t, uint32(1), letters[0].MsgID)
+	assert.Equal(t, []byte("a"), letters[0].Data)
+	assert.Equal(t, 2, letters[0].Attempts)
+	assert.Contains(t, letters[0].Err.Error(), "boom")
+	assert.Equal(t, uint32(2), letters[1].MsgID)
+	assert.EqualError(t, letters[1].Err, "fail")
+	assert.Equal(t, 3, router.calls)
+	assert.Equal(t, uint64(2), mh.WorkerStats().DeadLetters)
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	queue := NewDeadLetterQueue(2)
+	for i := uint32(1); i <= 3; i++ {
+		queue.Push(ziface.DeadLetter{MsgID: i})
+	}
+	letters := queue.Drain()
+	assert.Len(t, letters, 2)
+	assert.Equal(t, uint32(2), letters[0].MsgID)
+}
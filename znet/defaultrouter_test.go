@@ -0,0 +1,23 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRouter(t *testing.T) {
+	var calls []string
+	mh := NewMsgHandle()
+	mh.Use(recordMiddleware(&calls, "global"))
+	mh.AddRouter(1, &recordRouter{calls: &calls})
+
+	// 未设置默认路由时直接丢弃
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Empty(t, calls)
+
+	mh.SetDefaultRouter(&recordRouter{calls: &calls})
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, []byte("raw"))))
+	assert.Equal(t, []string{"global before", "pre", "handle", "post", "global after"}, calls)
+}
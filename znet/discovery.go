@@ -0,0 +1,148 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultRegistryTTL 注册到注册中心的默认租约时间
+const DefaultRegistryTTL = 10 * time.Second
+
+// SetRegistry 设置注册中心, Start时注册服务实例并按TTL/3续约、更新负载(当前连接数), Stop、Drain或Shutdown时注销
+// 需在Start之前调用
+func (s *Server) SetRegistry(config ziface.RegistryConfig) {
+	if config.TTL <= 0 {
+		config.TTL = DefaultRegistryTTL
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.registryConfig = config
+}
+
+// setConfRegistry 按照配置创建注册中心
+func (s *Server) setConfRegistry(config *zconf.Config) {
+	registry, err := zdiscovery.NewRegistry(config.Registry, config.RegistryAddrs, config.RegistryNamespace, config.RegistryToken)
+	if err != nil {
+		zlog.Ins().ErrorF("registry err: %v", err)
+		return
+	}
+	s.SetRegistry(ziface.RegistryConfig{
+		Registry: registry,
+		Name:     config.ServiceName,
+		Addr:     config.ServiceAddr,
+		TTL:      time.Duration(config.RegistryTTL) * time.Second,
+	})
+}
+
+// serviceInstance 注册的服务实例, 负载为当前连接数
+func (s *Server) serviceInstance(config ziface.RegistryConfig) ziface.ServiceInstance {
+	instance := ziface.ServiceInstance{
+		ID:       config.ID,
+		Name:     config.Name,
+		Addr:     config.Addr,
+		Load:     s.ConnMgr.Len(),
+		Metadata: config.Metadata,
+	}
+	if instance.Name == "" {
+		instance.Name = s.Name
+	}
+	if instance.Addr == "" {
+		instance.Addr = net.JoinHostPort(s.IP, strconv.Itoa(s.Port))
+	}
+	if instance.ID == "" {
+		instance.ID = instance.Name + "-" + instance.Addr
+	}
+
+	// 注册管理HTTP服务的地址
+	s.lock.RLock()
+	adminAddr, adminToken := s.adminAddr, s.adminToken
+	s.lock.RUnlock()
+	if adminAddr != "" && adminToken != "" {
+		metadata := make(map[string]string, len(config.Metadata)+1)
+		for key, value := range config.Metadata {
+			metadata[key] = value
+		}
+		metadata[zdiscovery.MetaAdminAddr] = adminAddr
+		instance.Metadata = metadata
+	}
+	return instance
+}
+
+// startRegistry 注册服务实例并开始续约
+func (s *Server) startRegistry() {
+	s.lock.Lock()
+	config := s.registryConfig
+	if config.Registry == nil || s.registryExit != nil {
+		s.lock.Unlock()
+		return
+	}
+	exitChan := make(chan struct{})
+	s.registryExit = exitChan
+	s.lock.Unlock()
+
+	go func() {
+		instance := s.serviceInstance(config)
+		zlog.Ins().InfoF("[START] register %s(%s) at %s", instance.Name, instance.ID, instance.Addr)
+		registered := s.register(config, instance)
+
+		ticker := time.NewTicker(config.TTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				instance = s.serviceInstance(config)
+				if registered {
+					ctx, cancel := context.WithTimeout(context.Background(), config.TTL/3)
+					err := config.Registry.KeepAlive(ctx, instance)
+					cancel()
+					if err == nil {
+						continue
+					}
+					zlog.Ins().ErrorF("registry keepalive %s err: %v", instance.ID, err)
+				}
+				// 续约失败(如租约已经过期)时重新注册
+				registered = s.register(config, instance)
+			case <-exitChan:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Server) register(config ziface.RegistryConfig, instance ziface.ServiceInstance) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), config.TTL/3)
+	defer cancel()
+	if err := config.Registry.Register(ctx, instance, config.TTL); err != nil {
+		zlog.Ins().ErrorF("registry register %s err: %v", instance.ID, err)
+		return false
+	}
+	return true
+}
+
+// stopRegistry 停止续约并注销服务实例
+func (s *Server) stopRegistry() {
+	s.lock.Lock()
+	config, exitChan := s.registryConfig, s.registryExit
+	s.registryExit = nil
+	s.lock.Unlock()
+
+	if exitChan == nil {
+		return
+	}
+	close(exitChan)
+
+	instance := s.serviceInstance(config)
+	ctx, cancel := context.WithTimeout(context.Background(), config.TTL/3)
+	defer cancel()
+	if err := config.Registry.Deregister(ctx, instance); err != nil {
+		zlog.Ins().ErrorF("registry deregister %s err: %v", instance.ID, err)
+	}
+}
@@ -0,0 +1,127 @@
+package znet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zdiscovery"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordRegistry 记录注册、续约与注销的注册中心
+type recordRegistry struct {
+	lock       sync.Mutex
+	instances  map[string]ziface.ServiceInstance
+	keepAlives int
+}
+
+func (r *recordRegistry) Register(ctx context.Context, instance ziface.ServiceInstance, ttl time.Duration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.instances[instance.ID] = instance
+	return nil
+}
+
+func (r *recordRegistry) KeepAlive(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.instances[instance.ID] = instance
+	r.keepAlives++
+	return nil
+}
+
+func (r *recordRegistry) Deregister(ctx context.Context, instance ziface.ServiceInstance) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.instances, instance.ID)
+	return nil
+}
+
+func (r *recordRegistry) Discover(ctx context.Context, name string) ([]ziface.ServiceInstance, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var instances []ziface.ServiceInstance
+	for _, instance := range r.instances {
+		if instance.Name == name {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func TestServerRegistry(t *testing.T) {
+	registry := &recordRegistry{instances: make(map[string]ziface.ServiceInstance)}
+	s := NewServer(WithRegistry(ziface.RegistryConfig{
+		Registry: registry,
+		Name:     "game",
+		Metadata: map[string]string{"version": "1"},
+		TTL:      150 * time.Millisecond,
+	})).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28978
+	s.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	instances, _ := registry.Discover(context.Background(), "game")
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, "game-127.0.0.1:28978", instances[0].ID)
+	assert.Equal(t, "127.0.0.1:28978", instances[0].Addr)
+	assert.Equal(t, "1", instances[0].Metadata["version"])
+	registry.lock.Lock()
+	assert.True(t, registry.keepAlives >= 1)
+	registry.lock.Unlock()
+
+	s.Stop()
+	instances, _ = registry.Discover(context.Background(), "game")
+	assert.Equal(t, 0, len(instances))
+}
+
+func TestServerShutdownRegistry(t *testing.T) {
+	registry := &recordRegistry{instances: make(map[string]ziface.ServiceInstance)}
+	s := NewServer(WithRegistry(ziface.RegistryConfig{
+		Registry: registry,
+		Name:     "game",
+		TTL:      150 * time.Millisecond,
+	})).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28961
+	s.Start()
+	assert.Eventually(t, func() bool {
+		instances, _ := registry.Discover(context.Background(), "game")
+		return len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// 平滑停止时同样注销服务实例
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, s.Shutdown(ctx))
+	instances, _ := registry.Discover(context.Background(), "game")
+	assert.Equal(t, 0, len(instances))
+}
+
+func TestServerConfRegistry(t *testing.T) {
+	s := NewServer().(*Server)
+	s.setConfRegistry(&zconf.Config{
+		Registry:      zdiscovery.KindConsul,
+		RegistryAddrs: []string{"http://127.0.0.1:8500"},
+		RegistryTTL:   30,
+		ServiceName:   "gate",
+		ServiceAddr:   "10.0.0.1:8999",
+	})
+	assert.IsType(t, &zdiscovery.ConsulRegistry{}, s.registryConfig.Registry)
+	assert.Equal(t, 30*time.Second, s.registryConfig.TTL)
+	assert.Equal(t, "gate-10.0.0.1:8999", s.serviceInstance(s.registryConfig).ID)
+
+	// 设置了管理HTTP服务时注册其地址
+	s.adminAddr, s.adminToken = ":8080", "secret"
+	assert.Equal(t, ":8080", s.serviceInstance(s.registryConfig).Metadata[zdiscovery.MetaAdminAddr])
+
+	// 未知的注册中心类型不注册
+	s = NewServer().(*Server)
+	s.setConfRegistry(&zconf.Config{Registry: "zookeeper"})
+	assert.Nil(t, s.registryConfig.Registry)
+}
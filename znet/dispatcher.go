@@ -0,0 +1,65 @@
+package znet
+
+import (
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// NewDispatcher 根据名称创建内置的Worker选择策略, 未知的名称使用DispatchAffinity
+func NewDispatcher(mode string) ziface.IDispatcher {
+	switch mode {
+	case ziface.DispatchHash:
+		return &HashDispatcher{}
+	case ziface.DispatchRoundRobin:
+		return &RoundRobinDispatcher{}
+	case ziface.DispatchLeastLoaded:
+		return &LeastLoadedDispatcher{}
+	}
+	return &AffinityDispatcher{}
+}
+
+// AffinityDispatcher 按连接ID选择Worker, 同一连接的请求按顺序处理
+type AffinityDispatcher struct{}
+
+func (d *AffinityDispatcher) Dispatch(request ziface.IRequest, pool ziface.IWorkerPool) uint64 {
+	return request.GetConnection().GetConnID()
+}
+
+// HashDispatcher 按连接ID与消息ID选择Worker, 同一连接的不同消息可以由不同的Worker同时处理
+type HashDispatcher struct{}
+
+func (d *HashDispatcher) Dispatch(request ziface.IRequest, pool ziface.IWorkerPool) uint64 {
+	return request.GetConnection().GetConnID()*31 + uint64(request.GetMsgID())
+}
+
+// RoundRobinDispatcher 轮流选择Worker, 适合请求之间没有顺序要求、追求吞吐量的场景
+type RoundRobinDispatcher struct {
+	next uint64
+}
+
+func (d *RoundRobinDispatcher) Dispatch(request ziface.IRequest, pool ziface.IWorkerPool) uint64 {
+	return atomic.AddUint64(&d.next, 1) - 1
+}
+
+// LeastLoadedDispatcher 选择任务队列最短的Worker, 队列长度相同时轮流选择
+type LeastLoadedDispatcher struct {
+	next uint64
+}
+
+func (d *LeastLoadedDispatcher) Dispatch(request ziface.IRequest, pool ziface.IWorkerPool) uint64 {
+	size := uint64(pool.Size())
+	if size == 0 {
+		return 0
+	}
+	start := atomic.AddUint64(&d.next, 1) - 1
+	best, bestLen := start%size, -1
+	for i := uint64(0); i < size; i++ {
+		workerID := (start + i) % size
+		length, _ := pool.QueueLen(uint32(workerID))
+		if bestLen < 0 || length < bestLen {
+			best, bestLen = workerID, length
+		}
+	}
+	return best
+}
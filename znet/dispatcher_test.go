@@ -0,0 +1,30 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	pool := NewWorkerPool(3, 8)
+	request := NewRequest(&Connection{connID: 5}, zpack.NewMsgPackage(2, nil))
+
+	assert.Equal(t, uint64(5), NewDispatcher("").Dispatch(request, pool))
+	assert.Equal(t, uint64(5*31+2), NewDispatcher(ziface.DispatchHash).Dispatch(request, pool))
+
+	rr := NewDispatcher(ziface.DispatchRoundRobin)
+	for i := uint64(0); i < 4; i++ {
+		assert.Equal(t, i, rr.Dispatch(request, pool))
+	}
+
+	// 未启动的工作池中Worker 0与Worker 1的队列有任务
+	pool.Submit(0, func() {})
+	pool.Submit(1, func() {})
+	ll := NewDispatcher(ziface.DispatchLeastLoaded)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint64(2), ll.Dispatch(request, pool))
+	}
+}
@@ -0,0 +1,105 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowRouter struct {
+	BaseRouter
+	started chan struct{}
+}
+
+func (r *slowRouter) Handle(request ziface.IRequest) {
+	r.started <- struct{}{}
+	time.Sleep(100 * time.Millisecond)
+	_ = request.GetConnection().SendBuffMsg(request.GetMsgID(), request.GetData())
+}
+
+func TestServerDrain(t *testing.T) {
+	s := NewServer(WithDrainMsg(2, []byte("bye"))).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28999
+	router := &slowRouter{started: make(chan struct{}, 1)}
+	s.AddRouter(1, router)
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:28999")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	dp := zpack.NewDataPack()
+	data, _ := dp.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	_, _ = conn.Write(data)
+	<-router.started
+
+	// 排空期间等待正在处理的请求完成
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.Nil(t, s.Drain(ctx))
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	msgs := make(map[uint32]string)
+	for i := 0; i < 2; i++ {
+		head := make([]byte, dp.GetHeadLen())
+		_, err = io.ReadFull(conn, head)
+		assert.Nil(t, err)
+		msg, _ := dp.Unpack(head)
+		body := make([]byte, msg.GetDataLen())
+		_, err = io.ReadFull(conn, body)
+		assert.Nil(t, err)
+		msgs[msg.GetMsgID()] = string(body)
+	}
+	assert.Equal(t, map[uint32]string{1: "ping", 2: "bye"}, msgs)
+
+	// 不再接受新的连接
+	_, err = net.DialTimeout("tcp", "127.0.0.1:28999", 100*time.Millisecond)
+	assert.NotNil(t, err)
+}
+
+func TestServerShutdown(t *testing.T) {
+	s := NewServer().(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28998
+
+	handled := make(chan struct{}, 1)
+	router := &slowRouter{started: make(chan struct{}, 1)}
+	s.AddRouter(1, router)
+	s.SetOnConnStop(func(conn ziface.IConnection) {
+		handled <- struct{}{}
+	})
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:28998")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	dp := zpack.NewDataPack()
+	data, _ := dp.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	_, _ = conn.Write(data)
+	<-router.started
+
+	// 截止时间早于请求处理完成
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+
+	// 全部连接的OnConnStop执行完成后返回
+	assert.Nil(t, s.Shutdown(context.Background()))
+	assert.Equal(t, 0, s.GetConnMgr().Len())
+	assert.Equal(t, int64(0), s.GetMsgHandler().InFlight())
+	select {
+	case <-handled:
+	default:
+		t.Fatal("OnConnStop not called")
+	}
+}
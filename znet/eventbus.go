@@ -0,0 +1,90 @@
+package znet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+type eventSub struct {
+	id      uint64
+	handler ziface.EventHandler
+	// 订阅的事件类型, nil表示全部
+	types map[ziface.EventType]struct{}
+}
+
+// EventBus 事件总线, 订阅者在发布事件的Goroutine中按订阅顺序依次调用
+type EventBus struct {
+	subs  []*eventSub
+	subID uint64
+	lock  sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (bus *EventBus) Subscribe(handler ziface.EventHandler, types ...ziface.EventType) uint64 {
+	sub := &eventSub{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[ziface.EventType]struct{}, len(types))
+		for _, t := range types {
+			sub.types[t] = struct{}{}
+		}
+	}
+
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	bus.subID++
+	sub.id = bus.subID
+	bus.subs = append(bus.subs, sub)
+	return sub.id
+}
+
+func (bus *EventBus) Unsubscribe(subID uint64) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	for i, sub := range bus.subs {
+		if sub.id == subID {
+			bus.subs = append(bus.subs[:i:i], bus.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (bus *EventBus) Publish(event ziface.Event) {
+	bus.lock.RLock()
+	if len(bus.subs) == 0 {
+		bus.lock.RUnlock()
+		return
+	}
+	handlers := make([]ziface.EventHandler, 0, len(bus.subs))
+	for _, sub := range bus.subs {
+		if sub.types != nil {
+			if _, ok := sub.types[event.Type]; !ok {
+				continue
+			}
+		}
+		handlers = append(handlers, sub.handler)
+	}
+	bus.lock.RUnlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, handler := range handlers {
+		bus.callHandler(handler, event)
+	}
+}
+
+func (bus *EventBus) callHandler(handler ziface.EventHandler, event ziface.Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("event %s handler panic: %v", event.Type, err)
+		}
+	}()
+	handler(event)
+}
@@ -0,0 +1,64 @@
+package znet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+
+	var all, closed []ziface.EventType
+	bus.Subscribe(func(event ziface.Event) {
+		all = append(all, event.Type)
+	})
+	subID := bus.Subscribe(func(event ziface.Event) {
+		closed = append(closed, event.Type)
+	}, ziface.EventConnClosed)
+	bus.Subscribe(func(event ziface.Event) {
+		panic("handler panic")
+	})
+
+	bus.Publish(ziface.Event{Type: ziface.EventConnOpened})
+	bus.Publish(ziface.Event{Type: ziface.EventConnClosed})
+	bus.Unsubscribe(subID)
+	bus.Publish(ziface.Event{Type: ziface.EventConnClosed})
+
+	assert.Equal(t, []ziface.EventType{ziface.EventConnOpened, ziface.EventConnClosed, ziface.EventConnClosed}, all)
+	assert.Equal(t, []ziface.EventType{ziface.EventConnClosed}, closed)
+}
+
+func TestServerEvents(t *testing.T) {
+	s := NewServer().(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28995
+
+	events := make(chan ziface.Event, 16)
+	s.GetEventBus().Subscribe(func(event ziface.Event) {
+		events <- event
+	})
+
+	s.Start()
+	assert.Equal(t, ziface.EventServerStarted, (<-events).Type)
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:28995")
+	assert.Nil(t, err)
+	// 收到数据后才能识别连接的协议
+	data, _ := zpack.NewDataPack().Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	_, _ = conn.Write(data)
+	opened := <-events
+	assert.Equal(t, ziface.EventConnOpened, opened.Type)
+	assert.NotNil(t, opened.Conn)
+
+	_ = conn.Close()
+	assert.Equal(t, ziface.EventConnClosed, (<-events).Type)
+
+	s.Stop()
+	assert.Equal(t, ziface.EventShutdownBegun, (<-events).Type)
+}
@@ -0,0 +1,282 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// BaseGroupHook 实现IGroupHook时，先嵌入这个基类，然后根据需要重写对应的方法
+type BaseGroupHook struct{}
+
+func (h *BaseGroupHook) OnCreate(group ziface.IGroup) {}
+
+func (h *BaseGroupHook) OnRemove(group ziface.IGroup) {}
+
+func (h *BaseGroupHook) OnJoin(group ziface.IGroup, conn ziface.IConnection) {}
+
+func (h *BaseGroupHook) OnLeave(group ziface.IGroup, conn ziface.IConnection) {}
+
+// Group 分组(房间)
+type Group struct {
+	name    string
+	members map[uint64]ziface.IConnection
+	mgr     *GroupManager
+	lock    sync.RWMutex
+	// 分组删除时结束, 取消分组的定时广播
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) Join(conn ziface.IConnection) {
+	g.lock.Lock()
+	if _, ok := g.members[conn.GetConnID()]; ok {
+		g.lock.Unlock()
+		return
+	}
+	g.members[conn.GetConnID()] = conn
+	g.lock.Unlock()
+
+	g.mgr.bind(conn.GetConnID(), g.name)
+	g.mgr.getHook().OnJoin(g, conn)
+}
+
+func (g *Group) Leave(conn ziface.IConnection) {
+	g.lock.Lock()
+	if _, ok := g.members[conn.GetConnID()]; !ok {
+		g.lock.Unlock()
+		return
+	}
+	delete(g.members, conn.GetConnID())
+	g.lock.Unlock()
+
+	g.mgr.unbind(conn.GetConnID(), g.name)
+	g.mgr.getHook().OnLeave(g, conn)
+}
+
+func (g *Group) Has(connID uint64) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	_, ok := g.members[connID]
+	return ok
+}
+
+func (g *Group) Members() []ziface.IConnection {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	members := make([]ziface.IConnection, 0, len(g.members))
+	for _, conn := range g.members {
+		members = append(members, conn)
+	}
+	return members
+}
+
+func (g *Group) Len() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return len(g.members)
+}
+
+func (g *Group) Broadcast(msgID uint32, data []byte) error {
+	return broadcast(g.Members(), msgID, data)
+}
+
+func (g *Group) BroadcastExcept(msgID uint32, data []byte, connID uint64) error {
+	g.lock.RLock()
+	members := make([]ziface.IConnection, 0, len(g.members))
+	for id, conn := range g.members {
+		if id != connID {
+			members = append(members, conn)
+		}
+	}
+	g.lock.RUnlock()
+
+	return broadcast(members, msgID, data)
+}
+
+// SendMsgAt 在t向全部成员广播消息(t时的成员), 用于定时广播, 分组删除时自动取消, 返回取消广播的方法
+func (g *Group) SendMsgAt(t time.Time, msgID uint32, data []byte) func() {
+	return timerAt(g.ctx, t, func() {
+		if err := g.Broadcast(msgID, data); err != nil {
+			zlog.Ins().ErrorF("SendMsgAt group = %s msgID = %d err: %v", g.name, msgID, err)
+		}
+	})
+}
+
+// SendMsgAfter delay之后向全部成员广播消息
+func (g *Group) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) func() {
+	return g.SendMsgAt(time.Now().Add(delay), msgID, data)
+}
+
+// GroupManager 分组管理器
+type GroupManager struct {
+	groups map[string]*Group
+	// 连接加入的分组索引, 用于连接断开时离开全部分组
+	connGroups map[uint64]map[string]struct{}
+	hook       ziface.IGroupHook
+	lock       sync.RWMutex
+}
+
+func NewGroupManager() *GroupManager {
+	return &GroupManager{
+		groups:     make(map[string]*Group),
+		connGroups: make(map[uint64]map[string]struct{}),
+		hook:       &BaseGroupHook{},
+	}
+}
+
+func (mgr *GroupManager) SetHook(hook ziface.IGroupHook) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	if hook == nil {
+		hook = &BaseGroupHook{}
+	}
+	mgr.hook = hook
+}
+
+func (mgr *GroupManager) getHook() ziface.IGroupHook {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+	return mgr.hook
+}
+
+func (mgr *GroupManager) Create(name string) (ziface.IGroup, error) {
+	group, created := mgr.getOrCreate(name)
+	if !created {
+		return nil, errors.New("group already exists")
+	}
+	return group, nil
+}
+
+func (mgr *GroupManager) Get(name string) (ziface.IGroup, bool) {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+
+	group, ok := mgr.groups[name]
+	if !ok {
+		return nil, false
+	}
+	return group, true
+}
+
+func (mgr *GroupManager) GetOrCreate(name string) ziface.IGroup {
+	group, _ := mgr.getOrCreate(name)
+	return group
+}
+
+func (mgr *GroupManager) getOrCreate(name string) (*Group, bool) {
+	mgr.lock.Lock()
+	if group, ok := mgr.groups[name]; ok {
+		mgr.lock.Unlock()
+		return group, false
+	}
+
+	group := &Group{
+		name:    name,
+		members: make(map[uint64]ziface.IConnection),
+		mgr:     mgr,
+	}
+	group.ctx, group.cancel = context.WithCancel(context.Background())
+	mgr.groups[name] = group
+	hook := mgr.hook
+	mgr.lock.Unlock()
+
+	hook.OnCreate(group)
+
+	return group, true
+}
+
+func (mgr *GroupManager) Remove(name string) {
+	mgr.lock.Lock()
+	group, ok := mgr.groups[name]
+	if !ok {
+		mgr.lock.Unlock()
+		return
+	}
+	delete(mgr.groups, name)
+	mgr.lock.Unlock()
+
+	// 取消分组的定时广播
+	group.cancel()
+	for _, conn := range group.Members() {
+		group.Leave(conn)
+	}
+	mgr.getHook().OnRemove(group)
+}
+
+func (mgr *GroupManager) Join(name string, conn ziface.IConnection) {
+	mgr.GetOrCreate(name).Join(conn)
+}
+
+func (mgr *GroupManager) Leave(name string, conn ziface.IConnection) {
+	if group, ok := mgr.Get(name); ok {
+		group.Leave(conn)
+	}
+}
+
+func (mgr *GroupManager) LeaveAll(conn ziface.IConnection) {
+	for _, name := range mgr.GroupsOf(conn.GetConnID()) {
+		mgr.Leave(name, conn)
+	}
+}
+
+func (mgr *GroupManager) GroupsOf(connID uint64) []string {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+
+	names := make([]string, 0, len(mgr.connGroups[connID]))
+	for name := range mgr.connGroups[connID] {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (mgr *GroupManager) Range(cb func(group ziface.IGroup) bool) {
+	mgr.lock.RLock()
+	groups := make([]ziface.IGroup, 0, len(mgr.groups))
+	for _, group := range mgr.groups {
+		groups = append(groups, group)
+	}
+	mgr.lock.RUnlock()
+
+	for _, group := range groups {
+		if !cb(group) {
+			return
+		}
+	}
+}
+
+func (mgr *GroupManager) Len() int {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+	return len(mgr.groups)
+}
+
+func (mgr *GroupManager) bind(connID uint64, name string) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+
+	if mgr.connGroups[connID] == nil {
+		mgr.connGroups[connID] = make(map[string]struct{})
+	}
+	mgr.connGroups[connID][name] = struct{}{}
+}
+
+func (mgr *GroupManager) unbind(connID uint64, name string) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+
+	delete(mgr.connGroups[connID], name)
+	if len(mgr.connGroups[connID]) == 0 {
+		delete(mgr.connGroups, connID)
+	}
+}
@@ -0,0 +1,52 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type countGroupHook struct {
+	BaseGroupHook
+	joins, leaves int
+}
+
+func (h *countGroupHook) OnJoin(group ziface.IGroup, conn ziface.IConnection) { h.joins++ }
+
+func (h *countGroupHook) OnLeave(group ziface.IGroup, conn ziface.IConnection) { h.leaves++ }
+
+func TestGroupManager(t *testing.T) {
+	mgr := NewGroupManager()
+	hook := &countGroupHook{}
+	mgr.SetHook(hook)
+
+	c1 := &Connection{connID: 1}
+	c2 := &Connection{connID: 2}
+
+	mgr.Join("room1", c1)
+	mgr.Join("room1", c2)
+	mgr.Join("room2", c1)
+	// 重复加入不会重复触发钩子
+	mgr.Join("room2", c1)
+
+	room1, ok := mgr.Get("room1")
+	assert.True(t, ok)
+	assert.Equal(t, 2, room1.Len())
+	assert.ElementsMatch(t, []string{"room1", "room2"}, mgr.GroupsOf(1))
+	assert.Equal(t, 3, hook.joins)
+
+	_, err := mgr.Create("room1")
+	assert.NotNil(t, err)
+
+	// 连接断开时离开全部分组
+	mgr.LeaveAll(c1)
+	assert.False(t, room1.Has(1))
+	assert.True(t, room1.Has(2))
+	assert.Empty(t, mgr.GroupsOf(1))
+
+	mgr.Remove("room1")
+	assert.Empty(t, mgr.GroupsOf(2))
+	assert.Equal(t, 3, hook.leaves)
+	assert.Equal(t, 1, mgr.Len())
+}
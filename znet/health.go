@@ -0,0 +1,147 @@
+package znet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultHealthQueueThreshold Worker任务队列的最高使用率超过该值时未就绪
+const DefaultHealthQueueThreshold = 0.8
+
+// SetHealthCheck 设置健康检查, 需在Start之前调用
+func (s *Server) SetHealthCheck(config ziface.HealthConfig) {
+	if config.QueueThreshold <= 0 {
+		config.QueueThreshold = DefaultHealthQueueThreshold
+	}
+
+	s.lock.Lock()
+	s.healthConfig = config
+	s.lock.Unlock()
+
+	if config.MsgID != 0 {
+		s.AddRouter(config.MsgID, &healthRouter{server: s})
+	}
+}
+
+func (s *Server) getHealthConfig() ziface.HealthConfig {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.healthConfig
+}
+
+// Health 获取服务的健康状态
+// 全部监听端口正常、没有开始停止服务、Worker任务队列使用率低于阈值且连接数未达到上限时就绪
+func (s *Server) Health() ziface.HealthStatus {
+	threshold := s.getHealthConfig().QueueThreshold
+	if threshold <= 0 {
+		threshold = DefaultHealthQueueThreshold
+	}
+
+	status := ziface.HealthStatus{
+		Live:              true,
+		Listeners:         int(atomic.LoadInt32(&s.listening)),
+		ExpectedListeners: 1 + len(s.listeners),
+		Connections:       s.ConnMgr.Len(),
+		MaxConn:           zconf.GlobalObject.MaxConn,
+		QueueUsage:        s.msgHandler.QueueUsage(),
+	}
+
+	s.lock.RLock()
+	started, closed := s.exitChan != nil, s.listenClosed
+	s.lock.RUnlock()
+
+	if !started || closed {
+		status.Reasons = append(status.Reasons, "server is not serving")
+	}
+	if status.Listeners < status.ExpectedListeners {
+		status.Reasons = append(status.Reasons, fmt.Sprintf("listeners up %d/%d", status.Listeners, status.ExpectedListeners))
+	}
+	if status.QueueUsage >= threshold {
+		status.Reasons = append(status.Reasons, fmt.Sprintf("worker queue usage %.2f", status.QueueUsage))
+	}
+	if status.Connections >= status.MaxConn {
+		status.Reasons = append(status.Reasons, fmt.Sprintf("connections %d reach max %d", status.Connections, status.MaxConn))
+	}
+	status.Ready = len(status.Reasons) == 0
+
+	return status
+}
+
+// handleHealth 注册HTTP健康检查, 未存活或未就绪时返回503
+func (s *Server) handleHealth(mux *http.ServeMux) {
+	mux.HandleFunc("/health/live", func(w http.ResponseWriter, r *http.Request) {
+		status := s.Health()
+		writeHealth(w, status, status.Live)
+	})
+	mux.HandleFunc("/health/ready", func(w http.ResponseWriter, r *http.Request) {
+		status := s.Health()
+		writeHealth(w, status, status.Ready)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, status ziface.HealthStatus, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// startHealthServer 启动独立的HTTP健康检查监听
+func (s *Server) startHealthServer() {
+	addr := s.getHealthConfig().Addr
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	s.handleHealth(mux)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	s.lock.Lock()
+	s.healthServer = server
+	s.lock.Unlock()
+
+	go func() {
+		zlog.Ins().InfoF("[START] health check listener at %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			zlog.Ins().ErrorF("health check listener err: %v", err)
+		}
+	}()
+}
+
+// stopHealthServer 停止服务之后关闭独立的HTTP健康检查监听
+func (s *Server) stopHealthServer() {
+	s.lock.Lock()
+	server := s.healthServer
+	s.healthServer = nil
+	s.lock.Unlock()
+
+	if server != nil {
+		_ = server.Shutdown(context.Background())
+	}
+}
+
+// healthRouter 在消息ID上响应健康检查
+type healthRouter struct {
+	BaseRouter
+	server *Server
+}
+
+func (hr *healthRouter) Handle(request ziface.IRequest) {
+	data, err := json.Marshal(hr.server.Health())
+	if err != nil {
+		zlog.Ins().ErrorF("marshal health status err: %v", err)
+		return
+	}
+	if err := request.GetConnection().SendMsg(request.GetMsgID(), data); err != nil {
+		zlog.Ins().ErrorF("reply health status err: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package znet
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerHealth(t *testing.T) {
+	s := NewServer(WithHealthCheck(ziface.HealthConfig{
+		Addr:  "127.0.0.1:28993",
+		MsgID: ziface.HealthMsgID,
+	})).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28994
+
+	// 未启动时未就绪
+	assert.False(t, s.Health().Ready)
+
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	status := s.Health()
+	assert.True(t, status.Live)
+	assert.True(t, status.Ready, status.Reasons)
+	assert.Equal(t, 1, status.Listeners)
+
+	// 独立的HTTP健康检查监听
+	resp, err := http.Get("http://127.0.0.1:28993/health/ready")
+	assert.Nil(t, err)
+	if err == nil {
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var httpStatus ziface.HealthStatus
+		assert.Nil(t, json.Unmarshal(body, &httpStatus))
+		assert.True(t, httpStatus.Ready)
+	}
+
+	// 消息ID上的健康检查
+	conn, err := net.Dial("tcp", "127.0.0.1:28994")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	dp := zpack.NewDataPack()
+	data, _ := dp.Pack(zpack.NewMsgPackage(ziface.HealthMsgID, nil))
+	_, _ = conn.Write(data)
+
+	head := make([]byte, dp.GetHeadLen())
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(conn, head)
+	assert.Nil(t, err)
+	msg, err := dp.Unpack(head)
+	assert.Nil(t, err)
+	assert.Equal(t, ziface.HealthMsgID, msg.GetMsgID())
+
+	body := make([]byte, msg.GetDataLen())
+	_, err = io.ReadFull(conn, body)
+	assert.Nil(t, err)
+	var msgStatus ziface.HealthStatus
+	assert.Nil(t, json.Unmarshal(body, &msgStatus))
+	assert.True(t, msgStatus.Live)
+
+	// 开始停止服务后未就绪
+	s.closeListeners()
+	assert.False(t, s.Health().Ready)
+	s.Stop()
+}
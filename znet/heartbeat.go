@@ -2,14 +2,17 @@ package znet
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
+
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
-	"time"
 )
 
 type HeartbeatChecker struct {
-	interval time.Duration // 心跳检测时间间隔
-	quitChan chan bool     // 退出信号
+	interval  int64         // 心跳检测时间间隔(纳秒), 运行时可以修改
+	quitChan  chan bool     // 退出信号
+	resetChan chan struct{} // 心跳间隔修改的信号
 
 	makeMsg ziface.HeartBeatMsgFunc //用户自定义的心跳检测消息处理方法
 
@@ -21,6 +24,16 @@ type HeartbeatChecker struct {
 	conn ziface.IConnection // 绑定的链接
 
 	beatFunc ziface.HeartBeatFunc // 用户自定义心跳发送函数
+
+	maxMisses int       // 连续未收到对端消息的心跳次数上限, 0表示按全局HeartbeatMax判断
+	misses    int       // 连续未收到对端消息的心跳次数
+	lastBeat  time.Time // 最后一次发送心跳的时间
+
+	validatePong   ziface.HeartBeatPongFunc // 用户自定义的心跳回复校验方法, nil表示收到对端的任何消息都表示存活
+	lastPong       int64                    // 最后一次收到校验通过的心跳回复的时间(unix纳秒)
+	policy         ziface.NotAlivePolicy    // 对端不存活时的处理策略
+	customNotAlive bool                     // 是否设置了用户自定义的不存活处理方法
+	suspect        bool                     // 连接是否已经标记为可疑
 }
 
 /*
@@ -51,8 +64,9 @@ func notAliveDefaultFunc(conn ziface.IConnection) {
 // NewHeartbeatChecker 创建心跳检测器
 func NewHeartbeatChecker(interval time.Duration) ziface.IHeartbeatChecker {
 	heartbeat := &HeartbeatChecker{
-		interval: interval,
-		quitChan: make(chan bool),
+		interval:  int64(interval),
+		quitChan:  make(chan bool),
+		resetChan: make(chan struct{}, 1),
 
 		//均使用默认的心跳消息生成函数和远程连接不存活时的处理方法
 		makeMsg:          makeDefaultMsg,
@@ -68,9 +82,21 @@ func NewHeartbeatChecker(interval time.Duration) ziface.IHeartbeatChecker {
 func (h *HeartbeatChecker) SetOnRemoteNotAlive(f ziface.OnRemoteNotAlive) {
 	if f != nil {
 		h.onRemoteNotAlive = f
+		h.customNotAlive = true
 	}
 }
 
+// SetPongValidator 设置心跳回复的校验方法, 心跳消息在路由之前校验, 需要在绑定连接之前设置
+// 设置后只有校验通过的心跳回复表示对端存活, 对端只发送业务消息而不回复心跳时也认为不存活
+func (h *HeartbeatChecker) SetPongValidator(f ziface.HeartBeatPongFunc) {
+	h.validatePong = f
+}
+
+// SetNotAlivePolicy 设置对端不存活时的处理策略
+func (h *HeartbeatChecker) SetNotAlivePolicy(policy ziface.NotAlivePolicy) {
+	h.policy = policy
+}
+
 func (h *HeartbeatChecker) SetHeartbeatMsgFunc(f ziface.HeartBeatMsgFunc) {
 	if f != nil {
 		h.makeMsg = f
@@ -83,6 +109,30 @@ func (h *HeartbeatChecker) SetHeartbeatFunc(beatFunc ziface.HeartBeatFunc) {
 	}
 }
 
+// SetMaxMisses 设置连续多少次心跳没有收到对端的任何消息时认为对端不存活, 小于等于0时按全局HeartbeatMax判断
+func (h *HeartbeatChecker) SetMaxMisses(maxMisses int) {
+	if maxMisses < 0 {
+		maxMisses = 0
+	}
+	h.maxMisses = maxMisses
+}
+
+// SetInterval 运行时修改心跳检测时间间隔, 下一次心跳按新的间隔发送, 小于等于0时不修改
+func (h *HeartbeatChecker) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	atomic.StoreInt64(&h.interval, int64(interval))
+	select {
+	case h.resetChan <- struct{}{}:
+	default:
+	}
+}
+
+func (h *HeartbeatChecker) getInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.interval))
+}
+
 func (h *HeartbeatChecker) BindRouter(msgID uint32, router ziface.IRouter) {
 	if router != nil && msgID != ziface.HeartBeatDefaultMsgID {
 		h.msgID = msgID
@@ -91,11 +141,19 @@ func (h *HeartbeatChecker) BindRouter(msgID uint32, router ziface.IRouter) {
 }
 
 func (h *HeartbeatChecker) start() {
-	ticker := time.NewTicker(h.interval)
+	// 每个连接重新开始计数, 客户端重连后复用同一个心跳检测器
+	h.misses = 0
+	h.lastBeat = time.Time{}
+	h.suspect = false
+	atomic.StoreInt64(&h.lastPong, time.Now().UnixNano())
+
+	ticker := time.NewTicker(h.getInterval())
 	for {
 		select {
 		case <-ticker.C:
 			h.check()
+		case <-h.resetChan:
+			ticker.Reset(h.getInterval())
 		case <-h.quitChan:
 			ticker.Stop()
 			return
@@ -134,19 +192,91 @@ func (h *HeartbeatChecker) check() (err error) {
 		return nil
 	}
 
-	if !h.conn.IsAlive() {
-		h.onRemoteNotAlive(h.conn)
-	} else {
-		if h.beatFunc != nil {
-			err = h.beatFunc(h.conn)
-		} else {
-			err = h.SendHeartBeatMsg()
+	if !h.remoteAlive() {
+		h.notAlive()
+		if h.policy == ziface.NotAliveClose {
+			return nil
 		}
+	} else if h.suspect {
+		// 对端恢复, 清除可疑标记
+		h.suspect = false
+		h.conn.RemoveProperty(ziface.HeartbeatSuspectProperty)
 	}
 
+	if h.beatFunc != nil {
+		err = h.beatFunc(h.conn)
+	} else {
+		err = h.SendHeartBeatMsg()
+	}
+	h.lastBeat = time.Now()
+
 	return err
 }
 
+// notAlive 按处理策略处理不存活的对端
+func (h *HeartbeatChecker) notAlive() {
+	switch h.policy {
+	case ziface.NotAliveNotify:
+		h.notify()
+	case ziface.NotAliveSuspect:
+		if !h.suspect {
+			h.suspect = true
+			h.conn.SetProperty(ziface.HeartbeatSuspectProperty, true)
+			h.notify()
+		}
+	default:
+		h.onRemoteNotAlive(h.conn)
+	}
+}
+
+// notify 通知对端不存活, 默认的处理方法会关闭连接, 只通知时没有设置处理方法则只记录日志
+func (h *HeartbeatChecker) notify() {
+	if h.customNotAlive {
+		h.onRemoteNotAlive(h.conn)
+		return
+	}
+	zlog.Ins().InfoF("Remote connection %s is not alive", h.conn.RemoteAddr())
+}
+
+// pong 收到校验通过的心跳回复
+func (h *HeartbeatChecker) pong() {
+	atomic.StoreInt64(&h.lastPong, time.Now().UnixNano())
+}
+
+// activityConn 可以获取最后一次读取到对端数据时间的链接
+type activityConn interface {
+	lastActivity() time.Time
+	heartbeatMax() time.Duration
+}
+
+// remoteAlive 判断对端是否存活
+// 设置了maxMisses时, 上次发送心跳之后没有收到对端的任何消息记为一次未响应, 连续未响应maxMisses次认为对端不存活
+// 设置了心跳回复校验时, 只按校验通过的心跳回复判断, 不计入其他消息
+func (h *HeartbeatChecker) remoteAlive() bool {
+	conn, ok := h.conn.(activityConn)
+	if !ok || (h.maxMisses <= 0 && h.validatePong == nil) {
+		return h.conn.IsAlive()
+	}
+	if h.conn.Context().Err() != nil {
+		return false
+	}
+
+	last := conn.lastActivity()
+	if h.validatePong != nil {
+		last = time.Unix(0, atomic.LoadInt64(&h.lastPong))
+	}
+	if h.maxMisses <= 0 {
+		return time.Since(last) < conn.heartbeatMax()
+	}
+
+	if h.lastBeat.IsZero() || last.After(h.lastBeat) {
+		h.misses = 0
+	} else {
+		h.misses++
+	}
+	return h.misses < h.maxMisses
+}
+
 // BindConn 绑定一个链接
 func (h *HeartbeatChecker) BindConn(conn ziface.IConnection) {
 	h.conn = conn
@@ -157,12 +287,18 @@ func (h *HeartbeatChecker) BindConn(conn ziface.IConnection) {
 func (h *HeartbeatChecker) Clone() ziface.IHeartbeatChecker {
 
 	heartbeat := &HeartbeatChecker{
-		interval:         h.interval,
+		interval:         atomic.LoadInt64(&h.interval),
 		quitChan:         make(chan bool),
+		resetChan:        make(chan struct{}, 1),
 		makeMsg:          h.makeMsg,
 		onRemoteNotAlive: h.onRemoteNotAlive,
 		msgID:            h.msgID,
 		router:           h.router,
+		beatFunc:         h.beatFunc,
+		maxMisses:        h.maxMisses,
+		validatePong:     h.validatePong,
+		policy:           h.policy,
+		customNotAlive:   h.customNotAlive,
 		conn:             nil, //绑定的链接需要重新赋值
 	}
 
@@ -176,3 +312,22 @@ func (h *HeartbeatChecker) MsgID() uint32 {
 func (h *HeartbeatChecker) Router() ziface.IRouter {
 	return h.router
 }
+
+// heartbeatConn 可以获取绑定的心跳检测器的链接
+type heartbeatConn interface {
+	getHeartBeat() ziface.IHeartbeatChecker
+}
+
+// checkPong 请求为连接绑定的心跳检测器的心跳消息时校验心跳回复, 校验通过时更新最后一次心跳回复的时间
+// 在路由之前执行, 用户的心跳路由保持原样(中间件、处理期限、panic恢复等接口不受影响)
+func checkPong(msgID uint32, req ziface.IRequest) {
+	conn := req.GetConnection()
+	c, ok := conn.(heartbeatConn)
+	if !ok {
+		return
+	}
+	h, ok := c.getHeartBeat().(*HeartbeatChecker)
+	if ok && h.validatePong != nil && h.msgID == msgID && h.validatePong(conn, req.GetData()) {
+		h.pong()
+	}
+}
@@ -0,0 +1,176 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type heartbeatReplyRouter struct {
+	BaseRouter
+	replies int32
+}
+
+func (r *heartbeatReplyRouter) Handle(req ziface.IRequest) {
+	if req.GetMsgID() == 10 && string(req.GetData()) == "ping" {
+		atomic.AddInt32(&r.replies, 1)
+	}
+}
+
+func TestClientHeartBeatMaxMisses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		// 第一个连接不回复心跳, 第二个连接原样回复
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() { _, _ = io.Copy(io.Discard, conn) }()
+		conn2, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn2.Close()
+		_, _ = io.Copy(conn2, conn2)
+	}()
+
+	router := &heartbeatReplyRouter{}
+	reconnected := make(chan uint64, 1)
+	addr := listener.Addr().(*net.TCPAddr)
+	client := NewClient("127.0.0.1", addr.Port,
+		WithReconnectClient(ziface.ReconnectConfig{
+			MinDelay: 10 * time.Millisecond,
+			OnReconnect: func(conn ziface.IConnection, reconnects uint64) {
+				reconnected <- reconnects
+			},
+		}),
+		WithHeartBeatClient(50*time.Millisecond, &ziface.HeartBeatOption{
+			MakeMsg:       func(conn ziface.IConnection) []byte { return []byte("ping") },
+			HeadBeatMsgID: 10,
+			Router:        router,
+			MaxMisses:     2,
+		}))
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case reconnects := <-reconnected:
+		assert.Equal(t, uint64(1), reconnects)
+	case <-time.After(2 * time.Second):
+		t.Fatal("client is not reconnected after missed heartbeats")
+	}
+
+	// 对端回复心跳, 连接保持
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, uint64(1), client.Reconnects())
+	assert.True(t, atomic.LoadInt32(&router.replies) >= 3)
+}
+
+func TestConnHeartbeatInterval(t *testing.T) {
+	local, remote := net.Pipe()
+	c := &Connection{conn: local, connID: 1, packet: zpack.NewDataPack()}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+	c.updateActivity()
+
+	checker := NewHeartbeatChecker(time.Hour)
+	checker.BindConn(c)
+	checker.Start()
+
+	// 运行中修改心跳间隔, 不需要等待原来的间隔
+	c.SetHeartbeatInterval(20*time.Millisecond, 0)
+	expected, _ := c.packet.Pack(zpack.NewMsgPackage(ziface.HeartBeatDefaultMsgID, makeDefaultMsg(c)))
+	_ = remote.SetReadDeadline(time.Now().Add(time.Second))
+	data := make([]byte, len(expected))
+	_, err := io.ReadFull(remote, data)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, data)
+	_ = remote.Close()
+	checker.Stop()
+
+	// 单独设置的超时时间代替全局的HeartbeatMax
+	atomic.StoreInt64(&c.lastActivityTime, time.Now().Add(-2*time.Second).UnixNano())
+	assert.True(t, c.IsAlive())
+	c.SetHeartbeatInterval(0, time.Second)
+	assert.False(t, c.IsAlive())
+	c.SetHeartbeatInterval(0, 0)
+	assert.True(t, c.IsAlive())
+}
+
+func TestHeartbeatHooks(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	c := &Connection{conn: local, connID: 1}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+	c.SetProperty("platform", "mobile")
+	c.SetHeartbeatInterval(0, 50*time.Millisecond)
+
+	var beats, notified int
+	checker := NewHeartbeatChecker(time.Hour).(*HeartbeatChecker)
+	checker.SetHeartbeatFunc(func(conn ziface.IConnection) error {
+		beats++
+		return nil
+	})
+	// 校验方法与处理方法都可以读取连接属性
+	checker.SetPongValidator(func(conn ziface.IConnection, data []byte) bool {
+		platform, _ := conn.GetProperty("platform")
+		return platform == "mobile" && string(data) == "pong"
+	})
+	checker.SetOnRemoteNotAlive(func(conn ziface.IConnection) { notified++ })
+	checker.SetNotAlivePolicy(ziface.NotAliveSuspect)
+	checker.BindConn(c)
+	checker.pong()
+	// 心跳回复在路由之前校验, 用户的心跳路由原样注册
+	assert.Equal(t, checker.router, checker.Router())
+	mh := NewMsgHandle()
+	mh.AddRouter(checker.MsgID(), checker.Router())
+	pong := func(data string) {
+		mh.doMsgHandler(NewRequest(c, zpack.NewMsgPackage(ziface.HeartBeatDefaultMsgID, []byte(data))))
+	}
+
+	assert.Nil(t, checker.check())
+	assert.Equal(t, 1, beats)
+
+	// 只有校验通过的心跳回复表示存活, 其他消息不计入
+	time.Sleep(60 * time.Millisecond)
+	c.updateActivity()
+	pong("hello")
+	assert.Nil(t, checker.check())
+	assert.Nil(t, checker.check())
+	suspect, err := c.GetProperty(ziface.HeartbeatSuspectProperty)
+	assert.Nil(t, err)
+	assert.Equal(t, true, suspect)
+	assert.Equal(t, 1, notified)
+	assert.Equal(t, 3, beats)
+
+	// 对端恢复后清除可疑标记
+	pong("pong")
+	assert.Nil(t, checker.check())
+	_, err = c.GetProperty(ziface.HeartbeatSuspectProperty)
+	assert.NotNil(t, err)
+
+	// 只通知时每次检测都通知, 继续发送心跳
+	checker.SetNotAlivePolicy(ziface.NotAliveNotify)
+	time.Sleep(60 * time.Millisecond)
+	assert.Nil(t, checker.check())
+	assert.Nil(t, checker.check())
+	assert.Equal(t, 3, notified)
+	assert.Equal(t, 6, beats)
+
+	// 默认策略调用处理方法后不再发送心跳
+	checker.SetNotAlivePolicy(ziface.NotAliveClose)
+	assert.Nil(t, checker.check())
+	assert.Equal(t, 4, notified)
+	assert.Equal(t, 6, beats)
+}
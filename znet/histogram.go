@@ -0,0 +1,75 @@
+package znet
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// histSubBits 每个2的幂区间划分的子区间数量为2^histSubBits, 相对误差不超过1/16
+	histSubBits = 4
+	histSub     = 1 << histSubBits
+	// histMaxExp 记录的最大值约为2^(histMaxExp+histSubBits+1)微秒(约19小时), 更大的值计入最后一个区间
+	histMaxExp  = 31
+	histBuckets = histMaxExp*histSub + 2*histSub
+)
+
+// latencyHistogram 对数线性分桶的延迟直方图(类似HDR Histogram), 以微秒为单位, 通过atomic访问
+type latencyHistogram struct {
+	counts [histBuckets]uint64
+}
+
+// histIndex 值所在的区间, 小于2*histSub的值精确记录
+func histIndex(v uint64) int {
+	if v < 2*histSub {
+		return int(v)
+	}
+	e := bits.Len64(v) - histSubBits - 1
+	if e > histMaxExp {
+		return histBuckets - 1
+	}
+	return e*histSub + int(v>>uint(e))
+}
+
+// histValue 区间的中间值
+func histValue(index int) uint64 {
+	if index < 2*histSub {
+		return uint64(index)
+	}
+	e := uint(index/histSub - 1)
+	m := uint64(index%histSub + histSub)
+	return m<<e + (1<<e)/2
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.AddUint64(&h.counts[histIndex(uint64(d/time.Microsecond))], 1)
+}
+
+// percentiles 计算多个分位数(0~1, 从小到大), 没有记录时返回0
+func (h *latencyHistogram) percentiles(qs ...float64) []time.Duration {
+	var counts [histBuckets]uint64
+	var total uint64
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&h.counts[i])
+		total += counts[i]
+	}
+
+	result := make([]time.Duration, len(qs))
+	if total == 0 {
+		return result
+	}
+	var seen uint64
+	q := 0
+	for i := 0; i < histBuckets && q < len(qs); i++ {
+		seen += counts[i]
+		for q < len(qs) && float64(seen) >= qs[q]*float64(total) && seen > 0 {
+			result[q] = time.Duration(histValue(i)) * time.Microsecond
+			q++
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	// 区间的中间值落在原区间中, 相对误差不超过1/16
+	for _, v := range []uint64{0, 1, 31, 32, 33, 100, 1000, 123456, 1 << 30} {
+		value := histValue(histIndex(v))
+		assert.Equal(t, histIndex(v), histIndex(value))
+		assert.InDelta(t, float64(v), float64(value), float64(v)/16+1)
+	}
+
+	var h latencyHistogram
+	assert.Equal(t, []time.Duration{0, 0}, h.percentiles(0.5, 0.99))
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	p := h.percentiles(0.5, 0.95, 0.99)
+	assert.InDelta(t, float64(50*time.Millisecond), float64(p[0]), float64(50*time.Millisecond)/16)
+	assert.InDelta(t, float64(95*time.Millisecond), float64(p[1]), float64(95*time.Millisecond)/16)
+	assert.InDelta(t, float64(99*time.Millisecond), float64(p[2]), float64(99*time.Millisecond)/16)
+}
+
+func TestRouteStatsLatency(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.AddRouter(1, &sleepRouter{})
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+
+	routes := mh.RouteStats()
+	assert.Len(t, routes, 1)
+	assert.Equal(t, uint64(1), routes[0].Count)
+	assert.True(t, routes[0].P50 >= 18*time.Millisecond)
+	assert.Equal(t, routes[0].P50, routes[0].P99)
+}
@@ -0,0 +1,120 @@
+package znet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// HTTPReadHeaderTimeout TCP端口上识别为HTTP请求后，读取请求行与请求头的超时时间
+const HTTPReadHeaderTimeout = 10 * time.Second
+
+// httpMethods 用于识别TCP端口上收到的HTTP请求
+var httpMethods = []string{"GET ", "POST ", "HEAD ", "PUT ", "DELETE ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE "}
+
+// isHTTPRequest 通过预读取连接的前几个字节，判断是否是HTTP请求
+// 只等待第一次读取到的数据，不会为了识别协议而阻塞等待更多的数据(自定义协议的首包可能很短)
+func isHTTPRequest(reader *bufio.Reader) bool {
+	if _, err := reader.Peek(1); err != nil {
+		return false
+	}
+
+	peek, _ := reader.Peek(reader.Buffered())
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(peek, []byte(method)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readHTTPRequest 读取TCP端口上收到的HTTP请求行与请求头，读取期间设置超时，防止只发送部分请求头的连接一直占用
+// 读取完成后清除超时，之后的读取(请求体、Websocket)不受影响
+func readHTTPRequest(conn net.Conn, reader *bufio.Reader, timeout time.Duration) (*http.Request, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// bufferedConn 识别协议时预读取的数据保存在reader中，读取时需要先从reader中读取
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func newBufferedConn(conn net.Conn, reader *bufio.Reader) net.Conn {
+	return &bufferedConn{
+		Conn:   conn,
+		reader: reader,
+	}
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// serveHTTP 处理TCP端口上收到的普通HTTP请求，响应后关闭连接
+func (s *Server) serveHTTP(conn net.Conn, request *http.Request) {
+	defer conn.Close()
+
+	w := newHTTPResponseWriter()
+	handler, pattern := s.httpMux.Handler(request)
+	if pattern == "" && s.httpRedirect != "" {
+		http.Redirect(w, request, s.httpRedirect, http.StatusFound)
+	} else {
+		handler.ServeHTTP(w, request)
+	}
+
+	if err := w.writeTo(conn, request); err != nil {
+		zlog.Ins().ErrorF("write http response err:%v", err)
+	}
+}
+
+// newDefaultHTTPMux 创建内置的HTTP处理方法
+func newDefaultHTTPMux(s *Server) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// 存活检测
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok"))
+	})
+	// 存活与就绪检测
+	s.handleHealth(mux)
+
+	// 基础运行指标
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintf(w, "zinx_connections %d\n", s.ConnMgr.Len())
+		_, _ = fmt.Fprintf(w, "zinx_goroutines %d\n", runtime.NumGoroutine())
+		_, _ = fmt.Fprintf(w, "zinx_memory_alloc_bytes %d\n", mem.Alloc)
+
+		// 发送缓冲队列已满时的处理结果
+		stats := s.getSendBuffStats()
+		_, _ = fmt.Fprintf(w, "zinx_send_buff_timeout_total %d\n", atomic.LoadUint64(&stats.Timeout))
+		_, _ = fmt.Fprintf(w, "zinx_send_buff_drop_oldest_total %d\n", atomic.LoadUint64(&stats.DropOldest))
+		_, _ = fmt.Fprintf(w, "zinx_send_buff_drop_newest_total %d\n", atomic.LoadUint64(&stats.DropNewest))
+		_, _ = fmt.Fprintf(w, "zinx_send_buff_closed_total %d\n", atomic.LoadUint64(&stats.Closed))
+	})
+
+	return mux
+}
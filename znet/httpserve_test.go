@@ -0,0 +1,73 @@
+package znet
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsHTTPRequest(t *testing.T) {
+	assert.True(t, isHTTPRequest(bufio.NewReader(strings.NewReader("GET /health HTTP/1.1\r\n\r\n"))))
+	assert.True(t, isHTTPRequest(bufio.NewReader(strings.NewReader("OPTIONS * HTTP/1.1\r\n\r\n"))))
+	assert.False(t, isHTTPRequest(bufio.NewReader(strings.NewReader("GETX"))))
+	assert.False(t, isHTTPRequest(bufio.NewReader(strings.NewReader("\x00\x00\x00\x01\x00\x00\x00\x00"))))
+	assert.False(t, isHTTPRequest(bufio.NewReader(strings.NewReader(""))))
+}
+
+func TestBufferedConn(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	go func() {
+		_, _ = client.Write([]byte("PING"))
+	}()
+
+	reader := bufio.NewReader(server)
+	assert.False(t, isHTTPRequest(reader))
+
+	//预读取的数据不会丢失
+	conn := newBufferedConn(server, reader)
+	buf := make([]byte, 4)
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "PING", string(buf[:n]))
+}
+
+func TestReadHTTPRequestTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// 请求头没有在超时时间内发送完成
+	go func() {
+		_, _ = client.Write([]byte("GET /health HTTP/1.1\r\n"))
+	}()
+	reader := bufio.NewReader(server)
+	assert.True(t, isHTTPRequest(reader))
+	_, err := readHTTPRequest(server, reader, 50*time.Millisecond)
+	assert.NotNil(t, err)
+
+	// 读取完成后清除超时
+	server, client = net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go func() {
+		_, _ = client.Write([]byte("GET /ws HTTP/1.1\r\nHost: zinx\r\n\r\n"))
+		time.Sleep(100 * time.Millisecond)
+		_, _ = client.Write([]byte("PING"))
+	}()
+	reader = bufio.NewReader(server)
+	request, err := readHTTPRequest(server, reader, 50*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, "/ws", request.URL.Path)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(reader, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "PING", string(buf))
+}
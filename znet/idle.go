@@ -0,0 +1,135 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// idleTimer 一个方向(读或写)的空闲计时
+type idleTimer struct {
+	kind    string
+	timeout time.Duration
+	// 最后一次读写的时间(UnixNano)
+	last  int64
+	timer *time.Timer
+}
+
+func (t *idleTimer) touch() {
+	atomic.StoreInt64(&t.last, time.Now().UnixNano())
+}
+
+func (t *idleTimer) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&t.last)))
+}
+
+// idleChecker 连接读写空闲检测, 与心跳检测相互独立
+// 每个方向使用一个定时器, 到期时根据最后一次读写时间判断是否空闲, 未空闲则按剩余时间重新计时
+type idleChecker struct {
+	conn    ziface.IConnection
+	read    idleTimer
+	write   idleTimer
+	started bool
+	stopped bool
+	lock    sync.Mutex
+}
+
+// idleTimeoutOwner 设置了读写空闲超时的Server, 连接创建时继承
+type idleTimeoutOwner interface {
+	getIdleTimeout() (read, write time.Duration)
+}
+
+func newIdleChecker(conn ziface.IConnection, readTimeout, writeTimeout time.Duration) *idleChecker {
+	return &idleChecker{
+		conn:  conn,
+		read:  idleTimer{kind: "read", timeout: readTimeout},
+		write: idleTimer{kind: "write", timeout: writeTimeout},
+	}
+}
+
+func (ic *idleChecker) touchRead() {
+	ic.read.touch()
+}
+
+func (ic *idleChecker) touchWrite() {
+	ic.write.touch()
+}
+
+// start 连接开始工作时开始计时
+func (ic *idleChecker) start() {
+	ic.read.touch()
+	ic.write.touch()
+
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	ic.started = true
+	ic.arm(&ic.read)
+	ic.arm(&ic.write)
+}
+
+// set 运行时修改空闲超时时间, 0表示不检测
+func (ic *idleChecker) set(readTimeout, writeTimeout time.Duration) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	ic.read.timeout = readTimeout
+	ic.write.timeout = writeTimeout
+	if ic.started {
+		ic.arm(&ic.read)
+		ic.arm(&ic.write)
+	}
+}
+
+func (ic *idleChecker) stop() {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	ic.stopped = true
+	for _, t := range []*idleTimer{&ic.read, &ic.write} {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+	}
+}
+
+// arm 按照剩余的空闲时间重新设置定时器, 需持有锁
+func (ic *idleChecker) arm(t *idleTimer) {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if ic.stopped || t.timeout <= 0 {
+		return
+	}
+
+	wait := t.timeout - t.idle()
+	if wait < 0 {
+		wait = 0
+	}
+	t.timer = time.AfterFunc(wait, func() {
+		ic.check(t)
+	})
+}
+
+func (ic *idleChecker) check(t *idleTimer) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+
+	if ic.stopped || t.timeout <= 0 {
+		return
+	}
+
+	// 期间有读写，按剩余时间重新计时
+	if t.idle() < t.timeout {
+		ic.arm(t)
+		return
+	}
+
+	ic.stopped = true
+	zlog.Ins().InfoF("connID = %d %s idle timeout %v, close connection", ic.conn.GetConnID(), t.kind, t.timeout)
+	ic.conn.Stop()
+}
@@ -0,0 +1,57 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleChecker(t *testing.T) {
+	conn := &Connection{connID: 1}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+
+	ic := newIdleChecker(conn, 0, 0)
+	ic.start()
+
+	// 运行时开启读空闲检测, 期间有读取数据则不会关闭
+	ic.set(50*time.Millisecond, 0)
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		ic.touchRead()
+	}
+	assert.Nil(t, conn.ctx.Err())
+
+	select {
+	case <-conn.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("idle connection not closed")
+	}
+}
+
+func TestIdleCheckerStop(t *testing.T) {
+	conn := &Connection{connID: 1}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+
+	ic := newIdleChecker(conn, 0, 20*time.Millisecond)
+	ic.start()
+	ic.stop()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, conn.ctx.Err())
+}
+
+func TestServerConnIdleTimeout(t *testing.T) {
+	s := NewServer().(*Server)
+	s.SetIdleTimeout(time.Minute, 2*time.Minute)
+
+	// 新建立的连接继承Server的读写空闲超时
+	local, remote := net.Pipe()
+	defer remote.Close()
+	c := newServerConn(s, local, 1).(*Connection)
+	defer s.GetConnMgr().Remove(c)
+	assert.Equal(t, time.Minute, c.idle.read.timeout)
+	assert.Equal(t, 2*time.Minute, c.idle.write.timeout)
+}
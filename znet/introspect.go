@@ -0,0 +1,127 @@
+package znet
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// Routes 已注册的路由, 按消息ID排列
+func (mh *MsgHandle) Routes() []ziface.RouteInfo {
+	routes := make([]ziface.RouteInfo, 0, len(mh.Apis))
+	for msgID, router := range mh.Apis {
+		info := ziface.RouteInfo{
+			MsgID:    msgID,
+			Router:   typeName(router),
+			Priority: mh.priorities[msgID],
+		}
+		if r, ok := router.(ziface.IMiddlewareRouter); ok {
+			info.Middlewares = len(r.Middlewares())
+		}
+		if r, ok := router.(ziface.ITimeoutRouter); ok {
+			info.Timeout = r.Timeout()
+		}
+		_, info.OwnPool = mh.routePools[msgID]
+		if sem, ok := mh.limits[msgID]; ok {
+			info.ConcurrencyLimit = cap(sem)
+		}
+		routes = append(routes, info)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].MsgID < routes[j].MsgID
+	})
+	return routes
+}
+
+// typeName 对象的类型名称, nil时为空
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Introspect 运行中服务的路由、拦截器与监听端口
+func (s *Server) Introspect() ziface.ServerInfo {
+	info := ziface.ServerInfo{
+		Name:            s.Name,
+		Routes:          s.msgHandler.Routes(),
+		Interceptors:    s.InterceptorNames(),
+		OutInterceptors: s.OutInterceptorNames(),
+		Connections:     s.ConnMgr.Len(),
+	}
+	if mh, ok := s.msgHandler.(*MsgHandle); ok {
+		info.DefaultRouter = typeName(mh.defaultRouter)
+	}
+
+	packet, decoder := typeName(s.GetPacket()), typeName(s.GetDecoder())
+	info.Listeners = append(info.Listeners, ziface.ListenerInfo{
+		Network: s.IPVersion,
+		Addr:    net.JoinHostPort(s.IP, strconv.Itoa(s.Port)),
+		Packet:  packet,
+		Decoder: decoder,
+	})
+	for _, l := range s.listeners {
+		listener := ziface.ListenerInfo{
+			Network: l.IPVersion,
+			Addr:    net.JoinHostPort(l.IP, strconv.Itoa(l.Port)),
+			Packet:  packet,
+			Decoder: decoder,
+		}
+		if listener.Network == "" {
+			listener.Network = s.IPVersion
+		}
+		if l.Packet != nil {
+			listener.Packet = typeName(l.Packet)
+		}
+		if l.Decoder != nil {
+			listener.Decoder = typeName(l.Decoder)
+		}
+		info.Listeners = append(info.Listeners, listener)
+	}
+	return info
+}
+
+// FindConns 当前Server上满足过滤条件的连接, 按连接ID排列
+func (s *Server) FindConns(filter ziface.ConnFilter) []ziface.IConnection {
+	conns := s.ownConns()
+	if len(filter.Tags) > 0 {
+		conns = s.ConnMgr.GetByTags(filter.Tags)
+	}
+	ip := normalizeIP(filter.RemoteIP)
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].GetConnID() < conns[j].GetConnID()
+	})
+
+	result := make([]ziface.IConnection, 0, len(conns))
+	for _, conn := range conns {
+		if c, ok := conn.(connOwner); s.sharedConnMgr && (!ok || c.getServer() != ziface.IServer(s)) {
+			continue
+		}
+		if ip != "" && remoteIP(conn.RemoteAddr()) != ip {
+			continue
+		}
+		if filter.MinBytesIn > 0 && conn.Stats().BytesIn < filter.MinBytesIn {
+			continue
+		}
+		result = append(result, conn)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// EffectiveConfig 当前生效的配置, 服务名称与监听地址使用Server的值, 不包括访问令牌等密钥
+func (s *Server) EffectiveConfig() zconf.Config {
+	config := *zconf.Dump()
+	config.Name = s.Name
+	config.Host = s.IP
+	config.TCPPort = s.Port
+	config.IPVersion = s.IPVersion
+	return config
+}
@@ -0,0 +1,76 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// addrConn 指定对端地址的net.Conn
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func TestServerIntrospect(t *testing.T) {
+	s := NewServer().(*Server)
+	s.AddRouter(2, &BaseRouter{})
+	s.AddRouter(1, &BaseRouter{})
+	s.SetMsgPriority(ziface.PriorityHigh, 1)
+	s.SetConcurrencyLimit(4, 2)
+	s.AddListener(ziface.ListenerConfig{IP: "127.0.0.1", Port: 28984, Packet: zpack.NewSeqDataPack()})
+
+	info := s.Introspect()
+	assert.Len(t, info.Routes, 2)
+	assert.Equal(t, uint32(1), info.Routes[0].MsgID)
+	assert.Equal(t, "*znet.BaseRouter", info.Routes[0].Router)
+	assert.Equal(t, ziface.PriorityHigh, info.Routes[0].Priority)
+	assert.Equal(t, 4, info.Routes[1].ConcurrencyLimit)
+	assert.Len(t, info.Listeners, 2)
+	assert.Equal(t, "127.0.0.1:28984", info.Listeners[1].Addr)
+	assert.Equal(t, s.IPVersion, info.Listeners[1].Network)
+	assert.NotEqual(t, info.Listeners[0].Packet, info.Listeners[1].Packet)
+
+	ips := []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}
+	for i, ip := range ips {
+		local, remote := net.Pipe()
+		defer remote.Close()
+		conn := &Connection{
+			conn:   &addrConn{Conn: local, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}},
+			connID: uint64(i + 1),
+			stats:  newConnStats(),
+		}
+		conn.ctx, conn.cancel = context.WithCancel(context.Background())
+		defer conn.cancel()
+		conn.stats.read(100 * (i + 1))
+		s.ConnMgr.Add(conn)
+	}
+	conn, _ := s.ConnMgr.Get(3)
+	s.ConnMgr.SetTag(conn, "room", "a")
+
+	connIDs := func(conns []ziface.IConnection) []uint64 {
+		ids := make([]uint64, 0, len(conns))
+		for _, conn := range conns {
+			ids = append(ids, conn.GetConnID())
+		}
+		return ids
+	}
+	assert.Equal(t, []uint64{1, 2, 3}, connIDs(s.FindConns(ziface.ConnFilter{})))
+	assert.Equal(t, []uint64{1, 3}, connIDs(s.FindConns(ziface.ConnFilter{RemoteIP: "10.0.0.1"})))
+	assert.Equal(t, []uint64{2, 3}, connIDs(s.FindConns(ziface.ConnFilter{MinBytesIn: 200})))
+	assert.Equal(t, []uint64{3}, connIDs(s.FindConns(ziface.ConnFilter{Tags: map[string]string{"room": "a"}})))
+	assert.Equal(t, []uint64{1}, connIDs(s.FindConns(ziface.ConnFilter{Limit: 1})))
+
+	config := s.EffectiveConfig()
+	assert.Equal(t, s.Name, config.Name)
+	assert.Equal(t, s.Port, config.TCPPort)
+	assert.Empty(t, config.AdminToken)
+}
@@ -0,0 +1,113 @@
+package znet
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerDualStack(t *testing.T) {
+	s := NewServer().(*Server)
+	s.IPVersion = "tcp4"
+	s.IP = "127.0.0.1"
+	s.Port = 28996
+	s.addConfListeners(&zconf.Config{
+		TCPPort:   28996,
+		Listeners: []zconf.ListenAddr{{IPVersion: "tcp6", Host: "::1"}},
+	})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, address := range []string{"127.0.0.1:28996", "[::1]:28996"} {
+		conn, err := net.Dial("tcp", address)
+		assert.Nil(t, err, address)
+		if err == nil {
+			_ = conn.Close()
+		}
+	}
+}
+
+// linePack 行协议的封包方式, 消息内容后追加换行符
+type linePack struct{}
+
+func (linePack) GetHeadLen() uint32 {
+	return 0
+}
+
+func (linePack) Pack(msg ziface.IMessage) ([]byte, error) {
+	return append(append([]byte{}, msg.GetData()...), '\n'), nil
+}
+
+func (linePack) Unpack([]byte) (ziface.IMessage, error) {
+	return zpack.NewMsgPackage(0, nil), nil
+}
+
+// connIDRouter 记录请求所属的连接ID并回复
+type connIDRouter struct {
+	BaseRouter
+	connIDs chan uint64
+}
+
+func (r *connIDRouter) Handle(request ziface.IRequest) {
+	r.connIDs <- request.GetConnection().GetConnID()
+	_ = request.GetConnection().SendMsg(request.GetMsgID(), append([]byte("pong:"), request.GetData()...))
+}
+
+func TestServerMultiListener(t *testing.T) {
+	s := NewServer().(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28963
+	// 附加的端口使用行协议, 与主端口共享路由
+	s.AddListener(ziface.ListenerConfig{IP: "127.0.0.1", Port: 28962, Packet: linePack{}, Decoder: zdecoder.NewLineDecoder(1, 64)})
+	router := &connIDRouter{connIDs: make(chan uint64, 2)}
+	s.AddRouter(1, router)
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	dp := zpack.NewDataPack()
+	tlvConn, err := net.Dial("tcp", "127.0.0.1:28963")
+	assert.Nil(t, err)
+	defer tlvConn.Close()
+	lineConn, err := net.Dial("tcp", "127.0.0.1:28962")
+	assert.Nil(t, err)
+	defer lineConn.Close()
+
+	data, _ := dp.Pack(zpack.NewMsgPackage(1, []byte("tlv")))
+	_, _ = tlvConn.Write(data)
+	_, _ = lineConn.Write([]byte("line\r\n"))
+
+	// 两个端口的连接各自按照自己的封包方式收到回复
+	_ = tlvConn.SetReadDeadline(time.Now().Add(time.Second))
+	head := make([]byte, dp.GetHeadLen())
+	_, err = io.ReadFull(tlvConn, head)
+	assert.Nil(t, err)
+	msg, _ := dp.Unpack(head)
+	body := make([]byte, msg.GetDataLen())
+	_, err = io.ReadFull(tlvConn, body)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong:tlv", string(body))
+
+	_ = lineConn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(lineConn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "pong:line\n", line)
+
+	// 连接ID在全部监听端口之间唯一
+	connIDs := []uint64{<-router.connIDs, <-router.connIDs}
+	assert.NotEqual(t, connIDs[0], connIDs[1])
+	assert.Equal(t, 2, s.GetConnMgr().Len())
+	for _, connID := range connIDs {
+		_, err := s.GetConnMgr().Get(connID)
+		assert.Nil(t, err)
+	}
+}
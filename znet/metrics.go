@@ -0,0 +1,98 @@
+package znet
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// 消息没有被处理的原因
+const (
+	dropNotFound = "notfound"
+	dropLimited  = "limited"
+	dropOverflow = "overflow"
+)
+
+// SetMetrics 设置指标接口, 读取、处理、丢弃消息以及任务排队时调用, 为nil时不调用
+func (mh *MsgHandle) SetMetrics(metrics ziface.IMetrics) {
+	mh.metrics = metrics
+}
+
+func msgTags(msgID uint32) map[string]string {
+	return map[string]string{"msgID": strconv.FormatUint(uint64(msgID), 10)}
+}
+
+// countReceived 记录读取的消息
+func (mh *MsgHandle) countReceived(request ziface.IRequest) {
+	if mh.metrics == nil {
+		return
+	}
+	tags := msgTags(request.GetMsgID())
+	mh.metrics.Counter(ziface.MetricMsgReceived, 1, tags)
+	mh.metrics.Counter(ziface.MetricMsgBytes, float64(len(request.GetData())), tags)
+}
+
+// countDropped 记录没有被处理的消息
+func (mh *MsgHandle) countDropped(msgID uint32, reason string) {
+	if mh.metrics == nil {
+		return
+	}
+	tags := msgTags(msgID)
+	tags["reason"] = reason
+	mh.metrics.Counter(ziface.MetricMsgDropped, 1, tags)
+}
+
+// observeHandled 记录处理完成的消息与端到端延迟
+func (mh *MsgHandle) observeHandled(msgID uint32, latency time.Duration, err error) {
+	if mh.metrics == nil {
+		return
+	}
+	tags := msgTags(msgID)
+	mh.metrics.Histogram(ziface.MetricMsgLatency, latency.Seconds(), tags)
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	tags = msgTags(msgID)
+	tags["result"] = result
+	mh.metrics.Counter(ziface.MetricMsgHandled, 1, tags)
+}
+
+// observeWait 记录任务在Worker任务队列中等待的时间
+func (mh *MsgHandle) observeWait(wait time.Duration) {
+	if mh.metrics == nil {
+		return
+	}
+	mh.metrics.Histogram(ziface.MetricWorkerWait, wait.Seconds(), nil)
+}
+
+// SetMetrics 设置指标接口, 在连接建立与关闭、监听端口出错以及读取、处理、丢弃消息时调用, 可以接入任意的指标系统
+// 需在Start之前调用, 为nil时不调用
+func (s *Server) SetMetrics(metrics ziface.IMetrics) {
+	s.msgHandler.SetMetrics(metrics)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.metricsSub != 0 {
+		s.eventBus.Unsubscribe(s.metricsSub)
+		s.metricsSub = 0
+	}
+	if metrics == nil {
+		return
+	}
+
+	s.metricsSub = s.eventBus.Subscribe(func(event ziface.Event) {
+		switch event.Type {
+		case ziface.EventConnOpened:
+			metrics.Counter(ziface.MetricConnOpened, 1, nil)
+		case ziface.EventConnClosed:
+			metrics.Counter(ziface.MetricConnClosed, 1, nil)
+		case ziface.EventListenerError:
+			metrics.Counter(ziface.MetricListenerErrs, 1, nil)
+			return
+		}
+		metrics.Gauge(ziface.MetricConnections, float64(s.ConnMgr.Len()), nil)
+	}, ziface.EventConnOpened, ziface.EventConnClosed, ziface.EventListenerError)
+}
@@ -0,0 +1,94 @@
+package znet
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordMetrics 记录每个指标(名称与标签)的累计值、最新值或观测次数
+type recordMetrics struct {
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+func newRecordMetrics() *recordMetrics {
+	return &recordMetrics{values: make(map[string]float64)}
+}
+
+func metricKey(name string, tags map[string]string) string {
+	key := name
+	for _, k := range []string{"msgID", "result", "reason"} {
+		if v, ok := tags[k]; ok {
+			key += "," + k + "=" + v
+		}
+	}
+	return key
+}
+
+func (m *recordMetrics) Counter(name string, value float64, tags map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[metricKey(name, tags)] += value
+}
+
+func (m *recordMetrics) Gauge(name string, value float64, tags map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[metricKey(name, tags)] = value
+}
+
+func (m *recordMetrics) Histogram(name string, value float64, tags map[string]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[metricKey(name, tags)]++
+}
+
+func (m *recordMetrics) get(key string) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.values[key]
+}
+
+func TestMsgHandleMetrics(t *testing.T) {
+	metrics := newRecordMetrics()
+	mh := NewMsgHandle()
+	mh.SetMetrics(metrics)
+	mh.AddRouter(1, &BaseRouter{})
+	mh.AddRouter(2, &failRouter{})
+
+	request := NewRequest(nil, zpack.NewMsgPackage(1, []byte("hello")))
+	mh.countReceived(request)
+	mh.doMsgHandler(request)
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(3, nil)))
+
+	assert.Equal(t, float64(1), metrics.get("zinx.msg.received,msgID=1"))
+	assert.Equal(t, float64(5), metrics.get("zinx.msg.bytes,msgID=1"))
+	assert.Equal(t, float64(1), metrics.get("zinx.msg.handled,msgID=1,result=ok"))
+	assert.Equal(t, float64(1), metrics.get("zinx.msg.latency,msgID=1"))
+	assert.Equal(t, float64(1), metrics.get("zinx.msg.handled,msgID=2,result=error"))
+	assert.Equal(t, float64(1), metrics.get("zinx.msg.dropped,msgID=3,reason=notfound"))
+}
+
+func TestServerMetrics(t *testing.T) {
+	metrics := newRecordMetrics()
+	s := NewServer(WithMetrics(metrics)).(*Server)
+
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventConnOpened})
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventConnOpened})
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventConnClosed})
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventListenerError})
+	assert.Equal(t, float64(2), metrics.get(ziface.MetricConnOpened))
+	assert.Equal(t, float64(1), metrics.get(ziface.MetricConnClosed))
+	assert.Equal(t, float64(1), metrics.get(ziface.MetricListenerErrs))
+	assert.Equal(t, float64(0), metrics.get(ziface.MetricConnections))
+
+	// 取消后不再调用
+	s.SetMetrics(nil)
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventConnOpened})
+	assert.Equal(t, float64(2), metrics.get(ziface.MetricConnOpened))
+}
@@ -0,0 +1,61 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordRouter struct {
+	BaseRouter
+	calls *[]string
+}
+
+func (r *recordRouter) PreHandle(request ziface.IRequest) {
+	*r.calls = append(*r.calls, "pre")
+}
+
+func (r *recordRouter) Handle(request ziface.IRequest) {
+	*r.calls = append(*r.calls, "handle")
+}
+
+func (r *recordRouter) PostHandle(request ziface.IRequest) {
+	*r.calls = append(*r.calls, "post")
+}
+
+func recordMiddleware(calls *[]string, name string) ziface.Middleware {
+	return func(next ziface.HandlerFunc) ziface.HandlerFunc {
+		return func(request ziface.IRequest) {
+			*calls = append(*calls, name+" before")
+			next(request)
+			*calls = append(*calls, name+" after")
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	mh := NewMsgHandle()
+	mh.Use(recordMiddleware(&calls, "global1"), recordMiddleware(&calls, "global2"))
+
+	router := &recordRouter{calls: &calls}
+	router.Use(recordMiddleware(&calls, "route"))
+	mh.AddRouter(1, router)
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, []string{
+		"global1 before", "global2 before", "route before",
+		"pre", "handle", "post",
+		"route after", "global2 after", "global1 after",
+	}, calls)
+
+	// 中间件不调用next时请求不交给路由处理
+	calls = nil
+	mh.Use(func(next ziface.HandlerFunc) ziface.HandlerFunc {
+		return func(request ziface.IRequest) {}
+	})
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, []string{"global1 before", "global2 before", "global2 after", "global1 after"}, calls)
+}
@@ -1,8 +1,13 @@
 package znet
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
 
 	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
@@ -10,12 +15,53 @@ import (
 	"github.com/aceld/zinx/zlog"
 )
 
+// ErrHandlerTimeout 路由处理超过路由设置的期限
+var ErrHandlerTimeout = errors.New("handler timeout")
+
 // MsgHandle 对消息的处理回调模块
 type MsgHandle struct {
 	Apis           map[uint32]ziface.IRouter // 存放每个MsgID 所对应的处理方法的map属性
 	WorkerPoolSize uint32                    // 业务工作Worker池的数量
-	TaskQueue      []chan ziface.IRequest    // Worker负责取任务的消息队列
+	pool           ziface.IWorkerPool        // Worker工作池, 可以由多个Server共享
 	builder        ziface.IBuilder           // 责任链构造器
+	inFlight       int64                     // 已分发但尚未处理完成的请求数量
+	middlewares    []ziface.Middleware       // 全局中间件, 作用于全部路由
+	groups         []*RouterGroup            // 路由分组, 分组的中间件作用于区间内的全部消息ID
+	defaultRouter  ziface.IRouter            // 没有匹配到消息ID时使用的默认路由
+	routeFunc      ziface.RouteFunc          // 根据请求内容计算实际路由的消息ID
+	recovery       ziface.RecoverHandler     // 路由处理请求发生panic时的恢复处理
+	replyMsgIDs    map[uint32]uint32         // 请求消息ID对应的回复消息ID
+
+	// 发布Worker任务队列已满事件, 每个Worker的队列从已满恢复到半满以下后才会再次发布
+	eventBus  ziface.IEventBus
+	saturated []int32
+
+	// 绑定了独立Worker工作池的消息ID
+	routePools map[uint32]ziface.IWorkerPool
+	// 消息在Worker任务队列中的优先级, 未设置的为普通优先级
+	priorities map[uint32]ziface.MsgPriority
+	// Worker工作池统计
+	stats *workerStats
+	// 处理失败的消息的死信处理
+	deadLetter ziface.DeadLetterConfig
+	// 路由处理时间超过该值时记录日志并发布SlowHandler事件, 0不检测
+	slowThreshold time.Duration
+	// 消息ID同时处理的请求数量上限, 每个消息ID一个信号量
+	limits map[uint32]chan struct{}
+	// 路由处理超时后的处理, 如回复超时消息
+	timeoutHandler ziface.HandlerFunc
+	// Worker选择策略
+	dispatcher ziface.IDispatcher
+	// Worker任务队列已满时的处理策略
+	overflowConfig ziface.OverflowConfig
+	// 每个路由的处理统计
+	routeStats *routeStats
+	// 访问日志, 为nil时不输出
+	accessLog *accessLog
+	// 指标接口, 为nil时不调用
+	metrics ziface.IMetrics
+	// 处理时间最长的请求, 为nil时不记录
+	slowLog *SlowLog
 }
 
 // NewMsgHandle 创建MsgHandle
@@ -24,26 +70,58 @@ func NewMsgHandle() *MsgHandle {
 		Apis:           make(map[uint32]ziface.IRouter),
 		WorkerPoolSize: zconf.GlobalObject.WorkerPoolSize,
 		// 一个worker对应一个queue
-		TaskQueue: make([]chan ziface.IRequest, zconf.GlobalObject.WorkerPoolSize),
-		builder:   zinterceptor.NewBuilder(),
+		pool:    newConfWorkerPool(),
+		builder: zinterceptor.NewBuilder(),
+		stats:   &workerStats{},
+
+		routeStats: &routeStats{},
+
+		slowThreshold: time.Duration(zconf.GlobalObject.SlowHandlerTime) * time.Millisecond,
+		dispatcher:    NewDispatcher(zconf.GlobalObject.WorkerDispatchMode),
 	}
+	handle.SetSlowLog(zconf.GlobalObject.SlowLogSize, zconf.GlobalObject.SlowLogPayload)
 	// 此处必须把 msghandler 添加到责任链中，并且是责任链最后一环，在msghandler中进行解码后由router做数据分发
 	handle.builder.Tail(handle)
 	return handle
 }
 
+// newConfWorkerPool 按照配置创建Worker工作池, WorkerPoolMaxSize大于WorkerPoolSize时创建自动伸缩的工作池,
+// 否则WorkerStealing为true时创建支持任务窃取的工作池
+func newConfWorkerPool() ziface.IWorkerPool {
+	conf := zconf.GlobalObject
+	if conf.WorkerPoolSize == 0 || conf.WorkerPoolMaxSize <= conf.WorkerPoolSize {
+		if conf.WorkerStealing && conf.WorkerPoolSize > 0 {
+			return NewStealingWorkerPool(conf.WorkerPoolSize, conf.MaxWorkerTaskLen)
+		}
+		return NewWorkerPool(conf.WorkerPoolSize, conf.MaxWorkerTaskLen)
+	}
+	return NewScalingWorkerPool(ziface.WorkerScaleConfig{
+		MinSize:       conf.WorkerPoolSize,
+		MaxSize:       conf.WorkerPoolMaxSize,
+		WaitThreshold: time.Duration(conf.WorkerScaleWaitTime) * time.Millisecond,
+	}, conf.MaxWorkerTaskLen)
+}
+
 func (mh *MsgHandle) Intercept(chain ziface.IChain) ziface.IcResp {
 	request := chain.Request()
 	if request != nil {
 		switch request.(type) {
 		case ziface.IRequest:
 			iRequest := request.(ziface.IRequest)
-			if zconf.GlobalObject.WorkerPoolSize > 0 {
+			// 对端对本端请求的回复直接交给等待方, 不进行路由, 逻辑会话的关联序号与连接的关联序号相互独立
+			if r, ok := iRequest.GetConnection().(seqResolver); ok && iRequest.GetSessionID() == 0 && r.resolveSeq(iRequest.GetMessage()) {
+				break
+			}
+			mh.countReceived(iRequest)
+			// 每个请求只计算一次实际路由的消息ID
+			msgID := mh.routeID(iRequest)
+			if mh.routePools[msgID] != nil || (mh.WorkerPoolSize > 0 && mh.pool.Size() > 0) {
 				// 已经启动工作池机制，将消息交给Worker处理
-				mh.SendMsgToTaskQueue(iRequest)
+				mh.sendMsgToTaskQueue(iRequest, msgID)
 			} else {
 				// 从绑定好的消息和对应的处理方法中执行对应的Handle方法
-				go mh.doMsgHandler(iRequest)
+				atomic.AddInt64(&mh.inFlight, 1)
+				go mh.handleMsg(iRequest, msgID)
 			}
 		}
 	}
@@ -56,37 +134,515 @@ func (mh *MsgHandle) AddInterceptor(interceptor ziface.IInterceptor) {
 	}
 }
 
+// AddNamedInterceptor 添加带名称的拦截器，可在运行时通过名称删除或替换
+func (mh *MsgHandle) AddNamedInterceptor(name string, interceptor ziface.IInterceptor) {
+	mh.builder.AddNamedInterceptor(name, interceptor)
+}
+
+func (mh *MsgHandle) RemoveInterceptor(name string) bool {
+	return mh.builder.RemoveInterceptor(name)
+}
+
+func (mh *MsgHandle) ReplaceInterceptor(name string, interceptor ziface.IInterceptor) bool {
+	return mh.builder.ReplaceInterceptor(name, interceptor)
+}
+
+func (mh *MsgHandle) InterceptorNames() []string {
+	return mh.builder.InterceptorNames()
+}
+
 // SendMsgToTaskQueue 将消息交给TaskQueue,由worker进行处理
 func (mh *MsgHandle) SendMsgToTaskQueue(request ziface.IRequest) {
-	// 根据ConnID来分配当前的连接应该由哪个worker负责处理
-	// 轮询的平均分配法则
-
-	// 得到需要处理此条连接的workerID
-	workerID := request.GetConnection().GetConnID() % uint64(mh.WorkerPoolSize)
-	// zlog.Ins().DebugF("Add ConnID=%d request msgID=%d to workerID=%d", request.GetConnection().GetConnID(), request.GetMsgID(), workerID)
-	// 将请求消息发送给任务队列
-	mh.TaskQueue[workerID] <- request
+	mh.sendMsgToTaskQueue(request, mh.routeID(request))
+}
+
+// sendMsgToTaskQueue 按实际路由的消息ID将请求交给TaskQueue
+func (mh *MsgHandle) sendMsgToTaskQueue(request ziface.IRequest, msgID uint32) {
+	// 将请求消息发送给任务队列, 绑定了独立工作池的消息交给独立的工作池处理
+	atomic.AddInt64(&mh.inFlight, 1)
+	atomic.AddUint64(&mh.stats.submitted, 1)
+	enqueued := time.Now()
+	pool := mh.routePools[msgID]
+	if pool == nil {
+		pool = mh.pool
+	}
+
+	// 根据Worker选择策略(默认按ConnID)分配当前的请求应该由哪个worker负责处理
+	key := mh.dispatcher.Dispatch(request, pool)
+	// zlog.Ins().DebugF("Add ConnID=%d request msgID=%d to key=%d", request.GetConnection().GetConnID(), request.GetMsgID(), key)
+	if pool == mh.pool {
+		mh.checkSaturated(uint32(key % uint64(mh.pool.Size())))
+	}
+	priority := mh.priorities[msgID]
+	task := func() {
+		start := time.Now()
+		mh.observeWait(start.Sub(enqueued))
+		mh.handleMsg(request, msgID)
+		mh.stats.observe(start.Sub(enqueued), time.Since(start))
+	}
+	if !pool.TrySubmitPriority(key, priority, task) {
+		mh.overflow(request, msgID, func() {
+			pool.SubmitPriority(key, priority, task)
+		})
+	}
 	zlog.Ins().DebugF("SendMsgToTaskQueue-->%s", hex.EncodeToString(request.GetData()))
 }
 
+// overflow 按配置的策略处理任务队列已满时的请求
+func (mh *MsgHandle) overflow(request ziface.IRequest, msgID uint32, submit func()) {
+	switch mh.overflowConfig.Policy {
+	case ziface.OverflowDrop, ziface.OverflowCallback:
+	default:
+		atomic.AddUint64(&mh.stats.overflowBlocked, 1)
+		submit()
+		return
+	}
+
+	// 请求不会被处理
+	atomic.AddInt64(&mh.inFlight, -1)
+	atomic.AddUint64(&mh.stats.submitted, ^uint64(0))
+
+	conf := mh.overflowConfig
+	if conf.Policy == ziface.OverflowDrop || conf.Handler == nil {
+		atomic.AddUint64(&mh.stats.overflowDropped, 1)
+		mh.countDropped(msgID, dropOverflow)
+		zlog.Ins().ErrorF("worker queue is full, request msgID = %d is dropped", request.GetMsgID())
+		if conf.BusyMsgID != 0 {
+			if err := request.GetConnection().SendMsg(conf.BusyMsgID, conf.BusyData); err != nil {
+				zlog.Ins().ErrorF("send busy msg error: %v", err)
+			}
+		}
+		return
+	}
+
+	atomic.AddUint64(&mh.stats.overflowCallbacks, 1)
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Ins().ErrorF("overload handler panic: %v", r)
+		}
+	}()
+	conf.Handler(request)
+}
+
+// SetOverflowPolicy 设置Worker任务队列已满时的处理策略, 默认阻塞, 需在服务启动之前调用
+func (mh *MsgHandle) SetOverflowPolicy(config ziface.OverflowConfig) {
+	mh.overflowConfig = config
+}
+
+// QueueUsage Worker任务队列(包括路由独立的工作池)的最高使用率, 未启用工作池时为0
+func (mh *MsgHandle) QueueUsage() float64 {
+	usage := mh.pool.QueueUsage()
+	for _, pool := range mh.routePools {
+		if u := pool.QueueUsage(); u > usage {
+			usage = u
+		}
+	}
+	return usage
+}
+
+// SetRoutePool 为消息ID绑定独立的Worker工作池, 耗时的路由不会占满共用的工作池, 需在服务启动之前调用
+// 消息ID为路由函数计算后的消息ID, 多个消息ID可以绑定同一个工作池
+func (mh *MsgHandle) SetRoutePool(pool ziface.IWorkerPool, msgIDs ...uint32) {
+	if mh.routePools == nil {
+		mh.routePools = make(map[uint32]ziface.IWorkerPool)
+	}
+	for _, msgID := range msgIDs {
+		mh.routePools[msgID] = pool
+	}
+}
+
+// SetMsgPriority 设置消息ID在Worker任务队列中的优先级, 高优先级的消息先于普通消息被处理, 需在服务启动之前调用
+func (mh *MsgHandle) SetMsgPriority(priority ziface.MsgPriority, msgIDs ...uint32) {
+	if mh.priorities == nil {
+		mh.priorities = make(map[uint32]ziface.MsgPriority)
+	}
+	for _, msgID := range msgIDs {
+		mh.priorities[msgID] = priority
+	}
+}
+
+// routeID 请求实际路由的消息ID
+func (mh *MsgHandle) routeID(request ziface.IRequest) uint32 {
+	if mh.routeFunc != nil {
+		return mh.routeFunc(request)
+	}
+	return request.GetMsgID()
+}
+
+// checkSaturated 检查Worker的任务队列是否已满
+func (mh *MsgHandle) checkSaturated(workerID uint32) {
+	if mh.eventBus == nil || int(workerID) >= len(mh.saturated) {
+		return
+	}
+
+	length, capacity := mh.pool.QueueLen(workerID)
+	if length >= capacity {
+		if atomic.CompareAndSwapInt32(&mh.saturated[workerID], 0, 1) {
+			mh.eventBus.Publish(ziface.Event{Type: ziface.EventWorkerQueueSaturated, WorkerID: workerID})
+		}
+	} else if length < capacity/2 {
+		atomic.StoreInt32(&mh.saturated[workerID], 0)
+	}
+}
+
+// SetEventBus 需在服务启动之前调用
+func (mh *MsgHandle) SetEventBus(bus ziface.IEventBus) {
+	mh.eventBus = bus
+	mh.saturated = make([]int32, mh.pool.Size())
+}
+
+// SetWorkerPool 使用外部的Worker工作池(如多个Server共享), 需在服务启动之前调用
+func (mh *MsgHandle) SetWorkerPool(pool ziface.IWorkerPool) {
+	mh.pool = pool
+	mh.WorkerPoolSize = pool.Size()
+	mh.saturated = make([]int32, pool.Size())
+}
+
+func (mh *MsgHandle) GetWorkerPool() ziface.IWorkerPool {
+	return mh.pool
+}
+
 // DoMsgHandler 马上以非阻塞方式处理消息
 func (mh *MsgHandle) doMsgHandler(request ziface.IRequest) {
+	mh.handleMsg(request, mh.routeID(request))
+}
+
+// handleMsg 按已经计算好的实际路由消息ID处理请求
+func (mh *MsgHandle) handleMsg(request ziface.IRequest, msgID uint32) {
+	// 请求处理结束时释放并发名额与处理中的计数
+	// 路由超时后仍在执行时, 由执行路由的Goroutine在路由真正退出后释放
+	var (
+		sem      chan struct{}
+		detached bool
+	)
+	release := func() {
+		if sem != nil {
+			<-sem
+		}
+		atomic.AddInt64(&mh.inFlight, -1)
+	}
+	defer func() {
+		if !detached {
+			release()
+		}
+	}()
+
+	var handler ziface.IRouter
 	defer func() {
 		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("doMsgHandler panic: %v", err)
+			mh.recoverPanic(handler, request, err)
 		}
 	}()
 
-	handler, ok := mh.Apis[request.GetMsgID()]
+	checkPong(msgID, request)
+
+	handler, ok := mh.Apis[msgID]
 	if !ok {
-		zlog.Ins().ErrorF("api msgID = %d is not FOUND!", request.GetMsgID())
+		if mh.defaultRouter == nil {
+			atomic.AddUint64(&mh.stats.dropped, 1)
+			mh.countDropped(msgID, dropNotFound)
+			zlog.Ins().ErrorF("api msgID = %d is not FOUND!", msgID)
+			return
+		}
+		// 交给默认路由处理原始请求
+		handler = mh.defaultRouter
+	}
+
+	// 同时处理的请求达到上限时丢弃请求
+	if limit, ok := mh.limits[msgID]; ok {
+		select {
+		case limit <- struct{}{}:
+			sem = limit
+		default:
+			atomic.AddUint64(&mh.stats.limited, 1)
+			mh.countDropped(msgID, dropLimited)
+			zlog.Ins().ErrorF("api msgID = %d reached concurrency limit %d, request is dropped", msgID, cap(limit))
+			return
+		}
+	}
+
+	// 绑定回复消息ID
+	if replyID, ok := mh.replyMsgIDs[msgID]; ok {
+		if r, ok := request.(*Request); ok {
+			r.setReplyMsgID(replyID)
+		}
+	}
+
+	// 路由设置了处理期限时, 超时后取消请求的Context
+	var timeout bool
+	if r, ok := handler.(ziface.ITimeoutRouter); ok && r.Timeout() > 0 {
+		ctx, cancel := context.WithTimeout(request.Context(), r.Timeout())
+		defer cancel()
+		request.SetContext(ctx)
+		timeout = true
+	}
+
+	// 记录请求的端到端延迟、访问日志与指标
+	var (
+		attempts int
+		err      error
+	)
+	if r, ok := request.(*Request); ok {
+		defer func() {
+			latency := time.Since(r.received)
+			mh.routeStats.observeLatency(msgID, latency)
+			mh.observeHandled(msgID, latency, err)
+			mh.accessLog.log(request, msgID, r.received, attempts, err)
+		}()
+	}
+
+	h := mh.handlerChain(msgID, handler)
+	for attempts = 1; ; attempts++ {
+		start := time.Now()
+		if timeout {
+			detached, err = mh.callHandlerTimeout(h, handler, request, msgID, release)
+		} else {
+			err = mh.callHandler(h, handler, request)
+		}
+		elapsed := time.Since(start)
+		mh.routeStats.observe(msgID, elapsed, err != nil)
+		if mh.slowThreshold > 0 && elapsed > mh.slowThreshold {
+			mh.reportSlow(request, msgID, elapsed)
+		}
+		if mh.slowLog != nil {
+			mh.slowLog.Record(request, msgID, elapsed)
+		}
+		if err == nil || mh.deadLetter.Handler == nil {
+			return
+		}
+		// 超时的路由仍在执行, 不再重试
+		if attempts >= mh.deadLetter.MaxAttempts || err == ErrHandlerTimeout {
+			mh.sendDeadLetter(request, err, attempts)
+			return
+		}
+		// 重试之前重置请求的处理状态
+		if r, ok := request.(*Request); ok {
+			r.reset()
+		}
+	}
+}
+
+// callHandler 执行一次路由处理, 返回路由设置的错误, 发生panic时返回panic的内容
+func (mh *MsgHandle) callHandler(h ziface.HandlerFunc, router ziface.IRouter, request ziface.IRequest) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			mh.recoverPanic(router, request, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	h(request)
+	return request.GetError()
+}
+
+// reportSlow 记录处理时间超过阈值的路由
+func (mh *MsgHandle) reportSlow(request ziface.IRequest, msgID uint32, elapsed time.Duration) {
+	atomic.AddUint64(&mh.stats.slow, 1)
+
+	var connID uint64
+	conn := request.GetConnection()
+	if conn != nil {
+		connID = conn.GetConnID()
+	}
+	zlog.Ins().ErrorF("slow handler msgID = %d, connID = %d, elapsed = %s", msgID, connID, elapsed)
+
+	if mh.eventBus != nil {
+		mh.eventBus.Publish(ziface.Event{Type: ziface.EventSlowHandler, Conn: conn, MsgID: msgID, Elapsed: elapsed})
+	}
+}
+
+// SetConcurrencyLimit 设置消息ID同时处理的请求数量上限, 达到上限后到达的请求被丢弃, limit<=0时取消限制
+// 每个消息ID单独计数, 需在服务启动之前调用
+func (mh *MsgHandle) SetConcurrencyLimit(limit int, msgIDs ...uint32) {
+	if mh.limits == nil {
+		mh.limits = make(map[uint32]chan struct{})
+	}
+	for _, msgID := range msgIDs {
+		if limit <= 0 {
+			delete(mh.limits, msgID)
+			continue
+		}
+		mh.limits[msgID] = make(chan struct{}, limit)
+	}
+}
+
+// SetDispatcher 设置Worker选择策略, 需在服务启动之前调用
+func (mh *MsgHandle) SetDispatcher(dispatcher ziface.IDispatcher) {
+	mh.dispatcher = dispatcher
+}
+
+// SetSlowThreshold 设置慢处理阈值, 路由处理时间超过该值时记录日志并发布SlowHandler事件, 0不检测
+func (mh *MsgHandle) SetSlowThreshold(threshold time.Duration) {
+	mh.slowThreshold = threshold
+}
+
+// callHandlerTimeout 在独立的Goroutine中执行路由处理, 请求的Context超时后不再等待,
+// 调用超时处理并返回ErrHandlerTimeout, 避免阻塞的下游调用使Worker永远无法处理其他请求
+// 超时后detached为true, 路由仍在执行, 由执行路由的Goroutine在路由退出后调用release释放并发名额与处理中的计数
+func (mh *MsgHandle) callHandlerTimeout(h ziface.HandlerFunc, router ziface.IRouter, request ziface.IRequest, msgID uint32, release func()) (detached bool, err error) {
+	const (
+		running int32 = iota
+		finished
+		abandoned
+	)
+	var state int32
+	ctx := request.Context()
+	done := make(chan error, 1)
+	go func() {
+		done <- mh.callHandler(h, router, request)
+		if !atomic.CompareAndSwapInt32(&state, running, finished) {
+			release()
+		}
+	}()
+
+	select {
+	case err := <-done:
+		return false, err
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			// 连接关闭导致的取消, 等待路由自行退出
+			return false, <-done
+		}
+	}
+
+	// 路由恰好在超时的同时退出
+	if !atomic.CompareAndSwapInt32(&state, running, abandoned) {
+		return false, <-done
+	}
+
+	atomic.AddUint64(&mh.stats.timeouts, 1)
+	zlog.Ins().ErrorF("api msgID = %d handler timeout", msgID)
+	if mh.timeoutHandler != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				zlog.Ins().ErrorF("timeout handler panic: %v", r)
+			}
+		}()
+		// 路由仍在使用原请求, 超时处理使用独立的请求回复
+		mh.timeoutHandler(timeoutRequest(request))
+	}
+	return true, ErrHandlerTimeout
+}
+
+// timeoutRequest 为超时处理创建独立的请求, 与原请求使用相同的连接、消息与回复方式, 不共享处理状态
+func timeoutRequest(request ziface.IRequest) ziface.IRequest {
+	r, ok := request.(*Request)
+	if !ok {
+		return NewRequest(request.GetConnection(), request.GetMessage())
+	}
+	req := NewRequest(r.conn, r.msg)
+	req.writer = r.writer
+	req.replyMsgID, req.hasReplyID = r.replyMsgID, r.hasReplyID
+	req.received = r.received
+	return req
+}
+
+// SetTimeoutHandler 设置路由处理超时后的处理, 如通过request.Reply回复超时消息
+func (mh *MsgHandle) SetTimeoutHandler(handler ziface.HandlerFunc) {
+	mh.timeoutHandler = handler
+}
+
+// sendDeadLetter 将处理失败的消息交给死信处理方法
+func (mh *MsgHandle) sendDeadLetter(request ziface.IRequest, err error, attempts int) {
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Ins().ErrorF("dead letter handler panic: %v", r)
+		}
+	}()
+
+	atomic.AddUint64(&mh.stats.deadLetters, 1)
+	mh.deadLetter.Handler(ziface.DeadLetter{
+		Conn:     request.GetConnection(),
+		MsgID:    request.GetMsgID(),
+		Data:     append([]byte(nil), request.GetData()...),
+		Err:      err,
+		Attempts: attempts,
+		Time:     time.Now(),
+	})
+}
+
+// SetDeadLetter 设置死信处理, 路由处理失败(request.SetError或panic)的消息按配置重试, 仍然失败时交给死信处理方法
+func (mh *MsgHandle) SetDeadLetter(config ziface.DeadLetterConfig) {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	mh.deadLetter = config
+}
+
+// SetAccessLog 设置访问日志, 每处理完成一个消息按采样配置输出一条记录, Handler为nil时关闭
+func (mh *MsgHandle) SetAccessLog(config ziface.AccessLogConfig) {
+	if config.Handler == nil {
+		mh.accessLog = nil
 		return
 	}
+	mh.accessLog = newAccessLog(config)
+}
+
+// recoverPanic 路由处理请求发生panic, 优先交给路由的恢复处理, 其次是全局的恢复处理, 都没有设置时只打印调用栈
+func (mh *MsgHandle) recoverPanic(router ziface.IRouter, request ziface.IRequest, recovered interface{}) {
+	stack := debug.Stack()
+	atomic.AddUint64(&mh.stats.panics, 1)
+
+	handler := mh.recovery
+	if r, ok := router.(ziface.IRecoverRouter); ok && r.RecoverHandler() != nil {
+		handler = r.RecoverHandler()
+	}
+	if handler == nil {
+		zlog.Ins().ErrorF("doMsgHandler panic: %v\n%s", recovered, stack)
+		return
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("recover handler panic: %v", err)
+		}
+	}()
+	handler(request.GetConnection(), request, recovered, stack)
+}
 
-	// Request请求绑定Router对应关系
-	request.BindRouter(handler)
-	// 执行对应处理方法
-	request.Call()
+// SetRecoverHandler 设置全局的panic恢复处理, 路由设置了自己的恢复处理时优先使用路由的
+func (mh *MsgHandle) SetRecoverHandler(handler ziface.RecoverHandler) {
+	mh.recovery = handler
+}
+
+// handlerChain 按照全局中间件、路由分组中间件、路由中间件的顺序包裹路由的处理方法
+func (mh *MsgHandle) handlerChain(msgID uint32, router ziface.IRouter) ziface.HandlerFunc {
+	h := func(request ziface.IRequest) {
+		// Request请求绑定Router对应关系
+		request.BindRouter(router)
+		// 执行对应处理方法
+		request.Call()
+	}
+
+	if r, ok := router.(ziface.IMiddlewareRouter); ok {
+		h = chainMiddlewares(r.Middlewares(), h)
+	}
+	for i := len(mh.groups) - 1; i >= 0; i-- {
+		if mh.groups[i].Contains(msgID) {
+			h = chainMiddlewares(mh.groups[i].middlewares, h)
+		}
+	}
+	return chainMiddlewares(mh.middlewares, h)
+}
+
+func chainMiddlewares(middlewares []ziface.Middleware, h ziface.HandlerFunc) ziface.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// Group 创建消息ID区间为[start, end]的路由分组, 需在服务启动之前调用
+// 多个分组的区间重叠时, 消息ID所在的全部分组的中间件按创建顺序由外到内执行
+func (mh *MsgHandle) Group(start, end uint32) ziface.IRouterGroup {
+	group := NewRouterGroup(start, end, mh)
+	mh.groups = append(mh.groups, group)
+	return group
+}
+
+// Use 添加全局中间件, 按添加顺序由外到内执行, 需在服务启动之前调用
+func (mh *MsgHandle) Use(middlewares ...ziface.Middleware) {
+	mh.middlewares = append(mh.middlewares, middlewares...)
 }
 
 func (mh *MsgHandle) Execute(request ziface.IRequest) {
@@ -105,27 +661,35 @@ func (mh *MsgHandle) AddRouter(msgID uint32, router ziface.IRouter) {
 	zlog.Ins().InfoF("Add Router msgID = %d", msgID)
 }
 
-// StartOneWorker 启动一个Worker工作流程
-func (mh *MsgHandle) StartOneWorker(workerID int, taskQueue chan ziface.IRequest) {
-	zlog.Ins().InfoF("Worker ID = %d is started.", workerID)
-	// 不断的等待队列中的消息
-	for {
-		select {
-		// 有消息则取出队列的Request，并执行绑定的业务方法
-		case request := <-taskQueue:
-			mh.doMsgHandler(request)
-		}
+// SetDefaultRouter 设置默认路由, 没有匹配到消息ID的请求交给它处理, 中间件同样生效
+func (mh *MsgHandle) SetDefaultRouter(router ziface.IRouter) {
+	mh.defaultRouter = router
+}
+
+// SetRouteFunc 设置路由函数, 根据请求内容(如消息体中的子命令)计算实际路由的消息ID
+// 路由分组的中间件按计算后的消息ID匹配, 请求本身的消息ID保持不变
+// 同一个请求可能多次调用路由函数, 路由函数不能有副作用
+func (mh *MsgHandle) SetRouteFunc(routeFunc ziface.RouteFunc) {
+	mh.routeFunc = routeFunc
+}
+
+// SetReplyMsgID 设置请求消息ID(路由函数计算后的消息ID)对应的回复消息ID, request.Reply使用该消息ID回复
+func (mh *MsgHandle) SetReplyMsgID(requestID, replyID uint32) {
+	if mh.replyMsgIDs == nil {
+		mh.replyMsgIDs = make(map[uint32]uint32)
 	}
+	mh.replyMsgIDs[requestID] = replyID
 }
 
-// StartWorkerPool 启动worker工作池
+// StartWorkerPool 启动worker工作池(包括路由独立的工作池), 共享的工作池只启动一次
 func (mh *MsgHandle) StartWorkerPool() {
-	// 遍历需要启动worker的数量，依此启动
-	for i := 0; i < int(mh.WorkerPoolSize); i++ {
-		// 一个worker被启动
-		// 给当前worker对应的任务队列开辟空间
-		mh.TaskQueue[i] = make(chan ziface.IRequest, zconf.GlobalObject.MaxWorkerTaskLen)
-		// 启动当前Worker，阻塞的等待对应的任务队列是否有消息传递进来
-		go mh.StartOneWorker(i, mh.TaskQueue[i])
+	mh.pool.Start()
+	for _, pool := range mh.routePools {
+		pool.Start()
 	}
 }
+
+// InFlight 已分发(包括在任务队列中等待)但尚未处理完成的请求数量
+func (mh *MsgHandle) InFlight() int64 {
+	return atomic.LoadInt64(&mh.inFlight)
+}
@@ -0,0 +1,140 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+var ErrMuxSessionClosed = errors.New("mux session closed")
+
+// packMuxMsg 执行发出消息的拦截器后封包属于逻辑会话的消息
+func packMuxMsg(conn ziface.IConnection, packet ziface.IDataPack, out *outChain, sessionID uint32, seq uint32, msgID uint32, data []byte) ([]byte, error) {
+	msg := zpack.NewMsgPackage(msgID, data)
+	msg.SessionID = sessionID
+	msg.Seq = seq
+	return out.pack(conn, packet, msg)
+}
+
+// MuxSession 客户端连接上的逻辑会话, 实现ziface.IMuxSession
+// 客户端重连后逻辑会话继续使用新的连接
+type MuxSession struct {
+	id         uint32
+	client     *Client
+	msgHandler *MsgHandle
+	calls      seqCalls
+	closed     int32
+}
+
+// NewMuxSession 在客户端连接上创建一个逻辑会话, 客户端与服务端需使用支持逻辑会话ID的封包方式与解码器(zpack.MuxDataPack, zdecoder.MuxTLVDecoder)
+// 服务端使用request.Reply回复时回传逻辑会话ID, 可以通过request.GetSessionID区分模拟的用户
+func (c *Client) NewMuxSession() ziface.IMuxSession {
+	s := &MuxSession{
+		id:         atomic.AddUint32(&c.muxID, 1),
+		client:     c,
+		msgHandler: NewMsgHandle(),
+	}
+	// 与客户端一样不使用协程池
+	s.msgHandler.SetWorkerPool(NewWorkerPool(0, 0))
+	c.muxSessions.Store(s.id, s)
+	if atomic.LoadInt32(&c.started) == 1 {
+		c.addMuxDemux()
+	}
+	return s
+}
+
+// addMuxDemux 在解码器之后添加分发逻辑会话消息的拦截器
+func (c *Client) addMuxDemux() {
+	c.muxDemuxOnce.Do(func() {
+		c.msgHandler.AddInterceptor(&muxDemux{client: c})
+	})
+}
+
+func (s *MuxSession) ID() uint32 {
+	return s.id
+}
+
+// AddRouter 添加逻辑会话独立的路由, 与客户端的路由互不影响
+func (s *MuxSession) AddRouter(msgID uint32, router ziface.IRouter) {
+	s.msgHandler.AddRouter(msgID, router)
+}
+
+func (s *MuxSession) send(seq uint32, msgID uint32, data []byte) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return ErrMuxSessionClosed
+	}
+	conn := s.client.Conn()
+	if conn == nil {
+		return ErrClientNotConnected
+	}
+	if w, ok := conn.(ziface.IMuxWriter); ok {
+		return w.SendMuxMsg(s.id, seq, msgID, data)
+	}
+	return conn.SendSeqMsg(seq, msgID, data)
+}
+
+func (s *MuxSession) SendMsg(msgID uint32, data []byte) error {
+	return s.send(0, msgID, data)
+}
+
+func (s *MuxSession) SendSeqMsg(seq uint32, msgID uint32, data []byte) error {
+	return s.send(seq, msgID, data)
+}
+
+// Call 发送请求并同步等待服务端回复, 关联序号在逻辑会话内分配
+func (s *MuxSession) Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) {
+	conn := s.client.Conn()
+	if conn == nil {
+		return nil, ErrClientNotConnected
+	}
+	return s.calls.call(ctx, conn.Context(), func(seq uint32) error {
+		return s.send(seq, msgID, data)
+	})
+}
+
+// Close 关闭逻辑会话, 之后收到的属于该逻辑会话的消息被丢弃
+func (s *MuxSession) Close() {
+	if atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		s.client.muxSessions.Delete(s.id)
+	}
+}
+
+// dispatch 处理属于逻辑会话的消息, 回复交给等待方, 其他消息交给逻辑会话的路由
+func (s *MuxSession) dispatch(request ziface.IRequest) {
+	if s.calls.resolve(request.GetMessage()) {
+		return
+	}
+	if seqMsg, ok := request.GetMessage().(ziface.ISeqMessage); ok && seqMsg.GetSeq()&ziface.SeqReplyFlag != 0 {
+		// 等待方已经超时, 丢弃迟到的回复
+		return
+	}
+	request.SetResponseWriter(s)
+	s.msgHandler.Execute(request)
+}
+
+// muxDemux 客户端解码之后的拦截器, 将属于逻辑会话的消息分发给逻辑会话
+type muxDemux struct {
+	client *Client
+}
+
+func (d *muxDemux) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok {
+		return chain.Proceed(chain.Request())
+	}
+	sessionID := request.GetSessionID()
+	if sessionID == 0 {
+		return chain.Proceed(chain.Request())
+	}
+
+	if s, ok := d.client.muxSessions.Load(sessionID); ok {
+		s.(*MuxSession).dispatch(request)
+	} else {
+		zlog.Ins().DebugF("mux session %d not found, drop msgID = %d", sessionID, request.GetMsgID())
+	}
+	return nil
+}
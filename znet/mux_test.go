@@ -0,0 +1,87 @@
+package znet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// muxUserRouter 服务端按逻辑会话区分模拟的用户
+type muxUserRouter struct {
+	BaseRouter
+}
+
+func (r *muxUserRouter) Handle(request ziface.IRequest) {
+	reply := []byte(fmt.Sprintf("user%d:%s", request.GetSessionID(), request.GetData()))
+	if request.GetMsgID() == 2 {
+		_ = request.Reply(reply)
+	} else {
+		_ = request.ReplyWith(4, reply)
+	}
+}
+
+type muxPushRouter struct {
+	BaseRouter
+	ch chan string
+}
+
+func (r *muxPushRouter) Handle(request ziface.IRequest) {
+	r.ch <- string(request.GetData())
+}
+
+func TestClientMuxSession(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewMuxDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28981
+	s.SetDecoder(zdecoder.NewMuxTLVDecoder())
+	s.AddRouter(2, &muxUserRouter{})
+	s.AddRouter(3, &muxUserRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewClient("127.0.0.1", 28981, WithPacketClient(zpack.NewMuxDataPack()))
+	client.SetDecoder(zdecoder.NewMuxTLVDecoder())
+	sessions := []ziface.IMuxSession{client.NewMuxSession(), client.NewMuxSession()}
+	pushes := make([]chan string, len(sessions))
+	for i, session := range sessions {
+		pushes[i] = make(chan string, 1)
+		session.AddRouter(4, &muxPushRouter{ch: pushes[i]})
+	}
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	// Start之后也可以创建逻辑会话
+	sessions = append(sessions, client.NewMuxSession())
+	pushes = append(pushes, make(chan string, 1))
+	sessions[2].AddRouter(4, &muxPushRouter{ch: pushes[2]})
+
+	// 每个逻辑会话的关联序号独立分配, 回复回到发出请求的逻辑会话
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, session := range sessions {
+		data, err := session.Call(ctx, 2, []byte("hi"))
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("user%d:hi", session.ID()), string(data))
+	}
+
+	// 服务端的消息交给逻辑会话自己的路由
+	assert.Nil(t, sessions[1].SendMsg(3, []byte("push")))
+	select {
+	case data := <-pushes[1]:
+		assert.Equal(t, fmt.Sprintf("user%d:push", sessions[1].ID()), data)
+	case <-time.After(time.Second):
+		t.Fatal("mux session push is not received")
+	}
+	assert.Equal(t, 0, len(pushes[0])+len(pushes[2]))
+
+	sessions[2].Close()
+	assert.Equal(t, ErrMuxSessionClosed, sessions[2].SendMsg(3, nil))
+}
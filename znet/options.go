@@ -1,6 +1,13 @@
 package znet
 
-import "github.com/aceld/zinx/ziface"
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
 
 //Server的服务Option
 type Option func(s *Server)
@@ -12,6 +19,27 @@ func WithPacket(pack ziface.IDataPack) Option {
 	}
 }
 
+// TCP端口上收到的普通HTTP请求没有匹配的处理方法时，重定向到指定地址
+func WithHTTPRedirect(url string) Option {
+	return func(s *Server) {
+		s.httpRedirect = url
+	}
+}
+
+// 启用协议版本协商, 每个连接按照协商后的版本选择封包方式与解码器
+func WithVersionNegotiator(negotiator ziface.IVersionNegotiator) Option {
+	return func(s *Server) {
+		s.SetVersionNegotiator(negotiator)
+	}
+}
+
+// 添加附加的监听端口, 该端口使用独立的封包方式与解码器
+func WithListener(config ziface.ListenerConfig) Option {
+	return func(s *Server) {
+		s.AddListener(config)
+	}
+}
+
 //Client的客户端Option
 type ClientOption func(c ziface.IClient)
 
@@ -21,3 +49,356 @@ func WithPacketClient(pack ziface.IDataPack) ClientOption {
 		c.SetPacket(pack)
 	}
 }
+
+// 限制每个连接的读写带宽
+func WithConnRateLimit(limit ziface.RateLimit) Option {
+	return func(s *Server) {
+		s.SetConnRateLimit(limit)
+	}
+}
+
+// 限制全部连接共享的读写带宽
+func WithServerRateLimit(limit ziface.RateLimit) Option {
+	return func(s *Server) {
+		s.SetServerRateLimit(limit)
+	}
+}
+
+// 设置发送缓冲队列已满时的处理策略
+func WithSendBuffPolicy(config ziface.SendBuffConfig) Option {
+	return func(s *Server) {
+		s.SetSendBuffConfig(config)
+	}
+}
+
+// 连接超过指定时间没有读(写)数据则关闭连接, 与心跳检测相互独立
+func WithIdleTimeout(read, write time.Duration) Option {
+	return func(s *Server) {
+		s.SetIdleTimeout(read, write)
+	}
+}
+
+// 排空连接时通知客户端服务即将关闭
+func WithDrainMsg(msgID uint32, data []byte) Option {
+	return func(s *Server) {
+		s.SetDrainMsg(msgID, data)
+	}
+}
+
+// 启用热重启, 收到SIGUSR2信号时启动新进程并传递监听端口, 当前进程在timeout时间内排空连接后退出Serve
+// timeout为0时使用DefaultRestartTimeout
+func WithHotRestart(timeout time.Duration) Option {
+	return func(s *Server) {
+		if timeout <= 0 {
+			timeout = DefaultRestartTimeout
+		}
+		s.SetRestartTimeout(timeout)
+	}
+}
+
+// 启用会话恢复, 连接断开后会话在timeout时间内可以被恢复
+func WithSession(timeout time.Duration, maxPending int) Option {
+	return func(s *Server) {
+		s.EnableSession(timeout, maxPending)
+	}
+}
+
+// 连接建立后与服务端进行协议版本握手
+func WithProtocolVersionClient(version uint32) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetProtocolVersion(version)
+	}
+}
+
+// 启用会话恢复, 连接建立后先发送会话令牌
+func WithSessionClient(token string) ClientOption {
+	return func(c ziface.IClient) {
+		c.EnableSession(token)
+	}
+}
+
+// 启用健康检查
+func WithHealthCheck(config ziface.HealthConfig) Option {
+	return func(s *Server) {
+		s.SetHealthCheck(config)
+	}
+}
+
+// 启用管理HTTP服务
+func WithAdmin(addr string, token string) Option {
+	return func(s *Server) {
+		s.SetAdmin(addr, token)
+	}
+}
+
+// 使用外部的ConnManager, 同一进程中的多个Server共享连接管理与最大连接数
+func WithConnManager(mgr ziface.IConnManager) Option {
+	return func(s *Server) {
+		s.SetConnMgr(mgr)
+	}
+}
+
+// 使用外部的Worker工作池, 同一进程中的多个Server共享Worker
+func WithWorkerPool(pool ziface.IWorkerPool) Option {
+	return func(s *Server) {
+		s.SetWorkerPool(pool)
+	}
+}
+
+// 设置路由处理请求发生panic时的恢复处理
+func WithRecoverHandler(handler ziface.RecoverHandler) Option {
+	return func(s *Server) {
+		s.SetRecoverHandler(handler)
+	}
+}
+
+// 设置请求消息ID对应的回复消息ID映射表
+func WithReplyMsgIDs(replyMsgIDs map[uint32]uint32) Option {
+	return func(s *Server) {
+		for requestID, replyID := range replyMsgIDs {
+			s.SetReplyMsgID(requestID, replyID)
+		}
+	}
+}
+
+// 为消息ID绑定独立的Worker工作池
+func WithRoutePool(pool ziface.IWorkerPool, msgIDs ...uint32) Option {
+	return func(s *Server) {
+		s.SetRoutePool(pool, msgIDs...)
+	}
+}
+
+// 设置消息ID在Worker任务队列中的优先级
+func WithMsgPriority(priority ziface.MsgPriority, msgIDs ...uint32) Option {
+	return func(s *Server) {
+		s.SetMsgPriority(priority, msgIDs...)
+	}
+}
+
+// 设置处理失败的消息的重试与死信处理
+func WithDeadLetter(config ziface.DeadLetterConfig) Option {
+	return func(s *Server) {
+		s.SetDeadLetter(config)
+	}
+}
+
+// 设置慢处理阈值, 路由处理时间超过该值时记录日志并发布SlowHandler事件
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(s *Server) {
+		s.SetSlowThreshold(threshold)
+	}
+}
+
+// 设置消息ID同时处理的请求数量上限
+func WithConcurrencyLimit(limit int, msgIDs ...uint32) Option {
+	return func(s *Server) {
+		s.SetConcurrencyLimit(limit, msgIDs...)
+	}
+}
+
+// 设置路由处理超时后的处理
+func WithTimeoutHandler(handler ziface.HandlerFunc) Option {
+	return func(s *Server) {
+		s.SetTimeoutHandler(handler)
+	}
+}
+
+// 设置Worker选择策略, 如NewDispatcher(ziface.DispatchLeastLoaded)
+func WithDispatcher(dispatcher ziface.IDispatcher) Option {
+	return func(s *Server) {
+		s.SetDispatcher(dispatcher)
+	}
+}
+
+// 设置Worker任务队列已满时的处理策略
+func WithOverflowPolicy(config ziface.OverflowConfig) Option {
+	return func(s *Server) {
+		s.SetOverflowPolicy(config)
+	}
+}
+
+// 在管理HTTP服务中提供Web控制台(/admin/ui)
+func WithAdminDashboard() Option {
+	return func(s *Server) {
+		s.SetAdminDashboard(true)
+	}
+}
+
+// 设置访问日志, 每处理完成一个消息输出一条结构化记录
+func WithAccessLog(config ziface.AccessLogConfig) Option {
+	return func(s *Server) {
+		s.SetAccessLog(config)
+	}
+}
+
+// 设置指标接口, 用于接入任意的指标系统
+func WithMetrics(metrics ziface.IMetrics) Option {
+	return func(s *Server) {
+		s.SetMetrics(metrics)
+	}
+}
+
+// 设置StatsD(DogStatsD)导出
+func WithStatsD(config ziface.StatsDConfig) Option {
+	return func(s *Server) {
+		s.SetStatsD(config)
+	}
+}
+
+// 设置慢日志, 保存处理时间最长的size个请求
+func WithSlowLog(size int, maxPayload int) Option {
+	return func(s *Server) {
+		s.SetSlowLog(size, maxPayload)
+	}
+}
+
+// 启用自动重连, 连接失败或断开后按指数退避重新连接
+func WithReconnectClient(config ziface.ReconnectConfig) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetReconnect(config)
+	}
+}
+
+// 设置Request默认的等待回复时间
+func WithRequestTimeoutClient(timeout time.Duration) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetRequestTimeout(timeout)
+	}
+}
+
+// 启动心跳检测, 可自定义心跳消息ID、消息内容、对端不存活时的处理以及允许连续未响应的心跳次数
+// 默认对端不存活时断开连接, 启用自动重连时随后重新连接
+func WithHeartBeatClient(interval time.Duration, option *ziface.HeartBeatOption) ClientOption {
+	return func(c ziface.IClient) {
+		c.StartHeartBeatWithOption(interval, option)
+	}
+}
+
+// 设置TLS配置并启用TLS, 如RootCAs、双向认证的客户端证书、ServerName
+func WithTLSConfigClient(config *tls.Config) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetTLSConfig(config)
+	}
+}
+
+// 设置Websocket握手请求的路径, 只对Websocket客户端有效
+func WithWsPathClient(path string) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetWsPath(path)
+	}
+}
+
+// 设置Websocket握手请求附加的Header, 如认证令牌, 只对Websocket客户端有效
+func WithWsHeaderClient(header http.Header) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetWsHeader(header)
+	}
+}
+
+// 设置客户端的指标接口, 如StatsDExporter, 用于集中监控大量的客户端
+func WithMetricsClient(metrics ziface.IMetrics) ClientOption {
+	return func(c ziface.IClient) {
+		c.SetMetrics(metrics)
+	}
+}
+
+// 设置注册中心, 服务启动时注册并定期续约, 停止时注销
+func WithRegistry(config ziface.RegistryConfig) Option {
+	return func(s *Server) {
+		s.SetRegistry(config)
+	}
+}
+
+// ServerOption NewServerWithOptions的选项, 可以是服务配置(ConfigOption)或服务选项(Option)
+type ServerOption interface {
+	applyServerOption(b *serverBuilder)
+}
+
+// serverBuilder NewServerWithOptions创建服务时收集的配置与服务选项
+type serverBuilder struct {
+	config *zconf.Config
+	opts   []Option
+}
+
+func (o Option) applyServerOption(b *serverBuilder) {
+	b.opts = append(b.opts, o)
+}
+
+// ConfigOption 以代码的方式设置服务配置, 在创建服务之前应用到配置, 用于NewServerWithOptions
+type ConfigOption func(c *zconf.Config)
+
+func (o ConfigOption) applyServerOption(b *serverBuilder) {
+	o(b.config)
+}
+
+// 设置服务名称
+func WithName(name string) ConfigOption {
+	return func(c *zconf.Config) {
+		c.Name = name
+	}
+}
+
+// 设置监听的IP
+func WithHost(host string) ConfigOption {
+	return func(c *zconf.Config) {
+		c.Host = host
+	}
+}
+
+// 设置监听的TCP端口
+func WithPort(port int) ConfigOption {
+	return func(c *zconf.Config) {
+		c.TCPPort = port
+	}
+}
+
+// 使用TLS加密连接, certFile与keyFile为证书与私钥文件
+func WithTLS(certFile, keyFile string) ConfigOption {
+	return func(c *zconf.Config) {
+		c.CertFile = certFile
+		c.PrivateKeyFile = keyFile
+	}
+}
+
+// 设置Worker工作池的数量, 自定义工作池见WithWorkerPool
+func WithWorkerPoolSize(size uint32) ConfigOption {
+	return func(c *zconf.Config) {
+		c.WorkerPoolSize = size
+	}
+}
+
+// 设置允许的最大连接数
+func WithMaxConn(maxConn int) ConfigOption {
+	return func(c *zconf.Config) {
+		c.MaxConn = maxConn
+	}
+}
+
+// 设置数据包的最大长度
+func WithMaxPacketSize(size uint32) ConfigOption {
+	return func(c *zconf.Config) {
+		c.MaxPacketSize = size
+	}
+}
+
+// 设置最长心跳检测间隔, 按秒取整
+func WithHeartbeatMax(max time.Duration) ConfigOption {
+	return func(c *zconf.Config) {
+		c.HeartbeatMax = int(max / time.Second)
+	}
+}
+
+// 日志输出到dir目录下的file文件
+func WithLogFile(dir, file string) ConfigOption {
+	return func(c *zconf.Config) {
+		c.LogDir = dir
+		c.LogFile = file
+	}
+}
+
+// 设置日志隔离级别
+func WithLogLevel(level int) ConfigOption {
+	return func(c *zconf.Config) {
+		c.LogIsolationLevel = level
+	}
+}
@@ -0,0 +1,134 @@
+package znet
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zlog"
+)
+
+// ErrMsgDropped 发出的消息被拦截器丢弃
+var ErrMsgDropped = errors.New("msg is dropped by out interceptor")
+
+// outChain 发出消息的拦截器责任链, 在消息封包之前执行
+// 拦截器的请求为ziface.IOutMessage, 可以修改消息(如压缩、加密、添加追踪信息)后调用chain.Proceed, 不调用时消息被丢弃
+// 修改消息时应使用SetData、SetMsgID, 以保留消息携带的关联序号
+type outChain struct {
+	builder ziface.IBuilder
+	// 拦截器数量, 没有拦截器时不执行责任链
+	size int32
+}
+
+func newOutChain() *outChain {
+	builder := zinterceptor.NewBuilder()
+	builder.Tail(outChainTail{})
+	return &outChain{builder: builder}
+}
+
+// outChainTail 责任链的最后一环, 返回最终要发出的消息
+type outChainTail struct{}
+
+func (outChainTail) Intercept(chain ziface.IChain) ziface.IcResp {
+	return chain.Request()
+}
+
+// outMessage 发出消息拦截器的请求, 实现ziface.IOutMessage
+type outMessage struct {
+	ziface.IMessage
+	conn ziface.IConnection
+}
+
+func (m *outMessage) GetMessage() ziface.IMessage {
+	return m.IMessage
+}
+
+func (m *outMessage) GetConnection() ziface.IConnection {
+	return m.conn
+}
+
+// outChainOwner 拥有发出消息拦截器的Server或Client, 连接创建时继承
+type outChainOwner interface {
+	getOutChain() *outChain
+}
+
+func (oc *outChain) add(name string, interceptor ziface.IInterceptor) {
+	oc.builder.AddNamedInterceptor(name, interceptor)
+	atomic.StoreInt32(&oc.size, int32(len(oc.builder.InterceptorNames())))
+}
+
+func (oc *outChain) remove(name string) bool {
+	ok := oc.builder.RemoveInterceptor(name)
+	atomic.StoreInt32(&oc.size, int32(len(oc.builder.InterceptorNames())))
+	return ok
+}
+
+func (oc *outChain) names() []string {
+	return oc.builder.InterceptorNames()
+}
+
+// enabled 是否安装了拦截器
+func (oc *outChain) enabled() bool {
+	return oc != nil && atomic.LoadInt32(&oc.size) > 0
+}
+
+// pack 执行发出消息的拦截器后封包, 消息被丢弃时返回ErrMsgDropped
+// conn为发出消息的连接, 拦截器可以通过ziface.IOutMessage获取
+func (oc *outChain) pack(conn ziface.IConnection, packet ziface.IDataPack, msg ziface.IMessage) ([]byte, error) {
+	if oc.enabled() {
+		switch out := oc.builder.Execute(&outMessage{IMessage: msg, conn: conn}).(type) {
+		case *outMessage:
+			msg = out.IMessage
+		case ziface.IMessage:
+			msg = out
+		default:
+			return nil, ErrMsgDropped
+		}
+	}
+
+	buf, err := packet.Pack(msg)
+	if err != nil {
+		zlog.Ins().ErrorF("Pack error msg ID = %d", msg.GetMsgID())
+		return nil, errors.New("Pack error msg ")
+	}
+	return buf, nil
+}
+
+// AddOutInterceptor 添加发出消息的拦截器, 对之后建立的连接发出的消息(包括回复)在封包之前生效, 名称已存在时替换
+func (s *Server) AddOutInterceptor(name string, interceptor ziface.IInterceptor) {
+	s.outChain.add(name, interceptor)
+}
+
+// RemoveOutInterceptor 删除指定名称的发出消息拦截器
+func (s *Server) RemoveOutInterceptor(name string) bool {
+	return s.outChain.remove(name)
+}
+
+// OutInterceptorNames 按顺序返回全部发出消息拦截器的名称
+func (s *Server) OutInterceptorNames() []string {
+	return s.outChain.names()
+}
+
+func (s *Server) getOutChain() *outChain {
+	return s.outChain
+}
+
+// AddOutInterceptor 添加发出消息的拦截器, 在封包之前执行, 用于压缩、加密、添加追踪信息等, 名称已存在时替换
+func (c *Client) AddOutInterceptor(name string, interceptor ziface.IInterceptor) {
+	c.outChain.add(name, interceptor)
+}
+
+// RemoveOutInterceptor 删除指定名称的发出消息拦截器
+func (c *Client) RemoveOutInterceptor(name string) bool {
+	return c.outChain.remove(name)
+}
+
+// OutInterceptorNames 按顺序返回全部发出消息拦截器的名称
+func (c *Client) OutInterceptorNames() []string {
+	return c.outChain.names()
+}
+
+func (c *Client) getOutChain() *outChain {
+	return c.outChain
+}
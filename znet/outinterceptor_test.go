@@ -0,0 +1,98 @@
+package znet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// prefixInterceptor 在发出的消息内容前添加前缀
+type prefixInterceptor string
+
+func (p prefixInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	msg := chain.Request().(ziface.IMessage)
+	msg.SetData(append([]byte(p), msg.GetData()...))
+	msg.SetDataLen(uint32(len(msg.GetData())))
+	return chain.Proceed(msg)
+}
+
+// dropInterceptor 丢弃指定消息ID的消息
+type dropInterceptor uint32
+
+func (d dropInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	if msg, ok := chain.Request().(ziface.IMessage); ok && msg.GetMsgID() == uint32(d) {
+		return nil
+	}
+	if request, ok := chain.Request().(ziface.IRequest); ok && request.GetMsgID() == uint32(d) {
+		return nil
+	}
+	return chain.Proceed(chain.Request())
+}
+
+type echoRouter struct {
+	BaseRouter
+}
+
+func (r *echoRouter) Handle(request ziface.IRequest) {
+	_ = request.GetConnection().SendMsg(request.GetMsgID(), request.GetData())
+}
+
+type collectRouter struct {
+	BaseRouter
+	data chan []byte
+}
+
+func (r *collectRouter) Handle(request ziface.IRequest) {
+	r.data <- request.GetData()
+}
+
+func TestOutInterceptor(t *testing.T) {
+	s := NewServer().(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28983
+	s.AddRouter(1, &echoRouter{})
+	s.AddRouter(2, &echoRouter{})
+	s.AddOutInterceptor("server", prefixInterceptor("s:"))
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	router := &collectRouter{data: make(chan []byte, 4)}
+	client := NewClient("127.0.0.1", 28983)
+	client.AddRouter(1, router)
+	client.AddRouter(2, router)
+	client.AddOutInterceptor("trace", prefixInterceptor("t:"))
+	client.AddOutInterceptor("drop", dropInterceptor(3))
+	assert.Equal(t, []string{"trace", "drop"}, client.OutInterceptorNames())
+	client.Start()
+	defer client.Stop()
+	// 在解码器之后过滤服务端推送的消息ID 2
+	client.AddNamedInterceptor("filter", dropInterceptor(2))
+	assert.Equal(t, []string{"", "filter"}, client.InterceptorNames())
+	time.Sleep(200 * time.Millisecond)
+
+	conn := client.Conn()
+	assert.Equal(t, ErrMsgDropped, conn.SendMsg(3, []byte("x")))
+	assert.Nil(t, conn.SendMsg(2, []byte("filtered")))
+	assert.Nil(t, conn.SendMsg(1, []byte("hello")))
+
+	select {
+	case data := <-router.data:
+		assert.True(t, bytes.Equal([]byte("s:t:hello"), data), string(data))
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+	}
+
+	assert.True(t, client.RemoveOutInterceptor("trace"))
+	assert.Nil(t, conn.SendMsg(1, []byte("bye")))
+	select {
+	case data := <-router.data:
+		assert.Equal(t, "s:bye", string(data))
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+	}
+	assert.Equal(t, []string{"server"}, s.Introspect().OutInterceptors)
+}
@@ -0,0 +1,57 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverflowPolicy(t *testing.T) {
+	mh := NewMsgHandle()
+	pool := NewWorkerPool(1, 1)
+	mh.SetWorkerPool(pool)
+	mh.AddRouter(1, &BaseRouter{})
+	conn := &Connection{connID: 1}
+	newRequest := func() ziface.IRequest {
+		return NewRequest(conn, zpack.NewMsgPackage(1, nil))
+	}
+
+	// 工作池未启动, 第一个请求占满任务队列
+	mh.SendMsgToTaskQueue(newRequest())
+
+	mh.SetOverflowPolicy(ziface.OverflowConfig{Policy: ziface.OverflowDrop})
+	mh.SendMsgToTaskQueue(newRequest())
+	assert.Equal(t, uint64(1), mh.WorkerStats().OverflowDropped)
+
+	var overloaded []ziface.IRequest
+	mh.SetOverflowPolicy(ziface.OverflowConfig{
+		Policy: ziface.OverflowCallback,
+		Handler: func(request ziface.IRequest) {
+			overloaded = append(overloaded, request)
+		},
+	})
+	mh.SendMsgToTaskQueue(newRequest())
+	assert.Len(t, overloaded, 1)
+	assert.Equal(t, uint64(1), mh.WorkerStats().OverflowCallbacks)
+	assert.Equal(t, int64(1), mh.InFlight())
+
+	// 默认阻塞直到任务队列有空位
+	mh.SetOverflowPolicy(ziface.OverflowConfig{})
+	done := make(chan struct{})
+	go func() {
+		mh.SendMsgToTaskQueue(newRequest())
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return mh.WorkerStats().OverflowBlocked == 1
+	}, time.Second, time.Millisecond)
+	pool.Start()
+	<-done
+	assert.Eventually(t, func() bool {
+		stats := mh.WorkerStats()
+		return stats.Submitted == 2 && stats.Completed == 2
+	}, time.Second, time.Millisecond)
+}
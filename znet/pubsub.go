@@ -0,0 +1,287 @@
+package znet
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	// TopicSeparator 主题层级分隔符
+	TopicSeparator = "."
+	// TopicWildcardOne 匹配一个层级
+	TopicWildcardOne = "*"
+	// TopicWildcardAll 匹配剩余的零个或多个层级，只能作为最后一级
+	TopicWildcardAll = "#"
+)
+
+var (
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrInvalidPattern = errors.New("invalid topic pattern")
+)
+
+// topicNode 主题树节点, 每一级主题对应一个节点
+type topicNode struct {
+	children map[string]*topicNode
+	conns    map[uint64]ziface.IConnection
+	funcs    map[uint64]ziface.TopicHandler
+}
+
+func newTopicNode() *topicNode {
+	return &topicNode{
+		children: make(map[string]*topicNode),
+		conns:    make(map[uint64]ziface.IConnection),
+		funcs:    make(map[uint64]ziface.TopicHandler),
+	}
+}
+
+func (n *topicNode) empty() bool {
+	return len(n.children) == 0 && len(n.conns) == 0 && len(n.funcs) == 0
+}
+
+// PubSub 主题发布订阅, 连接订阅的消息通过连接的发送队列投递
+type PubSub struct {
+	root *topicNode
+	// 连接的全部订阅, 用于连接断开时取消订阅
+	connSubs map[uint64]map[string]struct{}
+	// 服务内部订阅ID与订阅主题
+	funcSubs map[uint64]string
+	subID    uint64
+	lock     sync.RWMutex
+}
+
+func NewPubSub() *PubSub {
+	return &PubSub{
+		root:     newTopicNode(),
+		connSubs: make(map[uint64]map[string]struct{}),
+		funcSubs: make(map[uint64]string),
+	}
+}
+
+// splitPattern 校验并拆分订阅主题
+func splitPattern(pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, ErrInvalidPattern
+	}
+
+	segments := strings.Split(pattern, TopicSeparator)
+	for i, seg := range segments {
+		if seg == TopicWildcardAll && i != len(segments)-1 {
+			return nil, ErrInvalidPattern
+		}
+		if len(seg) > 1 && strings.ContainsAny(seg, TopicWildcardOne+TopicWildcardAll) {
+			return nil, ErrInvalidPattern
+		}
+	}
+	return segments, nil
+}
+
+// splitTopic 校验并拆分发布主题, 发布主题不能包含通配符
+func splitTopic(topic string) ([]string, error) {
+	if topic == "" || strings.ContainsAny(topic, TopicWildcardOne+TopicWildcardAll) {
+		return nil, ErrInvalidTopic
+	}
+	return strings.Split(topic, TopicSeparator), nil
+}
+
+// node 获取订阅主题对应的节点, create为true时不存在则创建
+func (ps *PubSub) node(segments []string, create bool) *topicNode {
+	node := ps.root
+	for _, seg := range segments {
+		child, ok := node.children[seg]
+		if !ok {
+			if !create {
+				return nil
+			}
+			child = newTopicNode()
+			node.children[seg] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// prune 删除订阅主题路径上不再使用的节点
+func (ps *PubSub) prune(segments []string) {
+	path := []*topicNode{ps.root}
+	for _, seg := range segments {
+		child, ok := path[len(path)-1].children[seg]
+		if !ok {
+			return
+		}
+		path = append(path, child)
+	}
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !path[i+1].empty() {
+			return
+		}
+		delete(path[i].children, segments[i])
+	}
+}
+
+func (ps *PubSub) Subscribe(pattern string, conn ziface.IConnection) error {
+	segments, err := splitPattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	ps.node(segments, true).conns[conn.GetConnID()] = conn
+	if ps.connSubs[conn.GetConnID()] == nil {
+		ps.connSubs[conn.GetConnID()] = make(map[string]struct{})
+	}
+	ps.connSubs[conn.GetConnID()][pattern] = struct{}{}
+
+	return nil
+}
+
+func (ps *PubSub) Unsubscribe(pattern string, conn ziface.IConnection) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	ps.unsubscribe(pattern, conn.GetConnID())
+}
+
+func (ps *PubSub) unsubscribe(pattern string, connID uint64) {
+	segments, err := splitPattern(pattern)
+	if err != nil {
+		return
+	}
+
+	if node := ps.node(segments, false); node != nil {
+		delete(node.conns, connID)
+		ps.prune(segments)
+	}
+
+	delete(ps.connSubs[connID], pattern)
+	if len(ps.connSubs[connID]) == 0 {
+		delete(ps.connSubs, connID)
+	}
+}
+
+func (ps *PubSub) UnsubscribeAll(conn ziface.IConnection) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for pattern := range ps.connSubs[conn.GetConnID()] {
+		ps.unsubscribe(pattern, conn.GetConnID())
+	}
+}
+
+func (ps *PubSub) Subscriptions(connID uint64) []string {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	patterns := make([]string, 0, len(ps.connSubs[connID]))
+	for pattern := range ps.connSubs[connID] {
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func (ps *PubSub) SubscribeFunc(pattern string, handler ziface.TopicHandler) (uint64, error) {
+	segments, err := splitPattern(pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	ps.subID++
+	ps.node(segments, true).funcs[ps.subID] = handler
+	ps.funcSubs[ps.subID] = pattern
+
+	return ps.subID, nil
+}
+
+func (ps *PubSub) UnsubscribeFunc(subID uint64) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	pattern, ok := ps.funcSubs[subID]
+	if !ok {
+		return
+	}
+	delete(ps.funcSubs, subID)
+
+	segments, _ := splitPattern(pattern)
+	if node := ps.node(segments, false); node != nil {
+		delete(node.funcs, subID)
+		ps.prune(segments)
+	}
+}
+
+// Publish 发布消息，同一连接的多个订阅匹配同一主题时只投递一次
+// 服务内部订阅者在当前Goroutine中依次调用
+func (ps *PubSub) Publish(topic string, msgID uint32, data []byte) (int, error) {
+	segments, err := splitTopic(topic)
+	if err != nil {
+		return 0, err
+	}
+
+	conns := make(map[uint64]ziface.IConnection)
+	var funcs []ziface.TopicHandler
+
+	ps.lock.RLock()
+	match(ps.root, segments, conns, &funcs)
+	ps.lock.RUnlock()
+
+	if len(conns) > 0 {
+		list := make([]ziface.IConnection, 0, len(conns))
+		for _, conn := range conns {
+			list = append(list, conn)
+		}
+		if err := broadcast(list, msgID, data); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, handler := range funcs {
+		ps.callHandler(handler, topic, msgID, data)
+	}
+
+	return len(conns), nil
+}
+
+func (ps *PubSub) callHandler(handler ziface.TopicHandler, topic string, msgID uint32, data []byte) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("topic %s handler panic: %v", topic, err)
+		}
+	}()
+	handler(topic, msgID, data)
+}
+
+// match 收集与主题匹配的全部订阅者
+func match(node *topicNode, segments []string, conns map[uint64]ziface.IConnection, funcs *[]ziface.TopicHandler) {
+	if all, ok := node.children[TopicWildcardAll]; ok {
+		collect(all, conns, funcs)
+	}
+
+	if len(segments) == 0 {
+		collect(node, conns, funcs)
+		return
+	}
+
+	if child, ok := node.children[segments[0]]; ok {
+		match(child, segments[1:], conns, funcs)
+	}
+	if one, ok := node.children[TopicWildcardOne]; ok {
+		match(one, segments[1:], conns, funcs)
+	}
+}
+
+func collect(node *topicNode, conns map[uint64]ziface.IConnection, funcs *[]ziface.TopicHandler) {
+	for connID, conn := range node.conns {
+		conns[connID] = conn
+	}
+	for _, handler := range node.funcs {
+		*funcs = append(*funcs, handler)
+	}
+}
@@ -0,0 +1,56 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubSubMatch(t *testing.T) {
+	ps := NewPubSub()
+
+	var got []string
+	sub := func(pattern string) uint64 {
+		subID, err := ps.SubscribeFunc(pattern, func(topic string, msgID uint32, data []byte) {
+			got = append(got, pattern)
+		})
+		assert.Nil(t, err)
+		return subID
+	}
+	sub("market.btc.price")
+	sub("market.*.price")
+	allID := sub("market.#")
+	sub("news.*")
+
+	_, err := ps.Publish("market.btc.price", 1, nil)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"market.btc.price", "market.*.price", "market.#"}, got)
+
+	got = nil
+	_, _ = ps.Publish("market", 1, nil)
+	assert.Equal(t, []string{"market.#"}, got)
+
+	got = nil
+	ps.UnsubscribeFunc(allID)
+	_, _ = ps.Publish("market.eth.depth", 1, nil)
+	_, _ = ps.Publish("news.a.b", 1, nil)
+	assert.Empty(t, got)
+
+	_, err = ps.SubscribeFunc("a.#.b", nil)
+	assert.Equal(t, ErrInvalidPattern, err)
+	_, err = ps.Publish("market.*", 1, nil)
+	assert.Equal(t, ErrInvalidTopic, err)
+}
+
+func TestPubSubConn(t *testing.T) {
+	ps := NewPubSub()
+	conn := &Connection{connID: 1}
+
+	assert.Nil(t, ps.Subscribe("chat.room1", conn))
+	assert.Nil(t, ps.Subscribe("chat.*", conn))
+	assert.ElementsMatch(t, []string{"chat.room1", "chat.*"}, ps.Subscriptions(1))
+
+	ps.UnsubscribeAll(conn)
+	assert.Empty(t, ps.Subscriptions(1))
+	assert.True(t, ps.root.empty())
+}
@@ -0,0 +1,141 @@
+package znet
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// RateLimiter 令牌桶限速器
+// 令牌不足时允许透支, 透支的部分由之后的调用者等待偿还，因此单次消耗可以超过突发量
+type RateLimiter struct {
+	rate   int
+	burst  int
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+// NewRateLimiter 创建限速器, rate为每秒令牌数(字节数), 0表示不限制, burst为0时与rate相同
+func NewRateLimiter(rate, burst int) *RateLimiter {
+	l := &RateLimiter{}
+	l.SetLimit(rate, burst)
+	return l
+}
+
+func (l *RateLimiter) SetLimit(rate, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if burst <= 0 {
+		burst = rate
+	}
+	l.rate = rate
+	l.burst = burst
+	l.tokens = float64(burst)
+	l.last = time.Now()
+}
+
+func (l *RateLimiter) Limit() (rate, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.rate, l.burst
+}
+
+// reserve 消耗n个令牌，返回需要等待的时间
+func (l *RateLimiter) reserve(n int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+}
+
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	wait := l.reserve(n)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimiters 连接的读写限速, 同时受连接自身与Server全局的限制
+type rateLimiters struct {
+	read, write       *RateLimiter
+	srvRead, srvWrite ziface.IRateLimiter
+}
+
+func newRateLimiters(limit ziface.RateLimit, srvRead, srvWrite ziface.IRateLimiter) *rateLimiters {
+	return &rateLimiters{
+		read:     NewRateLimiter(limit.ReadRate, limit.Burst),
+		write:    NewRateLimiter(limit.WriteRate, limit.Burst),
+		srvRead:  srvRead,
+		srvWrite: srvWrite,
+	}
+}
+
+// rateLimitOwner 设置了带宽限制的Server, 连接创建时继承
+type rateLimitOwner interface {
+	getConnRateLimit() ziface.RateLimit
+	getServerRateLimiters() (read ziface.IRateLimiter, write ziface.IRateLimiter)
+}
+
+// connRateLimiters 按照Server的带宽限制创建连接的限速器
+func connRateLimiters(server ziface.IServer) *rateLimiters {
+	owner, ok := server.(rateLimitOwner)
+	if !ok {
+		return newRateLimiters(ziface.RateLimit{}, nil, nil)
+	}
+	srvRead, srvWrite := owner.getServerRateLimiters()
+	return newRateLimiters(owner.getConnRateLimit(), srvRead, srvWrite)
+}
+
+func (r *rateLimiters) setLimit(limit ziface.RateLimit) {
+	r.read.SetLimit(limit.ReadRate, limit.Burst)
+	r.write.SetLimit(limit.WriteRate, limit.Burst)
+}
+
+func (r *rateLimiters) waitRead(ctx context.Context, n int) error {
+	if err := r.read.WaitN(ctx, n); err != nil {
+		return err
+	}
+	if r.srvRead != nil {
+		return r.srvRead.WaitN(ctx, n)
+	}
+	return nil
+}
+
+func (r *rateLimiters) waitWrite(ctx context.Context, n int) error {
+	if err := r.write.WaitN(ctx, n); err != nil {
+		return err
+	}
+	if r.srvWrite != nil {
+		return r.srvWrite.WaitN(ctx, n)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	// 不限制
+	l := NewRateLimiter(0, 0)
+	start := time.Now()
+	assert.Nil(t, l.WaitN(context.Background(), 1<<20))
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Millisecond))
+
+	// 突发量内不等待, 透支的部分按速率等待
+	l.SetLimit(1000, 100)
+	start = time.Now()
+	assert.Nil(t, l.WaitN(context.Background(), 100))
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Millisecond))
+	assert.Nil(t, l.WaitN(context.Background(), 100))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(80*time.Millisecond))
+
+	rate, burst := l.Limit()
+	assert.Equal(t, 1000, rate)
+	assert.Equal(t, 100, burst)
+
+	// ctx结束时不再等待
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, l.WaitN(ctx, 10000))
+}
+
+func TestSendMsgRateLimit(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	go func() { _, _ = io.Copy(io.Discard, remote) }()
+
+	c := &Connection{conn: local, connID: 1, packet: zpack.NewDataPack(), stats: newConnStats()}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+	c.limiters = newRateLimiters(ziface.RateLimit{WriteRate: 1000, Burst: 100}, nil, nil)
+
+	// 直接发送的消息同样受写限速, 第二条消息等待令牌
+	data := make([]byte, 100-int(c.packet.GetHeadLen()))
+	start := time.Now()
+	assert.Nil(t, c.SendMsg(1, data))
+	assert.Nil(t, c.SendMsg(1, data))
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(80*time.Millisecond))
+}
@@ -0,0 +1,94 @@
+package znet
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	// DefaultReconnectMinDelay 第一次重连前默认的等待时间
+	DefaultReconnectMinDelay = time.Second
+	// DefaultReconnectMaxDelay 重连默认的最长等待时间
+	DefaultReconnectMaxDelay = 30 * time.Second
+	// DefaultReconnectMultiplier 每次重连失败后等待时间默认的倍数
+	DefaultReconnectMultiplier = 2
+	// DefaultReconnectJitter 重连等待时间默认的随机浮动比例
+	DefaultReconnectJitter = 0.2
+)
+
+// SetReconnect 设置自动重连, 连接失败或断开后按指数退避重新连接, 每次连接建立后执行登录回调, 需在Start之前调用
+func (c *Client) SetReconnect(config ziface.ReconnectConfig) {
+	if config.MinDelay <= 0 {
+		config.MinDelay = DefaultReconnectMinDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = DefaultReconnectMaxDelay
+	}
+	if config.MaxDelay < config.MinDelay {
+		config.MaxDelay = config.MinDelay
+	}
+	if config.Multiplier < 1 {
+		config.Multiplier = DefaultReconnectMultiplier
+	}
+	if config.Jitter <= 0 || config.Jitter > 1 {
+		config.Jitter = DefaultReconnectJitter
+	}
+	c.reconnect = &config
+}
+
+// Reconnects 累计的重连次数
+func (c *Client) Reconnects() uint64 {
+	return atomic.LoadUint64(&c.reconnects)
+}
+
+// backoff 第attempts次重连(从0开始)前的等待时间
+func backoff(config *ziface.ReconnectConfig, attempts int) time.Duration {
+	delay := float64(config.MinDelay) * math.Pow(config.Multiplier, float64(attempts))
+	if delay > float64(config.MaxDelay) {
+		delay = float64(config.MaxDelay)
+	}
+	delay *= 1 + config.Jitter*(2*rand.Float64()-1)
+	return time.Duration(delay)
+}
+
+// nextReconnect 连接失败或断开后的等待时间, 没有设置自动重连或达到最多重连次数时返回false
+func (c *Client) nextReconnect(attempts int, err error) (time.Duration, bool) {
+	config := c.reconnect
+	if config == nil {
+		return 0, false
+	}
+	if config.MaxAttempts > 0 && attempts >= config.MaxAttempts {
+		zlog.Ins().ErrorF("client reconnect to %s:%d gave up after %d attempts", c.Ip, c.Port, attempts)
+		if config.OnGiveUp != nil {
+			if err == nil {
+				err = ErrClientNotConnected
+			}
+			config.OnGiveUp(err)
+		}
+		return 0, false
+	}
+
+	delay := backoff(config, attempts)
+	zlog.Ins().InfoF("client reconnect to %s:%d in %s, attempts = %d", c.Ip, c.Port, delay, attempts+1)
+	return delay, true
+}
+
+// afterConnect 连接建立后执行登录回调, 重连时再调用重连回调
+func (c *Client) afterConnect(conn ziface.IConnection) {
+	config := c.reconnect
+	if config.Login != nil {
+		if err := config.Login(conn); err != nil {
+			zlog.Ins().ErrorF("client login err: %v", err)
+			conn.Stop()
+			return
+		}
+	}
+	if reconnects := c.Reconnects(); reconnects > 0 && config.OnReconnect != nil {
+		config.OnReconnect(conn, reconnects)
+	}
+}
@@ -0,0 +1,94 @@
+package znet
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	client := &Client{}
+	client.SetReconnect(ziface.ReconnectConfig{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.1})
+	for attempts, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		delay := backoff(client.reconnect, attempts)
+		want *= time.Millisecond
+		assert.True(t, delay >= want*9/10 && delay <= want*11/10, "attempts %d delay %s", attempts, delay)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		// 第一个连接建立后立即断开
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_ = conn.Close()
+		conn, err = listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(2 * time.Second)
+	}()
+
+	var logins int32
+	reconnected := make(chan uint64, 1)
+	addr := listener.Addr().(*net.TCPAddr)
+	client := NewClient("127.0.0.1", addr.Port, WithReconnectClient(ziface.ReconnectConfig{
+		MinDelay: 10 * time.Millisecond,
+		Login: func(conn ziface.IConnection) error {
+			atomic.AddInt32(&logins, 1)
+			return nil
+		},
+		OnReconnect: func(conn ziface.IConnection, reconnects uint64) {
+			reconnected <- reconnects
+		},
+	}))
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case reconnects := <-reconnected:
+		assert.Equal(t, uint64(1), reconnects)
+	case <-time.After(time.Second):
+		t.Fatal("client is not reconnected")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+	assert.Equal(t, uint64(1), client.Reconnects())
+}
+
+func TestClientReconnectGiveUp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	_ = listener.Close()
+
+	gaveUp := make(chan error, 1)
+	p, _ := strconv.Atoi(port)
+	client := NewClient("127.0.0.1", p, WithReconnectClient(ziface.ReconnectConfig{
+		MaxAttempts: 2,
+		MinDelay:    10 * time.Millisecond,
+		OnGiveUp: func(err error) {
+			gaveUp <- err
+		},
+	}))
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case err := <-gaveUp:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("client does not give up")
+	}
+	assert.Equal(t, uint64(2), client.Reconnects())
+}
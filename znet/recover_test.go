@@ -0,0 +1,38 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicRouter struct {
+	BaseRouter
+}
+
+func (r *panicRouter) Handle(request ziface.IRequest) {
+	panic("boom")
+}
+
+func TestRecoverHandler(t *testing.T) {
+	var recovered []string
+	mh := NewMsgHandle()
+	mh.SetRecoverHandler(func(conn ziface.IConnection, request ziface.IRequest, err interface{}, stack []byte) {
+		assert.NotEmpty(t, stack)
+		recovered = append(recovered, "global")
+	})
+
+	mh.AddRouter(1, &panicRouter{})
+	router := &panicRouter{}
+	router.SetRecoverHandler(func(conn ziface.IConnection, request ziface.IRequest, err interface{}, stack []byte) {
+		assert.Equal(t, "boom", err)
+		recovered = append(recovered, "route")
+	})
+	mh.AddRouter(2, router)
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Equal(t, []string{"global", "route"}, recovered)
+}
@@ -0,0 +1,225 @@
+package znet
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/utils/commandline/args"
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultConfigWatchInterval 不支持文件通知的系统上检查配置文件是否变化的默认间隔
+const DefaultConfigWatchInterval = 5 * time.Second
+
+// reloadLock 保证同一时间只有一个配置重新加载
+var reloadLock sync.Mutex
+
+// hotReloadFields 服务运行中可以直接生效的配置, 值为配置变化后需要执行的操作(nil表示只需要更新全局配置)
+var hotReloadFields = map[string]func(s *Server, old, new *zconf.Config){
+	"LogIsolationLevel": func(s *Server, old, new *zconf.Config) {
+		zlog.SetLogLevel(new.LogIsolationLevel)
+	},
+	"MaxPacketSize":    nil,
+	"MsgMaxPacketSize": nil,
+	"MaxConn":          nil,
+	"MaxMsgChanLen":    nil,
+	"HeartbeatMax":     nil,
+	"ConnReadRate":     applyConnRateLimit,
+	"ConnWriteRate":    applyConnRateLimit,
+	"ServerReadRate":   applyServerRateLimit,
+	"ServerWriteRate":  applyServerRateLimit,
+	"BannedIPs":        applyBannedIPs,
+}
+
+func applyConnRateLimit(s *Server, old, new *zconf.Config) {
+	s.SetConnRateLimit(ziface.RateLimit{ReadRate: new.ConnReadRate, WriteRate: new.ConnWriteRate})
+}
+
+func applyServerRateLimit(s *Server, old, new *zconf.Config) {
+	s.SetServerRateLimit(ziface.RateLimit{ReadRate: new.ServerReadRate, WriteRate: new.ServerWriteRate})
+}
+
+func applyBannedIPs(s *Server, old, new *zconf.Config) {
+	banned := make(map[string]struct{}, len(new.BannedIPs))
+	for _, ip := range new.BannedIPs {
+		banned[normalizeIP(ip)] = struct{}{}
+	}
+	for _, ip := range old.BannedIPs {
+		if _, ok := banned[normalizeIP(ip)]; !ok {
+			s.Unban(ip)
+		}
+	}
+	for _, ip := range new.BannedIPs {
+		s.Ban(ip)
+	}
+}
+
+// applyConfLimits 创建服务时应用配置中的带宽限制与封禁IP
+func (s *Server) applyConfLimits(config *zconf.Config) {
+	if config.ConnReadRate != 0 || config.ConnWriteRate != 0 {
+		applyConnRateLimit(s, nil, config)
+	}
+	if config.ServerReadRate != 0 || config.ServerWriteRate != 0 {
+		applyServerRateLimit(s, nil, config)
+	}
+	for _, ip := range config.BannedIPs {
+		s.Ban(ip)
+	}
+}
+
+// ReloadConfig 重新加载配置文件, path为空时使用启动参数指定的配置文件
+// 可以直接生效的配置更新到全局配置并应用到运行中的服务, 其余变化的配置需要重启服务后生效
+func (s *Server) ReloadConfig(path string) (ziface.ConfigReloadReport, error) {
+	if path == "" {
+		path = args.Args.ConfigFile
+	}
+	return s.applyConfig(path, func(conf *zconf.Config) error {
+		if err := conf.Load(path); err != nil {
+			return err
+		}
+		// 环境变量与命令行参数仍然覆盖配置文件中的值
+		return conf.LoadOverrides()
+	})
+}
+
+// ApplyConfig 应用推送的JSON配置(如通过管理HTTP服务), 只需包含要修改的字段, 其余字段保持当前值
+// 与ReloadConfig相同, 可以直接生效的配置应用到运行中的服务, 其余变化的配置需要重启服务后生效
+func (s *Server) ApplyConfig(data []byte) (ziface.ConfigReloadReport, error) {
+	return s.applyConfig("pushed", func(conf *zconf.Config) error {
+		if err := json.Unmarshal(data, conf); err != nil {
+			return err
+		}
+		return conf.ResolveSecrets()
+	})
+}
+
+// applyConfig 在当前配置的拷贝上加载新的配置, 应用变化的配置
+func (s *Server) applyConfig(source string, load func(conf *zconf.Config) error) (ziface.ConfigReloadReport, error) {
+	var report ziface.ConfigReloadReport
+
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	// 深拷贝当前配置, 配置文件中没有的字段保持当前值
+	old := zconf.GlobalObject
+	data, err := json.Marshal(old)
+	if err != nil {
+		return report, err
+	}
+	conf := &zconf.Config{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return report, err
+	}
+	if err := load(conf); err != nil {
+		return report, err
+	}
+	// 有错误的配置不应用任何字段
+	if err := conf.Validate(); err != nil {
+		return report, err
+	}
+
+	// 应用之前的配置, 用于计算变化
+	prev := &zconf.Config{}
+	_ = json.Unmarshal(data, prev)
+
+	oldVal := reflect.ValueOf(old).Elem()
+	newVal := reflect.ValueOf(conf).Elem()
+	applied := make(map[uintptr]struct{})
+	for i := 0; i < newVal.NumField(); i++ {
+		name := newVal.Type().Field(i).Name
+		if reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			continue
+		}
+
+		apply, ok := hotReloadFields[name]
+		if !ok {
+			report.RestartRequired = append(report.RestartRequired, name)
+			continue
+		}
+
+		oldVal.Field(i).Set(newVal.Field(i))
+		report.Applied = append(report.Applied, name)
+
+		// 同一个操作只执行一次(如读写带宽一起设置)
+		if apply != nil {
+			key := reflect.ValueOf(apply).Pointer()
+			if _, ok := applied[key]; !ok {
+				applied[key] = struct{}{}
+				apply(s, prev, conf)
+			}
+		}
+	}
+
+	zlog.Ins().InfoF("[RELOAD] config %s, applied %v, restart required %v", source, report.Applied, report.RestartRequired)
+	return report, nil
+}
+
+// WatchConfig 监听配置文件, 变化时重新加载, 服务停止后不再监听
+// path为空时使用启动参数指定的配置文件; Linux上使用inotify, 其他系统按interval检查, interval为0时使用默认间隔
+func (s *Server) WatchConfig(path string, interval time.Duration) {
+	if path == "" {
+		path = args.Args.ConfigFile
+	}
+	if interval <= 0 {
+		interval = DefaultConfigWatchInterval
+	}
+	s.WatchConfigSource(zconf.NewFileSource(path, interval))
+}
+
+// WatchConfigSource 监听配置来源(如etcd、Nacos、Apollo配置中心), 变化时重新加载, 服务停止后不再监听
+// 与ReloadConfig相同, 环境变量与命令行参数仍然覆盖配置中的值, 其余变化的配置需要重启服务后生效
+func (s *Server) WatchConfigSource(source ziface.IConfigSource) {
+	watcher, err := zconf.NewSourceWatcher(source)
+	if err != nil {
+		zlog.Ins().ErrorF("[RELOAD] watch config %s err: %v", source.Name(), err)
+		return
+	}
+	watcher.OnChange(func(old, new *zconf.Config) {
+		if _, err := s.reloadSource(source); err != nil {
+			zlog.Ins().ErrorF("[RELOAD] config %s err: %v", source.Name(), err)
+		}
+	})
+
+	s.lock.Lock()
+	if s.listenClosed {
+		s.lock.Unlock()
+		watcher.Close()
+		return
+	}
+	prev := s.configWatcher
+	s.configWatcher = watcher
+	s.lock.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// reloadSource 从配置来源重新加载配置
+func (s *Server) reloadSource(source ziface.IConfigSource) (ziface.ConfigReloadReport, error) {
+	return s.applyConfig(source.Name(), func(conf *zconf.Config) error {
+		ctx, cancel := context.WithTimeout(context.Background(), zconf.DefaultSourceTimeout)
+		defer cancel()
+		if err := conf.LoadSource(ctx, source); err != nil {
+			return err
+		}
+		return conf.LoadOverrides()
+	})
+}
+
+// stopConfigWatcher 服务停止后不再监听配置文件
+func (s *Server) stopConfigWatcher() {
+	s.lock.Lock()
+	watcher := s.configWatcher
+	s.configWatcher = nil
+	s.lock.Unlock()
+
+	if watcher != nil {
+		watcher.Close()
+	}
+}
@@ -0,0 +1,83 @@
+package znet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerReloadConfig(t *testing.T) {
+	saved := *zconf.GlobalObject
+	defer func() {
+		*zconf.GlobalObject = saved
+	}()
+
+	s := NewServer().(*Server)
+
+	dir, err := ioutil.TempDir("", "zinx-reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "zinx.json")
+	conf := `{"MaxConn": 100, "ConnReadRate": 1024, "BannedIPs": ["10.0.0.1"], "TCPPort": 9999}`
+	assert.Nil(t, ioutil.WriteFile(path, []byte(conf), 0644))
+
+	report, err := s.ReloadConfig(path)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"MaxConn", "ConnReadRate", "BannedIPs"}, report.Applied)
+	assert.Equal(t, []string{"TCPPort"}, report.RestartRequired)
+
+	assert.Equal(t, 100, zconf.GlobalObject.MaxConn)
+	assert.Equal(t, saved.TCPPort, zconf.GlobalObject.TCPPort)
+	assert.Equal(t, ziface.RateLimit{ReadRate: 1024}, s.getConnRateLimit())
+	assert.Equal(t, []string{"10.0.0.1"}, s.BannedIPs())
+
+	// 从封禁列表中移除后解除封禁
+	conf = `{"MaxConn": 100, "ConnReadRate": 1024, "BannedIPs": []}`
+	assert.Nil(t, ioutil.WriteFile(path, []byte(conf), 0644))
+
+	report, err = s.ReloadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BannedIPs"}, report.Applied)
+	assert.Empty(t, s.BannedIPs())
+
+	_, err = s.ReloadConfig(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+
+	// 校验失败时不应用任何字段
+	conf = `{"MaxConn": 200, "WorkerPoolSize": 0}`
+	assert.Nil(t, ioutil.WriteFile(path, []byte(conf), 0644))
+	_, err = s.ReloadConfig(path)
+	assert.IsType(t, &zconf.ValidationError{}, err)
+	assert.Equal(t, 100, zconf.GlobalObject.MaxConn)
+}
+
+func TestServerWatchConfig(t *testing.T) {
+	saved := *zconf.GlobalObject
+	defer func() {
+		*zconf.GlobalObject = saved
+	}()
+
+	dir, err := ioutil.TempDir("", "zinx-watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "zinx.toml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn = 100\n"), 0644))
+
+	s := NewServer().(*Server)
+	s.WatchConfig(path, 50*time.Millisecond)
+	defer s.stopConfigWatcher()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("MaxConn = 300\n"), 0644))
+	assert.Eventually(t, func() bool {
+		reloadLock.Lock()
+		defer reloadLock.Unlock()
+		return zconf.GlobalObject.MaxConn == 300
+	}, 3*time.Second, 20*time.Millisecond)
+}
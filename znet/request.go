@@ -1,8 +1,10 @@
 package znet
 
 import (
+	"context"
 	"github.com/aceld/zinx/ziface"
 	"sync"
+	"time"
 )
 
 const (
@@ -22,6 +24,13 @@ type Request struct {
 	stepLock *sync.RWMutex      //并发互斥
 	needNext bool               //是否需要执行下一个路由函数
 	icResp   ziface.IcResp      //拦截器返回数据
+	ctx      context.Context    //请求的Context
+
+	writer     ziface.IResponseWriter //回复的写入方, 为nil时使用连接
+	replyMsgID uint32                 //Reply使用的消息ID
+	hasReplyID bool
+	err        error     //路由处理的错误
+	received   time.Time //创建请求(读取到消息)的时间
 }
 
 func (r *Request) GetResponse() ziface.IcResp {
@@ -39,6 +48,7 @@ func NewRequest(conn ziface.IConnection, msg ziface.IMessage) *Request {
 	req.msg = msg
 	req.stepLock = new(sync.RWMutex)
 	req.needNext = true
+	req.received = time.Now()
 
 	return req
 }
@@ -48,6 +58,85 @@ func (r *Request) GetMessage() ziface.IMessage {
 	return r.msg
 }
 
+// Context 请求的Context, 未设置时使用连接的Context, 连接关闭时被取消
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	if r.conn != nil {
+		if ctx := r.conn.Context(); ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+func (r *Request) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Reply 回复请求, 消息ID由MsgHandle的回复消息ID映射表决定, 没有映射时与请求的消息ID相同
+func (r *Request) Reply(data []byte) error {
+	msgID := r.GetMsgID()
+	if r.hasReplyID {
+		msgID = r.replyMsgID
+	}
+	return r.ReplyWith(msgID, data)
+}
+
+// ReplyWith 使用指定的消息ID回复请求, 请求携带关联序号时回复回传该序号
+func (r *Request) ReplyWith(msgID uint32, data []byte) error {
+	var writer ziface.IResponseWriter = r.conn
+	if r.writer != nil {
+		writer = r.writer
+	} else if r.conn == nil {
+		return ErrNoResponseWriter
+	}
+
+	seq := r.GetSeq()
+	if seq != 0 && seq&ziface.SeqReplyFlag == 0 {
+		seq |= ziface.SeqReplyFlag
+	} else {
+		seq = 0
+	}
+	// 回复回传请求的逻辑会话ID
+	if sessionID := r.GetSessionID(); sessionID != 0 {
+		if w, ok := writer.(ziface.IMuxWriter); ok {
+			return w.SendMuxMsg(sessionID, seq, msgID, data)
+		}
+	}
+	if seq != 0 {
+		return writer.SendSeqMsg(seq, msgID, data)
+	}
+	return writer.SendMsg(msgID, data)
+}
+
+func (r *Request) SetResponseWriter(writer ziface.IResponseWriter) {
+	r.writer = writer
+}
+
+func (r *Request) SetError(err error) {
+	r.err = err
+}
+
+func (r *Request) GetError() error {
+	return r.err
+}
+
+// reset 重试之前重置请求的处理状态
+func (r *Request) reset() {
+	r.stepLock.Lock()
+	r.steps = PRE_HANDLE
+	r.needNext = true
+	r.stepLock.Unlock()
+	r.err = nil
+}
+
+func (r *Request) setReplyMsgID(msgID uint32) {
+	r.replyMsgID = msgID
+	r.hasReplyID = true
+}
+
 // GetConnection 获取请求连接信息
 func (r *Request) GetConnection() ziface.IConnection {
 	return r.conn
@@ -63,6 +152,27 @@ func (r *Request) GetMsgID() uint32 {
 	return r.msg.GetMsgID()
 }
 
+// GetSeq 获取请求的关联序号, 0表示不携带
+func (r *Request) GetSeq() uint32 {
+	if seqMsg, ok := r.msg.(ziface.ISeqMessage); ok {
+		return seqMsg.GetSeq()
+	}
+	return 0
+}
+
+// GetSessionID 获取请求所属的逻辑会话ID, 0表示不属于任何逻辑会话
+func (r *Request) GetSessionID() uint32 {
+	if muxMsg, ok := r.msg.(ziface.IMuxMessage); ok {
+		return muxMsg.GetSessionID()
+	}
+	return 0
+}
+
+// GetProtocolVersion 获取当前连接协商后的协议版本号
+func (r *Request) GetProtocolVersion() uint32 {
+	return r.conn.GetProtocolVersion()
+}
+
 func (r *Request) BindRouter(router ziface.IRouter) {
 	r.router = router
 }
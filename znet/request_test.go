@@ -0,0 +1,102 @@
+package znet
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxRouter struct {
+	BaseRouter
+	done chan error
+}
+
+func (r *ctxRouter) Handle(request ziface.IRequest) {
+	<-request.Context().Done()
+	r.done <- request.Context().Err()
+}
+
+func TestRequestContext(t *testing.T) {
+	// 没有连接时使用Background
+	request := NewRequest(nil, zpack.NewMsgPackage(1, nil))
+	assert.Equal(t, context.Background(), request.Context())
+
+	// 路由的处理期限
+	mh := NewMsgHandle()
+	router := &ctxRouter{done: make(chan error, 1)}
+	router.SetTimeout(50 * time.Millisecond)
+	mh.AddRouter(1, router)
+
+	go mh.doMsgHandler(request)
+	select {
+	case err := <-router.done:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("request context not canceled")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	mh := NewMsgHandle()
+	var timeouts []uint32
+	mh.SetTimeoutHandler(func(request ziface.IRequest) {
+		timeouts = append(timeouts, request.GetMsgID())
+	})
+	router := &blockRouter{block: make(chan struct{}), handled: make(chan uint32, 1)}
+	router.SetTimeout(20 * time.Millisecond)
+	mh.AddRouter(2, router)
+
+	// 路由不响应Context时, 超时后不再等待
+	done := make(chan struct{})
+	go func() {
+		mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler timeout not detected")
+	}
+	assert.Equal(t, []uint32{2}, timeouts)
+	assert.Equal(t, uint64(1), mh.WorkerStats().Timeouts)
+
+	close(router.block)
+	assert.Equal(t, uint32(2), <-router.handled)
+}
+
+func TestRequestTimeoutHoldsSlot(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.SetConcurrencyLimit(1, 2)
+	var timeoutRequest ziface.IRequest
+	mh.SetTimeoutHandler(func(request ziface.IRequest) {
+		timeoutRequest = request
+	})
+	router := &blockRouter{block: make(chan struct{}), handled: make(chan uint32, 1)}
+	router.SetTimeout(20 * time.Millisecond)
+	mh.AddRouter(2, router)
+
+	// 超时返回后路由仍在执行, 并发名额与处理中计数保持占用
+	request := NewRequest(nil, zpack.NewMsgPackage(2, nil))
+	atomic.AddInt64(&mh.inFlight, 1)
+	mh.doMsgHandler(request)
+	assert.Equal(t, uint64(1), mh.WorkerStats().Timeouts)
+	assert.Equal(t, 1, len(mh.limits[2]))
+	assert.Equal(t, int64(1), mh.InFlight())
+
+	// 超时处理使用独立的请求对象
+	assert.NotNil(t, timeoutRequest)
+	assert.NotSame(t, request, timeoutRequest)
+	assert.Equal(t, uint32(2), timeoutRequest.GetMsgID())
+
+	// 路由真正退出后释放
+	close(router.block)
+	assert.Equal(t, uint32(2), <-router.handled)
+	assert.Eventually(t, func() bool {
+		return len(mh.limits[2]) == 0 && mh.InFlight() == 0
+	}, time.Second, time.Millisecond)
+}
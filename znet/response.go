@@ -0,0 +1,42 @@
+package znet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+var ErrNoResponseWriter = errors.New("request has no connection or response writer")
+
+// ResponseRecorder 记录请求的回复, 用于在没有真实连接的情况下测试路由
+type ResponseRecorder struct {
+	messages []ziface.IMessage
+	lock     sync.Mutex
+}
+
+func NewResponseRecorder() *ResponseRecorder {
+	return &ResponseRecorder{}
+}
+
+func (r *ResponseRecorder) SendMsg(msgID uint32, data []byte) error {
+	return r.SendSeqMsg(0, msgID, data)
+}
+
+func (r *ResponseRecorder) SendSeqMsg(seq uint32, msgID uint32, data []byte) error {
+	msg := zpack.NewMsgPackage(msgID, data)
+	msg.Seq = seq
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+// Messages 按顺序返回记录的全部回复
+func (r *ResponseRecorder) Messages() []ziface.IMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]ziface.IMessage(nil), r.messages...)
+}
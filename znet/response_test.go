@@ -0,0 +1,41 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type replyRouter struct {
+	BaseRouter
+}
+
+func (r *replyRouter) Handle(request ziface.IRequest) {
+	_ = request.Reply(append([]byte("re:"), request.GetData()...))
+}
+
+func TestRequestReply(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.AddRouter(1, &replyRouter{})
+	mh.AddRouter(2, &replyRouter{})
+	mh.SetReplyMsgID(1, 1001)
+
+	recorder := NewResponseRecorder()
+	for _, msgID := range []uint32{1, 2} {
+		request := NewRequest(nil, zpack.NewMsgPackage(msgID, []byte("hi")))
+		request.SetResponseWriter(recorder)
+		mh.doMsgHandler(request)
+	}
+
+	messages := recorder.Messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint32(1001), messages[0].GetMsgID())
+	assert.Equal(t, []byte("re:hi"), messages[0].GetData())
+	// 没有映射时使用请求的消息ID
+	assert.Equal(t, uint32(2), messages[1].GetMsgID())
+
+	request := NewRequest(nil, zpack.NewMsgPackage(3, nil))
+	assert.Equal(t, ErrNoResponseWriter, request.ReplyWith(3, nil))
+}
@@ -2,35 +2,93 @@ package znet
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 )
 
 type wsFakeWriter struct {
-	conn *net.TCPConn
-	rw   *bufio.ReadWriter
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	header http.Header
 }
 
-func newResponseWriter(conn *net.TCPConn) *wsFakeWriter {
+func newResponseWriter(conn net.Conn) *wsFakeWriter {
 	w := new(wsFakeWriter)
 	w.conn = conn
 	w.rw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	w.header = make(http.Header)
 
 	return w
 }
 
 func (w *wsFakeWriter) Header() http.Header {
-	return nil
+	return w.header
 }
 
 func (w *wsFakeWriter) Write(bytes []byte) (int, error) {
-	return 0, nil
+	return w.conn.Write(bytes)
 }
 
+// WriteHeader websocket升级失败时写回错误状态
 func (w *wsFakeWriter) WriteHeader(statusCode int) {
-	//TODO implement me
-	panic("implement me")
+	_, _ = fmt.Fprintf(w.conn, "HTTP/1.1 %d %s\r\nConnection: close\r\n\r\n", statusCode, http.StatusText(statusCode))
 }
+
 func (w *wsFakeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return w.conn, w.rw, nil
 }
+
+// httpResponseWriter 缓存HTTP处理方法写入的响应，处理完成后一次性写回连接
+type httpResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newHTTPResponseWriter() *httpResponseWriter {
+	return &httpResponseWriter{
+		header: make(http.Header),
+	}
+}
+
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *httpResponseWriter) Write(data []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *httpResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+// writeTo 将响应写回连接，写回后连接将被关闭
+func (w *httpResponseWriter) writeTo(conn net.Conn, request *http.Request) error {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.statusCode, http.StatusText(w.statusCode)),
+		StatusCode:    w.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          ioutil.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Close:         true,
+		Request:       request,
+	}
+
+	return resp.Write(conn)
+}
@@ -0,0 +1,157 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// InheritListenersEnv 热重启时传递给新进程的环境变量, 值为逗号分隔的监听地址
+// 第i个地址对应的监听端口文件描述符为 3+i (os/exec ExtraFiles 的约定)
+const InheritListenersEnv = "ZINX_INHERIT_LISTENERS"
+
+// DefaultRestartTimeout 热重启时当前进程排空连接的默认最长时间
+const DefaultRestartTimeout = 30 * time.Second
+
+// errRestartNotStarted 热重启的新进程没有启动, 当前进程继续提供服务
+var errRestartNotStarted = errors.New("hot restart process not started")
+
+var (
+	inheritOnce      sync.Once
+	inheritListeners map[string]*net.TCPListener
+	inheritErr       error
+)
+
+// inheritedListener 获取从父进程继承的监听端口, 没有继承时返回nil
+func inheritedListener(address string) (*net.TCPListener, error) {
+	inheritOnce.Do(func() {
+		inheritListeners, inheritErr = loadInheritedListeners()
+	})
+	if inheritErr != nil {
+		return nil, inheritErr
+	}
+	return inheritListeners[address], nil
+}
+
+func loadInheritedListeners() (map[string]*net.TCPListener, error) {
+	value := os.Getenv(InheritListenersEnv)
+	if value == "" {
+		return nil, nil
+	}
+	// 不再传递给之后启动的子进程
+	_ = os.Unsetenv(InheritListenersEnv)
+
+	listeners := make(map[string]*net.TCPListener)
+	for i, address := range strings.Split(value, ",") {
+		file := os.NewFile(uintptr(3+i), address)
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener %s: %v", address, err)
+		}
+
+		tcpListener, ok := listener.(*net.TCPListener)
+		if !ok {
+			_ = listener.Close()
+			return nil, fmt.Errorf("inherit listener %s: not a tcp listener", address)
+		}
+		listeners[address] = tcpListener
+		zlog.Ins().InfoF("[START] inherit listener %s from parent process", address)
+	}
+	return listeners, nil
+}
+
+func (s *Server) addNetListener(address string, listener *net.TCPListener) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.netListeners == nil {
+		s.netListeners = make(map[string]*net.TCPListener)
+	}
+	s.netListeners[address] = listener
+}
+
+// Restart 热重启
+// 启动一个新的进程(当前可执行文件与启动参数)并传递全部监听端口, 新进程启动后立即在相同端口上接受连接,
+// 当前进程不再接受新的连接, 排空已有连接后返回
+func (s *Server) Restart(ctx context.Context) error {
+	s.lock.RLock()
+	addresses := make([]string, 0, len(s.netListeners))
+	files := make([]*os.File, 0, len(s.netListeners))
+	for address, listener := range s.netListeners {
+		file, err := listener.File()
+		if err != nil {
+			s.lock.RUnlock()
+			closeFiles(files)
+			return fmt.Errorf("%w: %v", errRestartNotStarted, err)
+		}
+		addresses = append(addresses, address)
+		files = append(files, file)
+	}
+	s.lock.RUnlock()
+
+	if len(files) == 0 {
+		return fmt.Errorf("%w: no listener to inherit", errRestartNotStarted)
+	}
+
+	err := startProcess(addresses, files)
+	closeFiles(files)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRestartNotStarted, err)
+	}
+
+	zlog.Ins().InfoF("[RESTART] Zinx server , name %s, new process started, draining", s.Name)
+	return s.Drain(ctx)
+}
+
+// startProcess 启动新的进程, 监听端口通过ExtraFiles传递
+func startProcess(addresses []string, files []*os.File) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, InheritListenersEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, InheritListenersEnv+"="+strings.Join(addresses, ","))
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+
+	return cmd.Start()
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// SetRestartTimeout 启用收到热重启信号(SIGUSR2)时热重启, timeout为当前进程排空连接的最长时间
+func (s *Server) SetRestartTimeout(timeout time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.restartTimeout = timeout
+}
+
+func (s *Server) getRestartTimeout() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.restartTimeout
+}
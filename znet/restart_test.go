@@ -0,0 +1,22 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerRestartNoListener(t *testing.T) {
+	s := NewServer().(*Server)
+
+	err := s.Restart(context.Background())
+	assert.True(t, errors.Is(err, errRestartNotStarted))
+}
+
+func TestInheritedListenerNone(t *testing.T) {
+	listener, err := inheritedListener("127.0.0.1:8999")
+	assert.Nil(t, err)
+	assert.Nil(t, listener)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package znet
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignal 触发热重启的信号
+var restartSignal os.Signal = syscall.SIGUSR2
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package znet
+
+import "os"
+
+// restartSignal Windows不支持热重启
+var restartSignal os.Signal
@@ -0,0 +1,66 @@
+package znet
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteFunc(t *testing.T) {
+	var calls []string
+	mh := NewMsgHandle()
+	// 消息ID 1 下以消息体的第一个字节作为子命令
+	mh.SetRouteFunc(func(request ziface.IRequest) uint32 {
+		if request.GetMsgID() == 1 && len(request.GetData()) > 0 {
+			return 100 + uint32(request.GetData()[0])
+		}
+		return request.GetMsgID()
+	})
+	mh.Group(100, 199).Use(recordMiddleware(&calls, "sub"))
+	mh.AddRouter(101, &recordRouter{calls: &calls})
+	mh.AddRouter(2, &recordRouter{calls: &calls})
+
+	request := NewRequest(nil, zpack.NewMsgPackage(1, []byte{1, 'x'}))
+	mh.doMsgHandler(request)
+	assert.Equal(t, []string{"sub before", "pre", "handle", "post", "sub after"}, calls)
+	assert.Equal(t, uint32(1), request.GetMsgID())
+
+	calls = nil
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Equal(t, []string{"pre", "handle", "post"}, calls)
+
+	// 计算出的消息ID没有对应路由
+	calls = nil
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, []byte{9})))
+	assert.Empty(t, calls)
+}
+
+func TestRouteFuncOnce(t *testing.T) {
+	var calls int32
+	mh := NewMsgHandle()
+	mh.SetRouteFunc(func(request ziface.IRequest) uint32 {
+		atomic.AddInt32(&calls, 1)
+		return 100 + uint32(request.GetData()[0])
+	})
+	mh.SetWorkerPool(NewWorkerPool(1, 8))
+	mh.SetRoutePool(NewWorkerPool(1, 8), 101)
+	mh.SetMsgPriority(ziface.PriorityHigh, 101)
+	mh.StartWorkerPool()
+
+	router := &blockRouter{handled: make(chan uint32, 1)}
+	mh.AddRouter(101, router)
+
+	// 选择工作池、优先级与路由时只计算一次
+	mh.SendMsgToTaskQueue(NewRequest(&Connection{connID: 1}, zpack.NewMsgPackage(1, []byte{1})))
+	select {
+	case msgID := <-router.handled:
+		assert.Equal(t, uint32(1), msgID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("request not handled")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
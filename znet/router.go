@@ -1,9 +1,17 @@
 package znet
 
-import "github.com/aceld/zinx/ziface"
+import (
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
 
 //BaseRouter 实现router时，先嵌入这个基类，然后根据需要对这个基类的方法进行重写
-type BaseRouter struct{}
+type BaseRouter struct {
+	middlewares []ziface.Middleware
+	timeout     time.Duration
+	recovery    ziface.RecoverHandler
+}
 
 //这里之所以BaseRouter的方法都为空，
 // 是因为有的Router不希望有PreHandle或PostHandle
@@ -17,3 +25,33 @@ func (br *BaseRouter) Handle(req ziface.IRequest) {}
 
 //PostHandle -
 func (br *BaseRouter) PostHandle(req ziface.IRequest) {}
+
+//Use 添加只作用于当前路由的中间件, 在Server的全局中间件之后执行
+func (br *BaseRouter) Use(middlewares ...ziface.Middleware) {
+	br.middlewares = append(br.middlewares, middlewares...)
+}
+
+//Middlewares -
+func (br *BaseRouter) Middlewares() []ziface.Middleware {
+	return br.middlewares
+}
+
+//SetTimeout 设置路由处理请求的期限, 超时后请求的Context被取消, Worker不再等待路由, 0表示不限制
+func (br *BaseRouter) SetTimeout(timeout time.Duration) {
+	br.timeout = timeout
+}
+
+//Timeout -
+func (br *BaseRouter) Timeout() time.Duration {
+	return br.timeout
+}
+
+//SetRecoverHandler 设置只作用于当前路由的panic恢复处理, 优先于Server的恢复处理
+func (br *BaseRouter) SetRecoverHandler(handler ziface.RecoverHandler) {
+	br.recovery = handler
+}
+
+//RecoverHandler -
+func (br *BaseRouter) RecoverHandler() ziface.RecoverHandler {
+	return br.recovery
+}
@@ -0,0 +1,56 @@
+package znet
+
+import (
+	"fmt"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// RouterGroup 路由分组
+type RouterGroup struct {
+	start       uint32
+	end         uint32
+	handler     ziface.IMsgHandle
+	middlewares []ziface.Middleware
+}
+
+func NewRouterGroup(start, end uint32, handler ziface.IMsgHandle) *RouterGroup {
+	if start > end {
+		panic(fmt.Sprintf("invalid router group range [%d, %d]", start, end))
+	}
+	return &RouterGroup{
+		start:   start,
+		end:     end,
+		handler: handler,
+	}
+}
+
+func (g *RouterGroup) Use(middlewares ...ziface.Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+func (g *RouterGroup) AddRouter(msgID uint32, router ziface.IRouter) {
+	if !g.Contains(msgID) {
+		panic(fmt.Sprintf("msgID = %d out of router group range [%d, %d]", msgID, g.start, g.end))
+	}
+	g.handler.AddRouter(msgID, router)
+}
+
+func (g *RouterGroup) AddRouters(routers map[uint32]ziface.IRouter) {
+	for msgID := range routers {
+		if !g.Contains(msgID) {
+			panic(fmt.Sprintf("msgID = %d out of router group range [%d, %d]", msgID, g.start, g.end))
+		}
+	}
+	for msgID, router := range routers {
+		g.handler.AddRouter(msgID, router)
+	}
+}
+
+func (g *RouterGroup) Contains(msgID uint32) bool {
+	return msgID >= g.start && msgID <= g.end
+}
+
+func (g *RouterGroup) Range() (uint32, uint32) {
+	return g.start, g.end
+}
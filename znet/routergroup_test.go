@@ -0,0 +1,46 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterGroup(t *testing.T) {
+	var calls []string
+	mh := NewMsgHandle()
+	mh.Use(recordMiddleware(&calls, "global"))
+
+	auth := mh.Group(2000, 2999)
+	auth.Use(recordMiddleware(&calls, "auth"))
+	auth.AddRouters(map[uint32]ziface.IRouter{
+		2001: &recordRouter{calls: &calls},
+		2002: &recordRouter{calls: &calls},
+	})
+	// 直接注册在区间内的路由同样经过分组的中间件
+	mh.AddRouter(2003, &recordRouter{calls: &calls})
+	mh.AddRouter(1, &recordRouter{calls: &calls})
+
+	assert.Panics(t, func() {
+		auth.AddRouter(3000, &recordRouter{calls: &calls})
+	})
+	start, end := auth.Range()
+	assert.Equal(t, uint32(2000), start)
+	assert.Equal(t, uint32(2999), end)
+
+	for _, msgID := range []uint32{2001, 2003} {
+		calls = nil
+		mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(msgID, nil)))
+		assert.Equal(t, []string{
+			"global before", "auth before",
+			"pre", "handle", "post",
+			"auth after", "global after",
+		}, calls, msgID)
+	}
+
+	calls = nil
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, []string{"global before", "pre", "handle", "post", "global after"}, calls)
+}
@@ -0,0 +1,73 @@
+package znet
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// routeStat 一个路由的处理统计, 通过atomic访问
+type routeStat struct {
+	count   uint64
+	errors  uint64
+	execSum int64
+	maxExec int64
+	latency latencyHistogram
+}
+
+// routeStats 按消息ID记录路由的处理统计
+type routeStats struct {
+	routes sync.Map // msgID -> *routeStat
+}
+
+func (rs *routeStats) get(msgID uint32) *routeStat {
+	v, ok := rs.routes.Load(msgID)
+	if !ok {
+		v, _ = rs.routes.LoadOrStore(msgID, &routeStat{})
+	}
+	return v.(*routeStat)
+}
+
+// observe 记录路由的一次处理
+func (rs *routeStats) observe(msgID uint32, elapsed time.Duration, failed bool) {
+	stat := rs.get(msgID)
+	atomic.AddUint64(&stat.count, 1)
+	if failed {
+		atomic.AddUint64(&stat.errors, 1)
+	}
+	atomic.AddInt64(&stat.execSum, int64(elapsed))
+	storeMax(&stat.maxExec, int64(elapsed))
+}
+
+// observeLatency 记录请求的端到端延迟
+func (rs *routeStats) observeLatency(msgID uint32, latency time.Duration) {
+	rs.get(msgID).latency.record(latency)
+}
+
+// RouteStats 每个路由(消息ID)的处理次数、失败次数、处理时间与端到端延迟的分位数, 按消息ID排列
+func (mh *MsgHandle) RouteStats() []ziface.RouteStats {
+	var stats []ziface.RouteStats
+	mh.routeStats.routes.Range(func(key, value interface{}) bool {
+		stat := value.(*routeStat)
+		s := ziface.RouteStats{
+			MsgID:   key.(uint32),
+			Count:   atomic.LoadUint64(&stat.count),
+			Errors:  atomic.LoadUint64(&stat.errors),
+			MaxExec: time.Duration(atomic.LoadInt64(&stat.maxExec)),
+		}
+		if s.Count > 0 {
+			s.AvgExec = time.Duration(atomic.LoadInt64(&stat.execSum) / int64(s.Count))
+		}
+		p := stat.latency.percentiles(0.5, 0.95, 0.99)
+		s.P50, s.P95, s.P99 = p[0], p[1], p[2]
+		stats = append(stats, s)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].MsgID < stats[j].MsgID
+	})
+	return stats
+}
@@ -0,0 +1,276 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	DefaultWorkerScaleInterval      = time.Second
+	DefaultWorkerScaleWaitThreshold = 50 * time.Millisecond
+	DefaultWorkerScaleIdleRounds    = 3
+
+	// scaleBatch Worker每次从一个任务队列连续处理的最多任务数, 之后让出给其他队列
+	scaleBatch = 64
+)
+
+type scaleTask struct {
+	task     func()
+	enqueued time.Time
+}
+
+// scaleQueue 任务队列, 同一时刻只由一个Worker处理, 保证相同key(相同优先级)的任务按顺序执行
+type scaleQueue struct {
+	tasks     chan scaleTask
+	high      chan scaleTask
+	scheduled int32
+	// 连续执行的高优先级任务数量, 只由正在处理该队列的Worker访问
+	highRun int
+}
+
+// tryTake 取出下一个任务, 高优先级的任务先执行, 队列为空时返回false
+func (q *scaleQueue) tryTake() (scaleTask, bool) {
+	if q.highRun >= DefaultPriorityStarvationLimit {
+		select {
+		case t := <-q.tasks:
+			q.highRun = 0
+			return t, true
+		default:
+		}
+	}
+	select {
+	case t := <-q.high:
+		q.highRun++
+		return t, true
+	default:
+	}
+	select {
+	case t := <-q.tasks:
+		q.highRun = 0
+		return t, true
+	default:
+		return scaleTask{}, false
+	}
+}
+
+func (q *scaleQueue) len() int {
+	return len(q.tasks) + len(q.high)
+}
+
+// ScalingWorkerPool 可自动伸缩的Worker工作池
+// 任务按key分配到固定数量(MaxSize)的任务队列, 有任务的队列排队等待空闲的Worker处理,
+// Worker的数量在MinSize与MaxSize之间按任务积压与等待时间伸缩
+type ScalingWorkerPool struct {
+	config  ziface.WorkerScaleConfig
+	queues  []*scaleQueue
+	ready   chan *scaleQueue
+	shrink  chan struct{}
+	workers int32
+
+	// 当前检测周期内任务的等待时间
+	waitSum   int64
+	waitCount int64
+
+	startOnce sync.Once
+}
+
+// NewScalingWorkerPool 创建可自动伸缩的Worker工作池, queueLen为每个任务队列的长度
+func NewScalingWorkerPool(config ziface.WorkerScaleConfig, queueLen uint32) *ScalingWorkerPool {
+	if config.MaxSize < config.MinSize {
+		config.MaxSize = config.MinSize
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultWorkerScaleInterval
+	}
+	if config.WaitThreshold <= 0 {
+		config.WaitThreshold = DefaultWorkerScaleWaitThreshold
+	}
+	if config.IdleRounds <= 0 {
+		config.IdleRounds = DefaultWorkerScaleIdleRounds
+	}
+
+	pool := &ScalingWorkerPool{
+		config: config,
+		queues: make([]*scaleQueue, config.MaxSize),
+		ready:  make(chan *scaleQueue, config.MaxSize),
+		shrink: make(chan struct{}),
+	}
+	for i := range pool.queues {
+		pool.queues[i] = &scaleQueue{
+			tasks: make(chan scaleTask, queueLen),
+			high:  make(chan scaleTask, queueLen),
+		}
+	}
+	return pool
+}
+
+func (pool *ScalingWorkerPool) Start() {
+	pool.startOnce.Do(func() {
+		for i := uint32(0); i < pool.config.MinSize; i++ {
+			pool.addWorker()
+		}
+		if pool.config.MaxSize > pool.config.MinSize {
+			go pool.autoScale()
+		}
+	})
+}
+
+// Size 任务队列的数量, 即最多Worker数量
+func (pool *ScalingWorkerPool) Size() uint32 {
+	return pool.config.MaxSize
+}
+
+// Workers 当前Worker数量
+func (pool *ScalingWorkerPool) Workers() int {
+	return int(atomic.LoadInt32(&pool.workers))
+}
+
+func (pool *ScalingWorkerPool) Submit(key uint64, task func()) {
+	pool.SubmitPriority(key, ziface.PriorityNormal, task)
+}
+
+func (pool *ScalingWorkerPool) SubmitPriority(key uint64, priority ziface.MsgPriority, task func()) {
+	queue := pool.queues[key%uint64(len(pool.queues))]
+	if priority >= ziface.PriorityHigh {
+		queue.high <- scaleTask{task: task, enqueued: time.Now()}
+	} else {
+		queue.tasks <- scaleTask{task: task, enqueued: time.Now()}
+	}
+	pool.schedule(queue)
+}
+
+func (pool *ScalingWorkerPool) TrySubmitPriority(key uint64, priority ziface.MsgPriority, task func()) bool {
+	queue := pool.queues[key%uint64(len(pool.queues))]
+	ch := queue.tasks
+	if priority >= ziface.PriorityHigh {
+		ch = queue.high
+	}
+	select {
+	case ch <- scaleTask{task: task, enqueued: time.Now()}:
+	default:
+		return false
+	}
+	pool.schedule(queue)
+	return true
+}
+
+// schedule 任务队列不在等待或处理中时排队等待Worker
+func (pool *ScalingWorkerPool) schedule(queue *scaleQueue) {
+	if atomic.CompareAndSwapInt32(&queue.scheduled, 0, 1) {
+		pool.ready <- queue
+	}
+}
+
+func (pool *ScalingWorkerPool) QueueLen(workerID uint32) (int, int) {
+	if workerID >= uint32(len(pool.queues)) {
+		return 0, 0
+	}
+	queue := pool.queues[workerID].tasks
+	return len(queue), cap(queue)
+}
+
+// QueueUsage 任务队列(包括高优先级队列)的最高使用率
+func (pool *ScalingWorkerPool) QueueUsage() float64 {
+	var usage float64
+	for _, queue := range pool.queues {
+		for _, ch := range []chan scaleTask{queue.tasks, queue.high} {
+			if cap(ch) == 0 {
+				continue
+			}
+			if u := float64(len(ch)) / float64(cap(ch)); u > usage {
+				usage = u
+			}
+		}
+	}
+	return usage
+}
+
+func (pool *ScalingWorkerPool) addWorker() {
+	workerID := atomic.AddInt32(&pool.workers, 1)
+	zlog.Ins().InfoF("Scaling Worker ID = %d is started.", workerID)
+	go pool.startOneWorker()
+}
+
+// startOneWorker 不断的处理排队的任务队列, 收到缩容信号时退出
+func (pool *ScalingWorkerPool) startOneWorker() {
+	for {
+		select {
+		case <-pool.shrink:
+			return
+		case queue := <-pool.ready:
+			pool.runQueue(queue)
+		}
+	}
+}
+
+// runQueue 连续处理一个任务队列中的任务
+func (pool *ScalingWorkerPool) runQueue(queue *scaleQueue) {
+	for i := 0; i < scaleBatch; i++ {
+		t, ok := queue.tryTake()
+		if !ok {
+			// 队列已空, 释放队列后再次检查, 避免遗漏释放前刚提交的任务
+			atomic.StoreInt32(&queue.scheduled, 0)
+			if queue.len() > 0 {
+				pool.schedule(queue)
+			}
+			return
+		}
+		atomic.AddInt64(&pool.waitSum, int64(time.Since(t.enqueued)))
+		atomic.AddInt64(&pool.waitCount, 1)
+		t.task()
+	}
+	// 处理的任务数达到上限, 重新排队让出给其他队列
+	pool.ready <- queue
+}
+
+// autoScale 每个检测周期根据任务积压与平均等待时间调整Worker数量
+func (pool *ScalingWorkerPool) autoScale() {
+	ticker := time.NewTicker(pool.config.Interval)
+	defer ticker.Stop()
+
+	idleRounds := 0
+	for range ticker.C {
+		var pending int
+		for _, queue := range pool.queues {
+			pending += queue.len()
+		}
+		var avgWait time.Duration
+		if count := atomic.SwapInt64(&pool.waitCount, 0); count > 0 {
+			avgWait = time.Duration(atomic.SwapInt64(&pool.waitSum, 0) / count)
+		} else {
+			atomic.StoreInt64(&pool.waitSum, 0)
+		}
+
+		workers := uint32(pool.Workers())
+		switch {
+		case pending > int(workers) || avgWait > pool.config.WaitThreshold:
+			// 扩容: 每次增加当前数量的1/4(至少1个)
+			idleRounds = 0
+			grow := workers / 4
+			if grow == 0 {
+				grow = 1
+			}
+			for i := uint32(0); i < grow && workers < pool.config.MaxSize; i++ {
+				pool.addWorker()
+				workers++
+			}
+		case pending == 0 && avgWait < pool.config.WaitThreshold/2:
+			// 缩容: 连续空闲多个检测周期后每次减少1个, 避免负载波动时反复伸缩
+			idleRounds++
+			if idleRounds >= pool.config.IdleRounds && workers > pool.config.MinSize {
+				select {
+				case pool.shrink <- struct{}{}:
+					atomic.AddInt32(&pool.workers, -1)
+					idleRounds = 0
+				default:
+				}
+			}
+		default:
+			idleRounds = 0
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package znet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalingWorkerPool(t *testing.T) {
+	pool := NewScalingWorkerPool(ziface.WorkerScaleConfig{
+		MinSize:       1,
+		MaxSize:       4,
+		Interval:      10 * time.Millisecond,
+		WaitThreshold: 5 * time.Millisecond,
+		IdleRounds:    2,
+	}, 256)
+	pool.Start()
+	assert.Equal(t, uint32(4), pool.Size())
+	assert.Equal(t, 1, pool.Workers())
+
+	// 相同key的任务按顺序执行
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var order []int
+	for i := 0; i < 200; i++ {
+		i := i
+		wg.Add(1)
+		pool.Submit(1, func() {
+			defer wg.Done()
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+		})
+	}
+	wg.Wait()
+	for i := range order {
+		assert.Equal(t, i, order[i])
+	}
+
+	// 任务积压时扩容
+	block := make(chan struct{})
+	for key := uint64(0); key < 4; key++ {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			pool.Submit(key, func() {
+				defer wg.Done()
+				<-block
+			})
+		}
+	}
+	assert.Eventually(t, func() bool { return pool.Workers() == 4 }, 3*time.Second, 10*time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	// 连续空闲后缩容到最少数量
+	assert.Eventually(t, func() bool { return pool.Workers() == 1 }, 3*time.Second, 10*time.Millisecond)
+}
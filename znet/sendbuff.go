@@ -0,0 +1,113 @@
+package znet
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// DefaultSendBuffTimeout 发送缓冲队列已满时默认的阻塞等待时间
+const DefaultSendBuffTimeout = 5 * time.Millisecond
+
+var (
+	ErrSendBuffTimeout = errors.New("send buff msg timeout")
+	ErrSendBuffDropped = errors.New("send buff full, msg dropped")
+	ErrSlowConsumer    = errors.New("send buff full, slow consumer closed")
+	ErrSendBuffClosed  = errors.New("connection closed when send buff msg")
+)
+
+// sendBuffer 获取连接发送缓冲队列中等待发送(包括正在写出)的消息数量, 用于排空连接
+type sendBuffer interface {
+	sendBuffLen() int
+}
+
+// sendBuffOwner 设置了发送缓冲队列处理策略的Server, 连接创建时继承
+type sendBuffOwner interface {
+	getSendBuffConfig() ziface.SendBuffConfig
+	getSendBuffStats() *ziface.SendBuffStats
+}
+
+// pushSendBuff 将数据放入连接的发送缓冲队列, 队列已满时按照策略处理
+// pending 记录队列中等待发送的消息数量, 放入队列之前加一, 写Goroutine写出消息后减一
+func pushSendBuff(conn ziface.IConnection, ch chan []byte, pending *int64, config ziface.SendBuffConfig, stats *ziface.SendBuffStats, data []byte) (err error) {
+	if pending == nil {
+		pending = new(int64)
+	}
+	if stats == nil {
+		stats = &ziface.SendBuffStats{}
+	}
+
+	atomic.AddInt64(pending, 1)
+	defer func() {
+		if err != nil {
+			atomic.AddInt64(pending, -1)
+		}
+	}()
+
+	select {
+	case ch <- data:
+		return nil
+	default:
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 && config.Policy == ziface.SendBuffBlock {
+		timeout = DefaultSendBuffTimeout
+	}
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case ch <- data:
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	switch config.Policy {
+	case ziface.SendBuffDropOldest:
+		var closed <-chan struct{}
+		if ctx := conn.Context(); ctx != nil {
+			closed = ctx.Done()
+		}
+		for {
+			select {
+			case ch <- data:
+				return nil
+			default:
+			}
+
+			// 取出最早的消息丢弃, 腾出位置
+			// 队列中没有可丢弃的消息时(如无缓冲的队列, 写Goroutine正阻塞在限速等待中), 阻塞等待写Goroutine取走, 不空转
+			select {
+			case ch <- data:
+				return nil
+			case old := <-ch:
+				// 被丢弃的消息不再由写Goroutine写出, 在这里减去等待发送的数量
+				atomic.AddInt64(pending, -1)
+				atomic.AddUint64(&stats.DropOldest, 1)
+				if config.OnDrop != nil {
+					config.OnDrop(conn, old)
+				}
+			case <-closed:
+				return ErrSendBuffClosed
+			}
+		}
+	case ziface.SendBuffDropNewest:
+		atomic.AddUint64(&stats.DropNewest, 1)
+		if config.OnDrop != nil {
+			config.OnDrop(conn, data)
+		}
+		return ErrSendBuffDropped
+	case ziface.SendBuffCloseConn:
+		atomic.AddUint64(&stats.Closed, 1)
+		conn.Stop()
+		return ErrSlowConsumer
+	default:
+		atomic.AddUint64(&stats.Timeout, 1)
+		return ErrSendBuffTimeout
+	}
+}
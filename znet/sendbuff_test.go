@@ -0,0 +1,113 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushSendBuff(t *testing.T) {
+	conn := &Connection{connID: 1}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	stats := &ziface.SendBuffStats{}
+
+	var dropped [][]byte
+	config := ziface.SendBuffConfig{
+		OnDrop: func(conn ziface.IConnection, data []byte) {
+			dropped = append(dropped, data)
+		},
+	}
+
+	ch := make(chan []byte, 2)
+	assert.Nil(t, pushSendBuff(conn, ch, nil, config, stats, []byte("1")))
+	assert.Nil(t, pushSendBuff(conn, ch, nil, config, stats, []byte("2")))
+
+	// 默认阻塞等待, 超时返回错误
+	assert.Equal(t, ErrSendBuffTimeout, pushSendBuff(conn, ch, nil, config, stats, []byte("3")))
+	assert.Equal(t, uint64(1), stats.Timeout)
+
+	// 丢弃当前消息
+	config.Policy = ziface.SendBuffDropNewest
+	assert.Equal(t, ErrSendBuffDropped, pushSendBuff(conn, ch, nil, config, stats, []byte("3")))
+	assert.Equal(t, [][]byte{[]byte("3")}, dropped)
+
+	// 丢弃最早的消息
+	config.Policy = ziface.SendBuffDropOldest
+	assert.Nil(t, pushSendBuff(conn, ch, nil, config, stats, []byte("4")))
+	assert.Equal(t, []byte("1"), dropped[1])
+	assert.Equal(t, []byte("2"), <-ch)
+	assert.Equal(t, []byte("4"), <-ch)
+
+	// 关闭慢消费者连接
+	config.Policy = ziface.SendBuffCloseConn
+	ch <- []byte("5")
+	ch <- []byte("6")
+	assert.Equal(t, ErrSlowConsumer, pushSendBuff(conn, ch, nil, config, stats, []byte("7")))
+	assert.NotNil(t, conn.ctx.Err())
+
+	assert.Equal(t, ziface.SendBuffStats{Timeout: 1, DropOldest: 1, DropNewest: 1, Closed: 1}, *stats)
+}
+
+func TestSendBuffDropOldestDrain(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	c := &Connection{conn: local, connID: 1, stats: newConnStats(), msgBuffChan: make(chan []byte, 1)}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+	c.sendBuffConfig = ziface.SendBuffConfig{Policy: ziface.SendBuffDropOldest, Timeout: time.Millisecond}
+
+	// 队列已满时丢弃最早的消息, 被丢弃的消息不计入等待发送的数量
+	assert.Nil(t, c.SendToQueue([]byte("1")))
+	assert.Nil(t, c.SendToQueue([]byte("2")))
+	assert.Equal(t, 1, c.sendBuffLen())
+
+	go c.StartWriter()
+	buf := make([]byte, 1)
+	_, err := io.ReadFull(remote, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("2"), buf)
+
+	// 写出剩余的消息后排空完成
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, waitUntil(ctx, func() bool { return c.sendBuffLen() == 0 }))
+}
+
+func TestSendBuffDropOldestUnbuffered(t *testing.T) {
+	conn := &Connection{connID: 1}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	config := ziface.SendBuffConfig{Policy: ziface.SendBuffDropOldest, Timeout: time.Millisecond}
+
+	// 无缓冲的队列中没有可丢弃的消息, 阻塞等待写Goroutine取走
+	ch := make(chan []byte)
+	done := make(chan error, 1)
+	go func() {
+		done <- pushSendBuff(conn, ch, nil, config, nil, []byte("1"))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("push returned early: %v", err)
+	default:
+	}
+	assert.Equal(t, []byte("1"), <-ch)
+	assert.Nil(t, <-done)
+
+	// 连接关闭后不再等待
+	go func() {
+		done <- pushSendBuff(conn, ch, nil, config, nil, []byte("2"))
+	}()
+	conn.cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrSendBuffClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("push not canceled")
+	}
+}
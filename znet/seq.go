@@ -0,0 +1,98 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+var ErrCallConnClosed = errors.New("connection closed before call reply")
+
+// seqCalls 连接上等待对端回复的请求, 按关联序号匹配回复
+type seqCalls struct {
+	seq     uint32
+	pending map[uint32]chan ziface.IMessage
+	lock    sync.Mutex
+}
+
+// add 分配关联序号并登记等待回复, 序号在[1, SeqReplyFlag)内循环使用
+func (sc *seqCalls) add() (uint32, <-chan ziface.IMessage) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	if sc.pending == nil {
+		sc.pending = make(map[uint32]chan ziface.IMessage)
+	}
+	for {
+		sc.seq = (sc.seq + 1) &^ ziface.SeqReplyFlag
+		if _, ok := sc.pending[sc.seq]; sc.seq != 0 && !ok {
+			break
+		}
+	}
+
+	ch := make(chan ziface.IMessage, 1)
+	sc.pending[sc.seq] = ch
+	return sc.seq, ch
+}
+
+// remove 取消等待回复, 如超时或连接关闭
+func (sc *seqCalls) remove(seq uint32) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	delete(sc.pending, seq)
+}
+
+// resolve 消息是等待中的请求的回复时交给等待方, 返回false表示消息需要正常路由
+func (sc *seqCalls) resolve(msg ziface.IMessage) bool {
+	seqMsg, ok := msg.(ziface.ISeqMessage)
+	if !ok || seqMsg.GetSeq()&ziface.SeqReplyFlag == 0 {
+		return false
+	}
+	seq := seqMsg.GetSeq() &^ ziface.SeqReplyFlag
+
+	sc.lock.Lock()
+	ch, ok := sc.pending[seq]
+	delete(sc.pending, seq)
+	sc.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+	ch <- msg
+	return true
+}
+
+// seqResolver 支持按关联序号匹配回复的连接
+type seqResolver interface {
+	resolveSeq(msg ziface.IMessage) bool
+}
+
+// packSeqMsg 执行发出消息的拦截器后封包携带关联序号的消息
+func packSeqMsg(conn ziface.IConnection, packet ziface.IDataPack, out *outChain, seq uint32, msgID uint32, data []byte) ([]byte, error) {
+	msg := zpack.NewMsgPackage(msgID, data)
+	msg.Seq = seq
+	return out.pack(conn, packet, msg)
+}
+
+// call 使用send发送携带关联序号的请求并等待对端回复, 直到ctx取消或连接关闭
+func (sc *seqCalls) call(ctx context.Context, connCtx context.Context, send func(seq uint32) error) ([]byte, error) {
+	seq, ch := sc.add()
+	if err := send(seq); err != nil {
+		sc.remove(seq)
+		return nil, err
+	}
+
+	select {
+	case reply := <-ch:
+		return reply.GetData(), nil
+	case <-ctx.Done():
+		sc.remove(seq)
+		return nil, ctx.Err()
+	case <-connCtx.Done():
+		sc.remove(seq)
+		return nil, ErrCallConnClosed
+	}
+}
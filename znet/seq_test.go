@@ -0,0 +1,145 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeqCalls(t *testing.T) {
+	var calls seqCalls
+	seq, ch := calls.add()
+	assert.Equal(t, uint32(1), seq)
+
+	// 没有回复标记的消息是对端的请求, 正常路由
+	msg := zpack.NewMsgPackage(1, []byte("req"))
+	msg.Seq = seq
+	assert.False(t, calls.resolve(msg))
+
+	reply := zpack.NewMsgPackage(1, []byte("reply"))
+	reply.Seq = seq | ziface.SeqReplyFlag
+	assert.True(t, calls.resolve(reply))
+	assert.Equal(t, reply, <-ch)
+
+	// 已经匹配过或已取消的回复不再匹配
+	assert.False(t, calls.resolve(reply))
+	seq, _ = calls.add()
+	calls.remove(seq)
+	reply.Seq = seq | ziface.SeqReplyFlag
+	assert.False(t, calls.resolve(reply))
+
+	// 序号循环使用时跳过0
+	calls.seq = ziface.SeqReplyFlag - 1
+	seq, _ = calls.add()
+	assert.Equal(t, uint32(1), seq)
+}
+
+func TestSeqReply(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewSeqDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28988
+	s.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	s.AddRouter(1, &replyRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:28988")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	dp := zpack.NewSeqDataPack()
+	request := zpack.NewMsgPackage(1, []byte("hi"))
+	request.Seq = 7
+	data, _ := dp.Pack(request)
+	_, _ = conn.Write(data)
+
+	// 回复回传请求的关联序号并带上回复标记
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	head := make([]byte, dp.GetHeadLen())
+	_, err = io.ReadFull(conn, head)
+	assert.Nil(t, err)
+	reply, err := dp.Unpack(head)
+	assert.Nil(t, err)
+	assert.Equal(t, 7|ziface.SeqReplyFlag, reply.(ziface.ISeqMessage).GetSeq())
+	body := make([]byte, reply.GetDataLen())
+	_, _ = io.ReadFull(conn, body)
+	assert.Equal(t, []byte("re:hi"), body)
+}
+
+type callRouter struct {
+	BaseRouter
+}
+
+func (r *callRouter) Handle(request ziface.IRequest) {
+	// 回复之前反向调用客户端
+	data, err := request.GetConnection().Call(request.Context(), 2, request.GetData())
+	if err != nil {
+		return
+	}
+	_ = request.Reply(data)
+}
+
+func TestSeqCall(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewSeqDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28987
+	s.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	s.AddRouter(1, &callRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewClient("127.0.0.1", 28987, WithPacketClient(zpack.NewSeqDataPack()))
+	client.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	client.AddRouter(2, &replyRouter{})
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	data, err := client.Call(ctx, 1, []byte("hi"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("re:hi"), data)
+
+	// 对端不回复时等待超时
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.Call(ctx, 3, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestClientRequest(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewSeqDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28982
+	s.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	s.AddRouter(2, &replyRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewClient("127.0.0.1", 28982, WithPacketClient(zpack.NewSeqDataPack()), WithRequestTimeoutClient(100*time.Millisecond))
+	client.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	data, err := client.Request(context.Background(), 2, []byte("hi"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("re:hi"), data)
+
+	// 没有期限的ctx使用默认的等待时间
+	start := time.Now()
+	_, err = client.Request(context.Background(), 3, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -2,6 +2,7 @@ package znet
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"errors"
@@ -14,6 +15,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aceld/zinx/ziface"
@@ -62,20 +66,103 @@ type Server struct {
 
 	// websocket
 	upgrader *websocket.Upgrader
+
+	// TCP端口上收到的普通HTTP请求(非Websocket)的处理方法
+	httpMux *http.ServeMux
+	// 没有匹配的HTTP处理方法时重定向的地址，为空则返回404
+	httpRedirect string
+	// 协议版本协商器，为nil表示不启用版本协商
+	negotiator ziface.IVersionNegotiator
+	// 附加的监听端口
+	listeners []ziface.ListenerConfig
+	// 保护运行时可替换的解码器
+	lock sync.RWMutex
+	// 分组(房间)管理器
+	groupMgr ziface.IGroupManager
+	// 主题发布订阅
+	pubSub ziface.IPubSub
+	// 会话管理, nil表示未启用会话恢复
+	sessionMgr ziface.ISessionManager
+	// 新建连接默认的读写带宽限制
+	connRateLimit ziface.RateLimit
+	// 全部连接共享的读写带宽限制
+	readLimiter  *RateLimiter
+	writeLimiter *RateLimiter
+	// 发送缓冲队列已满时的处理策略
+	sendBuffConfig ziface.SendBuffConfig
+	// 发送缓冲队列已满时各种处理结果的计数
+	sendBuffStats ziface.SendBuffStats
+	// 新建连接默认的读写空闲超时时间, 0表示不检测
+	readIdleTimeout  time.Duration
+	writeIdleTimeout time.Duration
+	// 监听端口是否已经关闭
+	listenClosed bool
+	// 排空连接时通知客户端的消息, nil表示不通知
+	drainMsg ziface.IMessage
+	// 正在监听的TCP端口, 热重启时传递给新进程
+	netListeners map[string]*net.TCPListener
+	// 热重启时当前进程排空连接的最长时间
+	restartTimeout time.Duration
+	// 应用到新接受的TCP连接上的socket参数
+	tcpOptions tcpOptions
+	// 服务生命周期事件总线
+	eventBus ziface.IEventBus
+	// 健康检查配置
+	healthConfig ziface.HealthConfig
+	// 独立的HTTP健康检查监听
+	healthServer *http.Server
+	// 正在监听的端口数量
+	listening int32
+	// 管理HTTP服务的监听地址与访问令牌
+	adminAddr   string
+	adminToken  string
+	adminServer *http.Server
+	// 是否提供管理Web控制台
+	adminDashboard bool
+	// 禁止建立连接的IP
+	bans map[string]struct{}
+	// ConnManager由多个Server共享, 停止服务时只关闭当前Server的连接
+	sharedConnMgr bool
+	// 指标接口在事件总线上的订阅ID
+	metricsSub uint64
+	// StatsD导出, nil表示不推送
+	statsD *StatsDExporter
+	// 发出消息的拦截器
+	outChain *outChain
+	// 注册中心配置
+	registryConfig ziface.RegistryConfig
+	// 停止续约, nil表示没有注册
+	registryExit chan struct{}
+	// 配置文件监听, nil表示没有监听
+	configWatcher *zconf.Watcher
+	// 服务定时任务(AfterFunc、Every)绑定的ctx, 服务停止时取消
+	timerCtx    context.Context
+	timerCancel context.CancelFunc
 }
 
+// connIDSeq 连接ID生成计数, 全部监听端口以及同一进程中的全部Server共用, 多个Server可以共享ConnManager
+var connIDSeq uint64
+
 // NewServer 创建一个服务器句柄
 func NewServer(opts ...Option) ziface.IServer {
 	printLogo()
 
 	s := &Server{
 		Name:       zconf.GlobalObject.Name,
-		IPVersion:  "tcp",
+		IPVersion:  zconf.GlobalObject.IPVersion,
 		IP:         zconf.GlobalObject.Host,
 		Port:       zconf.GlobalObject.TCPPort,
 		msgHandler: NewMsgHandle(),
 		ConnMgr:    NewConnManager(),
+		groupMgr:   NewGroupManager(),
+		pubSub:     NewPubSub(),
+		eventBus:   NewEventBus(),
+		outChain:   newOutChain(),
 		exitChan:   nil,
+		tcpOptions: newTCPOptions(zconf.GlobalObject),
+		//默认不限制带宽
+		readLimiter:  NewRateLimiter(0, 0),
+		writeLimiter: NewRateLimiter(0, 0),
 		//默认使用zinx的TLV封包方式
 		packet:  zpack.Factory().NewPack(ziface.ZinxDataPack),
 		decoder: zdecoder.NewTLVDecoder(), //默认使用TLV的解码方式
@@ -86,6 +173,18 @@ func NewServer(opts ...Option) ziface.IServer {
 			},
 		},
 	}
+	s.httpMux = newDefaultHTTPMux(s)
+	s.addConfListeners(zconf.GlobalObject)
+	s.SetAdmin(zconf.GlobalObject.AdminAddr, zconf.GlobalObject.AdminToken)
+	s.SetAdminDashboard(zconf.GlobalObject.AdminDashboard)
+	s.applyConfLimits(zconf.GlobalObject)
+	s.msgHandler.SetEventBus(s.eventBus)
+	if zconf.GlobalObject.StatsDAddr != "" {
+		s.SetStatsD(newConfStatsD(zconf.GlobalObject))
+	}
+	if zconf.GlobalObject.Registry != "" {
+		s.setConfRegistry(zconf.GlobalObject)
+	}
 
 	for _, opt := range opts {
 		opt(s)
@@ -109,9 +208,18 @@ func NewUserConfServer(config *zconf.Config, opts ...Option) ziface.IServer {
 		Port:       config.TCPPort,
 		msgHandler: NewMsgHandle(),
 		ConnMgr:    NewConnManager(),
+		groupMgr:   NewGroupManager(),
+		pubSub:     NewPubSub(),
+		eventBus:   NewEventBus(),
+		outChain:   newOutChain(),
 		exitChan:   nil,
 		packet:     zpack.Factory().NewPack(ziface.ZinxDataPack),
 		decoder:    zdecoder.NewTLVDecoder(), //默认使用TLV的解码方式
+		tcpOptions: newTCPOptions(config),
+
+		//默认不限制带宽
+		readLimiter:  NewRateLimiter(0, 0),
+		writeLimiter: NewRateLimiter(0, 0),
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize: int(zconf.GlobalObject.IOReadBuffSize),
 			CheckOrigin: func(r *http.Request) bool {
@@ -119,6 +227,21 @@ func NewUserConfServer(config *zconf.Config, opts ...Option) ziface.IServer {
 			},
 		},
 	}
+	if config.IPVersion != "" {
+		s.IPVersion = config.IPVersion
+	}
+	s.httpMux = newDefaultHTTPMux(s)
+	s.addConfListeners(config)
+	s.SetAdmin(config.AdminAddr, config.AdminToken)
+	s.SetAdminDashboard(config.AdminDashboard)
+	s.applyConfLimits(config)
+	s.msgHandler.SetEventBus(s.eventBus)
+	if config.StatsDAddr != "" {
+		s.SetStatsD(newConfStatsD(config))
+	}
+	if config.Registry != "" {
+		s.setConfRegistry(config)
+	}
 	//更替打包方式
 	for _, opt := range opts {
 		opt(s)
@@ -132,162 +255,398 @@ func NewUserConfServer(config *zconf.Config, opts ...Option) ziface.IServer {
 	return s
 }
 
+// NewServerWithOptions 以代码的方式创建服务, 选项在编译时检查, 无需修改Config或依赖配置文件
+// 如NewServerWithOptions(WithPort(8899), WithTLS(cert, key), WithWorkerPoolSize(64), WithPacket(pack))
+// ConfigOption在全局配置的基础上修改并校验后写入GlobalObject, 配置错误时panic; 之后与NewServer相同应用Option
+func NewServerWithOptions(opts ...ServerOption) ziface.IServer {
+	config := *zconf.GlobalObject
+	b := &serverBuilder{config: &config}
+	for _, opt := range opts {
+		opt.applyServerOption(b)
+	}
+	if err := zconf.SetGlobal(b.config); err != nil {
+		panic(err)
+	}
+	return NewServer(b.opts...)
+}
+
 //============== 实现 ziface.IServer 里的全部接口方法 ========
 
 // Start 开启网络服务
 func (s *Server) Start() {
 	zlog.Ins().InfoF("[START] Server name: %s,listener at IP: %s, Port %d is starting", s.Name, s.IP, s.Port)
+	s.lock.Lock()
 	s.exitChan = make(chan struct{})
+	s.listenClosed = false
+	s.lock.Unlock()
 
 	// 将解码器添加到拦截器
-	if s.decoder != nil {
-		s.msgHandler.AddInterceptor(s.decoder)
+	// 每个连接使用各自的解码器(连接建立时Server的解码器、附加监听端口或协商后的协议版本指定)
+	s.msgHandler.AddNamedInterceptor(DecoderInterceptorName, &connDecoder{defaultDecoder: s.GetDecoder()})
+
+	//0 启动worker工作池机制
+	s.msgHandler.StartWorkerPool()
+
+	//开启go去做服务端Listener业务, 附加的监听端口与主端口共享路由
+	go s.listen(ziface.ListenerConfig{IPVersion: s.IPVersion, IP: s.IP, Port: s.Port})
+	for _, config := range s.listeners {
+		go s.listen(config)
 	}
+	s.startHealthServer()
+	s.startAdminServer()
+	s.startStatsD()
+	s.startRegistry()
 
-	//开启一个go去做服务端Listener业务
-	go func() {
-		//0 启动worker工作池机制
-		s.msgHandler.StartWorkerPool()
+	s.eventBus.Publish(ziface.Event{
+		Type:   ziface.EventServerStarted,
+		Server: s.Name,
+		Addr:   net.JoinHostPort(s.IP, strconv.Itoa(s.Port)),
+	})
+}
+
+// listen 监听一个服务地址并处理该地址上的新连接
+func (s *Server) listen(config ziface.ListenerConfig) {
+	if config.IPVersion == "" {
+		config.IPVersion = s.IPVersion
+	}
+
+	//1 获取一个TCP的Addr, IPv6地址需要使用[]包裹
+	address := net.JoinHostPort(config.IP, strconv.Itoa(config.Port))
+	addr, err := net.ResolveTCPAddr(config.IPVersion, address)
+	if err != nil {
+		zlog.Ins().ErrorF("[START] resolve tcp addr err: %v\n", err)
+		s.publishListenerError(address, err)
+		return
+	}
+
+	// 2 监听服务器地址, 热重启启动的进程继承父进程的监听端口
+	tcpListener, err := inheritedListener(address)
+	if err != nil {
+		s.publishListenerError(address, err)
+		panic(err)
+	}
+	if tcpListener == nil {
+		tcpListener, err = net.ListenTCP(config.IPVersion, addr)
+		if err != nil {
+			s.publishListenerError(address, err)
+			panic(err)
+		}
+	}
+	s.addNetListener(address, tcpListener)
+	atomic.AddInt32(&s.listening, 1)
+	defer atomic.AddInt32(&s.listening, -1)
 
-		//1 获取一个TCP的Addr
-		addr, err := net.ResolveTCPAddr(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port))
+	var listener net.Listener = &tcpOptionsListener{TCPListener: tcpListener, options: s.tcpOptions}
+	if zconf.GlobalObject.CertFile != "" && zconf.GlobalObject.PrivateKeyFile != "" {
+		// 读取证书和密钥
+		crt, err := loadX509KeyPair(zconf.GlobalObject.CertFile, zconf.GlobalObject.PrivateKeyFile, zconf.GlobalObject.PrivateKeyPassword)
 		if err != nil {
-			zlog.Ins().ErrorF("[START] resolve tcp addr err: %v\n", err)
-			return
+			panic(err)
 		}
 
-		// 2 监听服务器地址
-		var listener net.Listener
-		if zconf.GlobalObject.CertFile != "" && zconf.GlobalObject.PrivateKeyFile != "" {
-			// 读取证书和密钥
-			crt, err := tls.LoadX509KeyPair(zconf.GlobalObject.CertFile, zconf.GlobalObject.PrivateKeyFile)
-			if err != nil {
-				panic(err)
+		// TLS连接
+		tlsConfig := &tls.Config{}
+		tlsConfig.Certificates = []tls.Certificate{crt}
+		tlsConfig.Time = time.Now
+		tlsConfig.Rand = rand.Reader
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	// 每个监听端口各自的Accept重试延迟
+	delay := &acceptDelay{}
+
+	go func() {
+		//3 启动server网络连接业务
+		for {
+			//3.1 设置服务器最大连接控制,如果超过最大连接，则等待
+			if s.ConnMgr.Len() >= zconf.GlobalObject.MaxConn {
+				zlog.Ins().InfoF("Exceeded the maxConnNum:%d, Wait:%d", zconf.GlobalObject.MaxConn, delay.duration)
+				delay.Delay()
+				continue
 			}
 
-			// TLS连接
-			tlsConfig := &tls.Config{}
-			tlsConfig.Certificates = []tls.Certificate{crt}
-			tlsConfig.Time = time.Now
-			tlsConfig.Rand = rand.Reader
-			listener, err = tls.Listen(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port), tlsConfig)
+			//3.2 阻塞等待客户端建立连接请求
+			conn, err := listener.Accept()
 			if err != nil {
-				panic(err)
+				//Go 1.16+
+				if errors.Is(err, net.ErrClosed) {
+					zlog.Ins().ErrorF("Listener closed")
+					return
+				}
+				zlog.Ins().ErrorF("Accept err: %v", err)
+				s.publishListenerError(address, err)
+				delay.Delay()
+				continue
 			}
-		} else {
-			listener, err = net.ListenTCP(s.IPVersion, addr)
-			if err != nil {
-				panic(err)
+
+			delay.Reset()
+
+			//3.3 拒绝被封禁IP的连接
+			if s.isBanned(conn.RemoteAddr()) {
+				_ = conn.Close()
+				continue
 			}
-		}
 
-		// 4. 创建 ws连接服务
-		// 创建 HTTP 服务器
-		var cID uint64
-
-		go func() {
-			//3 启动server网络连接业务
-			for {
-				//3.1 设置服务器最大连接控制,如果超过最大连接，则等待
-				if s.ConnMgr.Len() >= zconf.GlobalObject.MaxConn {
-					zlog.Ins().InfoF("Exceeded the maxConnNum:%d, Wait:%d", zconf.GlobalObject.MaxConn, AcceptDelay.duration)
-					AcceptDelay.Delay()
-					continue
-				}
+			//3.4 识别连接的协议(TCP/HTTP/Websocket)，并启动当前链接的处理业务
+			//连接ID在全部监听端口与全部Server之间唯一
+			go s.handleConn(conn, atomic.AddUint64(&connIDSeq, 1)-1, config)
+		}
+	}()
 
-				//3.2 阻塞等待客户端建立连接请求
-				conn, err := listener.Accept()
-				if err != nil {
-					//Go 1.16+
-					if errors.Is(err, net.ErrClosed) {
-						zlog.Ins().ErrorF("Listener closed")
-						return
-					}
-					zlog.Ins().ErrorF("Accept err: %v", err)
-					AcceptDelay.Delay()
-					continue
-				}
+	select {
+	case <-s.exitChan:
+		err := listener.Close()
+		if err != nil {
+			zlog.Ins().ErrorF("listener close err: %v", err)
+		}
+	}
+}
 
-				AcceptDelay.Reset()
+// handleConn 识别新连接的协议，创建对应的Connection并启动连接的处理业务
+func (s *Server) handleConn(conn net.Conn, connID uint64, config ziface.ListenerConfig) {
+	var dealConn ziface.IConnection
+	reader := bufio.NewReader(conn)
 
-				var dealConn ziface.IConnection
-				reader := bufio.NewReader(conn)
-				peek, err := reader.Peek(1)
-				if err != nil {
-					zlog.Ins().ErrorF("Error peeking request err:%v", err)
-					return
-				}
-				// 3.3 判断连接是否是 HTTP 请求
-				if peek[0] == 'G' || peek[0] == 'P' || peek[0] == 'H' {
-					// 处理 HTTP 请求
-					// 创建 http ResponseWriter
-					w := newResponseWriter(conn.(*net.TCPConn))
-					// 把http连接解析成request
-					request, err := http.ReadRequest(reader)
-					if err != nil {
-						zlog.Ins().ErrorF("Error reading HTTP request err:%v", err)
-						return
-					}
-					// 3.4 把 net.conn 转成 websocket.conn 模式
-					wsConn, err := s.upgrader.Upgrade(w, request, nil)
-					if err != nil {
-						zlog.Ins().ErrorF("http convert websocket error:%v", err)
-					}
-					// 3.5 处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的
-					dealConn = newWebsocketConn(s, wsConn, cID)
-
-					// Websocket HeartBeat 心跳检测
-					if s.hc != nil {
-						//从Server端克隆一个心跳检测器
-						heartBeatChecker := s.hc.Clone()
-						heartBeatChecker.SetHeartbeatFunc(func(connection ziface.IConnection) error {
-							return connection.GetWsConn().WriteMessage(websocket.PingMessage, nil)
-						})
-						//绑定当前链接
-						heartBeatChecker.BindConn(dealConn)
-					}
-
-				} else {
-					//3.4 处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的
-					dealConn = newServerConn(s, conn, cID)
-
-					// TCP HeartBeat 心跳检测
-					if s.hc != nil {
-						//从Server端克隆一个心跳检测器
-						heartBeatChecker := s.hc.Clone()
-
-						//绑定当前链接
-						heartBeatChecker.BindConn(dealConn)
-					}
+	// 判断连接是否是 HTTP 请求
+	if isHTTPRequest(reader) {
+		// 把http连接解析成request
+		request, err := readHTTPRequest(conn, reader, HTTPReadHeaderTimeout)
+		if err != nil {
+			zlog.Ins().ErrorF("Error reading HTTP request err:%v", err)
+			_ = conn.Close()
+			return
+		}
 
-				}
+		// 普通的HTTP请求(非Websocket)，由HTTP处理方法响应后关闭连接
+		if !websocket.IsWebSocketUpgrade(request) {
+			s.serveHTTP(conn, request)
+			return
+		}
 
-				cID++
+		// 把 net.conn 转成 websocket.conn 模式
+		w := newResponseWriter(conn)
+		wsConn, err := s.upgrader.Upgrade(w, request, nil)
+		if err != nil {
+			zlog.Ins().ErrorF("http convert websocket error:%v", err)
+			_ = conn.Close()
+			return
+		}
+		// 处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的
+		dealConn = newWebsocketConn(s, wsConn, connID)
 
-				//3.4 启动当前链接的处理业务
-				go dealConn.Start()
+		// Websocket HeartBeat 心跳检测
+		if s.hc != nil {
+			//从Server端克隆一个心跳检测器
+			heartBeatChecker := s.hc.Clone()
+			heartBeatChecker.SetHeartbeatFunc(func(connection ziface.IConnection) error {
+				return connection.GetWsConn().WriteMessage(websocket.PingMessage, nil)
+			})
+			//绑定当前链接
+			heartBeatChecker.BindConn(dealConn)
+		}
+	} else {
+		// 处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的
+		// 识别协议时预读取的数据仍在reader中，需要由reader继续读取
+		dealConn = newServerConn(s, newBufferedConn(conn, reader), connID)
 
-			}
-		}()
+		// TCP HeartBeat 心跳检测
+		if s.hc != nil {
+			//从Server端克隆一个心跳检测器
+			heartBeatChecker := s.hc.Clone()
 
-		select {
-		case <-s.exitChan:
-			err := listener.Close()
-			if err != nil {
-				zlog.Ins().ErrorF("listener close err: %v", err)
-			}
+			//绑定当前链接
+			heartBeatChecker.BindConn(dealConn)
 		}
-	}()
+	}
+
+	// 监听端口指定了独立的封包方式与解码器
+	if config.Packet != nil || config.Decoder != nil {
+		dealConn.(connCodec).setCodec(config.Packet, config.Decoder)
+	}
+
+	// 启动当前链接的处理业务
+	dealConn.Start()
 }
 
-// Stop 停止服务
+// Stop 立即停止服务, 正在处理的请求可能丢失, 需要平滑停止时使用Shutdown
 func (s *Server) Stop() {
 	zlog.Ins().InfoF("[STOP] Zinx server , name %s", s.Name)
 
+	// 先从注册中心注销, 网关与其他节点不再选择当前实例
+	s.stopRegistry()
+	s.closeListeners()
 	//将其他需要清理的连接信息或者其他信息 也要一并停止或者清理
-	s.ConnMgr.ClearConn()
-	s.exitChan <- struct{}{}
+	s.clearConns()
+	s.stopHTTPServers()
+}
+
+// ownConns 当前Server的全部连接
+func (s *Server) ownConns() []ziface.IConnection {
+	conns := s.ConnMgr.GetAll()
+	if !s.sharedConnMgr {
+		return conns
+	}
+
+	own := conns[:0]
+	for _, conn := range conns {
+		if c, ok := conn.(connOwner); ok && c.getServer() == ziface.IServer(s) {
+			own = append(own, conn)
+		}
+	}
+	return own
+}
+
+// clearConns 关闭当前Server的全部连接
+func (s *Server) clearConns() {
+	if !s.sharedConnMgr {
+		s.ConnMgr.ClearConn()
+		return
+	}
+	for _, conn := range s.ownConns() {
+		conn.Stop()
+	}
+}
+
+// closeListeners 关闭全部监听端口，不再接受新的连接
+func (s *Server) closeListeners() {
+	s.lock.Lock()
+	if s.exitChan == nil || s.listenClosed {
+		s.lock.Unlock()
+		return
+	}
+	s.listenClosed = true
 	close(s.exitChan)
+	s.lock.Unlock()
+
+	s.eventBus.Publish(ziface.Event{Type: ziface.EventShutdownBegun, Server: s.Name})
+}
+
+// stopHTTPServers 服务停止后关闭独立的健康检查与管理HTTP服务, 并停止推送指标、监听配置文件与定时任务
+func (s *Server) stopHTTPServers() {
+	s.stopHealthServer()
+	s.stopAdminServer()
+	s.stopStatsD()
+	s.stopConfigWatcher()
+	s.stopTimers()
+}
+
+func (s *Server) publishListenerError(address string, err error) {
+	s.eventBus.Publish(ziface.Event{
+		Type:   ziface.EventListenerError,
+		Server: s.Name,
+		Addr:   address,
+		Err:    err,
+	})
+}
+
+// Drain 排空连接, 用于滚动发布时平滑下线
+// 停止接受新的连接, 通知已有的客户端服务即将关闭(如果设置了通知消息)，
+// 等待正在处理的请求与连接发送缓冲中的数据发送完成后关闭全部连接
+// ctx结束时不再等待, 直接关闭剩余的连接并返回ctx的错误
+func (s *Server) Drain(ctx context.Context) error {
+	zlog.Ins().InfoF("[DRAIN] Zinx server , name %s", s.Name)
+
+	s.stopRegistry()
+	s.closeListeners()
+
+	if msg := s.getDrainMsg(); msg != nil {
+		if err := broadcast(s.ownConns(), msg.GetMsgID(), msg.GetData()); err != nil {
+			zlog.Ins().ErrorF("[DRAIN] notify clients err: %v", err)
+		}
+	}
+
+	err := waitUntil(ctx, s.drained)
+	s.clearConns()
+	s.stopHTTPServers()
+
+	return err
+}
+
+// Shutdown 平滑停止服务
+// 关闭全部监听端口, 等待工作池中的请求处理完成, 关闭全部连接并等待连接的OnConnStop执行完成, 最后刷新日志
+// ctx结束时不再等待, 返回ctx的错误, 此时未处理完成的请求可能丢失
+func (s *Server) Shutdown(ctx context.Context) error {
+	zlog.Ins().InfoF("[SHUTDOWN] Zinx server , name %s", s.Name)
+
+	s.stopRegistry()
+	s.closeListeners()
+
+	err := waitUntil(ctx, func() bool {
+		return s.msgHandler.InFlight() == 0
+	})
+
+	// 连接在退出时执行OnConnStop, 并从连接管理中删除
+	for _, conn := range s.ownConns() {
+		conn.Stop()
+	}
+	if err == nil {
+		err = waitUntil(ctx, func() bool {
+			return len(s.ownConns()) == 0
+		})
+	}
+
+	s.stopHTTPServers()
+
+	if syncErr := zlog.Sync(); syncErr != nil && err == nil {
+		err = syncErr
+	}
+
+	return err
+}
+
+// waitUntil 等待直到cond返回true或者ctx结束
+func waitUntil(ctx context.Context, cond func() bool) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if cond() {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) drained() bool {
+	if s.msgHandler.InFlight() > 0 {
+		return false
+	}
+
+	for _, conn := range s.ownConns() {
+		if buf, ok := conn.(sendBuffer); ok && buf.sendBuffLen() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SetConnMgr 使用外部的ConnManager(如多个Server共享, 最大连接数限制全部Server的连接总数), 需在Start之前调用
+func (s *Server) SetConnMgr(mgr ziface.IConnManager) {
+	s.ConnMgr = mgr
+	s.sharedConnMgr = true
+}
+
+// SetWorkerPool 使用外部的Worker工作池(如多个Server共享), 需在Start之前调用
+func (s *Server) SetWorkerPool(pool ziface.IWorkerPool) {
+	s.msgHandler.SetWorkerPool(pool)
+}
+
+// SetDrainMsg 设置排空连接时通知客户端的消息
+func (s *Server) SetDrainMsg(msgID uint32, data []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.drainMsg = zpack.NewMsgPackage(msgID, data)
+}
+
+func (s *Server) getDrainMsg() ziface.IMessage {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.drainMsg
 }
 
 // Serve 运行服务
@@ -297,8 +656,29 @@ func (s *Server) Serve() {
 	c := make(chan os.Signal, 1)
 	//监听指定信号 ctrl+c kill信号
 	signal.Notify(c, os.Interrupt, os.Kill)
-	sig := <-c
-	zlog.Ins().InfoF("[SERVE] Zinx server , name %s, Serve Interrupt, signal = %v", s.Name, sig)
+	//启用热重启时监听热重启信号
+	if s.getRestartTimeout() > 0 && restartSignal != nil {
+		signal.Notify(c, restartSignal)
+	}
+
+	for {
+		sig := <-c
+		if sig == restartSignal {
+			ctx, cancel := context.WithTimeout(context.Background(), s.getRestartTimeout())
+			err := s.Restart(ctx)
+			cancel()
+			if errors.Is(err, errRestartNotStarted) {
+				//新进程没有启动, 继续提供服务
+				zlog.Ins().ErrorF("[SERVE] Zinx server , name %s, hot restart err: %v", s.Name, err)
+				continue
+			}
+			zlog.Ins().InfoF("[SERVE] Zinx server , name %s, hot restart finished, err = %v", s.Name, err)
+			return
+		}
+
+		zlog.Ins().InfoF("[SERVE] Zinx server , name %s, Serve Interrupt, signal = %v", s.Name, sig)
+		return
+	}
 }
 
 // AddRouter 路由功能：给当前服务注册一个路由业务方法，供客户端链接处理使用
@@ -306,11 +686,152 @@ func (s *Server) AddRouter(msgID uint32, router ziface.IRouter) {
 	s.msgHandler.AddRouter(msgID, router)
 }
 
+// Use 添加全局中间件, 按添加顺序由外到内包裹全部路由, 需在Start之前调用
+func (s *Server) Use(middlewares ...ziface.Middleware) {
+	s.msgHandler.Use(middlewares...)
+}
+
+// Group 创建消息ID区间为[start, end]的路由分组, 分组的中间件作用于区间内的全部消息ID, 需在Start之前调用
+func (s *Server) Group(start, end uint32) ziface.IRouterGroup {
+	return s.msgHandler.Group(start, end)
+}
+
+// SetDefaultRouter 设置默认路由, 没有匹配到消息ID的请求交给它处理, 用于协议桥接或回复未知消息, 需在Start之前调用
+func (s *Server) SetDefaultRouter(router ziface.IRouter) {
+	s.msgHandler.SetDefaultRouter(router)
+}
+
+// SetRouteFunc 设置路由函数, 根据请求内容计算实际路由的消息ID, 需在Start之前调用
+func (s *Server) SetRouteFunc(routeFunc ziface.RouteFunc) {
+	s.msgHandler.SetRouteFunc(routeFunc)
+}
+
+// SetRecoverHandler 设置路由处理请求发生panic时的恢复处理, 如回复错误消息、统计指标或关闭连接
+// 路由通过BaseRouter.SetRecoverHandler设置的恢复处理优先, 需在Start之前调用
+func (s *Server) SetRecoverHandler(handler ziface.RecoverHandler) {
+	s.msgHandler.SetRecoverHandler(handler)
+}
+
+// SetReplyMsgID 设置请求消息ID对应的回复消息ID, 路由中通过request.Reply回复时不需要硬编码回复消息ID, 需在Start之前调用
+func (s *Server) SetReplyMsgID(requestID, replyID uint32) {
+	s.msgHandler.SetReplyMsgID(requestID, replyID)
+}
+
+// SetRoutePool 为消息ID绑定独立的Worker工作池, 耗时的路由(如生成报表)不会占满共用的工作池而影响其他消息的延迟, 需在Start之前调用
+func (s *Server) SetRoutePool(pool ziface.IWorkerPool, msgIDs ...uint32) {
+	s.msgHandler.SetRoutePool(pool, msgIDs...)
+}
+
+// SetMsgPriority 设置消息ID在Worker任务队列中的优先级, 高优先级的消息(如心跳、战斗操作)先于普通消息被处理, 需在Start之前调用
+// 连续处理的高优先级消息达到DefaultPriorityStarvationLimit时先处理一个普通消息, 避免普通消息饿死
+func (s *Server) SetMsgPriority(priority ziface.MsgPriority, msgIDs ...uint32) {
+	s.msgHandler.SetMsgPriority(priority, msgIDs...)
+}
+
+// SetDeadLetter 设置死信处理, 路由通过request.SetError报告错误或发生panic时按MaxAttempts重试,
+// 仍然失败的消息连同错误、处理次数交给死信处理方法, 可使用DeadLetterQueue保存以便检查或重试, 需在Start之前调用
+func (s *Server) SetDeadLetter(config ziface.DeadLetterConfig) {
+	s.msgHandler.SetDeadLetter(config)
+}
+
+// SetAccessLog 设置访问日志, 每处理完成一个消息输出一条包含连接ID、对端地址、消息ID、字节数、延迟与结果的记录
+// 高频的消息ID可以通过RouteSample采样输出, 可使用NewAccessLogWriter按行输出JSON, 需在Start之前调用
+func (s *Server) SetAccessLog(config ziface.AccessLogConfig) {
+	s.msgHandler.SetAccessLog(config)
+}
+
+// SetSlowLog 设置慢日志, 保存处理时间最长的size个请求(消息ID、连接、处理时间与前maxPayload字节的消息内容),
+// 可以通过GetSlowLog或管理HTTP服务的/admin/slowlog查看, size为0时关闭
+func (s *Server) SetSlowLog(size int, maxPayload int) {
+	s.msgHandler.SetSlowLog(size, maxPayload)
+}
+
+// GetSlowLog 慢日志中的请求, 按处理时间从长到短排列
+func (s *Server) GetSlowLog() []ziface.SlowLogEntry {
+	return s.msgHandler.SlowLog()
+}
+
+func (s *Server) ResetSlowLog() {
+	s.msgHandler.ResetSlowLog()
+}
+
+// SetSlowThreshold 设置慢处理阈值, 路由处理时间超过该值时记录消息ID、连接ID与处理时间, 并发布SlowHandler事件
+// 默认使用配置SlowHandlerTime, 0不检测, 需在Start之前调用
+func (s *Server) SetSlowThreshold(threshold time.Duration) {
+	s.msgHandler.SetSlowThreshold(threshold)
+}
+
+// SetConcurrencyLimit 设置消息ID同时处理的请求数量上限, 避免耗时的操作(如创建角色)被大量并发请求触发
+// 达到上限后到达的请求被丢弃并计入WorkerStats.Limited, 需在Start之前调用
+func (s *Server) SetConcurrencyLimit(limit int, msgIDs ...uint32) {
+	s.msgHandler.SetConcurrencyLimit(limit, msgIDs...)
+}
+
+// SetTimeoutHandler 设置路由处理超过期限(BaseRouter.SetTimeout)后的处理, 如通过request.Reply回复超时消息
+// 超时后Worker不再等待路由, 继续处理其他请求, 需在Start之前调用
+func (s *Server) SetTimeoutHandler(handler ziface.HandlerFunc) {
+	s.msgHandler.SetTimeoutHandler(handler)
+}
+
+// SetDispatcher 设置Worker选择策略, 默认使用配置WorkerDispatchMode(affinity)
+// 需要同一连接的请求按顺序处理时使用DispatchAffinity, 追求吞吐量时使用DispatchLeastLoaded, 需在Start之前调用
+func (s *Server) SetDispatcher(dispatcher ziface.IDispatcher) {
+	s.msgHandler.SetDispatcher(dispatcher)
+}
+
+// SetOverflowPolicy 设置Worker任务队列(MaxWorkerTaskLen)已满时的处理策略, 默认阻塞读取数据的Goroutine,
+// 可以改为丢弃请求并回复服务繁忙, 或交给过载处理方法, 各策略的次数计入WorkerStats, 需在Start之前调用
+func (s *Server) SetOverflowPolicy(config ziface.OverflowConfig) {
+	s.msgHandler.SetOverflowPolicy(config)
+}
+
+// GetRouteStats 每个路由(消息ID)的处理次数、失败次数、处理时间与端到端延迟的p50/p95/p99
+func (s *Server) GetRouteStats() []ziface.RouteStats {
+	return s.msgHandler.RouteStats()
+}
+
+// GetWorkerStats Worker工作池统计: 每个Worker任务队列的深度、任务的等待与执行时间、丢弃的消息数
+func (s *Server) GetWorkerStats() ziface.WorkerStats {
+	return s.msgHandler.WorkerStats()
+}
+
+// GetEventBus 得到服务生命周期事件总线
+func (s *Server) GetEventBus() ziface.IEventBus {
+	return s.eventBus
+}
+
 // GetConnMgr 得到链接管理
 func (s *Server) GetConnMgr() ziface.IConnManager {
 	return s.ConnMgr
 }
 
+// GetGroupMgr 得到分组(房间)管理器
+func (s *Server) GetGroupMgr() ziface.IGroupManager {
+	return s.groupMgr
+}
+
+// GetPubSub 得到主题发布订阅
+func (s *Server) GetPubSub() ziface.IPubSub {
+	return s.pubSub
+}
+
+// EnableSession 启用会话恢复
+// timeout 连接断开后会话等待恢复的时间, maxPending 等待期间最多缓存的消息数量
+func (s *Server) EnableSession(timeout time.Duration, maxPending int) {
+	mgr := NewSessionManager(timeout, maxPending, s.groupMgr)
+
+	//添加会话建立/恢复的路由
+	s.AddRouter(ziface.SessionMsgID, &SessionRouter{mgr: mgr})
+
+	//server绑定会话管理
+	s.sessionMgr = mgr
+}
+
+// GetSessionMgr 得到会话管理, 未启用会话恢复时返回nil
+func (s *Server) GetSessionMgr() ziface.ISessionManager {
+	return s.sessionMgr
+}
+
 // SetOnConnStart 设置该Server的连接创建时Hook函数
 func (s *Server) SetOnConnStart(hookFunc func(ziface.IConnection)) {
 	s.onConnStart = hookFunc
@@ -348,8 +869,9 @@ func (s *Server) GetMsgHandler() ziface.IMsgHandle {
 func (s *Server) StartHeartBeat(interval time.Duration) {
 	checker := NewHeartbeatChecker(interval)
 
-	//添加心跳检测的路由
+	//添加心跳检测的路由, 心跳消息优先处理, 避免业务消息积压时误判超时
 	s.AddRouter(checker.MsgID(), checker.Router())
+	s.SetMsgPriority(ziface.PriorityHigh, checker.MsgID())
 
 	//server绑定心跳检测器
 	s.hc = checker
@@ -364,10 +886,14 @@ func (s *Server) StartHeartBeatWithOption(interval time.Duration, option *ziface
 		checker.SetHeartbeatMsgFunc(option.MakeMsg)
 		checker.SetOnRemoteNotAlive(option.OnRemoteNotAlive)
 		checker.BindRouter(option.HeadBeatMsgID, option.Router)
+		checker.SetMaxMisses(option.MaxMisses)
+		checker.SetPongValidator(option.ValidatePong)
+		checker.SetNotAlivePolicy(option.NotAlivePolicy)
 	}
 
-	//添加心跳检测的路由
+	//添加心跳检测的路由, 心跳消息优先处理, 避免业务消息积压时误判超时
 	s.AddRouter(checker.MsgID(), checker.Router())
+	s.SetMsgPriority(ziface.PriorityHigh, checker.MsgID())
 
 	//server绑定心跳检测器
 	s.hc = checker
@@ -377,13 +903,22 @@ func (s *Server) GetHeartBeat() ziface.IHeartbeatChecker {
 	return s.hc
 }
 
+// SetDecoder 设置解码器, 服务运行中也可以调用, 新的解码器只对之后建立的连接生效
 func (s *Server) SetDecoder(decoder ziface.IDecoder) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	s.decoder = decoder
 }
 
+func (s *Server) GetDecoder() ziface.IDecoder {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.decoder
+}
+
 func (s *Server) GetLengthField() *ziface.LengthField {
-	if s.decoder != nil {
-		return s.decoder.GetLengthField()
+	if decoder := s.GetDecoder(); decoder != nil {
+		return decoder.GetLengthField()
 	}
 	return nil
 }
@@ -392,6 +927,113 @@ func (s *Server) AddInterceptor(interceptor ziface.IInterceptor) {
 	s.msgHandler.AddInterceptor(interceptor)
 }
 
+// AddNamedInterceptor 添加带名称的拦截器，服务运行中可通过名称删除或替换(如紧急过滤规则)
+func (s *Server) AddNamedInterceptor(name string, interceptor ziface.IInterceptor) {
+	s.msgHandler.AddNamedInterceptor(name, interceptor)
+}
+
+// RemoveInterceptor 运行时删除指定名称的拦截器, 正在处理中的请求仍使用原责任链
+func (s *Server) RemoveInterceptor(name string) bool {
+	return s.msgHandler.RemoveInterceptor(name)
+}
+
+// ReplaceInterceptor 运行时替换指定名称的拦截器, 正在处理中的请求仍使用原责任链
+func (s *Server) ReplaceInterceptor(name string, interceptor ziface.IInterceptor) bool {
+	return s.msgHandler.ReplaceInterceptor(name, interceptor)
+}
+
+func (s *Server) InterceptorNames() []string {
+	return s.msgHandler.InterceptorNames()
+}
+
+// AddListener 添加一个附加的监听端口，该端口上的连接可以使用独立的封包方式与解码器，与主端口共享路由
+// 需在Start之前调用
+func (s *Server) AddListener(config ziface.ListenerConfig) {
+	s.listeners = append(s.listeners, config)
+}
+
+// addConfListeners 添加配置文件中附加绑定的地址
+func (s *Server) addConfListeners(config *zconf.Config) {
+	for _, addr := range config.Listeners {
+		port := addr.Port
+		if port == 0 {
+			port = config.TCPPort
+		}
+		s.AddListener(ziface.ListenerConfig{IPVersion: addr.IPVersion, IP: addr.Host, Port: port})
+	}
+}
+
+// SetVersionNegotiator 设置协议版本协商器, 连接建立后需先完成版本握手
+func (s *Server) SetVersionNegotiator(negotiator ziface.IVersionNegotiator) {
+	s.negotiator = negotiator
+}
+
+func (s *Server) getVersionNegotiator() ziface.IVersionNegotiator {
+	return s.negotiator
+}
+
+// SetConnRateLimit 设置每个连接默认的读写带宽限制, 只对之后建立的连接生效
+// 已建立的连接可以通过IConnection.SetRateLimit单独修改
+func (s *Server) SetConnRateLimit(limit ziface.RateLimit) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.connRateLimit = limit
+}
+
+func (s *Server) getConnRateLimit() ziface.RateLimit {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.connRateLimit
+}
+
+// SetServerRateLimit 设置全部连接共享的读写带宽限制, 服务运行中也可以调用
+func (s *Server) SetServerRateLimit(limit ziface.RateLimit) {
+	s.readLimiter.SetLimit(limit.ReadRate, limit.Burst)
+	s.writeLimiter.SetLimit(limit.WriteRate, limit.Burst)
+}
+
+func (s *Server) getServerRateLimiters() (read ziface.IRateLimiter, write ziface.IRateLimiter) {
+	return s.readLimiter, s.writeLimiter
+}
+
+// SetSendBuffConfig 设置发送缓冲队列已满时的处理策略, 只对之后建立的连接生效
+func (s *Server) SetSendBuffConfig(config ziface.SendBuffConfig) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sendBuffConfig = config
+}
+
+func (s *Server) getSendBuffConfig() ziface.SendBuffConfig {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.sendBuffConfig
+}
+
+// getSendBuffStats 全部连接发送缓冲队列已满时各种处理结果的计数
+func (s *Server) getSendBuffStats() *ziface.SendBuffStats {
+	return &s.sendBuffStats
+}
+
+// SetIdleTimeout 设置连接默认的读写空闲超时时间, 超时没有读(写)数据则关闭连接, 0表示不检测
+// 只对之后建立的连接生效, 已建立的连接可以通过IConnection.SetIdleTimeout单独修改
+func (s *Server) SetIdleTimeout(read, write time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.readIdleTimeout = read
+	s.writeIdleTimeout = write
+}
+
+func (s *Server) getIdleTimeout() (read, write time.Duration) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.readIdleTimeout, s.writeIdleTimeout
+}
+
+// AddHTTPHandler 给TCP端口上收到的普通HTTP请求(非Websocket)注册处理方法
+func (s *Server) AddHTTPHandler(pattern string, handler http.HandlerFunc) {
+	s.httpMux.HandleFunc(pattern, handler)
+}
+
 func printLogo() {
 	fmt.Println(zinxLogo)
 	fmt.Println(topLine)
@@ -0,0 +1,47 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServerWithOptions(t *testing.T) {
+	// 只恢复修改的字段, 之前测试的连接可能仍在读取其他字段
+	saved := *zconf.GlobalObject
+	defer func() {
+		conf := zconf.GlobalObject
+		conf.Name, conf.Host, conf.TCPPort = saved.Name, saved.Host, saved.TCPPort
+		conf.WorkerPoolSize, conf.MaxConn, conf.HeartbeatMax = saved.WorkerPoolSize, saved.MaxConn, saved.HeartbeatMax
+	}()
+
+	pack := zpack.Factory().NewPack(ziface.ZinxDataPack)
+	s := NewServerWithOptions(
+		WithName("options"),
+		WithHost("127.0.0.1"),
+		WithPort(28964),
+		WithWorkerPoolSize(4),
+		WithMaxConn(5),
+		WithHeartbeatMax(30*time.Second),
+		WithPacket(pack),
+	).(*Server)
+
+	assert.Equal(t, "options", s.Name)
+	assert.Equal(t, "127.0.0.1", s.IP)
+	assert.Equal(t, 28964, s.Port)
+	assert.Equal(t, pack, s.GetPacket())
+	assert.Equal(t, uint32(4), s.msgHandler.(*MsgHandle).WorkerPoolSize)
+	assert.Equal(t, 5, zconf.GlobalObject.MaxConn)
+	assert.Equal(t, 30, zconf.GlobalObject.HeartbeatMax)
+
+	// 配置错误时panic, 全局配置保持不变
+	assert.Panics(t, func() {
+		NewServerWithOptions(WithPort(-1), WithTLS("missing.crt", "missing.key"))
+	})
+	assert.Equal(t, 28964, zconf.GlobalObject.TCPPort)
+	assert.Equal(t, "", zconf.GlobalObject.CertFile)
+}
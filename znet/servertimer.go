@@ -0,0 +1,62 @@
+package znet
+
+import (
+	"context"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// timerContext 服务定时任务绑定的ctx, 第一次使用时创建, 服务停止时结束
+func (s *Server) timerContext() context.Context {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.timerCtx == nil {
+		s.timerCtx, s.timerCancel = context.WithCancel(context.Background())
+	}
+	return s.timerCtx
+}
+
+// stopTimers 取消服务的全部定时任务, 之后再创建的任务绑定新的ctx(如重新Start)
+func (s *Server) stopTimers() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.timerCancel != nil {
+		s.timerCancel()
+		s.timerCtx, s.timerCancel = nil, nil
+	}
+}
+
+// recoverTask 执行服务的定时任务, 恢复并记录任务中的panic
+func (s *Server) recoverTask(fn func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("[TIMER] server %s task panic: %v\n%s", s.Name, err, debug.Stack())
+		}
+	}()
+	fn()
+}
+
+// AfterFunc d之后执行fn, 服务停止(Stop、Drain、Shutdown)时自动取消, fn中的panic被恢复并记录日志
+// 可以在Start之前调用, 返回取消任务的方法
+func (s *Server) AfterFunc(d time.Duration, fn func()) func() {
+	return timerAt(s.timerContext(), time.Now().Add(d), func() { s.recoverTask(fn) })
+}
+
+// Every 每隔interval执行一次fn, 服务停止时自动停止, 用于清理过期数据、刷新缓存等后台维护任务
+// 上一次执行还没有完成时跳过本次, fn中的panic被恢复并记录日志; 可以在Start之前调用, 返回停止任务的方法
+func (s *Server) Every(interval time.Duration, fn func()) func() {
+	var running int32
+	return connTicker(s.timerContext(), interval, 0, func() {
+		if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+			zlog.Ins().InfoF("[TIMER] server %s task still running, skip", s.Name)
+			return
+		}
+		defer atomic.StoreInt32(&running, 0)
+		s.recoverTask(fn)
+	})
+}
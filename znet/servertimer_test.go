@@ -0,0 +1,48 @@
+package znet
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerTimers(t *testing.T) {
+	s := NewServer().(*Server)
+
+	var fired, ticks, panics, afterStop int32
+	s.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+	s.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })()
+	s.Every(time.Second, func() { atomic.AddInt32(&ticks, 1) })
+	// panic被恢复, 任务继续执行
+	s.Every(time.Second, func() {
+		atomic.AddInt32(&panics, 1)
+		panic("maintenance failed")
+	})
+	s.AfterFunc(3*time.Second, func() { atomic.AddInt32(&afterStop, 1) })
+
+	time.Sleep(2500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&ticks), int32(2))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&panics), int32(2))
+
+	// Shutdown之后全部任务停止
+	assert.Nil(t, s.Shutdown(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+	n := atomic.LoadInt32(&ticks)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&ticks))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&afterStop))
+
+	// 上一次没有执行完成时跳过
+	var running int32
+	stop := s.Every(time.Second, func() {
+		atomic.AddInt32(&running, 1)
+		time.Sleep(1500 * time.Millisecond)
+	})
+	time.Sleep(2500 * time.Millisecond)
+	stop()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&running))
+}
@@ -0,0 +1,574 @@
+package znet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultSessionMaxPending 会话断线期间默认最多缓存的消息数量
+const DefaultSessionMaxPending = 256
+
+// sessionStoreTimeout 访问会话存储的超时时间
+const sessionStoreTimeout = 5 * time.Second
+
+// sessionGroupsKey 会话存储中保存断线前加入的分组的键, 新建会话时写入, 用于标记会话存在
+const sessionGroupsKey = "zinx.groups"
+
+var ErrSessionPendingFull = errors.New("session pending messages full")
+
+type pendingMsg struct {
+	msgID uint32
+	data  []byte
+}
+
+// Session 会话
+type Session struct {
+	token    string
+	conn     ziface.IConnection
+	property map[string]interface{}
+	// 断线时连接加入的分组, 恢复会话后重新加入
+	groups []string
+	// 断线期间缓存的消息
+	pending    []pendingMsg
+	maxPending int
+	// 断线后的过期计时器
+	expireTimer *time.Timer
+	// 会话存储, 未设置时为nil
+	store *sessionStore
+	lock  sync.RWMutex
+}
+
+func (s *Session) Token() string {
+	return s.token
+}
+
+func (s *Session) GetConnection() ziface.IConnection {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.conn
+}
+
+func (s *Session) IsOnline() bool {
+	return s.GetConnection() != nil
+}
+
+// SetProperty 设置会话属性, 设置了会话存储时同时写入存储
+func (s *Session) SetProperty(key string, value interface{}) {
+	s.lock.Lock()
+	s.property[key] = value
+	s.lock.Unlock()
+
+	if s.store != nil {
+		s.store.set(s.token, key, value)
+	}
+}
+
+func (s *Session) GetProperty(key string) (interface{}, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if value, ok := s.property[key]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no property found")
+}
+
+func (s *Session) RemoveProperty(key string) {
+	s.lock.Lock()
+	delete(s.property, key)
+	s.lock.Unlock()
+
+	if s.store != nil {
+		s.store.delete(s.token, key)
+	}
+}
+
+// SendMsg 在线时通过连接的发送队列发送, 断线期间缓存，恢复会话后按序补发
+// 连接已经关闭但还未Detach时发送失败的消息同样缓存; 已经放入发送队列的消息在连接断开时可能丢失, 不会补发,
+// 需要可靠送达的消息应由业务层确认并在恢复会话后重发
+func (s *Session) SendMsg(msgID uint32, data []byte) error {
+	s.lock.Lock()
+	conn := s.conn
+	if conn == nil {
+		defer s.lock.Unlock()
+		return s.addPending(msgID, data)
+	}
+	s.lock.Unlock()
+
+	err := conn.SendBuffMsg(msgID, data)
+	if err == nil || conn.Context() == nil || conn.Context().Err() == nil {
+		return err
+	}
+
+	// 连接已经关闭, 会话等待恢复
+	s.lock.Lock()
+	if current := s.conn; current != nil && current != conn {
+		// 发送期间会话已经恢复到新的连接
+		s.lock.Unlock()
+		return current.SendBuffMsg(msgID, data)
+	}
+	defer s.lock.Unlock()
+	return s.addPending(msgID, data)
+}
+
+// addPending 缓存消息等待恢复会话后补发, 需持有锁
+func (s *Session) addPending(msgID uint32, data []byte) error {
+	if len(s.pending) >= s.maxPending {
+		return ErrSessionPendingFull
+	}
+	s.pending = append(s.pending, pendingMsg{msgID: msgID, data: data})
+	return nil
+}
+
+// SessionManager 会话管理
+type SessionManager struct {
+	sessions map[string]*Session
+	byConn   map[uint64]*Session
+	// 断线后等待恢复的时间
+	timeout    time.Duration
+	maxPending int
+	groupMgr   ziface.IGroupManager
+	onExpire   func(session ziface.ISession)
+	store      *sessionStore
+	lock       sync.RWMutex
+}
+
+// NewSessionManager 创建会话管理, timeout为断线后等待恢复的时间, groupMgr用于恢复会话时重新加入分组(可以为nil)
+func NewSessionManager(timeout time.Duration, maxPending int, groupMgr ziface.IGroupManager) *SessionManager {
+	if maxPending <= 0 {
+		maxPending = DefaultSessionMaxPending
+	}
+	return &SessionManager{
+		sessions:   make(map[string]*Session),
+		byConn:     make(map[uint64]*Session),
+		timeout:    timeout,
+		maxPending: maxPending,
+		groupMgr:   groupMgr,
+	}
+}
+
+func newSessionToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func (mgr *SessionManager) SetOnExpire(hookFunc func(session ziface.ISession)) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	mgr.onExpire = hookFunc
+}
+
+// SetStore 设置会话存储, 会话属性与断线前加入的分组写入存储, 本节点没有的会话凭令牌从存储中恢复
+// 多个节点共享同一个存储时, 客户端在节点故障后可以连接其他节点恢复会话
+// ttl为在线会话在存储中的过期时间, 新建、恢复会话与写入属性时刷新, 为0时不过期
+// 断线后存储中的会话在超时时间后过期, 调用Remove时从存储中删除
+// 从存储中恢复的属性值为JSON解码后的值, 如数字为float64, 断线期间缓存的消息不会写入存储
+// 需在创建会话之前调用
+func (mgr *SessionManager) SetStore(store ziface.ISessionStore, ttl time.Duration) {
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	if store == nil {
+		mgr.store = nil
+		return
+	}
+	mgr.store = &sessionStore{store: store, ttl: ttl}
+}
+
+func (mgr *SessionManager) Create(conn ziface.IConnection) ziface.ISession {
+	mgr.lock.RLock()
+	store := mgr.store
+	mgr.lock.RUnlock()
+
+	session := &Session{
+		token:      newSessionToken(),
+		conn:       conn,
+		property:   make(map[string]interface{}),
+		maxPending: mgr.maxPending,
+		store:      store,
+	}
+	if store != nil {
+		store.set(session.token, sessionGroupsKey, []string{})
+	}
+
+	mgr.lock.Lock()
+	mgr.sessions[session.token] = session
+	mgr.byConn[conn.GetConnID()] = session
+	mgr.lock.Unlock()
+
+	return session
+}
+
+func (mgr *SessionManager) Resume(token string, conn ziface.IConnection) (ziface.ISession, bool) {
+	mgr.lock.Lock()
+	session, ok := mgr.sessions[token]
+	if !ok && mgr.store != nil {
+		// 本节点没有的会话从存储中加载, 加载期间会话可能已被其他连接恢复
+		store := mgr.store
+		mgr.lock.Unlock()
+		loaded, found := mgr.load(store, token)
+		mgr.lock.Lock()
+		if session, ok = mgr.sessions[token]; !ok && found {
+			session, ok = loaded, true
+			mgr.sessions[token] = session
+		}
+	}
+	if !ok {
+		mgr.lock.Unlock()
+		return nil, false
+	}
+
+	session.lock.Lock()
+	// 旧连接还未被检测到断开时，会话直接转移到新连接
+	if session.conn != nil {
+		delete(mgr.byConn, session.conn.GetConnID())
+	}
+	if session.expireTimer != nil {
+		session.expireTimer.Stop()
+		session.expireTimer = nil
+	}
+	session.conn = conn
+	groups := session.groups
+	pending := session.pending
+	session.groups = nil
+	session.pending = nil
+	session.lock.Unlock()
+
+	mgr.byConn[conn.GetConnID()] = session
+	mgr.lock.Unlock()
+
+	if session.store != nil {
+		session.store.expire(token, session.store.ttl)
+	}
+
+	// 重新加入断线前的分组
+	if mgr.groupMgr != nil {
+		for _, name := range groups {
+			mgr.groupMgr.Join(name, conn)
+		}
+	}
+
+	// 补发断线期间缓存的消息
+	for _, msg := range pending {
+		if err := conn.SendBuffMsg(msg.msgID, msg.data); err != nil {
+			zlog.Ins().ErrorF("session %s resend msgID = %d err: %v", token, msg.msgID, err)
+		}
+	}
+
+	return session, true
+}
+
+// Detach 连接断开, 记录连接加入的分组, 会话在超时时间内可以被恢复
+// 需在连接离开分组之前调用
+func (mgr *SessionManager) Detach(conn ziface.IConnection) {
+	mgr.lock.Lock()
+	session, ok := mgr.byConn[conn.GetConnID()]
+	if !ok {
+		mgr.lock.Unlock()
+		return
+	}
+	delete(mgr.byConn, conn.GetConnID())
+
+	session.lock.Lock()
+	if session.conn != conn {
+		session.lock.Unlock()
+		mgr.lock.Unlock()
+		return
+	}
+	session.conn = nil
+	if mgr.groupMgr != nil {
+		session.groups = mgr.groupMgr.GroupsOf(conn.GetConnID())
+	}
+	groups := session.groups
+	session.expireTimer = time.AfterFunc(mgr.timeout, func() {
+		mgr.expire(session)
+	})
+	session.lock.Unlock()
+	mgr.lock.Unlock()
+
+	// 分组写入存储, 会话可以在其他节点上恢复并重新加入分组
+	// 存储中的会话与本节点同时过期, 会话可能已在其他节点上恢复, 本节点过期时不从存储中删除
+	if session.store != nil {
+		session.store.set(session.token, sessionGroupsKey, groups)
+		session.store.expire(session.token, mgr.timeout)
+	}
+}
+
+// load 从会话存储中加载会话
+func (mgr *SessionManager) load(store *sessionStore, token string) (*Session, bool) {
+	values, ok := store.load(token)
+	if !ok {
+		return nil, false
+	}
+
+	session := &Session{
+		token:      token,
+		property:   make(map[string]interface{}),
+		maxPending: mgr.maxPending,
+		store:      store,
+	}
+	for key, data := range values {
+		if key == sessionGroupsKey {
+			_ = json.Unmarshal(data, &session.groups)
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			zlog.Ins().ErrorF("session %s decode property %s err: %v", token, key, err)
+			continue
+		}
+		session.property[key] = value
+	}
+	return session, true
+}
+
+// expire 会话超时未恢复
+func (mgr *SessionManager) expire(session *Session) {
+	mgr.lock.Lock()
+	if current, ok := mgr.sessions[session.token]; !ok || current != session || session.IsOnline() {
+		mgr.lock.Unlock()
+		return
+	}
+	delete(mgr.sessions, session.token)
+	onExpire := mgr.onExpire
+	mgr.lock.Unlock()
+
+	if onExpire != nil {
+		onExpire(session)
+	}
+}
+
+func (mgr *SessionManager) Get(token string) (ziface.ISession, bool) {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+
+	session, ok := mgr.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	return session, true
+}
+
+func (mgr *SessionManager) GetByConn(connID uint64) (ziface.ISession, bool) {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+
+	session, ok := mgr.byConn[connID]
+	if !ok {
+		return nil, false
+	}
+	return session, true
+}
+
+// Remove 立即删除会话, 设置了会话存储时同时从存储中删除
+func (mgr *SessionManager) Remove(token string) {
+	mgr.lock.Lock()
+	session, ok := mgr.sessions[token]
+	if !ok {
+		mgr.lock.Unlock()
+		return
+	}
+	delete(mgr.sessions, token)
+
+	session.lock.Lock()
+	if session.conn != nil {
+		delete(mgr.byConn, session.conn.GetConnID())
+	}
+	if session.expireTimer != nil {
+		session.expireTimer.Stop()
+	}
+	session.lock.Unlock()
+	mgr.lock.Unlock()
+
+	if session.store != nil {
+		session.store.remove(token)
+	}
+}
+
+func (mgr *SessionManager) Len() int {
+	mgr.lock.RLock()
+	defer mgr.lock.RUnlock()
+	return len(mgr.sessions)
+}
+
+// sessionStore 会话存储的封装, 属性值使用JSON编码, 出错时记录日志
+type sessionStore struct {
+	store ziface.ISessionStore
+	ttl   time.Duration
+}
+
+func (ss *sessionStore) load(token string) (map[string][]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	values, ok, err := ss.store.Load(ctx, token)
+	if err != nil {
+		zlog.Ins().ErrorF("session %s load from store err: %v", token, err)
+		return nil, false
+	}
+	return values, ok
+}
+
+func (ss *sessionStore) set(token string, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		zlog.Ins().ErrorF("session %s encode property %s err: %v", token, key, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	if err := ss.store.Set(ctx, token, key, data); err != nil {
+		zlog.Ins().ErrorF("session %s store property %s err: %v", token, key, err)
+		return
+	}
+	if err := ss.store.Expire(ctx, token, ss.ttl); err != nil {
+		zlog.Ins().ErrorF("session %s store expire err: %v", token, err)
+	}
+}
+
+func (ss *sessionStore) delete(token string, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	if err := ss.store.Delete(ctx, token, key); err != nil {
+		zlog.Ins().ErrorF("session %s delete property %s err: %v", token, key, err)
+	}
+}
+
+func (ss *sessionStore) expire(token string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	if err := ss.store.Expire(ctx, token, ttl); err != nil {
+		zlog.Ins().ErrorF("session %s store expire err: %v", token, err)
+	}
+}
+
+func (ss *sessionStore) remove(token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreTimeout)
+	defer cancel()
+	if err := ss.store.Remove(ctx, token); err != nil {
+		zlog.Ins().ErrorF("session %s remove from store err: %v", token, err)
+	}
+}
+
+// MemorySessionStore 内存中的会话存储, 用于单节点或测试
+type MemorySessionStore struct {
+	lock     sync.Mutex
+	sessions map[string]*memoryStoredSession
+}
+
+type memoryStoredSession struct {
+	values   map[string][]byte
+	expireAt time.Time
+}
+
+// NewMemorySessionStore 创建内存中的会话存储
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*memoryStoredSession)}
+}
+
+// get 获取未过期的会话, 需持有锁
+func (ms *MemorySessionStore) get(token string) (*memoryStoredSession, bool) {
+	stored, ok := ms.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if !stored.expireAt.IsZero() && time.Now().After(stored.expireAt) {
+		delete(ms.sessions, token)
+		return nil, false
+	}
+	return stored, true
+}
+
+func (ms *MemorySessionStore) Load(ctx context.Context, token string) (map[string][]byte, bool, error) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.get(token)
+	if !ok {
+		return nil, false, nil
+	}
+	values := make(map[string][]byte, len(stored.values))
+	for key, value := range stored.values {
+		values[key] = value
+	}
+	return values, true, nil
+}
+
+func (ms *MemorySessionStore) Set(ctx context.Context, token string, key string, value []byte) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.get(token)
+	if !ok {
+		stored = &memoryStoredSession{values: make(map[string][]byte)}
+		ms.sessions[token] = stored
+	}
+	stored.values[key] = value
+	return nil
+}
+
+func (ms *MemorySessionStore) Delete(ctx context.Context, token string, key string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if stored, ok := ms.get(token); ok {
+		delete(stored.values, key)
+	}
+	return nil
+}
+
+func (ms *MemorySessionStore) Expire(ctx context.Context, token string, ttl time.Duration) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	stored, ok := ms.get(token)
+	if !ok {
+		return nil
+	}
+	if ttl > 0 {
+		stored.expireAt = time.Now().Add(ttl)
+	} else {
+		stored.expireAt = time.Time{}
+	}
+	return nil
+}
+
+func (ms *MemorySessionStore) Remove(ctx context.Context, token string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.sessions, token)
+	return nil
+}
+
+// SessionRouter 服务端处理会话建立/恢复请求的路由
+type SessionRouter struct {
+	BaseRouter
+	mgr ziface.ISessionManager
+}
+
+func (sr *SessionRouter) Handle(request ziface.IRequest) {
+	conn := request.GetConnection()
+
+	// 连接已经绑定了会话
+	session, ok := sr.mgr.GetByConn(conn.GetConnID())
+	if !ok {
+		if token := string(request.GetData()); token != "" {
+			session, ok = sr.mgr.Resume(token, conn)
+		}
+		if !ok {
+			session = sr.mgr.Create(conn)
+		}
+	}
+
+	if err := conn.SendMsg(ziface.SessionMsgID, []byte(session.Token())); err != nil {
+		zlog.Ins().ErrorF("reply session token err: %v", err)
+	}
+}
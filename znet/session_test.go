@@ -0,0 +1,147 @@
+package znet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionResume(t *testing.T) {
+	groupMgr := NewGroupManager()
+	mgr := NewSessionManager(time.Minute, 2, groupMgr)
+
+	c1 := &Connection{connID: 1}
+	session := mgr.Create(c1)
+	session.SetProperty("uid", 100)
+	groupMgr.Join("room1", c1)
+
+	// 连接断开, 会话等待恢复
+	mgr.Detach(c1)
+	groupMgr.LeaveAll(c1)
+	assert.False(t, session.IsOnline())
+	_, ok := mgr.GetByConn(1)
+	assert.False(t, ok)
+
+	// 断线期间缓存消息, 超过上限返回错误
+	assert.Nil(t, session.SendMsg(1, []byte("a")))
+	assert.Nil(t, session.SendMsg(1, []byte("b")))
+	assert.Equal(t, ErrSessionPendingFull, session.SendMsg(1, []byte("c")))
+	session.(*Session).pending = nil
+
+	_, ok = mgr.Resume("unknown", &Connection{connID: 3})
+	assert.False(t, ok)
+
+	// 新连接凭令牌恢复会话, 属性保留并重新加入分组
+	c2 := &Connection{connID: 2}
+	resumed, ok := mgr.Resume(session.Token(), c2)
+	assert.True(t, ok)
+	assert.Equal(t, session, resumed)
+	assert.Equal(t, c2, resumed.GetConnection())
+	uid, _ := resumed.GetProperty("uid")
+	assert.Equal(t, 100, uid)
+	assert.Equal(t, []string{"room1"}, groupMgr.GroupsOf(2))
+
+	// 旧连接的断开不影响已恢复的会话
+	mgr.Detach(c1)
+	assert.True(t, resumed.IsOnline())
+}
+
+func TestSessionSendClosedConn(t *testing.T) {
+	mgr := NewSessionManager(time.Minute, 0, nil)
+
+	// 连接已经关闭但还未Detach, 发送失败的消息缓存等待恢复会话后补发
+	c1 := &Connection{connID: 1, isClosed: true, msgBuffChan: make(chan []byte, 1)}
+	c1.ctx, c1.cancel = context.WithCancel(context.Background())
+	c1.cancel()
+	session := mgr.Create(c1)
+	assert.Nil(t, session.SendMsg(1, []byte("a")))
+	assert.Equal(t, []pendingMsg{{msgID: 1, data: []byte("a")}}, session.(*Session).pending)
+
+	mgr.Detach(c1)
+	assert.Nil(t, session.SendMsg(1, []byte("b")))
+	assert.Len(t, session.(*Session).pending, 2)
+}
+
+func TestSessionExpire(t *testing.T) {
+	mgr := NewSessionManager(10*time.Millisecond, 0, nil)
+
+	expired := make(chan ziface.ISession, 1)
+	mgr.SetOnExpire(func(session ziface.ISession) {
+		expired <- session
+	})
+
+	conn := &Connection{connID: 1}
+	session := mgr.Create(conn)
+	mgr.Detach(conn)
+
+	select {
+	case s := <-expired:
+		assert.Equal(t, session.Token(), s.Token())
+	case <-time.After(time.Second):
+		t.Fatal("session not expired")
+	}
+	_, ok := mgr.Get(session.Token())
+	assert.False(t, ok)
+	assert.Equal(t, 0, mgr.Len())
+}
+
+func TestSessionStore(t *testing.T) {
+	store := NewMemorySessionStore()
+	groupsA, groupsB := NewGroupManager(), NewGroupManager()
+	mgrA := NewSessionManager(time.Minute, 0, groupsA)
+	mgrA.SetStore(store, time.Minute)
+	mgrB := NewSessionManager(time.Minute, 0, groupsB)
+	mgrB.SetStore(store, time.Minute)
+
+	c1 := &Connection{connID: 1}
+	session := mgrA.Create(c1)
+	session.SetProperty("uid", 100)
+	session.SetProperty("flag", true)
+	session.RemoveProperty("flag")
+	groupsA.Join("room1", c1)
+
+	// 属性对其他节点可见
+	values, ok, err := store.Load(context.Background(), session.Token())
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "100", string(values["uid"]))
+	_, ok = values["flag"]
+	assert.False(t, ok)
+
+	// 节点A上的连接断开, 客户端在节点B上恢复会话, 属性保留并重新加入分组
+	mgrA.Detach(c1)
+	groupsA.LeaveAll(c1)
+	c2 := &Connection{connID: 2}
+	resumed, ok := mgrB.Resume(session.Token(), c2)
+	assert.True(t, ok)
+	assert.Equal(t, session.Token(), resumed.Token())
+	assert.Equal(t, c2, resumed.GetConnection())
+	uid, _ := resumed.GetProperty("uid")
+	assert.Equal(t, float64(100), uid)
+	_, err = resumed.GetProperty(sessionGroupsKey)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"room1"}, groupsB.GroupsOf(2))
+
+	// 删除会话时从存储中删除
+	mgrB.Remove(session.Token())
+	_, ok = mgrA.Resume(session.Token(), &Connection{connID: 3})
+	assert.True(t, ok)
+	mgrA.Remove(session.Token())
+	_, ok, _ = store.Load(context.Background(), session.Token())
+	assert.False(t, ok)
+	_, ok = mgrB.Resume(session.Token(), &Connection{connID: 4})
+	assert.False(t, ok)
+}
+
+func TestMemorySessionStoreExpire(t *testing.T) {
+	store := NewMemorySessionStore()
+	ctx := context.Background()
+	assert.Nil(t, store.Set(ctx, "t1", "k", []byte("1")))
+	assert.Nil(t, store.Expire(ctx, "t1", 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	_, ok, _ := store.Load(ctx, "t1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,41 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type sleepRouter struct {
+	BaseRouter
+}
+
+func (r *sleepRouter) Handle(request ziface.IRequest) {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestSlowHandler(t *testing.T) {
+	var events []ziface.Event
+	bus := NewEventBus()
+	bus.Subscribe(func(event ziface.Event) {
+		events = append(events, event)
+	}, ziface.EventSlowHandler)
+
+	mh := NewMsgHandle()
+	mh.SetEventBus(bus)
+	mh.SetSlowThreshold(10 * time.Millisecond)
+	mh.AddRouter(1, &sleepRouter{})
+	mh.AddRouter(2, &BaseRouter{})
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	assert.Empty(t, events)
+
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)))
+	assert.Len(t, events, 1)
+	assert.Equal(t, uint32(1), events[0].MsgID)
+	assert.True(t, events[0].Elapsed >= 20*time.Millisecond)
+	assert.Equal(t, uint64(1), mh.WorkerStats().Slow)
+}
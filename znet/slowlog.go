@@ -0,0 +1,119 @@
+package znet
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// SlowLog 保存处理时间最长的N个请求, 不需要开启全量追踪即可在事后查看哪些消息处理得慢
+type SlowLog struct {
+	size       int
+	maxPayload int
+	// 按处理时间从短到长排列
+	entries []ziface.SlowLogEntry
+	// 已满时最短的处理时间, 用于不加锁地跳过更快的请求
+	min  int64
+	lock sync.Mutex
+}
+
+// NewSlowLog 创建慢日志, size为保存的请求数量, maxPayload为保存的消息内容的最大字节数
+func NewSlowLog(size int, maxPayload int) *SlowLog {
+	if size <= 0 {
+		size = 1
+	}
+	if maxPayload < 0 {
+		maxPayload = 0
+	}
+	return &SlowLog{size: size, maxPayload: maxPayload}
+}
+
+// Record 记录一个请求的处理时间, 比已保存的请求都快时忽略
+func (l *SlowLog) Record(request ziface.IRequest, msgID uint32, duration time.Duration) {
+	if int64(duration) <= atomic.LoadInt64(&l.min) {
+		return
+	}
+
+	data := request.GetData()
+	entry := ziface.SlowLogEntry{
+		Time:       time.Now(),
+		MsgID:      msgID,
+		Duration:   duration,
+		PayloadLen: len(data),
+	}
+	if len(data) > l.maxPayload {
+		data = data[:l.maxPayload]
+	}
+	entry.Payload = append([]byte(nil), data...)
+	if conn := request.GetConnection(); conn != nil {
+		entry.ConnID = conn.GetConnID()
+		if addr := conn.RemoteAddr(); addr != nil {
+			entry.RemoteAddr = addr.String()
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.entries) >= l.size {
+		if duration <= l.entries[0].Duration {
+			return
+		}
+		l.entries = l.entries[1:]
+	}
+	i := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].Duration >= duration
+	})
+	l.entries = append(l.entries, ziface.SlowLogEntry{})
+	copy(l.entries[i+1:], l.entries[i:])
+	l.entries[i] = entry
+	if len(l.entries) >= l.size {
+		atomic.StoreInt64(&l.min, int64(l.entries[0].Duration))
+	}
+}
+
+// Entries 已保存的请求, 按处理时间从长到短排列
+func (l *SlowLog) Entries() []ziface.SlowLogEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entries := make([]ziface.SlowLogEntry, len(l.entries))
+	for i, entry := range l.entries {
+		entries[len(entries)-1-i] = entry
+	}
+	return entries
+}
+
+// Reset 清空已保存的请求
+func (l *SlowLog) Reset() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = nil
+	atomic.StoreInt64(&l.min, 0)
+}
+
+// SetSlowLog 设置慢日志, 保存处理时间最长的size个请求及其前maxPayload字节的消息内容, size为0时关闭
+func (mh *MsgHandle) SetSlowLog(size int, maxPayload int) {
+	if size <= 0 {
+		mh.slowLog = nil
+		return
+	}
+	mh.slowLog = NewSlowLog(size, maxPayload)
+}
+
+// SlowLog 慢日志中的请求, 按处理时间从长到短排列
+func (mh *MsgHandle) SlowLog() []ziface.SlowLogEntry {
+	if mh.slowLog == nil {
+		return nil
+	}
+	return mh.slowLog.Entries()
+}
+
+func (mh *MsgHandle) ResetSlowLog() {
+	if mh.slowLog != nil {
+		mh.slowLog.Reset()
+	}
+}
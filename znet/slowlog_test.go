@@ -0,0 +1,51 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowLog(t *testing.T) {
+	log := NewSlowLog(3, 4)
+	for i, ms := range []int{5, 1, 8, 3, 9, 2} {
+		request := NewRequest(nil, zpack.NewMsgPackage(uint32(i), []byte("payload")))
+		log.Record(request, uint32(i), time.Duration(ms)*time.Millisecond)
+	}
+
+	entries := log.Entries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, uint32(4), entries[0].MsgID)
+	assert.Equal(t, 9*time.Millisecond, entries[0].Duration)
+	assert.Equal(t, uint32(2), entries[1].MsgID)
+	assert.Equal(t, uint32(0), entries[2].MsgID)
+	assert.Equal(t, []byte("payl"), entries[0].Payload)
+	assert.Equal(t, 7, entries[0].PayloadLen)
+
+	log.Reset()
+	assert.Empty(t, log.Entries())
+	log.Record(NewRequest(nil, zpack.NewMsgPackage(1, nil)), 1, time.Millisecond)
+	assert.Len(t, log.Entries(), 1)
+}
+
+func TestMsgHandleSlowLog(t *testing.T) {
+	mh := NewMsgHandle()
+	assert.Nil(t, mh.SlowLog())
+
+	mh.SetSlowLog(2, 16)
+	mh.AddRouter(1, &sleepRouter{})
+	mh.AddRouter(2, &BaseRouter{})
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(2, nil)))
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, []byte("slow"))))
+
+	entries := mh.SlowLog()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, uint32(1), entries[0].MsgID)
+	assert.Equal(t, []byte("slow"), entries[0].Payload)
+	assert.True(t, entries[0].Duration >= 20*time.Millisecond)
+
+	mh.ResetSlowLog()
+	assert.Empty(t, mh.SlowLog())
+}
@@ -0,0 +1,255 @@
+package znet
+
+import (
+	"net"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	// DefaultStatsDInterval 推送服务运行状态以及发送缓冲的指标的默认间隔
+	DefaultStatsDInterval = 10 * time.Second
+	// DefaultStatsDPacketSize 每个UDP包默认的最大字节数, 避免在常见的MTU下分片
+	DefaultStatsDPacketSize = 1432
+)
+
+// StatsDExporter 通过UDP向StatsD(DogStatsD)推送指标, 实现ziface.IMetrics
+// 指标先写入缓冲, 缓冲达到UDP包的大小或者到达推送间隔时发送
+type StatsDExporter struct {
+	config ziface.StatsDConfig
+	conn   net.Conn
+	// 全部指标共同的标签, DogStatsD时为"|#k:v,..."格式, 否则为拼接到名称中的".v..."
+	commonTags string
+
+	lock     sync.Mutex
+	buf      []byte
+	exitChan chan struct{}
+}
+
+// NewStatsDExporter 创建StatsD导出
+func NewStatsDExporter(config ziface.StatsDConfig) (*StatsDExporter, error) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultStatsDInterval
+	}
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = DefaultStatsDPacketSize
+	}
+	conn, err := net.Dial("udp", config.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &StatsDExporter{config: config, conn: conn}
+	e.commonTags = e.formatTags(config.Tags)
+	return e, nil
+}
+
+// newConfStatsD 按照配置创建StatsD导出配置
+func newConfStatsD(config *zconf.Config) ziface.StatsDConfig {
+	statsD := ziface.StatsDConfig{
+		Addr:      config.StatsDAddr,
+		Prefix:    config.StatsDPrefix,
+		DogStatsD: config.DogStatsD,
+		Interval:  time.Duration(config.StatsDInterval) * time.Second,
+	}
+	for _, tag := range config.StatsDTags {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if statsD.Tags == nil {
+			statsD.Tags = make(map[string]string)
+		}
+		statsD.Tags[kv[0]] = kv[1]
+	}
+	return statsD
+}
+
+func (e *StatsDExporter) Counter(name string, value float64, tags map[string]string) {
+	e.write(name, value, "c", tags)
+}
+
+func (e *StatsDExporter) Gauge(name string, value float64, tags map[string]string) {
+	e.write(name, value, "g", tags)
+}
+
+func (e *StatsDExporter) Histogram(name string, value float64, tags map[string]string) {
+	e.write(name, value, "h", tags)
+}
+
+// formatTags 按键排序格式化标签
+func (e *StatsDExporter) formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if e.config.DogStatsD {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(k + ":" + tags[k])
+		} else {
+			b.WriteString("." + tags[k])
+		}
+	}
+	return b.String()
+}
+
+// write 格式化一条指标写入缓冲, 缓冲放不下时先发送缓冲
+func (e *StatsDExporter) write(name string, value float64, kind string, tags map[string]string) {
+	var b strings.Builder
+	if e.config.Prefix != "" {
+		b.WriteString(e.config.Prefix + ".")
+	}
+	b.WriteString(name)
+	if !e.config.DogStatsD {
+		b.WriteString(e.commonTags)
+		b.WriteString(e.formatTags(tags))
+	}
+	b.WriteString(":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + kind)
+	if e.config.DogStatsD {
+		tagStr := e.formatTags(tags)
+		if e.commonTags != "" && tagStr != "" {
+			tagStr = e.commonTags + "," + tagStr
+		} else if tagStr == "" {
+			tagStr = e.commonTags
+		}
+		if tagStr != "" {
+			b.WriteString("|#" + tagStr)
+		}
+	}
+	line := b.String()
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.buf) > 0 && len(e.buf)+1+len(line) > e.config.MaxPacketSize {
+		e.flushLocked()
+	}
+	if len(e.buf) > 0 {
+		e.buf = append(e.buf, '\n')
+	}
+	e.buf = append(e.buf, line...)
+}
+
+// Flush 发送缓冲中的指标
+func (e *StatsDExporter) Flush() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.flushLocked()
+}
+
+func (e *StatsDExporter) flushLocked() {
+	if len(e.buf) == 0 {
+		return
+	}
+	if _, err := e.conn.Write(e.buf); err != nil {
+		zlog.Ins().DebugF("statsd write err: %v", err)
+	}
+	e.buf = e.buf[:0]
+}
+
+// Start 按推送间隔调用collect记录运行状态并发送缓冲, collect可以为nil
+func (e *StatsDExporter) Start(collect func(metrics ziface.IMetrics)) {
+	e.lock.Lock()
+	if e.exitChan != nil {
+		e.lock.Unlock()
+		return
+	}
+	exitChan := make(chan struct{})
+	e.exitChan = exitChan
+	e.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(e.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if collect != nil {
+					collect(e)
+				}
+				e.Flush()
+			case <-exitChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止推送并发送缓冲中剩余的指标
+func (e *StatsDExporter) Stop() {
+	e.lock.Lock()
+	if e.exitChan != nil {
+		close(e.exitChan)
+		e.exitChan = nil
+	}
+	e.flushLocked()
+	e.lock.Unlock()
+}
+
+// SetStatsD 设置StatsD导出, 连接、消息处理等指标以及按间隔采集的连接数、Goroutine数量、内存等运行状态推送到StatsD
+// 会替换SetMetrics设置的指标接口, 需在Start之前调用
+func (s *Server) SetStatsD(config ziface.StatsDConfig) {
+	exporter, err := NewStatsDExporter(config)
+	if err != nil {
+		zlog.Ins().ErrorF("statsd exporter %s err: %v", config.Addr, err)
+		return
+	}
+
+	s.lock.Lock()
+	old := s.statsD
+	s.statsD = exporter
+	s.lock.Unlock()
+	if old != nil {
+		old.Stop()
+	}
+	s.SetMetrics(exporter)
+}
+
+func (s *Server) startStatsD() {
+	s.lock.RLock()
+	exporter := s.statsD
+	s.lock.RUnlock()
+
+	if exporter != nil {
+		zlog.Ins().InfoF("[START] statsd exporter to %s", exporter.config.Addr)
+		exporter.Start(s.collectMetrics)
+	}
+}
+
+func (s *Server) stopStatsD() {
+	s.lock.RLock()
+	exporter := s.statsD
+	s.lock.RUnlock()
+
+	if exporter != nil {
+		exporter.Stop()
+	}
+}
+
+// collectMetrics 记录服务的运行状态
+func (s *Server) collectMetrics(metrics ziface.IMetrics) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	metrics.Gauge(ziface.MetricConnections, float64(s.ConnMgr.Len()), nil)
+	metrics.Gauge(ziface.MetricInFlight, float64(s.msgHandler.InFlight()), nil)
+	metrics.Gauge(ziface.MetricQueueUsage, s.msgHandler.QueueUsage(), nil)
+	metrics.Gauge(ziface.MetricGoroutines, float64(runtime.NumGoroutine()), nil)
+	metrics.Gauge(ziface.MetricMemAlloc, float64(mem.Alloc), nil)
+}
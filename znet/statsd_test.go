@@ -0,0 +1,64 @@
+package znet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	return string(buf[:n])
+}
+
+func TestStatsDExporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	dog, err := NewStatsDExporter(ziface.StatsDConfig{
+		Addr:      conn.LocalAddr().String(),
+		Prefix:    "game",
+		Tags:      map[string]string{"env": "prod"},
+		DogStatsD: true,
+	})
+	assert.Nil(t, err)
+	dog.Counter(ziface.MetricMsgHandled, 1, map[string]string{"result": "ok", "msgID": "1"})
+	dog.Gauge(ziface.MetricConnections, 3, nil)
+	dog.Flush()
+	assert.Equal(t, "game.zinx.msg.handled:1|c|#env:prod,msgID:1,result:ok\ngame.zinx.connections:3|g|#env:prod", readPacket(t, conn))
+
+	// StatsD不支持标签, 标签值拼接到名称中
+	plain, err := NewStatsDExporter(ziface.StatsDConfig{
+		Addr:          conn.LocalAddr().String(),
+		Tags:          map[string]string{"env": "prod"},
+		MaxPacketSize: 40,
+	})
+	assert.Nil(t, err)
+	plain.Histogram(ziface.MetricMsgLatency, 0.25, map[string]string{"msgID": "2"})
+	// 超过UDP包的大小, 先发送缓冲
+	plain.Counter(ziface.MetricConnOpened, 1, nil)
+	assert.Equal(t, "zinx.msg.latency.prod.2:0.25|h", readPacket(t, conn))
+	plain.Stop()
+	assert.Equal(t, "zinx.conn.opened.prod:1|c", readPacket(t, conn))
+}
+
+func TestStatsDExporterStart(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	exporter, err := NewStatsDExporter(ziface.StatsDConfig{Addr: conn.LocalAddr().String(), Interval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	exporter.Start(func(metrics ziface.IMetrics) {
+		metrics.Gauge(ziface.MetricGoroutines, 8, nil)
+	})
+	defer exporter.Stop()
+	assert.Equal(t, "zinx.goroutines:8|g", readPacket(t, conn))
+}
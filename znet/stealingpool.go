@@ -0,0 +1,217 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+type stealTask struct {
+	key  uint64
+	task func()
+}
+
+// stealQueue Worker的任务队列, 由工作池的锁保护
+type stealQueue struct {
+	normal []stealTask
+	high   []stealTask
+	// 连续执行的高优先级任务数量
+	highRun int
+}
+
+// StealingWorkerPool 支持任务窃取的Worker工作池
+// 任务按key分配到所属Worker的任务队列, Worker空闲时从其他Worker的队列中窃取任务,
+// 避免一个繁忙的连接(消息ID)占满一个队列时与其哈希到同一队列的任务等待, 而其他Worker空闲
+// 相同key的任务不会同时执行, 并且按提交顺序执行(相同优先级)
+type StealingWorkerPool struct {
+	size     uint32
+	queueLen int
+	queues   []*stealQueue
+	// 正在执行的任务的key
+	running map[uint64]struct{}
+	steals  uint64
+
+	lock      sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+	startOnce sync.Once
+}
+
+// NewStealingWorkerPool 创建支持任务窃取的Worker工作池, size为Worker的数量, queueLen为每个Worker任务队列(每个优先级)的长度
+func NewStealingWorkerPool(size uint32, queueLen uint32) *StealingWorkerPool {
+	if queueLen == 0 {
+		queueLen = 1
+	}
+	pool := &StealingWorkerPool{
+		size:     size,
+		queueLen: int(queueLen),
+		queues:   make([]*stealQueue, size),
+		running:  make(map[uint64]struct{}),
+	}
+	for i := range pool.queues {
+		pool.queues[i] = &stealQueue{}
+	}
+	pool.notEmpty = sync.NewCond(&pool.lock)
+	pool.notFull = sync.NewCond(&pool.lock)
+	return pool
+}
+
+func (pool *StealingWorkerPool) Start() {
+	pool.startOnce.Do(func() {
+		for i := range pool.queues {
+			go pool.startOneWorker(i)
+		}
+	})
+}
+
+// startOneWorker 优先处理自己队列中的任务, 自己的队列没有可执行的任务时从其他队列窃取
+func (pool *StealingWorkerPool) startOneWorker(workerID int) {
+	zlog.Ins().InfoF("Stealing Worker ID = %d is started.", workerID)
+	for {
+		t := pool.take(workerID)
+		t.task()
+
+		pool.lock.Lock()
+		delete(pool.running, t.key)
+		pool.lock.Unlock()
+	}
+}
+
+// take 等待并取出下一个可执行的任务
+func (pool *StealingWorkerPool) take(workerID int) stealTask {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for {
+		for i := 0; i < len(pool.queues); i++ {
+			t, ok := pool.takeFrom(pool.queues[(workerID+i)%len(pool.queues)])
+			if !ok {
+				continue
+			}
+			if i > 0 {
+				atomic.AddUint64(&pool.steals, 1)
+			}
+			pool.running[t.key] = struct{}{}
+			pool.notFull.Broadcast()
+			return t
+		}
+		pool.notEmpty.Wait()
+	}
+}
+
+// takeFrom 取出队列中第一个可执行的任务, 高优先级的任务先执行
+func (pool *StealingWorkerPool) takeFrom(queue *stealQueue) (stealTask, bool) {
+	if queue.highRun >= DefaultPriorityStarvationLimit {
+		if t, ok := pool.takeRunnable(&queue.normal); ok {
+			queue.highRun = 0
+			return t, true
+		}
+	}
+	if t, ok := pool.takeRunnable(&queue.high); ok {
+		queue.highRun++
+		return t, true
+	}
+	if t, ok := pool.takeRunnable(&queue.normal); ok {
+		queue.highRun = 0
+		return t, true
+	}
+	return stealTask{}, false
+}
+
+// takeRunnable 取出第一个key没有在执行、并且前面没有相同key的任务的任务, 保证相同key的任务按顺序执行
+func (pool *StealingWorkerPool) takeRunnable(tasks *[]stealTask) (stealTask, bool) {
+	var skipped map[uint64]struct{}
+	for i, t := range *tasks {
+		if _, ok := pool.running[t.key]; !ok {
+			if _, ok := skipped[t.key]; !ok {
+				*tasks = append((*tasks)[:i], (*tasks)[i+1:]...)
+				return t, true
+			}
+		}
+		if skipped == nil {
+			skipped = make(map[uint64]struct{})
+		}
+		skipped[t.key] = struct{}{}
+	}
+	return stealTask{}, false
+}
+
+func (pool *StealingWorkerPool) Size() uint32 {
+	return pool.size
+}
+
+func (pool *StealingWorkerPool) Workers() int {
+	return int(pool.size)
+}
+
+// Steals 从其他Worker的队列中窃取的任务数
+func (pool *StealingWorkerPool) Steals() uint64 {
+	return atomic.LoadUint64(&pool.steals)
+}
+
+func (pool *StealingWorkerPool) Submit(key uint64, task func()) {
+	pool.SubmitPriority(key, ziface.PriorityNormal, task)
+}
+
+func (pool *StealingWorkerPool) SubmitPriority(key uint64, priority ziface.MsgPriority, task func()) {
+	queue := pool.queues[key%uint64(pool.size)]
+	tasks := &queue.normal
+	if priority >= ziface.PriorityHigh {
+		tasks = &queue.high
+	}
+
+	pool.lock.Lock()
+	for len(*tasks) >= pool.queueLen {
+		pool.notFull.Wait()
+	}
+	*tasks = append(*tasks, stealTask{key: key, task: task})
+	pool.lock.Unlock()
+	pool.notEmpty.Signal()
+}
+
+func (pool *StealingWorkerPool) TrySubmitPriority(key uint64, priority ziface.MsgPriority, task func()) bool {
+	queue := pool.queues[key%uint64(pool.size)]
+	tasks := &queue.normal
+	if priority >= ziface.PriorityHigh {
+		tasks = &queue.high
+	}
+
+	pool.lock.Lock()
+	if len(*tasks) >= pool.queueLen {
+		pool.lock.Unlock()
+		return false
+	}
+	*tasks = append(*tasks, stealTask{key: key, task: task})
+	pool.lock.Unlock()
+	pool.notEmpty.Signal()
+	return true
+}
+
+// QueueLen Worker普通优先级任务队列的长度与容量
+func (pool *StealingWorkerPool) QueueLen(workerID uint32) (int, int) {
+	if workerID >= pool.size {
+		return 0, 0
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return len(pool.queues[workerID].normal), pool.queueLen
+}
+
+// QueueUsage Worker任务队列(包括高优先级队列)的最高使用率
+func (pool *StealingWorkerPool) QueueUsage() float64 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	var length int
+	for _, queue := range pool.queues {
+		if len(queue.normal) > length {
+			length = len(queue.normal)
+		}
+		if len(queue.high) > length {
+			length = len(queue.high)
+		}
+	}
+	return float64(length) / float64(pool.queueLen)
+}
@@ -0,0 +1,83 @@
+package znet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStealingWorkerPool(t *testing.T) {
+	pool := NewStealingWorkerPool(2, 64)
+	pool.Start()
+
+	// 相同key的任务按顺序执行, 不会同时执行
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	orders := make(map[uint64][]int)
+	for i := 0; i < 50; i++ {
+		for key := uint64(0); key < 4; key++ {
+			i, key := i, key
+			wg.Add(1)
+			pool.Submit(key, func() {
+				defer wg.Done()
+				lock.Lock()
+				orders[key] = append(orders[key], i)
+				lock.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+	for key := uint64(0); key < 4; key++ {
+		for i, n := range orders[key] {
+			assert.Equal(t, i, n)
+		}
+	}
+}
+
+func TestStealingWorkerPoolSteal(t *testing.T) {
+	pool := NewStealingWorkerPool(2, 8)
+	pool.Start()
+
+	// key 0与key 2属于同一个Worker的队列, key 0阻塞时key 2的任务被空闲的Worker窃取
+	block := make(chan struct{})
+	started := make(chan struct{})
+	pool.Submit(0, func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	sameKey := make(chan struct{})
+	pool.Submit(0, func() {
+		close(sameKey)
+	})
+	done := make(chan struct{})
+	pool.SubmitPriority(2, ziface.PriorityHigh, func() {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("task not stolen")
+	}
+	assert.Equal(t, uint64(1), pool.Steals())
+
+	// 相同key的任务等待前一个任务完成
+	length, capacity := pool.QueueLen(0)
+	assert.Equal(t, 1, length)
+	assert.Equal(t, 8, capacity)
+	select {
+	case <-sameKey:
+		t.Fatal("same key task executed concurrently")
+	default:
+	}
+	close(block)
+	select {
+	case <-sameKey:
+	case <-time.After(3 * time.Second):
+		t.Fatal("same key task not executed")
+	}
+}
@@ -0,0 +1,352 @@
+// 流式消息，用于文件传输、大块状态同步等超过单个数据包大小的场景。
+// 发送端通过 conn.OpenStream(msgID) 获得io.WriteCloser，写入的数据按数据包大小切分为带序号的分片，
+// 每个分片作为一条普通消息发送；接收端为该msgID注册StreamRouter，按序重组后以io.Reader交给StreamHandler。
+//
+// 分片格式(作为消息的Data部分)：
+// +--------------+----------+----------+-----------+
+// | StreamID(4)  |  Seq(4)  | Flags(1) |  Payload  |
+// +--------------+----------+----------+-----------+
+
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	// StreamHeaderLen 分片头部长度
+	StreamHeaderLen = 9
+	// StreamFlagFin 流结束标记
+	StreamFlagFin uint8 = 1
+	// DefaultStreamChunkSize 未限制数据包大小时的分片大小
+	DefaultStreamChunkSize = 4096
+
+	// DefaultStreamWindow 接收端每条流允许提前到达的乱序分片范围，序号超出范围时重置该流
+	DefaultStreamWindow = 256
+	// DefaultMaxStreams 每个连接在一个StreamRouter上同时打开的流数量上限，超过后新流的分片被丢弃
+	DefaultMaxStreams = 64
+
+	// 接收端每条流缓存的分片数量，超过后阻塞处理该连接消息的Worker(背压)
+	streamBufferFrames = 64
+)
+
+var (
+	ErrStreamClosed = errors.New("stream closed")
+	// ErrStreamReset 流的分片超出乱序范围被重置，已读取的数据不完整
+	ErrStreamReset = errors.New("stream reset")
+)
+
+// streamWriter 发送端的一条消息流
+type streamWriter struct {
+	conn     ziface.IConnection
+	msgID    uint32
+	streamID uint32
+	seq      uint32
+	closed   bool
+	lock     sync.Mutex
+}
+
+func newStreamWriter(conn ziface.IConnection, msgID uint32, streamID uint32) io.WriteCloser {
+	return &streamWriter{
+		conn:     conn,
+		msgID:    msgID,
+		streamID: streamID,
+	}
+}
+
+// chunkSize 每个分片最多携带的数据长度
+func (w *streamWriter) chunkSize() int {
+	size := int(zconf.GlobalObject.MaxPacketSizeOf(w.msgID))
+	if size == 0 {
+		return DefaultStreamChunkSize
+	}
+	if size <= StreamHeaderLen {
+		return 1
+	}
+	return size - StreamHeaderLen
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, ErrStreamClosed
+	}
+
+	chunkSize := w.chunkSize()
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		if err := w.sendFrame(0, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// Close 发送流结束标记
+func (w *streamWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	return w.sendFrame(StreamFlagFin, nil)
+}
+
+func (w *streamWriter) sendFrame(flags uint8, payload []byte) error {
+	frame := make([]byte, StreamHeaderLen+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], w.streamID)
+	binary.BigEndian.PutUint32(frame[4:8], w.seq)
+	frame[8] = flags
+	copy(frame[StreamHeaderLen:], payload)
+
+	if err := w.conn.SendMsg(w.msgID, frame); err != nil {
+		return err
+	}
+	w.seq++
+
+	return nil
+}
+
+// stream 接收端的一条消息流
+type stream struct {
+	conn     ziface.IConnection
+	msgID    uint32
+	streamID uint32
+
+	frames   chan []byte   //按序交付给读取方的分片
+	done     chan struct{} //StreamHandler已返回，后续分片直接丢弃
+	finished chan struct{} //已收到流结束标记或流被重置
+	reset    chan struct{} //分片超出乱序范围，流被重置
+	buf      []byte        //当前未读完的分片
+
+	nextSeq uint32
+	pending map[uint32]streamFrame //提前到达的乱序分片
+	window  uint32                 //允许提前到达的乱序分片范围
+	fin     bool
+	lock    sync.Mutex
+}
+
+type streamFrame struct {
+	flags   uint8
+	payload []byte
+}
+
+func newStream(conn ziface.IConnection, msgID uint32, streamID uint32) *stream {
+	return &stream{
+		conn:     conn,
+		msgID:    msgID,
+		streamID: streamID,
+		frames:   make(chan []byte, streamBufferFrames),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+		reset:    make(chan struct{}),
+		pending:  make(map[uint32]streamFrame),
+		window:   DefaultStreamWindow,
+	}
+}
+
+func (s *stream) StreamID() uint32 {
+	return s.streamID
+}
+
+func (s *stream) MsgID() uint32 {
+	return s.msgID
+}
+
+func (s *stream) GetConnection() ziface.IConnection {
+	return s.conn
+}
+
+// Read 读取流数据, 流正常结束返回io.EOF, 连接在流结束前断开返回io.ErrUnexpectedEOF, 流被重置返回ErrStreamReset
+func (s *stream) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		// 优先读取已经到达的分片
+		select {
+		case data, ok := <-s.frames:
+			if !ok {
+				return 0, io.EOF
+			}
+			s.buf = data
+			continue
+		default:
+		}
+
+		select {
+		case data, ok := <-s.frames:
+			if !ok {
+				return 0, io.EOF
+			}
+			s.buf = data
+		case <-s.reset:
+			return 0, ErrStreamReset
+		case <-s.conn.Context().Done():
+			return 0, io.ErrUnexpectedEOF
+		}
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+
+	return n, nil
+}
+
+// push 接收一个分片，按序号顺序交付
+func (s *stream) push(seq uint32, frame streamFrame) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.fin || seq < s.nextSeq {
+		return
+	}
+	if seq-s.nextSeq >= s.window {
+		// 发送端不会超前这么多，丢失的分片无法补齐，重置该流释放缓存的乱序分片
+		zlog.Ins().ErrorF("stream frame out of window, streamID = %d, seq = %d, expect = %d, reset", s.streamID, seq, s.nextSeq)
+		s.fin = true
+		s.pending = nil
+		close(s.reset)
+		close(s.finished)
+		return
+	}
+	s.pending[seq] = frame
+
+	for {
+		next, ok := s.pending[s.nextSeq]
+		if !ok {
+			return
+		}
+		delete(s.pending, s.nextSeq)
+		s.nextSeq++
+
+		if len(next.payload) > 0 {
+			select {
+			case s.frames <- next.payload:
+			case <-s.done:
+			case <-s.conn.Context().Done():
+			}
+		}
+
+		if next.flags&StreamFlagFin != 0 {
+			s.fin = true
+			close(s.frames)
+			close(s.finished)
+			return
+		}
+	}
+}
+
+type streamKey struct {
+	conn     ziface.IConnection
+	streamID uint32
+}
+
+// StreamRouter 流式消息路由, 将同一条流的分片按序重组后交给StreamHandler
+// 每条流占用一个Goroutine, 每个连接同时打开的流数量与每条流缓存的乱序分片都有上限
+type StreamRouter struct {
+	BaseRouter
+	handler    ziface.StreamHandler
+	streams    map[streamKey]*stream
+	open       map[ziface.IConnection]int //各连接打开的流数量
+	maxStreams int
+	window     uint32
+	lock       sync.Mutex
+}
+
+func NewStreamRouter(handler ziface.StreamHandler) *StreamRouter {
+	return &StreamRouter{
+		handler:    handler,
+		streams:    make(map[streamKey]*stream),
+		open:       make(map[ziface.IConnection]int),
+		maxStreams: DefaultMaxStreams,
+		window:     DefaultStreamWindow,
+	}
+}
+
+// SetLimit 设置每个连接同时打开的流数量上限与每条流允许的乱序分片范围, 不大于0时使用默认值, 需要在注册路由之前设置
+func (sr *StreamRouter) SetLimit(maxStreams int, window int) {
+	if maxStreams <= 0 {
+		maxStreams = DefaultMaxStreams
+	}
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	sr.maxStreams, sr.window = maxStreams, uint32(window)
+}
+
+func (sr *StreamRouter) Handle(request ziface.IRequest) {
+	data := request.GetData()
+	if len(data) < StreamHeaderLen {
+		zlog.Ins().ErrorF("stream frame too short, msgID = %d, len = %d", request.GetMsgID(), len(data))
+		return
+	}
+
+	key := streamKey{
+		conn:     request.GetConnection(),
+		streamID: binary.BigEndian.Uint32(data[0:4]),
+	}
+	seq := binary.BigEndian.Uint32(data[4:8])
+	frame := streamFrame{
+		flags:   data[8],
+		payload: data[StreamHeaderLen:],
+	}
+
+	sr.lock.Lock()
+	s, ok := sr.streams[key]
+	if !ok {
+		if sr.open[key.conn] >= sr.maxStreams {
+			sr.lock.Unlock()
+			zlog.Ins().ErrorF("too many streams, connID = %d, max = %d, drop streamID = %d", key.conn.GetConnID(), sr.maxStreams, key.streamID)
+			return
+		}
+		s = newStream(key.conn, request.GetMsgID(), key.streamID)
+		s.window = sr.window
+		sr.streams[key] = s
+		sr.open[key.conn]++
+		go sr.serve(key, s)
+	}
+	sr.lock.Unlock()
+
+	s.push(seq, frame)
+}
+
+// serve 执行StreamHandler，在流结束或连接断开后释放该流
+func (sr *StreamRouter) serve(key streamKey, s *stream) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("stream handler panic, streamID = %d, err: %v", s.streamID, err)
+		}
+		close(s.done)
+
+		select {
+		case <-s.finished:
+		case <-s.conn.Context().Done():
+		}
+
+		sr.lock.Lock()
+		delete(sr.streams, key)
+		if sr.open[key.conn]--; sr.open[key.conn] <= 0 {
+			delete(sr.open, key.conn)
+		}
+		sr.lock.Unlock()
+	}()
+
+	sr.handler(s)
+}
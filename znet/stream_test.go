@@ -0,0 +1,84 @@
+package znet
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamReorder(t *testing.T) {
+	conn := &Connection{}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	defer conn.cancel()
+
+	s := newStream(conn, 1, 1)
+	// 乱序到达的分片按序号交付
+	s.push(2, streamFrame{payload: []byte("zinx")})
+	s.push(0, streamFrame{payload: []byte("hello ")})
+	s.push(3, streamFrame{flags: StreamFlagFin})
+	s.push(1, streamFrame{payload: []byte("stream ")})
+	// 结束后的分片被忽略
+	s.push(4, streamFrame{payload: []byte("ignored")})
+
+	data, err := ioutil.ReadAll(s)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello stream zinx", string(data))
+}
+
+func TestStreamLimit(t *testing.T) {
+	conn := &Connection{connID: 1}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	defer conn.cancel()
+
+	// 序号超出乱序范围时重置流, 读取方收到ErrStreamReset
+	s := newStream(conn, 1, 1)
+	s.window = 4
+	s.push(0, streamFrame{payload: []byte("hello")})
+	s.push(5, streamFrame{payload: []byte("far")})
+	s.push(1, streamFrame{flags: StreamFlagFin})
+
+	data, err := ioutil.ReadAll(s)
+	assert.Equal(t, ErrStreamReset, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Nil(t, s.pending)
+
+	// 每个连接同时打开的流数量超过上限时, 新流的分片被丢弃
+	handled := make(chan uint32, 4)
+	release := make(chan struct{})
+	sr := NewStreamRouter(func(stream ziface.IStream) {
+		handled <- stream.StreamID()
+		<-release
+	})
+	sr.SetLimit(2, 0)
+	frame := func(streamID uint32) ziface.IRequest {
+		data := make([]byte, StreamHeaderLen)
+		binary.BigEndian.PutUint32(data[0:4], streamID)
+		data[8] = StreamFlagFin
+		return NewRequest(conn, zpack.NewMsgPackage(1, data))
+	}
+	for streamID := uint32(1); streamID <= 3; streamID++ {
+		sr.Handle(frame(streamID))
+	}
+	assert.ElementsMatch(t, []uint32{<-handled, <-handled}, []uint32{1, 2})
+	sr.lock.Lock()
+	assert.Equal(t, 2, len(sr.streams))
+	sr.lock.Unlock()
+
+	// 流结束后释放名额
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, waitUntil(ctx, func() bool {
+		sr.lock.Lock()
+		defer sr.lock.Unlock()
+		return len(sr.open) == 0
+	}))
+	sr.Handle(frame(3))
+	assert.Equal(t, uint32(3), <-handled)
+}
@@ -0,0 +1,95 @@
+package znet
+
+import (
+	"net"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zlog"
+)
+
+// tcpOptions 应用到新接受的TCP连接上的socket参数
+type tcpOptions struct {
+	noDelay           *bool
+	keepAlive         int
+	keepAliveInterval int
+	keepAliveCount    int
+	readBuffer        int
+	writeBuffer       int
+	linger            int
+}
+
+func newTCPOptions(config *zconf.Config) tcpOptions {
+	return tcpOptions{
+		noDelay:           config.TCPNoDelay,
+		keepAlive:         config.TCPKeepAlive,
+		keepAliveInterval: config.TCPKeepAliveInterval,
+		keepAliveCount:    config.TCPKeepAliveCount,
+		readBuffer:        config.TCPReadBuffer,
+		writeBuffer:       config.TCPWriteBuffer,
+		linger:            config.TCPLinger,
+	}
+}
+
+// apply 设置连接的socket参数, 未配置的参数保持默认值
+func (o tcpOptions) apply(conn *net.TCPConn) error {
+	if o.noDelay != nil {
+		if err := conn.SetNoDelay(*o.noDelay); err != nil {
+			return err
+		}
+	}
+
+	if o.keepAlive < 0 {
+		if err := conn.SetKeepAlive(false); err != nil {
+			return err
+		}
+	} else if o.keepAlive > 0 {
+		if err := conn.SetKeepAlive(true); err != nil {
+			return err
+		}
+		if err := conn.SetKeepAlivePeriod(time.Duration(o.keepAlive) * time.Second); err != nil {
+			return err
+		}
+	}
+	if o.keepAlive >= 0 && (o.keepAliveInterval > 0 || o.keepAliveCount > 0) {
+		if err := setKeepAliveProbes(conn, o.keepAliveInterval, o.keepAliveCount); err != nil {
+			return err
+		}
+	}
+
+	if o.readBuffer > 0 {
+		if err := conn.SetReadBuffer(o.readBuffer); err != nil {
+			return err
+		}
+	}
+	if o.writeBuffer > 0 {
+		if err := conn.SetWriteBuffer(o.writeBuffer); err != nil {
+			return err
+		}
+	}
+
+	if o.linger < 0 {
+		return conn.SetLinger(0)
+	} else if o.linger > 0 {
+		return conn.SetLinger(o.linger)
+	}
+	return nil
+}
+
+// tcpOptionsListener 接受连接时设置连接的socket参数
+// 位于TLS之下，TLS连接同样生效
+type tcpOptionsListener struct {
+	*net.TCPListener
+	options tcpOptions
+}
+
+func (l *tcpOptionsListener) Accept() (net.Conn, error) {
+	conn, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.options.apply(conn); err != nil {
+		zlog.Ins().ErrorF("set tcp options of %s err: %v", conn.RemoteAddr(), err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !freebsd
+// +build !linux,!freebsd
+
+package znet
+
+import (
+	"errors"
+	"net"
+)
+
+// setKeepAliveProbes 当前平台不支持设置保活探测的间隔与次数
+func setKeepAliveProbes(conn *net.TCPConn, interval, count int) error {
+	return errors.New("tcp keepalive interval and count are not supported on this platform")
+}
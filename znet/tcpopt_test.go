@@ -0,0 +1,45 @@
+package znet
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCPOptions(t *testing.T) {
+	noDelay := false
+	config := &zconf.Config{
+		TCPNoDelay:     &noDelay,
+		TCPKeepAlive:   30,
+		TCPReadBuffer:  64 * 1024,
+		TCPWriteBuffer: 64 * 1024,
+		TCPLinger:      -1,
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" {
+		config.TCPKeepAliveInterval = 5
+		config.TCPKeepAliveCount = 3
+	}
+
+	tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	listener := &tcpOptionsListener{TCPListener: tcpListener, options: newTCPOptions(config)}
+	defer listener.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	assert.True(t, ok)
+	assert.Nil(t, newTCPOptions(config).apply(tcpConn))
+}
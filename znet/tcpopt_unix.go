@@ -0,0 +1,33 @@
+//go:build linux || freebsd
+// +build linux freebsd
+
+package znet
+
+import (
+	"net"
+	"syscall"
+)
+
+// setKeepAliveProbes 设置保活探测的间隔与次数
+func setKeepAliveProbes(conn *net.TCPConn, interval, count int) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if interval > 0 {
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, interval); sockErr != nil {
+				return
+			}
+		}
+		if count > 0 {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, count)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,38 @@
+package znet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// loadX509KeyPair 读取证书与私钥, password不为空时私钥可以是加密的PEM(RFC 1423, 如openssl genrsa -aes256生成)
+func loadX509KeyPair(certFile, keyFile, password string) (tls.Certificate, error) {
+	if password == "" {
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM data found in %s", keyFile)
+	}
+	// x509.DecryptPEMBlock已不推荐使用(RFC 1423的加密方式较弱), 但运维生成的加密私钥仍然常见
+	if x509.IsEncryptedPEMBlock(block) {
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("decrypt %s: %v", keyFile, err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
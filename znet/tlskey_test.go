@@ -0,0 +1,40 @@
+package znet
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEncryptedKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zinx-tlskey")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir, "server")
+
+	// 加密私钥
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(keyPEM)
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte("secret"), x509.PEMCipherAES256)
+	assert.Nil(t, err)
+	encryptedFile := filepath.Join(dir, "encrypted.key")
+	assert.Nil(t, ioutil.WriteFile(encryptedFile, pem.EncodeToMemory(encrypted), 0600))
+
+	_, err = loadX509KeyPair(certFile, encryptedFile, "secret")
+	assert.Nil(t, err)
+	_, err = loadX509KeyPair(certFile, encryptedFile, "wrong")
+	assert.NotNil(t, err)
+	_, err = loadX509KeyPair(certFile, encryptedFile, "")
+	assert.NotNil(t, err)
+
+	// 未加密的私钥设置了密码时同样可以读取
+	_, err = loadX509KeyPair(certFile, keyFile, "secret")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,71 @@
+package znet
+
+import (
+	"net"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// MaxUDPDatagramSize UDP数据报的最大长度
+const MaxUDPDatagramSize = 65535
+
+// NewUDPClient 创建使用UDP的客户端, 与TCP客户端使用相同的封包方式与解码器, 每个消息封包后作为一个数据报发送
+// UDP不保证送达与顺序, 需要可靠传输时由业务层处理(如使用Call等待回复并重试)
+func NewUDPClient(ip string, port int, opts ...ClientOption) ziface.IClient {
+
+	c := &Client{
+		Ip:         ip,
+		Port:       port,
+		msgHandler: NewMsgHandle(),
+		packet:     zpack.Factory().NewPack(ziface.ZinxDataPack), //默认使用zinx的TLV封包方式
+		decoder:    zdecoder.NewTLVDecoder(),                     //默认使用zinx的TLV解码器
+		version:    "udp",
+		ErrChan:    make(chan error),
+		outChain:   newOutChain(),
+	}
+
+	//应用Option设置
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// dialUDP 创建UDP连接
+func (c *Client) dialUDP() (ziface.IConnection, error) {
+	addr := &net.UDPAddr{
+		IP:   net.ParseIP(c.Ip),
+		Port: c.Port,
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		zlog.Ins().ErrorF("udp client connect to server failed, err:%v", err)
+		return nil, err
+	}
+	return newClientConn(c, &udpConn{Conn: conn, buf: make([]byte, MaxUDPDatagramSize)}), nil
+}
+
+// udpConn 按数据报读取的UDP连接
+// 每次读取完整的数据报, 读取缓冲(IOReadBuffSize)小于数据报时分多次返回, 避免超出部分被丢弃
+type udpConn struct {
+	net.Conn
+	buf     []byte
+	pending []byte
+}
+
+func (u *udpConn) Read(p []byte) (int, error) {
+	if len(u.pending) == 0 {
+		n, err := u.Conn.Read(u.buf)
+		if err != nil {
+			return 0, err
+		}
+		u.pending = u.buf[:n]
+	}
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package znet
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type udpEchoRouter struct {
+	BaseRouter
+	ch chan []byte
+}
+
+func (r *udpEchoRouter) Handle(request ziface.IRequest) {
+	r.ch <- request.GetData()
+}
+
+func TestUDPClient(t *testing.T) {
+	// 原样回复每个数据报
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer server.Close()
+	go func() {
+		buf := make([]byte, MaxUDPDatagramSize)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = server.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	router := &udpEchoRouter{ch: make(chan []byte, 2)}
+	client := NewUDPClient("127.0.0.1", server.LocalAddr().(*net.UDPAddr).Port)
+	client.AddRouter(1, router)
+	client.Start()
+	defer client.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// 超过读取缓冲的数据报分多次读取后解码
+	large := bytes.Repeat([]byte("x"), 4000)
+	for _, data := range [][]byte{[]byte("hello"), large} {
+		assert.Nil(t, client.Conn().SendMsg(1, data))
+		select {
+		case got := <-router.ch:
+			assert.Equal(t, data, got)
+		case <-time.After(time.Second):
+			t.Fatal("udp reply is not received")
+		}
+	}
+}
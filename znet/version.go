@@ -0,0 +1,109 @@
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// VersionHandshakeLen 版本握手帧长度, 4字节大端的协议版本号
+const VersionHandshakeLen = 4
+
+// DefaultVersionHandshakeTimeout 默认等待握手帧的超时时间
+const DefaultVersionHandshakeTimeout = 5 * time.Second
+
+var ErrVersionNotSupported = errors.New("protocol version not supported")
+
+// VersionNegotiator 默认的协议版本协商器
+// 对端版本号已注册时直接使用，否则选择不高于对端版本号的最高已注册版本(向下兼容)
+type VersionNegotiator struct {
+	codecs  map[uint32]*ziface.ProtocolCodec
+	timeout time.Duration
+	lock    sync.RWMutex
+}
+
+func NewVersionNegotiator(codecs ...*ziface.ProtocolCodec) ziface.IVersionNegotiator {
+	n := &VersionNegotiator{
+		codecs:  make(map[uint32]*ziface.ProtocolCodec),
+		timeout: DefaultVersionHandshakeTimeout,
+	}
+	for _, codec := range codecs {
+		n.AddVersion(codec)
+	}
+	return n
+}
+
+// SetTimeout 设置等待握手帧的超时时间, 0表示不超时
+func (n *VersionNegotiator) SetTimeout(timeout time.Duration) *VersionNegotiator {
+	n.timeout = timeout
+	return n
+}
+
+func (n *VersionNegotiator) AddVersion(codec *ziface.ProtocolCodec) {
+	if codec == nil || codec.Version == 0 {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.codecs[codec.Version] = codec
+}
+
+func (n *VersionNegotiator) Negotiate(clientVersion uint32) (*ziface.ProtocolCodec, bool) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if clientVersion == 0 {
+		return nil, false
+	}
+	if codec, ok := n.codecs[clientVersion]; ok {
+		return codec, true
+	}
+
+	versions := make([]uint32, 0, len(n.codecs))
+	for version := range n.codecs {
+		if version < clientVersion {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, false
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	return n.codecs[versions[0]], true
+}
+
+func (n *VersionNegotiator) GetCodec(version uint32) *ziface.ProtocolCodec {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return n.codecs[version]
+}
+
+func (n *VersionNegotiator) Timeout() time.Duration {
+	return n.timeout
+}
+
+// negotiatorOwner 设置了协议版本协商器的Server, 连接创建时继承
+type negotiatorOwner interface {
+	getVersionNegotiator() ziface.IVersionNegotiator
+}
+
+// encodeVersion 编码版本握手帧
+func encodeVersion(version uint32) []byte {
+	buf := make([]byte, VersionHandshakeLen)
+	binary.BigEndian.PutUint32(buf, version)
+	return buf
+}
+
+// decodeVersion 解码版本握手帧
+func decodeVersion(buf []byte) (uint32, error) {
+	if len(buf) < VersionHandshakeLen {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint32(buf[:VersionHandshakeLen]), nil
+}
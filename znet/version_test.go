@@ -0,0 +1,69 @@
+package znet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionNegotiator(t *testing.T) {
+	n := NewVersionNegotiator(
+		&ziface.ProtocolCodec{Version: 1},
+		&ziface.ProtocolCodec{Version: 3},
+	)
+
+	codec, ok := n.Negotiate(3)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(3), codec.Version)
+
+	// 对端版本较新时向下兼容
+	codec, ok = n.Negotiate(2)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), codec.Version)
+	codec, ok = n.Negotiate(9)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(3), codec.Version)
+
+	_, ok = n.Negotiate(0)
+	assert.False(t, ok)
+}
+
+func TestConnectionNegotiateVersion(t *testing.T) {
+	packet := zpack.NewDataPack()
+	serverSide, clientSide := net.Pipe()
+
+	server := &Connection{
+		conn:       serverSide,
+		negotiator: NewVersionNegotiator(&ziface.ProtocolCodec{Version: 2, Packet: packet}),
+	}
+	client := &Connection{
+		conn:            clientSide,
+		protocolVersion: 5,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.negotiateVersion()
+	}()
+
+	assert.Nil(t, client.negotiateVersion())
+	assert.Nil(t, <-errChan)
+	assert.Equal(t, uint32(2), client.GetProtocolVersion())
+	assert.Equal(t, uint32(2), server.GetProtocolVersion())
+	assert.Equal(t, packet, server.packet)
+
+	// 不支持的版本
+	serverSide, clientSide = net.Pipe()
+	server.conn = serverSide
+	client.conn = clientSide
+	client.protocolVersion = 1
+	go func() {
+		errChan <- server.negotiateVersion()
+	}()
+
+	assert.Equal(t, ErrVersionNotSupported, client.negotiateVersion())
+	assert.Equal(t, ErrVersionNotSupported, <-errChan)
+}
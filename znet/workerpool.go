@@ -0,0 +1,143 @@
+package znet
+
+import (
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DefaultPriorityStarvationLimit 连续执行的高优先级任务达到该数量且有普通任务等待时, 先执行一个普通任务, 避免普通任务饿死
+const DefaultPriorityStarvationLimit = 8
+
+// WorkerPool Worker工作池, 每个Worker对应一个任务队列
+type WorkerPool struct {
+	size      uint32
+	taskQueue []*priorityQueue
+	startOnce sync.Once
+}
+
+// priorityQueue Worker的任务队列, 高优先级的任务先执行
+type priorityQueue struct {
+	normal chan func()
+	high   chan func()
+	// 连续执行的高优先级任务数量, 只由所属的Worker访问
+	highRun int
+}
+
+// take 等待并取出下一个任务
+func (q *priorityQueue) take() func() {
+	if q.highRun >= DefaultPriorityStarvationLimit {
+		select {
+		case task := <-q.normal:
+			q.highRun = 0
+			return task
+		default:
+		}
+	}
+	select {
+	case task := <-q.high:
+		q.highRun++
+		return task
+	default:
+	}
+	select {
+	case task := <-q.high:
+		q.highRun++
+		return task
+	case task := <-q.normal:
+		q.highRun = 0
+		return task
+	}
+}
+
+// NewWorkerPool 创建Worker工作池, size为Worker的数量, queueLen为每个Worker任务队列(每个优先级)的长度
+func NewWorkerPool(size uint32, queueLen uint32) *WorkerPool {
+	pool := &WorkerPool{
+		size:      size,
+		taskQueue: make([]*priorityQueue, size),
+	}
+	for i := range pool.taskQueue {
+		pool.taskQueue[i] = &priorityQueue{
+			normal: make(chan func(), queueLen),
+			high:   make(chan func(), queueLen),
+		}
+	}
+	return pool
+}
+
+func (pool *WorkerPool) Start() {
+	pool.startOnce.Do(func() {
+		for i, queue := range pool.taskQueue {
+			go pool.startOneWorker(i, queue)
+		}
+	})
+}
+
+// startOneWorker 启动一个Worker工作流程, 不断的等待队列中的任务
+func (pool *WorkerPool) startOneWorker(workerID int, taskQueue *priorityQueue) {
+	zlog.Ins().InfoF("Worker ID = %d is started.", workerID)
+	for {
+		taskQueue.take()()
+	}
+}
+
+func (pool *WorkerPool) Size() uint32 {
+	return pool.size
+}
+
+func (pool *WorkerPool) Workers() int {
+	return int(pool.size)
+}
+
+func (pool *WorkerPool) Submit(key uint64, task func()) {
+	pool.SubmitPriority(key, ziface.PriorityNormal, task)
+}
+
+func (pool *WorkerPool) SubmitPriority(key uint64, priority ziface.MsgPriority, task func()) {
+	queue := pool.taskQueue[key%uint64(pool.size)]
+	if priority >= ziface.PriorityHigh {
+		queue.high <- task
+	} else {
+		queue.normal <- task
+	}
+}
+
+func (pool *WorkerPool) TrySubmitPriority(key uint64, priority ziface.MsgPriority, task func()) bool {
+	queue := pool.taskQueue[key%uint64(pool.size)]
+	ch := queue.normal
+	if priority >= ziface.PriorityHigh {
+		ch = queue.high
+	}
+	select {
+	case ch <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// QueueLen Worker普通优先级任务队列的长度与容量
+func (pool *WorkerPool) QueueLen(workerID uint32) (int, int) {
+	if workerID >= pool.size {
+		return 0, 0
+	}
+	queue := pool.taskQueue[workerID].normal
+	return len(queue), cap(queue)
+}
+
+// QueueUsage Worker任务队列(包括高优先级队列)的最高使用率
+func (pool *WorkerPool) QueueUsage() float64 {
+	var usage float64
+	for _, queue := range pool.taskQueue {
+		for _, ch := range []chan func(){queue.normal, queue.high} {
+			if cap(ch) == 0 {
+				continue
+			}
+			if u := float64(len(ch)) / float64(cap(ch)); u > usage {
+				usage = u
+			}
+		}
+	}
+	return usage
+}
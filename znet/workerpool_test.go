@@ -0,0 +1,169 @@
+package znet
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(2, 8)
+	pool.Start()
+	pool.Start()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var order []int
+	for i := 0; i < 5; i++ {
+		i := i
+		wg.Add(1)
+		// 相同key的任务按顺序执行
+		pool.Submit(1, func() {
+			defer wg.Done()
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+	assert.Equal(t, uint32(2), pool.Size())
+	length, capacity := pool.QueueLen(1)
+	assert.Equal(t, 0, length)
+	assert.Equal(t, 8, capacity)
+}
+
+type pingRouter struct {
+	BaseRouter
+}
+
+func (r *pingRouter) Handle(request ziface.IRequest) {
+	_ = request.GetConnection().SendMsg(request.GetMsgID(), request.GetData())
+}
+
+func TestServerSharedPool(t *testing.T) {
+	pool := NewWorkerPool(2, 16)
+	connMgr := NewConnManager()
+
+	s1 := NewServer(WithWorkerPool(pool), WithConnManager(connMgr)).(*Server)
+	s1.IP, s1.Port = "127.0.0.1", 28989
+	s2 := NewServer(WithWorkerPool(pool), WithConnManager(connMgr)).(*Server)
+	s2.IP, s2.Port = "127.0.0.1", 28990
+	s1.AddRouter(1, &pingRouter{})
+	s2.AddRouter(1, &pingRouter{})
+	s1.Start()
+	s2.Start()
+	defer s2.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	dp := zpack.NewDataPack()
+	data, _ := dp.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	for _, address := range []string{"127.0.0.1:28989", "127.0.0.1:28990"} {
+		conn, err := net.Dial("tcp", address)
+		assert.Nil(t, err)
+		defer conn.Close()
+		_, _ = conn.Write(data)
+
+		reply := make([]byte, len(data))
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(reply)
+		assert.Nil(t, err)
+		assert.Equal(t, data, reply)
+	}
+
+	assert.Equal(t, 2, connMgr.Len())
+	assert.Equal(t, 1, len(s1.ownConns()))
+
+	// 停止一个Server只关闭该Server的连接
+	s1.Stop()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, connMgr.Len())
+	assert.Equal(t, 1, len(s2.ownConns()))
+}
+
+type blockRouter struct {
+	BaseRouter
+	block   chan struct{}
+	handled chan uint32
+}
+
+func (r *blockRouter) Handle(request ziface.IRequest) {
+	if request.GetMsgID() == 2 {
+		<-r.block
+	}
+	r.handled <- request.GetMsgID()
+}
+
+func TestRoutePool(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.SetWorkerPool(NewWorkerPool(1, 8))
+	mh.SetRoutePool(NewWorkerPool(1, 8), 2)
+	mh.StartWorkerPool()
+
+	router := &blockRouter{block: make(chan struct{}), handled: make(chan uint32, 4)}
+	mh.AddRouter(1, router)
+	mh.AddRouter(2, router)
+
+	// 同一个连接上耗时的消息在独立的工作池中处理, 不阻塞其他消息
+	conn := &Connection{connID: 1}
+	mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(2, nil)))
+	mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	select {
+	case msgID := <-router.handled:
+		assert.Equal(t, uint32(1), msgID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("msg blocked by slow route")
+	}
+
+	close(router.block)
+	assert.Equal(t, uint32(2), <-router.handled)
+}
+
+func TestWorkerPoolPriority(t *testing.T) {
+	for _, pool := range []ziface.IWorkerPool{
+		NewWorkerPool(1, 64),
+		NewScalingWorkerPool(ziface.WorkerScaleConfig{MinSize: 1, MaxSize: 1}, 64),
+	} {
+		pool.Start()
+
+		// 阻塞Worker, 使后续任务在队列中积压
+		block := make(chan struct{})
+		started := make(chan struct{})
+		pool.Submit(0, func() {
+			close(started)
+			<-block
+		})
+		<-started
+
+		var wg sync.WaitGroup
+		var order []string
+		submit := func(priority ziface.MsgPriority, name string) {
+			wg.Add(1)
+			pool.SubmitPriority(0, priority, func() {
+				defer wg.Done()
+				order = append(order, name)
+			})
+		}
+		submit(ziface.PriorityNormal, "n1")
+		submit(ziface.PriorityNormal, "n2")
+		for i := 0; i < DefaultPriorityStarvationLimit+2; i++ {
+			submit(ziface.PriorityHigh, "h")
+		}
+		close(block)
+		wg.Wait()
+
+		// 高优先级先执行, 连续执行达到上限后插入一个普通任务
+		expected := make([]string, 0, len(order))
+		for i := 0; i < DefaultPriorityStarvationLimit; i++ {
+			expected = append(expected, "h")
+		}
+		expected = append(expected, "n1", "h", "h", "n2")
+		assert.Equal(t, expected, order)
+	}
+}
@@ -0,0 +1,78 @@
+package znet
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// workerStats Worker工作池统计计数, 通过atomic访问
+type workerStats struct {
+	submitted         uint64
+	completed         uint64
+	dropped           uint64
+	panics            uint64
+	deadLetters       uint64
+	slow              uint64
+	limited           uint64
+	timeouts          uint64
+	overflowBlocked   uint64
+	overflowDropped   uint64
+	overflowCallbacks uint64
+	waitSum           int64
+	execSum           int64
+	maxWait           int64
+	maxExec           int64
+}
+
+// observe 记录一个处理完成的任务的等待时间与执行时间
+func (ws *workerStats) observe(wait, exec time.Duration) {
+	atomic.AddInt64(&ws.waitSum, int64(wait))
+	atomic.AddInt64(&ws.execSum, int64(exec))
+	storeMax(&ws.maxWait, int64(wait))
+	storeMax(&ws.maxExec, int64(exec))
+	atomic.AddUint64(&ws.completed, 1)
+}
+
+func storeMax(addr *int64, value int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if value <= old || atomic.CompareAndSwapInt64(addr, old, value) {
+			return
+		}
+	}
+}
+
+// WorkerStats Worker工作池统计, 包括每个Worker任务队列的深度、任务的等待与执行时间、丢弃的消息数
+func (mh *MsgHandle) WorkerStats() ziface.WorkerStats {
+	ws := mh.stats
+	stats := ziface.WorkerStats{
+		Workers:           mh.pool.Workers(),
+		QueueDepth:        make([]int, mh.pool.Size()),
+		Submitted:         atomic.LoadUint64(&ws.submitted),
+		Completed:         atomic.LoadUint64(&ws.completed),
+		Dropped:           atomic.LoadUint64(&ws.dropped),
+		Panics:            atomic.LoadUint64(&ws.panics),
+		DeadLetters:       atomic.LoadUint64(&ws.deadLetters),
+		Slow:              atomic.LoadUint64(&ws.slow),
+		Limited:           atomic.LoadUint64(&ws.limited),
+		Timeouts:          atomic.LoadUint64(&ws.timeouts),
+		OverflowBlocked:   atomic.LoadUint64(&ws.overflowBlocked),
+		OverflowDropped:   atomic.LoadUint64(&ws.overflowDropped),
+		OverflowCallbacks: atomic.LoadUint64(&ws.overflowCallbacks),
+		MaxWait:           time.Duration(atomic.LoadInt64(&ws.maxWait)),
+		MaxExec:           time.Duration(atomic.LoadInt64(&ws.maxExec)),
+	}
+	if pool, ok := mh.pool.(interface{ Steals() uint64 }); ok {
+		stats.Steals = pool.Steals()
+	}
+	for i := range stats.QueueDepth {
+		stats.QueueDepth[i], stats.QueueCap = mh.pool.QueueLen(uint32(i))
+	}
+	if stats.Completed > 0 {
+		stats.AvgWait = time.Duration(atomic.LoadInt64(&ws.waitSum) / int64(stats.Completed))
+		stats.AvgExec = time.Duration(atomic.LoadInt64(&ws.execSum) / int64(stats.Completed))
+	}
+	return stats
+}
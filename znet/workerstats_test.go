@@ -0,0 +1,34 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerStats(t *testing.T) {
+	mh := NewMsgHandle()
+	mh.SetWorkerPool(NewWorkerPool(2, 8))
+	mh.StartWorkerPool()
+	mh.AddRouter(1, &BaseRouter{})
+	mh.AddRouter(2, &panicRouter{})
+
+	conn := &Connection{connID: 1}
+	for _, msgID := range []uint32{1, 1, 2, 3} {
+		mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(msgID, nil)))
+	}
+	assert.Eventually(t, func() bool { return mh.WorkerStats().Completed == 4 }, 3*time.Second, 10*time.Millisecond)
+
+	stats := mh.WorkerStats()
+	assert.Equal(t, 2, stats.Workers)
+	assert.Equal(t, []int{0, 0}, stats.QueueDepth)
+	assert.Equal(t, 8, stats.QueueCap)
+	assert.Equal(t, uint64(4), stats.Submitted)
+	assert.Equal(t, uint64(4), stats.Completed)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, uint64(1), stats.Panics)
+	assert.True(t, stats.MaxWait >= stats.AvgWait)
+	assert.True(t, stats.MaxExec >= stats.AvgExec)
+}
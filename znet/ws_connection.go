@@ -6,12 +6,13 @@ import (
 	"errors"
 	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
-	"github.com/aceld/zinx/zinterceptor"
 	"github.com/aceld/zinx/zlog"
 	"github.com/aceld/zinx/zpack"
 	"github.com/gorilla/websocket"
+	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,6 +32,10 @@ type WsConnection struct {
 	cancel context.CancelFunc
 	//有缓冲管道，用于读、写两个goroutine之间的消息通信
 	msgBuffChan chan []byte
+	//保护发送缓冲队列的创建, 发送方同时持有msgLock的读锁
+	msgBuffLock sync.Mutex
+	//发送缓冲队列中等待发送(包括正在写出)的消息数量
+	sendBuffPending int64
 	//用户收发消息的Lock
 	msgLock sync.RWMutex
 	//链接属性
@@ -47,12 +52,47 @@ type WsConnection struct {
 	onConnStop func(conn ziface.IConnection)
 	//数据报文封包方式
 	packet ziface.IDataPack
-	//最后一次活动时间
-	lastActivityTime time.Time
+	//最后一次活动时间(UnixNano), 读协程写入、心跳检测协程读取, 原子访问
+	lastActivityTime int64
+	// 单独设置的心跳超时时间(纳秒), 0表示使用全局的HeartbeatMax
+	heartbeatTimeout int64
 	//断粘包解码器
 	frameDecoder ziface.IFrameDecoder
+	//连接独立使用的解码器(监听端口或协商后的协议版本指定), nil表示使用Server/Client的解码器
+	decoder ziface.IDecoder
 	//心跳检测器
 	hc ziface.IHeartbeatChecker
+	//协议版本协商器(服务端)
+	negotiator ziface.IVersionNegotiator
+	//协商后的协议版本号, 客户端发起握手前为期望的版本号, 0表示未启用版本协商
+	protocolVersion uint32
+	//最近一次打开的流ID
+	streamID uint32
+	//当前链接所属Server的分组管理器, 连接断开时自动离开全部分组
+	groupMgr ziface.IGroupManager
+	//当前链接所属Server的主题发布订阅, 连接断开时自动取消全部订阅
+	pubSub ziface.IPubSub
+	//当前链接所属Server的会话管理, 连接断开时会话进入等待恢复状态
+	sessionMgr ziface.ISessionManager
+	//当前链接所属Server的事件总线
+	eventBus ziface.IEventBus
+	//当前链接所属的Server
+	server ziface.IServer
+	//读写带宽限制
+	limiters *rateLimiters
+	//发送缓冲队列已满时的处理策略
+	sendBuffConfig ziface.SendBuffConfig
+	sendBuffStats  *ziface.SendBuffStats
+	//读写空闲检测
+	idle *idleChecker
+	//等待对端回复的请求
+	calls seqCalls
+	//收发统计
+	stats *connStats
+	//发出消息的拦截器
+	out *outChain
+	//发送消息失败时的回调(客户端连接), nil表示不回调
+	onSendError func(conn ziface.IConnection, msgID uint32, err error)
 }
 
 // newServerConn :for Server, 创建一个Server服务端特性的连接的方法
@@ -67,19 +107,48 @@ func newWebsocketConn(server ziface.IServer, conn *websocket.Conn, connID uint64
 		property:    nil,
 	}
 
-	lengthField := server.GetLengthField()
-	if lengthField != nil {
-		c.frameDecoder = zinterceptor.NewFrameDecoder(*lengthField)
-	}
+	//连接创建时即初始化ctx, 连接开始工作之前也可以调用Call等方法
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.stats = newConnStats()
+
+	// 记录连接建立时Server的解码器，运行时替换Server解码器只对之后建立的连接生效
+	c.setCodec(nil, server.GetDecoder())
 
 	//从server继承过来的属性
 	c.packet = server.GetPacket()
 	c.onConnStart = server.GetOnConnStart()
 	c.onConnStop = server.GetOnConnStop()
 	c.msgHandler = server.GetMsgHandler()
+	if owner, ok := server.(negotiatorOwner); ok {
+		c.negotiator = owner.getVersionNegotiator()
+	}
+	if owner, ok := server.(outChainOwner); ok {
+		c.out = owner.getOutChain()
+	}
 
 	//将当前的Connection与Server的ConnManager绑定
 	c.connManager = server.GetConnMgr()
+	c.groupMgr = server.GetGroupMgr()
+	c.pubSub = server.GetPubSub()
+	c.sessionMgr = server.GetSessionMgr()
+	c.eventBus = server.GetEventBus()
+	c.server = server
+
+	//连接自身的带宽限制与Server全局的带宽限制
+	c.limiters = connRateLimiters(server)
+
+	//发送缓冲队列已满时的处理策略
+	if owner, ok := server.(sendBuffOwner); ok {
+		c.sendBuffConfig = owner.getSendBuffConfig()
+		c.sendBuffStats = owner.getSendBuffStats()
+	}
+
+	//读写空闲超时
+	var readIdle, writeIdle time.Duration
+	if owner, ok := server.(idleTimeoutOwner); ok {
+		readIdle, writeIdle = owner.getIdleTimeout()
+	}
+	c.idle = newIdleChecker(c, readIdle, writeIdle)
 
 	//将新创建的Conn添加到链接管理中
 	server.GetConnMgr().Add(c)
@@ -97,16 +166,26 @@ func newWsClientConn(client ziface.IClient, conn *websocket.Conn) ziface.IConnec
 		property:    nil,
 	}
 
-	lengthField := client.GetLengthField()
-	if lengthField != nil {
-		c.frameDecoder = zinterceptor.NewFrameDecoder(*lengthField)
-	}
+	//连接创建时即初始化ctx, 连接开始工作之前也可以调用Call等方法
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.stats = newConnStats()
+
+	c.frameDecoder = newFrameDecoder(client.GetDecoder())
 
 	//从client继承过来的属性
 	c.packet = client.GetPacket()
 	c.onConnStart = client.GetOnConnStart()
 	c.onConnStop = client.GetOnConnStop()
 	c.msgHandler = client.GetMsgHandler()
+	c.protocolVersion = client.GetProtocolVersion()
+	if owner, ok := client.(outChainOwner); ok {
+		c.out = owner.getOutChain()
+	}
+	if reporter, ok := client.(sendErrorReporter); ok {
+		c.onSendError = reporter.sendError
+	}
+	c.limiters = newRateLimiters(ziface.RateLimit{}, nil, nil)
+	c.idle = newIdleChecker(c, 0, 0)
 
 	return c
 }
@@ -120,14 +199,25 @@ func (c *WsConnection) StartWriter() {
 		select {
 		case data, ok := <-c.msgBuffChan:
 			if ok {
+				if err := c.waitWrite(len(data)); err != nil {
+					return
+				}
+
 				//有数据要写给对端
-				if err := c.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				err := c.conn.WriteMessage(websocket.BinaryMessage, data)
+				atomic.AddInt64(&c.sendBuffPending, -1)
+				if err != nil {
 					zlog.Ins().ErrorF("Send Buff Data error:, %s Conn Writer exit", err)
+					c.stats.error()
 					break
 				}
 
 				//写对端成功, 更新链接活动时间
 				//c.updateActivity()
+				c.stats.write(len(data))
+				if c.idle != nil {
+					c.idle.touchWrite()
+				}
 			} else {
 				zlog.Ins().ErrorF("msgBuffChan is Closed")
 				break
@@ -154,6 +244,9 @@ func (c *WsConnection) StartReader() {
 			//从conn的IO中读取数据到内存缓冲buffer中
 			messageType, buffer, err := c.conn.ReadMessage()
 			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					c.stats.error()
+				}
 				return
 			}
 			if messageType == websocket.PingMessage {
@@ -166,12 +259,20 @@ func (c *WsConnection) StartReader() {
 
 				return
 			}
+			//读带宽限制, 等待期间不再读取, 由TCP流控让对端放慢发送
+			if err := c.waitRead(n); err != nil {
+				return
+			}
 			zlog.Ins().DebugF("read buffer %s \n", hex.EncodeToString(buffer[0:n]))
 
 			//正常读取到对端数据，更新心跳检测Active状态
 			if n > 0 && c.hc != nil {
 				c.updateActivity()
 			}
+			if n > 0 && c.idle != nil {
+				c.idle.touchRead()
+			}
+			c.stats.read(n)
 
 			//处理自定义协议断粘包问题 add by uuxia 2023-03-21
 			if c.frameDecoder != nil {
@@ -185,12 +286,14 @@ func (c *WsConnection) StartReader() {
 					msg := zpack.NewMessage(uint32(len(bytes)), bytes)
 					//得到当前客户端请求的Request数据
 					req := NewRequest(c, msg)
+					c.stats.readMsg()
 					c.msgHandler.Execute(req)
 				}
 			} else {
 				msg := zpack.NewMessage(uint32(n), buffer[0:n])
 				//得到当前客户端请求的Request数据
 				req := NewRequest(c, msg)
+				c.stats.readMsg()
 				c.msgHandler.Execute(req)
 			}
 		}
@@ -199,7 +302,13 @@ func (c *WsConnection) StartReader() {
 
 // Start 启动连接，让当前连接开始工作
 func (c *WsConnection) Start() {
-	c.ctx, c.cancel = context.WithCancel(context.Background())
+	//协议版本协商, 需在连接开始工作之前完成
+	if err := c.negotiateVersion(); err != nil {
+		zlog.Ins().ErrorF("connID = %d negotiate protocol version error: %v", c.connID, err)
+		c.closeBeforeStart()
+		return
+	}
+
 	//按照用户传递进来的创建连接时需要处理的业务，执行钩子方法
 	c.callOnConnStart()
 
@@ -209,6 +318,11 @@ func (c *WsConnection) Start() {
 		c.updateActivity()
 	}
 
+	//开始读写空闲检测
+	if c.idle != nil {
+		c.idle.start()
+	}
+
 	//开启用户从客户端读取数据流程的Goroutine
 	go c.StartReader()
 
@@ -259,15 +373,24 @@ func (c *WsConnection) Send(data []byte) error {
 		return errors.New("WsConnection closed when send msg")
 	}
 
+	if err := c.waitWrite(len(data)); err != nil {
+		return err
+	}
+
 	//写回客户端
 	err := c.conn.WriteMessage(websocket.BinaryMessage, data)
 	if err != nil {
 		zlog.Ins().ErrorF("SendMsg err data = %+v, err = %+v", data, err)
+		c.stats.error()
 		return err
 	}
 
 	//写对端成功, 更新链接活动时间
 	//c.updateActivity()
+	c.stats.write(len(data))
+	if c.idle != nil {
+		c.idle.touchWrite()
+	}
 
 	return nil
 }
@@ -276,15 +399,7 @@ func (c *WsConnection) SendToQueue(data []byte) error {
 	c.msgLock.RLock()
 	defer c.msgLock.RUnlock()
 
-	if c.msgBuffChan == nil {
-		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
-		//开启用于写回客户端数据流程的Goroutine
-		//此方法只读取MsgBuffChan中的数据没调用SendBuffMsg可以分配内存和启用协程
-		go c.StartWriter()
-	}
-
-	idleTimeout := time.NewTimer(5 * time.Millisecond)
-	defer idleTimeout.Stop()
+	msgBuffChan := c.sendBuff()
 
 	if c.isClosed == true {
 		return errors.New("WsConnection closed when send buff msg")
@@ -295,17 +410,13 @@ func (c *WsConnection) SendToQueue(data []byte) error {
 		return errors.New("Pack data is nil")
 	}
 
-	// 发送超时
-	select {
-	case <-idleTimeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgBuffChan <- data:
-		return nil
-	}
+	// 发送缓冲队列已满时按照策略处理
+	return pushSendBuff(c, msgBuffChan, &c.sendBuffPending, c.sendBuffConfig, c.sendBuffStats, data)
 }
 
 // SendMsg 直接将Message数据发送数据给远程的TCP客户端
-func (c *WsConnection) SendMsg(msgID uint32, data []byte) error {
+func (c *WsConnection) SendMsg(msgID uint32, data []byte) (err error) {
+	defer func() { c.reportSendError(msgID, err) }()
 	c.msgLock.RLock()
 	defer c.msgLock.RUnlock()
 	if c.isClosed == true {
@@ -313,58 +424,101 @@ func (c *WsConnection) SendMsg(msgID uint32, data []byte) error {
 	}
 
 	//将data封包，并且发送
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	msg, err := c.out.pack(c, c.packet, zpack.NewMsgPackage(msgID, data))
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		if err != ErrMsgDropped {
+			c.stats.error()
+		}
+		return err
+	}
+
+	if err = c.waitWrite(len(msg)); err != nil {
+		return err
 	}
 
 	//写回客户端
 	err = c.conn.WriteMessage(websocket.BinaryMessage, msg)
 	if err != nil {
 		zlog.Ins().ErrorF("SendMsg err msg ID = %d, data = %+v, err = %+v", msgID, string(msg), err)
+		c.stats.error()
 		return err
 	}
 
 	//写对端成功, 更新链接活动时间
 	//c.updateActivity()
+	c.stats.write(len(msg))
+	if c.idle != nil {
+		c.idle.touchWrite()
+	}
 
 	return nil
 }
 
+// SendSeqMsg 发送携带关联序号的消息, 封包方式不支持关联序号(如默认的zinx_pack)时序号不会被发送
+func (c *WsConnection) SendSeqMsg(seq uint32, msgID uint32, data []byte) (err error) {
+	defer func() { c.reportSendError(msgID, err) }()
+	msg, err := packSeqMsg(c, c.packet, c.out, seq, msgID, data)
+	if err != nil {
+		return err
+	}
+	return c.Send(msg)
+}
+
+// Call 发送请求并同步等待对端的回复, 对端需使用request.Reply回复, 双方需使用支持关联序号的封包方式
+// ctx取消或连接关闭时返回错误
+func (c *WsConnection) Call(ctx context.Context, msgID uint32, data []byte) ([]byte, error) {
+	if c.ctx == nil {
+		return nil, ErrCallConnClosed
+	}
+	return c.calls.call(ctx, c.ctx, func(seq uint32) error {
+		return c.SendSeqMsg(seq, msgID, data)
+	})
+}
+
+// SendMuxMsg 发送属于逻辑会话的消息, 封包方式不支持逻辑会话ID时逻辑会话ID不会被发送
+func (c *WsConnection) SendMuxMsg(sessionID uint32, seq uint32, msgID uint32, data []byte) error {
+	msg, err := packMuxMsg(c, c.packet, c.out, sessionID, seq, msgID, data)
+	if err != nil {
+		return err
+	}
+	return c.Send(msg)
+}
+
+// reportSendError 发送消息失败时回调, 发出消息的拦截器丢弃的消息不回调
+func (c *WsConnection) reportSendError(msgID uint32, err error) {
+	if err != nil && err != ErrMsgDropped && c.onSendError != nil {
+		c.onSendError(c, msgID, err)
+	}
+}
+
+// resolveSeq 消息是等待中的请求的回复时交给等待方
+func (c *WsConnection) resolveSeq(msg ziface.IMessage) bool {
+	return c.calls.resolve(msg)
+}
+
 // SendBuffMsg  发生BuffMsg
-func (c *WsConnection) SendBuffMsg(msgID uint32, data []byte) error {
+func (c *WsConnection) SendBuffMsg(msgID uint32, data []byte) (err error) {
+	defer func() { c.reportSendError(msgID, err) }()
 	c.msgLock.RLock()
 	defer c.msgLock.RUnlock()
 
-	if c.msgBuffChan == nil {
-		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
-		//开启用于写回客户端数据流程的Goroutine
-		//此方法只读取MsgBuffChan中的数据没调用SendBuffMsg可以分配内存和启用协程
-		go c.StartWriter()
-	}
-
-	idleTimeout := time.NewTimer(5 * time.Millisecond)
-	defer idleTimeout.Stop()
+	msgBuffChan := c.sendBuff()
 
 	if c.isClosed == true {
 		return errors.New("WsConnection closed when send buff msg")
 	}
 
 	//将data封包，并且发送
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	msg, err := c.out.pack(c, c.packet, zpack.NewMsgPackage(msgID, data))
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		if err != ErrMsgDropped {
+			c.stats.error()
+		}
+		return err
 	}
 
-	// 发送超时
-	select {
-	case <-idleTimeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgBuffChan <- msg:
-		return nil
-	}
+	// 发送缓冲队列已满时按照策略处理
+	return pushSendBuff(c, msgBuffChan, &c.sendBuffPending, c.sendBuffConfig, c.sendBuffStats, msg)
 }
 
 // SetProperty 设置链接属性
@@ -390,6 +544,60 @@ func (c *WsConnection) GetProperty(key string) (interface{}, error) {
 	return nil, errors.New("no property found")
 }
 
+// GetOrSetProperty 属性不存在时设置为value, 返回属性的当前值, loaded表示属性已经存在
+func (c *WsConnection) GetOrSetProperty(key string, value interface{}) (actual interface{}, loaded bool) {
+	c.propertyLock.Lock()
+	defer c.propertyLock.Unlock()
+
+	if actual, ok := c.property[key]; ok {
+		return actual, true
+	}
+	if c.property == nil {
+		c.property = make(map[string]interface{})
+	}
+	c.property[key] = value
+
+	return value, false
+}
+
+// CompareAndSwapProperty 属性的当前值等于old时替换为new, old为nil表示属性不存在
+// 属性值需要是可比较的类型
+func (c *WsConnection) CompareAndSwapProperty(key string, old, new interface{}) bool {
+	c.propertyLock.Lock()
+	defer c.propertyLock.Unlock()
+
+	current, ok := c.property[key]
+	if !ok {
+		if old != nil {
+			return false
+		}
+	} else if current != old {
+		return false
+	}
+
+	if c.property == nil {
+		c.property = make(map[string]interface{})
+	}
+	c.property[key] = new
+
+	return true
+}
+
+// UpdateProperty 在锁内根据属性的当前值计算新值并设置, 返回新值
+func (c *WsConnection) UpdateProperty(key string, fn func(value interface{}, ok bool) interface{}) interface{} {
+	c.propertyLock.Lock()
+	defer c.propertyLock.Unlock()
+
+	value, ok := c.property[key]
+	value = fn(value, ok)
+	if c.property == nil {
+		c.property = make(map[string]interface{})
+	}
+	c.property[key] = value
+
+	return value
+}
+
 // RemoveProperty 移除链接属性
 func (c *WsConnection) RemoveProperty(key string) {
 	c.propertyLock.Lock()
@@ -407,6 +615,24 @@ func (c *WsConnection) finalizer() {
 	//如果用户注册了该链接的	关闭回调业务，那么在此刻应该显示调用
 	c.callOnConnStop()
 
+	//停止读写空闲检测
+	if c.idle != nil {
+		c.idle.stop()
+	}
+
+	//会话进入等待恢复状态, 需在离开分组之前记录连接加入的分组
+	if c.sessionMgr != nil {
+		c.sessionMgr.Detach(c)
+	}
+	//离开连接加入的全部分组
+	if c.groupMgr != nil {
+		c.groupMgr.LeaveAll(c)
+	}
+	//取消连接的全部主题订阅
+	if c.pubSub != nil {
+		c.pubSub.UnsubscribeAll(c)
+	}
+
 	c.msgLock.Lock()
 	defer c.msgLock.Unlock()
 
@@ -444,6 +670,7 @@ func (c *WsConnection) callOnConnStart() {
 		zlog.Ins().InfoF("ZINX CallOnConnStart....")
 		c.onConnStart(c)
 	}
+	c.publishEvent(ziface.EventConnOpened)
 }
 
 // callOnConnStart 调用连接OnConnStop Hook函数
@@ -452,6 +679,18 @@ func (c *WsConnection) callOnConnStop() {
 		zlog.Ins().InfoF("ZINX CallOnConnStop....")
 		c.onConnStop(c)
 	}
+	c.publishEvent(ziface.EventConnClosed)
+}
+
+func (c *WsConnection) getServer() ziface.IServer {
+	return c.server
+}
+
+// publishEvent 发布连接事件(服务端连接)
+func (c *WsConnection) publishEvent(eventType ziface.EventType) {
+	if c.eventBus != nil {
+		c.eventBus.Publish(ziface.Event{Type: eventType, Conn: c})
+	}
 }
 
 func (c *WsConnection) IsAlive() bool {
@@ -459,13 +698,202 @@ func (c *WsConnection) IsAlive() bool {
 		return false
 	}
 	// 检查连接最后一次活动时间，如果超过心跳间隔，则认为连接已经死亡
-	return time.Now().Sub(c.lastActivityTime) < zconf.GlobalObject.HeartbeatMaxDuration()
+	return time.Now().Sub(c.lastActivity()) < c.heartbeatMax()
+}
+
+// heartbeatMax 判断连接超时的最长间隔, 没有单独设置时使用全局的HeartbeatMax
+func (c *WsConnection) heartbeatMax() time.Duration {
+	if timeout := atomic.LoadInt64(&c.heartbeatTimeout); timeout > 0 {
+		return time.Duration(timeout)
+	}
+	return zconf.GlobalObject.HeartbeatMaxDuration()
+}
+
+// SetHeartbeatInterval 运行时修改当前连接的心跳间隔与超时时间, 如移动端使用比桌面端更长的心跳间隔
+// interval为0时不修改心跳间隔(服务没有启用心跳检测时不发送心跳), timeout为0时按全局HeartbeatMax判断超时
+func (c *WsConnection) SetHeartbeatInterval(interval, timeout time.Duration) {
+	atomic.StoreInt64(&c.heartbeatTimeout, int64(timeout))
+	if c.hc != nil {
+		c.hc.SetInterval(interval)
+	}
 }
 
 func (c *WsConnection) updateActivity() {
-	c.lastActivityTime = time.Now()
+	atomic.StoreInt64(&c.lastActivityTime, time.Now().UnixNano())
+}
+
+// lastActivity 最后一次读取到对端数据的时间
+func (c *WsConnection) lastActivity() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.lastActivityTime))
 }
 
 func (c *WsConnection) SetHeartBeat(checker ziface.IHeartbeatChecker) {
 	c.hc = checker
 }
+
+// getHeartBeat 绑定的心跳检测器
+func (c *WsConnection) getHeartBeat() ziface.IHeartbeatChecker {
+	return c.hc
+}
+
+// OpenStream 打开一条消息流，写入的数据按数据包大小分片发送，接收端需为msgID注册StreamRouter
+func (c *WsConnection) OpenStream(msgID uint32) (io.WriteCloser, error) {
+	c.msgLock.RLock()
+	defer c.msgLock.RUnlock()
+	if c.isClosed == true {
+		return nil, errors.New("connection closed when open stream")
+	}
+
+	return newStreamWriter(c, msgID, atomic.AddUint32(&c.streamID, 1)), nil
+}
+
+// GetProtocolVersion 获取协商后的协议版本号
+func (c *WsConnection) GetProtocolVersion() uint32 {
+	return c.protocolVersion
+}
+
+// negotiateVersion 协议版本握手, 握手帧为连接上的第一个websocket二进制消息
+func (c *WsConnection) negotiateVersion() error {
+	if c.negotiator != nil {
+		if timeout := c.negotiator.Timeout(); timeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+			defer c.conn.SetReadDeadline(time.Time{})
+		}
+
+		_, buf, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		clientVersion, err := decodeVersion(buf)
+		if err != nil {
+			return err
+		}
+
+		codec, ok := c.negotiator.Negotiate(clientVersion)
+		if !ok {
+			_ = c.conn.WriteMessage(websocket.BinaryMessage, encodeVersion(0))
+			return ErrVersionNotSupported
+		}
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, encodeVersion(codec.Version)); err != nil {
+			return err
+		}
+		c.useCodec(codec)
+
+		return nil
+	}
+
+	if c.protocolVersion > 0 {
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, encodeVersion(c.protocolVersion)); err != nil {
+			return err
+		}
+
+		_, buf, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		version, err := decodeVersion(buf)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			return ErrVersionNotSupported
+		}
+		c.protocolVersion = version
+	}
+
+	return nil
+}
+
+// useCodec 使用协商后版本对应的封包方式与解码器
+func (c *WsConnection) useCodec(codec *ziface.ProtocolCodec) {
+	c.protocolVersion = codec.Version
+	c.setCodec(codec.Packet, codec.Decoder)
+}
+
+// setCodec 设置连接独立使用的封包方式与解码器, 为nil的保持不变
+func (c *WsConnection) setCodec(packet ziface.IDataPack, decoder ziface.IDecoder) {
+	if packet != nil {
+		c.packet = packet
+	}
+	if decoder != nil {
+		c.decoder = decoder
+		c.frameDecoder = newFrameDecoder(decoder)
+	}
+}
+
+func (c *WsConnection) getDecoder() ziface.IDecoder {
+	return c.decoder
+}
+
+func (c *WsConnection) getPacket() ziface.IDataPack {
+	return c.packet
+}
+
+func (c *WsConnection) getOutChain() *outChain {
+	return c.out
+}
+
+// closeBeforeStart 连接开始工作之前(如版本协商失败)关闭连接，不触发OnConnStop
+func (c *WsConnection) closeBeforeStart() {
+	c.cancel()
+
+	c.msgLock.Lock()
+	defer c.msgLock.Unlock()
+
+	_ = c.conn.Close()
+	if c.connManager != nil {
+		c.connManager.Remove(c)
+	}
+	c.isClosed = true
+}
+
+// SetRateLimit 运行时修改当前连接的读写带宽限制, 0表示不限制
+func (c *WsConnection) SetRateLimit(limit ziface.RateLimit) {
+	if c.limiters != nil {
+		c.limiters.setLimit(limit)
+	}
+}
+
+func (c *WsConnection) waitRead(n int) error {
+	if c.limiters == nil {
+		return nil
+	}
+	return c.limiters.waitRead(c.ctx, n)
+}
+
+func (c *WsConnection) waitWrite(n int) error {
+	if c.limiters == nil || c.ctx == nil {
+		return nil
+	}
+	return c.limiters.waitWrite(c.ctx, n)
+}
+
+// SetIdleTimeout 运行时修改当前连接的读写空闲超时时间, 0表示不检测
+func (c *WsConnection) SetIdleTimeout(read, write time.Duration) {
+	if c.idle != nil {
+		c.idle.set(read, write)
+	}
+}
+
+// sendBuff 获取发送缓冲队列, 首次使用时创建
+func (c *WsConnection) sendBuff() chan []byte {
+	c.msgBuffLock.Lock()
+	defer c.msgBuffLock.Unlock()
+
+	if c.msgBuffChan == nil {
+		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
+		//开启用于写回客户端数据流程的Goroutine
+		//此方法只读取MsgBuffChan中的数据没调用SendBuffMsg可以分配内存和启用协程
+		go c.StartWriter()
+	}
+	return c.msgBuffChan
+}
+
+func (c *WsConnection) sendBuffLen() int {
+	return int(atomic.LoadInt64(&c.sendBuffPending))
+}
+
+// Stats 连接的收发统计快照
+func (c *WsConnection) Stats() ziface.ConnStats {
+	return c.stats.snapshot(c)
+}
@@ -0,0 +1,68 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/zpack"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWsClientCall(t *testing.T) {
+	s := NewServer(WithPacket(zpack.NewSeqDataPack())).(*Server)
+	s.IP = "127.0.0.1"
+	s.Port = 28980
+	s.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	s.AddRouter(2, &replyRouter{})
+	s.Start()
+	defer s.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// 与TCP客户端使用相同的IClient接口
+	client := NewWsClient("127.0.0.1", 28980, WithPacketClient(zpack.NewSeqDataPack()), WithRequestTimeoutClient(time.Second))
+	client.SetDecoder(zdecoder.NewSeqTLVDecoder())
+	client.Start()
+	defer client.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	assert.NotNil(t, client.Conn().GetWsConn())
+	data, err := client.Request(context.Background(), 2, []byte("hi"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("re:hi"), data)
+}
+
+func TestWsClientPathAndHeader(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	client := NewWsClient(host, p, WithWsPathClient("gate/ws"), WithWsHeaderClient(http.Header{"Authorization": {"Bearer abc"}}))
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case r := <-requests:
+		assert.Equal(t, "/gate/ws", r.URL.Path)
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+	case <-time.After(time.Second):
+		t.Fatal("websocket handshake is not received")
+	}
+}
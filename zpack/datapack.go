@@ -56,17 +56,17 @@ func (dp *DataPack) Unpack(binaryData []byte) (ziface.IMessage, error) {
 	msg := &Message{}
 
 	//读msgID
-	if err := binary.Read(dataBuff, binary.BigEndian, msg.GetMsgID()); err != nil {
+	if err := binary.Read(dataBuff, binary.BigEndian, &msg.ID); err != nil {
 		return nil, err
 	}
 
 	//读dataLen
-	if err := binary.Read(dataBuff, binary.BigEndian, msg.GetDataLen()); err != nil {
+	if err := binary.Read(dataBuff, binary.BigEndian, &msg.DataLen); err != nil {
 		return nil, err
 	}
 
-	//判断dataLen的长度是否超出我们允许的最大包长度
-	if zconf.GlobalObject.MaxPacketSize > 0 && msg.GetDataLen() > zconf.GlobalObject.MaxPacketSize {
+	//判断dataLen的长度是否超出该消息ID允许的最大包长度
+	if maxSize := zconf.GlobalObject.MaxPacketSizeOf(msg.ID); maxSize > 0 && msg.GetDataLen() > maxSize {
 		return nil, errors.New("too large msg data received")
 	}
 
@@ -2,6 +2,7 @@ package zpack
 
 import (
 	"fmt"
+	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
 	"io"
 	"net"
@@ -20,6 +21,9 @@ func TestDataPack(t *testing.T) {
 		fmt.Println("server listen err:", err)
 		return
 	}
+	defer listener.Close()
+	//服务端处理完连接后通知, 避免测试结束后仍在读取全局配置
+	done := make(chan struct{})
 
 	//创建服务器gotoutine，负责从客户端goroutine读取粘包的数据，然后进行解析
 	go func() {
@@ -27,10 +31,12 @@ func TestDataPack(t *testing.T) {
 			conn, err := listener.Accept()
 			if err != nil {
 				fmt.Println("server accept err:", err)
+				return
 			}
 
 			//处理客户端请求
 			go func(conn net.Conn) {
+				defer close(done)
 				//创建封包拆包对象dp
 				dp := Factory().NewPack(ziface.ZinxDataPack)
 				for {
@@ -39,6 +45,7 @@ func TestDataPack(t *testing.T) {
 					_, err := io.ReadFull(conn, headData) //ReadFull 会把msg填充满为止
 					if err != nil {
 						fmt.Println("read head error")
+						return
 					}
 					//将headData字节流 拆包到msg中
 					msgHead, err := dp.Unpack(headData)
@@ -106,11 +113,38 @@ func TestDataPack(t *testing.T) {
 
 		//向服务器端写数据
 		conn.Write(sendData1)
+		conn.Close()
 	}()
 
 	//客户端阻塞
 	select {
+	case <-done:
 	case <-time.After(time.Second):
-		return
+	}
+}
+
+// 测试按消息ID限制数据包的最大长度
+func TestDataPackMsgMaxPacketSize(t *testing.T) {
+	zconf.GlobalObject.MsgMaxPacketSize = map[uint32]uint32{1: 4}
+	defer func() {
+		zconf.GlobalObject.MsgMaxPacketSize = nil
+	}()
+
+	dp := NewDataPack()
+
+	//msgID=1 限制为4字节
+	data, _ := dp.Pack(NewMsgPackage(1, []byte("hello")))
+	if _, err := dp.Unpack(data[:dp.GetHeadLen()]); err == nil {
+		t.Fatal("msgID 1 should be limited to 4 bytes")
+	}
+
+	//msgID=2 使用全局的MaxPacketSize
+	data, _ = dp.Pack(NewMsgPackage(2, []byte("hello")))
+	msg, err := dp.Unpack(data[:dp.GetHeadLen()])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetMsgID() != 2 || msg.GetDataLen() != 5 {
+		t.Fatalf("unpack head err, msgID = %d, dataLen = %d", msg.GetMsgID(), msg.GetDataLen())
 	}
 }
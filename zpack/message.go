@@ -6,6 +6,9 @@ type Message struct {
 	ID      uint32 //消息的ID
 	Data    []byte //消息的内容
 	rawData []byte //原始数据
+	Seq     uint32 //关联序号, 0表示不携带
+
+	SessionID uint32 //逻辑会话ID, 0表示不属于任何逻辑会话
 }
 
 // NewMsgPackage 创建一个Message消息包
@@ -76,3 +79,23 @@ func (msg *Message) SetMsgID(msgID uint32) {
 func (msg *Message) SetData(data []byte) {
 	msg.Data = data
 }
+
+// GetSeq 获取关联序号
+func (msg *Message) GetSeq() uint32 {
+	return msg.Seq
+}
+
+// SetSeq 设置关联序号
+func (msg *Message) SetSeq(seq uint32) {
+	msg.Seq = seq
+}
+
+// GetSessionID 获取逻辑会话ID
+func (msg *Message) GetSessionID() uint32 {
+	return msg.SessionID
+}
+
+// SetSessionID 设置逻辑会话ID
+func (msg *Message) SetSessionID(sessionID uint32) {
+	msg.SessionID = sessionID
+}
@@ -0,0 +1,69 @@
+package zpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+var muxHeaderLen uint32 = 16
+
+// MuxDataPack 携带逻辑会话ID与关联序号的封包拆包, 包头为 ID uint32 + SessionID uint32 + Seq uint32 + DataLen uint32
+// 一个物理连接上复用多个逻辑会话, 每个逻辑会话有独立的路由与关联序号空间, 对端回复时回传请求的逻辑会话ID
+type MuxDataPack struct{}
+
+// NewMuxDataPack 携带逻辑会话ID的封包拆包实例初始化方法
+func NewMuxDataPack() ziface.IDataPack {
+	return &MuxDataPack{}
+}
+
+// GetHeadLen 获取包头长度方法
+func (dp *MuxDataPack) GetHeadLen() uint32 {
+	//ID uint32(4字节) + SessionID uint32(4字节) + Seq uint32(4字节) + DataLen uint32(4字节)
+	return muxHeaderLen
+}
+
+// Pack 封包方法, 消息不携带逻辑会话ID或关联序号时为0
+func (dp *MuxDataPack) Pack(msg ziface.IMessage) ([]byte, error) {
+	var sessionID, seq uint32
+	if muxMsg, ok := msg.(ziface.IMuxMessage); ok {
+		sessionID = muxMsg.GetSessionID()
+	}
+	if seqMsg, ok := msg.(ziface.ISeqMessage); ok {
+		seq = seqMsg.GetSeq()
+	}
+
+	dataBuff := bytes.NewBuffer(make([]byte, 0, muxHeaderLen+msg.GetDataLen()))
+	for _, field := range []uint32{msg.GetMsgID(), sessionID, seq, msg.GetDataLen()} {
+		if err := binary.Write(dataBuff, binary.BigEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	dataBuff.Write(msg.GetData())
+
+	return dataBuff.Bytes(), nil
+}
+
+// Unpack 拆包方法, 只解析包头
+func (dp *MuxDataPack) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	if uint32(len(binaryData)) < muxHeaderLen {
+		return nil, errors.New("incomplete msg head received")
+	}
+
+	msg := &Message{
+		ID:        binary.BigEndian.Uint32(binaryData[0:4]),
+		SessionID: binary.BigEndian.Uint32(binaryData[4:8]),
+		Seq:       binary.BigEndian.Uint32(binaryData[8:12]),
+		DataLen:   binary.BigEndian.Uint32(binaryData[12:16]),
+	}
+
+	//判断dataLen的长度是否超出该消息ID允许的最大包长度
+	if maxSize := zconf.GlobalObject.MaxPacketSizeOf(msg.ID); maxSize > 0 && msg.GetDataLen() > maxSize {
+		return nil, errors.New("too large msg data received")
+	}
+
+	return msg, nil
+}
@@ -33,6 +33,16 @@ func (f *pack_factory) NewPack(kind string) ziface.IDataPack {
 		dataPack = NewDataPack()
 		break
 
+	//携带关联序号的封包拆包方式
+	case ziface.ZinxSeqDataPack:
+		dataPack = NewSeqDataPack()
+		break
+
+	//携带逻辑会话ID与关联序号的封包拆包方式
+	case ziface.ZinxMuxDataPack:
+		dataPack = NewMuxDataPack()
+		break
+
     //case 自定义封包拆包方式case
 
 	default:
@@ -0,0 +1,65 @@
+package zpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+var seqHeaderLen uint32 = 12
+
+// SeqDataPack 携带关联序号的封包拆包, 包头为 ID uint32 + Seq uint32 + DataLen uint32
+// 对端回复时回传请求的关联序号, 用于RPC方式的请求与回复匹配
+type SeqDataPack struct{}
+
+// NewSeqDataPack 携带关联序号的封包拆包实例初始化方法
+func NewSeqDataPack() ziface.IDataPack {
+	return &SeqDataPack{}
+}
+
+// GetHeadLen 获取包头长度方法
+func (dp *SeqDataPack) GetHeadLen() uint32 {
+	//ID uint32(4字节) + Seq uint32(4字节) + DataLen uint32(4字节)
+	return seqHeaderLen
+}
+
+// Pack 封包方法, 消息不携带关联序号时序号为0
+func (dp *SeqDataPack) Pack(msg ziface.IMessage) ([]byte, error) {
+	var seq uint32
+	if seqMsg, ok := msg.(ziface.ISeqMessage); ok {
+		seq = seqMsg.GetSeq()
+	}
+
+	dataBuff := bytes.NewBuffer(make([]byte, 0, seqHeaderLen+msg.GetDataLen()))
+	for _, field := range []uint32{msg.GetMsgID(), seq, msg.GetDataLen()} {
+		if err := binary.Write(dataBuff, binary.BigEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	dataBuff.Write(msg.GetData())
+
+	return dataBuff.Bytes(), nil
+}
+
+// Unpack 拆包方法, 只解析包头
+func (dp *SeqDataPack) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	if uint32(len(binaryData)) < seqHeaderLen {
+		return nil, errors.New("incomplete msg head received")
+	}
+
+	msg := &Message{
+		ID:      binary.BigEndian.Uint32(binaryData[0:4]),
+		Seq:     binary.BigEndian.Uint32(binaryData[4:8]),
+		DataLen: binary.BigEndian.Uint32(binaryData[8:12]),
+	}
+
+	//判断dataLen的长度是否超出该消息ID允许的最大包长度
+	if maxSize := zconf.GlobalObject.MaxPacketSizeOf(msg.ID); maxSize > 0 && msg.GetDataLen() > maxSize {
+		return nil, errors.New("too large msg data received")
+	}
+
+	return msg, nil
+}
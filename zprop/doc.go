@@ -0,0 +1,9 @@
+// Package zprop 类型安全的连接属性读写, 基于泛型封装 IConnection 的属性接口，
+// 避免业务代码中到处出现 interface{} 的类型断言，并提供原子的 GetOrSet/CompareAndSwap/Update 操作。
+//
+// 需要 Go 1.21 及以上版本: 模块的go版本为1.16, 只有Go 1.21起才允许单个文件通过构建约束使用泛型。
+//
+//	zprop.Set(conn, "uid", uint64(10001))
+//	uid, ok := zprop.Get[uint64](conn, "uid")
+//	count := zprop.Update(conn, "count", func(old int, ok bool) int { return old + 1 })
+package zprop
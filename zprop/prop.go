@@ -0,0 +1,51 @@
+//go:build go1.21
+
+package zprop
+
+import "github.com/aceld/zinx/ziface"
+
+// Get 获取指定类型的连接属性, 属性不存在或类型不匹配时ok为false
+func Get[T any](conn ziface.IConnection, key string) (value T, ok bool) {
+	v, err := conn.GetProperty(key)
+	if err != nil {
+		return value, false
+	}
+	value, ok = v.(T)
+	return value, ok
+}
+
+// GetOr 获取指定类型的连接属性, 属性不存在或类型不匹配时返回def
+func GetOr[T any](conn ziface.IConnection, key string, def T) T {
+	if value, ok := Get[T](conn, key); ok {
+		return value
+	}
+	return def
+}
+
+// Set 设置连接属性
+func Set[T any](conn ziface.IConnection, key string, value T) {
+	conn.SetProperty(key, value)
+}
+
+// GetOrSet 属性不存在时设置为value, 返回属性的当前值, loaded表示属性已经存在
+// 属性已存在但类型不匹配时返回零值, loaded为true
+func GetOrSet[T any](conn ziface.IConnection, key string, value T) (actual T, loaded bool) {
+	v, loaded := conn.GetOrSetProperty(key, value)
+	actual, _ = v.(T)
+	return actual, loaded
+}
+
+// CompareAndSwap 属性的当前值等于old时替换为new
+func CompareAndSwap[T comparable](conn ziface.IConnection, key string, old, new T) bool {
+	return conn.CompareAndSwapProperty(key, old, new)
+}
+
+// Update 在锁内根据属性的当前值计算新值并设置, 返回新值。属性不存在或类型不匹配时old为零值、ok为false
+func Update[T any](conn ziface.IConnection, key string, fn func(old T, ok bool) T) T {
+	v := conn.UpdateProperty(key, func(value interface{}, ok bool) interface{} {
+		old, typed := value.(T)
+		return fn(old, ok && typed)
+	})
+	value, _ := v.(T)
+	return value
+}
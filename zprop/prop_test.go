@@ -0,0 +1,94 @@
+//go:build go1.21
+
+package zprop
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn 只实现属性相关方法的连接
+type fakeConn struct {
+	ziface.IConnection
+	property map[string]interface{}
+	lock     sync.Mutex
+}
+
+func (c *fakeConn) SetProperty(key string, value interface{}) {
+	c.UpdateProperty(key, func(interface{}, bool) interface{} { return value })
+}
+
+func (c *fakeConn) GetProperty(key string) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if value, ok := c.property[key]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no property found")
+}
+
+func (c *fakeConn) GetOrSetProperty(key string, value interface{}) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if actual, ok := c.property[key]; ok {
+		return actual, true
+	}
+	c.property[key] = value
+	return value, false
+}
+
+func (c *fakeConn) CompareAndSwapProperty(key string, old, new interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if current, ok := c.property[key]; (ok && current != old) || (!ok && old != nil) {
+		return false
+	}
+	c.property[key] = new
+	return true
+}
+
+func (c *fakeConn) UpdateProperty(key string, fn func(value interface{}, ok bool) interface{}) interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	value, ok := c.property[key]
+	c.property[key] = fn(value, ok)
+	return c.property[key]
+}
+
+func TestProp(t *testing.T) {
+	conn := &fakeConn{property: make(map[string]interface{})}
+
+	Set(conn, "uid", uint64(10001))
+	uid, ok := Get[uint64](conn, "uid")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10001), uid)
+
+	// 类型不匹配
+	_, ok = Get[string](conn, "uid")
+	assert.False(t, ok)
+	assert.Equal(t, "none", GetOr(conn, "name", "none"))
+
+	name, loaded := GetOrSet(conn, "name", "aceld")
+	assert.False(t, loaded)
+	name, loaded = GetOrSet(conn, "name", "other")
+	assert.True(t, loaded)
+	assert.Equal(t, "aceld", name)
+
+	assert.True(t, CompareAndSwap(conn, "name", "aceld", "zinx"))
+	assert.False(t, CompareAndSwap(conn, "name", "aceld", "zinx2"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Update(conn, "count", func(old int, ok bool) int { return old + 1 })
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 100, GetOr(conn, "count", 0))
+}
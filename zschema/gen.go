@@ -0,0 +1,322 @@
+package zschema
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Schema 消息定义, 用于 `zinx gen` 生成代码
+type Schema struct {
+	Package  string    `json:"package"`  //生成代码所在的包名
+	Messages []Message `json:"messages"` //全部消息定义
+	Proto    bool      `json:"-"`        //消息结构体由protoc生成，此时只生成注册、路由与发送代码
+}
+
+// Message 一条消息的定义
+type Message struct {
+	ID      uint32  `json:"id"`      //MsgID
+	Name    string  `json:"name"`    //消息结构体名称
+	Comment string  `json:"comment"` //注释
+	Fields  []Field `json:"fields"`  //字段定义(JSON Schema)
+}
+
+// Field 消息字段定义
+// Type 支持 string,bool,int32,int64,uint32,uint64,float32,float64,bytes、同一Schema内的消息名称，以及前缀"[]"表示的列表
+type Field struct {
+	Name     string `json:"name"`     //JSON字段名
+	Type     string `json:"type"`     //字段类型
+	Required bool   `json:"required"` //是否必填
+	MaxLen   int    `json:"max_len"`  //字符串/列表的最大长度, 0表示不限制
+	Comment  string `json:"comment"`  //注释
+}
+
+var scalarTypes = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int32":   "int32",
+	"int64":   "int64",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"float32": "float32",
+	"float64": "float64",
+	"bytes":   "[]byte",
+}
+
+// ParseJSONSchema 解析JSON格式的消息定义
+func ParseJSONSchema(data []byte) (*Schema, error) {
+	schema := &Schema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+var (
+	protoMsgIDRegexp   = regexp.MustCompile(`^//\s*@msgid\s+(\d+)`)
+	protoMessageRegexp = regexp.MustCompile(`^message\s+(\w+)\s*\{`)
+	protoPackageRegexp = regexp.MustCompile(`^package\s+([\w.]+)\s*;`)
+	protoGoPkgRegexp   = regexp.MustCompile(`^option\s+go_package\s*=\s*"([^"]+)"\s*;`)
+)
+
+// ParseProto 解析proto文件中的消息定义，在顶层message前一行使用 `// @msgid 1` 注释指定MsgID，
+// 未指定MsgID的message不会生成代码
+func ParseProto(data []byte) (*Schema, error) {
+	schema := &Schema{Proto: true}
+
+	var pendingID string
+	depth := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if depth == 0 {
+			if m := protoMsgIDRegexp.FindStringSubmatch(line); m != nil {
+				pendingID = m[1]
+				continue
+			}
+			if m := protoPackageRegexp.FindStringSubmatch(line); m != nil && schema.Package == "" {
+				parts := strings.Split(m[1], ".")
+				schema.Package = parts[len(parts)-1]
+			}
+			if m := protoGoPkgRegexp.FindStringSubmatch(line); m != nil {
+				schema.Package = goPackageName(m[1])
+			}
+			if m := protoMessageRegexp.FindStringSubmatch(line); m != nil && pendingID != "" {
+				id, err := strconv.ParseUint(pendingID, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid msgid %s", lineNo, pendingID)
+				}
+				schema.Messages = append(schema.Messages, Message{ID: uint32(id), Name: m[1]})
+			}
+			if !strings.HasPrefix(line, "//") {
+				pendingID = ""
+			}
+		}
+
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// goPackageName 从go_package选项中取出包名, 如 "github.com/x/pb;pb" 或 "github.com/x/pb"
+func goPackageName(goPackage string) string {
+	if i := strings.LastIndex(goPackage, ";"); i >= 0 {
+		return goPackage[i+1:]
+	}
+	parts := strings.Split(goPackage, "/")
+	return parts[len(parts)-1]
+}
+
+// genField 模板使用的字段信息
+type genField struct {
+	Field
+	GoName string
+	GoType string
+	Checks []string //校验代码
+}
+
+type genMessage struct {
+	Message
+	Fields []genField
+}
+
+// Generate 根据消息定义生成Go代码: MsgID常量、消息结构体与校验方法、注册、路由与发送方法
+func Generate(schema *Schema) ([]byte, error) {
+	if schema.Package == "" {
+		return nil, fmt.Errorf("zschema: package name is required")
+	}
+
+	names := make(map[string]bool)
+	ids := make(map[uint32]string)
+	for _, msg := range schema.Messages {
+		if msg.Name == "" {
+			return nil, fmt.Errorf("zschema: msgID %d has no name", msg.ID)
+		}
+		if other, ok := ids[msg.ID]; ok {
+			return nil, fmt.Errorf("zschema: msgID %d used by both %s and %s", msg.ID, other, msg.Name)
+		}
+		ids[msg.ID] = msg.Name
+		names[msg.Name] = true
+	}
+
+	messages := make([]genMessage, 0, len(schema.Messages))
+	needErrors := false
+	for _, msg := range schema.Messages {
+		gm := genMessage{Message: msg}
+		if !schema.Proto {
+			for _, field := range msg.Fields {
+				goType, err := goTypeOf(field.Type, names)
+				if err != nil {
+					return nil, fmt.Errorf("zschema: %s.%s: %v", msg.Name, field.Name, err)
+				}
+				gf := genField{Field: field, GoName: exportedName(field.Name), GoType: goType}
+				gf.Checks = fieldChecks(msg.Name, gf)
+				for _, check := range gf.Checks {
+					if strings.Contains(check, "errors.New") {
+						needErrors = true
+					}
+				}
+				gm.Fields = append(gm.Fields, gf)
+			}
+		}
+		messages = append(messages, gm)
+	}
+
+	var buf bytes.Buffer
+	err := genTemplate.Execute(&buf, map[string]interface{}{
+		"Package":    schema.Package,
+		"Proto":      schema.Proto,
+		"Messages":   messages,
+		"NeedErrors": needErrors,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func goTypeOf(typ string, names map[string]bool) (string, error) {
+	if strings.HasPrefix(typ, "[]") {
+		elem, err := goTypeOf(typ[2:], names)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	}
+	if goType, ok := scalarTypes[typ]; ok {
+		return goType, nil
+	}
+	if names[typ] {
+		return "*" + typ, nil
+	}
+	return "", fmt.Errorf("unknown type %q", typ)
+}
+
+// fieldChecks 生成字段的校验代码
+func fieldChecks(msgName string, f genField) []string {
+	var checks []string
+	field := "m." + f.GoName
+	desc := msgName + "." + f.Name
+
+	isList := strings.HasPrefix(f.GoType, "[]")
+	isMsg := strings.HasPrefix(f.GoType, "*")
+
+	if f.Required {
+		switch {
+		case f.GoType == "string":
+			checks = append(checks, fmt.Sprintf("if %s == \"\" {\n\treturn errors.New(%q)\n}", field, desc+" is required"))
+		case isList:
+			checks = append(checks, fmt.Sprintf("if len(%s) == 0 {\n\treturn errors.New(%q)\n}", field, desc+" is required"))
+		case isMsg:
+			checks = append(checks, fmt.Sprintf("if %s == nil {\n\treturn errors.New(%q)\n}", field, desc+" is required"))
+		case f.GoType != "bool":
+			checks = append(checks, fmt.Sprintf("if %s == 0 {\n\treturn errors.New(%q)\n}", field, desc+" is required"))
+		}
+	}
+
+	if f.MaxLen > 0 && (f.GoType == "string" || isList) {
+		checks = append(checks, fmt.Sprintf("if len(%s) > %d {\n\treturn errors.New(%q)\n}", field, f.MaxLen,
+			fmt.Sprintf("%s exceeds max length %d", desc, f.MaxLen)))
+	}
+
+	// 嵌套消息递归校验
+	if isMsg {
+		checks = append(checks, fmt.Sprintf("if %s != nil {\n\tif err := %s.Validate(); err != nil {\n\t\treturn err\n\t}\n}", field, field))
+	}
+
+	return checks
+}
+
+// exportedName 将JSON字段名(如user_name)转换为导出的Go字段名(UserName)
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	s := b.String()
+	if strings.HasSuffix(s, "Id") {
+		s = s[:len(s)-2] + "ID"
+	}
+	return s
+}
+
+var genTemplate = template.Must(template.New("zinx").Parse(`// Code generated by zinx gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .NeedErrors}}
+	"errors"
+{{end}}
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zschema"
+)
+
+const (
+{{- range .Messages}}
+	MsgID{{.Name}} uint32 = {{.ID}}{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+)
+
+func init() {
+{{- range .Messages}}
+	zschema.Register(MsgID{{.Name}}, (*{{.Name}})(nil))
+{{- end}}
+}
+{{$proto := .Proto}}
+{{- range .Messages}}
+{{- if not $proto}}
+// {{.Name}} {{if .Comment}}{{.Comment}}{{else}}消息, MsgID = {{.ID}}{{end}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.GoName}} {{.GoType}} ` + "`json:\"{{.Name}}{{if not .Required}},omitempty{{end}}\"`" + `{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+
+// Validate 校验{{.Name}}消息
+func (m *{{.Name}}) Validate() error {
+{{- range .Fields}}{{range .Checks}}
+	{{.}}
+{{- end}}{{end}}
+	return nil
+}
+{{- end}}
+
+// {{.Name}}Handler {{.Name}}消息的业务处理方法
+type {{.Name}}Handler func(request ziface.IRequest, msg *{{.Name}})
+
+// New{{.Name}}Router 创建{{.Name}}消息的路由, 解码或校验失败的消息被丢弃
+func New{{.Name}}Router(handler {{.Name}}Handler) ziface.IRouter {
+	return zschema.NewRouter(func(request ziface.IRequest, msg interface{}) {
+		handler(request, msg.(*{{.Name}}))
+	})
+}
+
+// Send{{.Name}} 发送{{.Name}}消息
+func Send{{.Name}}(conn ziface.IConnection, msg *{{.Name}}) error {
+	return zschema.Send(conn, msg)
+}
+{{end}}`))
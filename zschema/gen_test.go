@@ -0,0 +1,73 @@
+package zschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJSONSchema = `{
+	"package": "pb",
+	"messages": [
+		{"id": 1, "name": "Login", "comment": "登录请求", "fields": [
+			{"name": "user_id", "type": "uint64", "required": true},
+			{"name": "token", "type": "string", "required": true, "max_len": 64},
+			{"name": "device", "type": "Device"}
+		]},
+		{"id": 2, "name": "Device", "fields": [
+			{"name": "tags", "type": "[]string", "max_len": 8}
+		]}
+	]
+}`
+
+func TestGenerateJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(testJSONSchema))
+	assert.Nil(t, err)
+
+	code, err := Generate(schema)
+	assert.Nil(t, err)
+
+	src := string(code)
+	assert.True(t, strings.Contains(src, "MsgIDLogin  uint32 = 1"))
+	assert.True(t, strings.Contains(src, "UserID uint64"))
+	assert.True(t, strings.Contains(src, "Device *Device `json:\"device,omitempty\"`"))
+	assert.True(t, strings.Contains(src, `errors.New("Login.token exceeds max length 64")`))
+	assert.True(t, strings.Contains(src, "func SendLogin(conn ziface.IConnection, msg *Login) error"))
+
+	// MsgID重复
+	schema.Messages[1].ID = 1
+	_, err = Generate(schema)
+	assert.NotNil(t, err)
+}
+
+func TestParseProto(t *testing.T) {
+	schema, err := ParseProto([]byte(`syntax = "proto3";
+package game.pb;
+option go_package = "github.com/demo/pb;pb";
+
+// @msgid 100
+message Move {
+	message Point {
+		int32 x = 1;
+	}
+	Point to = 1;
+}
+
+message Internal {}
+
+// @msgid 101
+// 聊天消息
+message Chat {
+	string text = 1;
+}
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, "pb", schema.Package)
+	assert.Equal(t, []Message{{ID: 100, Name: "Move"}, {ID: 101, Name: "Chat"}}, schema.Messages)
+
+	code, err := Generate(schema)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(code), "type Move struct"))
+	assert.True(t, strings.Contains(string(code), "func NewMoveRouter(handler MoveHandler) ziface.IRouter"))
+}
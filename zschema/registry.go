@@ -0,0 +1,228 @@
+// Package zschema 消息结构注册中心, 维护MsgID与消息结构体类型的映射,
+// 负责消息的编解码与校验，避免服务端与客户端各自手工维护MsgID与结构体的对应关系。
+//
+// 消息结构体可以由 `zinx gen` 工具根据JSON Schema定义或proto定义生成。
+package zschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	ErrMsgIDNotRegistered = errors.New("zschema: msgID not registered")
+	ErrTypeNotRegistered  = errors.New("zschema: message type not registered")
+)
+
+// Codec 消息序列化方式
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Validator 消息结构体实现该接口时，解码后与编码前会进行校验
+type Validator interface {
+	Validate() error
+}
+
+// JSONCodec JSON序列化
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ProtoCodec protobuf序列化, 消息结构体需实现proto.Message
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("zschema: %T is not proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("zschema: %T is not proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+type entry struct {
+	msgID uint32
+	typ   reflect.Type //消息结构体类型(非指针)
+	codec Codec
+}
+
+// Registry MsgID与消息结构体类型的注册表
+type Registry struct {
+	byID   map[uint32]*entry
+	byType map[reflect.Type]*entry
+	lock   sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byID:   make(map[uint32]*entry),
+		byType: make(map[reflect.Type]*entry),
+	}
+}
+
+// Default 默认注册表, 生成代码在init中注册到此处
+var Default = NewRegistry()
+
+// Register 注册MsgID对应的消息结构体, prototype为结构体指针(可以为nil指针, 如 (*Login)(nil))
+// 实现了proto.Message的结构体使用protobuf序列化，其余使用JSON序列化
+func (r *Registry) Register(msgID uint32, prototype interface{}) {
+	codec := Codec(JSONCodec{})
+	if _, ok := prototype.(proto.Message); ok {
+		codec = ProtoCodec{}
+	}
+	r.RegisterWithCodec(msgID, prototype, codec)
+}
+
+// RegisterWithCodec 注册MsgID对应的消息结构体，并指定序列化方式
+func (r *Registry) RegisterWithCodec(msgID uint32, prototype interface{}, codec Codec) {
+	typ := reflect.TypeOf(prototype)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("zschema: msgID %d prototype must be a struct pointer, got %T", msgID, prototype))
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if old, ok := r.byID[msgID]; ok && old.typ != typ.Elem() {
+		panic(fmt.Sprintf("zschema: msgID %d already registered by %s", msgID, old.typ))
+	}
+
+	e := &entry{msgID: msgID, typ: typ.Elem(), codec: codec}
+	r.byID[msgID] = e
+	r.byType[e.typ] = e
+}
+
+// TypeOf 获取MsgID对应的消息结构体类型
+func (r *Registry) TypeOf(msgID uint32) (reflect.Type, bool) {
+	e, ok := r.lookupID(msgID)
+	if !ok {
+		return nil, false
+	}
+	return e.typ, true
+}
+
+// MsgIDOf 获取消息结构体对应的MsgID
+func (r *Registry) MsgIDOf(msg interface{}) (uint32, bool) {
+	e, ok := r.lookupType(msg)
+	if !ok {
+		return 0, false
+	}
+	return e.msgID, true
+}
+
+// MsgIDs 全部已注册的MsgID
+func (r *Registry) MsgIDs() []uint32 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	ids := make([]uint32, 0, len(r.byID))
+	for id := range r.byID {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// New 创建MsgID对应的消息结构体指针
+func (r *Registry) New(msgID uint32) (interface{}, error) {
+	e, ok := r.lookupID(msgID)
+	if !ok {
+		return nil, ErrMsgIDNotRegistered
+	}
+	return reflect.New(e.typ).Interface(), nil
+}
+
+// Decode 将数据解码为MsgID对应的消息结构体指针并校验
+func (r *Registry) Decode(msgID uint32, data []byte) (interface{}, error) {
+	e, ok := r.lookupID(msgID)
+	if !ok {
+		return nil, ErrMsgIDNotRegistered
+	}
+
+	msg := reflect.New(e.typ).Interface()
+	if err := e.codec.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	if v, ok := msg.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// Encode 校验消息并编码, 返回消息结构体对应的MsgID
+func (r *Registry) Encode(msg interface{}) (uint32, []byte, error) {
+	e, ok := r.lookupType(msg)
+	if !ok {
+		return 0, nil, ErrTypeNotRegistered
+	}
+	if v, ok := msg.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	data, err := e.codec.Marshal(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	return e.msgID, data, nil
+}
+
+func (r *Registry) lookupID(msgID uint32) (*entry, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	e, ok := r.byID[msgID]
+	return e, ok
+}
+
+func (r *Registry) lookupType(msg interface{}) (*entry, bool) {
+	typ := reflect.TypeOf(msg)
+	if typ == nil {
+		return nil, false
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	e, ok := r.byType[typ]
+	return e, ok
+}
+
+// Register 注册到默认注册表
+func Register(msgID uint32, prototype interface{}) {
+	Default.Register(msgID, prototype)
+}
+
+// Decode 使用默认注册表解码
+func Decode(msgID uint32, data []byte) (interface{}, error) {
+	return Default.Decode(msgID, data)
+}
+
+// Encode 使用默认注册表编码
+func Encode(msg interface{}) (uint32, []byte, error) {
+	return Default.Encode(msg)
+}
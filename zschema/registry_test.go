@@ -0,0 +1,41 @@
+package zschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogin struct {
+	User string `json:"user"`
+}
+
+func (m *testLogin) Validate() error {
+	if m.User == "" {
+		return errors.New("user is required")
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register(1, (*testLogin)(nil))
+
+	msgID, data, err := r.Encode(&testLogin{User: "aceld"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), msgID)
+
+	msg, err := r.Decode(1, data)
+	assert.Nil(t, err)
+	assert.Equal(t, &testLogin{User: "aceld"}, msg)
+
+	_, err = r.Decode(1, []byte(`{}`))
+	assert.NotNil(t, err)
+	_, err = r.Decode(2, data)
+	assert.Equal(t, ErrMsgIDNotRegistered, err)
+	_, _, err = r.Encode(&struct{}{})
+	assert.Equal(t, ErrTypeNotRegistered, err)
+
+	assert.Panics(t, func() { r.Register(1, &struct{}{}) })
+}
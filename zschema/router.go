@@ -0,0 +1,56 @@
+package zschema
+
+import (
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// MsgHandler 已解码并校验通过的消息处理方法, msg为消息结构体指针
+type MsgHandler func(request ziface.IRequest, msg interface{})
+
+// Router 按照注册表解码消息后交给MsgHandler处理的路由，解码或校验失败的消息被丢弃
+type Router struct {
+	registry *Registry
+	handler  MsgHandler
+}
+
+// NewRouter 创建使用该注册表解码的路由
+func (r *Registry) NewRouter(handler MsgHandler) ziface.IRouter {
+	return &Router{
+		registry: r,
+		handler:  handler,
+	}
+}
+
+// NewRouter 创建使用默认注册表解码的路由
+func NewRouter(handler MsgHandler) ziface.IRouter {
+	return Default.NewRouter(handler)
+}
+
+func (rt *Router) PreHandle(request ziface.IRequest) {}
+
+func (rt *Router) Handle(request ziface.IRequest) {
+	msg, err := rt.registry.Decode(request.GetMsgID(), request.GetData())
+	if err != nil {
+		zlog.Ins().ErrorF("zschema decode msgID = %d error: %v", request.GetMsgID(), err)
+		return
+	}
+
+	rt.handler(request, msg)
+}
+
+func (rt *Router) PostHandle(request ziface.IRequest) {}
+
+// Send 编码消息并按照消息结构体对应的MsgID发送
+func (r *Registry) Send(conn ziface.IConnection, msg interface{}) error {
+	msgID, data, err := r.Encode(msg)
+	if err != nil {
+		return err
+	}
+	return conn.SendMsg(msgID, data)
+}
+
+// Send 使用默认注册表编码并发送消息
+func Send(conn ziface.IConnection, msg interface{}) error {
+	return Default.Send(conn, msg)
+}
@@ -0,0 +1,370 @@
+package ztimer
+
+// cron表达式
+// 支持6个字段(秒 分 时 日 月 周)或标准的5个字段(分 时 日 月 周, 秒为0), 如:
+//     "0 */5 * * * *"       每5分钟
+//     "30 0 4 * * MON-FRI"  工作日4点0分30秒
+//     "0 0 * * *"           每天0点
+// 每个字段支持 * ? , - / 与月份、星期的英文缩写(JAN-DEC, SUN-SAT), 星期的0与7都表示星期日
+// 日与周同时指定时满足其一即可(与标准cron相同)
+// 预定义: @yearly(@annually) @monthly @weekly @daily(@midnight) @hourly
+// 时区: 表达式前加"CRON_TZ=时区 "或"TZ=时区 ", 如"CRON_TZ=Asia/Shanghai 0 0 4 * * *", 默认使用本地时区
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// CronSchedule 解析后的cron表达式
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// 日或周为*时两者同时满足, 否则满足其一即可
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// cronField 每个字段的取值范围与名称
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSecond = cronField{name: "second", min: 0, max: 59}
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 星期的7为星期日, 解析后与0合并
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron 解析cron表达式, 格式见文件开头的说明
+func ParseCron(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	loc := time.Local
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		sep := strings.IndexAny(spec, " \t")
+		if sep < 0 {
+			return nil, fmt.Errorf("cron %q: missing fields after time zone", spec)
+		}
+		name := spec[strings.Index(spec, "=")+1 : sep]
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("cron %q: %v", spec, err)
+		}
+		spec = strings.TrimSpace(spec[sep:])
+	}
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: expected 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	s := &CronSchedule{loc: loc}
+	var err error
+	parsers := []struct {
+		bits  *uint64
+		field cronField
+	}{
+		{&s.second, cronSecond}, {&s.minute, cronMinute}, {&s.hour, cronHour},
+		{&s.dom, cronDom}, {&s.month, cronMonth}, {&s.dow, cronDow},
+	}
+	for i, p := range parsers {
+		if *p.bits, err = parseCronField(fields[i], p.field); err != nil {
+			return nil, fmt.Errorf("cron %q: %v", spec, err)
+		}
+	}
+	// 星期日可以写作0或7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// parseCronField 解析一个字段, 返回取值的位图
+func parseCronField(expr string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", field.name, part)
+			}
+			rangeExpr, step = part[:i], n
+		}
+
+		start, end := field.min, field.max
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+		case strings.Contains(rangeExpr, "-"):
+			i := strings.Index(rangeExpr, "-")
+			var err error
+			if start, err = field.value(rangeExpr[:i]); err != nil {
+				return 0, err
+			}
+			if end, err = field.value(rangeExpr[i+1:]); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if start, err = field.value(rangeExpr); err != nil {
+				return 0, err
+			}
+			// "5/10"表示从5开始到最大值, 每10个
+			if !strings.Contains(part, "/") {
+				end = start
+			}
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range in %s %q", field.name, part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value 解析字段中的一个值(数字或名称)并检查范围
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d out of range [%d, %d]", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// dayMatches 日期是否满足日与周的条件
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next 晚于t的下一个触发时间, 返回的时间使用t的时区; 5年内没有触发时间(如2月30日)时返回零值
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	t = t.In(s.loc)
+	// 从下一秒开始
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+	// 从月到秒依次检查, 不满足时跳到该单位的下一个值并重新检查
+	for t.Year() <= yearLimit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.loc).Add(time.Minute)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t.In(origLoc)
+	}
+	return time.Time{}
+}
+
+// repeatSchedule 周期定时器的触发时间, 如CronSchedule
+type repeatSchedule interface {
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule 固定间隔, 触发时间按创建时间对齐为anchor+k*interval, 再加上[0, jitter)的随机偏移
+// 每次从对齐的时间计算而不是从上一次执行结束计算, 执行耗时与随机偏移都不会累积;
+// 落后超过一个间隔时跳过错过的次数
+type intervalSchedule struct {
+	anchor   time.Time
+	interval time.Duration
+	jitter   time.Duration
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	k := int64(0)
+	if d := t.Sub(s.anchor); d >= 0 {
+		k = int64(d/s.interval) + 1
+	}
+	next := s.anchor.Add(time.Duration(k) * s.interval)
+	if s.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+	}
+	return next
+}
+
+// cronJob 调度器中的周期任务(cron或固定间隔)
+type cronJob struct {
+	schedule repeatSchedule
+	f        func()
+	ctx      context.Context
+}
+
+// Cron 按cron表达式周期执行f, 如scheduler.Cron("0 */5 * * * *", fn), 返回的句柄用于取消
+// 每次触发时按表达式计算下一次的时间, 并使用同一个tID重新加入时间轮
+func (ts *TimerScheduler) Cron(spec string, f func()) (*TimerHandle, error) {
+	return ts.cron(nil, spec, f)
+}
+
+// CronContext 与Cron相同, ctx结束时自动取消
+func (ts *TimerScheduler) CronContext(ctx context.Context, spec string, f func()) (*TimerHandle, error) {
+	return ts.cron(ctx, spec, f)
+}
+
+// Every 每隔interval执行一次f, 返回的句柄用于取消
+func (ts *TimerScheduler) Every(interval time.Duration, f func()) (*TimerHandle, error) {
+	return ts.every(nil, interval, 0, f)
+}
+
+// EveryContext 与Every相同, ctx结束时自动取消
+func (ts *TimerScheduler) EveryContext(ctx context.Context, interval time.Duration, f func()) (*TimerHandle, error) {
+	return ts.every(ctx, interval, 0, f)
+}
+
+// Repeat 每隔interval执行一次f, 每次的触发时间加上[0, jitter)的随机偏移, 返回的句柄用于取消
+// 大量连接同时创建相同间隔的任务时(如心跳、存盘), 随机偏移将它们分散开, 避免同时触发
+func (ts *TimerScheduler) Repeat(interval, jitter time.Duration, f func()) (*TimerHandle, error) {
+	return ts.every(nil, interval, jitter, f)
+}
+
+// RepeatContext 与Repeat相同, ctx结束时自动取消
+func (ts *TimerScheduler) RepeatContext(ctx context.Context, interval, jitter time.Duration, f func()) (*TimerHandle, error) {
+	return ts.every(ctx, interval, jitter, f)
+}
+
+func (ts *TimerScheduler) every(ctx context.Context, interval, jitter time.Duration, f func()) (*TimerHandle, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("every: interval must be positive, got %v", interval)
+	}
+	if jitter < 0 || jitter >= interval {
+		return nil, fmt.Errorf("every: jitter must be in [0, %v), got %v", interval, jitter)
+	}
+	return ts.repeat(ctx, &intervalSchedule{anchor: time.Now(), interval: interval, jitter: jitter}, f)
+}
+
+func (ts *TimerScheduler) cron(ctx context.Context, spec string, f func()) (*TimerHandle, error) {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron %q: no next run time", spec)
+	}
+	return ts.repeat(ctx, schedule, f)
+}
+
+// repeat 按schedule周期执行f
+func (ts *TimerScheduler) repeat(ctx context.Context, schedule repeatSchedule, f func()) (*TimerHandle, error) {
+	if ctx != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	next := schedule.Next(time.Now())
+
+	ts.Lock()
+	defer ts.Unlock()
+
+	tID := ts.nextID()
+	ts.crons[tID] = &cronJob{schedule: schedule, f: f, ctx: ctx}
+	if ctx != nil {
+		ts.bindContext(ctx, tID)
+	}
+	if err := ts.addCronTimer(tID, next); err != nil {
+		delete(ts.crons, tID)
+		ts.unbindContext(tID)
+		return nil, err
+	}
+	return &TimerHandle{ts: ts, tID: tID}, nil
+}
+
+// addCronTimer 将cron任务在next的定时器加入时间轮, 超过maxDelay时先加入一个中间的定时器, 调用者需持有锁
+func (ts *TimerScheduler) addCronTimer(tID uint32, next time.Time) error {
+	at, due := next, true
+	if time.Until(next) > ts.maxDelay() {
+		at, due = time.Now().Add(ts.maxDelay()), false
+	}
+	df := NewDelayFunc(ts.cronFired, []interface{}{tID, next, due})
+	return ts.tw.AddTimer(tID, NewTimerAt(df, at.UnixNano()))
+}
+
+// cronFired cron定时器触发: 先安排下一次, 到期时再执行任务
+func (ts *TimerScheduler) cronFired(v ...interface{}) {
+	tID, next, due := v[0].(uint32), v[1].(time.Time), v[2].(bool)
+
+	ts.Lock()
+	job, ok := ts.crons[tID]
+	if !ok || (job.ctx != nil && job.ctx.Err() != nil) {
+		// 已经被取消, 或ctx已经结束等待取消
+		ts.Unlock()
+		return
+	}
+	if due {
+		// 以本次的计划时间计算, 避免定时器提前触发时重复执行; 执行已经落后时从当前时间计算
+		base := next
+		if now := time.Now(); now.After(base) {
+			base = now
+		}
+		next = job.schedule.Next(base)
+	}
+	if next.IsZero() {
+		delete(ts.crons, tID)
+		ts.unbindContext(tID)
+	} else if err := ts.addCronTimer(tID, next); err != nil {
+		zlog.Ins().ErrorF("cron timer %d reschedule err: %v", tID, err)
+	}
+	ts.Unlock()
+
+	if due {
+		job.f()
+	}
+}
@@ -0,0 +1,119 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 2, 30, 500, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"CRON_TZ=UTC 0 */5 * * * *", time.Date(2024, 3, 15, 10, 5, 0, 0, time.UTC)},
+		{"TZ=UTC * * * * * *", time.Date(2024, 3, 15, 10, 2, 31, 0, time.UTC)},
+		// 5个字段时秒为0
+		{"TZ=UTC 30 4 * * *", time.Date(2024, 3, 16, 4, 30, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 9 * * MON-FRI", time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 0 * * 7", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 0 1 jan,jul ?", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 15/20 * * * * *", time.Date(2024, 3, 15, 10, 2, 35, 0, time.UTC)},
+		{"TZ=UTC @monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// 日与周同时指定时满足其一即可: 3月20日(周三)早于周五3月22日
+		{"TZ=UTC 0 0 0 20 * FRI", time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 上海时间4点为UTC前一天20点
+		{"CRON_TZ=Asia/Shanghai 0 0 4 * * *", time.Date(2024, 3, 15, 20, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := ParseCron(c.spec)
+		if !assert.Nil(t, err, c.spec) {
+			continue
+		}
+		assert.True(t, c.want.Equal(s.Next(from)), "%s: want %v, got %v", c.spec, c.want, s.Next(from))
+	}
+
+	// 不存在的日期
+	s, err := ParseCron("0 0 0 30 2 *")
+	assert.Nil(t, err)
+	assert.True(t, s.Next(from).IsZero())
+}
+
+func TestParseCronError(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"5-1 * * * * *",
+		"* * * * foo *",
+		"CRON_TZ=Mars/Base * * * * * *",
+		"TZ=UTC",
+	} {
+		_, err := ParseCron(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestCronScheduler(t *testing.T) {
+	ts := NewAutoExecTimerScheduler()
+
+	var count int32
+	handle, err := ts.Cron("* * * * * *", func() { atomic.AddInt32(&count, 1) })
+	assert.Nil(t, err)
+	time.Sleep(2500 * time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&count), int32(2))
+
+	handle.Cancel()
+	time.Sleep(100 * time.Millisecond)
+	fired := atomic.LoadInt32(&count)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, fired, atomic.LoadInt32(&count))
+
+	_, err = ts.Cron("* * *", func() {})
+	assert.NotNil(t, err)
+	_, err = ts.Every(0, func() {})
+	assert.NotNil(t, err)
+}
+
+func TestRepeatJitter(t *testing.T) {
+	anchor := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &intervalSchedule{anchor: anchor, interval: time.Second, jitter: 100 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		// 从带偏移的触发时间计算, 下一次仍然对齐到anchor+k*interval
+		fired := s.Next(anchor.Add(time.Duration(i) * time.Second))
+		nominal := anchor.Add(time.Duration(i+1) * time.Second)
+		assert.False(t, fired.Before(nominal))
+		assert.True(t, fired.Before(nominal.Add(100*time.Millisecond)))
+		next := s.Next(fired)
+		assert.False(t, next.Before(nominal.Add(time.Second)))
+		assert.True(t, next.Before(nominal.Add(1100*time.Millisecond)))
+	}
+	// 落后时跳过错过的次数
+	s.jitter = 0
+	assert.Equal(t, anchor.Add(6*time.Second), s.Next(anchor.Add(5500*time.Millisecond)))
+
+	ts := newTestScheduler(t)
+	_, err := ts.Repeat(time.Second, time.Second, func() {})
+	assert.NotNil(t, err)
+	_, err = ts.Repeat(time.Second, -time.Millisecond, func() {})
+	assert.NotNil(t, err)
+
+	// 执行耗时不会使后续的触发时间漂移
+	var count int32
+	handle, err := ts.Repeat(200*time.Millisecond, 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(50 * time.Millisecond)
+	})
+	assert.Nil(t, err)
+	time.Sleep(2100 * time.Millisecond)
+	handle.Cancel()
+	assert.Equal(t, int32(10), atomic.LoadInt32(&count))
+}
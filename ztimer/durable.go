@@ -0,0 +1,179 @@
+package ztimer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// DurableStoreTimeout 读写持久化定时器存储的超时时间
+const DurableStoreTimeout = 5 * time.Second
+
+// DurableTask 持久化定时器触发时执行的任务, payload为创建定时器时的参数
+type DurableTask func(payload []byte)
+
+// RegisterTask 注册持久化定时器的任务, 需要在SetStore之前注册, 进程重启后按名称找到执行的函数
+func (ts *TimerScheduler) RegisterTask(name string, task DurableTask) {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.tasks[name] = task
+}
+
+// SetStore 设置持久化定时器的存储(如FileTimerStore、zcluster.RedisTimerStore), 并将存储中的定时器重新加入时间轮
+// 进程重启期间已经过期的定时器立即执行; 任务没有注册的定时器保留在存储中, 不加入时间轮
+func (ts *TimerScheduler) SetStore(store ziface.ITimerStore) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DurableStoreTimeout)
+	defer cancel()
+	timers, err := store.LoadAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	ts.Lock()
+	defer ts.Unlock()
+
+	ts.store = store
+	restored := 0
+	for _, timer := range timers {
+		if _, ok := ts.tasks[timer.Task]; !ok {
+			zlog.Ins().ErrorF("durable timer %s: task %q not registered", timer.ID, timer.Task)
+			continue
+		}
+		if _, err := ts.armDurable(timer); err != nil {
+			return err
+		}
+		restored++
+	}
+	zlog.Ins().InfoF("restored %d durable timers", restored)
+	return nil
+}
+
+// CreateDurableTimerAt 创建一个在at触发的持久化定时器, 保存到存储之后加入时间轮, 触发时以payload执行名为task的任务
+// 相同id的定时器替换之前的; 任务执行完成后从存储中删除, 执行期间进程退出时重启后会再次执行
+func (ts *TimerScheduler) CreateDurableTimerAt(id, task string, payload []byte, at time.Time) (*TimerHandle, error) {
+	ts.RLock()
+	store, registered := ts.store, ts.tasks[task] != nil
+	ts.RUnlock()
+	if store == nil {
+		return nil, errors.New("durable timer: store not set")
+	}
+	if !registered {
+		return nil, fmt.Errorf("durable timer: task %q not registered", task)
+	}
+
+	ts.durableLock.Lock()
+	defer ts.durableLock.Unlock()
+
+	timer := ziface.DurableTimer{ID: id, Task: task, Payload: payload, At: at.UnixNano()}
+	ctx, cancel := context.WithTimeout(context.Background(), DurableStoreTimeout)
+	defer cancel()
+	if err := store.Save(ctx, timer); err != nil {
+		return nil, err
+	}
+
+	ts.Lock()
+	defer ts.Unlock()
+	return ts.armDurable(timer)
+}
+
+// CreateDurableTimerAfter 创建一个在duration之后触发的持久化定时器
+func (ts *TimerScheduler) CreateDurableTimerAfter(id, task string, payload []byte, duration time.Duration) (*TimerHandle, error) {
+	return ts.CreateDurableTimerAt(id, task, payload, time.Now().Add(duration))
+}
+
+// CancelDurableTimer 按id取消持久化的定时器并从存储中删除, 用于进程重启之后没有句柄的定时器
+func (ts *TimerScheduler) CancelDurableTimer(id string) error {
+	ts.Lock()
+	if tID, ok := ts.durableIDs[id]; ok {
+		ts.removeTimer(tID)
+	}
+	ts.Unlock()
+	return ts.deleteDurable(id)
+}
+
+// armDurable 将持久化定时器加入时间轮, 替换相同id的定时器, 调用者需持有锁
+func (ts *TimerScheduler) armDurable(timer ziface.DurableTimer) (*TimerHandle, error) {
+	if old, ok := ts.durableIDs[timer.ID]; ok {
+		ts.removeTimer(old)
+	}
+
+	tID := ts.nextID()
+	if err := ts.addDurableTimer(tID, timer); err != nil {
+		return nil, err
+	}
+	ts.durables[tID] = timer.ID
+	ts.durableIDs[timer.ID] = tID
+	return &TimerHandle{ts: ts, tID: tID}, nil
+}
+
+// addDurableTimer 超过maxDelay时先加入一个中间的定时器, 调用者需持有锁
+func (ts *TimerScheduler) addDurableTimer(tID uint32, timer ziface.DurableTimer) error {
+	at := timer.At
+	if hop := time.Now().Add(ts.maxDelay()).UnixNano(); at > hop {
+		at = hop
+	}
+	df := NewDelayFunc(ts.durableFired, []interface{}{tID, timer})
+	return ts.tw.AddTimer(tID, NewTimerAt(df, at))
+}
+
+// durableFired 持久化定时器触发, 执行任务之后从存储中删除
+func (ts *TimerScheduler) durableFired(v ...interface{}) {
+	tID, timer := v[0].(uint32), v[1].(ziface.DurableTimer)
+
+	ts.Lock()
+	if id, ok := ts.durables[tID]; !ok || id != timer.ID {
+		// 已经被取消或替换
+		ts.Unlock()
+		return
+	}
+	if time.Until(time.Unix(0, timer.At)) > ts.maxDelay() {
+		if err := ts.addDurableTimer(tID, timer); err != nil {
+			zlog.Ins().ErrorF("durable timer %s reschedule err: %v", timer.ID, err)
+		}
+		ts.Unlock()
+		return
+	}
+	delete(ts.durables, tID)
+	delete(ts.durableIDs, timer.ID)
+	task := ts.tasks[timer.Task]
+	ts.Unlock()
+
+	task(timer.Payload)
+
+	// 执行期间创建了相同id的新定时器时不删除
+	ts.durableLock.Lock()
+	defer ts.durableLock.Unlock()
+	ts.RLock()
+	_, replaced := ts.durableIDs[timer.ID]
+	ts.RUnlock()
+	if replaced {
+		return
+	}
+	if err := ts.deleteStored(timer.ID); err != nil {
+		zlog.Ins().ErrorF("delete durable timer %s err: %v", timer.ID, err)
+	}
+}
+
+// deleteDurable 从存储中删除定时器
+func (ts *TimerScheduler) deleteDurable(id string) error {
+	ts.durableLock.Lock()
+	defer ts.durableLock.Unlock()
+	return ts.deleteStored(id)
+}
+
+// deleteStored 调用者需持有durableLock
+func (ts *TimerScheduler) deleteStored(id string) error {
+	ts.RLock()
+	store := ts.store
+	ts.RUnlock()
+	if store == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DurableStoreTimeout)
+	defer cancel()
+	return store.Delete(ctx, id)
+}
@@ -0,0 +1,90 @@
+package ztimer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScheduler(t *testing.T) *TimerScheduler {
+	ts, err := NewTimerSchedulerWithTiers(10*time.Millisecond, 100, 60)
+	assert.Nil(t, err)
+	ts.Start()
+	go func() {
+		for df := range ts.GetTriggerChan() {
+			df.Call()
+		}
+	}()
+	return ts
+}
+
+func TestDurableTimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timers.json")
+	ctx := context.Background()
+	unbanned := make(chan string, 4)
+	unban := func(payload []byte) { unbanned <- string(payload) }
+
+	ts := newTestScheduler(t)
+	_, err := ts.CreateDurableTimerAfter("unban:1", "unban", []byte("1"), time.Hour)
+	assert.NotNil(t, err, "store not set")
+	ts.RegisterTask("unban", unban)
+	assert.Nil(t, ts.SetStore(NewFileTimerStore(path)))
+	_, err = ts.CreateDurableTimerAfter("x", "missing", nil, time.Hour)
+	assert.NotNil(t, err, "task not registered")
+
+	_, err = ts.CreateDurableTimerAfter("unban:1", "unban", []byte("1"), 100*time.Millisecond)
+	assert.Nil(t, err)
+	_, err = ts.CreateDurableTimerAfter("unban:2", "unban", []byte("2"), time.Hour)
+	assert.Nil(t, err)
+	// 相同ID替换之前的定时器
+	_, err = ts.CreateDurableTimerAfter("unban:3", "unban", []byte("3"), 100*time.Millisecond)
+	assert.Nil(t, err)
+	_, err = ts.CreateDurableTimerAfter("unban:3", "unban", []byte("3"), 2*time.Hour)
+	assert.Nil(t, err)
+
+	select {
+	case id := <-unbanned:
+		assert.Equal(t, "1", id)
+	case <-time.After(time.Second):
+		t.Fatal("durable timer not fired")
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.Empty(t, unbanned)
+	timers, err := NewFileTimerStore(path).LoadAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, timers, 2)
+	assert.Equal(t, "unban:2", timers[0].ID)
+	assert.Equal(t, "unban:3", timers[1].ID)
+
+	// 重启: 存储中的定时器重新加入时间轮, 重启期间过期的立即执行, 没有注册的任务保留在存储中
+	store := NewFileTimerStore(path)
+	assert.Nil(t, store.Save(ctx, ziface.DurableTimer{ID: "unban:4", Task: "unban", Payload: []byte("4"), At: time.Now().Add(-time.Minute).UnixNano()}))
+	assert.Nil(t, store.Save(ctx, ziface.DurableTimer{ID: "mail:1", Task: "mail", At: time.Now().UnixNano()}))
+	restarted := newTestScheduler(t)
+	restarted.RegisterTask("unban", unban)
+	assert.Nil(t, restarted.SetStore(store))
+	select {
+	case id := <-unbanned:
+		assert.Equal(t, "4", id)
+	case <-time.After(time.Second):
+		t.Fatal("overdue durable timer not fired")
+	}
+	restarted.RLock()
+	assert.Len(t, restarted.durableIDs, 2)
+	restarted.RUnlock()
+
+	// 没有句柄时按ID取消
+	assert.Nil(t, restarted.CancelDurableTimer("unban:2"))
+	time.Sleep(100 * time.Millisecond)
+	timers, err = store.LoadAll(ctx)
+	assert.Nil(t, err)
+	ids := make([]string, 0, len(timers))
+	for _, timer := range timers {
+		ids = append(ids, timer.ID)
+	}
+	assert.ElementsMatch(t, []string{"mail:1", "unban:3"}, ids)
+}
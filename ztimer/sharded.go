@@ -0,0 +1,214 @@
+package ztimer
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// ShardedTimerScheduler 分片的定时器调度器
+// 由多个独立的TimerScheduler(各自的分层时间轮与锁)组成, 新建的定时器轮流加入各个分片,
+// 数十万连接各自持有心跳、空闲定时器时, 创建与取消分散到不同的锁上, 避免单个调度器的锁成为热点
+// 全部分片共用一个触发队列; 定时器的tID按分片数错开, 由tID即可找到所在的分片
+// 分片数向上取整为2的幂, tID的计数回绕之后仍然落在原来的分片
+// 持久化定时器请使用TimerScheduler
+type ShardedTimerScheduler struct {
+	shards      []*TimerScheduler
+	triggerChan chan *DelayFunc
+	//轮流选择分片的计数器
+	next    uint32
+	metrics ziface.IMetrics
+	//停止汇总指标的Goroutine
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewShardedTimerScheduler 返回一个分片的定时器调度器, 每个分片按全局配置的TimerTick与TimerScales创建时间轮
+// shards为分片数, 不大于0时使用CPU核数, 不是2的幂时向上取整
+func NewShardedTimerScheduler(shards int) *ShardedTimerScheduler {
+	tick := time.Duration(zconf.GlobalObject.TimerTick) * time.Millisecond
+	sts, err := NewShardedTimerSchedulerWithTiers(shards, tick, zconf.GlobalObject.TimerScales...)
+	if err != nil {
+		zlog.Ins().ErrorF("%v, use default timer wheels", err)
+		sts, _ = NewShardedTimerSchedulerWithTiers(shards, DefaultTimerTick, DefaultTimerScales...)
+	}
+	return sts
+}
+
+// NewShardedTimerSchedulerWithTiers 返回一个分片的定时器调度器, 每个分片的时间轮参数与NewTimerSchedulerWithTiers相同
+func NewShardedTimerSchedulerWithTiers(shards int, tick time.Duration, scales ...int) (*ShardedTimerScheduler, error) {
+	if shards <= 0 {
+		shards = runtime.NumCPU()
+	}
+	//tID按分片数递增, 分片数整除2^32时计数回绕后tID%shards不变
+	for n := 1; ; n <<= 1 {
+		if n >= shards {
+			shards = n
+			break
+		}
+	}
+	sts := &ShardedTimerScheduler{
+		shards:      make([]*TimerScheduler, shards),
+		triggerChan: make(chan *DelayFunc, MaxChanBuff),
+		stop:        make(chan struct{}),
+	}
+	for i := range sts.shards {
+		ts, err := NewTimerSchedulerWithTiers(tick, scales...)
+		if err != nil {
+			return nil, err
+		}
+		//第i个分片的tID为i+shards, i+2*shards...
+		ts.IDGen, ts.idStep = uint32(i), uint32(shards)
+		ts.triggerChan = sts.triggerChan
+		ts.sharded = true
+		sts.shards[i] = ts
+	}
+	return sts, nil
+}
+
+// NewAutoExecShardedTimerScheduler 分片的时间轮定时器 自动调度
+func NewAutoExecShardedTimerScheduler(shards int) *ShardedTimerScheduler {
+	sts := NewShardedTimerScheduler(shards)
+	sts.Start()
+
+	go func() {
+		for df := range sts.triggerChan {
+			go df.Call()
+		}
+	}()
+
+	return sts
+}
+
+// shard 轮流选择一个分片加入新的定时器
+func (sts *ShardedTimerScheduler) shard() *TimerScheduler {
+	n := atomic.AddUint32(&sts.next, 1)
+	return sts.shards[n%uint32(len(sts.shards))]
+}
+
+// CreateTimerAt 创建一个定点Timer, 返回Timer的句柄
+func (sts *ShardedTimerScheduler) CreateTimerAt(df *DelayFunc, unixNano int64) (*TimerHandle, error) {
+	return sts.shard().CreateTimerAt(df, unixNano)
+}
+
+// CreateTimerAfter 创建一个延迟Timer, 返回Timer的句柄
+func (sts *ShardedTimerScheduler) CreateTimerAfter(df *DelayFunc, duration time.Duration) (*TimerHandle, error) {
+	return sts.shard().CreateTimerAfter(df, duration)
+}
+
+// CreateTimerAtContext 创建一个绑定ctx的定点Timer, ctx结束时自动取消
+func (sts *ShardedTimerScheduler) CreateTimerAtContext(ctx context.Context, df *DelayFunc, unixNano int64) (*TimerHandle, error) {
+	return sts.shard().CreateTimerAtContext(ctx, df, unixNano)
+}
+
+// CreateTimerAfterContext 创建一个绑定ctx的延迟Timer, ctx结束时自动取消
+func (sts *ShardedTimerScheduler) CreateTimerAfterContext(ctx context.Context, df *DelayFunc, duration time.Duration) (*TimerHandle, error) {
+	return sts.shard().CreateTimerAfterContext(ctx, df, duration)
+}
+
+// Cron 按cron表达式周期执行f
+func (sts *ShardedTimerScheduler) Cron(spec string, f func()) (*TimerHandle, error) {
+	return sts.shard().Cron(spec, f)
+}
+
+// CronContext 与Cron相同, ctx结束时自动取消
+func (sts *ShardedTimerScheduler) CronContext(ctx context.Context, spec string, f func()) (*TimerHandle, error) {
+	return sts.shard().CronContext(ctx, spec, f)
+}
+
+// Every 每隔interval执行一次f
+func (sts *ShardedTimerScheduler) Every(interval time.Duration, f func()) (*TimerHandle, error) {
+	return sts.shard().Every(interval, f)
+}
+
+// EveryContext 与Every相同, ctx结束时自动取消
+func (sts *ShardedTimerScheduler) EveryContext(ctx context.Context, interval time.Duration, f func()) (*TimerHandle, error) {
+	return sts.shard().EveryContext(ctx, interval, f)
+}
+
+// Repeat 每隔interval执行一次f, 每次的触发时间加上[0, jitter)的随机偏移
+func (sts *ShardedTimerScheduler) Repeat(interval, jitter time.Duration, f func()) (*TimerHandle, error) {
+	return sts.shard().Repeat(interval, jitter, f)
+}
+
+// RepeatContext 与Repeat相同, ctx结束时自动取消
+func (sts *ShardedTimerScheduler) RepeatContext(ctx context.Context, interval, jitter time.Duration, f func()) (*TimerHandle, error) {
+	return sts.shard().RepeatContext(ctx, interval, jitter, f)
+}
+
+// CancelTimer 按tID找到所在的分片并删除timer
+func (sts *ShardedTimerScheduler) CancelTimer(tID uint32) {
+	sts.shards[tID%uint32(len(sts.shards))].CancelTimer(tID)
+}
+
+// GetTriggerChan 获取全部分片计时结束的延迟执行函数通道
+func (sts *ShardedTimerScheduler) GetTriggerChan() chan *DelayFunc {
+	return sts.triggerChan
+}
+
+// SetMetrics 设置指标接口, 需要在Start之前设置
+func (sts *ShardedTimerScheduler) SetMetrics(metrics ziface.IMetrics) {
+	sts.metrics = metrics
+	for _, ts := range sts.shards {
+		ts.SetMetrics(metrics)
+	}
+}
+
+// Start 非阻塞的方式启动全部分片
+func (sts *ShardedTimerScheduler) Start() {
+	for _, ts := range sts.shards {
+		ts.Start()
+	}
+	if sts.metrics == nil {
+		return
+	}
+	//分片不单独记录等待触发的定时器数, 每秒汇总记录一次
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sts.metrics.Gauge(ziface.MetricTimerPending, float64(sts.Stats().Pending), nil)
+			case <-sts.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止Start启动的汇总指标Goroutine, 可以重复调用
+// 各分片的时间轮与TimerScheduler一样在进程内一直运行, 停止后不应再创建定时器
+func (sts *ShardedTimerScheduler) Stop() {
+	sts.stopOnce.Do(func() {
+		close(sts.stop)
+	})
+}
+
+// Stats 全部分片汇总的统计
+func (sts *ShardedTimerScheduler) Stats() TimerStats {
+	stats := TimerStats{Queued: len(sts.triggerChan)}
+	var latencySum time.Duration
+	for _, ts := range sts.shards {
+		s := ts.Stats()
+		stats.Pending += s.Pending
+		stats.Fired += s.Fired
+		stats.Late += s.Late
+		stats.Blocked += s.Blocked
+		stats.Dropped += s.Dropped
+		latencySum += s.AvgLatency * time.Duration(s.Fired)
+		if s.MaxLatency > stats.MaxLatency {
+			stats.MaxLatency = s.MaxLatency
+		}
+	}
+	if stats.Fired > 0 {
+		stats.AvgLatency = latencySum / time.Duration(stats.Fired)
+	}
+	return stats
+}
@@ -0,0 +1,116 @@
+package ztimer
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedTimerScheduler(t *testing.T) {
+	sts, err := NewShardedTimerSchedulerWithTiers(4, 10*time.Millisecond, 100, 60)
+	assert.Nil(t, err)
+	sts.Start()
+	go func() {
+		for df := range sts.GetTriggerChan() {
+			df.Call()
+		}
+	}()
+
+	var fired int32
+	df := NewDelayFunc(func(v ...interface{}) { atomic.AddInt32(&fired, 1) }, nil)
+	ids := make(map[uint32]bool)
+	for i := 0; i < 100; i++ {
+		handle, err := sts.CreateTimerAfter(df, 100*time.Millisecond)
+		assert.Nil(t, err)
+		assert.False(t, ids[handle.ID()])
+		ids[handle.ID()] = true
+	}
+	// 定时器平均分配到各个分片
+	for _, ts := range sts.shards {
+		assert.Equal(t, 25, ts.Stats().Pending)
+	}
+
+	// 一半通过tID取消, 需要找到所在的分片
+	for id := range ids {
+		if len(ids) > 50 {
+			sts.CancelTimer(id)
+			delete(ids, id)
+		}
+	}
+	assert.Equal(t, 50, sts.Stats().Pending)
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(50), atomic.LoadInt32(&fired))
+	stats := sts.Stats()
+	assert.Equal(t, 0, stats.Pending)
+	assert.Equal(t, uint64(50), stats.Fired)
+}
+
+func TestShardedTimerIDWrap(t *testing.T) {
+	// 分片数向上取整为2的幂
+	sts, err := NewShardedTimerSchedulerWithTiers(3, 10*time.Millisecond, 100, 60)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(sts.shards))
+
+	// tID的计数回绕之后仍然可以找到所在的分片并取消
+	for i, ts := range sts.shards {
+		ts.IDGen = math.MaxUint32 - 3 + uint32(i)
+	}
+	var handles []*TimerHandle
+	df := NewDelayFunc(func(v ...interface{}) {}, nil)
+	for i := 0; i < 8; i++ {
+		handle, err := sts.CreateTimerAfter(df, time.Second)
+		assert.Nil(t, err)
+		handles = append(handles, handle)
+	}
+	assert.Equal(t, 8, sts.Stats().Pending)
+	for _, handle := range handles {
+		sts.CancelTimer(handle.ID())
+	}
+	assert.Equal(t, 0, sts.Stats().Pending)
+
+	sts.Stop()
+	sts.Stop()
+}
+
+// 并发创建并取消定时器(连接的心跳、空闲定时器的典型用法), 比较单个调度器与分片调度器
+// go test -run '^$' -bench 'Scheduler$' -cpu 1,8 ./ztimer, 单核的Xeon虚拟机上的结果:
+//
+//	                                 删除时遍历全部刻度   按slotOf删除
+//	BenchmarkTimerScheduler          2126 ns/op          759 ns/op
+//	BenchmarkTimerScheduler-8        3355 ns/op          771 ns/op
+//	BenchmarkShardedTimerScheduler                       763 ns/op
+//	BenchmarkShardedTimerScheduler-8                     839 ns/op
+//
+// 单核上没有锁的并行争用, 分片只有少量额外开销; 分片的收益需要在多核机器上测量
+func benchmarkScheduler(b *testing.B, create func(df *DelayFunc) (*TimerHandle, error)) {
+	df := NewDelayFunc(func(v ...interface{}) {}, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			handle, err := create(df)
+			if err != nil {
+				b.Fatal(err)
+			}
+			handle.Cancel()
+		}
+	})
+}
+
+func BenchmarkTimerScheduler(b *testing.B) {
+	ts := NewTimerScheduler()
+	benchmarkScheduler(b, func(df *DelayFunc) (*TimerHandle, error) {
+		return ts.CreateTimerAfter(df, 30*time.Second)
+	})
+}
+
+func BenchmarkShardedTimerScheduler(b *testing.B) {
+	sts := NewShardedTimerScheduler(0)
+	benchmarkScheduler(b, func(df *DelayFunc) (*TimerHandle, error) {
+		return sts.CreateTimerAfter(df, 30*time.Second)
+	})
+}
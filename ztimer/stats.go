@@ -0,0 +1,91 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// timerStats 调度器统计计数, 通过atomic访问
+type timerStats struct {
+	fired      uint64
+	late       uint64
+	blocked    uint64
+	latencySum int64
+	maxLatency int64
+}
+
+// TimerStats 定时器调度器的统计, 用于发现时间轮在高负载下落后
+type TimerStats struct {
+	Pending    int           `json:"pending"`    //时间轮中等待触发的定时器数(包括cron与持久化定时器)
+	Queued     int           `json:"queued"`     //已经触发、等待执行方取走的延迟函数数
+	Fired      uint64        `json:"fired"`      //触发的定时器数
+	Late       uint64        `json:"late"`       //触发时间晚于计划时间超过MaxTimeDelay毫秒的定时器数
+	Blocked    uint64        `json:"blocked"`    //触发队列已满, 等待执行方取走的次数
+	Dropped    uint64        `json:"dropped"`    //时间轮转动时重新加入失败而丢失的定时器数
+	AvgLatency time.Duration `json:"avgLatency"` //实际触发时间与计划时间的平均差
+	MaxLatency time.Duration `json:"maxLatency"` //实际触发时间与计划时间的最大差
+}
+
+// SetMetrics 设置指标接口, 定时器触发时记录延迟, 每秒记录等待触发的定时器数; 需要在Start之前设置
+func (ts *TimerScheduler) SetMetrics(metrics ziface.IMetrics) {
+	ts.metrics = metrics
+}
+
+// Stats 调度器的统计
+func (ts *TimerScheduler) Stats() TimerStats {
+	stats := TimerStats{
+		Queued:     len(ts.triggerChan),
+		Fired:      atomic.LoadUint64(&ts.stats.fired),
+		Late:       atomic.LoadUint64(&ts.stats.late),
+		Blocked:    atomic.LoadUint64(&ts.stats.blocked),
+		MaxLatency: time.Duration(atomic.LoadInt64(&ts.stats.maxLatency)),
+	}
+	for tw := ts.tw; tw != nil; tw = tw.nextTimeWheel {
+		stats.Pending += tw.Len()
+		stats.Dropped += atomic.LoadUint64(&tw.dropped)
+	}
+	if stats.Fired > 0 {
+		stats.AvgLatency = time.Duration(atomic.LoadInt64(&ts.stats.latencySum) / int64(stats.Fired))
+	}
+	return stats
+}
+
+// observeFired 记录一个触发的定时器, latency为实际触发时间与计划时间之差, 提前触发(小于0)的按0记录
+func (ts *TimerScheduler) observeFired(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	atomic.AddUint64(&ts.stats.fired, 1)
+	atomic.AddInt64(&ts.stats.latencySum, int64(latency))
+	for {
+		old := atomic.LoadInt64(&ts.stats.maxLatency)
+		if int64(latency) <= old || atomic.CompareAndSwapInt64(&ts.stats.maxLatency, old, int64(latency)) {
+			break
+		}
+	}
+	late := latency > MaxTimeDelay*time.Millisecond
+	if late {
+		atomic.AddUint64(&ts.stats.late, 1)
+	}
+
+	if ts.metrics != nil {
+		ts.metrics.Histogram(ziface.MetricTimerLatency, latency.Seconds(), nil)
+		if late {
+			ts.metrics.Counter(ziface.MetricTimerLate, 1, nil)
+		}
+	}
+}
+
+// reportPending 记录等待触发的定时器数
+func (ts *TimerScheduler) reportPending() {
+	if ts.metrics == nil || ts.sharded {
+		return
+	}
+	pending := 0
+	for tw := ts.tw; tw != nil; tw = tw.nextTimeWheel {
+		pending += tw.Len()
+	}
+	ts.metrics.Gauge(ziface.MetricTimerPending, float64(pending), nil)
+}
@@ -0,0 +1,71 @@
+package ztimer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordMetrics 记录每个指标最后的值与次数
+type recordMetrics struct {
+	lock   sync.Mutex
+	values map[string]float64
+	counts map[string]int
+}
+
+func (m *recordMetrics) record(name string, value float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[name] = value
+	m.counts[name]++
+}
+
+func (m *recordMetrics) Counter(name string, value float64, tags map[string]string) {
+	m.record(name, value)
+}
+
+func (m *recordMetrics) Gauge(name string, value float64, tags map[string]string) {
+	m.record(name, value)
+}
+
+func (m *recordMetrics) Histogram(name string, value float64, tags map[string]string) {
+	m.record(name, value)
+}
+
+func TestTimerStats(t *testing.T) {
+	ts, err := NewTimerSchedulerWithTiers(10*time.Millisecond, 100, 60)
+	assert.Nil(t, err)
+	metrics := &recordMetrics{values: map[string]float64{}, counts: map[string]int{}}
+	ts.SetMetrics(metrics)
+
+	df := NewDelayFunc(func(v ...interface{}) {}, nil)
+	_, err = ts.CreateTimerAfter(df, 50*time.Millisecond)
+	assert.Nil(t, err)
+	// 计划时间已经过去500毫秒的定时器
+	_, err = ts.CreateTimerAt(df, time.Now().Add(-500*time.Millisecond).UnixNano())
+	assert.Nil(t, err)
+	_, err = ts.CreateTimerAfter(df, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, ts.Stats().Pending)
+
+	// 没有执行方取走时触发的延迟函数在队列中等待
+	ts.Start()
+	time.Sleep(1200 * time.Millisecond)
+	stats := ts.Stats()
+	assert.Equal(t, 1, stats.Pending)
+	assert.Equal(t, 2, stats.Queued)
+	assert.Equal(t, uint64(2), stats.Fired)
+	assert.Equal(t, uint64(1), stats.Late)
+	assert.Equal(t, uint64(0), stats.Dropped)
+	assert.GreaterOrEqual(t, int64(stats.MaxLatency), int64(400*time.Millisecond))
+	assert.Greater(t, int64(stats.MaxLatency), int64(stats.AvgLatency))
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	assert.Equal(t, 2, metrics.counts[ziface.MetricTimerLatency])
+	assert.Equal(t, 1, metrics.counts[ziface.MetricTimerLate])
+	assert.Equal(t, float64(1), metrics.values[ziface.MetricTimerPending])
+}
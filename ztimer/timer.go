@@ -33,8 +33,14 @@ const (
 	SecondScales = 60
 	//TimersMaxCap //每个时间轮刻度挂载定时器的最大个数
 	TimersMaxCap = 2048
+
+	//DefaultTimerTick 默认的定时器精度, 即最底层(秒级)时间轮的刻度间隔
+	DefaultTimerTick = SecondInterval * time.Millisecond
 )
 
+//DefaultTimerScales 默认从最底层开始每层时间轮的刻度数: 秒、分钟、小时
+var DefaultTimerScales = []int{SecondScales, MinuteScales, HourScales}
+
 /*
    注意：
     有关时间的几个换算
@@ -0,0 +1,85 @@
+package ztimer
+
+import (
+	"context"
+	"time"
+)
+
+// TimerHandle 定时器的句柄, 由调度器的各个创建方法返回, 用于取消定时器
+type TimerHandle struct {
+	ts  *TimerScheduler
+	tID uint32
+}
+
+// ID 定时器的tID
+func (h *TimerHandle) ID() uint32 {
+	return h.tID
+}
+
+// Cancel 取消定时器, 已经触发(非cron定时器)或已经取消时没有影响
+func (h *TimerHandle) Cancel() {
+	h.ts.CancelTimer(h.tID)
+}
+
+// CreateTimerAtContext 创建一个绑定ctx的定点Timer, ctx结束时自动取消
+// 如绑定连接的conn.Context(), 连接断开后不再执行该连接的延迟任务
+func (ts *TimerScheduler) CreateTimerAtContext(ctx context.Context, df *DelayFunc, unixNano int64) (*TimerHandle, error) {
+	return ts.createTimer(ctx, df, unixNano)
+}
+
+// CreateTimerAfterContext 创建一个绑定ctx的延迟Timer, ctx结束时自动取消
+func (ts *TimerScheduler) CreateTimerAfterContext(ctx context.Context, df *DelayFunc, duration time.Duration) (*TimerHandle, error) {
+	return ts.createTimer(ctx, df, time.Now().Add(duration).UnixNano())
+}
+
+// createTimer 创建Timer并加入分层时间轮, ctx为nil时不绑定
+func (ts *TimerScheduler) createTimer(ctx context.Context, df *DelayFunc, unixNano int64) (*TimerHandle, error) {
+	if ctx != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	ts.Lock()
+	defer ts.Unlock()
+
+	tID := ts.nextID()
+	if ctx != nil {
+		inner := df
+		ts.bindContext(ctx, tID)
+		df = NewDelayFunc(func(v ...interface{}) {
+			ts.Lock()
+			ts.unbindContext(tID)
+			ts.Unlock()
+			// 触发之后执行之前ctx结束的也不再执行
+			if ctx.Err() == nil {
+				inner.Call()
+			}
+		}, nil)
+	}
+	if err := ts.tw.AddTimer(tID, NewTimerAt(df, unixNano)); err != nil {
+		ts.unbindContext(tID)
+		return nil, err
+	}
+	return &TimerHandle{ts: ts, tID: tID}, nil
+}
+
+// bindContext ctx结束时取消tID的定时器, 调用者需持有锁
+// 定时器触发或取消时调用unbindContext结束等待的go程
+func (ts *TimerScheduler) bindContext(ctx context.Context, tID uint32) {
+	done := make(chan struct{})
+	ts.contexts[tID] = done
+	go func() {
+		select {
+		case <-ctx.Done():
+			ts.CancelTimer(tID)
+		case <-done:
+		}
+	}()
+}
+
+// unbindContext 调用者需持有锁
+func (ts *TimerScheduler) unbindContext(tID uint32) {
+	if done, ok := ts.contexts[tID]; ok {
+		delete(ts.contexts, tID)
+		close(done)
+	}
+}
@@ -0,0 +1,53 @@
+package ztimer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimerContext(t *testing.T) {
+	ts := newTestScheduler(t)
+
+	var fired int32
+	df := NewDelayFunc(func(v ...interface{}) { atomic.AddInt32(&fired, 1) }, nil)
+	contexts := func() int {
+		ts.RLock()
+		defer ts.RUnlock()
+		return len(ts.contexts)
+	}
+
+	// ctx结束时自动取消
+	ctx, cancel := context.WithCancel(context.Background())
+	handle, err := ts.CreateTimerAfterContext(ctx, df, 100*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotZero(t, handle.ID())
+	cron, err := ts.CronContext(ctx, "* * * * * *", func() { atomic.AddInt32(&fired, 1) })
+	assert.Nil(t, err)
+	assert.NotEqual(t, handle.ID(), cron.ID())
+	cancel()
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fired))
+	assert.Equal(t, 0, contexts())
+
+	// 已经结束的ctx
+	_, err = ts.CreateTimerAtContext(ctx, df, time.Now().UnixNano())
+	assert.Equal(t, context.Canceled, err)
+
+	// 正常触发之后不再等待ctx
+	_, err = ts.CreateTimerAfterContext(context.Background(), df, 50*time.Millisecond)
+	assert.Nil(t, err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+	assert.Equal(t, 0, contexts())
+
+	// 通过句柄取消
+	handle, err = ts.CreateTimerAfter(df, 50*time.Millisecond)
+	assert.Nil(t, err)
+	handle.Cancel()
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+}
@@ -10,10 +10,14 @@ package ztimer
  */
 
 import (
+	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
 )
 
@@ -28,62 +32,153 @@ const (
 type TimerScheduler struct {
 	//当前调度器的最高级时间轮
 	tw *TimeWheel
+	//最底层时间轮的刻度间隔，即定时器的精度
+	tick time.Duration
+	//最高级时间轮转动一圈的时间
+	span time.Duration
 	//定时器编号累加器
 	IDGen uint32
+	//编号每次增加的步长, 分片调度器中每个分片的编号按分片数错开
+	idStep uint32
+	//是否为分片调度器的分片, 等待触发的定时器数由分片调度器汇总记录
+	sharded bool
 	//已经触发定时器的channel
 	triggerChan chan *DelayFunc
+	//cron任务, key为定时器的tID
+	crons map[uint32]*cronJob
+	//绑定context的定时器, 定时器触发或取消时关闭对应的channel
+	contexts map[uint32]chan struct{}
+	//持久化定时器的存储与注册的任务
+	store ziface.ITimerStore
+	tasks map[string]DurableTask
+	//持久化定时器的tID与ID的对应关系
+	durables   map[uint32]string
+	durableIDs map[string]uint32
+	//串行化存储的写入与时间轮的修改
+	durableLock sync.Mutex
+	//统计计数与指标接口
+	stats   timerStats
+	metrics ziface.IMetrics
 	//互斥锁
 	sync.RWMutex
 }
 
-// NewTimerScheduler 返回一个定时器调度器 ，主要创建分层定时器，并做关联，并依次启动
+// NewTimerScheduler 返回一个定时器调度器 ，按全局配置的TimerTick与TimerScales创建分层定时器，并做关联，并依次启动
+// 默认为秒、分钟、小时三层时间轮
 func NewTimerScheduler() *TimerScheduler {
+	tick := time.Duration(zconf.GlobalObject.TimerTick) * time.Millisecond
+	ts, err := NewTimerSchedulerWithTiers(tick, zconf.GlobalObject.TimerScales...)
+	if err != nil {
+		zlog.Ins().ErrorF("%v, use default timer wheels", err)
+		ts, _ = NewTimerSchedulerWithTiers(DefaultTimerTick, DefaultTimerScales...)
+	}
+	return ts
+}
 
-	//创建秒级时间轮
-	secondTw := NewTimeWheel(SecondName, SecondInterval, SecondScales, TimersMaxCap)
-	//创建分钟级时间轮
-	minuteTw := NewTimeWheel(MinuteName, MinuteInterval, MinuteScales, TimersMaxCap)
-	//创建小时级时间轮
-	hourTw := NewTimeWheel(HourName, HourInterval, HourScales, TimersMaxCap)
+// NewTimerSchedulerWithTiers 返回一个自定义分层时间轮的定时器调度器
+// tick: 最底层时间轮的刻度间隔, 即定时器的精度, 为1ms的整数倍(定时器以ms为最小精度), 0使用默认(1秒)
+// scales: 从最底层开始每层时间轮的刻度数, 层数为参数个数, 为空时使用默认(60, 60, 12); 上一层的刻度间隔为下一层转动一圈的时间
+// 如战斗中的毫秒级定时器可以使用NewTimerSchedulerWithTiers(time.Millisecond, 1000, 60, 60, 12), 精度越高时间轮转动越频繁
+func NewTimerSchedulerWithTiers(tick time.Duration, scales ...int) (*TimerScheduler, error) {
+	if tick == 0 {
+		tick = DefaultTimerTick
+	}
+	if len(scales) == 0 {
+		scales = DefaultTimerScales
+	}
+	if tick < time.Millisecond || tick%time.Millisecond != 0 {
+		return nil, fmt.Errorf("timer tick must be a positive multiple of 1ms, got %v", tick)
+	}
+	for i, n := range scales {
+		if n < 2 {
+			return nil, fmt.Errorf("timer scales[%d] must be at least 2, got %d", i, n)
+		}
+	}
 
-	//将分层时间轮做关联
-	hourTw.AddTimeWheel(minuteTw)
-	minuteTw.AddTimeWheel(secondTw)
+	//从最底层开始创建时间轮，并将分层时间轮做关联
+	var top *TimeWheel
+	interval := int64(tick / time.Millisecond)
+	for _, n := range scales {
+		tw := NewTimeWheel(tierName(interval), interval, n, tierMaxCap(n))
+		if top != nil {
+			tw.AddTimeWheel(top)
+		}
+		top = tw
+		interval *= int64(n)
+	}
 
 	//时间轮运行
-	secondTw.Run()
-	minuteTw.Run()
-	hourTw.Run()
+	for tw := top; tw != nil; tw = tw.nextTimeWheel {
+		tw.Run()
+	}
 
 	return &TimerScheduler{
-		tw:          hourTw,
+		tw:          top,
+		tick:        tick,
+		span:        time.Duration(interval) * time.Millisecond,
+		idStep:      1,
 		triggerChan: make(chan *DelayFunc, MaxChanBuff),
-	}
+		crons:       make(map[uint32]*cronJob),
+		contexts:    make(map[uint32]chan struct{}),
+		tasks:       make(map[string]DurableTask),
+		durables:    make(map[uint32]string),
+		durableIDs:  make(map[string]uint32),
+	}, nil
 }
 
-//CreateTimerAt 创建一个定点Timer 并将Timer添加到分层时间轮中， 返回Timer的tID
-func (ts *TimerScheduler) CreateTimerAt(df *DelayFunc, unixNano int64) (uint32, error) {
-	ts.Lock()
-	defer ts.Unlock()
+// tierName 时间轮的名称, 默认的三层仍使用SECOND、MINUTE、HOUR
+func tierName(interval int64) string {
+	switch interval {
+	case SecondInterval:
+		return SecondName
+	case MinuteInterval:
+		return MinuteName
+	case HourInterval:
+		return HourName
+	}
+	return fmt.Sprintf("%dMS", interval)
+}
 
-	ts.IDGen++
-	return ts.IDGen, ts.tw.AddTimer(ts.IDGen, NewTimerAt(df, unixNano))
+// tierMaxCap 每个刻度预分配的定时器容量, 刻度越多每个刻度上的定时器越少
+func tierMaxCap(scales int) int {
+	if maxCap := TimersMaxCap * SecondScales / scales; maxCap < TimersMaxCap {
+		return maxCap
+	}
+	return TimersMaxCap
 }
 
-//CreateTimerAfter 创建一个延迟Timer 并将Timer添加到分层时间轮中， 返回Timer的tID
-func (ts *TimerScheduler) CreateTimerAfter(df *DelayFunc, duration time.Duration) (uint32, error) {
-	ts.Lock()
-	defer ts.Unlock()
+//CreateTimerAt 创建一个定点Timer 并将Timer添加到分层时间轮中， 返回Timer的句柄
+func (ts *TimerScheduler) CreateTimerAt(df *DelayFunc, unixNano int64) (*TimerHandle, error) {
+	return ts.createTimer(nil, df, unixNano)
+}
 
-	ts.IDGen++
-	return ts.IDGen, ts.tw.AddTimer(ts.IDGen, NewTimerAfter(df, duration))
+//CreateTimerAfter 创建一个延迟Timer 并将Timer添加到分层时间轮中， 返回Timer的句柄
+func (ts *TimerScheduler) CreateTimerAfter(df *DelayFunc, duration time.Duration) (*TimerHandle, error) {
+	return ts.createTimer(nil, df, time.Now().Add(duration).UnixNano())
 }
 
-//CancelTimer 删除timer
+//CancelTimer 删除timer, 持久化的定时器同时从存储中删除
 func (ts *TimerScheduler) CancelTimer(tID uint32) {
 	ts.Lock()
-	defer ts.Unlock()
+	id, durable := ts.durables[tID]
+	ts.removeTimer(tID)
+	ts.Unlock()
 
+	if durable {
+		if err := ts.deleteDurable(id); err != nil {
+			zlog.Ins().ErrorF("delete durable timer %s err: %v", id, err)
+		}
+	}
+}
+
+//removeTimer 从分层时间轮中删除timer, 调用者需持有锁
+func (ts *TimerScheduler) removeTimer(tID uint32) {
+	delete(ts.crons, tID)
+	ts.unbindContext(tID)
+	if id, ok := ts.durables[tID]; ok {
+		delete(ts.durables, tID)
+		delete(ts.durableIDs, id)
+	}
 	tw := ts.tw
 	for tw != nil {
 		tw.RemoveTimer(tID)
@@ -91,6 +186,17 @@ func (ts *TimerScheduler) CancelTimer(tID uint32) {
 	}
 }
 
+//nextID 生成定时器编号, 调用者需持有锁
+func (ts *TimerScheduler) nextID() uint32 {
+	ts.IDGen += ts.idStep
+	return ts.IDGen
+}
+
+//maxDelay 一次加入时间轮的最长延迟, 最高级时间轮转动一圈之后的时间先加入一个中间的定时器分段等待
+func (ts *TimerScheduler) maxDelay() time.Duration {
+	return ts.span - time.Duration(ts.tw.interval)*time.Millisecond
+}
+
 //GetTriggerChan 获取计时结束的延迟执行函数通道
 func (ts *TimerScheduler) GetTriggerChan() chan *DelayFunc {
 	return ts.triggerChan
@@ -98,20 +204,37 @@ func (ts *TimerScheduler) GetTriggerChan() chan *DelayFunc {
 
 //Start 非阻塞的方式启动timerSchedule
 func (ts *TimerScheduler) Start() {
+	//获取超时定时器的时间窗口，不超过定时器的精度与MaxTimeDelay
+	window := MaxTimeDelay * time.Millisecond
+	if ts.tick < window {
+		window = ts.tick
+	}
 	go func() {
+		lastReport := time.Now()
 		for {
 			//当前时间
 			now := UnixMilli()
-			//获取最近MaxTimeDelay 毫秒的超时定时器集合
-			timerList := ts.tw.GetTimerWithIn(MaxTimeDelay * time.Millisecond)
+			//获取最近window时间内的超时定时器集合
+			timerList := ts.tw.GetTimerWithIn(window)
 			for _, timer := range timerList {
 				if math.Abs(float64(now-timer.unixts)) > MaxTimeDelay {
 					//已经超时的定时器，报警
 					zlog.Error("want call at ", timer.unixts, "; real call at", now, "; delay ", now-timer.unixts)
 				}
-				ts.triggerChan <- timer.delayFunc
+				ts.observeFired(time.Duration(now-timer.unixts) * time.Millisecond)
+				select {
+				case ts.triggerChan <- timer.delayFunc:
+				default:
+					//执行方来不及取走，等待期间时间轮上的其他定时器也会延迟
+					atomic.AddUint64(&ts.stats.blocked, 1)
+					ts.triggerChan <- timer.delayFunc
+				}
+			}
+			if time.Since(lastReport) >= time.Second {
+				ts.reportPending()
+				lastReport = time.Now()
 			}
-			time.Sleep(MaxTimeDelay / 2 * time.Millisecond)
+			time.Sleep(window / 2)
 		}
 	}()
 }
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
 )
 
 //触发函数
@@ -30,9 +31,9 @@ func TestNewTimerScheduler(t *testing.T) {
 	//在scheduler中添加timer
 	for i := 1; i < 2000; i++ {
 		f := NewDelayFunc(foo, []interface{}{i, i * 3})
-		tID, err := timerScheduler.CreateTimerAfter(f, time.Duration(3*i)*time.Millisecond)
+		_, err := timerScheduler.CreateTimerAfter(f, time.Duration(3*i)*time.Millisecond)
 		if err != nil {
-			zlog.Error("create timer error", tID, err)
+			zlog.Error("create timer error", i, err)
 			break
 		}
 	}
@@ -56,9 +57,9 @@ func TestNewAutoExecTimerScheduler(t *testing.T) {
 	//给调度器添加Timer
 	for i := 0; i < 2000; i++ {
 		f := NewDelayFunc(foo, []interface{}{i, i * 3})
-		tID, err := autoTS.CreateTimerAfter(f, time.Duration(3*i)*time.Millisecond)
+		_, err := autoTS.CreateTimerAfter(f, time.Duration(3*i)*time.Millisecond)
 		if err != nil {
-			zlog.Error("create timer error", tID, err)
+			zlog.Error("create timer error", i, err)
 			break
 		}
 	}
@@ -80,9 +81,42 @@ func TestCancelTimerScheduler(t *testing.T) {
 	if nil != err {
 		t.Log("Scheduler.CreateTimerAfter(f1, time.Duration(3)*time.Second)", "err：", err)
 	}
-	log.Printf("timerID1=%d ,timerID2=%d\n", timerID1, timerID2)
-	Scheduler.CancelTimer(timerID1) //删除timerID1
+	log.Printf("timerID1=%d ,timerID2=%d\n", timerID1.ID(), timerID2.ID())
+	timerID1.Cancel() //删除timerID1
 
 	//阻塞等待
 	select {}
 }
+
+//自定义分层时间轮的精度
+func TestNewTimerSchedulerWithTiers(t *testing.T) {
+	_, err := NewTimerSchedulerWithTiers(500*time.Microsecond, 100)
+	assert.NotNil(t, err)
+	_, err = NewTimerSchedulerWithTiers(time.Millisecond, 1000, 1)
+	assert.NotNil(t, err)
+
+	//10毫秒精度, 两层时间轮共60秒
+	ts, err := NewTimerSchedulerWithTiers(10*time.Millisecond, 100, 60)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, ts.span)
+	ts.Start()
+
+	start := time.Now()
+	fired := make(chan time.Duration, 1)
+	_, err = ts.CreateTimerAfter(NewDelayFunc(func(v ...interface{}) {
+		fired <- time.Since(start)
+	}, nil), 150*time.Millisecond)
+	assert.Nil(t, err)
+
+	go func() {
+		for df := range ts.GetTriggerChan() {
+			df.Call()
+		}
+	}()
+	select {
+	case delay := <-fired:
+		assert.InDelta(t, float64(150*time.Millisecond), float64(delay), float64(30*time.Millisecond))
+	case <-time.After(time.Second):
+		t.Fatal("timer not fired")
+	}
+}
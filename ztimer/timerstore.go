@@ -0,0 +1,105 @@
+package ztimer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// FileTimerStore 保存在本地文件中的持久化定时器存储, 适合单机部署
+// 全部定时器以JSON保存在一个文件中, 每次修改先写入临时文件再替换, 进程在写入期间退出时不会损坏文件
+type FileTimerStore struct {
+	path   string
+	lock   sync.Mutex
+	timers map[string]ziface.DurableTimer
+}
+
+func NewFileTimerStore(path string) *FileTimerStore {
+	return &FileTimerStore{path: path}
+}
+
+// load 第一次使用时读取文件, 文件不存在时为空
+func (s *FileTimerStore) load() error {
+	if s.timers != nil {
+		return nil
+	}
+	timers := make(map[string]ziface.DurableTimer)
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		var list []ziface.DurableTimer
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, timer := range list {
+			timers[timer.ID] = timer
+		}
+	}
+	s.timers = timers
+	return nil
+}
+
+// list 按触发时间排序的全部定时器
+func (s *FileTimerStore) list() []ziface.DurableTimer {
+	list := make([]ziface.DurableTimer, 0, len(s.timers))
+	for _, timer := range s.timers {
+		list = append(list, timer)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].At < list[j].At })
+	return list
+}
+
+func (s *FileTimerStore) flush() error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileTimerStore) Save(ctx context.Context, timer ziface.DurableTimer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.timers[timer.ID] = timer
+	return s.flush()
+}
+
+func (s *FileTimerStore) Delete(ctx context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.timers[id]; !ok {
+		return nil
+	}
+	delete(s.timers, id)
+	return s.flush()
+}
+
+func (s *FileTimerStore) LoadAll(ctx context.Context) ([]ziface.DurableTimer, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.list(), nil
+}
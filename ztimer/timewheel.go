@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aceld/zinx/zlog"
@@ -41,8 +42,12 @@ type TimeWheel struct {
 	//当前时间轮上的所有timer
 	timerQueue map[int]map[uint32]*Timer //map[int] VALUE  其中int表示当前时间轮的刻度,
 	// map[int] map[uint32] *Timer, uint32表示Timer的ID号
+	//定时器所在的刻度, 删除定时器时直接找到刻度, 不需要遍历全部刻度
+	slotOf map[uint32]int
 	//下一层时间轮
 	nextTimeWheel *TimeWheel
+	//转动时重新加入失败而丢失的定时器数
+	dropped uint64
 	//互斥锁（继承RWMutex的 RWLock,UnLock 等方法）
 	sync.RWMutex
 }
@@ -60,6 +65,7 @@ func NewTimeWheel(name string, interval int64, scales int, maxCap int) *TimeWhee
 		scales:     scales,
 		maxCap:     maxCap,
 		timerQueue: make(map[int]map[uint32]*Timer, scales),
+		slotOf:     make(map[uint32]int),
 	}
 	//初始化map
 	for i := 0; i < scales; i++ {
@@ -81,14 +87,13 @@ func NewTimeWheel(name string, interval int64, scales int, maxCap int) *TimeWhee
 	如果当前的timer的超时时间间隔 小于一个刻度 :
 					如果没有下一轮时间轮
 */
-func (tw *TimeWheel) addTimer(tID uint32, t *Timer, forceNext bool) error {
-	defer func() error {
-		if err := recover(); err != nil {
-			errstr := fmt.Sprintf("addTimer function err : %s", err)
-			zlog.Ins().ErrorF("addTimer function err : %s", err)
-			return errors.New(errstr)
+func (tw *TimeWheel) addTimer(tID uint32, t *Timer, forceNext bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			errstr := fmt.Sprintf("addTimer function err : %s", r)
+			zlog.Ins().ErrorF("addTimer function err : %s", r)
+			err = errors.New(errstr)
 		}
-		return nil
 	}()
 
 	//得到当前的超时时间间隔(ms)毫秒为单位
@@ -99,7 +104,7 @@ func (tw *TimeWheel) addTimer(tID uint32, t *Timer, forceNext bool) error {
 		//得到需要跨越几个刻度
 		dn := delayInterval / tw.interval
 		//在对应的刻度上的定时器Timer集合map加入当前定时器(由于是环形，所以要求余)
-		tw.timerQueue[(tw.curIndex+int(dn))%tw.scales][tID] = t
+		tw.setTimer((tw.curIndex+int(dn))%tw.scales, tID, t)
 
 		return nil
 	}
@@ -112,22 +117,30 @@ func (tw *TimeWheel) addTimer(tID uint32, t *Timer, forceNext bool) error {
 			//因为这是底层时间轮，该定时器在转动的时候，如果没有被调度者取走的话，该定时器将不会再被发现
 			//因为时间轮刻度已经过去，如果不强制把该定时器Timer移至下时刻，就永远不会被取走并触发调用
 			//所以这里强制将timer移至下个刻度的集合中，等待调用者在下次轮转之前取走该定时器
-			tw.timerQueue[(tw.curIndex+1)%tw.scales][tID] = t
+			tw.setTimer((tw.curIndex+1)%tw.scales, tID, t)
 		} else {
 			//如果手动添加定时器，那么直接将timer添加到对应底层时间轮的当前刻度集合中
-			tw.timerQueue[tw.curIndex][tID] = t
+			tw.setTimer(tw.curIndex, tID, t)
 		}
 		return nil
 	}
 
 	//如果当前的超时时间，小于一个刻度的时间间隔，并且有下一层时间轮
 	if delayInterval < tw.interval {
+		//时间轮转动时从当前时间轮移至下一层
+		delete(tw.slotOf, tID)
 		return tw.nextTimeWheel.AddTimer(tID, t)
 	}
 
 	return nil
 }
 
+//setTimer 将定时器放到slot刻度上, 调用者需持有锁
+func (tw *TimeWheel) setTimer(slot int, tID uint32, t *Timer) {
+	tw.timerQueue[slot][tID] = t
+	tw.slotOf[tID] = slot
+}
+
 //AddTimer 添加一个timer到一个时间轮中(非时间轮自转情况)
 func (tw *TimeWheel) AddTimer(tID uint32, t *Timer) error {
 	tw.Lock()
@@ -141,13 +154,20 @@ func (tw *TimeWheel) RemoveTimer(tID uint32) {
 	tw.Lock()
 	defer tw.Unlock()
 
-	for i := 0; i < tw.scales; i++ {
-		if _, ok := tw.timerQueue[i][tID]; ok {
-			delete(tw.timerQueue[i], tID)
-		}
+	if slot, ok := tw.slotOf[tID]; ok {
+		delete(tw.timerQueue[slot], tID)
+		delete(tw.slotOf, tID)
 	}
 }
 
+//Len 当前时间轮上的定时器个数
+func (tw *TimeWheel) Len() int {
+	tw.RLock()
+	defer tw.RUnlock()
+
+	return len(tw.slotOf)
+}
+
 //AddTimeWheel 给一个时间轮添加下层时间轮 比如给小时时间轮添加分钟时间轮，给分钟时间轮添加秒时间轮
 func (tw *TimeWheel) AddTimeWheel(next *TimeWheel) {
 	tw.nextTimeWheel = next
@@ -158,10 +178,10 @@ func (tw *TimeWheel) AddTimeWheel(next *TimeWheel) {
 	启动时间轮
 */
 func (tw *TimeWheel) run() {
-	for {
-		//时间轮每间隔interval一刻度时间，触发转动一次
-		time.Sleep(time.Duration(tw.interval) * time.Millisecond)
-
+	//时间轮每间隔interval一刻度时间，触发转动一次; 使用Ticker避免毫秒级刻度时误差累积
+	ticker := time.NewTicker(time.Duration(tw.interval) * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
 		tw.Lock()
 		//取出挂载在当前刻度的全部定时器
 		curTimers := tw.timerQueue[tw.curIndex]
@@ -169,14 +189,20 @@ func (tw *TimeWheel) run() {
 		tw.timerQueue[tw.curIndex] = make(map[uint32]*Timer, tw.maxCap)
 		for tID, timer := range curTimers {
 			//这里属于时间轮自动转动，forceNext设置为true
-			tw.addTimer(tID, timer, true)
+			if tw.addTimer(tID, timer, true) != nil {
+				delete(tw.slotOf, tID)
+				atomic.AddUint64(&tw.dropped, 1)
+			}
 		}
 
 		//取出下一个刻度 挂载的全部定时器 进行重新添加 (为了安全起见,待考慮)
 		nextTimers := tw.timerQueue[(tw.curIndex+1)%tw.scales]
 		tw.timerQueue[(tw.curIndex+1)%tw.scales] = make(map[uint32]*Timer, tw.maxCap)
 		for tID, timer := range nextTimers {
-			tw.addTimer(tID, timer, true)
+			if tw.addTimer(tID, timer, true) != nil {
+				delete(tw.slotOf, tID)
+				atomic.AddUint64(&tw.dropped, 1)
+			}
 		}
 
 		//当前刻度指针 走一格
@@ -215,6 +241,7 @@ func (tw *TimeWheel) GetTimerWithIn(duration time.Duration) map[uint32]*Timer {
 			timerList[tID] = timer
 			//定时器已经超时被取走，从当前时间轮上 摘除该定时器
 			delete(leaftw.timerQueue[leaftw.curIndex], tID)
+			delete(leaftw.slotOf, tID)
 		}
 	}
 